@@ -33,6 +33,10 @@ func main() {
 
 	log.Info().Msg("Starting Chinese Chess Backend Server")
 
+	// Root context for background loops, cancelled on shutdown
+	rootCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -64,19 +68,85 @@ func main() {
 	moveRepo := repository.NewMoveRepository(db)
 
 	// Initialize services
-	userService := services.NewUserService(userRepo)
-	gameService := services.NewGameService(gameRepo, moveRepo, userRepo)
-	matchmakingService := services.NewMatchmakingService(redisClient, gameService)
+	userService := services.NewUserServiceWithRenameInterval(
+		userRepo, time.Duration(cfg.RateLimit.RenameIntervalHours)*time.Hour,
+	)
+	ratingBounds := services.NewRatingBounds(cfg.Rating.Floor, cfg.Rating.Ceiling)
+	userService.SetRatingBounds(ratingBounds)
+	gameService := services.NewGameServiceWithSnapshots(gameRepo, moveRepo, userRepo, redisClient, cfg.Snapshot.Interval)
+	gameService.SetRatingBounds(ratingBounds)
+	if cfg.Notify.ResultWebhookURL != "" {
+		gameService.SetResultHook(services.NewWebhookResultHook(
+			cfg.Notify.ResultWebhookURL, time.Duration(cfg.Notify.WebhookTimeoutSeconds)*time.Second,
+		))
+	}
+	gameService.SetSearchPool(services.NewSearchPool(cfg.Analysis.Workers, cfg.Analysis.QueueSize))
+	matchmakingService := services.NewMatchmakingServiceWithClaimWindow(
+		redisClient, gameService, time.Duration(cfg.Matchmaking.ClaimWindowSeconds)*time.Second,
+	)
+	matchmakingService.SetMaxWait(time.Duration(cfg.Matchmaking.MaxWaitSeconds) * time.Second)
+	go matchmakingService.Run(rootCtx)
+	rematchService := services.NewRematchService(redisClient, gameService, userService)
+	challengeService := services.NewChallengeService(
+		redisClient, redisClient, gameService, userService, time.Duration(cfg.Matchmaking.ChallengeTTLSeconds)*time.Second,
+	)
+	statsService := services.NewStatsService(gameRepo, userRepo, matchmakingService)
+	connectTokenService := services.NewConnectTokenService(
+		redisClient, gameService, time.Duration(cfg.WebSocket.ConnectTokenTTL)*time.Second,
+	)
+
+	reconnectTokens, err := services.NewReconnectTokens(cfg.WebSocket.ReconnectSecret)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create reconnect token secret")
+	}
+	// Seats are remembered across instances only when every instance can
+	// verify the tokens they ask for
+	if reconnectTokens.Shared() {
+		reconnectTokens.SetSeatStore(redisClient)
+	} else if cfg.Environment == "production" {
+		log.Fatal().Msg("A reconnect secret must be configured in production")
+	} else {
+		log.Warn().Msg("No reconnect secret configured; reconnection tokens only verify on this instance")
+	}
 
 	// Initialize WebSocket hub
-	wsHub := websocket.NewHub(gameService)
+	wsHub := websocket.NewHub(gameService, cfg.Rules)
+	wsHub.SetMaxConnections(cfg.WebSocket.MaxConnections)
+	wsHub.SetMessageRateLimit(cfg.WebSocket.MessagesPerSecond)
+	wsHub.SetDisconnectCountdownInterval(time.Duration(cfg.WebSocket.DisconnectCountdownSeconds) * time.Second)
+	wsHub.SetMaxSpectators(cfg.WebSocket.MaxSpectatorsPerGame)
+	wsHub.SetReconnectTokens(reconnectTokens)
+	wsHub.SetMatchmakingService(matchmakingService)
 	go wsHub.Run()
 
 	// Initialize handlers
 	userHandler := handlers.NewUserHandler(userService)
-	matchmakingHandler := handlers.NewMatchmakingHandler(matchmakingService)
+	matchmakingHandler := handlers.NewMatchmakingHandler(matchmakingService, cfg.Matchmaking.TurnTimeoutPresets)
 	gameHandler := handlers.NewGameHandlerWithUserService(gameService, userService, wsHub)
-	wsHandler := handlers.NewWebSocketHandler(wsHub, gameService)
+	gameHandler.SetAnalysisLimits(services.NewAnalysisLimits(
+		cfg.Analysis.MaxDepth,
+		time.Duration(cfg.Analysis.TimeoutMs)*time.Millisecond,
+	))
+	rematchHandler := handlers.NewRematchHandler(rematchService)
+	challengeHandler := handlers.NewChallengeHandler(challengeService, cfg.Matchmaking.TurnTimeoutPresets)
+	statsHandler := handlers.NewStatsHandler(statsService)
+	wsHandler := handlers.NewWebSocketHandlerWithTokens(wsHub, gameService, connectTokenService, cfg.WebSocket)
+	wsHandler.SetReconnectTokens(reconnectTokens)
+
+	// Rate limiters, shared across instances when backed by Redis
+	registrationWindow := time.Duration(cfg.RateLimit.RegistrationWindowMinutes) * time.Minute
+	var requestLimiter, registrationLimiter func(http.Handler) http.Handler
+	if cfg.RateLimit.Backend == config.RateLimitBackendRedis {
+		requestLimiter = custommiddleware.RedisRateLimiter(redisClient, 100) // 100 requests per minute
+		registrationLimiter = custommiddleware.RedisRegistrationRateLimiter(
+			redisClient, cfg.RateLimit.RegistrationsPerIP, registrationWindow,
+		)
+	} else {
+		requestLimiter = custommiddleware.RateLimiterWithContext(rootCtx, 100) // 100 requests per minute
+		registrationLimiter = custommiddleware.RegistrationRateLimiterWithContext(
+			rootCtx, cfg.RateLimit.RegistrationsPerIP, registrationWindow,
+		)
+	}
 
 	// Setup router
 	r := chi.NewRouter()
@@ -131,11 +201,11 @@ func main() {
 	r.Route("/api/v1", func(r chi.Router) {
 		// Apply authentication middleware to all API routes
 		r.Use(custommiddleware.DeviceAuth)
-		r.Use(custommiddleware.RateLimiter(100)) // 100 requests per minute
+		r.Use(requestLimiter)
 
 		// User routes
 		r.Route("/users", func(r chi.Router) {
-			r.Post("/register", userHandler.Register)
+			r.With(registrationLimiter).Post("/register", userHandler.Register)
 			r.Get("/{deviceId}", userHandler.GetProfile)
 			r.Patch("/{deviceId}", userHandler.UpdateProfile)
 		})
@@ -147,17 +217,36 @@ func main() {
 			r.Get("/status", matchmakingHandler.GetStatus)
 		})
 
+		// Challenge routes
+		r.Route("/challenges", func(r chi.Router) {
+			r.Post("/", challengeHandler.CreateChallenge)
+			r.Get("/", challengeHandler.ListChallenges)
+			r.Post("/{challengeId}/accept", challengeHandler.AcceptChallenge)
+		})
+
 		// Game routes
 		r.Route("/games", func(r chi.Router) {
 			r.Get("/history", gameHandler.GetHistory)
 			r.Get("/active", gameHandler.GetActiveGames)
+			r.Get("/live", gameHandler.GetLiveGames)
+			r.Post("/import", gameHandler.ImportGame)
+			r.Post("/bot", gameHandler.CreateBotGame)
 			r.Get("/{gameId}", gameHandler.GetGame)
 			r.Get("/{gameId}/moves", gameHandler.GetMoves)
+			r.Get("/{gameId}/positions", gameHandler.GetPositions)
 			r.Get("/{gameId}/full", gameHandler.GetGameWithMoves)
+			r.Get("/{gameId}/export", gameHandler.ExportGame)
+			r.Get("/{gameId}/evaluation", gameHandler.GetEvaluation)
+			r.Get("/{gameId}/moves/{moveNumber}/analysis", gameHandler.AnalyzeMove)
+			r.Post("/{gameId}/rematch", rematchHandler.RequestRematch)
+			r.Post("/{gameId}/connect-token", wsHandler.IssueConnectToken)
 		})
 
 		// User stats route
 		r.Get("/users/{userId}/stats", gameHandler.GetUserStats)
+
+		// Server stats route
+		r.Get("/stats/server", statsHandler.GetServerStats)
 	})
 
 	// WebSocket route (outside API route group)
@@ -187,6 +276,9 @@ func main() {
 
 	log.Info().Msg("Shutting down server...")
 
+	// Stop background loops
+	stopBackground()
+
 	// Create shutdown context with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -0,0 +1,3 @@
+-- Rollback: Remove auto-rematch setting from users
+
+ALTER TABLE users DROP COLUMN IF EXISTS auto_rematch;
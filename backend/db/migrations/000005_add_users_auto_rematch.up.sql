@@ -0,0 +1,7 @@
+-- Migration: Add auto-rematch setting to users
+-- Chinese Chess (Xiangqi) Backend
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS auto_rematch BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN users.auto_rematch IS 'Whether rematch requests from opponents are accepted automatically';
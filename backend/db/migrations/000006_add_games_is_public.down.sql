@@ -0,0 +1,4 @@
+-- Rollback: Remove public visibility setting from games
+
+DROP INDEX IF EXISTS idx_games_live_public;
+ALTER TABLE games DROP COLUMN IF EXISTS is_public;
@@ -0,0 +1,11 @@
+-- Migration: Add public visibility setting to games
+-- Chinese Chess (Xiangqi) Backend
+
+ALTER TABLE games
+    ADD COLUMN IF NOT EXISTS is_public BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Index for listing live public games (newest first)
+CREATE INDEX IF NOT EXISTS idx_games_live_public ON games(created_at DESC)
+    WHERE status = 'active' AND is_public;
+
+COMMENT ON COLUMN games.is_public IS 'Whether the game is listed for spectating while in progress';
@@ -0,0 +1,3 @@
+-- Rollback: Remove display name change tracking from users
+
+ALTER TABLE users DROP COLUMN IF EXISTS display_name_changed_at;
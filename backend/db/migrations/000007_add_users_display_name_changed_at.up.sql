@@ -0,0 +1,7 @@
+-- Migration: Track when users last changed their display name
+-- Chinese Chess (Xiangqi) Backend
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS display_name_changed_at TIMESTAMP WITH TIME ZONE;
+
+COMMENT ON COLUMN users.display_name_changed_at IS 'When the display name was last changed; NULL if never changed since registration';
@@ -0,0 +1,3 @@
+-- Rollback: Remove ranked setting from games
+
+ALTER TABLE games DROP COLUMN IF EXISTS ranked;
@@ -0,0 +1,8 @@
+-- Migration: Add ranked setting to games
+-- Chinese Chess (Xiangqi) Backend
+
+-- Existing games were all ranked
+ALTER TABLE games
+    ADD COLUMN IF NOT EXISTS ranked BOOLEAN NOT NULL DEFAULT TRUE;
+
+COMMENT ON COLUMN games.ranked IS 'Whether the result counts towards player stats and ratings';
@@ -0,0 +1,3 @@
+-- Rollback: Remove final position FEN from games
+
+ALTER TABLE games DROP COLUMN IF EXISTS final_fen;
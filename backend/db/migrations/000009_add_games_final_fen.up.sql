@@ -0,0 +1,7 @@
+-- Migration: Add final position FEN to games
+-- Chinese Chess (Xiangqi) Backend
+
+ALTER TABLE games
+    ADD COLUMN IF NOT EXISTS final_fen VARCHAR(100);
+
+COMMENT ON COLUMN games.final_fen IS 'FEN of the final position, set when the game completes';
@@ -0,0 +1,8 @@
+-- Rollback: Remove win breakdown from users
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS wins_by_checkmate,
+    DROP COLUMN IF EXISTS wins_by_stalemate,
+    DROP COLUMN IF EXISTS wins_by_timeout,
+    DROP COLUMN IF EXISTS wins_by_resignation,
+    DROP COLUMN IF EXISTS wins_by_abandonment;
@@ -0,0 +1,10 @@
+-- Migration: Add win breakdown by result type to users
+-- Chinese Chess (Xiangqi) Backend
+
+-- Wins recorded before this migration are not broken down
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS wins_by_checkmate INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS wins_by_stalemate INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS wins_by_timeout INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS wins_by_resignation INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS wins_by_abandonment INTEGER NOT NULL DEFAULT 0;
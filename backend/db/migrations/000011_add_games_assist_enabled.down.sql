@@ -0,0 +1,3 @@
+-- Rollback: Remove legal move assist setting from games
+
+ALTER TABLE games DROP COLUMN IF EXISTS assist_enabled;
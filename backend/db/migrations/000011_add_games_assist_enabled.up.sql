@@ -0,0 +1,8 @@
+-- Migration: Add legal move assist setting to games
+-- Chinese Chess (Xiangqi) Backend
+
+-- Existing games all had assistance
+ALTER TABLE games
+    ADD COLUMN IF NOT EXISTS assist_enabled BOOLEAN NOT NULL DEFAULT TRUE;
+
+COMMENT ON COLUMN games.assist_enabled IS 'Whether the server sends legal move hints such as forced moves';
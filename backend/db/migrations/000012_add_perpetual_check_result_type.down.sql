@@ -0,0 +1,9 @@
+-- Rollback: Remove perpetual check result type
+-- Postgres cannot drop an enum value, so games ended by perpetual check are
+-- recorded as checkmates and the type is rebuilt without it
+
+ALTER TABLE games ALTER COLUMN result_type TYPE TEXT;
+UPDATE games SET result_type = 'checkmate' WHERE result_type = 'perpetual_check';
+DROP TYPE result_type;
+CREATE TYPE result_type AS ENUM ('checkmate', 'timeout', 'resignation', 'abandonment', 'draw', 'stalemate');
+ALTER TABLE games ALTER COLUMN result_type TYPE result_type USING result_type::result_type;
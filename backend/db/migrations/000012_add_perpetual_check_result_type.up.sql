@@ -0,0 +1,5 @@
+-- Migration: Add perpetual check result type
+-- Chinese Chess (Xiangqi) Backend
+
+-- Perpetual check is a loss for the checking side under Asian rules
+ALTER TYPE result_type ADD VALUE IF NOT EXISTS 'perpetual_check';
@@ -0,0 +1,3 @@
+-- Rollback: Remove computer opponent difficulty from games
+
+ALTER TABLE games DROP COLUMN IF EXISTS bot_difficulty;
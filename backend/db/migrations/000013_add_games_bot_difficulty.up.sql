@@ -0,0 +1,8 @@
+-- Migration: Add computer opponent difficulty to games
+-- Chinese Chess (Xiangqi) Backend
+
+-- Existing games were all between two people
+ALTER TABLE games
+    ADD COLUMN IF NOT EXISTS bot_difficulty INTEGER NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN games.bot_difficulty IS 'Difficulty of the computer opponent playing black, or 0 when both players are human';
@@ -0,0 +1,4 @@
+-- Rollback: Remove time control modes from games
+
+ALTER TABLE games DROP COLUMN IF EXISTS increment_seconds;
+ALTER TABLE games DROP COLUMN IF EXISTS time_control;
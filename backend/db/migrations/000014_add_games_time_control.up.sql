@@ -0,0 +1,14 @@
+-- Migration: Add time control modes to games
+-- Chinese Chess (Xiangqi) Backend
+
+-- Existing games were all played with a per-move timeout
+ALTER TABLE games
+    ADD COLUMN IF NOT EXISTS time_control VARCHAR(16) NOT NULL DEFAULT 'per_move',
+    ADD COLUMN IF NOT EXISTS increment_seconds INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE games
+    ADD CONSTRAINT valid_time_control CHECK (time_control IN ('per_move', 'fischer', 'bronstein')),
+    ADD CONSTRAINT valid_increment_seconds CHECK (increment_seconds >= 0);
+
+COMMENT ON COLUMN games.time_control IS 'How the clocks run: per_move, fischer or bronstein';
+COMMENT ON COLUMN games.increment_seconds IS 'Fischer increment or Bronstein delay, in seconds';
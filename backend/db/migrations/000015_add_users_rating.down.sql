@@ -0,0 +1,4 @@
+-- Rollback: Remove ELO ratings from users
+
+ALTER TABLE users DROP COLUMN IF EXISTS rating_deviation;
+ALTER TABLE users DROP COLUMN IF EXISTS rating;
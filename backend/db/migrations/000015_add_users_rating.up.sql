@@ -0,0 +1,10 @@
+-- Migration: Add ELO ratings to users
+-- Chinese Chess (Xiangqi) Backend
+
+-- Existing players start from the default rating
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS rating INTEGER NOT NULL DEFAULT 1200,
+    ADD COLUMN IF NOT EXISTS rating_deviation INTEGER NOT NULL DEFAULT 350;
+
+COMMENT ON COLUMN users.rating IS 'ELO rating from ranked games';
+COMMENT ON COLUMN users.rating_deviation IS 'How uncertain the rating is; narrows as more ranked games are played';
@@ -0,0 +1,4 @@
+-- Rollback: Remove the winning color from games
+
+ALTER TABLE games DROP CONSTRAINT IF EXISTS valid_winner_color;
+ALTER TABLE games DROP COLUMN IF EXISTS winner_color;
@@ -0,0 +1,12 @@
+-- Migration: Add the winning color to games
+-- Chinese Chess (Xiangqi) Backend
+
+-- Imported games have one owner playing both sides, so winner_id cannot
+-- tell which side won
+ALTER TABLE games
+    ADD COLUMN IF NOT EXISTS winner_color VARCHAR(5);
+
+ALTER TABLE games
+    ADD CONSTRAINT valid_winner_color CHECK (winner_color IN ('red', 'black'));
+
+COMMENT ON COLUMN games.winner_color IS 'Color of the winning side of an imported game (NULL otherwise)';
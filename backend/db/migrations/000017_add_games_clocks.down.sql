@@ -0,0 +1,6 @@
+-- Rollback: Remove the base time and saved clocks from games
+
+ALTER TABLE games DROP CONSTRAINT IF EXISTS valid_base_time_seconds;
+ALTER TABLE games DROP COLUMN IF EXISTS black_time_remaining;
+ALTER TABLE games DROP COLUMN IF EXISTS red_time_remaining;
+ALTER TABLE games DROP COLUMN IF EXISTS base_time_seconds;
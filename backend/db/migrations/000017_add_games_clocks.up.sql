@@ -0,0 +1,16 @@
+-- Migration: Add the base time and saved clocks to games
+-- Chinese Chess (Xiangqi) Backend
+
+-- Fischer and Bronstein games used to take the turn timeout as each side's
+-- time for the game, so existing games keep a base time of 0
+ALTER TABLE games
+    ADD COLUMN IF NOT EXISTS base_time_seconds INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS red_time_remaining INTEGER,
+    ADD COLUMN IF NOT EXISTS black_time_remaining INTEGER;
+
+ALTER TABLE games
+    ADD CONSTRAINT valid_base_time_seconds CHECK (base_time_seconds >= 0);
+
+COMMENT ON COLUMN games.base_time_seconds IS 'Each side''s time for a Fischer or Bronstein game, in seconds (0 for per-move games)';
+COMMENT ON COLUMN games.red_time_remaining IS 'Red''s clock after the last move, in seconds (NULL before the first move)';
+COMMENT ON COLUMN games.black_time_remaining IS 'Black''s clock after the last move, in seconds (NULL before the first move)';
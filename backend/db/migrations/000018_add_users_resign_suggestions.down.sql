@@ -0,0 +1,3 @@
+-- Rollback: Remove resign suggestion setting from users
+
+ALTER TABLE users DROP COLUMN IF EXISTS resign_suggestions;
@@ -0,0 +1,7 @@
+-- Migration: Add resign suggestion setting to users
+-- Chinese Chess (Xiangqi) Backend
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS resign_suggestions BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN users.resign_suggestions IS 'Whether the player is sent resign suggestions in hopeless positions';
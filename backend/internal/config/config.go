@@ -3,6 +3,7 @@ package config
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/spf13/viper"
@@ -10,10 +11,18 @@ import (
 
 // Config holds all configuration for the application.
 type Config struct {
-	Environment string         `mapstructure:"environment"`
-	Server      ServerConfig   `mapstructure:"server"`
-	Database    DatabaseConfig `mapstructure:"database"`
-	Redis       RedisConfig    `mapstructure:"redis"`
+	Environment string            `mapstructure:"environment"`
+	Server      ServerConfig      `mapstructure:"server"`
+	Database    DatabaseConfig    `mapstructure:"database"`
+	Redis       RedisConfig       `mapstructure:"redis"`
+	Rules       RulesConfig       `mapstructure:"rules"`
+	WebSocket   WebSocketConfig   `mapstructure:"websocket"`
+	RateLimit   RateLimitConfig   `mapstructure:"rate_limit"`
+	Snapshot    SnapshotConfig    `mapstructure:"snapshot"`
+	Matchmaking MatchmakingConfig `mapstructure:"matchmaking"`
+	Analysis    AnalysisConfig    `mapstructure:"analysis"`
+	Notify      NotifyConfig      `mapstructure:"notifications"`
+	Rating      RatingConfig      `mapstructure:"rating"`
 }
 
 // ServerConfig holds HTTP server configuration.
@@ -34,6 +43,10 @@ type DatabaseConfig struct {
 	SSLMode      string `mapstructure:"sslmode"`
 	MaxOpenConns int    `mapstructure:"max_open_conns"`
 	MaxIdleConns int    `mapstructure:"max_idle_conns"`
+
+	// ReadReplicaURL is an optional connection string for a read replica.
+	// Read-heavy queries such as game history use it when set.
+	ReadReplicaURL string `mapstructure:"read_replica_url"`
 }
 
 // DSN returns the PostgreSQL connection string.
@@ -57,6 +70,159 @@ func (c RedisConfig) Address() string {
 	return fmt.Sprintf("%s:%d", c.Host, c.Port)
 }
 
+// WebSocketConfig holds WebSocket connection configuration.
+type WebSocketConfig struct {
+	// EnableCompression negotiates permessage-deflate with clients that support it.
+	EnableCompression bool `mapstructure:"enable_compression"`
+	// CompressionLevel is the flate level used for compressed messages (1-9).
+	CompressionLevel int `mapstructure:"compression_level"`
+	// CompressionThreshold is the minimum message size in bytes worth compressing.
+	CompressionThreshold int `mapstructure:"compression_threshold"`
+	// MaxConnections caps concurrent WebSocket connections; 0 means unlimited.
+	MaxConnections int `mapstructure:"max_connections"`
+	// ConnectTokenTTL is how long a single-use connect token stays valid, in seconds.
+	ConnectTokenTTL int `mapstructure:"connect_token_ttl"`
+	// MessagesPerSecond caps messages each client may send; 0 means unlimited.
+	MessagesPerSecond int `mapstructure:"messages_per_second"`
+	// DisconnectCountdownSeconds is how often a waiting player is told the
+	// grace left for a disconnected opponent; 0 disables the countdown.
+	DisconnectCountdownSeconds int `mapstructure:"disconnect_countdown_seconds"`
+	// MaxSpectatorsPerGame caps the spectators watching each game; 0 means
+	// unlimited.
+	MaxSpectatorsPerGame int `mapstructure:"max_spectators_per_game"`
+	// ReconnectSecret keys the tokens players present when reconnecting to
+	// a game. It must be shared by all instances; when empty a random secret
+	// is generated at startup.
+	ReconnectSecret string `mapstructure:"reconnect_secret"`
+}
+
+// RateLimitConfig holds request rate limit configuration.
+type RateLimitConfig struct {
+	// RegistrationsPerIP is the number of registrations allowed per IP per window.
+	RegistrationsPerIP int `mapstructure:"registrations_per_ip"`
+	// RegistrationWindowMinutes is the length of the registration window.
+	RegistrationWindowMinutes int `mapstructure:"registration_window_minutes"`
+	// RenameIntervalHours is the minimum time between display name changes;
+	// 0 means unlimited.
+	RenameIntervalHours int `mapstructure:"rename_interval_hours"`
+	// Backend is where request counts are kept: "memory" for a single
+	// instance, or "redis" to share limits across instances.
+	Backend string `mapstructure:"backend"`
+}
+
+// Rate limit backends.
+const (
+	// RateLimitBackendMemory keeps request counts in the process.
+	RateLimitBackendMemory = "memory"
+	// RateLimitBackendRedis keeps request counts in Redis, shared across
+	// instances.
+	RateLimitBackendRedis = "redis"
+)
+
+// MatchmakingConfig holds matchmaking configuration.
+type MatchmakingConfig struct {
+	// TurnTimeoutPresets lists the turn timeouts in seconds players may queue
+	// with. Players are only matched with others using the same preset.
+	TurnTimeoutPresets []int `mapstructure:"turn_timeout_presets"`
+	// ClaimWindowSeconds is how long a match result stays available to a
+	// player who has not opened the game yet.
+	ClaimWindowSeconds int `mapstructure:"claim_window_seconds"`
+	// MaxWaitSeconds is how long a player may wait in the queue before being
+	// removed with a timeout status.
+	MaxWaitSeconds int `mapstructure:"max_wait_seconds"`
+	// ChallengeTTLSeconds is how long a direct challenge to another player
+	// can be accepted.
+	ChallengeTTLSeconds int `mapstructure:"challenge_ttl_seconds"`
+}
+
+// SnapshotConfig holds game snapshot persistence configuration.
+type SnapshotConfig struct {
+	// Interval is the number of moves between snapshot flushes; 0 disables
+	// periodic snapshots.
+	Interval int `mapstructure:"interval"`
+}
+
+// AnalysisConfig limits the CPU spent on engine analysis and AI requests.
+type AnalysisConfig struct {
+	// MaxDepth is the deepest search a request may ask for.
+	MaxDepth int `mapstructure:"max_depth"`
+	// TimeoutMs is the compute budget of a single request in milliseconds.
+	TimeoutMs int `mapstructure:"timeout_ms"`
+	// Workers is the number of searches run at once; 0 means one per CPU.
+	Workers int `mapstructure:"workers"`
+	// QueueSize is how many searches may wait for a worker before new ones
+	// are turned away as busy.
+	QueueSize int `mapstructure:"queue_size"`
+}
+
+// RatingConfig holds ELO rating configuration.
+type RatingConfig struct {
+	// Floor is the lowest rating a player can drop to.
+	Floor int `mapstructure:"floor"`
+	// Ceiling is the highest rating a player can reach.
+	Ceiling int `mapstructure:"ceiling"`
+}
+
+// NotifyConfig holds settings for notifying external systems of game events.
+type NotifyConfig struct {
+	// ResultWebhookURL receives a JSON POST for every completed game; empty
+	// disables the webhook.
+	ResultWebhookURL string `mapstructure:"result_webhook_url"`
+	// WebhookTimeoutSeconds bounds each webhook request.
+	WebhookTimeoutSeconds int `mapstructure:"webhook_timeout_seconds"`
+}
+
+// RulesConfig holds optional game rule settings.
+type RulesConfig struct {
+	// NoRollbackAfterCheck forbids rollback requests from a player who has
+	// just been put in check by the opponent's latest move.
+	NoRollbackAfterCheck bool `mapstructure:"no_rollback_after_check"`
+	// ResignSuggestionThreshold is the material evaluation, in tenths of a
+	// soldier, at or below which a position counts as hopeless.
+	ResignSuggestionThreshold int `mapstructure:"resign_suggestion_threshold"`
+	// ResignSuggestionMoves is how many consecutive hopeless positions are
+	// needed before suggesting resignation.
+	ResignSuggestionMoves int `mapstructure:"resign_suggestion_moves"`
+	// FirstMoveGraceSeconds is extra time added to each side's first move.
+	FirstMoveGraceSeconds int `mapstructure:"first_move_grace_seconds"`
+	// CasualUnlimitedRollbacks lets players in casual games take back moves
+	// without limit. The opponent must still accept each rollback.
+	CasualUnlimitedRollbacks bool `mapstructure:"casual_unlimited_rollbacks"`
+	// NoCaptureDrawPlies draws a game after this many plies without a
+	// capture. Zero disables the rule. Imported and replayed games never
+	// use it.
+	NoCaptureDrawPlies int `mapstructure:"no_capture_draw_plies"`
+	// ThreatHints adds the side to move's attacked, undefended pieces to
+	// game_state as threatened_pieces. It costs an attack scan per update.
+	ThreatHints bool `mapstructure:"threat_hints"`
+	// Features lists the feature flags enabled in this environment. New
+	// variant rules are gated on a flag so they can be rolled out gradually.
+	Features []string `mapstructure:"features"`
+}
+
+// Feature names a rule that is rolled out behind a feature flag.
+type Feature string
+
+const (
+	// FeatureRepetition ends games on threefold repetition.
+	FeatureRepetition Feature = "repetition"
+	// FeaturePerpetualCheck makes perpetual check a loss for the checking side.
+	FeaturePerpetualCheck Feature = "perpetual_check"
+)
+
+// knownFeatures lists every feature flag with a rule behind it.
+var knownFeatures = []Feature{FeatureRepetition, FeaturePerpetualCheck}
+
+// Enabled reports whether the feature flag is turned on.
+func (c RulesConfig) Enabled(feature Feature) bool {
+	for _, name := range c.Features {
+		if Feature(strings.TrimSpace(name)) == feature {
+			return true
+		}
+	}
+	return false
+}
+
 // Load reads configuration from environment variables and config files.
 func Load() (*Config, error) {
 	// Set default values
@@ -74,12 +240,55 @@ func Load() (*Config, error) {
 	viper.SetDefault("database.sslmode", "disable")
 	viper.SetDefault("database.max_open_conns", 25)
 	viper.SetDefault("database.max_idle_conns", 5)
+	viper.SetDefault("database.read_replica_url", "")
 
 	viper.SetDefault("redis.host", "localhost")
 	viper.SetDefault("redis.port", 6379)
 	viper.SetDefault("redis.password", "")
 	viper.SetDefault("redis.db", 0)
 
+	viper.SetDefault("websocket.enable_compression", true)
+	viper.SetDefault("websocket.compression_level", 1)
+	viper.SetDefault("websocket.compression_threshold", 512)
+	viper.SetDefault("websocket.max_connections", 10000)
+	viper.SetDefault("websocket.connect_token_ttl", 30)
+	viper.SetDefault("websocket.messages_per_second", 20)
+	viper.SetDefault("websocket.disconnect_countdown_seconds", 5)
+	viper.SetDefault("websocket.max_spectators_per_game", 50)
+	viper.SetDefault("websocket.reconnect_secret", "")
+
+	viper.SetDefault("rate_limit.registrations_per_ip", 5)
+	viper.SetDefault("rate_limit.registration_window_minutes", 60)
+	viper.SetDefault("rate_limit.rename_interval_hours", 24)
+	viper.SetDefault("rate_limit.backend", "memory")
+
+	viper.SetDefault("snapshot.interval", 5)
+
+	viper.SetDefault("matchmaking.turn_timeout_presets", []int{30, 60, 300})
+	viper.SetDefault("matchmaking.claim_window_seconds", 60)
+	viper.SetDefault("matchmaking.max_wait_seconds", 120)
+	viper.SetDefault("matchmaking.challenge_ttl_seconds", 300)
+
+	viper.SetDefault("analysis.max_depth", 5)
+	viper.SetDefault("analysis.timeout_ms", 2000)
+	viper.SetDefault("analysis.workers", 0)
+	viper.SetDefault("analysis.queue_size", 16)
+
+	viper.SetDefault("rating.floor", 100)
+	viper.SetDefault("rating.ceiling", 3000)
+
+	viper.SetDefault("notifications.result_webhook_url", "")
+	viper.SetDefault("notifications.webhook_timeout_seconds", 5)
+
+	viper.SetDefault("rules.no_rollback_after_check", false)
+	viper.SetDefault("rules.resign_suggestion_threshold", -90)
+	viper.SetDefault("rules.resign_suggestion_moves", 3)
+	viper.SetDefault("rules.first_move_grace_seconds", 0)
+	viper.SetDefault("rules.casual_unlimited_rollbacks", false)
+	viper.SetDefault("rules.no_capture_draw_plies", 120)
+	viper.SetDefault("rules.threat_hints", false)
+	viper.SetDefault("rules.features", []string{})
+
 	// Read from config file if exists
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
@@ -105,5 +314,18 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("unable to decode config: %w", err)
 	}
 
+	for _, name := range cfg.Rules.Features {
+		if !slices.Contains(knownFeatures, Feature(strings.TrimSpace(name))) {
+			return nil, fmt.Errorf("unknown rules feature flag %q", name)
+		}
+	}
+
+	switch cfg.RateLimit.Backend {
+	case RateLimitBackendMemory, RateLimitBackendRedis:
+	default:
+		return nil, fmt.Errorf("unknown rate limit backend %q: must be %q or %q",
+			cfg.RateLimit.Backend, RateLimitBackendMemory, RateLimitBackendRedis)
+	}
+
 	return &cfg, nil
 }
@@ -0,0 +1,50 @@
+// Package config provides unit tests for application configuration.
+package config
+
+import "testing"
+
+func TestRulesConfig_Enabled(t *testing.T) {
+	rules := RulesConfig{Features: []string{" perpetual_check "}}
+
+	if !rules.Enabled(FeaturePerpetualCheck) {
+		t.Error("Expected a listed feature to be enabled")
+	}
+	if rules.Enabled(FeatureRepetition) {
+		t.Error("Expected an unlisted feature to be disabled")
+	}
+	if (RulesConfig{}).Enabled(FeatureRepetition) {
+		t.Error("Expected features to be disabled by default")
+	}
+}
+
+func TestLoad_FeaturesFromEnvironment(t *testing.T) {
+	t.Setenv("XIANGQI_RULES_FEATURES", "repetition")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if !cfg.Rules.Enabled(FeatureRepetition) {
+		t.Errorf("Expected flags from the environment to be enabled, got %v", cfg.Rules.Features)
+	}
+	if cfg.Rules.Enabled(FeaturePerpetualCheck) {
+		t.Error("Expected perpetual_check to stay disabled")
+	}
+}
+
+func TestLoad_RejectsUnknownRateLimitBackend(t *testing.T) {
+	t.Setenv("XIANGQI_RATE_LIMIT_BACKEND", "memcached")
+
+	if _, err := Load(); err == nil {
+		t.Error("Expected an unknown rate limit backend to be rejected")
+	}
+}
+
+func TestLoad_RejectsUnknownFeatureFlag(t *testing.T) {
+	t.Setenv("XIANGQI_RULES_FEATURES", "repetition,chasing")
+
+	if _, err := Load(); err == nil {
+		t.Error("Expected a feature flag without a rule to be rejected")
+	}
+}
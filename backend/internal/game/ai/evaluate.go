@@ -0,0 +1,51 @@
+package ai
+
+import (
+	"github.com/xiangqi/chinese-chess-backend/internal/game"
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
+
+// soldierAdvanceBonus rewards a crossed soldier by how many ranks past the
+// river it stands. Soldiers near the palace threaten most; one on the last
+// rank can only move sideways.
+var soldierAdvanceBonus = [5]int{0, 2, 4, 4, 1}
+
+// Positional weights, in tenths of a soldier like game.Evaluate.
+const (
+	// generalRaisedPenalty is charged for each rank the general has left
+	// its back rank, where the palace guards it best.
+	generalRaisedPenalty = 5
+	// palaceGuardBonus is given for each advisor or elephant still
+	// guarding its own side.
+	palaceGuardBonus = 2
+)
+
+// Evaluate scores the board from color's point of view: the material
+// balance of game.Evaluate plus positional terms for soldier advancement
+// and general safety. Positive values favor color.
+func Evaluate(board *game.Board, color models.PlayerColor) int {
+	return game.Evaluate(board, color) + positional(board, color) - positional(board, color.Opposite())
+}
+
+// positional sums one side's positional bonuses.
+func positional(board *game.Board, color models.PlayerColor) int {
+	total := 0
+	for _, piece := range board.GetPieces(color) {
+		ranksAdvanced := piece.Position.Rank
+		if color == models.PlayerColorBlack {
+			ranksAdvanced = game.RankCount - 1 - piece.Position.Rank
+		}
+
+		switch piece.Type {
+		case models.PieceTypeSoldier:
+			if piece.Position.HasCrossedRiver(color) {
+				total += soldierAdvanceBonus[ranksAdvanced-game.RankCount/2]
+			}
+		case models.PieceTypeGeneral:
+			total -= generalRaisedPenalty * ranksAdvanced
+		case models.PieceTypeAdvisor, models.PieceTypeElephant:
+			total += palaceGuardBonus
+		}
+	}
+	return total
+}
@@ -0,0 +1,53 @@
+// Package ai provides unit tests for position evaluation.
+package ai
+
+import (
+	"testing"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/game"
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
+
+// kingsOnly returns a board with just the two generals on their home
+// squares.
+func kingsOnly() *game.Board {
+	board := game.NewBoard()
+	place(board, models.PieceTypeGeneral, models.PlayerColorRed, 4, 0)
+	place(board, models.PieceTypeGeneral, models.PlayerColorBlack, 3, 9)
+	return board
+}
+
+func TestEvaluate_InitialPositionIsBalanced(t *testing.T) {
+	if score := Evaluate(game.NewInitialBoard(), models.PlayerColorRed); score != 0 {
+		t.Errorf("Expected balanced initial position, got %d", score)
+	}
+}
+
+func TestEvaluate_AdvancedSoldierScoresHigher(t *testing.T) {
+	crossed := kingsOnly()
+	place(crossed, models.PieceTypeSoldier, models.PlayerColorRed, 2, 5)
+	advanced := kingsOnly()
+	place(advanced, models.PieceTypeSoldier, models.PlayerColorRed, 2, 7)
+
+	if Evaluate(advanced, models.PlayerColorRed) <= Evaluate(crossed, models.PlayerColorRed) {
+		t.Error("Expected a soldier near the palace to score higher than one just across the river")
+	}
+
+	// Black's soldiers advance down the board
+	black := kingsOnly()
+	place(black, models.PieceTypeSoldier, models.PlayerColorBlack, 2, 2)
+	if score := Evaluate(black, models.PlayerColorBlack); score != Evaluate(advanced, models.PlayerColorRed) {
+		t.Errorf("Expected black's advanced soldier to score like red's, got %d", score)
+	}
+}
+
+func TestEvaluate_RaisedGeneralIsPenalized(t *testing.T) {
+	home := kingsOnly()
+	raised := game.NewBoard()
+	place(raised, models.PieceTypeGeneral, models.PlayerColorRed, 4, 1)
+	place(raised, models.PieceTypeGeneral, models.PlayerColorBlack, 3, 9)
+
+	if got := Evaluate(home, models.PlayerColorRed) - Evaluate(raised, models.PlayerColorRed); got != generalRaisedPenalty {
+		t.Errorf("Expected raising the general one rank to cost %d, got %d", generalRaisedPenalty, got)
+	}
+}
@@ -0,0 +1,166 @@
+// Package ai searches Xiangqi positions for the best move, for position
+// analysis and computer opponents.
+package ai
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/game"
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
+
+// mateScore is the score of checkmating the opponent. Mates found at a
+// lower ply score higher, so the search prefers the quickest mate.
+const mateScore = 100000
+
+// infinity bounds the alpha-beta window.
+const infinity = mateScore + 1
+
+var (
+	// ErrNoLegalMoves is returned when the side to move has no legal moves.
+	ErrNoLegalMoves = errors.New("no legal moves")
+	// ErrInvalidDifficulty is returned for a difficulty outside
+	// MinDifficulty to MaxDifficulty.
+	ErrInvalidDifficulty = errors.New("invalid difficulty")
+)
+
+// Difficulty levels offered to players of computer opponents.
+const (
+	MinDifficulty = 1
+	MaxDifficulty = 3
+)
+
+// difficultyDepths maps each difficulty level to its search depth.
+var difficultyDepths = map[int]int{1: 1, 2: 2, 3: 3}
+
+// DepthForDifficulty returns the search depth of a difficulty level.
+func DepthForDifficulty(difficulty int) (int, error) {
+	depth, ok := difficultyDepths[difficulty]
+	if !ok {
+		return 0, ErrInvalidDifficulty
+	}
+	return depth, nil
+}
+
+// Engine searches positions with negamax and alpha-beta pruning, scoring
+// leaves with Evaluate.
+type Engine struct {
+	rules *game.RulesEngine
+}
+
+// NewEngine creates a new search engine.
+func NewEngine() *Engine {
+	return &Engine{rules: game.NewRulesEngine()}
+}
+
+// Result is the outcome of a search.
+type Result struct {
+	Move game.Move
+	// Score is from the point of view of the side that moved, in the units
+	// of Evaluate.
+	Score int
+}
+
+// BestMove returns color's best move, searching depth plies. Only legal
+// moves are considered, so the move never leaves color's general in check.
+func (e *Engine) BestMove(board *game.Board, color models.PlayerColor, depth int) (game.Move, error) {
+	result, err := e.Search(context.Background(), board, color, depth)
+	if err != nil {
+		return game.Move{}, err
+	}
+	return result.Move, nil
+}
+
+// Search returns the best move for color, searching depth plies. It stops
+// with the context's error once ctx is done. The board is restored before
+// returning.
+func (e *Engine) Search(ctx context.Context, board *game.Board, color models.PlayerColor, depth int) (Result, error) {
+	moves := e.orderedMoves(board, color)
+	if len(moves) == 0 {
+		return Result{}, ErrNoLegalMoves
+	}
+
+	best := Result{Score: -infinity}
+	alpha := -infinity
+	for _, move := range moves {
+		score, err := e.scoreMove(ctx, board, color, move, depth, 1, alpha, infinity)
+		if err != nil {
+			return Result{}, err
+		}
+		if score > best.Score {
+			best = Result{Move: move, Score: score}
+		}
+		alpha = max(alpha, score)
+	}
+
+	return best, nil
+}
+
+// EvaluateMove returns the score of playing move for color, searching
+// depth plies including the move itself, so the score can be compared with
+// a Search of the same depth.
+func (e *Engine) EvaluateMove(ctx context.Context, board *game.Board, color models.PlayerColor, move game.Move, depth int) (int, error) {
+	return e.scoreMove(ctx, board, color, move, depth, 1, -infinity, infinity)
+}
+
+// scoreMove plays move and searches the reply, returning the score from
+// color's point of view.
+func (e *Engine) scoreMove(ctx context.Context, board *game.Board, color models.PlayerColor, move game.Move, depth, ply, alpha, beta int) (int, error) {
+	var score int
+	var err error
+	withMove(board, move, func() {
+		score, err = e.negamax(ctx, board, color.Opposite(), depth-1, ply, -beta, -alpha)
+	})
+	return -score, err
+}
+
+// negamax returns the score of the position for the side to move.
+func (e *Engine) negamax(ctx context.Context, board *game.Board, color models.PlayerColor, depth, ply, alpha, beta int) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if depth <= 0 {
+		return Evaluate(board, color), nil
+	}
+
+	moves := e.orderedMoves(board, color)
+	if len(moves) == 0 {
+		// Checkmate and stalemate are both losses
+		return -(mateScore - ply), nil
+	}
+
+	for _, move := range moves {
+		score, err := e.scoreMove(ctx, board, color, move, depth, ply+1, alpha, beta)
+		if err != nil {
+			return 0, err
+		}
+		if score >= beta {
+			return beta, nil
+		}
+		alpha = max(alpha, score)
+	}
+
+	return alpha, nil
+}
+
+// orderedMoves returns color's legal moves with captures first, which lets
+// alpha-beta prune more of the tree.
+func (e *Engine) orderedMoves(board *game.Board, color models.PlayerColor) []game.Move {
+	moves := e.rules.GetAllLegalMoves(board, color)
+	sort.SliceStable(moves, func(i, j int) bool {
+		return moves[i].CapturedPiece != nil && moves[j].CapturedPiece == nil
+	})
+	return moves
+}
+
+// withMove makes a move on the board, calls fn and then restores the board.
+func withMove(board *game.Board, move game.Move, fn func()) {
+	captured := board.Move(move.From, move.To)
+	fn()
+	board.Move(move.To, move.From)
+	if captured != nil {
+		board.Place(captured)
+	}
+}
@@ -0,0 +1,174 @@
+// Package ai provides unit tests for the search engine.
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/game"
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
+
+// place puts a piece on the board.
+func place(board *game.Board, pieceType models.PieceType, color models.PlayerColor, file, rank int) {
+	board.Place(&game.Piece{Type: pieceType, Color: color, Position: game.Position{File: file, Rank: rank}})
+}
+
+// mateInOneBoard returns a board where red mates with the chariot b1-e1:
+// the black general is hemmed in by its own soldiers, which cannot block.
+func mateInOneBoard() *game.Board {
+	board := game.NewBoard()
+	place(board, models.PieceTypeGeneral, models.PlayerColorRed, 3, 0)
+	place(board, models.PieceTypeChariot, models.PlayerColorRed, 1, 1)
+	place(board, models.PieceTypeGeneral, models.PlayerColorBlack, 4, 9)
+	place(board, models.PieceTypeSoldier, models.PlayerColorBlack, 3, 9)
+	place(board, models.PieceTypeSoldier, models.PlayerColorBlack, 5, 9)
+	return board
+}
+
+func TestEngine_Search_FindsMateInOne(t *testing.T) {
+	board := mateInOneBoard()
+	before := board.ToFEN()
+
+	result, err := NewEngine().Search(context.Background(), board, models.PlayerColorRed, 2)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+
+	if result.Move.From != (game.Position{File: 1, Rank: 1}) || result.Move.To != (game.Position{File: 4, Rank: 1}) {
+		t.Errorf("Expected the mating move b1-e1, got %s-%s", result.Move.From.Notation(), result.Move.To.Notation())
+	}
+	if result.Score < mateScore-10 {
+		t.Errorf("Expected a mate score, got %d", result.Score)
+	}
+	if board.ToFEN() != before {
+		t.Error("Search should leave the board unchanged")
+	}
+}
+
+func TestEngine_Search_TakesFreePiece(t *testing.T) {
+	board := game.NewBoard()
+	place(board, models.PieceTypeGeneral, models.PlayerColorRed, 4, 0)
+	place(board, models.PieceTypeChariot, models.PlayerColorRed, 0, 0)
+	place(board, models.PieceTypeGeneral, models.PlayerColorBlack, 3, 9)
+	place(board, models.PieceTypeChariot, models.PlayerColorBlack, 0, 5)
+
+	result, err := NewEngine().Search(context.Background(), board, models.PlayerColorRed, 1)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if result.Move.To != (game.Position{File: 0, Rank: 5}) {
+		t.Errorf("Expected the chariot to take on a5, got %s-%s", result.Move.From.Notation(), result.Move.To.Notation())
+	}
+}
+
+func TestEngine_EvaluateMove_NeverBeatsBestMove(t *testing.T) {
+	board := game.NewInitialBoard()
+	engine := NewEngine()
+	ctx := context.Background()
+
+	best, err := engine.Search(ctx, board, models.PlayerColorRed, 2)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+
+	for _, move := range game.NewRulesEngine().GetAllLegalMoves(board, models.PlayerColorRed) {
+		score, err := engine.EvaluateMove(ctx, board, models.PlayerColorRed, move, 2)
+		if err != nil {
+			t.Fatalf("EvaluateMove failed: %v", err)
+		}
+		if score > best.Score {
+			t.Errorf("Move %s-%s scores %d, above the best move's %d",
+				move.From.Notation(), move.To.Notation(), score, best.Score)
+		}
+	}
+}
+
+func TestEngine_Search_Errors(t *testing.T) {
+	engine := NewEngine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := engine.Search(ctx, game.NewInitialBoard(), models.PlayerColorRed, 2); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	// Black is mated after b1-e1
+	board := mateInOneBoard()
+	board.Move(game.Position{File: 1, Rank: 1}, game.Position{File: 4, Rank: 1})
+	if _, err := engine.Search(context.Background(), board, models.PlayerColorBlack, 1); !errors.Is(err, ErrNoLegalMoves) {
+		t.Errorf("Expected ErrNoLegalMoves, got %v", err)
+	}
+}
+
+func TestEngine_BestMove_PlaysOnlyLegalMoves(t *testing.T) {
+	searcher := NewEngine()
+	engine := game.NewGameEngine("game-001", "red-player", "black-player")
+
+	// Bot against bot: every chosen move must be accepted by the game engine
+	for ply := 0; ply < 12; ply++ {
+		color := engine.GetCurrentTurn()
+		move, err := searcher.BestMove(engine.GetBoard().Copy(), color, 2)
+		if err != nil {
+			t.Fatalf("Ply %d: BestMove failed: %v", ply+1, err)
+		}
+
+		playerID := "red-player"
+		if color == models.PlayerColorBlack {
+			playerID = "black-player"
+		}
+		result := engine.ValidateAndMakeMove(game.MoveRequest{
+			PlayerID: playerID,
+			From:     move.From.Notation(),
+			To:       move.To.Notation(),
+		})
+		if !result.Success {
+			t.Fatalf("Ply %d: illegal move %s-%s: %s", ply+1, move.From.Notation(), move.To.Notation(), result.ErrorMessage)
+		}
+		if engine.IsGameOver() {
+			break
+		}
+	}
+}
+
+func TestEngine_BestMove_EscapesCheck(t *testing.T) {
+	// Black is in check from the chariot on e5; taking the horse on a2 with
+	// the other chariot would win material but leave the general in check
+	board := game.NewBoard()
+	place(board, models.PieceTypeGeneral, models.PlayerColorRed, 3, 0)
+	place(board, models.PieceTypeChariot, models.PlayerColorRed, 4, 5)
+	place(board, models.PieceTypeHorse, models.PlayerColorRed, 0, 2)
+	place(board, models.PieceTypeGeneral, models.PlayerColorBlack, 4, 9)
+	place(board, models.PieceTypeChariot, models.PlayerColorBlack, 0, 8)
+
+	move, err := NewEngine().BestMove(board, models.PlayerColorBlack, 2)
+	if err != nil {
+		t.Fatalf("BestMove failed: %v", err)
+	}
+
+	board.Move(move.From, move.To)
+	if game.NewRulesEngine().IsInCheck(board, models.PlayerColorBlack) {
+		t.Errorf("Move %s-%s leaves black in check", move.From.Notation(), move.To.Notation())
+	}
+}
+
+func TestDepthForDifficulty(t *testing.T) {
+	previous := 0
+	for difficulty := MinDifficulty; difficulty <= MaxDifficulty; difficulty++ {
+		depth, err := DepthForDifficulty(difficulty)
+		if err != nil {
+			t.Fatalf("DepthForDifficulty(%d) failed: %v", difficulty, err)
+		}
+		if depth <= previous {
+			t.Errorf("Expected difficulty %d to search deeper than %d plies, got %d", difficulty, previous, depth)
+		}
+		previous = depth
+	}
+
+	for _, difficulty := range []int{MinDifficulty - 1, MaxDifficulty + 1} {
+		if _, err := DepthForDifficulty(difficulty); !errors.Is(err, ErrInvalidDifficulty) {
+			t.Errorf("DepthForDifficulty(%d): expected ErrInvalidDifficulty, got %v", difficulty, err)
+		}
+	}
+}
@@ -2,7 +2,9 @@
 package game
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/xiangqi/chinese-chess-backend/internal/models"
 )
@@ -213,6 +215,153 @@ func (b *Board) String() string {
 	return result
 }
 
+// fenPieceLetters maps piece types to their FEN letters. Red pieces are
+// written in uppercase and black pieces in lowercase.
+var fenPieceLetters = map[models.PieceType]byte{
+	models.PieceTypeGeneral:  'k',
+	models.PieceTypeAdvisor:  'a',
+	models.PieceTypeElephant: 'b',
+	models.PieceTypeHorse:    'n',
+	models.PieceTypeChariot:  'r',
+	models.PieceTypeCannon:   'c',
+	models.PieceTypeSoldier:  'p',
+}
+
+// ToFEN returns the piece placement field of the board in Xiangqi FEN,
+// listing ranks from the black side (rank 9) down to the red side (rank 0).
+func (b *Board) ToFEN() string {
+	var sb strings.Builder
+	for rank := RankCount - 1; rank >= 0; rank-- {
+		empty := 0
+		for file := 0; file < FileCount; file++ {
+			piece := b.squares[rank][file]
+			if piece == nil {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				sb.WriteByte(byte('0' + empty))
+				empty = 0
+			}
+			letter := fenPieceLetters[piece.Type]
+			if piece.Color == models.PlayerColorRed {
+				letter -= 'a' - 'A'
+			}
+			sb.WriteByte(letter)
+		}
+		if empty > 0 {
+			sb.WriteByte(byte('0' + empty))
+		}
+		if rank > 0 {
+			sb.WriteByte('/')
+		}
+	}
+	return sb.String()
+}
+
+// ErrInvalidFEN is returned when a FEN string cannot be parsed.
+var ErrInvalidFEN = errors.New("invalid FEN")
+
+// fenPieceTypes maps FEN letters, in lowercase, back to piece types.
+var fenPieceTypes = func() map[byte]models.PieceType {
+	types := make(map[byte]models.PieceType, len(fenPieceLetters))
+	for pieceType, letter := range fenPieceLetters {
+		types[letter] = pieceType
+	}
+	return types
+}()
+
+// ParseFEN parses the piece placement field of a Xiangqi FEN, the format
+// written by ToFEN. Any fields after the placement, such as the side to
+// move, are ignored. The board must pass Validate, so each side has exactly
+// one general.
+func ParseFEN(fen string) (*Board, error) {
+	fields := strings.Fields(fen)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: empty string", ErrInvalidFEN)
+	}
+
+	rows := strings.Split(fields[0], "/")
+	if len(rows) != RankCount {
+		return nil, fmt.Errorf("%w: expected %d ranks, got %d", ErrInvalidFEN, RankCount, len(rows))
+	}
+
+	board := NewBoard()
+	for i, row := range rows {
+		rank := RankCount - 1 - i
+		file := 0
+		for j := 0; j < len(row); j++ {
+			c := row[j]
+			if c >= '1' && c <= '9' {
+				file += int(c - '0')
+				continue
+			}
+
+			color := models.PlayerColorBlack
+			letter := c
+			if c >= 'A' && c <= 'Z' {
+				color = models.PlayerColorRed
+				letter += 'a' - 'A'
+			}
+			pieceType, ok := fenPieceTypes[letter]
+			if !ok {
+				return nil, fmt.Errorf("%w: invalid character %q in rank %d", ErrInvalidFEN, c, rank)
+			}
+			if file >= FileCount {
+				return nil, fmt.Errorf("%w: rank %d has more than %d files", ErrInvalidFEN, rank, FileCount)
+			}
+			board.Place(&Piece{Type: pieceType, Color: color, Position: Position{File: file, Rank: rank}})
+			file++
+		}
+		if file != FileCount {
+			return nil, fmt.Errorf("%w: rank %d has %d files, expected %d", ErrInvalidFEN, rank, file, FileCount)
+		}
+	}
+
+	if err := board.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFEN, err)
+	}
+	return board, nil
+}
+
+// MaxPiecesPerSide is the most pieces of each type a side may have. These
+// are the starting counts, so a board with more of any type cannot come
+// from a real game. Every side must have exactly one general.
+var MaxPiecesPerSide = map[models.PieceType]int{
+	models.PieceTypeGeneral:  1,
+	models.PieceTypeAdvisor:  2,
+	models.PieceTypeElephant: 2,
+	models.PieceTypeHorse:    2,
+	models.PieceTypeChariot:  2,
+	models.PieceTypeCannon:   2,
+	models.PieceTypeSoldier:  5,
+}
+
+// Validate checks that the board could arise from a real game: each side
+// has exactly one general and no more of any piece type than it starts with.
+func (b *Board) Validate() error {
+	for _, color := range []models.PlayerColor{models.PlayerColorRed, models.PlayerColorBlack} {
+		counts := make(map[models.PieceType]int)
+		for _, piece := range b.GetPieces(color) {
+			if _, ok := MaxPiecesPerSide[piece.Type]; !ok {
+				return fmt.Errorf("unknown piece type %q at %s", piece.Type, piece.Position.Notation())
+			}
+			counts[piece.Type]++
+		}
+
+		if counts[models.PieceTypeGeneral] != 1 {
+			return fmt.Errorf("%s has %d generals, expected exactly 1", color, counts[models.PieceTypeGeneral])
+		}
+		for pieceType, limit := range MaxPiecesPerSide {
+			if counts[pieceType] > limit {
+				return fmt.Errorf("%s has %d %ss, at most %d allowed", color, counts[pieceType], pieceType, limit)
+			}
+		}
+	}
+
+	return nil
+}
+
 // pieceChar returns the Chinese character for a piece.
 func pieceChar(p *Piece) string {
 	switch p.Type {
@@ -2,6 +2,8 @@
 package game
 
 import (
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/xiangqi/chinese-chess-backend/internal/models"
@@ -293,6 +295,94 @@ func TestBoardGetGeneral(t *testing.T) {
 	}
 }
 
+// TestBoardToFEN tests FEN serialization of the piece placement.
+func TestBoardToFEN(t *testing.T) {
+	initial := NewInitialBoard().ToFEN()
+	expected := "rnbakabnr/9/1c5c1/p1p1p1p1p/9/9/P1P1P1P1P/1C5C1/9/RNBAKABNR"
+	if initial != expected {
+		t.Errorf("Initial board FEN: expected %s, got %s", expected, initial)
+	}
+
+	board := NewBoard()
+	board.Place(&Piece{Type: models.PieceTypeGeneral, Color: models.PlayerColorRed, Position: Position{4, 0}})
+	board.Place(&Piece{Type: models.PieceTypeGeneral, Color: models.PlayerColorBlack, Position: Position{3, 9}})
+	board.Place(&Piece{Type: models.PieceTypeChariot, Color: models.PlayerColorRed, Position: Position{8, 9}})
+	if got := board.ToFEN(); got != "3k4R/9/9/9/9/9/9/9/9/4K4" {
+		t.Errorf("Sparse board FEN: got %s", got)
+	}
+}
+
+// TestParseFEN_RoundTrip tests that parsing a serialized board reproduces
+// the same piece layout.
+func TestParseFEN_RoundTrip(t *testing.T) {
+	midgame := NewInitialBoard()
+	midgame.Move(Position{7, 2}, Position{4, 2})
+	midgame.Move(Position{7, 9}, Position{6, 7})
+	midgame.Move(Position{0, 3}, Position{0, 4})
+
+	for _, board := range []*Board{NewInitialBoard(), midgame} {
+		fen := board.ToFEN()
+		parsed, err := ParseFEN(fen)
+		if err != nil {
+			t.Fatalf("ParseFEN(%q) failed: %v", fen, err)
+		}
+
+		for rank := 0; rank < RankCount; rank++ {
+			for file := 0; file < FileCount; file++ {
+				pos := Position{file, rank}
+				want, got := board.At(pos), parsed.At(pos)
+				if (want == nil) != (got == nil) {
+					t.Fatalf("%s: occupancy differs after round trip of %q", pos.Notation(), fen)
+				}
+				if want != nil && (want.Type != got.Type || want.Color != got.Color || got.Position != pos) {
+					t.Errorf("%s: expected %s %s, got %s %s at %s",
+						pos.Notation(), want.Color, want.Type, got.Color, got.Type, got.Position.Notation())
+				}
+			}
+		}
+	}
+}
+
+// TestParseFEN_IgnoresSideToMove tests that the fields after the piece
+// placement are ignored.
+func TestParseFEN_IgnoresSideToMove(t *testing.T) {
+	board, err := ParseFEN("rnbakabnr/9/1c5c1/p1p1p1p1p/9/9/P1P1P1P1P/1C5C1/9/RNBAKABNR b - - 0 1")
+	if err != nil {
+		t.Fatalf("ParseFEN failed: %v", err)
+	}
+	if board.ToFEN() != NewInitialBoard().ToFEN() {
+		t.Errorf("Expected the initial position, got %s", board.ToFEN())
+	}
+}
+
+// TestParseFEN_Rejects tests that malformed placements are rejected.
+func TestParseFEN_Rejects(t *testing.T) {
+	testCases := []struct {
+		name string
+		fen  string
+	}{
+		{"empty", ""},
+		{"too few ranks", "4k4/9/9/9/9/9/9/9/4K4"},
+		{"too many ranks", "4k4/9/9/9/9/9/9/9/9/9/4K4"},
+		{"short rank", "4k3/9/9/9/9/9/9/9/9/4K4"},
+		{"long rank", "4k5/9/9/9/9/9/9/9/9/4K4"},
+		{"piece past the last file", "9R/4k4/9/9/9/9/9/9/9/4K4"},
+		{"invalid character", "4k4/9/9/9/4x4/9/9/9/9/4K4"},
+		{"zero digit", "4k4/09/9/9/9/9/9/9/9/4K4"},
+		{"two red generals", "4k4/9/9/9/9/9/9/9/4K4/4K4"},
+		{"two black generals", "3kk4/9/9/9/9/9/9/9/9/4K4"},
+		{"no black general", "9/9/9/9/9/9/9/9/9/4K4"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ParseFEN(tc.fen); !errors.Is(err, ErrInvalidFEN) {
+				t.Errorf("ParseFEN(%q): expected ErrInvalidFEN, got %v", tc.fen, err)
+			}
+		})
+	}
+}
+
 // TestPositionIsValid tests position validity.
 func TestPositionIsValid(t *testing.T) {
 	testCases := []struct {
@@ -412,3 +502,53 @@ func TestPositionOffset(t *testing.T) {
 		t.Errorf("Expected (2,2), got (%d,%d)", newPos.File, newPos.Rank)
 	}
 }
+
+func TestBoardValidate_InitialBoard(t *testing.T) {
+	if err := NewInitialBoard().Validate(); err != nil {
+		t.Errorf("Expected the initial board to be valid, got %v", err)
+	}
+}
+
+func TestBoardValidate_SixSoldiers(t *testing.T) {
+	board := NewInitialBoard()
+	board.Place(createPiece(models.PieceTypeSoldier, models.PlayerColorRed, 1, 3))
+
+	err := board.Validate()
+	if err == nil {
+		t.Fatal("Expected a board with six red soldiers to be rejected")
+	}
+	if !strings.Contains(err.Error(), "soldier") {
+		t.Errorf("Expected the error to name the soldiers, got %v", err)
+	}
+}
+
+func TestBoardValidate_TwoGenerals(t *testing.T) {
+	board := NewInitialBoard()
+	board.Place(createPiece(models.PieceTypeGeneral, models.PlayerColorBlack, 4, 8))
+
+	err := board.Validate()
+	if err == nil {
+		t.Fatal("Expected a board with two black generals to be rejected")
+	}
+	if !strings.Contains(err.Error(), "general") {
+		t.Errorf("Expected the error to name the generals, got %v", err)
+	}
+}
+
+func TestBoardValidate_MissingGeneral(t *testing.T) {
+	board := NewInitialBoard()
+	board.Remove(Position{4, 0})
+
+	if err := board.Validate(); err == nil {
+		t.Error("Expected a board without a red general to be rejected")
+	}
+}
+
+func TestBoardFromState_RejectsInvalidCounts(t *testing.T) {
+	state := NewGameEngine("game-001", "red", "black").GetGameState().Board
+	state[3][1] = PieceState{Type: string(models.PieceTypeSoldier), Color: string(models.PlayerColorRed)}
+
+	if _, err := BoardFromState(state); err == nil {
+		t.Error("Expected BoardFromState to reject six soldiers")
+	}
+}
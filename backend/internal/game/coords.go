@@ -0,0 +1,78 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CoordinateSystem is a way of writing board squares for clients. The
+// a0–i9 notation stays canonical everywhere inside the server; other
+// systems only exist at the API boundary.
+type CoordinateSystem string
+
+const (
+	// CoordsAlgebraic is the canonical notation, files a–i from red's left
+	// and ranks 0–9 from red's side, e.g. "e0".
+	CoordsAlgebraic CoordinateSystem = "algebraic"
+	// CoordsNumeric numbers files 1–9 from red's right and ranks 1–10 from
+	// red's side, written "file-rank", e.g. "5-1" for e0.
+	CoordsNumeric CoordinateSystem = "numeric"
+)
+
+// ErrUnknownCoordinateSystem is returned for an unsupported system name.
+var ErrUnknownCoordinateSystem = errors.New("unknown coordinate system")
+
+// ParseCoordinateSystem returns the named system. An empty name selects the
+// canonical algebraic notation.
+func ParseCoordinateSystem(name string) (CoordinateSystem, error) {
+	switch CoordinateSystem(name) {
+	case "", CoordsAlgebraic:
+		return CoordsAlgebraic, nil
+	case CoordsNumeric:
+		return CoordsNumeric, nil
+	}
+	return "", ErrUnknownCoordinateSystem
+}
+
+// Format converts a square in canonical notation to this system.
+func (c CoordinateSystem) Format(notation string) (string, error) {
+	pos, err := ParsePosition(notation)
+	if err != nil {
+		return "", err
+	}
+
+	switch c {
+	case CoordsAlgebraic:
+		return pos.Notation(), nil
+	case CoordsNumeric:
+		return fmt.Sprintf("%d-%d", FileCount-pos.File, pos.Rank+1), nil
+	}
+	return "", ErrUnknownCoordinateSystem
+}
+
+// Parse converts a square written in this system to canonical notation.
+func (c CoordinateSystem) Parse(square string) (string, error) {
+	switch c {
+	case CoordsAlgebraic:
+		pos, err := ParsePosition(square)
+		if err != nil {
+			return "", err
+		}
+		return pos.Notation(), nil
+	case CoordsNumeric:
+		fileText, rankText, ok := strings.Cut(square, "-")
+		file, fileErr := strconv.Atoi(fileText)
+		rank, rankErr := strconv.Atoi(rankText)
+		if !ok || fileErr != nil || rankErr != nil {
+			return "", fmt.Errorf("invalid numeric square %q", square)
+		}
+		pos := Position{File: FileCount - file, Rank: rank - 1}
+		if !pos.IsValid() {
+			return "", fmt.Errorf("numeric square %q is off the board", square)
+		}
+		return pos.Notation(), nil
+	}
+	return "", ErrUnknownCoordinateSystem
+}
@@ -0,0 +1,55 @@
+// Package game provides unit tests for coordinate systems.
+package game
+
+import "testing"
+
+func TestCoordinateSystem_NumericRoundTrip(t *testing.T) {
+	tests := []struct {
+		canonical string
+		numeric   string
+	}{
+		{"e0", "5-1"},  // Red general
+		{"a0", "9-1"},  // Red's left corner is the ninth file from red's right
+		{"i0", "1-1"},  // Red's right corner
+		{"b2", "8-3"},  // Red cannon
+		{"i9", "1-10"}, // Far corner, tenth rank
+	}
+
+	for _, tt := range tests {
+		got, err := CoordsNumeric.Format(tt.canonical)
+		if err != nil {
+			t.Fatalf("Format(%s) failed: %v", tt.canonical, err)
+		}
+		if got != tt.numeric {
+			t.Errorf("Format(%s) = %s, want %s", tt.canonical, got, tt.numeric)
+		}
+
+		back, err := CoordsNumeric.Parse(got)
+		if err != nil {
+			t.Fatalf("Parse(%s) failed: %v", got, err)
+		}
+		if back != tt.canonical {
+			t.Errorf("Parse(%s) = %s, want %s", got, back, tt.canonical)
+		}
+	}
+}
+
+func TestCoordinateSystem_ParseRejectsInvalidSquares(t *testing.T) {
+	for _, square := range []string{"", "5", "5-", "0-1", "10-1", "5-0", "5-11", "a-1", "5-1-2"} {
+		if got, err := CoordsNumeric.Parse(square); err == nil {
+			t.Errorf("Parse(%q) should fail, got %s", square, got)
+		}
+	}
+}
+
+func TestParseCoordinateSystem(t *testing.T) {
+	if coords, err := ParseCoordinateSystem(""); err != nil || coords != CoordsAlgebraic {
+		t.Errorf("Empty name should select algebraic, got %q, %v", coords, err)
+	}
+	if coords, err := ParseCoordinateSystem("numeric"); err != nil || coords != CoordsNumeric {
+		t.Errorf("Expected numeric, got %q, %v", coords, err)
+	}
+	if _, err := ParseCoordinateSystem("polar"); err != ErrUnknownCoordinateSystem {
+		t.Errorf("Expected ErrUnknownCoordinateSystem, got %v", err)
+	}
+}
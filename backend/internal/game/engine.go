@@ -3,6 +3,8 @@ package game
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/xiangqi/chinese-chess-backend/internal/models"
@@ -10,17 +12,90 @@ import (
 
 // GameEngine manages the state and logic for a single game.
 type GameEngine struct {
-	board       *Board
-	currentTurn models.PlayerColor
-	rules       *RulesEngine
-	moveHistory []MoveRecord
-	gameID      string
-	redPlayerID string
+	board         *Board
+	currentTurn   models.PlayerColor
+	rules         *RulesEngine
+	moveHistory   []MoveRecord
+	gameID        string
+	redPlayerID   string
 	blackPlayerID string
-	isCheck     bool
-	isCheckmate bool
-	isStalemate bool
-	winner      *models.PlayerColor
+	isCheck       bool
+	isCheckmate   bool
+	isStalemate   bool
+	winner        *models.PlayerColor
+
+	// pieceMoveCounts tracks how many times each side moved each piece type.
+	pieceMoveCounts map[models.PlayerColor]map[models.PieceType]int
+
+	// pliesSinceCapture counts the plies played since the last capture, and
+	// the game is drawn once it reaches noCaptureDrawPlies. Zero disables
+	// the rule.
+	pliesSinceCapture  int
+	noCaptureDrawPlies int
+	isDraw             bool
+
+	// perpetualCheckLoses makes a perpetual check lose the game for the
+	// checking side.
+	perpetualCheckLoses bool
+
+	// positionCounts counts how often each position, keyed by PositionKey,
+	// has occurred with the same side to move.
+	positionCounts map[uint64]int
+
+	// lastMoveID numbers the moves made by this engine, and checkpoints are
+	// the points in the move history that RestoreCheckpoint can return to.
+	lastMoveID  uint64
+	checkpoints []checkpoint
+}
+
+// checkpoint marks a point in the move history: its length and the ID of
+// its last move, which tells an undone and replaced move from the original.
+type checkpoint struct {
+	length     int
+	lastMoveID uint64
+}
+
+// ErrInvalidCheckpoint is returned when restoring a checkpoint that was not
+// issued, or whose moves have since been undone and replaced.
+var ErrInvalidCheckpoint = errors.New("invalid checkpoint")
+
+// RepetitionDrawCount is how many times a position must occur, with the same
+// side to move, for the game to be drawn by repetition.
+const RepetitionDrawCount = 3
+
+// DefaultNoCaptureDrawPlies is the customary number of plies without a
+// capture that draws the game: sixty moves each. The rule is off unless
+// WithNoCaptureDrawPlies turns it on.
+const DefaultNoCaptureDrawPlies = 120
+
+// EngineOption configures a GameEngine.
+type EngineOption func(*GameEngine)
+
+// WithNoCaptureDrawPlies sets how many plies without a capture draw the
+// game. Zero, the default, disables the rule.
+func WithNoCaptureDrawPlies(plies int) EngineOption {
+	return func(e *GameEngine) {
+		e.noCaptureDrawPlies = plies
+	}
+}
+
+// WithPerpetualCheckRule sets whether a perpetual check loses the game for
+// the checking side. It is off by default, as the rule is rolled out behind
+// a feature flag; when off, DetectPerpetualCheck still reports perpetual
+// checks but the game goes on.
+func WithPerpetualCheckRule(enabled bool) EngineOption {
+	return func(e *GameEngine) {
+		e.perpetualCheckLoses = enabled
+	}
+}
+
+// SetOptions applies opts to the engine, keeping the options it does not
+// set; a new engine starts with every optional rule off. It lets an engine
+// rebuilt from stored moves take on the rules for the rest of the game.
+func (e *GameEngine) SetOptions(opts ...EngineOption) {
+	for _, opt := range opts {
+		opt(e)
+	}
 }
 
 // MoveRecord records a move with all its details.
@@ -33,43 +108,102 @@ type MoveRecord struct {
 	IsCheck       bool
 	Timestamp     time.Time
 	PlayerID      string
+
+	// Captured is the piece taken by the move, kept so the move can be
+	// undone. Records rebuilt from storage leave it nil.
+	Captured *Piece
+
+	// id identifies moves made by the engine; records rebuilt from storage
+	// have none.
+	id uint64
+
+	// positionKey is the key of the position the move led to.
+	positionKey uint64
 }
 
 // NewGameEngine creates a new game engine with the initial board position.
-func NewGameEngine(gameID, redPlayerID, blackPlayerID string) *GameEngine {
-	return &GameEngine{
-		board:         NewInitialBoard(),
-		currentTurn:   models.PlayerColorRed,
-		rules:         NewRulesEngine(),
-		moveHistory:   make([]MoveRecord, 0),
-		gameID:        gameID,
-		redPlayerID:   redPlayerID,
-		blackPlayerID: blackPlayerID,
-		isCheck:       false,
-		isCheckmate:   false,
-		isStalemate:   false,
-		winner:        nil,
-	}
-}
-
-// NewGameEngineFromState creates a game engine from an existing state.
-func NewGameEngineFromState(gameID, redPlayerID, blackPlayerID string, board *Board, currentTurn models.PlayerColor, moves []MoveRecord) *GameEngine {
+func NewGameEngine(gameID, redPlayerID, blackPlayerID string, opts ...EngineOption) *GameEngine {
+	engine := &GameEngine{
+		board:           NewInitialBoard(),
+		currentTurn:     models.PlayerColorRed,
+		rules:           NewRulesEngine(),
+		moveHistory:     make([]MoveRecord, 0),
+		gameID:          gameID,
+		redPlayerID:     redPlayerID,
+		blackPlayerID:   blackPlayerID,
+		isCheck:         false,
+		isCheckmate:     false,
+		isStalemate:     false,
+		winner:          nil,
+		pieceMoveCounts: newPieceMoveCounts(),
+	}
+	engine.SetOptions(opts...)
+	engine.positionCounts = map[uint64]int{engine.positionKey(): 1}
+	return engine
+}
+
+// NewGameEngineFromState creates a game engine from an existing state. It
+// returns an error if the board could not arise from a real game or the turn
+// is not a player color, since a corrupted stored state could otherwise be
+// mis-evaluated.
+func NewGameEngineFromState(gameID, redPlayerID, blackPlayerID string, board *Board, currentTurn models.PlayerColor, moves []MoveRecord, opts ...EngineOption) (*GameEngine, error) {
+	if err := board.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid board: %w", err)
+	}
+	if currentTurn != models.PlayerColorRed && currentTurn != models.PlayerColorBlack {
+		return nil, fmt.Errorf("invalid current turn %q", currentTurn)
+	}
+
 	engine := &GameEngine{
-		board:         board,
-		currentTurn:   currentTurn,
-		rules:         NewRulesEngine(),
-		moveHistory:   moves,
-		gameID:        gameID,
-		redPlayerID:   redPlayerID,
-		blackPlayerID: blackPlayerID,
+		board:           board,
+		currentTurn:     currentTurn,
+		rules:           NewRulesEngine(),
+		moveHistory:     moves,
+		gameID:          gameID,
+		redPlayerID:     redPlayerID,
+		blackPlayerID:   blackPlayerID,
+		pieceMoveCounts: newPieceMoveCounts(),
+	}
+	engine.SetOptions(opts...)
+
+	// Earlier positions are not known, so repetitions count from here
+	engine.positionCounts = map[uint64]int{engine.positionKey(): 1}
+
+	for _, move := range moves {
+		color := models.PlayerColorRed
+		if move.PlayerID == blackPlayerID {
+			color = models.PlayerColorBlack
+		}
+		engine.pieceMoveCounts[color][move.PieceType]++
 	}
 
 	// Recalculate check status
-	engine.isCheck = engine.rules.IsInCheck(board, currentTurn)
-	engine.isCheckmate = engine.rules.IsCheckmate(board, currentTurn)
-	engine.isStalemate = engine.rules.IsStalemate(board, currentTurn)
+	engine.isCheck, engine.isCheckmate, engine.isStalemate = engine.rules.GetStatus(board, currentTurn)
 
-	return engine
+	return engine, nil
+}
+
+// NewGameEngineFromFEN creates a game engine that resumes from the position
+// in fen. The side to move is read from the field after the piece placement
+// ("w" or "r" for red, "b" for black) and defaults to red when missing.
+func NewGameEngineFromFEN(gameID, redPlayerID, blackPlayerID, fen string, opts ...EngineOption) (*GameEngine, error) {
+	board, err := ParseFEN(fen)
+	if err != nil {
+		return nil, err
+	}
+
+	turn := models.PlayerColorRed
+	if fields := strings.Fields(fen); len(fields) > 1 {
+		switch fields[1] {
+		case "w", "r":
+		case "b":
+			turn = models.PlayerColorBlack
+		default:
+			return nil, fmt.Errorf("%w: invalid side to move %q", ErrInvalidFEN, fields[1])
+		}
+	}
+
+	return NewGameEngineFromState(gameID, redPlayerID, blackPlayerID, board, turn, make([]MoveRecord, 0), opts...)
 }
 
 // GetBoard returns the current board state.
@@ -82,6 +216,16 @@ func (e *GameEngine) GetCurrentTurn() models.PlayerColor {
 	return e.currentTurn
 }
 
+// GetFEN returns the current position in Xiangqi FEN: the piece placement
+// followed by the side to move ("w" for red, "b" for black).
+func (e *GameEngine) GetFEN() string {
+	side := "w"
+	if e.currentTurn == models.PlayerColorBlack {
+		side = "b"
+	}
+	return e.board.ToFEN() + " " + side
+}
+
 // IsCheck returns true if the current player is in check.
 func (e *GameEngine) IsCheck() bool {
 	return e.isCheck
@@ -99,7 +243,12 @@ func (e *GameEngine) IsStalemate() bool {
 
 // IsGameOver returns true if the game has ended.
 func (e *GameEngine) IsGameOver() bool {
-	return e.isCheckmate || e.isStalemate || e.winner != nil
+	return e.isCheckmate || e.isStalemate || e.isDraw || e.winner != nil
+}
+
+// IsDraw returns true if the game was drawn by the no-capture rule.
+func (e *GameEngine) IsDraw() bool {
+	return e.isDraw
 }
 
 // GetWinner returns the winner if the game is over.
@@ -107,11 +256,97 @@ func (e *GameEngine) GetWinner() *models.PlayerColor {
 	return e.winner
 }
 
+// MovesSinceCapture returns the number of plies played since the last
+// capture, or since the start of the game if nothing has been captured.
+func (e *GameEngine) MovesSinceCapture() int {
+	return e.pliesSinceCapture
+}
+
+// SetMovesSinceCapture restores the no-capture counter of an engine rebuilt
+// from stored state, whose board alone does not show when the last capture
+// happened.
+func (e *GameEngine) SetMovesSinceCapture(plies int) {
+	e.pliesSinceCapture = plies
+}
+
+// RepetitionCount returns how many times the current position has occurred
+// with the same side to move, including now.
+func (e *GameEngine) RepetitionCount() int {
+	return e.positionCounts[e.positionKey()]
+}
+
+// PositionCounts returns a copy of the repetition counts of every position
+// reached, keyed by PositionKey, for persisting the engine's state.
+func (e *GameEngine) PositionCounts() map[uint64]int {
+	counts := make(map[uint64]int, len(e.positionCounts))
+	for key, count := range e.positionCounts {
+		counts[key] = count
+	}
+	return counts
+}
+
+// SetPositionCounts restores the repetition counts of an engine rebuilt
+// from stored state, whose board alone does not show the earlier positions.
+// Empty counts are ignored.
+func (e *GameEngine) SetPositionCounts(counts map[uint64]int) {
+	if len(counts) == 0 {
+		return
+	}
+	e.positionCounts = make(map[uint64]int, len(counts))
+	for key, count := range counts {
+		e.positionCounts[key] = count
+	}
+}
+
+// positionKey returns the key of the current position.
+func (e *GameEngine) positionKey() uint64 {
+	return e.rules.PositionKey(e.board, e.currentTurn)
+}
+
 // GetMoveHistory returns all moves made in the game.
 func (e *GameEngine) GetMoveHistory() []MoveRecord {
 	return e.moveHistory
 }
 
+// LastMove returns the most recent move of the game, for highlighting its
+// squares. It returns false if no moves have been made.
+func (e *GameEngine) LastMove() (*MoveRecord, bool) {
+	if len(e.moveHistory) == 0 {
+		return nil, false
+	}
+	move := e.moveHistory[len(e.moveHistory)-1]
+	return &move, true
+}
+
+// LastMoveBy returns the most recent move made by the given player.
+// It returns false if the player has not moved yet.
+func (e *GameEngine) LastMoveBy(playerID string) (*MoveRecord, bool) {
+	for i := len(e.moveHistory) - 1; i >= 0; i-- {
+		if e.moveHistory[i].PlayerID == playerID {
+			move := e.moveHistory[i]
+			return &move, true
+		}
+	}
+	return nil, false
+}
+
+// GetPieceMoveCounts returns how many times the given side has moved each piece type.
+func (e *GameEngine) GetPieceMoveCounts(color models.PlayerColor) map[models.PieceType]int {
+	counts := make(map[models.PieceType]int, len(e.pieceMoveCounts[color]))
+	for pieceType, count := range e.pieceMoveCounts[color] {
+		counts[pieceType] = count
+	}
+	return counts
+}
+
+// newPieceMoveCounts creates an empty per-side move counter.
+func newPieceMoveCounts() map[models.PlayerColor]map[models.PieceType]int {
+	return map[models.PlayerColor]map[models.PieceType]int{
+		models.PlayerColorRed:   make(map[models.PieceType]int),
+		models.PlayerColorBlack: make(map[models.PieceType]int),
+	}
+}
+
 // ValidateMoveRequest validates a move request from a player.
 type MoveRequest struct {
 	PlayerID string
@@ -119,6 +354,9 @@ type MoveRequest struct {
 	To       string // Notation like "e1"
 }
 
+// ErrSameSquare is reported when a move's destination is its origin square.
+var ErrSameSquare = errors.New("piece must move to a different square")
+
 // MoveResult contains the result of a move attempt.
 type MoveResult struct {
 	Success       bool
@@ -129,6 +367,15 @@ type MoveResult struct {
 	IsStalemate   bool
 	CapturedPiece *models.PieceType
 	WinnerID      *string
+	// IsRepetitionDraw is set when the move repeats a position, with the
+	// same side to move, for the RepetitionDrawCount-th time.
+	IsRepetitionDraw bool
+	// IsPerpetualCheck is set when the move completes a perpetual check.
+	// The checking side loses, and WinnerID names its opponent.
+	IsPerpetualCheck bool
+	// IsDraw is set when the move ends the game in a draw under the
+	// no-capture rule.
+	IsDraw bool
 }
 
 // ValidateAndMakeMove validates and executes a move.
@@ -171,6 +418,14 @@ func (e *GameEngine) ValidateAndMakeMove(req MoveRequest) MoveResult {
 		}
 	}
 
+	// Reject staying in place up front so no piece validator has to
+	if fromPos == toPos {
+		return MoveResult{
+			Success:      false,
+			ErrorMessage: ErrSameSquare.Error(),
+		}
+	}
+
 	// Get the piece at the from position
 	piece := e.board.At(fromPos)
 	if piece == nil {
@@ -204,13 +459,22 @@ func (e *GameEngine) ValidateAndMakeMove(req MoveRequest) MoveResult {
 		capturedType = &ct
 	}
 
+	e.pieceMoveCounts[e.currentTurn][piece.Type]++
+	if captured != nil {
+		e.pliesSinceCapture = 0
+	} else {
+		e.pliesSinceCapture++
+	}
+
 	// Switch turn
 	e.currentTurn = e.currentTurn.Opposite()
 
+	key := e.positionKey()
+	e.positionCounts[key]++
+	isRepetitionDraw := e.positionCounts[key] == RepetitionDrawCount
+
 	// Check game state after move
-	e.isCheck = e.rules.IsInCheck(e.board, e.currentTurn)
-	e.isCheckmate = e.rules.IsCheckmate(e.board, e.currentTurn)
-	e.isStalemate = e.rules.IsStalemate(e.board, e.currentTurn)
+	e.isCheck, e.isCheckmate, e.isStalemate = e.rules.GetStatus(e.board, e.currentTurn)
 
 	// Determine winner if game is over
 	var winnerID *string
@@ -250,20 +514,82 @@ func (e *GameEngine) ValidateAndMakeMove(req MoveRequest) MoveResult {
 		IsCheck:       e.isCheck,
 		Timestamp:     time.Now(),
 		PlayerID:      req.PlayerID,
+		Captured:      captured,
 	}
+	e.lastMoveID++
+	moveRecord.id = e.lastMoveID
+	moveRecord.positionKey = key
 	e.moveHistory = append(e.moveHistory, moveRecord)
 
+	// Under Asian rules perpetual check is not a draw: the checking side loses
+	var isPerpetualCheck bool
+	if e.winner == nil && e.perpetualCheckLoses {
+		if offender, ok := e.DetectPerpetualCheck(); ok {
+			winner := offender.Opposite()
+			e.winner = &winner
+			if winner == models.PlayerColorRed {
+				winnerID = &e.redPlayerID
+			} else {
+				winnerID = &e.blackPlayerID
+			}
+			isPerpetualCheck = true
+			isRepetitionDraw = false
+		}
+	}
+
+	// A move that decides the game is not drawn by the no-capture rule
+	if !e.IsGameOver() && e.noCaptureDrawPlies > 0 && e.pliesSinceCapture >= e.noCaptureDrawPlies {
+		e.isDraw = true
+	}
+
 	return MoveResult{
-		Success:       true,
-		Move:          &moveRecord,
-		IsCheck:       e.isCheck,
-		IsCheckmate:   e.isCheckmate,
-		IsStalemate:   e.isStalemate,
-		CapturedPiece: capturedType,
-		WinnerID:      winnerID,
+		Success:          true,
+		Move:             &moveRecord,
+		IsCheck:          e.isCheck,
+		IsCheckmate:      e.isCheckmate,
+		IsStalemate:      e.isStalemate,
+		CapturedPiece:    capturedType,
+		WinnerID:         winnerID,
+		IsRepetitionDraw: isRepetitionDraw,
+		IsPerpetualCheck: isPerpetualCheck,
+		IsDraw:           e.isDraw,
 	}
 }
 
+// DetectPerpetualCheck reports whether the side that just moved is checking
+// perpetually: the current checking position has occurred
+// RepetitionDrawCount times, and every move that side made since its first
+// occurrence gave check. It returns the offending side. Moves rebuilt from
+// storage carry no position keys, so a cycle that reaches back past the moves
+// this engine has played cannot be confirmed and is not reported.
+func (e *GameEngine) DetectPerpetualCheck() (offender *models.PlayerColor, ok bool) {
+	key := e.positionKey()
+	if !e.isCheck || e.positionCounts[key] < RepetitionDrawCount {
+		return nil, false
+	}
+
+	last := len(e.moveHistory) - 1
+	seen := 1
+	for i := last; i >= 0; i-- {
+		move := e.moveHistory[i]
+		if move.id == 0 {
+			return nil, false
+		}
+		if i < last && move.positionKey == key {
+			if seen++; seen == RepetitionDrawCount {
+				break
+			}
+		}
+		// Every other move, counting back from the last, was the offender's
+		if (last-i)%2 == 0 && !move.IsCheck {
+			return nil, false
+		}
+	}
+
+	color := e.currentTurn.Opposite()
+	return &color, true
+}
+
 // GetValidMoves returns all valid moves for a piece at the given position.
 func (e *GameEngine) GetValidMoves(pos string) ([]string, error) {
 	position, err := ParsePosition(pos)
@@ -285,39 +611,178 @@ func (e *GameEngine) GetValidMoves(pos string) ([]string, error) {
 	return result, nil
 }
 
-// UndoLastMove reverts the last move (for rollback functionality).
+// ValidMove is a legal destination for a piece, tagged with what it captures.
+type ValidMove struct {
+	To            string            `json:"to"`
+	IsCapture     bool              `json:"is_capture"`
+	CapturedPiece *models.PieceType `json:"captured_piece,omitempty"`
+}
+
+// GetValidMoveDetails returns the legal destinations for the piece at the
+// given position, marking which of them capture an enemy piece.
+func (e *GameEngine) GetValidMoveDetails(pos string) ([]ValidMove, error) {
+	position, err := ParsePosition(pos)
+	if err != nil {
+		return nil, err
+	}
+
+	piece := e.board.At(position)
+	if piece == nil {
+		return nil, errors.New("no piece at the specified position")
+	}
+
+	legalMoves := e.rules.GetLegalMoves(piece, e.board)
+	result := make([]ValidMove, len(legalMoves))
+	for i, move := range legalMoves {
+		result[i] = ValidMove{To: move.Notation()}
+		if target := e.board.At(move); target != nil {
+			captured := target.Type
+			result[i].IsCapture = true
+			result[i].CapturedPiece = &captured
+		}
+	}
+
+	return result, nil
+}
+
+// UndoLastMove reverts the last move (for rollback functionality). The
+// moved piece goes back to its square and any captured piece is restored,
+// so engines resumed from a stored position can undo as well.
 func (e *GameEngine) UndoLastMove() error {
 	if len(e.moveHistory) == 0 {
 		return errors.New("no moves to undo")
 	}
 
-	// This is a simplified implementation
-	// In a full implementation, we would need to store more state
-	// to properly restore captured pieces and other game state
+	last := e.moveHistory[len(e.moveHistory)-1]
+	if e.board.At(last.To) == nil {
+		return fmt.Errorf("no piece at %s to undo move %d", last.To.Notation(), last.MoveNumber)
+	}
 
-	// For now, we'll rebuild the board from scratch by replaying moves
-	e.board = NewInitialBoard()
-	e.currentTurn = models.PlayerColorRed
+	// The position being left no longer counts towards repetition
+	key := e.positionKey()
+	if e.positionCounts[key]--; e.positionCounts[key] <= 0 {
+		delete(e.positionCounts, key)
+	}
 
-	// Replay all moves except the last one
-	moves := e.moveHistory[:len(e.moveHistory)-1]
-	e.moveHistory = make([]MoveRecord, 0)
+	piece := e.board.Remove(last.To)
+	mover := piece.Color
 
-	for _, move := range moves {
-		e.board.Move(move.From, move.To)
-		e.currentTurn = e.currentTurn.Opposite()
-		e.moveHistory = append(e.moveHistory, move)
+	piece.Position = last.From
+	e.board.Place(piece)
+	if captured := last.capturedPiece(mover); captured != nil {
+		e.board.Place(captured)
 	}
 
-	// Recalculate check status
+	e.moveHistory = e.moveHistory[:len(e.moveHistory)-1]
+	e.pieceMoveCounts[mover][last.PieceType]--
+	e.currentTurn = mover
+
+	if last.CapturedPiece == nil {
+		e.pliesSinceCapture--
+	} else {
+		e.pliesSinceCapture = e.pliesSinceLastRecordedCapture()
+	}
+
+	// The position before the move was not over, or the move could not
+	// have been made
 	e.isCheck = e.rules.IsInCheck(e.board, e.currentTurn)
 	e.isCheckmate = false
 	e.isStalemate = false
+	e.isDraw = false
 	e.winner = nil
 
 	return nil
 }
 
+// Checkpoint marks the current point in the game and returns a token for
+// RestoreCheckpoint, so an analysis board can try a variation and return.
+func (e *GameEngine) Checkpoint() int {
+	cp := checkpoint{length: len(e.moveHistory)}
+	if cp.length > 0 {
+		cp.lastMoveID = e.moveHistory[cp.length-1].id
+	}
+	e.checkpoints = append(e.checkpoints, cp)
+	return len(e.checkpoints) - 1
+}
+
+// RestoreCheckpoint undoes the moves made since the checkpoint, restoring its
+// board, turn and history. A checkpoint can be restored any number of times
+// while the moves up to it remain in the history.
+func (e *GameEngine) RestoreCheckpoint(token int) error {
+	if token < 0 || token >= len(e.checkpoints) {
+		return ErrInvalidCheckpoint
+	}
+	cp := e.checkpoints[token]
+	if cp.length > len(e.moveHistory) ||
+		(cp.length > 0 && e.moveHistory[cp.length-1].id != cp.lastMoveID) {
+		return ErrInvalidCheckpoint
+	}
+
+	for len(e.moveHistory) > cp.length {
+		if err := e.UndoLastMove(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Perft counts the legal move sequences of the given depth from the current
+// position, for checking move generation against known counts. The board
+// is restored after each move, so the engine is left unchanged.
+func (e *GameEngine) Perft(depth int) uint64 {
+	return e.perft(e.currentTurn, depth)
+}
+
+// perft counts the move sequences of the given depth for color to start.
+func (e *GameEngine) perft(color models.PlayerColor, depth int) uint64 {
+	if depth <= 0 {
+		return 1
+	}
+
+	moves := e.rules.GetAllLegalMoves(e.board, color)
+	if depth == 1 {
+		return uint64(len(moves))
+	}
+
+	var nodes uint64
+	for _, move := range moves {
+		captured := e.board.Move(move.From, move.To)
+		nodes += e.perft(color.Opposite(), depth-1)
+		e.board.Move(move.To, move.From)
+		if captured != nil {
+			e.board.Place(captured)
+		}
+	}
+	return nodes
+}
+
+// capturedPiece returns the piece captured by the move, back on the square
+// it was taken on, or nil if the move was not a capture. Records rebuilt
+// from storage only carry the captured type, so the piece is recreated from
+// it with the opponent's color.
+func (m MoveRecord) capturedPiece(mover models.PlayerColor) *Piece {
+	if m.Captured != nil {
+		captured := *m.Captured
+		captured.Position = m.To
+		return &captured
+	}
+	if m.CapturedPiece != nil {
+		return &Piece{Type: *m.CapturedPiece, Color: mover.Opposite(), Position: m.To}
+	}
+	return nil
+}
+
+// pliesSinceLastRecordedCapture counts the moves in the history after the
+// last capture, or all of them if none was a capture.
+func (e *GameEngine) pliesSinceLastRecordedCapture() int {
+	for i := len(e.moveHistory) - 1; i >= 0; i-- {
+		if e.moveHistory[i].CapturedPiece != nil {
+			return len(e.moveHistory) - 1 - i
+		}
+	}
+	return len(e.moveHistory)
+}
+
 // GetGameState returns the current game state for serialization.
 func (e *GameEngine) GetGameState() *GameState {
 	boardState := make([][]PieceState, RankCount)
@@ -334,30 +799,107 @@ func (e *GameEngine) GetGameState() *GameState {
 		}
 	}
 
+	pieceMoveCounts := make(map[string]map[string]int, len(e.pieceMoveCounts))
+	for color, counts := range e.pieceMoveCounts {
+		pieceMoveCounts[string(color)] = make(map[string]int, len(counts))
+		for pieceType, count := range counts {
+			pieceMoveCounts[string(color)][string(pieceType)] = count
+		}
+	}
+
+	var lastMove *LastMoveState
+	if move, ok := e.LastMove(); ok {
+		lastMove = &LastMoveState{
+			From:       move.From.Notation(),
+			To:         move.To.Notation(),
+			PieceType:  string(move.PieceType),
+			MoveNumber: move.MoveNumber,
+		}
+	}
+
+	var forcedMove *ForcedMoveState
+	if !e.IsGameOver() {
+		if move, ok := e.rules.HasSingleLegalMove(e.board, e.currentTurn); ok {
+			forcedMove = &ForcedMoveState{
+				From:      move.From.Notation(),
+				To:        move.To.Notation(),
+				PieceType: string(move.PieceType),
+			}
+		}
+	}
+
 	return &GameState{
-		GameID:        e.gameID,
-		Board:         boardState,
-		CurrentTurn:   string(e.currentTurn),
-		IsCheck:       e.isCheck,
-		IsCheckmate:   e.isCheckmate,
-		IsStalemate:   e.isStalemate,
-		MoveCount:     len(e.moveHistory),
-		RedPlayerID:   e.redPlayerID,
-		BlackPlayerID: e.blackPlayerID,
+		GameID:          e.gameID,
+		Board:           boardState,
+		CurrentTurn:     string(e.currentTurn),
+		IsCheck:         e.isCheck,
+		IsCheckmate:     e.isCheckmate,
+		IsStalemate:     e.isStalemate,
+		MoveCount:       len(e.moveHistory),
+		RedPlayerID:     e.redPlayerID,
+		BlackPlayerID:   e.blackPlayerID,
+		PieceMoveCounts: pieceMoveCounts,
+		LastMove:        lastMove,
+		ForcedMove:      forcedMove,
+	}
+}
+
+// GetThreatenedPieces returns the undefended pieces of the side to move
+// that the opponent now attacks, for a threats overlay.
+func (e *GameEngine) GetThreatenedPieces() []ThreatState {
+	threats := e.rules.GetThreats(e.board, e.currentTurn)
+	states := make([]ThreatState, 0, len(threats))
+	for _, threat := range threats {
+		attackers := make([]string, 0, len(threat.Attackers))
+		for _, attacker := range threat.Attackers {
+			attackers = append(attackers, attacker.Position.Notation())
+		}
+		states = append(states, ThreatState{
+			Position:  threat.Target.Position.Notation(),
+			PieceType: string(threat.Target.Type),
+			Attackers: attackers,
+		})
 	}
+	return states
+}
+
+// ThreatState describes a threatened piece for serialization.
+type ThreatState struct {
+	Position  string   `json:"position"`
+	PieceType string   `json:"piece_type"`
+	Attackers []string `json:"attackers"`
 }
 
 // GameState represents the serializable state of a game.
 type GameState struct {
-	GameID        string          `json:"game_id"`
-	Board         [][]PieceState  `json:"board"`
-	CurrentTurn   string          `json:"current_turn"`
-	IsCheck       bool            `json:"is_check"`
-	IsCheckmate   bool            `json:"is_checkmate"`
-	IsStalemate   bool            `json:"is_stalemate"`
-	MoveCount     int             `json:"move_count"`
-	RedPlayerID   string          `json:"red_player_id"`
-	BlackPlayerID string          `json:"black_player_id"`
+	GameID          string                    `json:"game_id"`
+	Board           [][]PieceState            `json:"board"`
+	CurrentTurn     string                    `json:"current_turn"`
+	IsCheck         bool                      `json:"is_check"`
+	IsCheckmate     bool                      `json:"is_checkmate"`
+	IsStalemate     bool                      `json:"is_stalemate"`
+	MoveCount       int                       `json:"move_count"`
+	RedPlayerID     string                    `json:"red_player_id"`
+	BlackPlayerID   string                    `json:"black_player_id"`
+	PieceMoveCounts map[string]map[string]int `json:"piece_move_counts"`
+	LastMove        *LastMoveState            `json:"last_move,omitempty"`
+	ForcedMove      *ForcedMoveState          `json:"forced_move,omitempty"`
+}
+
+// LastMoveState describes the latest move so clients can highlight it.
+type LastMoveState struct {
+	From       string `json:"from"`
+	To         string `json:"to"`
+	PieceType  string `json:"piece_type"`
+	MoveNumber int    `json:"move_number"`
+}
+
+// ForcedMoveState describes the only legal move of the side to move, so
+// clients can hint or auto-play it.
+type ForcedMoveState struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	PieceType string `json:"piece_type"`
 }
 
 // PieceState represents a piece for serialization.
@@ -366,26 +908,68 @@ type PieceState struct {
 	Color string `json:"color,omitempty"`
 }
 
+// BoardFromState rebuilds a board from its serialized form, as produced by
+// GetGameState. Empty squares have an empty PieceState. Boards with impossible
+// piece counts are rejected.
+func BoardFromState(state [][]PieceState) (*Board, error) {
+	if len(state) != RankCount {
+		return nil, fmt.Errorf("expected %d ranks, got %d", RankCount, len(state))
+	}
+
+	board := NewBoard()
+	for rank, row := range state {
+		if len(row) != FileCount {
+			return nil, fmt.Errorf("expected %d files in rank %d, got %d", FileCount, rank, len(row))
+		}
+		for file, square := range row {
+			if square.Type == "" {
+				continue
+			}
+			color := models.PlayerColor(square.Color)
+			if color != models.PlayerColorRed && color != models.PlayerColorBlack {
+				return nil, fmt.Errorf("invalid piece color %q at %s", square.Color, Position{file, rank}.Notation())
+			}
+			board.Place(&Piece{
+				Type:     models.PieceType(square.Type),
+				Color:    color,
+				Position: Position{File: file, Rank: rank},
+			})
+		}
+	}
+
+	if err := board.Validate(); err != nil {
+		return nil, err
+	}
+
+	return board, nil
+}
+
 // ParsePosition parses a position notation string (e.g., "e4") into a Position.
+// Surrounding whitespace is ignored and the file letter is case-insensitive,
+// so " E4 " parses the same as "e4".
 func ParsePosition(notation string) (Position, error) {
+	notation = strings.TrimSpace(notation)
 	if len(notation) < 2 {
 		return Position{}, errors.New("notation too short")
 	}
+	if len(notation) > 2 {
+		return Position{}, errors.New("notation too long")
+	}
 
 	fileChar := notation[0]
+	if fileChar >= 'A' && fileChar <= 'Z' {
+		fileChar += 'a' - 'A'
+	}
 	if fileChar < 'a' || fileChar > 'i' {
 		return Position{}, errors.New("invalid file character")
 	}
 	file := int(fileChar - 'a')
 
-	rankStr := notation[1:]
-	rank := 0
-	for _, c := range rankStr {
-		if c < '0' || c > '9' {
-			return Position{}, errors.New("invalid rank character")
-		}
-		rank = rank*10 + int(c-'0')
+	rankChar := notation[1]
+	if rankChar < '0' || rankChar > '9' {
+		return Position{}, errors.New("invalid rank character")
 	}
+	rank := int(rankChar - '0')
 
 	if rank < 0 || rank >= RankCount {
 		return Position{}, errors.New("rank out of bounds")
@@ -394,6 +978,16 @@ func ParsePosition(notation string) (Position, error) {
 	return Position{File: file, Rank: rank}, nil
 }
 
+// NormalizePosition validates a position notation string and returns it in
+// canonical form (lowercase file, no surrounding whitespace).
+func NormalizePosition(notation string) (string, error) {
+	pos, err := ParsePosition(notation)
+	if err != nil {
+		return "", err
+	}
+	return pos.Notation(), nil
+}
+
 // SetResignation marks a player as having resigned.
 func (e *GameEngine) SetResignation(resigningPlayerID string) {
 	if resigningPlayerID == e.redPlayerID {
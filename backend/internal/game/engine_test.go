@@ -2,6 +2,7 @@
 package game
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/xiangqi/chinese-chess-backend/internal/models"
@@ -161,6 +162,41 @@ func TestEngine_ValidateAndMakeMove_InvalidMove(t *testing.T) {
 	}
 }
 
+func TestEngine_ValidateAndMakeMove_SameSquare(t *testing.T) {
+	// One origin per piece type in the red starting position
+	origins := map[string]string{
+		"chariot":  "a0",
+		"horse":    "b0",
+		"elephant": "c0",
+		"advisor":  "d0",
+		"general":  "e0",
+		"cannon":   "b2",
+		"soldier":  "a3",
+	}
+
+	for name, square := range origins {
+		t.Run(name, func(t *testing.T) {
+			engine := NewGameEngine("game-001", "red-player", "black-player")
+
+			result := engine.ValidateAndMakeMove(MoveRequest{
+				PlayerID: "red-player",
+				From:     square,
+				To:       square,
+			})
+
+			if result.Success {
+				t.Fatal("Should reject a move onto the origin square")
+			}
+			if result.ErrorMessage != ErrSameSquare.Error() {
+				t.Errorf("Expected %q error, got: %s", ErrSameSquare.Error(), result.ErrorMessage)
+			}
+			if engine.GetCurrentTurn() != models.PlayerColorRed {
+				t.Error("Turn should not change after a rejected move")
+			}
+		})
+	}
+}
+
 func TestEngine_ValidateAndMakeMove_Capture(t *testing.T) {
 	engine := NewGameEngine("game-001", "red-player", "black-player")
 
@@ -168,24 +204,24 @@ func TestEngine_ValidateAndMakeMove_Capture(t *testing.T) {
 	// Move red chariot to capture black soldier
 	// First, let's make some moves to enable a capture
 
-	// Red moves horse
+	// Red advances the edge soldier
 	engine.ValidateAndMakeMove(MoveRequest{
 		PlayerID: "red-player",
-		From:     "b0",
-		To:       "c2",
+		From:     "a3",
+		To:       "a4",
 	})
 
-	// Black moves soldier
+	// Black advances the opposing soldier
 	engine.ValidateAndMakeMove(MoveRequest{
 		PlayerID: "black-player",
 		From:     "a6",
 		To:       "a5",
 	})
 
-	// Red chariot can now potentially capture
+	// Red soldier can now capture straight ahead
 	result := engine.ValidateAndMakeMove(MoveRequest{
 		PlayerID: "red-player",
-		From:     "a0",
+		From:     "a4",
 		To:       "a5", // Capture black soldier
 	})
 
@@ -224,6 +260,123 @@ func TestEngine_ValidateAndMakeMove_GameOver(t *testing.T) {
 	}
 }
 
+// ========== General Exposure Tests ==========
+
+// newFaceOffEngine creates an engine with both generals on the e-file and the
+// given pieces placed on the board. Removing the only piece between the
+// generals would leave them facing each other.
+func newFaceOffEngine(t *testing.T, turn models.PlayerColor, pieces ...*Piece) *GameEngine {
+	t.Helper()
+	board := NewBoard()
+	board.Place(createPiece(models.PieceTypeGeneral, models.PlayerColorRed, 4, 0))
+	board.Place(createPiece(models.PieceTypeGeneral, models.PlayerColorBlack, 4, 9))
+	for _, piece := range pieces {
+		board.Place(piece)
+	}
+	return newEngineFromState(t, board, turn, nil)
+}
+
+// newEngineFromState creates an engine for the test players from the given
+// board, failing the test if the board is rejected.
+func newEngineFromState(t *testing.T, board *Board, turn models.PlayerColor, moves []MoveRecord) *GameEngine {
+	t.Helper()
+	engine, err := NewGameEngineFromState("game-001", "red-player", "black-player", board, turn, moves)
+	if err != nil {
+		t.Fatalf("NewGameEngineFromState failed: %v", err)
+	}
+	return engine
+}
+
+// assertExposureRejected checks that a move exposing the generals is rejected
+// without changing the board or awarding a win to either side.
+func assertExposureRejected(t *testing.T, engine *GameEngine, req MoveRequest) {
+	t.Helper()
+	before := engine.GetBoard().String()
+	turn := engine.GetCurrentTurn()
+
+	result := engine.ValidateAndMakeMove(req)
+
+	if result.Success {
+		t.Fatalf("Move %s-%s exposing the generals should be rejected", req.From, req.To)
+	}
+	if result.WinnerID != nil {
+		t.Errorf("Rejected move should not award a win, got winner %s", *result.WinnerID)
+	}
+	if result.IsCheckmate || result.IsStalemate {
+		t.Error("Rejected move should not end the game")
+	}
+	if engine.IsGameOver() {
+		t.Error("Game should not be over after a rejected move")
+	}
+	if engine.GetWinner() != nil {
+		t.Errorf("Engine should have no winner, got %s", *engine.GetWinner())
+	}
+	if engine.GetCurrentTurn() != turn {
+		t.Errorf("Turn should stay with %s, got %s", turn, engine.GetCurrentTurn())
+	}
+	if len(engine.GetMoveHistory()) != 0 {
+		t.Errorf("Rejected move should not be recorded, have %d moves", len(engine.GetMoveHistory()))
+	}
+	if after := engine.GetBoard().String(); after != before {
+		t.Errorf("Board changed after rejected move:\nbefore:\n%s\nafter:\n%s", before, after)
+	}
+}
+
+func TestEngine_GeneralExposure_RedBlockerMovesAway(t *testing.T) {
+	engine := newFaceOffEngine(t, models.PlayerColorRed,
+		createPiece(models.PieceTypeChariot, models.PlayerColorRed, 4, 4),
+	)
+
+	assertExposureRejected(t, engine, MoveRequest{PlayerID: "red-player", From: "e4", To: "a4"})
+}
+
+func TestEngine_GeneralExposure_BlackBlockerMovesAway(t *testing.T) {
+	engine := newFaceOffEngine(t, models.PlayerColorBlack,
+		createPiece(models.PieceTypeHorse, models.PlayerColorBlack, 4, 7),
+	)
+
+	assertExposureRejected(t, engine, MoveRequest{PlayerID: "black-player", From: "e7", To: "d5"})
+}
+
+func TestEngine_GeneralExposure_CaptureOffFile(t *testing.T) {
+	// Capturing the black chariot would take material but leave the generals
+	// facing, so the capture must be rejected and the chariot kept.
+	engine := newFaceOffEngine(t, models.PlayerColorRed,
+		createPiece(models.PieceTypeChariot, models.PlayerColorRed, 4, 5),
+		createPiece(models.PieceTypeChariot, models.PlayerColorBlack, 0, 5),
+	)
+
+	assertExposureRejected(t, engine, MoveRequest{PlayerID: "red-player", From: "e5", To: "a5"})
+
+	if piece := engine.GetBoard().At(Position{0, 5}); piece == nil || piece.Color != models.PlayerColorBlack {
+		t.Error("Black chariot should still be on a5")
+	}
+}
+
+func TestEngine_GeneralExposure_GeneralStepsOntoOpenFile(t *testing.T) {
+	board := NewBoard()
+	board.Place(createPiece(models.PieceTypeGeneral, models.PlayerColorRed, 3, 0))
+	board.Place(createPiece(models.PieceTypeGeneral, models.PlayerColorBlack, 4, 9))
+	engine := newEngineFromState(t, board, models.PlayerColorRed, nil)
+
+	assertExposureRejected(t, engine, MoveRequest{PlayerID: "red-player", From: "d0", To: "e0"})
+}
+
+func TestEngine_GeneralExposure_BlockerMovesAlongFile(t *testing.T) {
+	engine := newFaceOffEngine(t, models.PlayerColorRed,
+		createPiece(models.PieceTypeChariot, models.PlayerColorRed, 4, 4),
+	)
+
+	// Staying on the file keeps the generals blocked
+	result := engine.ValidateAndMakeMove(MoveRequest{PlayerID: "red-player", From: "e4", To: "e6"})
+	if !result.Success {
+		t.Fatalf("Move along the file should be allowed: %s", result.ErrorMessage)
+	}
+	if result.WinnerID != nil || engine.IsGameOver() {
+		t.Error("Blocking move should not end the game")
+	}
+}
+
 // ========== GetValidMoves Tests ==========
 
 func TestEngine_GetValidMoves_ValidPiece(t *testing.T) {
@@ -274,6 +427,49 @@ func TestEngine_GetValidMoves_EmptySquare(t *testing.T) {
 	}
 }
 
+func TestEngine_GetValidMoveDetails_TagsCaptures(t *testing.T) {
+	engine := newFaceOffEngine(t, models.PlayerColorRed,
+		createPiece(models.PieceTypeAdvisor, models.PlayerColorRed, 4, 1),
+		createPiece(models.PieceTypeChariot, models.PlayerColorRed, 0, 0),
+		createPiece(models.PieceTypeHorse, models.PlayerColorBlack, 0, 5),
+	)
+
+	moves, err := engine.GetValidMoveDetails("a0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	simple, _ := engine.GetValidMoves("a0")
+	if len(moves) != len(simple) {
+		t.Fatalf("Expected %d destinations, got %d", len(simple), len(moves))
+	}
+
+	captures := 0
+	for _, m := range moves {
+		if m.To == "a5" {
+			if !m.IsCapture || m.CapturedPiece == nil || *m.CapturedPiece != models.PieceTypeHorse {
+				t.Errorf("Expected a5 to be tagged as capturing a horse, got %+v", m)
+			}
+		} else if m.IsCapture || m.CapturedPiece != nil {
+			t.Errorf("Expected %s to be a quiet move, got %+v", m.To, m)
+		}
+		if m.IsCapture {
+			captures++
+		}
+	}
+	if captures != 1 {
+		t.Errorf("Expected exactly one capture, got %d", captures)
+	}
+}
+
+func TestEngine_GetValidMoveDetails_EmptySquare(t *testing.T) {
+	engine := NewGameEngine("game-001", "red-player", "black-player")
+
+	if _, err := engine.GetValidMoveDetails("d4"); err == nil {
+		t.Error("Expected error for empty square")
+	}
+}
+
 // ========== UndoLastMove Tests ==========
 
 func TestEngine_UndoLastMove_Success(t *testing.T) {
@@ -361,6 +557,414 @@ func TestEngine_UndoLastMove_MultipleUndos(t *testing.T) {
 	}
 }
 
+func TestEngine_UndoLastMove_RestoresCapturedPiece(t *testing.T) {
+	engine := NewGameEngine("game-001", "red-player", "black-player")
+	before := engine.GetFEN()
+
+	// The red cannon jumps the black cannon to take the horse on h9
+	result := engine.ValidateAndMakeMove(MoveRequest{PlayerID: "red-player", From: "h2", To: "h9"})
+	if !result.Success || result.CapturedPiece == nil {
+		t.Fatalf("Expected a capture, got %+v", result)
+	}
+
+	if err := engine.UndoLastMove(); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+
+	horse := engine.GetBoard().At(Position{7, 9})
+	if horse == nil || horse.Type != models.PieceTypeHorse || horse.Color != models.PlayerColorBlack {
+		t.Fatalf("Expected the black horse back on h9, got %+v", horse)
+	}
+	if horse.Position != (Position{7, 9}) {
+		t.Errorf("Restored horse reports position %s", horse.Position.Notation())
+	}
+	cannon := engine.GetBoard().At(Position{7, 2})
+	if cannon == nil || cannon.Type != models.PieceTypeCannon || cannon.Color != models.PlayerColorRed {
+		t.Errorf("Expected the red cannon back on h2, got %+v", cannon)
+	}
+	if engine.GetFEN() != before {
+		t.Errorf("Expected position %s after undo, got %s", before, engine.GetFEN())
+	}
+	if got := engine.GetPieceMoveCounts(models.PlayerColorRed)[models.PieceTypeCannon]; got != 0 {
+		t.Errorf("Expected red cannon count 0 after undo, got %d", got)
+	}
+
+	// The horse can be captured again
+	if result := engine.ValidateAndMakeMove(MoveRequest{PlayerID: "red-player", From: "h2", To: "h9"}); !result.Success {
+		t.Errorf("Expected the capture to be playable again: %s", result.ErrorMessage)
+	}
+}
+
+func TestEngine_UndoLastMove_RecordWithoutCapturedPiece(t *testing.T) {
+	// History rebuilt from storage only knows the captured type
+	board := NewInitialBoard()
+	board.Move(Position{7, 2}, Position{7, 9})
+	captured := models.PieceTypeHorse
+	moves := []MoveRecord{{
+		MoveNumber:    1,
+		From:          Position{7, 2},
+		To:            Position{7, 9},
+		PieceType:     models.PieceTypeCannon,
+		CapturedPiece: &captured,
+		PlayerID:      "red-player",
+	}}
+	engine := newEngineFromState(t, board, models.PlayerColorBlack, moves)
+
+	if err := engine.UndoLastMove(); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if engine.GetBoard().ToFEN() != NewInitialBoard().ToFEN() {
+		t.Errorf("Expected the initial position after undo, got %s", engine.GetBoard().ToFEN())
+	}
+}
+
+func TestEngine_UndoLastMove_ResumedFromFEN(t *testing.T) {
+	fen := "4k4/9/9/9/9/9/9/9/9/3K1R3 b"
+	engine, err := NewGameEngineFromFEN("game-001", "red-player", "black-player", fen)
+	if err != nil {
+		t.Fatalf("NewGameEngineFromFEN failed: %v", err)
+	}
+	if result := engine.ValidateAndMakeMove(MoveRequest{PlayerID: "black-player", From: "e9", To: "e8"}); !result.Success {
+		t.Fatalf("Move failed: %s", result.ErrorMessage)
+	}
+
+	// Undo reverses the move instead of replaying from the initial position
+	if err := engine.UndoLastMove(); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if engine.GetFEN() != fen {
+		t.Errorf("Expected %s after undo, got %s", fen, engine.GetFEN())
+	}
+}
+
+// horseShuffle moves both left horses out and back, returning to the
+// starting position.
+var horseShuffle = []MoveRequest{
+	{PlayerID: "red-player", From: "b0", To: "c2"},
+	{PlayerID: "black-player", From: "b9", To: "c7"},
+	{PlayerID: "red-player", From: "c2", To: "b0"},
+	{PlayerID: "black-player", From: "c7", To: "b9"},
+}
+
+func TestEngine_ThreefoldRepetition(t *testing.T) {
+	engine := NewGameEngine("game-001", "red-player", "black-player")
+	if got := engine.RepetitionCount(); got != 1 {
+		t.Fatalf("Expected the initial position once, got %d", got)
+	}
+
+	// The initial position recurs after each shuffle and is drawn on its
+	// third occurrence, at ply 8
+	moves := append(append([]MoveRequest{}, horseShuffle...), horseShuffle...)
+	for i, req := range moves {
+		result := engine.ValidateAndMakeMove(req)
+		if !result.Success {
+			t.Fatalf("Move %d failed: %s", i+1, result.ErrorMessage)
+		}
+		if want := i == len(moves)-1; result.IsRepetitionDraw != want {
+			t.Errorf("Move %d: expected IsRepetitionDraw %v", i+1, want)
+		}
+	}
+	if got := engine.RepetitionCount(); got != 3 {
+		t.Errorf("Expected the position to have occurred 3 times, got %d", got)
+	}
+
+	// Undo takes the last occurrence back
+	if err := engine.UndoLastMove(); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if got := engine.RepetitionCount(); got != 2 {
+		t.Errorf("Expected 2 occurrences of the position after ply 7, got %d", got)
+	}
+	result := engine.ValidateAndMakeMove(horseShuffle[3])
+	if !result.IsRepetitionDraw {
+		t.Error("Expected replaying the move to repeat the position a third time again")
+	}
+}
+
+func TestEngine_Repetition_SideToMoveMatters(t *testing.T) {
+	engine := NewGameEngine("game-001", "red-player", "black-player")
+
+	// Red's chariot goes round a triangle while black's horse goes out and
+	// back, so every piece is on its starting square but black is to move
+	for _, req := range []MoveRequest{
+		{PlayerID: "red-player", From: "a0", To: "a1"},
+		{PlayerID: "black-player", From: "b9", To: "c7"},
+		{PlayerID: "red-player", From: "a1", To: "a2"},
+		{PlayerID: "black-player", From: "c7", To: "b9"},
+		{PlayerID: "red-player", From: "a2", To: "a0"},
+	} {
+		if result := engine.ValidateAndMakeMove(req); !result.Success {
+			t.Fatalf("Move %s-%s failed: %s", req.From, req.To, result.ErrorMessage)
+		}
+	}
+
+	if engine.GetBoard().ToFEN() != NewInitialBoard().ToFEN() {
+		t.Fatalf("Expected the starting placement, got %s", engine.GetBoard().ToFEN())
+	}
+	if got := engine.RepetitionCount(); got != 1 {
+		t.Errorf("Expected a new position with black to move, got %d occurrences", got)
+	}
+}
+
+// ========== Perpetual Check Tests ==========
+
+// perpetualCheckFEN has red's chariot ready to chase black's general up and
+// down the palace with checks along the ranks.
+const perpetualCheckFEN = "9/4k4/R8/9/9/9/9/9/9/3K5 w"
+
+func TestEngine_PerpetualCheck_CheckingSideLoses(t *testing.T) {
+	engine, err := NewGameEngineFromFEN("game-001", "red-player", "black-player", perpetualCheckFEN,
+		WithPerpetualCheckRule(true))
+	if err != nil {
+		t.Fatalf("NewGameEngineFromFEN failed: %v", err)
+	}
+
+	// Every red move checks; the checking position a8 recurs on plies 1, 5
+	// and 9
+	cycle := []MoveRequest{
+		{PlayerID: "red-player", From: "a7", To: "a8"},
+		{PlayerID: "black-player", From: "e8", To: "e9"},
+		{PlayerID: "red-player", From: "a8", To: "a9"},
+		{PlayerID: "black-player", From: "e9", To: "e8"},
+		{PlayerID: "red-player", From: "a9", To: "a8"},
+		{PlayerID: "black-player", From: "e8", To: "e9"},
+		{PlayerID: "red-player", From: "a8", To: "a9"},
+		{PlayerID: "black-player", From: "e9", To: "e8"},
+		{PlayerID: "red-player", From: "a9", To: "a8"},
+	}
+	var result MoveResult
+	for i, req := range cycle {
+		result = engine.ValidateAndMakeMove(req)
+		if !result.Success {
+			t.Fatalf("Move %d failed: %s", i+1, result.ErrorMessage)
+		}
+		if i < len(cycle)-1 && result.IsPerpetualCheck {
+			t.Fatalf("Move %d: perpetual check flagged too early", i+1)
+		}
+	}
+
+	if !result.IsPerpetualCheck || result.IsRepetitionDraw {
+		t.Fatalf("Expected a perpetual check rather than a draw, got %+v", result)
+	}
+	if result.WinnerID == nil || *result.WinnerID != "black-player" {
+		t.Errorf("Expected black to win, got %v", result.WinnerID)
+	}
+	offender, ok := engine.DetectPerpetualCheck()
+	if !ok || *offender != models.PlayerColorRed {
+		t.Errorf("Expected red to be the offender, got %v", offender)
+	}
+	if !engine.IsGameOver() {
+		t.Error("Expected the game to be over")
+	}
+}
+
+func TestEngine_PerpetualCheck_RuleDisabledByDefault(t *testing.T) {
+	engine, err := NewGameEngineFromFEN("game-001", "red-player", "black-player", perpetualCheckFEN)
+	if err != nil {
+		t.Fatalf("NewGameEngineFromFEN failed: %v", err)
+	}
+
+	cycle := []MoveRequest{
+		{PlayerID: "red-player", From: "a7", To: "a8"},
+		{PlayerID: "black-player", From: "e8", To: "e9"},
+		{PlayerID: "red-player", From: "a8", To: "a9"},
+		{PlayerID: "black-player", From: "e9", To: "e8"},
+		{PlayerID: "red-player", From: "a9", To: "a8"},
+		{PlayerID: "black-player", From: "e8", To: "e9"},
+		{PlayerID: "red-player", From: "a8", To: "a9"},
+		{PlayerID: "black-player", From: "e9", To: "e8"},
+		{PlayerID: "red-player", From: "a9", To: "a8"},
+	}
+	for i, req := range cycle {
+		if result := engine.ValidateAndMakeMove(req); !result.Success || result.IsPerpetualCheck {
+			t.Fatalf("Move %d: expected the game to go on, got %+v", i+1, result)
+		}
+	}
+
+	// The perpetual check is still reported, but does not end the game
+	if _, ok := engine.DetectPerpetualCheck(); !ok {
+		t.Error("Expected the perpetual check to be detected")
+	}
+	if engine.IsGameOver() {
+		t.Error("Perpetual check should not end the game with the rule disabled")
+	}
+}
+
+func TestEngine_PerpetualCheck_RequiresEveryMoveToCheck(t *testing.T) {
+	engine, err := NewGameEngineFromFEN("game-001", "red-player", "black-player", perpetualCheckFEN,
+		WithPerpetualCheckRule(true))
+	if err != nil {
+		t.Fatalf("NewGameEngineFromFEN failed: %v", err)
+	}
+
+	// Red checks on a8 but retreats to a7 without check in between, so the
+	// checking position recurs on plies 1, 5 and 9 all the same
+	check := MoveRequest{PlayerID: "red-player", From: "a7", To: "a8"}
+	cycle := []MoveRequest{
+		check,
+		{PlayerID: "black-player", From: "e8", To: "e9"},
+		{PlayerID: "red-player", From: "a8", To: "a7"},
+		{PlayerID: "black-player", From: "e9", To: "e8"},
+	}
+	moves := append(append(append([]MoveRequest{}, cycle...), cycle...), check)
+	for i, req := range moves {
+		result := engine.ValidateAndMakeMove(req)
+		if !result.Success {
+			t.Fatalf("Move %d failed: %s", i+1, result.ErrorMessage)
+		}
+		if result.IsPerpetualCheck {
+			t.Fatalf("Move %d: intermittent checks are not perpetual", i+1)
+		}
+	}
+
+	if !engine.IsCheck() || engine.RepetitionCount() != RepetitionDrawCount {
+		t.Fatalf("Expected the checking position to have occurred 3 times, got %d", engine.RepetitionCount())
+	}
+	if _, ok := engine.DetectPerpetualCheck(); ok {
+		t.Error("Expected no perpetual check")
+	}
+	if engine.IsGameOver() {
+		t.Error("Expected the game to continue")
+	}
+}
+
+func TestEngine_PerpetualCheck_NotConfirmedPastRebuiltMoves(t *testing.T) {
+	played, err := NewGameEngineFromFEN("game-001", "red-player", "black-player", perpetualCheckFEN,
+		WithPerpetualCheckRule(true))
+	if err != nil {
+		t.Fatalf("NewGameEngineFromFEN failed: %v", err)
+	}
+
+	// The checking position occurs twice, with a quiet retreat in between
+	check := MoveRequest{PlayerID: "red-player", From: "a7", To: "a8"}
+	cycle := []MoveRequest{
+		check,
+		{PlayerID: "black-player", From: "e8", To: "e9"},
+		{PlayerID: "red-player", From: "a8", To: "a7"},
+		{PlayerID: "black-player", From: "e9", To: "e8"},
+	}
+	for i, req := range append(append([]MoveRequest{}, cycle...), cycle...) {
+		if result := played.ValidateAndMakeMove(req); !result.Success {
+			t.Fatalf("Move %d failed: %s", i+1, result.ErrorMessage)
+		}
+	}
+
+	// Rebuild from a snapshot: the moves come back from storage without
+	// position keys, and the repetition counts are restored
+	history := played.GetMoveHistory()
+	for i := range history {
+		history[i].id, history[i].positionKey = 0, 0
+	}
+	engine, err := NewGameEngineFromState("game-001", "red-player", "black-player",
+		played.GetBoard().Copy(), played.GetCurrentTurn(), history, WithPerpetualCheckRule(true))
+	if err != nil {
+		t.Fatalf("NewGameEngineFromState failed: %v", err)
+	}
+	engine.SetPositionCounts(played.PositionCounts())
+
+	result := engine.ValidateAndMakeMove(check)
+	if !result.Success {
+		t.Fatalf("Check failed: %s", result.ErrorMessage)
+	}
+	if engine.RepetitionCount() != RepetitionDrawCount {
+		t.Fatalf("Expected the checking position to have occurred 3 times, got %d", engine.RepetitionCount())
+	}
+	if result.IsPerpetualCheck {
+		t.Error("A single check after the snapshot should not be ruled perpetual")
+	}
+	if _, ok := engine.DetectPerpetualCheck(); ok {
+		t.Error("Expected no perpetual check")
+	}
+}
+
+// ========== Checkpoint Tests ==========
+
+func TestEngine_RestoreCheckpoint(t *testing.T) {
+	engine := NewGameEngine("game-001", "red-player", "black-player")
+	engine.ValidateAndMakeMove(MoveRequest{PlayerID: "red-player", From: "b0", To: "c2"})
+
+	token := engine.Checkpoint()
+	fen := engine.GetFEN()
+	history := engine.GetMoveHistory()
+
+	// Try a variation with a capture
+	for _, req := range []MoveRequest{
+		{PlayerID: "black-player", From: "h7", To: "h0"},
+		{PlayerID: "red-player", From: "i0", To: "h0"},
+	} {
+		if result := engine.ValidateAndMakeMove(req); !result.Success {
+			t.Fatalf("Move %s-%s failed: %s", req.From, req.To, result.ErrorMessage)
+		}
+	}
+
+	if err := engine.RestoreCheckpoint(token); err != nil {
+		t.Fatalf("RestoreCheckpoint failed: %v", err)
+	}
+	if engine.GetFEN() != fen {
+		t.Errorf("Expected %s after restoring, got %s", fen, engine.GetFEN())
+	}
+	if engine.GetCurrentTurn() != models.PlayerColorBlack {
+		t.Error("Should be black's turn after restoring")
+	}
+	if got := engine.GetMoveHistory(); len(got) != len(history) || got[0].From != history[0].From || got[0].To != history[0].To {
+		t.Errorf("Expected history %+v after restoring, got %+v", history, got)
+	}
+
+	// A second variation can be tried from the same checkpoint
+	engine.ValidateAndMakeMove(MoveRequest{PlayerID: "black-player", From: "b9", To: "c7"})
+	if err := engine.RestoreCheckpoint(token); err != nil {
+		t.Fatalf("Restoring the checkpoint again failed: %v", err)
+	}
+	if engine.GetFEN() != fen {
+		t.Errorf("Expected %s after restoring again, got %s", fen, engine.GetFEN())
+	}
+}
+
+func TestEngine_RestoreCheckpoint_Nested(t *testing.T) {
+	engine := NewGameEngine("game-001", "red-player", "black-player")
+	start := engine.Checkpoint()
+	initial := engine.GetFEN()
+
+	engine.ValidateAndMakeMove(MoveRequest{PlayerID: "red-player", From: "b0", To: "c2"})
+	inner := engine.Checkpoint()
+	afterFirst := engine.GetFEN()
+	engine.ValidateAndMakeMove(MoveRequest{PlayerID: "black-player", From: "b9", To: "c7"})
+
+	if err := engine.RestoreCheckpoint(inner); err != nil || engine.GetFEN() != afterFirst {
+		t.Fatalf("Expected %s after restoring the inner checkpoint, got %s (err %v)", afterFirst, engine.GetFEN(), err)
+	}
+	if err := engine.RestoreCheckpoint(start); err != nil || engine.GetFEN() != initial {
+		t.Fatalf("Expected %s after restoring the outer checkpoint, got %s (err %v)", initial, engine.GetFEN(), err)
+	}
+}
+
+func TestEngine_RestoreCheckpoint_Invalid(t *testing.T) {
+	engine := NewGameEngine("game-001", "red-player", "black-player")
+
+	for _, token := range []int{-1, 0, 5} {
+		if err := engine.RestoreCheckpoint(token); !errors.Is(err, ErrInvalidCheckpoint) {
+			t.Errorf("RestoreCheckpoint(%d): expected ErrInvalidCheckpoint, got %v", token, err)
+		}
+	}
+
+	engine.ValidateAndMakeMove(MoveRequest{PlayerID: "red-player", From: "b0", To: "c2"})
+	token := engine.Checkpoint()
+
+	// The checkpoint's move is undone and a different one played
+	if err := engine.UndoLastMove(); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	engine.ValidateAndMakeMove(MoveRequest{PlayerID: "red-player", From: "h0", To: "g2"})
+	fen := engine.GetFEN()
+
+	if err := engine.RestoreCheckpoint(token); !errors.Is(err, ErrInvalidCheckpoint) {
+		t.Errorf("Expected ErrInvalidCheckpoint for a replaced branch, got %v", err)
+	}
+	if engine.GetFEN() != fen {
+		t.Error("A rejected restore should leave the position unchanged")
+	}
+}
+
 // ========== GetGameState Tests ==========
 
 func TestEngine_GetGameState(t *testing.T) {
@@ -537,6 +1141,77 @@ func TestParsePosition_Invalid(t *testing.T) {
 	}
 }
 
+func TestParsePosition_Uppercase(t *testing.T) {
+	testCases := []struct {
+		notation string
+		expected Position
+	}{
+		{"E4", Position{4, 4}},
+		{"A0", Position{0, 0}},
+		{"I9", Position{8, 9}},
+	}
+
+	for _, tc := range testCases {
+		pos, err := ParsePosition(tc.notation)
+		if err != nil {
+			t.Errorf("ParsePosition(%s) returned error: %v", tc.notation, err)
+			continue
+		}
+		if pos != tc.expected {
+			t.Errorf("ParsePosition(%s) = %v, expected %v", tc.notation, pos, tc.expected)
+		}
+	}
+}
+
+func TestParsePosition_Padded(t *testing.T) {
+	testCases := []string{" e4", "e4 ", "  e4\t", "\nE4\n"}
+
+	for _, notation := range testCases {
+		pos, err := ParsePosition(notation)
+		if err != nil {
+			t.Errorf("ParsePosition(%q) returned error: %v", notation, err)
+			continue
+		}
+		if pos != (Position{4, 4}) {
+			t.Errorf("ParsePosition(%q) = %v, expected e4", notation, pos)
+		}
+	}
+}
+
+func TestParsePosition_InvalidAfterNormalization(t *testing.T) {
+	invalidNotations := []string{
+		"   ",
+		"J0",  // Invalid file, uppercase
+		"Z5",  // Invalid file, uppercase
+		"e 4", // Inner whitespace
+		"e04", // Extra digit
+		"e4x", // Trailing garbage
+		"E-1", // Negative rank
+		"é4",  // Non-ASCII file
+		"e４",  // Full-width digit
+	}
+
+	for _, notation := range invalidNotations {
+		if _, err := ParsePosition(notation); err == nil {
+			t.Errorf("ParsePosition(%q) should return error", notation)
+		}
+	}
+}
+
+func TestNormalizePosition(t *testing.T) {
+	normalized, err := NormalizePosition(" E4 ")
+	if err != nil {
+		t.Fatalf("NormalizePosition returned error: %v", err)
+	}
+	if normalized != "e4" {
+		t.Errorf("Expected 'e4', got '%s'", normalized)
+	}
+
+	if _, err := NormalizePosition("k4"); err == nil {
+		t.Error("NormalizePosition should reject invalid notation")
+	}
+}
+
 // ========== Check Detection During Game Tests ==========
 
 func TestEngine_CheckDetection(t *testing.T) {
@@ -609,6 +1284,102 @@ func TestEngine_MoveHistory(t *testing.T) {
 	}
 }
 
+func TestEngine_LastMove(t *testing.T) {
+	engine := NewGameEngine("game-001", "red-player", "black-player")
+
+	if _, ok := engine.LastMove(); ok {
+		t.Error("Expected no last move before any move is made")
+	}
+	if engine.GetGameState().LastMove != nil {
+		t.Error("Expected no last move in the game state before any move is made")
+	}
+
+	moves := []MoveRequest{
+		{PlayerID: "red-player", From: "b0", To: "c2"},
+		{PlayerID: "black-player", From: "b9", To: "c7"},
+		{PlayerID: "red-player", From: "h2", To: "e2"},
+	}
+	for _, move := range moves {
+		if result := engine.ValidateAndMakeMove(move); !result.Success {
+			t.Fatalf("Move %s-%s failed: %s", move.From, move.To, result.ErrorMessage)
+		}
+	}
+
+	last, ok := engine.LastMove()
+	if !ok {
+		t.Fatal("Expected a last move")
+	}
+	if last.MoveNumber != 3 || last.From != (Position{7, 2}) || last.To != (Position{4, 2}) {
+		t.Errorf("Expected move 3 h2-e2, got move %d %s-%s", last.MoveNumber, last.From.Notation(), last.To.Notation())
+	}
+
+	state := engine.GetGameState().LastMove
+	if state == nil || state.From != "h2" || state.To != "e2" || state.PieceType != "cannon" {
+		t.Errorf("Expected game state last move h2-e2 cannon, got %+v", state)
+	}
+}
+
+// ========== Piece Move Count Tests ==========
+
+func TestEngine_PieceMoveCounts(t *testing.T) {
+	engine := NewGameEngine("game-001", "red-player", "black-player")
+
+	moves := []MoveRequest{
+		{PlayerID: "red-player", From: "b0", To: "c2"},   // Red horse
+		{PlayerID: "black-player", From: "b9", To: "c7"}, // Black horse
+		{PlayerID: "red-player", From: "h0", To: "g2"},   // Red horse
+		{PlayerID: "black-player", From: "e6", To: "e5"}, // Black soldier
+		{PlayerID: "red-player", From: "e0", To: "e1"},   // Red general
+		{PlayerID: "black-player", From: "a9", To: "a8"}, // Black chariot
+	}
+
+	for i, move := range moves {
+		result := engine.ValidateAndMakeMove(move)
+		if !result.Success {
+			t.Fatalf("Move %d failed: %s", i+1, result.ErrorMessage)
+		}
+	}
+
+	red := engine.GetPieceMoveCounts(models.PlayerColorRed)
+	if red[models.PieceTypeHorse] != 2 {
+		t.Errorf("Expected red horse count 2, got %d", red[models.PieceTypeHorse])
+	}
+	if red[models.PieceTypeGeneral] != 1 {
+		t.Errorf("Expected red general count 1, got %d", red[models.PieceTypeGeneral])
+	}
+	if red[models.PieceTypeSoldier] != 0 {
+		t.Errorf("Expected red soldier count 0, got %d", red[models.PieceTypeSoldier])
+	}
+
+	black := engine.GetPieceMoveCounts(models.PlayerColorBlack)
+	if black[models.PieceTypeHorse] != 1 || black[models.PieceTypeSoldier] != 1 || black[models.PieceTypeChariot] != 1 {
+		t.Errorf("Unexpected black counts: %v", black)
+	}
+
+	state := engine.GetGameState()
+	if state.PieceMoveCounts["red"]["horse"] != 2 {
+		t.Errorf("Expected game state to report 2 red horse moves, got %d", state.PieceMoveCounts["red"]["horse"])
+	}
+}
+
+func TestEngine_PieceMoveCounts_AfterUndo(t *testing.T) {
+	engine := NewGameEngine("game-001", "red-player", "black-player")
+
+	engine.ValidateAndMakeMove(MoveRequest{PlayerID: "red-player", From: "b0", To: "c2"})
+	engine.ValidateAndMakeMove(MoveRequest{PlayerID: "black-player", From: "b9", To: "c7"})
+
+	if err := engine.UndoLastMove(); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+
+	if got := engine.GetPieceMoveCounts(models.PlayerColorBlack)[models.PieceTypeHorse]; got != 0 {
+		t.Errorf("Expected black horse count 0 after undo, got %d", got)
+	}
+	if got := engine.GetPieceMoveCounts(models.PlayerColorRed)[models.PieceTypeHorse]; got != 1 {
+		t.Errorf("Expected red horse count 1 after undo, got %d", got)
+	}
+}
+
 // ========== NewGameEngineFromState Tests ==========
 
 func TestNewGameEngineFromState(t *testing.T) {
@@ -628,14 +1399,7 @@ func TestNewGameEngineFromState(t *testing.T) {
 		},
 	}
 
-	engine := NewGameEngineFromState(
-		"game-001",
-		"red-player",
-		"black-player",
-		board,
-		models.PlayerColorBlack,
-		moves,
-	)
+	engine := newEngineFromState(t, board, models.PlayerColorBlack, moves)
 
 	if engine.GetCurrentTurn() != models.PlayerColorBlack {
 		t.Error("Should be black's turn")
@@ -652,6 +1416,178 @@ func TestNewGameEngineFromState(t *testing.T) {
 	}
 }
 
+func TestNewGameEngineFromFEN(t *testing.T) {
+	// A lone black general against red's general and chariot
+	engine, err := NewGameEngineFromFEN("game-001", "red-player", "black-player", "4k4/9/9/9/9/9/9/9/9/3K1R3 b")
+	if err != nil {
+		t.Fatalf("NewGameEngineFromFEN failed: %v", err)
+	}
+	if engine.GetCurrentTurn() != models.PlayerColorBlack {
+		t.Error("Should be black's turn")
+	}
+	if piece := engine.GetBoard().At(Position{5, 0}); piece == nil || piece.Type != models.PieceTypeChariot {
+		t.Error("Red chariot should be at f0")
+	}
+	if engine.GetFEN() != "4k4/9/9/9/9/9/9/9/9/3K1R3 b" {
+		t.Errorf("Unexpected FEN %s", engine.GetFEN())
+	}
+
+	// The resumed game continues from the position
+	result := engine.ValidateAndMakeMove(MoveRequest{PlayerID: "black-player", From: "e9", To: "e8"})
+	if !result.Success {
+		t.Fatalf("Expected black's move to succeed: %s", result.ErrorMessage)
+	}
+}
+
+func TestNewGameEngineFromFEN_DefaultsToRed(t *testing.T) {
+	engine, err := NewGameEngineFromFEN("game-001", "red-player", "black-player", NewInitialBoard().ToFEN())
+	if err != nil {
+		t.Fatalf("NewGameEngineFromFEN failed: %v", err)
+	}
+	if engine.GetCurrentTurn() != models.PlayerColorRed {
+		t.Error("Should be red's turn")
+	}
+}
+
+func TestNewGameEngineFromFEN_Rejects(t *testing.T) {
+	for _, fen := range []string{"4k4/9/9/9/9/9/9/9/9/4K4 x", "4k4/9/9/9/9/9/9/9/4K4 w", "not a fen"} {
+		if _, err := NewGameEngineFromFEN("game-001", "red-player", "black-player", fen); !errors.Is(err, ErrInvalidFEN) {
+			t.Errorf("NewGameEngineFromFEN(%q): expected ErrInvalidFEN, got %v", fen, err)
+		}
+	}
+}
+
+func TestGameEngine_MovesSinceCapture(t *testing.T) {
+	engine := NewGameEngine("game-001", "red-player", "black-player")
+	moves := []MoveRequest{
+		{PlayerID: "red-player", From: "b0", To: "c2"},
+		{PlayerID: "black-player", From: "b9", To: "c7"},
+		{PlayerID: "red-player", From: "h2", To: "h9"}, // captures the horse
+		{PlayerID: "black-player", From: "a9", To: "a8"},
+	}
+	expected := []int{1, 2, 0, 1}
+
+	for i, req := range moves {
+		if result := engine.ValidateAndMakeMove(req); !result.Success {
+			t.Fatalf("Move %d failed: %s", i+1, result.ErrorMessage)
+		}
+		if got := engine.MovesSinceCapture(); got != expected[i] {
+			t.Errorf("After move %d: expected %d plies since capture, got %d", i+1, expected[i], got)
+		}
+	}
+
+	// Undoing replays the remaining moves, including the capture
+	if err := engine.UndoLastMove(); err != nil {
+		t.Fatalf("UndoLastMove failed: %v", err)
+	}
+	if got := engine.MovesSinceCapture(); got != 0 {
+		t.Errorf("Expected 0 plies since capture after undo, got %d", got)
+	}
+}
+
+func TestGameEngine_NoCaptureDraw(t *testing.T) {
+	engine := NewGameEngine("game-001", "red-player", "black-player", WithNoCaptureDrawPlies(4))
+
+	for i, req := range horseShuffle {
+		result := engine.ValidateAndMakeMove(req)
+		if !result.Success {
+			t.Fatalf("Move %d failed: %s", i+1, result.ErrorMessage)
+		}
+		if want := i == len(horseShuffle)-1; result.IsDraw != want {
+			t.Errorf("Move %d: expected IsDraw %v", i+1, want)
+		}
+	}
+	if !engine.IsDraw() || !engine.IsGameOver() {
+		t.Fatal("Expected the game to be drawn after 4 plies without a capture")
+	}
+	if result := engine.ValidateAndMakeMove(horseShuffle[0]); result.Success {
+		t.Error("Expected moves to be rejected after the draw")
+	}
+
+	// Undoing the last move takes the draw back
+	if err := engine.UndoLastMove(); err != nil {
+		t.Fatalf("UndoLastMove failed: %v", err)
+	}
+	if engine.IsDraw() {
+		t.Error("Expected the draw to be undone")
+	}
+}
+
+func TestGameEngine_NoCaptureDraw_DefaultThreshold(t *testing.T) {
+	engine := NewGameEngine("game-001", "red-player", "black-player", WithNoCaptureDrawPlies(DefaultNoCaptureDrawPlies))
+	engine.SetMovesSinceCapture(DefaultNoCaptureDrawPlies - 2)
+
+	if result := engine.ValidateAndMakeMove(horseShuffle[0]); result.IsDraw {
+		t.Fatalf("Expected no draw after %d plies", DefaultNoCaptureDrawPlies-1)
+	}
+	if result := engine.ValidateAndMakeMove(horseShuffle[1]); !result.IsDraw {
+		t.Errorf("Expected a draw after %d plies", DefaultNoCaptureDrawPlies)
+	}
+}
+
+func TestGameEngine_NoCaptureDraw_CaptureResets(t *testing.T) {
+	engine := NewGameEngine("game-001", "red-player", "black-player", WithNoCaptureDrawPlies(3))
+	moves := []MoveRequest{
+		{PlayerID: "red-player", From: "b0", To: "c2"},
+		{PlayerID: "black-player", From: "b9", To: "c7"},
+		{PlayerID: "red-player", From: "h2", To: "h9"}, // captures the horse on the third ply
+		{PlayerID: "black-player", From: "a9", To: "a8"},
+		{PlayerID: "red-player", From: "h9", To: "h8"},
+	}
+
+	for i, req := range moves {
+		result := engine.ValidateAndMakeMove(req)
+		if !result.Success {
+			t.Fatalf("Move %d failed: %s", i+1, result.ErrorMessage)
+		}
+		if result.IsDraw {
+			t.Fatalf("Move %d: the capture should have reset the count", i+1)
+		}
+	}
+
+	// The third ply after the capture draws
+	if result := engine.ValidateAndMakeMove(MoveRequest{PlayerID: "black-player", From: "a8", To: "a9"}); !result.IsDraw {
+		t.Error("Expected a draw three plies after the capture")
+	}
+}
+
+func TestGameEngine_NoCaptureDraw_OffByDefault(t *testing.T) {
+	engine := NewGameEngine("game-001", "red-player", "black-player")
+	engine.SetMovesSinceCapture(1000)
+
+	if result := engine.ValidateAndMakeMove(horseShuffle[0]); !result.Success || result.IsDraw {
+		t.Errorf("Expected no draw unless the rule is enabled, got %+v", result)
+	}
+}
+
+func TestGameEngine_NoCaptureDraw_Disabled(t *testing.T) {
+	engine := NewGameEngine("game-001", "red-player", "black-player", WithNoCaptureDrawPlies(0))
+	engine.SetMovesSinceCapture(1000)
+
+	if result := engine.ValidateAndMakeMove(horseShuffle[0]); !result.Success || result.IsDraw {
+		t.Errorf("Expected no draw with the rule disabled, got %+v", result)
+	}
+}
+
+func TestNewGameEngineFromState_RejectsMissingGeneral(t *testing.T) {
+	board := NewInitialBoard()
+	board.Remove(Position{4, 9})
+
+	engine, err := NewGameEngineFromState("game-001", "red-player", "black-player", board, models.PlayerColorRed, nil)
+	if err == nil {
+		t.Fatal("Expected a board without a black general to be rejected")
+	}
+	if engine != nil {
+		t.Error("Expected no engine for a rejected board")
+	}
+}
+
+func TestNewGameEngineFromState_RejectsInvalidTurn(t *testing.T) {
+	if _, err := NewGameEngineFromState("game-001", "red-player", "black-player", NewInitialBoard(), "green", nil); err == nil {
+		t.Error("Expected an invalid current turn to be rejected")
+	}
+}
+
 // ========== Complete Game Simulation ==========
 
 func TestEngine_CompleteGame(t *testing.T) {
@@ -0,0 +1,80 @@
+// Package game contains the core Xiangqi game logic.
+package game
+
+import "github.com/xiangqi/chinese-chess-backend/internal/models"
+
+// pieceValues are material values in tenths of a soldier. The general is
+// left out since both sides always have one.
+var pieceValues = map[models.PieceType]int{
+	models.PieceTypeChariot:  90,
+	models.PieceTypeCannon:   45,
+	models.PieceTypeHorse:    40,
+	models.PieceTypeAdvisor:  20,
+	models.PieceTypeElephant: 20,
+	models.PieceTypeSoldier:  10,
+}
+
+// crossedSoldierValue is the value of a soldier that has crossed the river
+// and can move sideways.
+const crossedSoldierValue = 20
+
+// Evaluate returns the material balance of the board from the given color's
+// point of view, in tenths of a soldier. Positive values favor color.
+func Evaluate(board *Board, color models.PlayerColor) int {
+	if color == models.PlayerColorBlack {
+		return -board.MaterialBalance()
+	}
+	return board.MaterialBalance()
+}
+
+// MaterialBalance returns red's material minus black's, in tenths of a
+// soldier: a chariot is 90, a cannon 45, a horse 40, an advisor or elephant
+// 20, and a soldier 10, or 20 once it has crossed the river. Generals are
+// not counted, as neither side can be without one.
+func (b *Board) MaterialBalance() int {
+	return material(b, models.PlayerColorRed) - material(b, models.PlayerColorBlack)
+}
+
+// startingPieces is how many pieces of each type a side starts with.
+var startingPieces = map[models.PieceType]int{
+	models.PieceTypeChariot:  2,
+	models.PieceTypeCannon:   2,
+	models.PieceTypeHorse:    2,
+	models.PieceTypeAdvisor:  2,
+	models.PieceTypeElephant: 2,
+	models.PieceTypeSoldier:  5,
+}
+
+// CapturedPieces counts the pieces of the given color missing from the
+// board, by type, which in a game from the starting position are the ones
+// the opponent has captured.
+func (b *Board) CapturedPieces(color models.PlayerColor) map[models.PieceType]int {
+	captured := make(map[models.PieceType]int, len(startingPieces))
+	for pieceType, count := range startingPieces {
+		captured[pieceType] = count
+	}
+	for _, piece := range b.GetPieces(color) {
+		if _, ok := captured[piece.Type]; ok {
+			captured[piece.Type]--
+		}
+	}
+	for pieceType, count := range captured {
+		if count <= 0 {
+			delete(captured, pieceType)
+		}
+	}
+	return captured
+}
+
+// material sums the values of one side's pieces.
+func material(board *Board, color models.PlayerColor) int {
+	total := 0
+	for _, piece := range board.GetPieces(color) {
+		if piece.Type == models.PieceTypeSoldier && piece.Position.HasCrossedRiver(color) {
+			total += crossedSoldierValue
+			continue
+		}
+		total += pieceValues[piece.Type]
+	}
+	return total
+}
@@ -0,0 +1,91 @@
+// Package game provides unit tests for position evaluation.
+package game
+
+import (
+	"testing"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
+
+func TestEvaluate_InitialPositionIsBalanced(t *testing.T) {
+	board := NewInitialBoard()
+
+	if score := Evaluate(board, models.PlayerColorRed); score != 0 {
+		t.Errorf("Expected balanced initial position, got %d", score)
+	}
+}
+
+func TestEvaluate_MaterialAdvantage(t *testing.T) {
+	board := NewInitialBoard()
+	board.Remove(Position{0, 9}) // Black chariot
+
+	if score := Evaluate(board, models.PlayerColorRed); score != 90 {
+		t.Errorf("Expected red to be a chariot up (90), got %d", score)
+	}
+	if score := Evaluate(board, models.PlayerColorBlack); score != -90 {
+		t.Errorf("Expected black to be a chariot down (-90), got %d", score)
+	}
+}
+
+func TestEvaluate_CrossedSoldier(t *testing.T) {
+	board := NewBoard()
+	board.Place(createPiece(models.PieceTypeGeneral, models.PlayerColorRed, 4, 0))
+	board.Place(createPiece(models.PieceTypeGeneral, models.PlayerColorBlack, 3, 9))
+	board.Place(createPiece(models.PieceTypeSoldier, models.PlayerColorRed, 2, 6))
+	board.Place(createPiece(models.PieceTypeSoldier, models.PlayerColorBlack, 6, 6))
+
+	// Red's soldier has crossed the river; black's has not
+	if score := Evaluate(board, models.PlayerColorRed); score != crossedSoldierValue-10 {
+		t.Errorf("Expected crossed soldier bonus of %d, got %d", crossedSoldierValue-10, score)
+	}
+}
+
+func TestBoard_MaterialBalance(t *testing.T) {
+	if balance := NewInitialBoard().MaterialBalance(); balance != 0 {
+		t.Errorf("Expected a balance of 0 in the initial position, got %d", balance)
+	}
+
+	tests := []struct {
+		name   string
+		remove []Position
+		want   int
+	}{
+		{"black chariot down", []Position{{0, 9}}, 90},
+		{"red cannon down", []Position{{1, 2}}, -45},
+		{"red horse for black cannon", []Position{{1, 0}, {7, 7}}, 5},
+		{"black down both advisors and a soldier", []Position{{3, 9}, {5, 9}, {4, 6}}, 50},
+	}
+	for _, tt := range tests {
+		board := NewInitialBoard()
+		for _, pos := range tt.remove {
+			board.Remove(pos)
+		}
+		if balance := board.MaterialBalance(); balance != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, balance)
+		}
+	}
+}
+
+func TestBoard_MaterialBalance_CrossedSoldiersCountDouble(t *testing.T) {
+	board := NewInitialBoard()
+	board.Move(Position{4, 3}, Position{4, 5}) // Red's middle soldier crosses
+
+	if balance := board.MaterialBalance(); balance != crossedSoldierValue-10 {
+		t.Errorf("Expected a crossed soldier to be worth %d more, got %d", crossedSoldierValue-10, balance)
+	}
+}
+
+func TestBoard_CapturedPieces(t *testing.T) {
+	board := NewInitialBoard()
+	board.Remove(Position{0, 9}) // Black chariot
+	board.Remove(Position{4, 6}) // Black soldier
+	board.Remove(Position{6, 6}) // Black soldier
+
+	captured := board.CapturedPieces(models.PlayerColorBlack)
+	if len(captured) != 2 || captured[models.PieceTypeChariot] != 1 || captured[models.PieceTypeSoldier] != 2 {
+		t.Errorf("Expected a chariot and two soldiers captured, got %v", captured)
+	}
+	if captured := board.CapturedPieces(models.PlayerColorRed); len(captured) != 0 {
+		t.Errorf("Expected no red pieces captured, got %v", captured)
+	}
+}
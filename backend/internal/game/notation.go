@@ -0,0 +1,277 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
+
+// MoveNotation is a way of writing whole moves for clients and exporters.
+// Moves are stored as a pair of canonical squares; other notations only
+// exist at the API boundary.
+type MoveNotation string
+
+const (
+	// NotationCoordinate writes a move as its two canonical squares.
+	NotationCoordinate MoveNotation = "coordinate"
+	// NotationICCS joins the two squares with a dash, e.g. "b0-c2".
+	NotationICCS MoveNotation = "iccs"
+	// NotationWXF is the traditional notation relative to the moving side,
+	// piece, file, direction and destination, e.g. "H2+3".
+	NotationWXF MoveNotation = "wxf"
+)
+
+var (
+	// ErrUnknownNotation is returned for an unsupported notation name.
+	ErrUnknownNotation = errors.New("unknown move notation")
+	// ErrInvalidNotation is returned when a move cannot be read.
+	ErrInvalidNotation = errors.New("invalid move notation")
+)
+
+// ParseMoveNotation returns the named notation. An empty name selects the
+// canonical coordinate notation.
+func ParseMoveNotation(name string) (MoveNotation, error) {
+	switch MoveNotation(strings.ToLower(name)) {
+	case "", NotationCoordinate:
+		return NotationCoordinate, nil
+	case NotationICCS:
+		return NotationICCS, nil
+	case NotationWXF:
+		return NotationWXF, nil
+	}
+	return "", ErrUnknownNotation
+}
+
+// ICCS returns the move in ICCS notation, e.g. "b0-c2".
+func (m MoveRecord) ICCS() string {
+	return m.From.Notation() + "-" + m.To.Notation()
+}
+
+// ParseICCS reads a move in ICCS notation. The dash is optional and case is
+// ignored. It does not check that the move is legal.
+func ParseICCS(notation string) (from, to Position, err error) {
+	notation = strings.ReplaceAll(strings.TrimSpace(notation), "-", "")
+	if len(notation) != 4 {
+		return Position{}, Position{}, fmt.Errorf("%w: %q is not two squares", ErrInvalidNotation, notation)
+	}
+	if from, err = ParsePosition(notation[:2]); err != nil {
+		return Position{}, Position{}, fmt.Errorf("%w: %v", ErrInvalidNotation, err)
+	}
+	if to, err = ParsePosition(notation[2:]); err != nil {
+		return Position{}, Position{}, fmt.Errorf("%w: %v", ErrInvalidNotation, err)
+	}
+	return from, to, nil
+}
+
+// wxfLetters are the WXF piece letters.
+var wxfLetters = map[models.PieceType]byte{
+	models.PieceTypeGeneral:  'K',
+	models.PieceTypeAdvisor:  'A',
+	models.PieceTypeElephant: 'E',
+	models.PieceTypeHorse:    'H',
+	models.PieceTypeChariot:  'R',
+	models.PieceTypeCannon:   'C',
+	models.PieceTypeSoldier:  'P',
+}
+
+// WXF returns the move in WXF notation. The board must hold the position
+// before the move, which gives the moving side and tells two identical
+// pieces on the same file apart: those are written "+C" for the front piece
+// and "-C" for the rear one instead of a file number. Advisors and elephants
+// never need this, as their direction and destination tell them apart. It
+// returns an empty string if the board has no piece on the move's origin.
+func (m MoveRecord) WXF(board *Board) string {
+	piece := board.At(m.From)
+	if piece == nil {
+		return ""
+	}
+	color := piece.Color
+	letter := string(wxfLetters[piece.Type])
+
+	var sb strings.Builder
+	if front, ok := tandemFront(board, piece); ok {
+		if front {
+			sb.WriteString("+")
+		} else {
+			sb.WriteString("-")
+		}
+		sb.WriteString(letter)
+	} else {
+		sb.WriteString(letter)
+		sb.WriteString(strconv.Itoa(wxfFile(color, m.From.File)))
+	}
+
+	advance := m.To.Rank - m.From.Rank
+	if color == models.PlayerColorBlack {
+		advance = -advance
+	}
+	switch {
+	case advance == 0:
+		sb.WriteString(".")
+		sb.WriteString(strconv.Itoa(wxfFile(color, m.To.File)))
+		return sb.String()
+	case advance > 0:
+		sb.WriteString("+")
+	default:
+		sb.WriteString("-")
+	}
+
+	// Pieces moving along a file give the ranks moved, the others the
+	// file they land on
+	if movesDiagonally(piece.Type) {
+		sb.WriteString(strconv.Itoa(wxfFile(color, m.To.File)))
+	} else {
+		sb.WriteString(strconv.Itoa(Abs(advance)))
+	}
+	return sb.String()
+}
+
+// ParseWXF reads a move in WXF notation for the given side, using the board
+// to find the piece that moves. It does not check that the move is legal.
+func ParseWXF(board *Board, color models.PlayerColor, notation string) (from, to Position, err error) {
+	notation = strings.ToUpper(strings.TrimSpace(notation))
+	if len(notation) != 4 {
+		return Position{}, Position{}, fmt.Errorf("%w: %q", ErrInvalidNotation, notation)
+	}
+
+	letter := notation[0]
+	if letter == '+' || letter == '-' {
+		letter = notation[1]
+	}
+	var pieceType models.PieceType
+	for t, l := range wxfLetters {
+		if l == letter {
+			pieceType = t
+		}
+	}
+	number, numErr := strconv.Atoi(notation[3:])
+	if pieceType == "" || numErr != nil || number < 1 || number > FileCount {
+		return Position{}, Position{}, fmt.Errorf("%w: %q", ErrInvalidNotation, notation)
+	}
+
+	// Work out where each piece of the type would land and keep the one
+	// that writes the same notation
+	matches := 0
+	for _, piece := range board.GetPieces(color) {
+		if piece.Type != pieceType {
+			continue
+		}
+		dest, ok := wxfDestination(piece, notation[2], number)
+		if !ok {
+			continue
+		}
+		record := MoveRecord{From: piece.Position, To: dest, PieceType: pieceType}
+		if record.WXF(board) == notation {
+			from, to = piece.Position, dest
+			matches++
+		}
+	}
+
+	switch matches {
+	case 0:
+		return Position{}, Position{}, fmt.Errorf("%w: no piece can play %q", ErrInvalidNotation, notation)
+	case 1:
+		return from, to, nil
+	}
+	return Position{}, Position{}, fmt.Errorf("%w: %q is ambiguous", ErrInvalidNotation, notation)
+}
+
+// wxfDestination returns where the piece lands for a WXF direction and
+// number, or false if the piece cannot move that way.
+func wxfDestination(piece *Piece, direction byte, number int) (Position, bool) {
+	from := piece.Position
+	forward := 1
+	if piece.Color == models.PlayerColorBlack {
+		forward = -1
+	}
+
+	var dest Position
+	switch {
+	case direction == '.':
+		if movesDiagonally(piece.Type) {
+			return Position{}, false
+		}
+		dest = Position{File: wxfFileIndex(piece.Color, number), Rank: from.Rank}
+	case direction != '+' && direction != '-':
+		return Position{}, false
+	case movesDiagonally(piece.Type):
+		file := wxfFileIndex(piece.Color, number)
+		files := Abs(file - from.File)
+		var ranks int
+		switch piece.Type {
+		case models.PieceTypeAdvisor:
+			ranks = 1
+		case models.PieceTypeElephant:
+			ranks = 2
+		default:
+			ranks = 3 - files
+		}
+		if files == 0 || files > 2 || ranks < 1 {
+			return Position{}, false
+		}
+		dest = Position{File: file, Rank: from.Rank + ranks*forward}
+	default:
+		dest = Position{File: from.File, Rank: from.Rank + number*forward}
+	}
+
+	if direction == '-' {
+		// Retreats mirror the rank change
+		dest.Rank = from.Rank - (dest.Rank - from.Rank)
+	}
+	return dest, dest.IsValid() && dest != from
+}
+
+// tandemFront reports whether the piece is the front one of exactly two
+// identical pieces sharing a file. It returns false for ok if the piece
+// has no such twin, or is an advisor or elephant.
+func tandemFront(board *Board, piece *Piece) (front, ok bool) {
+	if piece.Type == models.PieceTypeAdvisor || piece.Type == models.PieceTypeElephant {
+		return false, false
+	}
+
+	var twin *Piece
+	count := 0
+	for _, other := range board.GetPieces(piece.Color) {
+		if other.Type == piece.Type && other.Position.File == piece.Position.File {
+			count++
+			if other != piece {
+				twin = other
+			}
+		}
+	}
+	if count != 2 || twin == nil {
+		return false, false
+	}
+
+	if piece.Color == models.PlayerColorRed {
+		return piece.Position.Rank > twin.Position.Rank, true
+	}
+	return piece.Position.Rank < twin.Position.Rank, true
+}
+
+// movesDiagonally reports whether WXF writes the piece's destination file
+// rather than the ranks it moves.
+func movesDiagonally(pieceType models.PieceType) bool {
+	return pieceType == models.PieceTypeAdvisor ||
+		pieceType == models.PieceTypeElephant ||
+		pieceType == models.PieceTypeHorse
+}
+
+// wxfFile numbers a file from the side's own right, 1–9.
+func wxfFile(color models.PlayerColor, file int) int {
+	if color == models.PlayerColorRed {
+		return FileCount - file
+	}
+	return file + 1
+}
+
+// wxfFileIndex is the inverse of wxfFile.
+func wxfFileIndex(color models.PlayerColor, number int) int {
+	if color == models.PlayerColorRed {
+		return FileCount - number
+	}
+	return number - 1
+}
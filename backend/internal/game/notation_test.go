@@ -0,0 +1,153 @@
+// Package game provides unit tests for move notations.
+package game
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
+
+func TestMoveRecord_ICCSRoundTrip(t *testing.T) {
+	record := MoveRecord{From: Position{1, 0}, To: Position{2, 2}}
+	if got := record.ICCS(); got != "b0-c2" {
+		t.Fatalf("Expected b0-c2, got %s", got)
+	}
+
+	for _, notation := range []string{"b0-c2", "B0-C2", "b0c2"} {
+		from, to, err := ParseICCS(notation)
+		if err != nil {
+			t.Fatalf("ParseICCS(%q) failed: %v", notation, err)
+		}
+		if from != record.From || to != record.To {
+			t.Errorf("ParseICCS(%q) = %s-%s", notation, from.Notation(), to.Notation())
+		}
+	}
+
+	for _, notation := range []string{"", "b0", "b0-c", "j0-c2", "b0-c2-d3"} {
+		if _, _, err := ParseICCS(notation); !errors.Is(err, ErrInvalidNotation) {
+			t.Errorf("ParseICCS(%q) should fail with ErrInvalidNotation, got %v", notation, err)
+		}
+	}
+}
+
+func TestMoveRecord_WXF_Openings(t *testing.T) {
+	board := NewInitialBoard()
+	tests := []struct {
+		from, to string
+		want     string
+	}{
+		{"h0", "g2", "H2+3"}, // Red horse, counted from red's right
+		{"h2", "e2", "C2.5"}, // Central cannon
+		{"a0", "a1", "R9+1"},
+		{"f0", "e1", "A4+5"},
+		{"g0", "e2", "E3+5"},
+		{"e3", "e4", "P5+1"},
+		{"b9", "c7", "H2+3"}, // Black counts files from its own right
+		{"h7", "e7", "C8.5"},
+		{"e9", "e8", "K5+1"},
+	}
+
+	for _, tt := range tests {
+		from, _ := ParsePosition(tt.from)
+		to, _ := ParsePosition(tt.to)
+		piece := board.At(from)
+		record := MoveRecord{From: from, To: to, PieceType: piece.Type}
+		if got := record.WXF(board); got != tt.want {
+			t.Errorf("%s-%s: expected %s, got %s", tt.from, tt.to, tt.want, got)
+		}
+
+		gotFrom, gotTo, err := ParseWXF(board, piece.Color, tt.want)
+		if err != nil {
+			t.Fatalf("ParseWXF(%s) failed: %v", tt.want, err)
+		}
+		if gotFrom != from || gotTo != to {
+			t.Errorf("ParseWXF(%s) = %s-%s, want %s-%s", tt.want, gotFrom.Notation(), gotTo.Notation(), tt.from, tt.to)
+		}
+	}
+}
+
+func TestMoveRecord_WXF_CannonsOnOneFile(t *testing.T) {
+	// Both red cannons on the h-file and both black cannons on the b-file
+	board, err := ParseFEN("4k4/9/1c7/9/1c7/7C1/9/7C1/9/3K5")
+	if err != nil {
+		t.Fatalf("ParseFEN failed: %v", err)
+	}
+
+	tests := []struct {
+		color    models.PlayerColor
+		from, to string
+		want     string
+	}{
+		{models.PlayerColorRed, "h4", "e4", "+C.5"}, // Front is further up for red
+		{models.PlayerColorRed, "h2", "h3", "-C+1"},
+		{models.PlayerColorRed, "h2", "h0", "-C-2"},
+		{models.PlayerColorBlack, "b5", "b4", "+C+1"}, // Front is further down for black
+		{models.PlayerColorBlack, "b7", "e7", "-C.5"},
+	}
+
+	for _, tt := range tests {
+		from, _ := ParsePosition(tt.from)
+		to, _ := ParsePosition(tt.to)
+		record := MoveRecord{From: from, To: to, PieceType: models.PieceTypeCannon}
+		if got := record.WXF(board); got != tt.want {
+			t.Errorf("%s-%s: expected %s, got %s", tt.from, tt.to, tt.want, got)
+		}
+
+		gotFrom, gotTo, err := ParseWXF(board, tt.color, tt.want)
+		if err != nil {
+			t.Fatalf("ParseWXF(%s) failed: %v", tt.want, err)
+		}
+		if gotFrom != from || gotTo != to {
+			t.Errorf("ParseWXF(%s) = %s-%s, want %s-%s", tt.want, gotFrom.Notation(), gotTo.Notation(), tt.from, tt.to)
+		}
+	}
+
+	// The file number no longer names a single cannon
+	if _, _, err := ParseWXF(board, models.PlayerColorRed, "C2.5"); !errors.Is(err, ErrInvalidNotation) {
+		t.Errorf("Expected C2.5 to be rejected when both cannons share the file, got %v", err)
+	}
+}
+
+func TestParseWXF_RoundTripsEveryLegalMove(t *testing.T) {
+	rules := NewRulesEngine()
+	board := NewInitialBoard()
+	for _, color := range []models.PlayerColor{models.PlayerColorRed, models.PlayerColorBlack} {
+		for _, move := range rules.GetAllLegalMoves(board, color) {
+			notation := MoveRecord{From: move.From, To: move.To, PieceType: move.PieceType}.WXF(board)
+			from, to, err := ParseWXF(board, color, notation)
+			if err != nil {
+				t.Fatalf("ParseWXF(%s) failed: %v", notation, err)
+			}
+			if from != move.From || to != move.To {
+				t.Errorf("%s round-tripped %s-%s to %s-%s", notation,
+					move.From.Notation(), move.To.Notation(), from.Notation(), to.Notation())
+			}
+		}
+	}
+}
+
+func TestParseWXF_RejectsInvalidNotation(t *testing.T) {
+	board := NewInitialBoard()
+	for _, notation := range []string{"", "H2+", "X2+3", "H2*3", "H2+0", "R1.1", "K5.5"} {
+		if _, _, err := ParseWXF(board, models.PlayerColorRed, notation); !errors.Is(err, ErrInvalidNotation) {
+			t.Errorf("ParseWXF(%q) should fail with ErrInvalidNotation, got %v", notation, err)
+		}
+	}
+}
+
+func TestParseMoveNotation(t *testing.T) {
+	for name, want := range map[string]MoveNotation{
+		"":           NotationCoordinate,
+		"coordinate": NotationCoordinate,
+		"ICCS":       NotationICCS,
+		"wxf":        NotationWXF,
+	} {
+		if got, err := ParseMoveNotation(name); err != nil || got != want {
+			t.Errorf("ParseMoveNotation(%q) = %s, %v", name, got, err)
+		}
+	}
+	if _, err := ParseMoveNotation("pgn"); !errors.Is(err, ErrUnknownNotation) {
+		t.Errorf("Expected ErrUnknownNotation, got %v", err)
+	}
+}
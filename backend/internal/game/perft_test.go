@@ -0,0 +1,27 @@
+// Package game provides perft tests for legal move generation.
+package game
+
+import "testing"
+
+// initialPerft holds the known numbers of legal move sequences from the
+// initial position, indexed by depth.
+var initialPerft = []uint64{1, 44, 1920, 79666, 3290240}
+
+func TestEngine_Perft_InitialPosition(t *testing.T) {
+	engine := NewGameEngine("game-001", "red-player", "black-player")
+	fen := engine.GetFEN()
+
+	maxDepth := len(initialPerft) - 1
+	if testing.Short() {
+		maxDepth = 3
+	}
+	for depth := 1; depth <= maxDepth; depth++ {
+		if got := engine.Perft(depth); got != initialPerft[depth] {
+			t.Errorf("Perft(%d) = %d, want %d", depth, got, initialPerft[depth])
+		}
+	}
+
+	if engine.GetFEN() != fen {
+		t.Errorf("Expected perft to leave the position unchanged, got %s", engine.GetFEN())
+	}
+}
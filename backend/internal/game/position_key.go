@@ -0,0 +1,66 @@
+// Package game implements the Xiangqi (Chinese Chess) game logic.
+package game
+
+import (
+	"math/rand"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
+
+// zobristSeed fixes the hashing keys so position keys stay the same across
+// restarts and can be stored.
+const zobristSeed = 0x5869616e677169
+
+// zobristPieceIndex numbers the piece types for the key tables.
+var zobristPieceIndex = map[models.PieceType]int{
+	models.PieceTypeGeneral:  0,
+	models.PieceTypeAdvisor:  1,
+	models.PieceTypeElephant: 2,
+	models.PieceTypeHorse:    3,
+	models.PieceTypeChariot:  4,
+	models.PieceTypeCannon:   5,
+	models.PieceTypeSoldier:  6,
+}
+
+var (
+	// zobristPieces holds a random key per piece type, color and square.
+	zobristPieces [7][2][RankCount * FileCount]uint64
+	// zobristBlackToMove is mixed into the key when black is to move.
+	zobristBlackToMove uint64
+)
+
+func init() {
+	rng := rand.New(rand.NewSource(zobristSeed))
+	for piece := range zobristPieces {
+		for color := range zobristPieces[piece] {
+			for square := range zobristPieces[piece][color] {
+				zobristPieces[piece][color][square] = rng.Uint64()
+			}
+		}
+	}
+	zobristBlackToMove = rng.Uint64()
+}
+
+// PositionKey returns a Zobrist hash of the position: every piece's type,
+// color and square, and the side to move. Equal positions always have equal
+// keys; different positions collide only with negligible probability.
+func (r *RulesEngine) PositionKey(board *Board, turn models.PlayerColor) uint64 {
+	var key uint64
+	for rank := 0; rank < RankCount; rank++ {
+		for file := 0; file < FileCount; file++ {
+			piece := board.squares[rank][file]
+			if piece == nil {
+				continue
+			}
+			color := 0
+			if piece.Color == models.PlayerColorBlack {
+				color = 1
+			}
+			key ^= zobristPieces[zobristPieceIndex[piece.Type]][color][rank*FileCount+file]
+		}
+	}
+	if turn == models.PlayerColorBlack {
+		key ^= zobristBlackToMove
+	}
+	return key
+}
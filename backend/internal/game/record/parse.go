@@ -0,0 +1,127 @@
+package record
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/game"
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
+
+// ParsedGame is a game read from a PGN record.
+type ParsedGame struct {
+	Moves []models.MovePayload
+	// Result is the record's Result tag, or the result closing its move
+	// list if it has no tag.
+	Result Result
+}
+
+// ParseError reports the first move of a record that cannot be read.
+type ParseError struct {
+	// Index is the zero-based position of the move in the record.
+	Index int
+	Move  string
+	Err   error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("move %d %q: %v", e.Index, e.Move, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	pgnTag        = regexp.MustCompile(`^\[(\w+)\s+"(.*)"\]$`)
+	pgnComment    = regexp.MustCompile(`\{[^}]*\}`)
+	pgnMoveNumber = regexp.MustCompile(`^\d+\.+`)
+)
+
+// ParsePGN reads a PGN record, or a bare move list, with moves in WXF or
+// ICCS notation. WXF moves are resolved against the position they are
+// played from, so a move whose piece is missing stops the parse with a
+// *ParseError. It does not check that the moves are legal.
+func ParsePGN(text string) (*ParsedGame, error) {
+	parsed := &ParsedGame{Result: ResultUnknown}
+	tagged := false
+
+	var movetext strings.Builder
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if match := pgnTag.FindStringSubmatch(line); match != nil {
+			if strings.EqualFold(match[1], "Result") {
+				result, err := ParseResult(match[2])
+				if err != nil {
+					return nil, err
+				}
+				parsed.Result, tagged = result, true
+			}
+			continue
+		}
+		// A semicolon comments out the rest of the line
+		if i := strings.IndexByte(line, ';'); i >= 0 {
+			line = line[:i]
+		}
+		movetext.WriteString(line)
+		movetext.WriteString(" ")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	board := game.NewInitialBoard()
+	color := models.PlayerColorRed
+	for _, token := range strings.Fields(pgnComment.ReplaceAllString(movetext.String(), " ")) {
+		token = pgnMoveNumber.ReplaceAllString(token, "")
+		if token == "" {
+			continue
+		}
+		if result, err := ParseResult(token); err == nil {
+			if !tagged {
+				parsed.Result = result
+			}
+			continue
+		}
+
+		from, to, err := parseMove(board, color, token)
+		if err != nil {
+			return nil, &ParseError{Index: len(parsed.Moves), Move: token, Err: err}
+		}
+		parsed.Moves = append(parsed.Moves, models.MovePayload{From: from.Notation(), To: to.Notation()})
+		board.Move(from, to)
+		color = color.Opposite()
+	}
+	return parsed, nil
+}
+
+// parseMove reads one move for the side to move, in ICCS notation if it is
+// written as two squares and in WXF notation otherwise.
+func parseMove(board *game.Board, color models.PlayerColor, token string) (from, to game.Position, err error) {
+	if isICCS(token) {
+		from, to, err = game.ParseICCS(token)
+		if err != nil {
+			return from, to, err
+		}
+		if piece := board.At(from); piece == nil || piece.Color != color {
+			return from, to, fmt.Errorf("%w: no %s piece on %s", game.ErrInvalidNotation, color, from.Notation())
+		}
+		return from, to, nil
+	}
+	return game.ParseWXF(board, color, token)
+}
+
+// isICCS reports whether a move is written as two squares, such as "h2e2"
+// or "h2-e2". WXF moves never have a file letter in third place.
+func isICCS(token string) bool {
+	if len(token) == 5 && token[2] == '-' {
+		token = token[:2] + token[3:]
+	}
+	token = strings.ToLower(token)
+	return len(token) == 4 &&
+		token[0] >= 'a' && token[0] <= 'i' && token[1] >= '0' && token[1] <= '9' &&
+		token[2] >= 'a' && token[2] <= 'i' && token[3] >= '0' && token[3] <= '9'
+}
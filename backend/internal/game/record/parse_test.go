@@ -0,0 +1,89 @@
+// Package record provides unit tests for game record parsing.
+package record
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/game"
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
+
+func TestParsePGN_ReadsExportedRecord(t *testing.T) {
+	g, moves := fixtureGame()
+	finish(g, models.GameStatusCompleted, "bob", models.ResultTypeResignation)
+	written, err := ToPGN(g, moves)
+	if err != nil {
+		t.Fatalf("ToPGN failed: %v", err)
+	}
+
+	parsed, err := ParsePGN(written)
+	if err != nil {
+		t.Fatalf("ParsePGN failed: %v", err)
+	}
+	if parsed.Result != ResultBlackWins {
+		t.Errorf("Expected result %s, got %s", ResultBlackWins, parsed.Result)
+	}
+	if len(parsed.Moves) != len(moves) {
+		t.Fatalf("Expected %d moves, got %d", len(moves), len(parsed.Moves))
+	}
+	for i, move := range parsed.Moves {
+		if move.From != moves[i].FromPosition || move.To != moves[i].ToPosition {
+			t.Errorf("Move %d: expected %s-%s, got %s-%s", i, moves[i].FromPosition, moves[i].ToPosition, move.From, move.To)
+		}
+	}
+}
+
+func TestParsePGN_ICCSMoveList(t *testing.T) {
+	text := `1. h2-e2 {central cannon} h9g7
+2. H0-G2 ; the horse defends
+1/2-1/2`
+
+	parsed, err := ParsePGN(text)
+	if err != nil {
+		t.Fatalf("ParsePGN failed: %v", err)
+	}
+	want := []models.MovePayload{{From: "h2", To: "e2"}, {From: "h9", To: "g7"}, {From: "h0", To: "g2"}}
+	if len(parsed.Moves) != len(want) {
+		t.Fatalf("Expected %d moves, got %v", len(want), parsed.Moves)
+	}
+	for i, move := range parsed.Moves {
+		if move != want[i] {
+			t.Errorf("Move %d: expected %v, got %v", i, want[i], move)
+		}
+	}
+	if parsed.Result != ResultDraw {
+		t.Errorf("Expected the closing result to be read, got %s", parsed.Result)
+	}
+}
+
+func TestParsePGN_ReportsUnreadableMove(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"wxf without a piece", "1. C2.5 H8+7 2. C2.5"},
+		{"iccs from an empty square", "1. h2e2 h9g7 2. e5e6"},
+		{"iccs moving the opponent's piece", "1. h2e2 h9g7 2. a9a8"},
+	}
+
+	for _, tt := range tests {
+		_, err := ParsePGN(tt.text)
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Fatalf("%s: expected *ParseError, got %v", tt.name, err)
+		}
+		if parseErr.Index != 2 {
+			t.Errorf("%s: expected the error at index 2, got %d", tt.name, parseErr.Index)
+		}
+		if !errors.Is(err, game.ErrInvalidNotation) {
+			t.Errorf("%s: expected ErrInvalidNotation, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestParsePGN_RejectsInvalidResultTag(t *testing.T) {
+	if _, err := ParsePGN(`[Result "2-0"]`); !errors.Is(err, ErrInvalidResult) {
+		t.Errorf("Expected ErrInvalidResult, got %v", err)
+	}
+}
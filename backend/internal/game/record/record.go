@@ -0,0 +1,244 @@
+// Package record writes finished and ongoing games as textual game records
+// that other xiangqi software can replay.
+package record
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/game"
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
+
+// Format is a game record format.
+type Format string
+
+const (
+	// FormatPGN is the WXF flavour of PGN, with moves in WXF notation.
+	FormatPGN Format = "pgn"
+	// FormatDPXQ is the DhtmlXQ format used by dpxq.com, with moves as
+	// four digit coordinates.
+	FormatDPXQ Format = "dpxq"
+)
+
+// Result is the outcome of a game as written in PGN.
+type Result string
+
+const (
+	ResultRedWins   Result = "1-0"
+	ResultBlackWins Result = "0-1"
+	ResultDraw      Result = "1/2-1/2"
+	// ResultUnknown is used for games in progress or abandoned without a
+	// winner.
+	ResultUnknown Result = "*"
+)
+
+// ParseResult reads a PGN result. An empty string is an unknown result.
+func ParseResult(s string) (Result, error) {
+	switch result := Result(strings.TrimSpace(s)); result {
+	case ResultRedWins, ResultBlackWins, ResultDraw, ResultUnknown:
+		return result, nil
+	case "":
+		return ResultUnknown, nil
+	}
+	return "", ErrInvalidResult
+}
+
+// Winner returns the winning color of a decisive result.
+func (r Result) Winner() (models.PlayerColor, bool) {
+	switch r {
+	case ResultRedWins:
+		return models.PlayerColorRed, true
+	case ResultBlackWins:
+		return models.PlayerColorBlack, true
+	}
+	return "", false
+}
+
+var (
+	// ErrUnknownFormat is returned for an unsupported record format name.
+	ErrUnknownFormat = errors.New("unknown record format")
+	// ErrInvalidMove is returned when a stored move cannot be replayed.
+	ErrInvalidMove = errors.New("move cannot be replayed")
+	// ErrInvalidResult is returned for a result that is not a PGN result.
+	ErrInvalidResult = errors.New("invalid game result")
+)
+
+// ParseFormat returns the named record format. An empty name selects PGN.
+func ParseFormat(name string) (Format, error) {
+	switch Format(strings.ToLower(name)) {
+	case "", FormatPGN:
+		return FormatPGN, nil
+	case FormatDPXQ:
+		return FormatDPXQ, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// Write writes the game in the given format.
+func Write(format Format, g *models.Game, moves []*models.Move) (string, error) {
+	switch format {
+	case FormatPGN:
+		return ToPGN(g, moves)
+	case FormatDPXQ:
+		return ToDPXQ(g, moves)
+	}
+	return "", ErrUnknownFormat
+}
+
+// ToPGN writes the game as PGN with numbered moves in WXF notation. Players
+// are identified by their IDs. Games still in progress, and abandoned games
+// without a winner, have the unknown result "*".
+func ToPGN(g *models.Game, moves []*models.Move) (string, error) {
+	written, err := replay(moves, func(record game.MoveRecord, board *game.Board) string {
+		return record.WXF(board)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	result := pgnResult(g)
+	var sb strings.Builder
+	writeTag(&sb, "Game", "Chinese Chess")
+	writeTag(&sb, "Date", g.CreatedAt.UTC().Format("2006.01.02"))
+	writeTag(&sb, "Red", g.RedPlayerID)
+	writeTag(&sb, "Black", g.BlackPlayerID)
+	writeTag(&sb, "Result", string(result))
+	if termination := termination(g); termination != "" {
+		writeTag(&sb, "Termination", termination)
+	}
+	writeTag(&sb, "Format", "WXF")
+	sb.WriteString("\n")
+
+	for i := 0; i < len(written); i += 2 {
+		sb.WriteString(strconv.Itoa(i/2 + 1))
+		sb.WriteString(". ")
+		sb.WriteString(written[i])
+		if i+1 < len(written) {
+			sb.WriteString(" ")
+			sb.WriteString(written[i+1])
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString(string(result))
+	sb.WriteString("\n")
+	return sb.String(), nil
+}
+
+// ToDPXQ writes the game in the DhtmlXQ format. Each move is four digits:
+// the file and row of its origin and then of its destination, with files
+// counted from red's left and rows from black's back rank.
+func ToDPXQ(g *models.Game, moves []*models.Move) (string, error) {
+	written, err := replay(moves, func(record game.MoveRecord, _ *game.Board) string {
+		return dpxqSquare(record.From) + dpxqSquare(record.To)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("[DhtmlXQ]\n")
+	writeField(&sb, "title", g.RedPlayerID+" vs "+g.BlackPlayerID)
+	writeField(&sb, "red", g.RedPlayerID)
+	writeField(&sb, "black", g.BlackPlayerID)
+	writeField(&sb, "date", g.CreatedAt.UTC().Format("2006-01-02"))
+	writeField(&sb, "result", dpxqResult(g))
+	writeField(&sb, "movelist", strings.Join(written, ""))
+	sb.WriteString("[/DhtmlXQ]\n")
+	return sb.String(), nil
+}
+
+// replay plays the moves from the initial position, writing each one with
+// the board as it stood before the move.
+func replay(moves []*models.Move, write func(game.MoveRecord, *game.Board) string) ([]string, error) {
+	written := make([]string, len(moves))
+	board := game.NewInitialBoard()
+	for i, move := range moves {
+		from, fromErr := game.ParsePosition(move.FromPosition)
+		to, toErr := game.ParsePosition(move.ToPosition)
+		if fromErr != nil || toErr != nil || board.At(from) == nil {
+			return nil, fmt.Errorf("%w: move %d from %q to %q", ErrInvalidMove, i+1, move.FromPosition, move.ToPosition)
+		}
+
+		record := game.MoveRecord{From: from, To: to, PieceType: move.PieceType}
+		written[i] = write(record, board)
+		board.Move(from, to)
+	}
+	return written, nil
+}
+
+// winner returns the color of the game's winner, if it has one.
+func winner(g *models.Game) (models.PlayerColor, bool) {
+	if g.WinnerColor != nil {
+		return *g.WinnerColor, true
+	}
+	if g.WinnerID == nil {
+		return "", false
+	}
+	if *g.WinnerID == g.RedPlayerID {
+		return models.PlayerColorRed, true
+	}
+	return models.PlayerColorBlack, true
+}
+
+// isDraw reports whether a game without a winner was drawn. Imported games
+// of unknown result are completed without a result type.
+func isDraw(g *models.Game) bool {
+	return g.Status == models.GameStatusCompleted && g.ResultType != nil
+}
+
+func pgnResult(g *models.Game) Result {
+	if color, ok := winner(g); ok {
+		if color == models.PlayerColorRed {
+			return ResultRedWins
+		}
+		return ResultBlackWins
+	}
+	if isDraw(g) {
+		return ResultDraw
+	}
+	return ResultUnknown
+}
+
+func dpxqResult(g *models.Game) string {
+	if color, ok := winner(g); ok {
+		if color == models.PlayerColorRed {
+			return "红胜"
+		}
+		return "黑胜"
+	}
+	if isDraw(g) {
+		return "和棋"
+	}
+	return "未知"
+}
+
+// termination describes how the game ended, or returns an empty string for a
+// game still in progress.
+func termination(g *models.Game) string {
+	if g.ResultType != nil {
+		return string(*g.ResultType)
+	}
+	if g.Status == models.GameStatusAbandoned {
+		return string(models.ResultTypeAbandonment)
+	}
+	return ""
+}
+
+// dpxqSquare writes a square as its file from red's left and its row from
+// black's back rank.
+func dpxqSquare(p game.Position) string {
+	return strconv.Itoa(p.File) + strconv.Itoa(9-p.Rank)
+}
+
+func writeTag(sb *strings.Builder, name, value string) {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	fmt.Fprintf(sb, "[%s \"%s\"]\n", name, value)
+}
+
+func writeField(sb *strings.Builder, name, value string) {
+	fmt.Fprintf(sb, "[DhtmlXQ_%s]%s[/DhtmlXQ_%s]\n", name, value, name)
+}
@@ -0,0 +1,151 @@
+// Package record provides unit tests for game record export.
+package record
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
+
+// fixtureGame returns a game between "alice" as red and "bob" as black
+// after three opening moves.
+func fixtureGame() (*models.Game, []*models.Move) {
+	g := &models.Game{
+		ID:            "game-1",
+		RedPlayerID:   "alice",
+		BlackPlayerID: "bob",
+		Status:        models.GameStatusActive,
+		CreatedAt:     time.Date(2024, 3, 9, 15, 4, 5, 0, time.UTC),
+	}
+	moves := []*models.Move{
+		{MoveNumber: 1, FromPosition: "h2", ToPosition: "e2", PieceType: models.PieceTypeCannon},
+		{MoveNumber: 2, FromPosition: "h9", ToPosition: "g7", PieceType: models.PieceTypeHorse},
+		{MoveNumber: 3, FromPosition: "b0", ToPosition: "c2", PieceType: models.PieceTypeHorse},
+	}
+	return g, moves
+}
+
+func finish(g *models.Game, status models.GameStatus, winnerID string, resultType models.ResultType) {
+	g.Status = status
+	if winnerID != "" {
+		g.WinnerID = &winnerID
+	}
+	g.ResultType = &resultType
+}
+
+func TestToPGN_MatchesFixture(t *testing.T) {
+	g, moves := fixtureGame()
+	finish(g, models.GameStatusCompleted, "alice", models.ResultTypeResignation)
+
+	want := `[Game "Chinese Chess"]
+[Date "2024.03.09"]
+[Red "alice"]
+[Black "bob"]
+[Result "1-0"]
+[Termination "resignation"]
+[Format "WXF"]
+
+1. C2.5 H8+7
+2. H8+7
+1-0
+`
+	got, err := ToPGN(g, moves)
+	if err != nil {
+		t.Fatalf("ToPGN failed: %v", err)
+	}
+	if got != want {
+		t.Errorf("Unexpected PGN:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestToPGN_Results(t *testing.T) {
+	tests := []struct {
+		name            string
+		status          models.GameStatus
+		winnerID        string
+		resultType      models.ResultType
+		wantResult      string
+		wantTermination string
+	}{
+		{"black wins", models.GameStatusCompleted, "bob", models.ResultTypeCheckmate, `[Result "0-1"]`, `[Termination "checkmate"]`},
+		{"draw", models.GameStatusCompleted, "", models.ResultTypeDraw, `[Result "1/2-1/2"]`, `[Termination "draw"]`},
+		{"abandoned with winner", models.GameStatusAbandoned, "alice", models.ResultTypeAbandonment, `[Result "1-0"]`, `[Termination "abandonment"]`},
+		{"abandoned without winner", models.GameStatusAbandoned, "", models.ResultTypeAbandonment, `[Result "*"]`, `[Termination "abandonment"]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, moves := fixtureGame()
+			finish(g, tt.status, tt.winnerID, tt.resultType)
+
+			got, err := ToPGN(g, moves)
+			if err != nil {
+				t.Fatalf("ToPGN failed: %v", err)
+			}
+			if !strings.Contains(got, tt.wantResult) || !strings.Contains(got, tt.wantTermination) {
+				t.Errorf("Expected %s and %s, got:\n%s", tt.wantResult, tt.wantTermination, got)
+			}
+		})
+	}
+}
+
+func TestToPGN_GameInProgress(t *testing.T) {
+	g, moves := fixtureGame()
+
+	got, err := ToPGN(g, moves)
+	if err != nil {
+		t.Fatalf("ToPGN failed: %v", err)
+	}
+	if !strings.Contains(got, `[Result "*"]`) || strings.Contains(got, "Termination") {
+		t.Errorf("Expected an unknown result without termination, got:\n%s", got)
+	}
+	if !strings.HasSuffix(got, "\n*\n") {
+		t.Errorf("Expected the move list to end with *, got:\n%s", got)
+	}
+}
+
+func TestToPGN_RejectsUnplayableMoves(t *testing.T) {
+	g, moves := fixtureGame()
+	moves[2].FromPosition = "e5"
+
+	if _, err := ToPGN(g, moves); !errors.Is(err, ErrInvalidMove) {
+		t.Errorf("Expected ErrInvalidMove, got %v", err)
+	}
+}
+
+func TestToDPXQ_MatchesFixture(t *testing.T) {
+	g, moves := fixtureGame()
+	finish(g, models.GameStatusCompleted, "", models.ResultTypeDraw)
+
+	want := `[DhtmlXQ]
+[DhtmlXQ_title]alice vs bob[/DhtmlXQ_title]
+[DhtmlXQ_red]alice[/DhtmlXQ_red]
+[DhtmlXQ_black]bob[/DhtmlXQ_black]
+[DhtmlXQ_date]2024-03-09[/DhtmlXQ_date]
+[DhtmlXQ_result]和棋[/DhtmlXQ_result]
+[DhtmlXQ_movelist]774770621927[/DhtmlXQ_movelist]
+[/DhtmlXQ]
+`
+	got, err := ToDPXQ(g, moves)
+	if err != nil {
+		t.Fatalf("ToDPXQ failed: %v", err)
+	}
+	if got != want {
+		t.Errorf("Unexpected DhtmlXQ record:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := map[string]Format{"": FormatPGN, "pgn": FormatPGN, "PGN": FormatPGN, "dpxq": FormatDPXQ}
+	for name, want := range tests {
+		if got, err := ParseFormat(name); err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := ParseFormat("xml"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Expected ErrUnknownFormat, got %v", err)
+	}
+}
@@ -1,7 +1,11 @@
 // Package game implements the Xiangqi (Chinese Chess) game logic.
 package game
 
-import "github.com/xiangqi/chinese-chess-backend/internal/models"
+import (
+	"github.com/rs/zerolog/log"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
 
 // RulesEngine provides methods for checking game rules and conditions.
 type RulesEngine struct{}
@@ -27,12 +31,16 @@ func (r *RulesEngine) IsFlyingGeneral(board *Board) bool {
 		return false
 	}
 
-	// Check if there are any pieces between the generals
-	minRank := redGeneral.Position.Rank + 1
+	// Check if there are any pieces between the generals, whichever side
+	// is lower on the board
+	minRank := redGeneral.Position.Rank
 	maxRank := blackGeneral.Position.Rank
+	if minRank > maxRank {
+		minRank, maxRank = maxRank, minRank
+	}
 	file := redGeneral.Position.File
 
-	for rank := minRank; rank < maxRank; rank++ {
+	for rank := minRank + 1; rank < maxRank; rank++ {
 		if board.HasPiece(Position{file, rank}) {
 			return false // There's a piece between, no flying general
 		}
@@ -103,28 +111,78 @@ func (r *RulesEngine) GetCheckingPieces(board *Board, color models.PlayerColor)
 		return nil
 	}
 
-	generalPos := general.Position
-	var checkingPieces []*Piece
-
-	// Get enemy color
-	enemyColor := models.PlayerColorBlack
-	if color == models.PlayerColorBlack {
-		enemyColor = models.PlayerColorRed
-	}
+	return r.Attackers(general.Position, color.Opposite(), board)
+}
 
-	enemyPieces := board.GetPieces(enemyColor)
-	for _, piece := range enemyPieces {
+// Attackers returns the pieces of the given color that could capture on pos.
+// Moves are checked against the movement rules only, so a pinned piece still
+// counts as an attacker.
+func (r *RulesEngine) Attackers(pos Position, color models.PlayerColor, board *Board) []*Piece {
+	var attackers []*Piece
+	for _, piece := range board.GetPieces(color) {
 		validator := GetValidator(piece.Type)
 		if validator == nil {
 			continue
 		}
 
-		if validator.IsValidMove(piece, generalPos, board) {
-			checkingPieces = append(checkingPieces, piece)
+		if validator.IsValidMove(piece, pos, board) {
+			attackers = append(attackers, piece)
+		}
+	}
+
+	return attackers
+}
+
+// IsDefended returns true if a piece of the given color could recapture on
+// pos. The square is treated as if an enemy piece had just captured there:
+// a friendly piece on pos is replaced by an enemy one of the same type, and
+// an empty square gets an enemy soldier. The replacement is made on a copy
+// of the board, so the board passed in is only read.
+func (r *RulesEngine) IsDefended(pos Position, color models.PlayerColor, board *Board) bool {
+	if !pos.IsValid() {
+		return false
+	}
+
+	occupant := board.At(pos)
+	if occupant != nil && occupant.Color != color {
+		return len(r.Attackers(pos, color, board)) > 0
+	}
+
+	captor := &Piece{Type: models.PieceTypeSoldier, Color: color.Opposite(), Position: pos}
+	if occupant != nil {
+		captor.Type = occupant.Type
+	}
+	scratch := board.Copy()
+	scratch.Place(captor)
+
+	return len(r.Attackers(pos, color, scratch)) > 0
+}
+
+// Threat is a piece that the opponent attacks and that no friendly piece
+// defends, together with the pieces attacking it.
+type Threat struct {
+	Target    *Piece
+	Attackers []*Piece
+}
+
+// GetThreats returns the pieces of the given color that are attacked and
+// undefended. The general is left out, since an attack on it is check.
+func (r *RulesEngine) GetThreats(board *Board, color models.PlayerColor) []Threat {
+	var threats []Threat
+	for _, piece := range board.GetPieces(color) {
+		if piece.Type == models.PieceTypeGeneral {
+			continue
+		}
+
+		attackers := r.Attackers(piece.Position, color.Opposite(), board)
+		if len(attackers) == 0 || r.IsDefended(piece.Position, color, board) {
+			continue
 		}
+
+		threats = append(threats, Threat{Target: piece, Attackers: attackers})
 	}
 
-	return checkingPieces
+	return threats
 }
 
 // HasLegalMoves returns true if the specified color has any legal moves.
@@ -139,20 +197,71 @@ func (r *RulesEngine) HasLegalMoves(board *Board, color models.PlayerColor) bool
 
 		validMoves := validator.GetValidMoves(piece, board)
 		for _, to := range validMoves {
-			// Simulate the move
-			testBoard := board.Copy()
-			testBoard.Move(piece.Position, to)
-
-			// Check if this move would leave the general in check
-			if !r.IsInCheck(testBoard, color) && !r.IsFlyingGeneral(testBoard) {
+			if r.isLegalAfterMove(board, piece, to) {
 				return true // Found at least one legal move
 			}
 		}
 	}
 
+	// Stalemate is a loss in Xiangqi, so a wrong "no moves" here would
+	// decide the game. Confirm it with an independent search first.
+	if move, found := r.crossCheckLegalMove(board, color); found {
+		log.Error().
+			Str("color", string(color)).
+			Str("from", move.From.Notation()).
+			Str("to", move.To.Notation()).
+			Str("board", board.ToFEN()).
+			Msg("Legal move search found no moves but cross-check found one")
+		return true
+	}
+
 	return false
 }
 
+// crossCheckLegalMove searches for a legal move without the in-place
+// make/unmake of isLegalAfterMove. Each candidate is played on a copy of the
+// board, and the attacks on the general and the flying general rule are
+// checked separately, so a bug in one path shows up as a disagreement.
+func (r *RulesEngine) crossCheckLegalMove(board *Board, color models.PlayerColor) (Move, bool) {
+	for _, piece := range board.GetPieces(color) {
+		validator := GetValidator(piece.Type)
+		if validator == nil {
+			continue
+		}
+
+		for _, to := range validator.GetValidMoves(piece, board) {
+			after := board.Copy()
+			after.Move(piece.Position, to)
+
+			general := after.GetGeneral(color)
+			if general == nil {
+				continue
+			}
+			if len(r.Attackers(general.Position, color.Opposite(), after)) > 0 {
+				continue
+			}
+			if r.IsFlyingGeneral(after) {
+				continue
+			}
+
+			return Move{From: piece.Position, To: to, PieceType: piece.Type}, true
+		}
+	}
+
+	return Move{}, false
+}
+
+// GetStatus returns whether the specified color is in check, checkmate or
+// stalemate. The legal move search runs at most once, so callers that need
+// all three should prefer this over calling each check separately.
+func (r *RulesEngine) GetStatus(board *Board, color models.PlayerColor) (inCheck, checkmate, stalemate bool) {
+	inCheck = r.IsInCheck(board, color)
+	if r.HasLegalMoves(board, color) {
+		return inCheck, false, false
+	}
+	return inCheck, inCheck, !inCheck
+}
+
 // IsCheckmate returns true if the specified color is in checkmate.
 // Checkmate occurs when:
 // 1. The general is in check
@@ -190,12 +299,8 @@ func (r *RulesEngine) GetLegalMoves(piece *Piece, board *Board) []Position {
 	var legalMoves []Position
 
 	for _, to := range validMoves {
-		// Simulate the move
-		testBoard := board.Copy()
-		testBoard.Move(piece.Position, to)
-
-		// Check if this move would leave the general in check or create flying general
-		if !r.IsInCheck(testBoard, piece.Color) && !r.IsFlyingGeneral(testBoard) {
+		// Filter moves that would leave the general in check or create flying general
+		if r.isLegalAfterMove(board, piece, to) {
 			legalMoves = append(legalMoves, to)
 		}
 	}
@@ -216,21 +321,8 @@ func (r *RulesEngine) IsValidMove(piece *Piece, to Position, board *Board) bool
 		return false
 	}
 
-	// Simulate the move
-	testBoard := board.Copy()
-	testBoard.Move(piece.Position, to)
-
-	// Check if this move would leave the general in check
-	if r.IsInCheck(testBoard, piece.Color) {
-		return false
-	}
-
-	// Check for flying general
-	if r.IsFlyingGeneral(testBoard) {
-		return false
-	}
-
-	return true
+	// Check if this move would leave the general in check or create flying general
+	return r.isLegalAfterMove(board, piece, to)
 }
 
 // CanCapture checks if a piece at 'from' can legally capture a piece at 'to'.
@@ -255,9 +347,34 @@ func (r *RulesEngine) CanCapture(from, to Position, board *Board) bool {
 
 // WouldExposeGeneral checks if a move would expose the general to check.
 func (r *RulesEngine) WouldExposeGeneral(piece *Piece, to Position, board *Board) bool {
-	testBoard := board.Copy()
-	testBoard.Move(piece.Position, to)
-	return r.IsInCheck(testBoard, piece.Color)
+	exposed := false
+	withMove(board, piece.Position, to, func() {
+		exposed = r.IsInCheck(board, piece.Color)
+	})
+	return exposed
+}
+
+// isLegalAfterMove reports whether moving the piece to 'to' leaves its own
+// general safe from check and from facing the enemy general.
+func (r *RulesEngine) isLegalAfterMove(board *Board, piece *Piece, to Position) bool {
+	legal := false
+	withMove(board, piece.Position, to, func() {
+		legal = !r.IsInCheck(board, piece.Color) && !r.IsFlyingGeneral(board)
+	})
+	return legal
+}
+
+// withMove makes a move on the board, calls fn and then restores the board.
+// Making and unmaking the move in place avoids copying the board for every
+// candidate move, which dominates the cost of legal move generation.
+// The board must not be read concurrently while fn runs.
+func withMove(board *Board, from, to Position, fn func()) {
+	captured := board.Move(from, to)
+	fn()
+	board.Move(to, from)
+	if captured != nil {
+		board.Place(captured)
+	}
 }
 
 // GetAllLegalMoves returns all legal moves for a color.
@@ -275,17 +392,12 @@ func (r *RulesEngine) GetAllLegalMoves(board *Board, color models.PlayerColor) [
 				capturedType = &ct
 			}
 
-			// Create move and check if it results in check
-			testBoard := board.Copy()
-			testBoard.Move(piece.Position, to)
-			isCheck := r.IsInCheck(testBoard, color.Opposite())
-
 			moves = append(moves, Move{
 				From:          piece.Position,
 				To:            to,
 				PieceType:     piece.Type,
 				CapturedPiece: capturedType,
-				IsCheck:       isCheck,
+				IsCheck:       r.givesCheck(board, piece, to),
 			})
 		}
 	}
@@ -293,6 +405,71 @@ func (r *RulesEngine) GetAllLegalMoves(board *Board, color models.PlayerColor) [
 	return moves
 }
 
+// GetCheckingMoves returns all legal moves for a color that give check to
+// the opponent.
+func (r *RulesEngine) GetCheckingMoves(board *Board, color models.PlayerColor) []Move {
+	var checks []Move
+	for _, move := range r.GetAllLegalMoves(board, color) {
+		if move.IsCheck {
+			checks = append(checks, move)
+		}
+	}
+	return checks
+}
+
+// HasSingleLegalMove returns the only legal move for a color when exactly
+// one exists. The search stops as soon as a second legal move is found.
+func (r *RulesEngine) HasSingleLegalMove(board *Board, color models.PlayerColor) (Move, bool) {
+	var (
+		only  *Piece
+		to    Position
+		found bool
+	)
+
+	for _, piece := range board.GetPieces(color) {
+		validator := GetValidator(piece.Type)
+		if validator == nil {
+			continue
+		}
+
+		for _, candidate := range validator.GetValidMoves(piece, board) {
+			if !r.isLegalAfterMove(board, piece, candidate) {
+				continue
+			}
+			if found {
+				return Move{}, false
+			}
+			only, to, found = piece, candidate, true
+		}
+	}
+
+	if !found {
+		return Move{}, false
+	}
+
+	move := Move{From: only.Position, To: to, PieceType: only.Type}
+	if captured := board.At(to); captured != nil {
+		ct := captured.Type
+		move.CapturedPiece = &ct
+	}
+	move.IsCheck = r.givesCheck(board, only, to)
+
+	return move, true
+}
+
+// givesCheck reports whether moving the piece to 'to' checks the opponent,
+// by the moved piece or a piece it uncovers, with the same rules as
+// IsInCheck. A move that would leave the generals facing is never legal,
+// since it exposes the mover's general as well, so facing generals cannot
+// make a legal move a check.
+func (r *RulesEngine) givesCheck(board *Board, piece *Piece, to Position) bool {
+	check := false
+	withMove(board, piece.Position, to, func() {
+		check = r.IsInCheck(board, piece.Color.Opposite())
+	})
+	return check
+}
+
 // Move represents a move in the game.
 type Move struct {
 	From          Position
@@ -301,11 +478,3 @@ type Move struct {
 	CapturedPiece *models.PieceType
 	IsCheck       bool
 }
-
-// Opposite returns the opposite color.
-func (c models.PlayerColor) Opposite() models.PlayerColor {
-	if c == models.PlayerColorRed {
-		return models.PlayerColorBlack
-	}
-	return models.PlayerColorRed
-}
@@ -59,6 +59,27 @@ func TestRulesEngine_FlyingGeneral_FacingWithoutPieceBetween(t *testing.T) {
 	}
 }
 
+func TestRulesEngine_FlyingGeneral_SwappedRanks(t *testing.T) {
+	board := NewBoard()
+
+	// Custom position with the red general above the black general
+	redGeneral := createPiece(models.PieceTypeGeneral, models.PlayerColorRed, 4, 8)
+	blackGeneral := createPiece(models.PieceTypeGeneral, models.PlayerColorBlack, 4, 1)
+	board.Place(redGeneral)
+	board.Place(blackGeneral)
+
+	rules := NewRulesEngine()
+
+	if !rules.IsFlyingGeneral(board) {
+		t.Error("Facing generals should trigger flying general regardless of rank order")
+	}
+
+	board.Place(createPiece(models.PieceTypeHorse, models.PlayerColorBlack, 4, 4))
+	if rules.IsFlyingGeneral(board) {
+		t.Error("Piece between generals on swapped ranks should block flying general")
+	}
+}
+
 // ========== Check Detection Tests ==========
 
 func TestRulesEngine_IsInCheck_NotInCheck(t *testing.T) {
@@ -150,7 +171,7 @@ func TestRulesEngine_IsCheckmate_BasicCheckmate(t *testing.T) {
 	// Red general in corner, blocked by own pieces, attacked by chariot
 	redGeneral := createPiece(models.PieceTypeGeneral, models.PlayerColorRed, 3, 0)
 	redAdvisor1 := createPiece(models.PieceTypeAdvisor, models.PlayerColorRed, 4, 0)
-	redAdvisor2 := createPiece(models.PieceTypeAdvisor, models.PlayerColorRed, 3, 1)
+	redAdvisor2 := createPiece(models.PieceTypeAdvisor, models.PlayerColorRed, 4, 1)
 	blackChariot := createPiece(models.PieceTypeChariot, models.PlayerColorBlack, 3, 5)
 	board.Place(redGeneral)
 	board.Place(redAdvisor1)
@@ -223,6 +244,63 @@ func TestRulesEngine_IsNotCheckmate_CanEvade(t *testing.T) {
 	}
 }
 
+// onlyEscapeBoard returns a board where red is in check and the general's
+// only legal move is sideways from e0 to f0.
+func onlyEscapeBoard() *Board {
+	board := NewBoard()
+	board.Place(createPiece(models.PieceTypeGeneral, models.PlayerColorRed, 4, 0))
+	board.Place(createPiece(models.PieceTypeChariot, models.PlayerColorBlack, 4, 5)) // Gives check
+	board.Place(createPiece(models.PieceTypeChariot, models.PlayerColorBlack, 3, 7)) // Covers d0
+	board.Place(createPiece(models.PieceTypeGeneral, models.PlayerColorBlack, 3, 9))
+	return board
+}
+
+func TestRulesEngine_OnlyEscapeIsNotCheckmate(t *testing.T) {
+	board := onlyEscapeBoard()
+	rules := NewRulesEngine()
+
+	if !rules.IsInCheck(board, models.PlayerColorRed) {
+		t.Fatal("Red should be in check")
+	}
+
+	move, single := rules.HasSingleLegalMove(board, models.PlayerColorRed)
+	if !single {
+		t.Fatal("Red should have exactly one legal move")
+	}
+	if move.To != (Position{5, 0}) {
+		t.Errorf("Expected the escape to f0, got %s", move.To.Notation())
+	}
+
+	inCheck, checkmate, stalemate := rules.GetStatus(board, models.PlayerColorRed)
+	if !inCheck || checkmate || stalemate {
+		t.Errorf("Expected check only, got check=%v mate=%v stalemate=%v", inCheck, checkmate, stalemate)
+	}
+}
+
+func TestRulesEngine_CrossCheckLegalMove_AgreesWithSearch(t *testing.T) {
+	rules := NewRulesEngine()
+
+	// The cross-check finds the same single escape
+	move, found := rules.crossCheckLegalMove(onlyEscapeBoard(), models.PlayerColorRed)
+	if !found {
+		t.Fatal("Cross-check should find the escape move")
+	}
+	if move.To != (Position{5, 0}) {
+		t.Errorf("Expected the escape to f0, got %s", move.To.Notation())
+	}
+
+	// Covering f0 as well makes it mate, and both searches agree
+	board := onlyEscapeBoard()
+	board.Place(createPiece(models.PieceTypeChariot, models.PlayerColorBlack, 5, 7))
+
+	if !rules.IsCheckmate(board, models.PlayerColorRed) {
+		t.Error("Red should be checkmated once f0 is covered")
+	}
+	if move, found := rules.crossCheckLegalMove(board, models.PlayerColorRed); found {
+		t.Errorf("Cross-check should find no move, got %s-%s", move.From.Notation(), move.To.Notation())
+	}
+}
+
 // ========== Stalemate Tests ==========
 
 func TestRulesEngine_IsStalemate_NotStalemate(t *testing.T) {
@@ -520,3 +598,427 @@ func TestRulesEngine_GetCheckingPieces_Double(t *testing.T) {
 		t.Errorf("Expected 2 checking pieces, got %d", len(checkingPieces))
 	}
 }
+
+// ========== Attackers / IsDefended Tests ==========
+
+func TestRulesEngine_Attackers(t *testing.T) {
+	board := NewBoard()
+	board.Place(createPiece(models.PieceTypeChariot, models.PlayerColorRed, 0, 4))
+	board.Place(createPiece(models.PieceTypeHorse, models.PlayerColorRed, 3, 2))
+	board.Place(createPiece(models.PieceTypeChariot, models.PlayerColorBlack, 8, 4))
+	target := createPiece(models.PieceTypeCannon, models.PlayerColorBlack, 4, 4)
+	board.Place(target)
+
+	rules := NewRulesEngine()
+
+	// The red chariot along the rank and the red horse (d2 -> e4) both attack e4
+	if attackers := rules.Attackers(target.Position, models.PlayerColorRed, board); len(attackers) != 2 {
+		t.Errorf("Expected 2 red attackers on e4, got %d", len(attackers))
+	}
+	// Black pieces cannot capture their own cannon
+	if attackers := rules.Attackers(target.Position, models.PlayerColorBlack, board); len(attackers) != 0 {
+		t.Errorf("Expected no black attackers on e4, got %d", len(attackers))
+	}
+}
+
+func TestRulesEngine_IsDefended_DefendedPiece(t *testing.T) {
+	board := NewBoard()
+	horse := createPiece(models.PieceTypeHorse, models.PlayerColorRed, 4, 4)
+	board.Place(horse)
+	board.Place(createPiece(models.PieceTypeChariot, models.PlayerColorRed, 4, 0))
+
+	rules := NewRulesEngine()
+	before := board.String()
+
+	if !rules.IsDefended(horse.Position, models.PlayerColorRed, board) {
+		t.Error("Horse on e4 should be defended by the chariot on e0")
+	}
+	if board.String() != before || horse.Color != models.PlayerColorRed {
+		t.Error("IsDefended should leave the board unchanged")
+	}
+}
+
+func TestRulesEngine_IsDefended_UndefendedPiece(t *testing.T) {
+	board := NewBoard()
+	horse := createPiece(models.PieceTypeHorse, models.PlayerColorRed, 4, 4)
+	board.Place(horse)
+	board.Place(createPiece(models.PieceTypeChariot, models.PlayerColorRed, 3, 0))
+	board.Place(createPiece(models.PieceTypeChariot, models.PlayerColorBlack, 4, 9))
+
+	rules := NewRulesEngine()
+
+	if rules.IsDefended(horse.Position, models.PlayerColorRed, board) {
+		t.Error("Horse on e4 should not be defended")
+	}
+}
+
+func TestRulesEngine_IsDefended_CannonNeedsScreen(t *testing.T) {
+	board := NewBoard()
+	soldier := createPiece(models.PieceTypeSoldier, models.PlayerColorRed, 4, 5)
+	board.Place(soldier)
+	board.Place(createPiece(models.PieceTypeCannon, models.PlayerColorRed, 4, 1))
+
+	rules := NewRulesEngine()
+
+	if rules.IsDefended(soldier.Position, models.PlayerColorRed, board) {
+		t.Error("Cannon without a screen should not defend e5")
+	}
+
+	board.Place(createPiece(models.PieceTypeAdvisor, models.PlayerColorRed, 4, 3))
+	if !rules.IsDefended(soldier.Position, models.PlayerColorRed, board) {
+		t.Error("Cannon with a screen should defend e5")
+	}
+}
+
+func TestRulesEngine_IsDefended_EmptySquare(t *testing.T) {
+	board := NewBoard()
+	board.Place(createPiece(models.PieceTypeChariot, models.PlayerColorBlack, 0, 6))
+
+	rules := NewRulesEngine()
+	square := Position{5, 6}
+
+	if !rules.IsDefended(square, models.PlayerColorBlack, board) {
+		t.Error("Empty f6 should be defended by the chariot on a6")
+	}
+	if board.HasPiece(square) {
+		t.Error("IsDefended should not leave a piece on an empty square")
+	}
+	if rules.IsDefended(Position{5, 5}, models.PlayerColorBlack, board) {
+		t.Error("Empty f5 should not be defended")
+	}
+}
+
+// ========== GetCheckingMoves Tests ==========
+
+func TestRulesEngine_GetCheckingMoves(t *testing.T) {
+	board := NewBoard()
+	board.Place(createPiece(models.PieceTypeGeneral, models.PlayerColorRed, 3, 0))
+	board.Place(createPiece(models.PieceTypeGeneral, models.PlayerColorBlack, 4, 9))
+	board.Place(createPiece(models.PieceTypeChariot, models.PlayerColorRed, 0, 5))
+
+	rules := NewRulesEngine()
+	before := board.String()
+	checks := rules.GetCheckingMoves(board, models.PlayerColorRed)
+
+	// The chariot checks from e5 along the file and from a9 along the back rank
+	expected := map[Position]bool{{4, 5}: true, {0, 9}: true}
+	if len(checks) != len(expected) {
+		t.Fatalf("Expected %d checking moves, got %d: %v", len(expected), len(checks), checks)
+	}
+	for _, move := range checks {
+		if !move.IsCheck || !expected[move.To] {
+			t.Errorf("Unexpected checking move %s-%s", move.From.Notation(), move.To.Notation())
+		}
+	}
+	if board.String() != before {
+		t.Error("GetCheckingMoves should leave the board unchanged")
+	}
+
+	// Quiet moves such as a5-a6 are excluded
+	for _, move := range checks {
+		if move.To == (Position{0, 6}) {
+			t.Error("Non-checking move a5-a6 should be excluded")
+		}
+	}
+}
+
+// legalMovesFrom returns color's legal moves from a square, by destination.
+func legalMovesFrom(board *Board, color models.PlayerColor, from Position) map[Position]Move {
+	moves := make(map[Position]Move)
+	for _, move := range NewRulesEngine().GetAllLegalMoves(board, color) {
+		if move.From == from {
+			moves[move.To] = move
+		}
+	}
+	return moves
+}
+
+func TestRulesEngine_GetAllLegalMoves_FlagsDiscoveredCheck(t *testing.T) {
+	// The horse on e4 stands between red's chariot and black's general
+	board := NewBoard()
+	board.Place(createPiece(models.PieceTypeGeneral, models.PlayerColorRed, 3, 0))
+	board.Place(createPiece(models.PieceTypeGeneral, models.PlayerColorBlack, 4, 9))
+	board.Place(createPiece(models.PieceTypeChariot, models.PlayerColorRed, 4, 1))
+	board.Place(createPiece(models.PieceTypeHorse, models.PlayerColorRed, 4, 4))
+
+	moves := legalMovesFrom(board, models.PlayerColorRed, Position{4, 4})
+	if len(moves) == 0 {
+		t.Fatal("Expected the horse to have legal moves")
+	}
+	for _, move := range moves {
+		if !move.IsCheck {
+			t.Errorf("Horse move e4-%s uncovers the chariot and should be flagged as check", move.To.Notation())
+		}
+	}
+}
+
+func TestRulesEngine_GetAllLegalMoves_FlagsCannonScreens(t *testing.T) {
+	// The cannon on a2 can line up behind the horse on e5
+	board := NewBoard()
+	board.Place(createPiece(models.PieceTypeGeneral, models.PlayerColorRed, 3, 0))
+	board.Place(createPiece(models.PieceTypeGeneral, models.PlayerColorBlack, 4, 9))
+	board.Place(createPiece(models.PieceTypeCannon, models.PlayerColorRed, 0, 2))
+	board.Place(createPiece(models.PieceTypeHorse, models.PlayerColorRed, 4, 5))
+
+	moves := legalMovesFrom(board, models.PlayerColorRed, Position{0, 2})
+	if !moves[Position{4, 2}].IsCheck {
+		t.Error("Cannon a2-e2 behind the horse should be flagged as check")
+	}
+	if moves[Position{3, 2}].IsCheck {
+		t.Error("Cannon a2-d2 should not be flagged as check")
+	}
+
+	// A soldier stepping in front of a cannon on the general's file makes
+	// the screen
+	board = NewBoard()
+	board.Place(createPiece(models.PieceTypeGeneral, models.PlayerColorRed, 3, 0))
+	board.Place(createPiece(models.PieceTypeGeneral, models.PlayerColorBlack, 4, 9))
+	board.Place(createPiece(models.PieceTypeCannon, models.PlayerColorRed, 4, 2))
+	board.Place(createPiece(models.PieceTypeSoldier, models.PlayerColorRed, 3, 5))
+
+	moves = legalMovesFrom(board, models.PlayerColorRed, Position{3, 5})
+	if !moves[Position{4, 5}].IsCheck {
+		t.Error("Soldier d5-e5 screening the cannon should be flagged as check")
+	}
+	if moves[Position{3, 6}].IsCheck {
+		t.Error("Soldier d5-d6 should not be flagged as check")
+	}
+}
+
+func TestRulesEngine_GetAllLegalMoves_NeverOpensGeneralsFile(t *testing.T) {
+	// The chariot is the only piece between the generals. Leaving the file
+	// would expose red's own general, so none of those moves is legal to be
+	// flagged as check.
+	board := NewBoard()
+	board.Place(createPiece(models.PieceTypeGeneral, models.PlayerColorRed, 4, 0))
+	board.Place(createPiece(models.PieceTypeGeneral, models.PlayerColorBlack, 4, 9))
+	board.Place(createPiece(models.PieceTypeChariot, models.PlayerColorRed, 4, 4))
+
+	moves := legalMovesFrom(board, models.PlayerColorRed, Position{4, 4})
+	if len(moves) == 0 {
+		t.Fatal("Expected the chariot to have moves along the file")
+	}
+	for _, move := range moves {
+		if move.To.File != 4 {
+			t.Errorf("Chariot e4-%s opens the generals' file and should not be legal", move.To.Notation())
+		}
+		// Short of taking the general, the chariot checks from anywhere on the
+		// open file
+		if move.CapturedPiece == nil && !move.IsCheck {
+			t.Errorf("Chariot e4-%s stays on the open file and should be flagged as check", move.To.Notation())
+		}
+	}
+}
+
+func TestRulesEngine_GetCheckingMoves_None(t *testing.T) {
+	board := NewInitialBoard()
+	rules := NewRulesEngine()
+
+	if checks := rules.GetCheckingMoves(board, models.PlayerColorRed); len(checks) != 0 {
+		t.Errorf("Expected no checking moves from the initial position, got %d", len(checks))
+	}
+}
+
+// ========== HasSingleLegalMove Tests ==========
+
+func TestRulesEngine_HasSingleLegalMove(t *testing.T) {
+	board := NewBoard()
+	board.Place(createPiece(models.PieceTypeGeneral, models.PlayerColorBlack, 4, 9))
+	// The red general covers the f-file and the chariot the d-file, leaving
+	// e9-e8 as black's only move
+	board.Place(createPiece(models.PieceTypeGeneral, models.PlayerColorRed, 5, 0))
+	board.Place(createPiece(models.PieceTypeChariot, models.PlayerColorRed, 3, 1))
+
+	rules := NewRulesEngine()
+	before := board.String()
+	move, ok := rules.HasSingleLegalMove(board, models.PlayerColorBlack)
+	if !ok {
+		t.Fatal("Expected a single legal move")
+	}
+	if move.From != (Position{4, 9}) || move.To != (Position{4, 8}) || move.PieceType != models.PieceTypeGeneral {
+		t.Errorf("Expected forced move e9-e8, got %s-%s", move.From.Notation(), move.To.Notation())
+	}
+	if board.String() != before {
+		t.Error("HasSingleLegalMove should leave the board unchanged")
+	}
+}
+
+func TestRulesEngine_HasSingleLegalMove_Multiple(t *testing.T) {
+	board := NewBoard()
+	board.Place(createPiece(models.PieceTypeGeneral, models.PlayerColorBlack, 4, 9))
+	board.Place(createPiece(models.PieceTypeGeneral, models.PlayerColorRed, 5, 0))
+
+	rules := NewRulesEngine()
+	if move, ok := rules.HasSingleLegalMove(board, models.PlayerColorBlack); ok {
+		t.Errorf("Expected several legal moves, got forced move %s-%s", move.From.Notation(), move.To.Notation())
+	}
+	if _, ok := rules.HasSingleLegalMove(NewInitialBoard(), models.PlayerColorRed); ok {
+		t.Error("Expected no forced move from the initial position")
+	}
+}
+
+// ========== GetThreats Tests ==========
+
+func TestRulesEngine_GetThreats_NewThreat(t *testing.T) {
+	board := NewBoard()
+	board.Place(createPiece(models.PieceTypeGeneral, models.PlayerColorRed, 4, 0))
+	board.Place(createPiece(models.PieceTypeGeneral, models.PlayerColorBlack, 3, 9))
+	board.Place(createPiece(models.PieceTypeHorse, models.PlayerColorRed, 0, 4))
+	board.Place(createPiece(models.PieceTypeChariot, models.PlayerColorBlack, 8, 7))
+
+	rules := NewRulesEngine()
+	if threats := rules.GetThreats(board, models.PlayerColorRed); len(threats) != 0 {
+		t.Fatalf("Expected no threats before the chariot moves, got %d", len(threats))
+	}
+
+	// The black chariot swings to rank 4, attacking the undefended horse
+	board.Move(Position{8, 7}, Position{8, 4})
+	threats := rules.GetThreats(board, models.PlayerColorRed)
+	if len(threats) != 1 {
+		t.Fatalf("Expected 1 threat, got %d", len(threats))
+	}
+	if threats[0].Target.Position != (Position{0, 4}) {
+		t.Errorf("Expected the horse on a4 to be threatened, got %s", threats[0].Target.Position.Notation())
+	}
+	if len(threats[0].Attackers) != 1 || threats[0].Attackers[0].Position != (Position{8, 4}) {
+		t.Errorf("Expected the chariot on i4 as the attacker, got %v", threats[0].Attackers)
+	}
+}
+
+func TestRulesEngine_GetThreats_DefendedPieceIgnored(t *testing.T) {
+	board := NewBoard()
+	board.Place(createPiece(models.PieceTypeGeneral, models.PlayerColorRed, 4, 0))
+	board.Place(createPiece(models.PieceTypeGeneral, models.PlayerColorBlack, 3, 9))
+	board.Place(createPiece(models.PieceTypeHorse, models.PlayerColorRed, 0, 4))
+	board.Place(createPiece(models.PieceTypeChariot, models.PlayerColorRed, 0, 0))
+	board.Place(createPiece(models.PieceTypeChariot, models.PlayerColorBlack, 8, 4))
+
+	rules := NewRulesEngine()
+	if threats := rules.GetThreats(board, models.PlayerColorRed); len(threats) != 0 {
+		t.Errorf("Expected the defended horse not to be reported, got %d threats", len(threats))
+	}
+}
+
+// ========== Dense Position Tests ==========
+
+// newDenseMateBoard returns the initial position with a red horse on d7 and a
+// red chariot on e7 giving double check. Every black piece is still on the
+// board, so proving mate requires trying every black move.
+func newDenseMateBoard() *Board {
+	board := NewInitialBoard()
+	board.Move(Position{1, 0}, Position{3, 7}) // Red horse b0 -> d7
+	board.Move(Position{0, 0}, Position{4, 7}) // Red chariot a0 -> e7
+	return board
+}
+
+func TestRulesEngine_IsCheckmate_DensePosition(t *testing.T) {
+	board := newDenseMateBoard()
+	rules := NewRulesEngine()
+
+	if !rules.IsCheckmate(board, models.PlayerColorBlack) {
+		t.Errorf("Black should be checkmated by double check:\n%s", board)
+	}
+	if rules.HasLegalMoves(board, models.PlayerColorBlack) {
+		t.Error("Black should have no legal moves")
+	}
+
+	// Without the chariot the horse check can be escaped
+	board.Remove(Position{4, 7})
+	if rules.IsCheckmate(board, models.PlayerColorBlack) {
+		t.Error("Single horse check should not be checkmate")
+	}
+}
+
+func TestRulesEngine_LegalMoveSearch_LeavesBoardUnchanged(t *testing.T) {
+	board := newDenseMateBoard()
+	before := board.String()
+	rules := NewRulesEngine()
+
+	rules.HasLegalMoves(board, models.PlayerColorBlack)
+	rules.GetAllLegalMoves(board, models.PlayerColorRed)
+	rules.GetAllLegalMoves(board, models.PlayerColorBlack)
+
+	if after := board.String(); after != before {
+		t.Errorf("Legal move search modified the board:\nbefore:\n%s\nafter:\n%s", before, after)
+	}
+	for _, piece := range append(board.GetPieces(models.PlayerColorRed), board.GetPieces(models.PlayerColorBlack)...) {
+		if board.At(piece.Position) != piece {
+			t.Errorf("Piece %s at %s has a stale position", piece.Type, piece.Position.Notation())
+		}
+	}
+}
+
+// ========== Benchmarks ==========
+
+func BenchmarkRulesEngine_IsCheckmate_DenseMate(b *testing.B) {
+	board := newDenseMateBoard()
+	rules := NewRulesEngine()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rules.IsCheckmate(board, models.PlayerColorBlack)
+	}
+}
+
+func BenchmarkRulesEngine_GetAllLegalMoves_Initial(b *testing.B) {
+	board := NewInitialBoard()
+	rules := NewRulesEngine()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rules.GetAllLegalMoves(board, models.PlayerColorRed)
+	}
+}
+
+// ========== Position Key Tests ==========
+
+func TestRulesEngine_PositionKey(t *testing.T) {
+	rules := NewRulesEngine()
+	initial := NewInitialBoard()
+
+	if rules.PositionKey(initial, models.PlayerColorRed) != rules.PositionKey(NewInitialBoard(), models.PlayerColorRed) {
+		t.Error("Equal positions should have equal keys")
+	}
+	if rules.PositionKey(initial, models.PlayerColorRed) == rules.PositionKey(initial, models.PlayerColorBlack) {
+		t.Error("Key should include the side to move")
+	}
+
+	moved := NewInitialBoard()
+	moved.Move(Position{1, 0}, Position{2, 2})
+	if rules.PositionKey(initial, models.PlayerColorRed) == rules.PositionKey(moved, models.PlayerColorRed) {
+		t.Error("Key should include every piece's square")
+	}
+
+	// Same squares, different piece: swap a red horse for a red chariot
+	swapped := NewInitialBoard()
+	swapped.Remove(Position{1, 0})
+	swapped.Place(&Piece{Type: models.PieceTypeChariot, Color: models.PlayerColorRed, Position: Position{1, 0}})
+	if rules.PositionKey(initial, models.PlayerColorRed) == rules.PositionKey(swapped, models.PlayerColorRed) {
+		t.Error("Key should include piece types")
+	}
+
+	// Same squares and type, different color
+	recolored := NewInitialBoard()
+	recolored.At(Position{0, 3}).Color = models.PlayerColorBlack
+	if rules.PositionKey(initial, models.PlayerColorRed) == rules.PositionKey(recolored, models.PlayerColorRed) {
+		t.Error("Key should include piece colors")
+	}
+}
+
+func TestRulesEngine_PositionKey_Transposition(t *testing.T) {
+	rules := NewRulesEngine()
+
+	first := NewInitialBoard()
+	first.Move(Position{1, 0}, Position{2, 2})
+	first.Move(Position{7, 0}, Position{6, 2})
+
+	second := NewInitialBoard()
+	second.Move(Position{7, 0}, Position{6, 2})
+	second.Move(Position{1, 0}, Position{2, 2})
+
+	if rules.PositionKey(first, models.PlayerColorRed) != rules.PositionKey(second, models.PlayerColorRed) {
+		t.Error("Positions reached by different move orders should have equal keys")
+	}
+}
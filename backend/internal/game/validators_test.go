@@ -2,6 +2,7 @@
 package game
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/xiangqi/chinese-chess-backend/internal/models"
@@ -16,6 +17,16 @@ func createPiece(pieceType models.PieceType, color models.PlayerColor, file, ran
 	}
 }
 
+// containsPosition reports whether positions includes pos.
+func containsPosition(positions []Position, pos Position) bool {
+	for _, p := range positions {
+		if p == pos {
+			return true
+		}
+	}
+	return false
+}
+
 // ========== General Validator Tests ==========
 
 func TestGeneralValidator_ValidMoves(t *testing.T) {
@@ -259,10 +270,9 @@ func TestChariotValidator_ValidMoves(t *testing.T) {
 	validator := &ChariotValidator{}
 	moves := validator.GetValidMoves(chariot, board)
 
-	// From center, chariot can move to 17 positions (8 vertical + 9 horizontal - 1)
-	// Actually: 4 up + 4 down + 4 left + 4 right = 16 positions
-	if len(moves) != 16 {
-		t.Errorf("Expected 16 moves from center, got %d", len(moves))
+	// From e4, chariot can move 5 up + 4 down + 4 left + 4 right = 17 positions
+	if len(moves) != 17 {
+		t.Errorf("Expected 17 moves from center, got %d", len(moves))
 	}
 }
 
@@ -392,6 +402,72 @@ func TestCannonValidator_BlockedForNonCapture(t *testing.T) {
 	}
 }
 
+func TestCannonValidator_ScreenCounting(t *testing.T) {
+	type placement struct {
+		color    models.PlayerColor
+		position Position
+	}
+	red, black := models.PlayerColorRed, models.PlayerColorBlack
+
+	testCases := []struct {
+		name     string
+		from     Position
+		to       Position
+		others   []placement
+		expected bool
+	}{
+		{"capture over one screen", Position{0, 0}, Position{0, 6},
+			[]placement{{red, Position{0, 3}}, {black, Position{0, 6}}}, true},
+		{"capture over an enemy screen", Position{0, 0}, Position{0, 6},
+			[]placement{{black, Position{0, 3}}, {black, Position{0, 6}}}, true},
+		{"capture with the screen adjacent to the target", Position{0, 0}, Position{0, 6},
+			[]placement{{red, Position{0, 5}}, {black, Position{0, 6}}}, true},
+		{"capture with zero screens", Position{0, 0}, Position{0, 6},
+			[]placement{{black, Position{0, 6}}}, false},
+		{"capture adjacent with zero screens", Position{0, 0}, Position{0, 1},
+			[]placement{{black, Position{0, 1}}}, false},
+		{"capture with two screens", Position{0, 0}, Position{0, 6},
+			[]placement{{red, Position{0, 2}}, {black, Position{0, 4}}, {black, Position{0, 6}}}, false},
+		{"capture own piece over a screen", Position{0, 0}, Position{0, 6},
+			[]placement{{red, Position{0, 3}}, {red, Position{0, 6}}}, false},
+		{"quiet move with a screen in the way", Position{0, 0}, Position{0, 6},
+			[]placement{{red, Position{0, 3}}}, false},
+		{"quiet move to the square past a screen", Position{0, 0}, Position{0, 4},
+			[]placement{{black, Position{0, 3}}}, false},
+		{"quiet move with a clear path", Position{0, 0}, Position{0, 6},
+			[]placement{{red, Position{0, 7}}}, true},
+		{"horizontal capture across the board", Position{0, 4}, Position{8, 4},
+			[]placement{{red, Position{4, 4}}, {black, Position{8, 4}}}, true},
+		{"horizontal capture back across the board", Position{8, 4}, Position{0, 4},
+			[]placement{{black, Position{1, 4}}, {black, Position{0, 4}}}, true},
+		{"horizontal capture across the board with two screens", Position{0, 4}, Position{8, 4},
+			[]placement{{red, Position{2, 4}}, {red, Position{6, 4}}, {black, Position{8, 4}}}, false},
+		{"downward capture over one screen", Position{4, 9}, Position{4, 0},
+			[]placement{{black, Position{4, 5}}, {black, Position{4, 0}}}, true},
+	}
+
+	validator := &CannonValidator{}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			board := NewBoard()
+			cannon := createPiece(models.PieceTypeCannon, red, tc.from.File, tc.from.Rank)
+			board.Place(cannon)
+			for _, other := range tc.others {
+				board.Place(createPiece(models.PieceTypeSoldier, other.color, other.position.File, other.position.Rank))
+			}
+
+			if got := validator.IsValidMove(cannon, tc.to, board); got != tc.expected {
+				t.Errorf("IsValidMove(%s-%s) = %v, want %v", tc.from.Notation(), tc.to.Notation(), got, tc.expected)
+			}
+
+			// Move generation must agree with validation
+			if got := containsPosition(validator.GetValidMoves(cannon, board), tc.to); got != tc.expected {
+				t.Errorf("GetValidMoves includes %s = %v, want %v", tc.to.Notation(), got, tc.expected)
+			}
+		})
+	}
+}
+
 // ========== Soldier Validator Tests ==========
 
 func TestSoldierValidator_BeforeRiver(t *testing.T) {
@@ -461,6 +537,56 @@ func TestSoldierValidator_BlackSoldierDirection(t *testing.T) {
 	}
 }
 
+func TestSoldierValidator_EdgeSquares(t *testing.T) {
+	testCases := []struct {
+		name     string
+		color    models.PlayerColor
+		from     Position
+		expected []Position
+	}{
+		{"red crossed on file a", models.PlayerColorRed, Position{0, 5}, []Position{{0, 6}, {1, 5}}},
+		{"red crossed on file i", models.PlayerColorRed, Position{8, 5}, []Position{{8, 6}, {7, 5}}},
+		{"red on black back rank", models.PlayerColorRed, Position{4, 9}, []Position{{3, 9}, {5, 9}}},
+		{"red in corner a9", models.PlayerColorRed, Position{0, 9}, []Position{{1, 9}}},
+		{"red in corner i9", models.PlayerColorRed, Position{8, 9}, []Position{{7, 9}}},
+		{"black crossed on file a", models.PlayerColorBlack, Position{0, 4}, []Position{{0, 3}, {1, 4}}},
+		{"black crossed on file i", models.PlayerColorBlack, Position{8, 4}, []Position{{8, 3}, {7, 4}}},
+		{"black on red back rank", models.PlayerColorBlack, Position{4, 0}, []Position{{3, 0}, {5, 0}}},
+		{"black in corner a0", models.PlayerColorBlack, Position{0, 0}, []Position{{1, 0}}},
+		{"black in corner i0", models.PlayerColorBlack, Position{8, 0}, []Position{{7, 0}}},
+	}
+
+	validator := &SoldierValidator{}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			board := NewBoard()
+			soldier := createPiece(models.PieceTypeSoldier, tc.color, tc.from.File, tc.from.Rank)
+			board.Place(soldier)
+
+			moves := validator.GetValidMoves(soldier, board)
+			if len(moves) != len(tc.expected) {
+				t.Fatalf("Expected %d moves from %s, got %v", len(tc.expected), tc.from.Notation(), moves)
+			}
+			for _, to := range tc.expected {
+				if !containsPosition(moves, to) {
+					t.Errorf("Expected move %s-%s, got %v", tc.from.Notation(), to.Notation(), moves)
+				}
+			}
+
+			// Off-board destinations are rejected rather than wrapped
+			forward := 1
+			if tc.color == models.PlayerColorBlack {
+				forward = -1
+			}
+			for _, to := range []Position{tc.from.Offset(-1, 0), tc.from.Offset(1, 0), tc.from.Offset(0, forward)} {
+				if !to.IsValid() && validator.IsValidMove(soldier, to, board) {
+					t.Errorf("Soldier on %s should not move off the board to %v", tc.from.Notation(), to)
+				}
+			}
+		})
+	}
+}
+
 // ========== GetValidator Factory Tests ==========
 
 func TestGetValidator_ReturnsCorrectType(t *testing.T) {
@@ -484,3 +610,101 @@ func TestGetValidator_ReturnsCorrectType(t *testing.T) {
 		}
 	}
 }
+
+// ========== Edge Coverage Tests ==========
+
+// allPieceTypes lists every piece type for table-driven validator tests.
+var allPieceTypes = []models.PieceType{
+	models.PieceTypeGeneral,
+	models.PieceTypeAdvisor,
+	models.PieceTypeElephant,
+	models.PieceTypeHorse,
+	models.PieceTypeChariot,
+	models.PieceTypeCannon,
+	models.PieceTypeSoldier,
+}
+
+// edgeTestBoards returns boards to place a piece on: an empty board and a
+// board with enemy pieces along every edge so captures near the edges are
+// generated too. Squares already occupied are skipped by the caller.
+func edgeTestBoards(color models.PlayerColor) map[string]*Board {
+	edges := NewBoard()
+	enemy := color.Opposite()
+	for file := 0; file < FileCount; file += 2 {
+		edges.Place(createPiece(models.PieceTypeChariot, enemy, file, 0))
+		edges.Place(createPiece(models.PieceTypeChariot, enemy, file, RankCount-1))
+	}
+	for rank := 2; rank < RankCount-1; rank += 3 {
+		edges.Place(createPiece(models.PieceTypeChariot, enemy, 0, rank))
+		edges.Place(createPiece(models.PieceTypeChariot, enemy, FileCount-1, rank))
+	}
+
+	return map[string]*Board{
+		"empty": NewBoard(),
+		"edges": edges,
+	}
+}
+
+func TestValidators_GeneratedMovesStayOnBoard(t *testing.T) {
+	for _, pieceType := range allPieceTypes {
+		for _, color := range []models.PlayerColor{models.PlayerColorRed, models.PlayerColorBlack} {
+			for name, board := range edgeTestBoards(color) {
+				t.Run(fmt.Sprintf("%s/%s/%s", color, pieceType, name), func(t *testing.T) {
+					validator := GetValidator(pieceType)
+
+					for rank := 0; rank < RankCount; rank++ {
+						for file := 0; file < FileCount; file++ {
+							from := Position{file, rank}
+							if board.HasPiece(from) {
+								continue
+							}
+
+							piece := createPiece(pieceType, color, file, rank)
+							board.Place(piece)
+
+							seen := make(map[Position]bool)
+							for _, to := range validator.GetValidMoves(piece, board) {
+								if !to.IsValid() {
+									t.Errorf("Move %v from %s is off the board", to, from.Notation())
+									continue
+								}
+								if to == from {
+									t.Errorf("Move from %s to itself", from.Notation())
+								}
+								if seen[to] {
+									t.Errorf("Duplicate move %s-%s", from.Notation(), to.Notation())
+								}
+								seen[to] = true
+								if !validator.IsValidMove(piece, to, board) {
+									t.Errorf("Generated move %s-%s breaks the movement rule", from.Notation(), to.Notation())
+								}
+							}
+
+							// Every move the rule allows must be generated
+							for r := 0; r < RankCount; r++ {
+								for f := 0; f < FileCount; f++ {
+									to := Position{f, r}
+									if validator.IsValidMove(piece, to, board) && !seen[to] {
+										t.Errorf("Valid move %s-%s was not generated", from.Notation(), to.Notation())
+									}
+								}
+							}
+
+							// Off-board targets near the edges are never valid
+							for df := -2; df <= 2; df++ {
+								for dr := -2; dr <= 2; dr++ {
+									to := from.Offset(df, dr)
+									if !to.IsValid() && validator.IsValidMove(piece, to, board) {
+										t.Errorf("Off-board move %s to %v was accepted", from.Notation(), to)
+									}
+								}
+							}
+
+							board.Remove(from)
+						}
+					}
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Package handlers contains HTTP request handlers.
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+	"github.com/xiangqi/chinese-chess-backend/internal/services"
+)
+
+// ChallengeHandler handles direct challenges between players.
+type ChallengeHandler struct {
+	challengeService *services.ChallengeService
+	timeoutPresets   []int
+}
+
+// NewChallengeHandler creates a new ChallengeHandler that only accepts the
+// given turn timeout presets.
+func NewChallengeHandler(challengeService *services.ChallengeService, timeoutPresets []int) *ChallengeHandler {
+	return &ChallengeHandler{
+		challengeService: challengeService,
+		timeoutPresets:   timeoutPresets,
+	}
+}
+
+// CreateChallengeRequest represents a request to challenge another player.
+type CreateChallengeRequest struct {
+	OpponentDeviceID string `json:"opponent_device_id"`
+	TurnTimeout      int    `json:"turn_timeout"`
+}
+
+// CreateChallenge handles challenging a specific player.
+func (h *ChallengeHandler) CreateChallenge(w http.ResponseWriter, r *http.Request) {
+	deviceID := r.Header.Get("X-Device-ID")
+	if deviceID == "" {
+		respondError(w, http.StatusUnauthorized, "missing_device_id", "Device ID is required")
+		return
+	}
+
+	var req CreateChallengeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+	if req.OpponentDeviceID == "" {
+		respondError(w, http.StatusBadRequest, "missing_opponent", "Opponent device ID is required")
+		return
+	}
+
+	turnTimeout, ok := resolveTurnTimeout(h.timeoutPresets, req.TurnTimeout)
+	if !ok {
+		respondError(w, http.StatusBadRequest, "invalid_turn_timeout",
+			fmt.Sprintf("Turn timeout must be one of %v seconds", h.timeoutPresets))
+		return
+	}
+
+	challenge, err := h.challengeService.Create(r.Context(), deviceID, req.OpponentDeviceID, turnTimeout)
+	if err != nil {
+		if errors.Is(err, services.ErrSelfChallenge) {
+			respondError(w, http.StatusBadRequest, "self_challenge", "You cannot challenge yourself")
+			return
+		}
+		if errors.Is(err, services.ErrUserNotFound) {
+			respondError(w, http.StatusNotFound, "user_not_found", "User not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "challenge_failed", "Failed to create challenge")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, challengeResponse(challenge))
+}
+
+// challengeResponse returns the response body for a challenge.
+func challengeResponse(challenge *services.Challenge) map[string]interface{} {
+	return map[string]interface{}{
+		"id":              challenge.ID,
+		"challenger_id":   challenge.ChallengerID,
+		"challenger_name": challenge.ChallengerName,
+		"opponent_id":     challenge.OpponentID,
+		"turn_timeout":    challenge.TurnTimeout,
+		"created_at":      models.FormatTimestamp(challenge.CreatedAt),
+		"expires_at":      models.FormatTimestamp(challenge.ExpiresAt),
+	}
+}
+
+// ListChallenges handles listing the challenges sent to the caller.
+func (h *ChallengeHandler) ListChallenges(w http.ResponseWriter, r *http.Request) {
+	deviceID := r.Header.Get("X-Device-ID")
+	if deviceID == "" {
+		respondError(w, http.StatusUnauthorized, "missing_device_id", "Device ID is required")
+		return
+	}
+
+	challenges, err := h.challengeService.ListIncoming(r.Context(), deviceID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "list_failed", "Failed to list challenges")
+		return
+	}
+
+	response := make([]map[string]interface{}, len(challenges))
+	for i, challenge := range challenges {
+		response[i] = challengeResponse(challenge)
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"challenges": response,
+	})
+}
+
+// AcceptChallenge handles accepting a challenge, responding with the new
+// game ID and the caller's color.
+func (h *ChallengeHandler) AcceptChallenge(w http.ResponseWriter, r *http.Request) {
+	deviceID := r.Header.Get("X-Device-ID")
+	if deviceID == "" {
+		respondError(w, http.StatusUnauthorized, "missing_device_id", "Device ID is required")
+		return
+	}
+
+	challengeID := chi.URLParam(r, "challengeId")
+	if challengeID == "" {
+		respondError(w, http.StatusBadRequest, "missing_challenge_id", "Challenge ID is required")
+		return
+	}
+
+	game, err := h.challengeService.Accept(r.Context(), challengeID, deviceID)
+	if err != nil {
+		if errors.Is(err, services.ErrChallengeNotFound) {
+			respondError(w, http.StatusNotFound, "challenge_not_found", "Challenge not found")
+			return
+		}
+		if errors.Is(err, services.ErrNotChallenged) {
+			respondError(w, http.StatusForbidden, "not_challenged", "This challenge was sent to another player")
+			return
+		}
+		if errors.Is(err, services.ErrChallengeExpired) {
+			respondError(w, http.StatusGone, "challenge_expired", "Challenge has expired")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "accept_failed", "Failed to accept challenge")
+		return
+	}
+
+	color := models.PlayerColorBlack
+	if game.RedPlayerID == deviceID {
+		color = models.PlayerColorRed
+	}
+
+	respondJSON(w, http.StatusCreated, map[string]interface{}{
+		"game_id":    game.ID,
+		"your_color": color,
+	})
+}
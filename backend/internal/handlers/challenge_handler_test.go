@@ -0,0 +1,242 @@
+// Package handlers provides integration tests for the challenge handler.
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+	"github.com/xiangqi/chinese-chess-backend/internal/services"
+)
+
+// challengeFixture wires the challenge routes to in-memory repositories with
+// two registered players, "alice" and "bob".
+type challengeFixture struct {
+	router   http.Handler
+	gameRepo *mockGameRepo
+}
+
+func setupChallengeHandler(ttl time.Duration) *challengeFixture {
+	userRepo := newMockUserRepo()
+	userRepo.users["alice"] = &models.User{ID: "alice", DisplayName: "Alice"}
+	userRepo.users["bob"] = &models.User{ID: "bob", DisplayName: "Bob"}
+	gameRepo := newMockGameRepo()
+
+	gameService := services.NewGameService(gameRepo, &mockMoveRepo{}, userRepo)
+	challengeService := services.NewChallengeService(
+		newMockKeyValueStore(), newMockHashStore(), gameService, services.NewUserService(userRepo), ttl,
+	)
+	handler := NewChallengeHandler(challengeService, []int{30, 60, 300})
+
+	r := chi.NewRouter()
+	r.Post("/challenges", handler.CreateChallenge)
+	r.Get("/challenges", handler.ListChallenges)
+	r.Post("/challenges/{challengeId}/accept", handler.AcceptChallenge)
+	return &challengeFixture{router: r, gameRepo: gameRepo}
+}
+
+func (f *challengeFixture) do(method, path, deviceID string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		_ = json.NewEncoder(&buf).Encode(body)
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("X-Device-ID", deviceID)
+	w := httptest.NewRecorder()
+	f.router.ServeHTTP(w, req)
+	return w
+}
+
+func (f *challengeFixture) create(t *testing.T, challengerID, opponentID string) services.Challenge {
+	t.Helper()
+	w := f.do(http.MethodPost, "/challenges", challengerID, CreateChallengeRequest{
+		OpponentDeviceID: opponentID,
+		TurnTimeout:      60,
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create challenge: expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var challenge services.Challenge
+	if err := json.Unmarshal(w.Body.Bytes(), &challenge); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	return challenge
+}
+
+func (f *challengeFixture) list(t *testing.T, deviceID string) []services.Challenge {
+	t.Helper()
+	w := f.do(http.MethodGet, "/challenges", deviceID, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("list challenges: expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var resp struct {
+		Challenges []services.Challenge `json:"challenges"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	return resp.Challenges
+}
+
+func TestChallengeHandler_CreateChallenge(t *testing.T) {
+	f := setupChallengeHandler(time.Minute)
+
+	challenge := f.create(t, "alice", "bob")
+	if challenge.ID == "" {
+		t.Error("Expected a challenge ID")
+	}
+	if challenge.ChallengerID != "alice" || challenge.OpponentID != "bob" {
+		t.Errorf("Expected alice to challenge bob, got %s to %s", challenge.ChallengerID, challenge.OpponentID)
+	}
+	if challenge.ChallengerName != "Alice" {
+		t.Errorf("Expected challenger name Alice, got %q", challenge.ChallengerName)
+	}
+	if challenge.TurnTimeout != 60 {
+		t.Errorf("Expected turn timeout 60, got %d", challenge.TurnTimeout)
+	}
+
+	listed := f.do(http.MethodGet, "/challenges", "bob", nil)
+	var raw struct {
+		Challenges []map[string]interface{} `json:"challenges"`
+	}
+	if err := json.Unmarshal(listed.Body.Bytes(), &raw); err != nil || len(raw.Challenges) != 1 {
+		t.Fatalf("Expected one listed challenge, got %s", listed.Body.String())
+	}
+	if raw.Challenges[0]["expires_at"] != models.FormatTimestamp(challenge.ExpiresAt) {
+		t.Errorf("Expected expires_at in the API timestamp format, got %v", raw.Challenges[0]["expires_at"])
+	}
+}
+
+func TestChallengeHandler_CreateChallenge_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		deviceID   string
+		body       interface{}
+		wantStatus int
+		wantCode   string
+	}{
+		{"missing device", "", CreateChallengeRequest{OpponentDeviceID: "bob"}, http.StatusUnauthorized, "missing_device_id"},
+		{"missing opponent", "alice", CreateChallengeRequest{}, http.StatusBadRequest, "missing_opponent"},
+		{"self challenge", "alice", CreateChallengeRequest{OpponentDeviceID: "alice"}, http.StatusBadRequest, "self_challenge"},
+		{"unknown opponent", "alice", CreateChallengeRequest{OpponentDeviceID: "carol"}, http.StatusNotFound, "user_not_found"},
+		{"unknown challenger", "carol", CreateChallengeRequest{OpponentDeviceID: "bob"}, http.StatusNotFound, "user_not_found"},
+		{"non-preset timeout", "alice", CreateChallengeRequest{OpponentDeviceID: "bob", TurnTimeout: 45}, http.StatusBadRequest, "invalid_turn_timeout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := setupChallengeHandler(time.Minute)
+			w := f.do(http.MethodPost, "/challenges", tt.deviceID, tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			var response map[string]map[string]string
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			if response["error"]["code"] != tt.wantCode {
+				t.Errorf("Expected error code %q, got %q", tt.wantCode, response["error"]["code"])
+			}
+		})
+	}
+}
+
+func TestChallengeHandler_ListChallenges(t *testing.T) {
+	f := setupChallengeHandler(time.Minute)
+
+	if got := f.list(t, "bob"); len(got) != 0 {
+		t.Fatalf("Expected no challenges, got %d", len(got))
+	}
+
+	first := f.create(t, "alice", "bob")
+	second := f.create(t, "alice", "bob")
+
+	got := f.list(t, "bob")
+	if len(got) != 1 {
+		t.Fatalf("Expected a new challenge to replace the old one, got %d challenges", len(got))
+	}
+	if got[0].ID != second.ID {
+		t.Errorf("Expected challenge %s, got %s", second.ID, got[0].ID)
+	}
+
+	if w := f.do(http.MethodPost, "/challenges/"+first.ID+"/accept", "bob", nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected replaced challenge to be gone, got status %d", w.Code)
+	}
+	if got := f.list(t, "alice"); len(got) != 0 {
+		t.Errorf("Expected the challenger to have no incoming challenges, got %d", len(got))
+	}
+}
+
+func TestChallengeHandler_AcceptChallenge(t *testing.T) {
+	f := setupChallengeHandler(time.Minute)
+	challenge := f.create(t, "alice", "bob")
+
+	w := f.do(http.MethodPost, "/challenges/"+challenge.ID+"/accept", "alice", nil)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected the challenger to be refused, got status %d", w.Code)
+	}
+
+	w = f.do(http.MethodPost, "/challenges/"+challenge.ID+"/accept", "bob", nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var resp struct {
+		GameID    string             `json:"game_id"`
+		YourColor models.PlayerColor `json:"your_color"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	game, ok := f.gameRepo.games[resp.GameID]
+	if !ok {
+		t.Fatalf("Expected game %s to be created", resp.GameID)
+	}
+	players := map[string]bool{game.RedPlayerID: true, game.BlackPlayerID: true}
+	if !players["alice"] || !players["bob"] {
+		t.Errorf("Expected alice and bob to play, got red %s and black %s", game.RedPlayerID, game.BlackPlayerID)
+	}
+	wantColor := models.PlayerColorBlack
+	if game.RedPlayerID == "bob" {
+		wantColor = models.PlayerColorRed
+	}
+	if resp.YourColor != wantColor {
+		t.Errorf("Expected color %s, got %s", wantColor, resp.YourColor)
+	}
+	if game.TurnTimeoutSeconds != 60 {
+		t.Errorf("Expected turn timeout 60, got %d", game.TurnTimeoutSeconds)
+	}
+
+	if got := f.list(t, "bob"); len(got) != 0 {
+		t.Errorf("Expected the accepted challenge to be removed, got %d", len(got))
+	}
+	w = f.do(http.MethodPost, "/challenges/"+challenge.ID+"/accept", "bob", nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected a second accept to fail with %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestChallengeHandler_AcceptChallenge_Expired(t *testing.T) {
+	f := setupChallengeHandler(20 * time.Millisecond)
+	challenge := f.create(t, "alice", "bob")
+
+	time.Sleep(30 * time.Millisecond)
+
+	if got := f.list(t, "bob"); len(got) != 0 {
+		t.Errorf("Expected expired challenges to be hidden, got %d", len(got))
+	}
+
+	w := f.do(http.MethodPost, "/challenges/"+challenge.ID+"/accept", "bob", nil)
+	if w.Code != http.StatusGone {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusGone, w.Code, w.Body.String())
+	}
+	if len(f.gameRepo.games) != 0 {
+		t.Errorf("Expected no game to be created, got %d", len(f.gameRepo.games))
+	}
+}
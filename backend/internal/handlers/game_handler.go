@@ -2,12 +2,18 @@
 package handlers
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
 
+	"github.com/xiangqi/chinese-chess-backend/internal/game"
+	"github.com/xiangqi/chinese-chess-backend/internal/game/ai"
+	"github.com/xiangqi/chinese-chess-backend/internal/game/record"
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
 	"github.com/xiangqi/chinese-chess-backend/internal/services"
 	"github.com/xiangqi/chinese-chess-backend/internal/websocket"
 )
@@ -17,25 +23,36 @@ type GameHandler struct {
 	gameService *services.GameService
 	userService *services.UserService
 	wsHub       *websocket.Hub
+
+	// Caps the search done for each move analysis request
+	analysisLimits services.AnalysisLimits
 }
 
 // NewGameHandler creates a new GameHandler.
 func NewGameHandler(gameService *services.GameService, wsHub *websocket.Hub) *GameHandler {
 	return &GameHandler{
-		gameService: gameService,
-		wsHub:       wsHub,
+		gameService:    gameService,
+		wsHub:          wsHub,
+		analysisLimits: services.NewAnalysisLimits(0, 0),
 	}
 }
 
 // NewGameHandlerWithUserService creates a new GameHandler with user service.
 func NewGameHandlerWithUserService(gameService *services.GameService, userService *services.UserService, wsHub *websocket.Hub) *GameHandler {
 	return &GameHandler{
-		gameService: gameService,
-		userService: userService,
-		wsHub:       wsHub,
+		gameService:    gameService,
+		userService:    userService,
+		wsHub:          wsHub,
+		analysisLimits: services.NewAnalysisLimits(0, 0),
 	}
 }
 
+// SetAnalysisLimits sets the search depth and time allowed for each move
+// analysis request.
+func (h *GameHandler) SetAnalysisLimits(limits services.AnalysisLimits) {
+	h.analysisLimits = limits
+}
+
 // GetHistory handles getting match history.
 func (h *GameHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
 	deviceID := r.Header.Get("X-Device-ID")
@@ -44,16 +61,7 @@ func (h *GameHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse pagination parameters
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	if page < 1 {
-		page = 1
-	}
-
-	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
-	if pageSize < 1 || pageSize > 50 {
-		pageSize = 20
-	}
+	page, pageSize := parsePagination(r)
 
 	games, total, err := h.gameService.GetHistory(r.Context(), deviceID, page, pageSize)
 	if err != nil {
@@ -96,25 +104,59 @@ func (h *GameHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
 			"result":       result,
 			"result_type":  game.ResultType,
 			"total_moves":  game.TotalMoves,
-			"played_at":    game.CreatedAt.Format("2006-01-02T15:04:05Z"),
+			"ranked":       game.Ranked,
+			"played_at":    models.FormatTimestamp(game.CreatedAt),
 		}
 
 		if game.CompletedAt != nil {
 			duration := int(game.CompletedAt.Sub(game.CreatedAt).Seconds())
 			gameResponses[i]["duration_seconds"] = duration
 		}
+		if game.FinalFEN != nil {
+			gameResponses[i]["final_fen"] = *game.FinalFEN
+		}
 	}
 
-	totalPages := (total + pageSize - 1) / pageSize
+	response := map[string]interface{}{
+		"games":      gameResponses,
+		"pagination": NewPagination(page, pageSize, total),
+	}
+
+	respondJSON(w, http.StatusOK, response)
+}
+
+// GetLiveGames handles listing in-progress public games for spectating.
+func (h *GameHandler) GetLiveGames(w http.ResponseWriter, r *http.Request) {
+	page, pageSize := parsePagination(r)
+
+	games, total, err := h.gameService.GetLivePublicGames(r.Context(), page, pageSize)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "fetch_failed", "Failed to get live games")
+		return
+	}
+
+	gameResponses := make([]map[string]interface{}, len(games))
+	for i, live := range games {
+		gameResponses[i] = map[string]interface{}{
+			"id": live.Game.ID,
+			"red_player": map[string]string{
+				"id":           live.Game.RedPlayerID,
+				"display_name": live.RedPlayerName,
+			},
+			"black_player": map[string]string{
+				"id":           live.Game.BlackPlayerID,
+				"display_name": live.BlackPlayerName,
+			},
+			"total_moves":  live.Game.TotalMoves,
+			"turn_timeout": live.Game.TurnTimeoutSeconds,
+			"ranked":       live.Game.Ranked,
+			"created_at":   models.FormatTimestamp(live.Game.CreatedAt),
+		}
+	}
 
 	response := map[string]interface{}{
-		"games": gameResponses,
-		"pagination": map[string]int{
-			"page":        page,
-			"page_size":   pageSize,
-			"total_pages": totalPages,
-			"total_count": total,
-		},
+		"games":      gameResponses,
+		"pagination": NewPagination(page, pageSize, total),
 	}
 
 	respondJSON(w, http.StatusOK, response)
@@ -138,27 +180,41 @@ func (h *GameHandler) GetGame(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	respondJSON(w, http.StatusOK, gameResponse(game))
+}
+
+// gameResponse returns the response body for a game.
+func gameResponse(game *models.Game) map[string]interface{} {
 	response := map[string]interface{}{
-		"id":            game.ID,
-		"red_player_id": game.RedPlayerID,
+		"id":              game.ID,
+		"red_player_id":   game.RedPlayerID,
 		"black_player_id": game.BlackPlayerID,
-		"status":        game.Status,
-		"turn_timeout":  game.TurnTimeoutSeconds,
-		"total_moves":   game.TotalMoves,
-		"created_at":    game.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		"status":          game.Status,
+		"turn_timeout":    game.TurnTimeoutSeconds,
+		"total_moves":     game.TotalMoves,
+		"ranked":          game.Ranked,
+		"created_at":      models.FormatTimestamp(game.CreatedAt),
 	}
 
 	if game.WinnerID != nil {
 		response["winner_id"] = *game.WinnerID
 	}
+	// Imported games have one owner on both sides, so only the color tells
+	// who won
+	if game.WinnerColor != nil {
+		response["winner_color"] = *game.WinnerColor
+	}
 	if game.ResultType != nil {
 		response["result_type"] = *game.ResultType
 	}
 	if game.CompletedAt != nil {
-		response["completed_at"] = game.CompletedAt.Format("2006-01-02T15:04:05Z")
+		response["completed_at"] = models.FormatTimestamp(*game.CompletedAt)
+	}
+	if game.FinalFEN != nil {
+		response["final_fen"] = *game.FinalFEN
 	}
 
-	respondJSON(w, http.StatusOK, response)
+	return response
 }
 
 // GetMoves handles getting moves for a game.
@@ -169,25 +225,26 @@ func (h *GameHandler) GetMoves(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	coords, ok := parseCoordinateSystem(w, r)
+	if !ok {
+		return
+	}
+	notation, err := game.ParseMoveNotation(r.URL.Query().Get("notation"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_notation", "Unknown move notation")
+		return
+	}
+
 	moves, err := h.gameService.GetMoves(r.Context(), gameID)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "fetch_failed", "Failed to get moves")
 		return
 	}
 
-	moveResponses := make([]map[string]interface{}, len(moves))
-	for i, move := range moves {
-		moveResponses[i] = map[string]interface{}{
-			"move_number": move.MoveNumber,
-			"player_id":   move.PlayerID,
-			"from":        move.FromPosition,
-			"to":          move.ToPosition,
-			"piece":       move.PieceType,
-			"is_check":    move.IsCheck,
-			"timestamp":   move.Timestamp.Format("2006-01-02T15:04:05Z"),
-		}
-		if move.CapturedPiece != nil {
-			moveResponses[i]["captured"] = *move.CapturedPiece
+	moveResponses := buildMoveResponses(moves, coords)
+	if notation != game.NotationCoordinate {
+		for i, written := range writeMoves(moves, notation) {
+			moveResponses[i]["notation"] = written
 		}
 	}
 
@@ -199,6 +256,154 @@ func (h *GameHandler) GetMoves(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, response)
 }
 
+// GetPositions handles getting the board position after each move, so a
+// client can step through a game without replaying it. Each position comes
+// with the move that led to it, written in the requested coordinate system.
+// Long games are paged with the start and limit query parameters.
+func (h *GameHandler) GetPositions(w http.ResponseWriter, r *http.Request) {
+	gameID := chi.URLParam(r, "gameId")
+	if gameID == "" {
+		respondError(w, http.StatusBadRequest, "missing_game_id", "Game ID is required")
+		return
+	}
+
+	coords, ok := parseCoordinateSystem(w, r)
+	if !ok {
+		return
+	}
+	start := 0
+	if value := r.URL.Query().Get("start"); value != "" {
+		var err error
+		if start, err = strconv.Atoi(value); err != nil || start < 0 {
+			respondError(w, http.StatusBadRequest, "invalid_start", "Start must be a non-negative integer")
+			return
+		}
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	positions, total, err := h.gameService.GetPositions(r.Context(), gameID, start, limit)
+	if err != nil {
+		if errors.Is(err, services.ErrGameNotFound) {
+			respondError(w, http.StatusNotFound, "game_not_found", "Game not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "fetch_failed", "Failed to get positions")
+		return
+	}
+
+	fens := make([]string, len(positions))
+	moves := make([]map[string]interface{}, len(positions))
+	for i, position := range positions {
+		fens[i] = position.FEN
+		if position.Move != nil {
+			moves[i] = map[string]interface{}{
+				"from": formatSquare(position.Move.FromPosition, coords),
+				"to":   formatSquare(position.Move.ToPosition, coords),
+			}
+		}
+	}
+
+	response := map[string]interface{}{
+		"game_id":         gameID,
+		"start":           start,
+		"positions":       fens,
+		"moves":           moves,
+		"total_positions": total,
+	}
+
+	respondJSON(w, http.StatusOK, response)
+}
+
+// GetEvaluation handles getting the material balance of a game's current
+// position and the pieces each side has captured.
+func (h *GameHandler) GetEvaluation(w http.ResponseWriter, r *http.Request) {
+	gameID := chi.URLParam(r, "gameId")
+	if gameID == "" {
+		respondError(w, http.StatusBadRequest, "missing_game_id", "Game ID is required")
+		return
+	}
+
+	evaluation, err := h.gameService.EvaluatePosition(r.Context(), gameID)
+	if err != nil {
+		if errors.Is(err, services.ErrGameNotFound) {
+			respondError(w, http.StatusNotFound, "game_not_found", "Game not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "evaluation_failed", "Failed to evaluate position")
+		return
+	}
+
+	response := map[string]interface{}{
+		"game_id":          gameID,
+		"move_number":      evaluation.MoveNumber,
+		"fen":              evaluation.FEN,
+		"material_balance": evaluation.MaterialBalance,
+		"captured":         evaluation.Captured,
+	}
+
+	respondJSON(w, http.StatusOK, response)
+}
+
+// AnalyzeMove handles analyzing a move of a finished game, comparing the
+// move that was played with the engine's best move in the same position.
+// The optional depth query parameter asks for a shallower search than the
+// configured maximum.
+func (h *GameHandler) AnalyzeMove(w http.ResponseWriter, r *http.Request) {
+	deviceID := r.Header.Get("X-Device-ID")
+	if deviceID == "" {
+		respondError(w, http.StatusUnauthorized, "missing_device_id", "Device ID is required")
+		return
+	}
+
+	gameID := chi.URLParam(r, "gameId")
+	if gameID == "" {
+		respondError(w, http.StatusBadRequest, "missing_game_id", "Game ID is required")
+		return
+	}
+
+	moveNumber, err := strconv.Atoi(chi.URLParam(r, "moveNumber"))
+	if err != nil || moveNumber < 1 {
+		respondError(w, http.StatusBadRequest, "invalid_move_number", "Move number must be a positive integer")
+		return
+	}
+
+	depth := 0
+	if value := r.URL.Query().Get("depth"); value != "" {
+		if depth, err = strconv.Atoi(value); err != nil || depth < 1 {
+			respondError(w, http.StatusBadRequest, "invalid_depth", "Depth must be a positive integer")
+			return
+		}
+	}
+
+	analysis, err := h.gameService.AnalyzeMove(r.Context(), gameID, deviceID, moveNumber, depth, h.analysisLimits)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrDepthExceeded):
+			respondError(w, http.StatusBadRequest, "depth_exceeded", "Depth exceeds the maximum search depth")
+		case errors.Is(err, services.ErrInvalidDepth):
+			respondError(w, http.StatusBadRequest, "invalid_depth", "Depth must be a positive integer")
+		case errors.Is(err, services.ErrGameNotFound):
+			respondError(w, http.StatusNotFound, "game_not_found", "Game not found")
+		case errors.Is(err, services.ErrMoveNotFound):
+			respondError(w, http.StatusNotFound, "move_not_found", "Move not found")
+		case errors.Is(err, services.ErrPlayerNotInGame):
+			respondError(w, http.StatusForbidden, "not_in_game", "You are not a player in this game")
+		case errors.Is(err, services.ErrGameNotFinished):
+			respondError(w, http.StatusConflict, "game_not_finished", "Game is still in progress")
+		case errors.Is(err, services.ErrAnalysisTimeout):
+			respondError(w, http.StatusServiceUnavailable, "analysis_timeout", "Analysis did not finish in time")
+		case errors.Is(err, services.ErrSearchBusy):
+			w.Header().Set("Retry-After", "1")
+			respondError(w, http.StatusServiceUnavailable, "busy", "The server is busy, please try again shortly")
+		default:
+			respondError(w, http.StatusInternalServerError, "analysis_failed", "Failed to analyze move")
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusOK, analysis)
+}
+
 // GetGameWithMoves handles getting a game with all its moves in one request.
 func (h *GameHandler) GetGameWithMoves(w http.ResponseWriter, r *http.Request) {
 	gameID := chi.URLParam(r, "gameId")
@@ -207,6 +412,11 @@ func (h *GameHandler) GetGameWithMoves(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	coords, ok := parseCoordinateSystem(w, r)
+	if !ok {
+		return
+	}
+
 	// Get game
 	game, err := h.gameService.GetGame(r.Context(), gameID)
 	if err != nil {
@@ -226,21 +436,7 @@ func (h *GameHandler) GetGameWithMoves(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Build move responses
-	moveResponses := make([]map[string]interface{}, len(moves))
-	for i, move := range moves {
-		moveResponses[i] = map[string]interface{}{
-			"move_number": move.MoveNumber,
-			"player_id":   move.PlayerID,
-			"from":        move.FromPosition,
-			"to":          move.ToPosition,
-			"piece":       move.PieceType,
-			"is_check":    move.IsCheck,
-			"timestamp":   move.Timestamp.Format("2006-01-02T15:04:05Z"),
-		}
-		if move.CapturedPiece != nil {
-			moveResponses[i]["captured"] = *move.CapturedPiece
-		}
-	}
+	moveResponses := buildMoveResponses(moves, coords)
 
 	// Build response
 	response := map[string]interface{}{
@@ -250,7 +446,8 @@ func (h *GameHandler) GetGameWithMoves(w http.ResponseWriter, r *http.Request) {
 		"status":          game.Status,
 		"turn_timeout":    game.TurnTimeoutSeconds,
 		"total_moves":     game.TotalMoves,
-		"created_at":      game.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		"ranked":          game.Ranked,
+		"created_at":      models.FormatTimestamp(game.CreatedAt),
 		"moves":           moveResponses,
 		"red_rollbacks_remaining":   game.RedRollbacksRemaining,
 		"black_rollbacks_remaining": game.BlackRollbacksRemaining,
@@ -263,12 +460,244 @@ func (h *GameHandler) GetGameWithMoves(w http.ResponseWriter, r *http.Request) {
 		response["result_type"] = *game.ResultType
 	}
 	if game.CompletedAt != nil {
-		response["completed_at"] = game.CompletedAt.Format("2006-01-02T15:04:05Z")
+		response["completed_at"] = models.FormatTimestamp(*game.CompletedAt)
+	}
+	if game.FinalFEN != nil {
+		response["final_fen"] = *game.FinalFEN
 	}
 
 	respondJSON(w, http.StatusOK, response)
 }
 
+// ExportGame handles downloading a game record, as PGN by default or in the
+// format named by the format query parameter.
+func (h *GameHandler) ExportGame(w http.ResponseWriter, r *http.Request) {
+	gameID := chi.URLParam(r, "gameId")
+	if gameID == "" {
+		respondError(w, http.StatusBadRequest, "missing_game_id", "Game ID is required")
+		return
+	}
+
+	format, err := record.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_format", "Unknown record format")
+		return
+	}
+
+	game, err := h.gameService.GetGame(r.Context(), gameID)
+	if err != nil {
+		if errors.Is(err, services.ErrGameNotFound) {
+			respondError(w, http.StatusNotFound, "game_not_found", "Game not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "fetch_failed", "Failed to get game")
+		return
+	}
+
+	moves, err := h.gameService.GetMoves(r.Context(), gameID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "fetch_failed", "Failed to get moves")
+		return
+	}
+
+	written, err := record.Write(format, game, moves)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "export_failed", "Failed to export game")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", gameID+"."+string(format)))
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(written))
+}
+
+// ImportGameRequest represents a game record to import for review.
+type ImportGameRequest struct {
+	// Record is a PGN record or a bare move list, in WXF or ICCS notation
+	Record string `json:"record"`
+	// Result overrides the record's own result if set
+	Result string `json:"result"`
+}
+
+// ImportGame handles importing a game played elsewhere. Every move is
+// validated, and the game is stored as a completed, private game owned by
+// the caller.
+func (h *GameHandler) ImportGame(w http.ResponseWriter, r *http.Request) {
+	deviceID := r.Header.Get("X-Device-ID")
+	if deviceID == "" {
+		respondError(w, http.StatusUnauthorized, "missing_device_id", "Device ID is required")
+		return
+	}
+
+	var req ImportGameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+	if req.Record == "" {
+		respondError(w, http.StatusBadRequest, "missing_record", "Game record is required")
+		return
+	}
+
+	parsed, err := record.ParsePGN(req.Record)
+	if err != nil {
+		var parseErr *record.ParseError
+		if errors.As(err, &parseErr) {
+			respondMoveError(w, "invalid_move", fmt.Sprintf("Move %q cannot be read", parseErr.Move), parseErr.Index)
+			return
+		}
+		respondError(w, http.StatusBadRequest, "invalid_result", "Result must be 1-0, 0-1, 1/2-1/2 or *")
+		return
+	}
+	result := parsed.Result
+	if req.Result != "" {
+		if result, err = record.ParseResult(req.Result); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid_result", "Result must be 1-0, 0-1, 1/2-1/2 or *")
+			return
+		}
+	}
+
+	imported, err := h.gameService.ImportGame(r.Context(), deviceID, parsed.Moves, result)
+	if err != nil {
+		var importErr *services.ImportError
+		if errors.As(err, &importErr) {
+			respondMoveError(w, "illegal_move", importErr.Reason, importErr.Index)
+			return
+		}
+		if errors.Is(err, services.ErrEmptyImport) {
+			respondError(w, http.StatusBadRequest, "empty_import", "Game record has no moves")
+			return
+		}
+		if errors.Is(err, services.ErrImportResultMismatch) {
+			respondError(w, http.StatusBadRequest, "result_mismatch", "Result does not match the final position")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "import_failed", "Failed to import game")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, gameResponse(imported))
+}
+
+// CreateBotGameRequest represents a request to play the computer.
+type CreateBotGameRequest struct {
+	// Difficulty is the computer's level, from 1 to 3
+	Difficulty int `json:"difficulty"`
+}
+
+// CreateBotGame handles creating a game against the computer. The caller
+// plays red and the room plays the computer's moves as black.
+func (h *GameHandler) CreateBotGame(w http.ResponseWriter, r *http.Request) {
+	deviceID := r.Header.Get("X-Device-ID")
+	if deviceID == "" {
+		respondError(w, http.StatusUnauthorized, "missing_device_id", "Device ID is required")
+		return
+	}
+
+	var req CreateBotGameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+		return
+	}
+
+	created, err := h.gameService.CreateBotGame(r.Context(), deviceID, req.Difficulty)
+	if err != nil {
+		if errors.Is(err, ai.ErrInvalidDifficulty) {
+			respondError(w, http.StatusBadRequest, "invalid_difficulty",
+				fmt.Sprintf("Difficulty must be from %d to %d", ai.MinDifficulty, ai.MaxDifficulty))
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "create_failed", "Failed to create game")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, map[string]interface{}{
+		"game_id":        created.ID,
+		"your_color":     models.PlayerColorRed,
+		"bot_difficulty": created.BotDifficulty,
+	})
+}
+
+// respondMoveError responds to an import rejected at one of its moves,
+// giving the move's zero-based index.
+func respondMoveError(w http.ResponseWriter, code, message string, index int) {
+	respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":       code,
+			"message":    message,
+			"move_index": index,
+		},
+	})
+}
+
+// parseCoordinateSystem reads the coords query parameter, responding with
+// an error and returning false if it names an unknown system.
+func parseCoordinateSystem(w http.ResponseWriter, r *http.Request) (game.CoordinateSystem, bool) {
+	coords, err := game.ParseCoordinateSystem(r.URL.Query().Get("coords"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid_coords", "Unknown coordinate system")
+		return "", false
+	}
+	return coords, true
+}
+
+// buildMoveResponses converts moves to their JSON form, writing squares in
+// the requested coordinate system.
+func buildMoveResponses(moves []*models.Move, coords game.CoordinateSystem) []map[string]interface{} {
+	moveResponses := make([]map[string]interface{}, len(moves))
+	for i, move := range moves {
+		moveResponses[i] = map[string]interface{}{
+			"move_number": move.MoveNumber,
+			"player_id":   move.PlayerID,
+			"from":        formatSquare(move.FromPosition, coords),
+			"to":          formatSquare(move.ToPosition, coords),
+			"piece":       move.PieceType,
+			"is_check":    move.IsCheck,
+			"timestamp":   models.FormatTimestamp(move.Timestamp),
+		}
+		if move.CapturedPiece != nil {
+			moveResponses[i]["captured"] = *move.CapturedPiece
+		}
+	}
+	return moveResponses
+}
+
+// writeMoves writes each move in the given notation, replaying the game from
+// the initial position for the board context WXF needs. Moves after one that
+// cannot be read are left empty.
+func writeMoves(moves []*models.Move, notation game.MoveNotation) []string {
+	written := make([]string, len(moves))
+	board := game.NewInitialBoard()
+	for i, move := range moves {
+		from, fromErr := game.ParsePosition(move.FromPosition)
+		to, toErr := game.ParsePosition(move.ToPosition)
+		if fromErr != nil || toErr != nil {
+			break
+		}
+
+		record := game.MoveRecord{From: from, To: to, PieceType: move.PieceType}
+		switch notation {
+		case game.NotationICCS:
+			written[i] = record.ICCS()
+		case game.NotationWXF:
+			written[i] = record.WXF(board)
+		}
+		board.Move(from, to)
+	}
+	return written
+}
+
+// formatSquare converts a stored square to the requested coordinate system,
+// leaving it unchanged if it cannot be parsed.
+func formatSquare(square string, coords game.CoordinateSystem) string {
+	formatted, err := coords.Format(square)
+	if err != nil {
+		return square
+	}
+	return formatted
+}
+
 // GetUserStats handles getting user statistics.
 func (h *GameHandler) GetUserStats(w http.ResponseWriter, r *http.Request) {
 	deviceID := chi.URLParam(r, "userId")
@@ -302,11 +731,14 @@ func (h *GameHandler) GetUserStats(w http.ResponseWriter, r *http.Request) {
 	response := map[string]interface{}{
 		"user_id": deviceID,
 		"stats": map[string]interface{}{
-			"total_games":    stats.TotalGames,
-			"wins":           stats.Wins,
-			"losses":         stats.Losses,
-			"draws":          stats.Draws,
-			"win_percentage": stats.WinPercentage,
+			"total_games":      stats.TotalGames,
+			"wins":             stats.Wins,
+			"losses":           stats.Losses,
+			"draws":            stats.Draws,
+			"win_percentage":   stats.WinPercentage,
+			"win_breakdown":    stats.WinBreakdown,
+			"rating":           stats.Rating,
+			"rating_deviation": stats.RatingDeviation,
 		},
 	}
 
@@ -343,7 +775,7 @@ func (h *GameHandler) GetActiveGames(w http.ResponseWriter, r *http.Request) {
 			"opponent_id": opponentID,
 			"your_color":  yourColor,
 			"total_moves": game.TotalMoves,
-			"created_at":  game.CreatedAt.Format("2006-01-02T15:04:05Z"),
+			"created_at":  models.FormatTimestamp(game.CreatedAt),
 		}
 	}
 
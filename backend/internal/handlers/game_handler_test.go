@@ -0,0 +1,861 @@
+// Package handlers provides tests for the game handlers.
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+	"github.com/xiangqi/chinese-chess-backend/internal/repository"
+	"github.com/xiangqi/chinese-chess-backend/internal/services"
+)
+
+// mockGameRepo is an in-memory game repository for testing handlers.
+type mockGameRepo struct {
+	games map[string]*models.Game
+}
+
+func newMockGameRepo() *mockGameRepo {
+	return &mockGameRepo{games: make(map[string]*models.Game)}
+}
+
+func (m *mockGameRepo) Create(ctx context.Context, game *models.Game) error {
+	m.games[game.ID] = game
+	return nil
+}
+
+func (m *mockGameRepo) CreateWithMoves(ctx context.Context, game *models.Game, moves []*models.Move) error {
+	return m.Create(ctx, game)
+}
+
+func (m *mockGameRepo) GetByID(ctx context.Context, id string) (*models.Game, error) {
+	game, ok := m.games[id]
+	if !ok {
+		return nil, repository.ErrGameNotFound
+	}
+	return game, nil
+}
+
+func (m *mockGameRepo) Update(ctx context.Context, game *models.Game) error {
+	m.games[game.ID] = game
+	return nil
+}
+
+func (m *mockGameRepo) GetHistoryByPlayer(ctx context.Context, playerID string, limit, offset int) ([]*models.Game, error) {
+	return nil, nil
+}
+
+func (m *mockGameRepo) CountByPlayer(ctx context.Context, playerID string) (int, error) {
+	return 0, nil
+}
+
+func (m *mockGameRepo) GetActiveByPlayer(ctx context.Context, playerID string) ([]*models.Game, error) {
+	return nil, nil
+}
+
+// GetLivePublic mirrors the repository query: active public games, newest first.
+func (m *mockGameRepo) GetLivePublic(ctx context.Context, limit, offset int) ([]*models.Game, error) {
+	games := m.livePublic()
+	if offset >= len(games) {
+		return []*models.Game{}, nil
+	}
+	games = games[offset:]
+	if len(games) > limit {
+		games = games[:limit]
+	}
+	return games, nil
+}
+
+func (m *mockGameRepo) CountLivePublic(ctx context.Context) (int, error) {
+	return len(m.livePublic()), nil
+}
+
+func (m *mockGameRepo) livePublic() []*models.Game {
+	var games []*models.Game
+	for _, game := range m.games {
+		if game.Status == models.GameStatusActive && game.IsPublic {
+			games = append(games, game)
+		}
+	}
+	sort.Slice(games, func(i, j int) bool {
+		return games[i].CreatedAt.After(games[j].CreatedAt)
+	})
+	return games
+}
+
+// mockMoveRepo is a read-only move repository for testing handlers.
+type mockMoveRepo struct {
+	moves map[string][]*models.Move
+}
+
+func (m *mockMoveRepo) Create(ctx context.Context, move *models.Move) error {
+	return nil
+}
+
+func (m *mockMoveRepo) GetByGameID(ctx context.Context, gameID string) ([]*models.Move, error) {
+	return m.moves[gameID], nil
+}
+
+func (m *mockMoveRepo) DeleteAfterMoveNumber(ctx context.Context, gameID string, moveNumber int) error {
+	return nil
+}
+
+// ========== GetLiveGames Handler Tests ==========
+
+// liveGamesResponse is the decoded body of GET /games/live.
+type liveGamesResponse struct {
+	Games []struct {
+		ID        string `json:"id"`
+		RedPlayer struct {
+			ID          string `json:"id"`
+			DisplayName string `json:"display_name"`
+		} `json:"red_player"`
+		TotalMoves int `json:"total_moves"`
+	} `json:"games"`
+	Pagination struct {
+		Page       int `json:"page"`
+		PageSize   int `json:"page_size"`
+		TotalPages int `json:"total_pages"`
+		TotalCount int `json:"total_count"`
+	} `json:"pagination"`
+}
+
+// setupLiveGamesHandler creates a handler with five live public games
+// ("public-0" newest to "public-4" oldest), one private game and one
+// finished public game.
+func setupLiveGamesHandler() *GameHandler {
+	ctx := context.Background()
+	userRepo := newMockUserRepo()
+	userRepo.Create(ctx, &models.User{ID: "red-player", DisplayName: "RedPlayer"})
+	userRepo.Create(ctx, &models.User{ID: "black-player", DisplayName: "BlackPlayer"})
+
+	gameRepo := newMockGameRepo()
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		gameRepo.Create(ctx, &models.Game{
+			ID:            fmt.Sprintf("public-%d", i),
+			RedPlayerID:   "red-player",
+			BlackPlayerID: "black-player",
+			Status:        models.GameStatusActive,
+			TotalMoves:    i,
+			IsPublic:      true,
+			CreatedAt:     now.Add(-time.Duration(i) * time.Minute),
+		})
+	}
+	gameRepo.Create(ctx, &models.Game{
+		ID:            "private",
+		RedPlayerID:   "red-player",
+		BlackPlayerID: "black-player",
+		Status:        models.GameStatusActive,
+		CreatedAt:     now,
+	})
+	gameRepo.Create(ctx, &models.Game{
+		ID:            "finished",
+		RedPlayerID:   "red-player",
+		BlackPlayerID: "black-player",
+		Status:        models.GameStatusCompleted,
+		IsPublic:      true,
+		CreatedAt:     now,
+	})
+
+	gameService := services.NewGameService(gameRepo, &mockMoveRepo{}, userRepo)
+	return NewGameHandler(gameService, nil)
+}
+
+func getLiveGames(t *testing.T, handler *GameHandler, query string) liveGamesResponse {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/games/live"+query, nil)
+	w := httptest.NewRecorder()
+
+	handler.GetLiveGames(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response liveGamesResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return response
+}
+
+func TestGameHandler_GetLiveGames_ExcludesPrivateAndFinished(t *testing.T) {
+	response := getLiveGames(t, setupLiveGamesHandler(), "")
+
+	if response.Pagination.TotalCount != 5 {
+		t.Errorf("Expected 5 live public games, got %d", response.Pagination.TotalCount)
+	}
+	for _, game := range response.Games {
+		if game.ID == "private" || game.ID == "finished" {
+			t.Errorf("Game %s should not be listed", game.ID)
+		}
+	}
+
+	first := response.Games[0]
+	if first.ID != "public-0" {
+		t.Errorf("Expected newest game first, got %s", first.ID)
+	}
+	if first.RedPlayer.DisplayName != "RedPlayer" {
+		t.Errorf("Expected red player name 'RedPlayer', got %q", first.RedPlayer.DisplayName)
+	}
+}
+
+func TestGameHandler_GetLiveGames_Pagination(t *testing.T) {
+	handler := setupLiveGamesHandler()
+
+	response := getLiveGames(t, handler, "?page=2&page_size=2")
+	if len(response.Games) != 2 {
+		t.Fatalf("Expected 2 games on page 2, got %d", len(response.Games))
+	}
+	if response.Games[0].ID != "public-2" || response.Games[1].ID != "public-3" {
+		t.Errorf("Expected public-2 and public-3, got %s and %s", response.Games[0].ID, response.Games[1].ID)
+	}
+	if response.Games[0].TotalMoves != 2 {
+		t.Errorf("Expected 2 moves, got %d", response.Games[0].TotalMoves)
+	}
+	if response.Pagination.TotalPages != 3 {
+		t.Errorf("Expected 3 pages, got %d", response.Pagination.TotalPages)
+	}
+
+	response = getLiveGames(t, handler, "?page=4&page_size=2")
+	if len(response.Games) != 0 {
+		t.Errorf("Expected no games past the last page, got %d", len(response.Games))
+	}
+}
+
+// ========== GetPositions Handler Tests ==========
+
+// positionsResponse is the decoded body of GET /games/{gameId}/positions.
+type positionsResponse struct {
+	Start     int      `json:"start"`
+	Positions []string `json:"positions"`
+	Moves     []*struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+	} `json:"moves"`
+	TotalPositions int `json:"total_positions"`
+}
+
+// setupPositionsHandler creates a handler with one game of the given moves
+// and returns a router serving its positions and moves.
+func setupPositionsHandler(moves [][2]string) http.Handler {
+	ctx := context.Background()
+	gameRepo := newMockGameRepo()
+	gameRepo.Create(ctx, &models.Game{
+		ID:            "game-1",
+		RedPlayerID:   "red-player",
+		BlackPlayerID: "black-player",
+		Status:        models.GameStatusActive,
+	})
+
+	moveRepo := &mockMoveRepo{moves: make(map[string][]*models.Move)}
+	for i, move := range moves {
+		playerID := "red-player"
+		if i%2 == 1 {
+			playerID = "black-player"
+		}
+		moveRepo.moves["game-1"] = append(moveRepo.moves["game-1"], &models.Move{
+			GameID:       "game-1",
+			MoveNumber:   i + 1,
+			PlayerID:     playerID,
+			FromPosition: move[0],
+			ToPosition:   move[1],
+		})
+	}
+
+	handler := NewGameHandler(services.NewGameService(gameRepo, moveRepo, newMockUserRepo()), nil)
+	r := chi.NewRouter()
+	r.Get("/api/v1/games/{gameId}/positions", handler.GetPositions)
+	r.Get("/api/v1/games/{gameId}/moves", handler.GetMoves)
+	r.Get("/api/v1/games/{gameId}/evaluation", handler.GetEvaluation)
+	r.Get("/api/v1/games/{gameId}/export", handler.ExportGame)
+	return r
+}
+
+func getPositions(t *testing.T, router http.Handler, path string) positionsResponse {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response positionsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return response
+}
+
+func TestGameHandler_GetPositions(t *testing.T) {
+	router := setupPositionsHandler([][2]string{{"h2", "e2"}, {"h9", "g7"}, {"h0", "g2"}})
+
+	response := getPositions(t, router, "/api/v1/games/game-1/positions")
+
+	if len(response.Positions) != 4 || response.TotalPositions != 4 {
+		t.Fatalf("Expected 4 positions for 3 moves, got %d of %d", len(response.Positions), response.TotalPositions)
+	}
+	if response.Positions[0] != "rnbakabnr/9/1c5c1/p1p1p1p1p/9/9/P1P1P1P1P/1C5C1/9/RNBAKABNR w" {
+		t.Errorf("Expected the initial position first, got %s", response.Positions[0])
+	}
+	if response.Positions[1] != "rnbakabnr/9/1c5c1/p1p1p1p1p/9/9/P1P1P1P1P/1C2C4/9/RNBAKABNR b" {
+		t.Errorf("Expected the position after h2-e2, got %s", response.Positions[1])
+	}
+}
+
+func TestGameHandler_GetPositions_Paged(t *testing.T) {
+	router := setupPositionsHandler([][2]string{{"h2", "e2"}, {"h9", "g7"}, {"h0", "g2"}})
+	all := getPositions(t, router, "/api/v1/games/game-1/positions")
+
+	response := getPositions(t, router, "/api/v1/games/game-1/positions?start=2&limit=1")
+	if response.Start != 2 || response.TotalPositions != 4 {
+		t.Errorf("Expected start 2 of 4 positions, got start %d of %d", response.Start, response.TotalPositions)
+	}
+	if len(response.Positions) != 1 || response.Positions[0] != all.Positions[2] {
+		t.Errorf("Expected only the position after move 2, got %v", response.Positions)
+	}
+}
+
+func TestGameHandler_GetPositions_InvalidStart(t *testing.T) {
+	router := setupPositionsHandler([][2]string{{"h2", "e2"}})
+
+	for _, start := range []string{"-1", "abc"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/games/game-1/positions?start="+start, nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("start=%s: expected status 400, got %d", start, w.Code)
+		}
+	}
+}
+
+func TestGameHandler_GetPositions_NumericCoords(t *testing.T) {
+	router := setupPositionsHandler([][2]string{{"h2", "e2"}, {"h9", "g7"}})
+
+	response := getPositions(t, router, "/api/v1/games/game-1/positions?coords=numeric")
+
+	if len(response.Moves) != 3 || response.Moves[0] != nil {
+		t.Fatalf("Expected no move into the initial position, got %v", response.Moves)
+	}
+	if move := response.Moves[1]; move == nil || move.From != "2-3" || move.To != "5-3" {
+		t.Errorf("Expected h2-e2 as 2-3 to 5-3, got %+v", move)
+	}
+	if move := response.Moves[2]; move == nil || move.From != "2-10" || move.To != "3-8" {
+		t.Errorf("Expected h9-g7 as 2-10 to 3-8, got %+v", move)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/games/game-1/positions?coords=polar", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown system, got %d", w.Code)
+	}
+}
+
+func TestGameHandler_GetPositions_NotFound(t *testing.T) {
+	router := setupPositionsHandler(nil)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/games/missing/positions", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+// ========== Coordinate System Tests ==========
+
+func TestGameHandler_GetMoves_NumericCoords(t *testing.T) {
+	router := setupPositionsHandler([][2]string{{"h2", "e2"}, {"h9", "g7"}})
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/games/game-1/moves?coords=numeric", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Moves []struct {
+			From string `json:"from"`
+			To   string `json:"to"`
+		} `json:"moves"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if len(response.Moves) != 2 {
+		t.Fatalf("Expected 2 moves, got %d", len(response.Moves))
+	}
+	if response.Moves[0].From != "2-3" || response.Moves[0].To != "5-3" {
+		t.Errorf("Expected h2-e2 as 2-3 to 5-3, got %s to %s", response.Moves[0].From, response.Moves[0].To)
+	}
+	if response.Moves[1].From != "2-10" || response.Moves[1].To != "3-8" {
+		t.Errorf("Expected h9-g7 as 2-10 to 3-8, got %s to %s", response.Moves[1].From, response.Moves[1].To)
+	}
+}
+
+func TestGameHandler_GetMoves_UnknownCoords(t *testing.T) {
+	router := setupPositionsHandler(nil)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/games/game-1/moves?coords=polar", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+// ========== Evaluation Tests ==========
+
+func TestGameHandler_GetEvaluation(t *testing.T) {
+	// Red's central cannon takes the middle soldier
+	router := setupPositionsHandler([][2]string{{"h2", "e2"}, {"h9", "g7"}, {"e2", "e6"}})
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/games/game-1/evaluation", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		MoveNumber      int                       `json:"move_number"`
+		MaterialBalance int                       `json:"material_balance"`
+		Captured        map[string]map[string]int `json:"captured"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if response.MoveNumber != 3 {
+		t.Errorf("Expected the position after move 3, got %d", response.MoveNumber)
+	}
+	if response.MaterialBalance != 10 {
+		t.Errorf("Expected red to be a soldier up (10), got %d", response.MaterialBalance)
+	}
+	if response.Captured["red"]["soldier"] != 1 || len(response.Captured["black"]) != 0 {
+		t.Errorf("Expected red to have captured one soldier, got %v", response.Captured)
+	}
+}
+
+func TestGameHandler_GetEvaluation_NotFound(t *testing.T) {
+	router := setupPositionsHandler(nil)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/games/missing/evaluation", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+// ========== Move Notation Tests ==========
+
+func TestGameHandler_GetMoves_WXFNotation(t *testing.T) {
+	router := setupPositionsHandler([][2]string{{"h2", "e2"}, {"h9", "g7"}, {"h0", "g2"}})
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/games/game-1/moves?notation=wxf", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Moves []struct {
+			From     string `json:"from"`
+			Notation string `json:"notation"`
+		} `json:"moves"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	want := []string{"C2.5", "H8+7", "H2+3"}
+	if len(response.Moves) != len(want) {
+		t.Fatalf("Expected %d moves, got %d", len(want), len(response.Moves))
+	}
+	for i, move := range response.Moves {
+		if move.Notation != want[i] {
+			t.Errorf("Move %d: expected %s, got %s", i+1, want[i], move.Notation)
+		}
+	}
+	if response.Moves[0].From != "h2" {
+		t.Errorf("Expected the squares to stay in coordinate notation, got %s", response.Moves[0].From)
+	}
+}
+
+func TestGameHandler_GetMoves_UnknownNotation(t *testing.T) {
+	router := setupPositionsHandler(nil)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/games/game-1/moves?notation=pgn", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func TestGameHandler_ExportGame(t *testing.T) {
+	router := setupPositionsHandler([][2]string{{"h2", "e2"}, {"h9", "g7"}, {"h0", "g2"}})
+
+	tests := []struct {
+		query    string
+		filename string
+		want     string
+	}{
+		{"", "game-1.pgn", "1. C2.5 H8+7\n2. H2+3\n*\n"},
+		{"?format=dpxq", "game-1.dpxq", "[DhtmlXQ_movelist]774770627967[/DhtmlXQ_movelist]"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/games/game-1/export"+tt.query, nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("%q: expected status 200, got %d: %s", tt.query, w.Code, w.Body.String())
+		}
+		if !strings.Contains(w.Body.String(), tt.want) {
+			t.Errorf("%q: expected the record to contain %q, got:\n%s", tt.query, tt.want, w.Body.String())
+		}
+		if disposition := w.Header().Get("Content-Disposition"); !strings.Contains(disposition, tt.filename) {
+			t.Errorf("%q: expected filename %s, got %q", tt.query, tt.filename, disposition)
+		}
+	}
+}
+
+func TestGameHandler_ExportGame_Errors(t *testing.T) {
+	router := setupPositionsHandler(nil)
+
+	tests := []struct {
+		path       string
+		wantStatus int
+	}{
+		{"/api/v1/games/game-1/export?format=xml", http.StatusBadRequest},
+		{"/api/v1/games/missing/export", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.wantStatus {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.wantStatus, w.Code)
+		}
+	}
+}
+
+// setupImportHandler returns a router serving game imports and the
+// repository games are stored in.
+func setupImportHandler() (http.Handler, *mockGameRepo) {
+	gameRepo := newMockGameRepo()
+	moveRepo := &mockMoveRepo{moves: make(map[string][]*models.Move)}
+	handler := NewGameHandler(services.NewGameService(gameRepo, moveRepo, newMockUserRepo()), nil)
+
+	r := chi.NewRouter()
+	r.Post("/api/v1/games/import", handler.ImportGame)
+	return r, gameRepo
+}
+
+func postImport(router http.Handler, body ImportGameRequest) *httptest.ResponseRecorder {
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/games/import", strings.NewReader(string(data)))
+	req.Header.Set("X-Device-ID", "owner")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestGameHandler_ImportGame(t *testing.T) {
+	router, gameRepo := setupImportHandler()
+
+	w := postImport(router, ImportGameRequest{
+		Record: "[Result \"0-1\"]\n\n1. C2.5 H8+7\n2. h0-g2 0-1\n",
+	})
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var imported map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &imported); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if imported["winner_color"] != "black" || imported["completed_at"] == nil {
+		t.Errorf("Expected the GetGame response shape, got %v", imported)
+	}
+
+	stored, ok := gameRepo.games[imported["id"].(string)]
+	if !ok {
+		t.Fatalf("Expected game %v to be stored", imported["id"])
+	}
+	if stored.Status != models.GameStatusCompleted || stored.IsPublic {
+		t.Errorf("Expected a completed private game, got status %s, public %v", stored.Status, stored.IsPublic)
+	}
+	if stored.RedPlayerID != "owner" || stored.BlackPlayerID != "owner" {
+		t.Errorf("Expected the uploader to own the game, got red %s and black %s", stored.RedPlayerID, stored.BlackPlayerID)
+	}
+	if stored.WinnerColor == nil || *stored.WinnerColor != models.PlayerColorBlack {
+		t.Errorf("Expected black to win, got %v", stored.WinnerColor)
+	}
+	if stored.TotalMoves != 3 {
+		t.Errorf("Expected 3 moves, got %d", stored.TotalMoves)
+	}
+}
+
+func TestGameHandler_ImportGame_RejectsIllegalMove(t *testing.T) {
+	tests := []struct {
+		name      string
+		record    string
+		wantCode  string
+		wantIndex float64
+	}{
+		// The chariot cannot jump its own soldier
+		{"illegal", "1. h2e2 h9g7 2. a0a5 i9h9", "illegal_move", 2},
+		{"unreadable", "1. C2.5 H8+7 2. C2.5", "invalid_move", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, gameRepo := setupImportHandler()
+
+			w := postImport(router, ImportGameRequest{Record: tt.record})
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status 400, got %d: %s", w.Code, w.Body.String())
+			}
+			var response map[string]map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response["error"]["code"] != tt.wantCode || response["error"]["move_index"] != tt.wantIndex {
+				t.Errorf("Expected %s at move %v, got %v", tt.wantCode, tt.wantIndex, response["error"])
+			}
+			if len(gameRepo.games) != 0 {
+				t.Error("Nothing should be stored for a rejected import")
+			}
+		})
+	}
+}
+
+func TestGameHandler_ImportGame_InvalidRequests(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     ImportGameRequest
+		wantCode string
+	}{
+		{"missing record", ImportGameRequest{}, "missing_record"},
+		{"no moves", ImportGameRequest{Record: "[Event \"Casual\"]\n*"}, "empty_import"},
+		{"invalid result", ImportGameRequest{Record: "1. C2.5", Result: "2-0"}, "invalid_result"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, _ := setupImportHandler()
+
+			w := postImport(router, tt.body)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status 400, got %d: %s", w.Code, w.Body.String())
+			}
+			var response map[string]map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response["error"]["code"] != tt.wantCode {
+				t.Errorf("Expected error code %s, got %v", tt.wantCode, response["error"]["code"])
+			}
+		})
+	}
+}
+
+func TestGameHandler_CreateBotGame(t *testing.T) {
+	gameRepo := newMockGameRepo()
+	moveRepo := &mockMoveRepo{moves: make(map[string][]*models.Move)}
+	handler := NewGameHandler(services.NewGameService(gameRepo, moveRepo, newMockUserRepo()), nil)
+
+	tests := []struct {
+		difficulty int
+		wantStatus int
+	}{
+		{2, http.StatusCreated},
+		{0, http.StatusBadRequest},
+		{4, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		body := fmt.Sprintf(`{"difficulty":%d}`, tt.difficulty)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/games/bot", strings.NewReader(body))
+		req.Header.Set("X-Device-ID", "player")
+		w := httptest.NewRecorder()
+
+		handler.CreateBotGame(w, req)
+
+		if w.Code != tt.wantStatus {
+			t.Fatalf("Difficulty %d: expected status %d, got %d: %s", tt.difficulty, tt.wantStatus, w.Code, w.Body.String())
+		}
+		if w.Code != http.StatusCreated {
+			continue
+		}
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		stored := gameRepo.games[response["game_id"].(string)]
+		if stored == nil || stored.RedPlayerID != "player" || stored.BlackPlayerID != services.BotPlayerID(2) {
+			t.Errorf("Expected a game against the level 2 computer, got %+v", stored)
+		}
+	}
+}
+
+// setupAnalysisHandler returns a router serving move analysis for a game
+// with the given status and moves.
+func setupAnalysisHandler(status models.GameStatus, moves [][2]string) http.Handler {
+	ctx := context.Background()
+	gameRepo := newMockGameRepo()
+	gameRepo.Create(ctx, &models.Game{
+		ID:            "game-1",
+		RedPlayerID:   "red-player",
+		BlackPlayerID: "black-player",
+		Status:        status,
+	})
+
+	moveRepo := &mockMoveRepo{moves: make(map[string][]*models.Move)}
+	for i, move := range moves {
+		playerID := "red-player"
+		if i%2 == 1 {
+			playerID = "black-player"
+		}
+		moveRepo.moves["game-1"] = append(moveRepo.moves["game-1"], &models.Move{
+			GameID:       "game-1",
+			MoveNumber:   i + 1,
+			PlayerID:     playerID,
+			FromPosition: move[0],
+			ToPosition:   move[1],
+		})
+	}
+
+	handler := NewGameHandler(services.NewGameService(gameRepo, moveRepo, newMockUserRepo()), nil)
+	handler.SetAnalysisLimits(services.NewAnalysisLimits(2, 5*time.Second))
+	r := chi.NewRouter()
+	r.Get("/api/v1/games/{gameId}/moves/{moveNumber}/analysis", handler.AnalyzeMove)
+	return r
+}
+
+func analyzeMove(router http.Handler, path, deviceID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("X-Device-ID", deviceID)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestGameHandler_AnalyzeMove(t *testing.T) {
+	router := setupAnalysisHandler(models.GameStatusCompleted, [][2]string{{"h2", "e2"}, {"h9", "g7"}})
+
+	w := analyzeMove(router, "/api/v1/games/game-1/moves/2/analysis", "black-player")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var analysis services.MoveAnalysis
+	if err := json.Unmarshal(w.Body.Bytes(), &analysis); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if analysis.MoveNumber != 2 {
+		t.Errorf("Expected move number 2, got %d", analysis.MoveNumber)
+	}
+	if !strings.HasSuffix(analysis.FEN, " b") {
+		t.Errorf("Expected the position before black's move, got %q", analysis.FEN)
+	}
+	if analysis.Played.From != "h9" || analysis.Played.To != "g7" {
+		t.Errorf("Expected played move h9-g7, got %s-%s", analysis.Played.From, analysis.Played.To)
+	}
+	if analysis.Best.From == "" || analysis.Best.To == "" {
+		t.Error("Expected a best move")
+	}
+	if analysis.Depth != 2 {
+		t.Errorf("Expected the search to reach depth 2, got %d", analysis.Depth)
+	}
+	if analysis.Played.Score > analysis.Best.Score {
+		t.Errorf("Played move scores %d, above the best move's %d", analysis.Played.Score, analysis.Best.Score)
+	}
+}
+
+func TestGameHandler_AnalyzeMove_RequestedDepth(t *testing.T) {
+	router := setupAnalysisHandler(models.GameStatusCompleted, [][2]string{{"h2", "e2"}, {"h9", "g7"}})
+
+	w := analyzeMove(router, "/api/v1/games/game-1/moves/2/analysis?depth=1", "black-player")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var analysis services.MoveAnalysis
+	if err := json.Unmarshal(w.Body.Bytes(), &analysis); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if analysis.Depth != 1 {
+		t.Errorf("Expected the search to stop at the requested depth 1, got %d", analysis.Depth)
+	}
+}
+
+func TestGameHandler_AnalyzeMove_Errors(t *testing.T) {
+	moves := [][2]string{{"h2", "e2"}, {"h9", "g7"}}
+	completed := setupAnalysisHandler(models.GameStatusCompleted, moves)
+	active := setupAnalysisHandler(models.GameStatusActive, moves)
+
+	tests := []struct {
+		name     string
+		router   http.Handler
+		path     string
+		deviceID string
+		status   int
+		code     string
+	}{
+		{"not a participant", completed, "/api/v1/games/game-1/moves/1/analysis", "spectator", http.StatusForbidden, "not_in_game"},
+		{"game in progress", active, "/api/v1/games/game-1/moves/1/analysis", "red-player", http.StatusConflict, "game_not_finished"},
+		{"unknown game", completed, "/api/v1/games/missing/moves/1/analysis", "red-player", http.StatusNotFound, "game_not_found"},
+		{"move out of range", completed, "/api/v1/games/game-1/moves/3/analysis", "red-player", http.StatusNotFound, "move_not_found"},
+		{"invalid move number", completed, "/api/v1/games/game-1/moves/zero/analysis", "red-player", http.StatusBadRequest, "invalid_move_number"},
+		{"depth above maximum", completed, "/api/v1/games/game-1/moves/1/analysis?depth=15", "red-player", http.StatusBadRequest, "depth_exceeded"},
+		{"invalid depth", completed, "/api/v1/games/game-1/moves/1/analysis?depth=0", "red-player", http.StatusBadRequest, "invalid_depth"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := analyzeMove(tt.router, tt.path, tt.deviceID)
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tt.code) {
+				t.Errorf("Expected error code %q, got %s", tt.code, w.Body.String())
+			}
+		})
+	}
+}
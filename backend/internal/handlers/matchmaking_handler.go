@@ -4,27 +4,80 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/xiangqi/chinese-chess-backend/internal/models"
 	"github.com/xiangqi/chinese-chess-backend/internal/services"
 )
 
+// defaultTurnTimeout is used when a player does not pick a turn timeout.
+const defaultTurnTimeout = 300
+
 // MatchmakingHandler handles matchmaking-related HTTP requests.
 type MatchmakingHandler struct {
 	matchmakingService *services.MatchmakingService
+	timeoutPresets     []int
+}
+
+// NewMatchmakingHandler creates a new MatchmakingHandler that only accepts
+// the given turn timeout presets.
+func NewMatchmakingHandler(matchmakingService *services.MatchmakingService, timeoutPresets []int) *MatchmakingHandler {
+	return &MatchmakingHandler{
+		matchmakingService: matchmakingService,
+		timeoutPresets:     timeoutPresets,
+	}
+}
+
+// resolveTurnTimeout returns the turn timeout to queue with.
+func (h *MatchmakingHandler) resolveTurnTimeout(timeout int) (int, bool) {
+	return resolveTurnTimeout(h.timeoutPresets, timeout)
 }
 
-// NewMatchmakingHandler creates a new MatchmakingHandler.
-func NewMatchmakingHandler(matchmakingService *services.MatchmakingService) *MatchmakingHandler {
-	return &MatchmakingHandler{matchmakingService: matchmakingService}
+// resolveTurnTimeout returns the turn timeout a game is played with and
+// whether it is one of the presets. A zero timeout selects the default, or
+// the first preset if the default is not allowed.
+func resolveTurnTimeout(presets []int, timeout int) (int, bool) {
+	if timeout == 0 {
+		if isTimeoutPreset(presets, defaultTurnTimeout) || len(presets) == 0 {
+			return defaultTurnTimeout, true
+		}
+		return presets[0], true
+	}
+	return timeout, isTimeoutPreset(presets, timeout)
+}
+
+func isTimeoutPreset(presets []int, timeout int) bool {
+	for _, preset := range presets {
+		if preset == timeout {
+			return true
+		}
+	}
+	return false
 }
 
 // JoinQueueRequest represents a request to join the matchmaking queue.
 type JoinQueueRequest struct {
 	Settings struct {
-		TurnTimeout    int     `json:"turn_timeout"`
+		TurnTimeout int `json:"turn_timeout"`
+		// PreferredColor is "red" or "black"; it is honored unless the
+		// opponent asked for the same color
 		PreferredColor *string `json:"preferred_color"`
+		Public         bool    `json:"public"`
+		// TimeControl is "per_move" (the default), "fischer" or
+		// "bronstein"; BaseTime is each side's time for a Fischer or
+		// Bronstein game and Increment the Fischer increment or Bronstein
+		// delay, in seconds
+		TimeControl models.TimeControl `json:"time_control"`
+		BaseTime    int                `json:"base_time"`
+		Increment   int                `json:"increment"`
+		// Ranked defaults to true; casual games don't affect stats or ratings
+		Ranked *bool `json:"ranked"`
+		// Assist defaults to true; it is only on if both players allow it
+		Assist *bool `json:"assist"`
+		// MatchPreference is "fast" to accept any opponent, "close" to wait
+		// longer for a similar rating, or empty for the default
+		MatchPreference models.MatchPreference `json:"match_preference"`
 	} `json:"settings"`
 }
 
@@ -42,15 +95,48 @@ func (h *MatchmakingHandler) JoinQueue(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Default timeout to 5 minutes if not specified
-	if req.Settings.TurnTimeout == 0 {
-		req.Settings.TurnTimeout = 300
+	turnTimeout, ok := h.resolveTurnTimeout(req.Settings.TurnTimeout)
+	if !ok {
+		respondError(w, http.StatusBadRequest, "invalid_turn_timeout",
+			fmt.Sprintf("Turn timeout must be one of %v seconds", h.timeoutPresets))
+		return
+	}
+
+	if !services.ValidTimeControl(req.Settings.TimeControl, req.Settings.BaseTime, req.Settings.Increment) {
+		respondError(w, http.StatusBadRequest, "invalid_time_control",
+			fmt.Sprintf("Time control must be \"per_move\", or \"fischer\" or \"bronstein\" with a base time of %d to %d seconds and an increment of at most %d seconds",
+				services.MinBaseTimeSeconds, services.MaxBaseTimeSeconds, services.MaxIncrementSeconds))
+		return
+	}
+
+	if !services.ValidMatchPreference(req.Settings.MatchPreference) {
+		respondError(w, http.StatusBadRequest, "invalid_match_preference",
+			"Match preference must be \"fast\" or \"close\"")
+		return
+	}
+
+	var preferredColor models.PlayerColor
+	if req.Settings.PreferredColor != nil {
+		preferredColor = models.PlayerColor(*req.Settings.PreferredColor)
+		if preferredColor != models.PlayerColorRed && preferredColor != models.PlayerColorBlack {
+			respondError(w, http.StatusBadRequest, "invalid_preferred_color",
+				"Preferred color must be \"red\" or \"black\"")
+			return
+		}
 	}
 
 	entry := &models.MatchmakingEntry{
-		DeviceID:    deviceID,
-		DisplayName: "Player", // TODO: Get from user service
-		TurnTimeout: req.Settings.TurnTimeout,
+		DeviceID:       deviceID,
+		DisplayName:    "Player", // TODO: Get from user service
+		TurnTimeout:    turnTimeout,
+		TimeControl:    req.Settings.TimeControl,
+		BaseTime:       req.Settings.BaseTime,
+		Increment:      req.Settings.Increment,
+		Public:         req.Settings.Public,
+		Ranked:         req.Settings.Ranked == nil || *req.Settings.Ranked,
+		Assist:         req.Settings.Assist == nil || *req.Settings.Assist,
+		Preference:     req.Settings.MatchPreference,
+		PreferredColor: preferredColor,
 	}
 
 	status, err := h.matchmakingService.JoinQueue(r.Context(), entry)
@@ -73,6 +159,7 @@ func (h *MatchmakingHandler) JoinQueue(w http.ResponseWriter, r *http.Request) {
 		response["game_id"] = status.GameID
 		response["opponent_name"] = status.OpponentName
 		response["your_color"] = status.YourColor
+		response["claim_seconds_remaining"] = status.ClaimSecondsRemaining
 	}
 
 	respondJSON(w, http.StatusOK, response)
@@ -119,6 +206,7 @@ func (h *MatchmakingHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
 		response["game_id"] = status.GameID
 		response["opponent_name"] = status.OpponentName
 		response["your_color"] = status.YourColor
+		response["claim_seconds_remaining"] = status.ClaimSecondsRemaining
 	}
 
 	respondJSON(w, http.StatusOK, response)
@@ -0,0 +1,147 @@
+// Package handlers provides tests for the matchmaking handlers.
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// testTimeoutPresets mirrors the default configuration.
+var testTimeoutPresets = []int{30, 60, 300}
+
+func TestMatchmakingHandler_JoinQueue_RejectsNonPresetTimeout(t *testing.T) {
+	handler := NewMatchmakingHandler(nil, testTimeoutPresets)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"settings": map[string]interface{}{"turn_timeout": 45},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/matchmaking/join", bytes.NewReader(body))
+	req.Header.Set("X-Device-ID", "device-123")
+	w := httptest.NewRecorder()
+
+	handler.JoinQueue(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", w.Code)
+	}
+
+	var response map[string]map[string]string
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response["error"]["code"] != "invalid_turn_timeout" {
+		t.Errorf("Expected error code 'invalid_turn_timeout', got %q", response["error"]["code"])
+	}
+}
+
+func TestMatchmakingHandler_ResolveTurnTimeout(t *testing.T) {
+	handler := NewMatchmakingHandler(nil, testTimeoutPresets)
+
+	tests := []struct {
+		timeout int
+		want    int
+		ok      bool
+	}{
+		{30, 30, true},
+		{60, 60, true},
+		{300, 300, true},
+		{0, 300, true},
+		{45, 45, false},
+		{-30, -30, false},
+		{600, 600, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := handler.resolveTurnTimeout(tt.timeout)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("resolveTurnTimeout(%d) = (%d, %v), want (%d, %v)", tt.timeout, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestMatchmakingHandler_ResolveTurnTimeout_DefaultNotPreset(t *testing.T) {
+	handler := NewMatchmakingHandler(nil, []int{30, 60})
+
+	if got, ok := handler.resolveTurnTimeout(0); got != 30 || !ok {
+		t.Errorf("Expected unset timeout to use the first preset, got (%d, %v)", got, ok)
+	}
+	if _, ok := handler.resolveTurnTimeout(300); ok {
+		t.Error("Expected 300 to be rejected when it is not a preset")
+	}
+}
+
+func TestMatchmakingHandler_JoinQueue_RejectsUnknownMatchPreference(t *testing.T) {
+	handler := NewMatchmakingHandler(nil, testTimeoutPresets)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"settings": map[string]interface{}{"match_preference": "slow"},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/matchmaking/join", bytes.NewReader(body))
+	req.Header.Set("X-Device-ID", "device-123")
+	w := httptest.NewRecorder()
+
+	handler.JoinQueue(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", w.Code)
+	}
+
+	var response map[string]map[string]string
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response["error"]["code"] != "invalid_match_preference" {
+		t.Errorf("Expected error code 'invalid_match_preference', got %q", response["error"]["code"])
+	}
+}
+
+func TestMatchmakingHandler_JoinQueue_RejectsUnknownPreferredColor(t *testing.T) {
+	handler := NewMatchmakingHandler(nil, testTimeoutPresets)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"settings": map[string]interface{}{"preferred_color": "green"},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/matchmaking/join", bytes.NewReader(body))
+	req.Header.Set("X-Device-ID", "device-123")
+	w := httptest.NewRecorder()
+
+	handler.JoinQueue(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", w.Code)
+	}
+
+	var response map[string]map[string]string
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response["error"]["code"] != "invalid_preferred_color" {
+		t.Errorf("Expected error code 'invalid_preferred_color', got %q", response["error"]["code"])
+	}
+}
+
+func TestMatchmakingHandler_JoinQueue_RejectsInvalidTimeControl(t *testing.T) {
+	testCases := []map[string]interface{}{
+		{"time_control": "hourglass"},
+		{"time_control": "per_move", "increment": 5},
+		{"time_control": "per_move", "base_time": 600},
+		{"time_control": "fischer", "base_time": 600, "increment": -1},
+		{"time_control": "bronstein", "base_time": 600, "increment": 3600},
+		{"time_control": "fischer", "increment": 5},
+		{"time_control": "fischer", "base_time": 86400, "increment": 5},
+	}
+
+	for _, settings := range testCases {
+		handler := NewMatchmakingHandler(nil, testTimeoutPresets)
+
+		body, _ := json.Marshal(map[string]interface{}{"settings": settings})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/matchmaking/join", bytes.NewReader(body))
+		req.Header.Set("X-Device-ID", "device-123")
+		w := httptest.NewRecorder()
+
+		handler.JoinQueue(w, req)
+
+		var response map[string]map[string]string
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if w.Code != http.StatusBadRequest || response["error"]["code"] != "invalid_time_control" {
+			t.Errorf("%v: expected 400 invalid_time_control, got %d %q", settings, w.Code, response["error"]["code"])
+		}
+	}
+}
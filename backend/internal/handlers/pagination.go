@@ -0,0 +1,54 @@
+// Package handlers contains HTTP request handlers.
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 50
+)
+
+// Pagination is the pagination metadata returned by paged endpoints.
+type Pagination struct {
+	Page       int `json:"page"`
+	PageSize   int `json:"page_size"`
+	TotalPages int `json:"total_pages"`
+	TotalCount int `json:"total_count"`
+}
+
+// NewPagination returns the metadata for the given page of totalCount items.
+func NewPagination(page, pageSize, totalCount int) Pagination {
+	return Pagination{
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages(totalCount, pageSize),
+		TotalCount: totalCount,
+	}
+}
+
+// totalPages returns how many pages of pageSize it takes to hold count items.
+func totalPages(count, pageSize int) int {
+	if pageSize < 1 {
+		return 0
+	}
+	return (count + pageSize - 1) / pageSize
+}
+
+// parsePagination reads the page and page_size query parameters, falling
+// back to the first page and the default size for missing or invalid values.
+func parsePagination(r *http.Request) (page, pageSize int) {
+	page, _ = strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	pageSize, _ = strconv.Atoi(r.URL.Query().Get("page_size"))
+	if pageSize < 1 || pageSize > maxPageSize {
+		pageSize = defaultPageSize
+	}
+
+	return page, pageSize
+}
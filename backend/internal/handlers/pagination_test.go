@@ -0,0 +1,56 @@
+// Package handlers provides unit tests for pagination metadata.
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewPagination_TotalPages(t *testing.T) {
+	testCases := []struct {
+		name       string
+		totalCount int
+		pageSize   int
+		expected   int
+	}{
+		{"no items", 0, 20, 0},
+		{"fewer than a page", 5, 20, 1},
+		{"exactly one page", 20, 20, 1},
+		{"exactly divisible", 60, 20, 3},
+		{"remainder", 61, 20, 4},
+		{"single item pages", 7, 1, 7},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pagination := NewPagination(2, tc.pageSize, tc.totalCount)
+			if pagination.TotalPages != tc.expected {
+				t.Errorf("Expected %d pages for %d items of %d, got %d", tc.expected, tc.totalCount, tc.pageSize, pagination.TotalPages)
+			}
+			if pagination.Page != 2 || pagination.PageSize != tc.pageSize || pagination.TotalCount != tc.totalCount {
+				t.Errorf("Unexpected pagination %+v", pagination)
+			}
+		})
+	}
+}
+
+func TestParsePagination(t *testing.T) {
+	testCases := []struct {
+		query          string
+		page, pageSize int
+	}{
+		{"", 1, defaultPageSize},
+		{"?page=3&page_size=10", 3, 10},
+		{"?page=0&page_size=0", 1, defaultPageSize},
+		{"?page=-2&page_size=500", 1, defaultPageSize},
+		{"?page=abc&page_size=50", 1, 50},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest("GET", "/api/v1/games/history"+tc.query, nil)
+		page, pageSize := parsePagination(req)
+		if page != tc.page || pageSize != tc.pageSize {
+			t.Errorf("%q: expected page %d size %d, got page %d size %d", tc.query, tc.page, tc.pageSize, page, pageSize)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Package handlers contains HTTP request handlers.
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/services"
+)
+
+// RematchHandler handles rematch-related HTTP requests.
+type RematchHandler struct {
+	rematchService *services.RematchService
+}
+
+// NewRematchHandler creates a new RematchHandler.
+func NewRematchHandler(rematchService *services.RematchService) *RematchHandler {
+	return &RematchHandler{rematchService: rematchService}
+}
+
+// RequestRematch handles a rematch request for a finished game.
+// It responds with the new game ID when the rematch is created right away,
+// or with a pending status while waiting for the opponent.
+func (h *RematchHandler) RequestRematch(w http.ResponseWriter, r *http.Request) {
+	deviceID := r.Header.Get("X-Device-ID")
+	if deviceID == "" {
+		respondError(w, http.StatusUnauthorized, "missing_device_id", "Device ID is required")
+		return
+	}
+
+	gameID := chi.URLParam(r, "gameId")
+	if gameID == "" {
+		respondError(w, http.StatusBadRequest, "missing_game_id", "Game ID is required")
+		return
+	}
+
+	status, err := h.rematchService.RequestRematch(r.Context(), gameID, deviceID)
+	if err != nil {
+		if errors.Is(err, services.ErrGameNotFound) {
+			respondError(w, http.StatusNotFound, "game_not_found", "Game not found")
+			return
+		}
+		if errors.Is(err, services.ErrPlayerNotInGame) {
+			respondError(w, http.StatusForbidden, "not_in_game", "You are not a player in this game")
+			return
+		}
+		if errors.Is(err, services.ErrGameNotFinished) {
+			respondError(w, http.StatusConflict, "game_not_finished", "Game is still in progress")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "rematch_failed", "Failed to request rematch")
+		return
+	}
+
+	response := map[string]interface{}{
+		"status": status.Status,
+	}
+
+	httpStatus := http.StatusAccepted
+	if status.Status == services.RematchStatusCreated {
+		response["game_id"] = status.GameID
+		response["your_color"] = status.YourColor
+		httpStatus = http.StatusCreated
+	}
+
+	respondJSON(w, httpStatus, response)
+}
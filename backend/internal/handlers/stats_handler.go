@@ -0,0 +1,30 @@
+// Package handlers contains HTTP request handlers.
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/services"
+)
+
+// StatsHandler handles server stats HTTP requests.
+type StatsHandler struct {
+	statsService *services.StatsService
+}
+
+// NewStatsHandler creates a new StatsHandler.
+func NewStatsHandler(statsService *services.StatsService) *StatsHandler {
+	return &StatsHandler{statsService: statsService}
+}
+
+// GetServerStats returns aggregate server stats for the public
+// "games in progress" widget.
+func (h *StatsHandler) GetServerStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.statsService.GetServerStats(r.Context())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "fetch_failed", "Failed to get server stats")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, stats)
+}
@@ -0,0 +1,98 @@
+// Package handlers provides tests for the stats handlers.
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+	"github.com/xiangqi/chinese-chess-backend/internal/services"
+)
+
+// mockStatsCounts serves fixed counts for the stats service dependencies.
+type mockStatsCounts struct {
+	users       int
+	gamesToday  int
+	activeGames int
+	queueSize   int
+	since       time.Time
+}
+
+func (m *mockStatsCounts) Count(ctx context.Context) (int, error) {
+	return m.users, nil
+}
+
+func (m *mockStatsCounts) CountCreatedSince(ctx context.Context, since time.Time) (int, error) {
+	m.since = since
+	return m.gamesToday, nil
+}
+
+func (m *mockStatsCounts) CountByStatus(ctx context.Context, status models.GameStatus) (int, error) {
+	if status != models.GameStatusActive {
+		return 0, nil
+	}
+	return m.activeGames, nil
+}
+
+func (m *mockStatsCounts) QueueSize(ctx context.Context) (int, error) {
+	return m.queueSize, nil
+}
+
+func getServerStats(t *testing.T, handler *StatsHandler) map[string]int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/stats/server", nil)
+	w := httptest.NewRecorder()
+
+	handler.GetServerStats(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var response map[string]int
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return response
+}
+
+func TestStatsHandler_GetServerStats(t *testing.T) {
+	counts := &mockStatsCounts{users: 120, gamesToday: 35, activeGames: 7, queueSize: 3}
+	handler := NewStatsHandler(services.NewStatsService(counts, counts, counts))
+
+	response := getServerStats(t, handler)
+
+	expected := map[string]int{
+		"total_users":  120,
+		"games_today":  35,
+		"active_games": 7,
+		"queue_size":   3,
+	}
+	for field, want := range expected {
+		if got, ok := response[field]; !ok || got != want {
+			t.Errorf("Expected %s = %d, got %d (present: %v)", field, want, got, ok)
+		}
+	}
+
+	// Games today are counted from midnight UTC
+	if counts.since.Hour() != 0 || counts.since.Minute() != 0 || counts.since.Location() != time.UTC {
+		t.Errorf("Expected games today to count from midnight UTC, got %v", counts.since)
+	}
+}
+
+func TestStatsHandler_GetServerStats_Cached(t *testing.T) {
+	counts := &mockStatsCounts{users: 10, activeGames: 2}
+	handler := NewStatsHandler(services.NewStatsService(counts, counts, counts))
+
+	getServerStats(t, handler)
+	counts.users = 11
+	counts.activeGames = 5
+
+	response := getServerStats(t, handler)
+	if response["total_users"] != 10 || response["active_games"] != 2 {
+		t.Errorf("Expected cached stats, got %v", response)
+	}
+}
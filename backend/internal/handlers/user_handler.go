@@ -8,6 +8,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
 	"github.com/xiangqi/chinese-chess-backend/internal/services"
 )
 
@@ -34,8 +35,11 @@ type UserResponse struct {
 	ID          string        `json:"id"`
 	DisplayName string        `json:"display_name"`
 	Stats       StatsResponse `json:"stats"`
+	AutoRematch bool          `json:"auto_rematch"`
 	CreatedAt   string        `json:"created_at"`
 	UpdatedAt   string        `json:"updated_at,omitempty"`
+
+	ResignSuggestions bool `json:"resign_suggestions"`
 }
 
 // StatsResponse represents user stats in API responses.
@@ -45,6 +49,9 @@ type StatsResponse struct {
 	Losses        int     `json:"losses"`
 	Draws         int     `json:"draws"`
 	WinPercentage float64 `json:"win_percentage"`
+
+	Rating          int `json:"rating"`
+	RatingDeviation int `json:"rating_deviation"`
 }
 
 // Register handles user registration.
@@ -88,9 +95,15 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 			Losses:        stats.Losses,
 			Draws:         stats.Draws,
 			WinPercentage: stats.WinPercentage,
+
+			Rating:          stats.Rating,
+			RatingDeviation: stats.RatingDeviation,
 		},
-		CreatedAt: user.CreatedAt.Format("2006-01-02T15:04:05Z"),
-		UpdatedAt: user.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+		AutoRematch: user.AutoRematch,
+		CreatedAt:   models.FormatTimestamp(user.CreatedAt),
+		UpdatedAt:   models.FormatTimestamp(user.UpdatedAt),
+
+		ResignSuggestions: user.ResignSuggestions,
 	}
 
 	respondJSON(w, http.StatusCreated, response)
@@ -124,17 +137,26 @@ func (h *UserHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 			Losses:        stats.Losses,
 			Draws:         stats.Draws,
 			WinPercentage: stats.WinPercentage,
+
+			Rating:          stats.Rating,
+			RatingDeviation: stats.RatingDeviation,
 		},
-		CreatedAt: user.CreatedAt.Format("2006-01-02T15:04:05Z"),
-		UpdatedAt: user.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+		AutoRematch: user.AutoRematch,
+		CreatedAt:   models.FormatTimestamp(user.CreatedAt),
+		UpdatedAt:   models.FormatTimestamp(user.UpdatedAt),
+
+		ResignSuggestions: user.ResignSuggestions,
 	}
 
 	respondJSON(w, http.StatusOK, response)
 }
 
 // UpdateProfileRequest represents a profile update request.
+// Any field may be omitted; at least one must be provided.
 type UpdateProfileRequest struct {
-	DisplayName string `json:"display_name"`
+	DisplayName       string `json:"display_name"`
+	AutoRematch       *bool  `json:"auto_rematch,omitempty"`
+	ResignSuggestions *bool  `json:"resign_suggestions,omitempty"`
 }
 
 // UpdateProfile handles updating a user profile.
@@ -151,7 +173,20 @@ func (h *UserHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.userService.UpdateDisplayName(r.Context(), deviceID, req.DisplayName)
+	var user *models.User
+	var err error
+
+	// A request without any setting is treated as a display name update so
+	// that the usual validation error is returned.
+	if req.DisplayName != "" || (req.AutoRematch == nil && req.ResignSuggestions == nil) {
+		user, err = h.userService.UpdateDisplayName(r.Context(), deviceID, req.DisplayName)
+	}
+	if err == nil && req.AutoRematch != nil {
+		user, err = h.userService.SetAutoRematch(r.Context(), deviceID, *req.AutoRematch)
+	}
+	if err == nil && req.ResignSuggestions != nil {
+		user, err = h.userService.SetResignSuggestions(r.Context(), deviceID, *req.ResignSuggestions)
+	}
 	if err != nil {
 		if errors.Is(err, services.ErrUserNotFound) {
 			respondError(w, http.StatusNotFound, "user_not_found", "User not found")
@@ -164,6 +199,10 @@ func (h *UserHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 			respondError(w, http.StatusBadRequest, "invalid_display_name", err.Error())
 			return
 		}
+		if errors.Is(err, services.ErrRenameTooSoon) {
+			respondError(w, http.StatusTooManyRequests, "rename_too_soon", "Display name was changed too recently")
+			return
+		}
 		respondError(w, http.StatusInternalServerError, "update_failed", "Failed to update profile")
 		return
 	}
@@ -171,7 +210,10 @@ func (h *UserHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	response := map[string]interface{}{
 		"id":           user.ID,
 		"display_name": user.DisplayName,
-		"updated_at":   user.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+		"auto_rematch": user.AutoRematch,
+		"updated_at":   models.FormatTimestamp(user.UpdatedAt),
+
+		"resign_suggestions": user.ResignSuggestions,
 	}
 
 	respondJSON(w, http.StatusOK, response)
@@ -55,6 +55,9 @@ func (m *mockUserRepo) UpdateStats(ctx context.Context, id string, stats models.
 		user.Wins = stats.Wins
 		user.Losses = stats.Losses
 		user.Draws = stats.Draws
+		user.WinBreakdown = stats.WinBreakdown
+		user.Rating = stats.Rating
+		user.RatingDeviation = stats.RatingDeviation
 	}
 	return nil
 }
@@ -82,8 +85,6 @@ func TestUserHandler_Register_ValidRequest(t *testing.T) {
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/users/register", bytes.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
 
-	w := httptest.NewRecorder()
-
 	// For integration tests, we would use the actual handler with mocked service
 	// Here we test the JSON parsing
 	var parsed RegisterRequest
@@ -156,7 +157,7 @@ func TestUserHandler_GetProfile_ValidRequest(t *testing.T) {
 				Draws:         1,
 				WinPercentage: 60.0,
 			},
-			CreatedAt: time.Now().Format("2006-01-02T15:04:05Z"),
+			CreatedAt: models.FormatTimestamp(time.Now()),
 		}
 		respondJSON(w, http.StatusOK, response)
 	})
@@ -216,7 +217,7 @@ func TestUserHandler_UpdateProfile_ValidRequest(t *testing.T) {
 		response := map[string]interface{}{
 			"id":           deviceID,
 			"display_name": req.DisplayName,
-			"updated_at":   time.Now().Format("2006-01-02T15:04:05Z"),
+			"updated_at":   models.FormatTimestamp(time.Now()),
 		}
 
 		respondJSON(w, http.StatusOK, response)
@@ -267,6 +268,65 @@ func TestUserHandler_UpdateProfile_InvalidJSON(t *testing.T) {
 	}
 }
 
+func TestUserHandler_UpdateProfile_RenameTooSoon(t *testing.T) {
+	repo := newMockUserRepo()
+	changedAt := time.Now().Add(-time.Hour)
+	repo.Create(context.Background(), &models.User{ID: "device-123", DisplayName: "OldName", DisplayNameChangedAt: &changedAt})
+
+	handler := NewUserHandler(services.NewUserServiceWithRenameInterval(repo, 24*time.Hour))
+	r := chi.NewRouter()
+	r.Patch("/api/v1/users/{deviceId}", handler.UpdateProfile)
+
+	body, _ := json.Marshal(UpdateProfileRequest{DisplayName: "NewName"})
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/users/device-123", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status 429, got %d", w.Code)
+	}
+
+	var response map[string]map[string]string
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response["error"]["code"] != "rename_too_soon" {
+		t.Errorf("Expected error code 'rename_too_soon', got '%s'", response["error"]["code"])
+	}
+}
+
+func TestUserHandler_UpdateProfile_ResignSuggestions(t *testing.T) {
+	repo := newMockUserRepo()
+	repo.Create(context.Background(), &models.User{ID: "device-123", DisplayName: "Player"})
+
+	handler := NewUserHandler(services.NewUserService(repo))
+	r := chi.NewRouter()
+	r.Patch("/api/v1/users/{deviceId}", handler.UpdateProfile)
+
+	enabled := true
+	body, _ := json.Marshal(UpdateProfileRequest{ResignSuggestions: &enabled})
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/users/device-123", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response["resign_suggestions"] != true || response["display_name"] != "Player" {
+		t.Errorf("Expected resign suggestions on and the name kept, got %v", response)
+	}
+
+	user, _ := repo.GetByID(context.Background(), "device-123")
+	if !user.ResignSuggestions {
+		t.Error("Expected the setting to be stored")
+	}
+}
+
 // ========== Response Helper Tests ==========
 
 func TestRespondJSON(t *testing.T) {
@@ -2,6 +2,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"os"
 	"strings"
@@ -10,6 +11,8 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/rs/zerolog/log"
 
+	"github.com/xiangqi/chinese-chess-backend/internal/config"
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
 	"github.com/xiangqi/chinese-chess-backend/internal/services"
 	ws "github.com/xiangqi/chinese-chess-backend/internal/websocket"
 )
@@ -30,52 +33,120 @@ func isDevelopment() bool {
 	return env == "" || env == "development"
 }
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		origin := r.Header.Get("Origin")
-
-		// In development, allow localhost origins
-		if isDevelopment() {
-			if origin == "" ||
-				strings.HasPrefix(origin, "http://localhost") ||
-				strings.HasPrefix(origin, "http://127.0.0.1") ||
-				strings.HasPrefix(origin, "capacitor://") ||
-				strings.HasPrefix(origin, "ionic://") {
-				return true
-			}
+// checkOrigin reports whether a WebSocket upgrade request comes from an allowed origin.
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+
+	// In development, allow localhost origins
+	if isDevelopment() {
+		if origin == "" ||
+			strings.HasPrefix(origin, "http://localhost") ||
+			strings.HasPrefix(origin, "http://127.0.0.1") ||
+			strings.HasPrefix(origin, "capacitor://") ||
+			strings.HasPrefix(origin, "ionic://") {
+			return true
 		}
+	}
 
-		// Check against allowed origins
-		for _, allowed := range AllowedOrigins {
-			if origin == allowed {
-				return true
-			}
+	// Check against allowed origins
+	for _, allowed := range AllowedOrigins {
+		if origin == allowed {
+			return true
 		}
+	}
 
-		// Log rejected origins for monitoring
-		log.Warn().
-			Str("origin", origin).
-			Str("remote_addr", r.RemoteAddr).
-			Msg("WebSocket connection rejected: origin not allowed")
+	// Log rejected origins for monitoring
+	log.Warn().
+		Str("origin", origin).
+		Str("remote_addr", r.RemoteAddr).
+		Msg("WebSocket connection rejected: origin not allowed")
+
+	return false
+}
 
-		return false
-	},
+// newUpgrader creates a WebSocket upgrader. When compression is enabled,
+// permessage-deflate is negotiated with clients that offer it.
+func newUpgrader(cfg config.WebSocketConfig) websocket.Upgrader {
+	return websocket.Upgrader{
+		ReadBufferSize:    1024,
+		WriteBufferSize:   1024,
+		CheckOrigin:       checkOrigin,
+		EnableCompression: cfg.EnableCompression,
+	}
 }
 
 // WebSocketHandler handles WebSocket connections.
 type WebSocketHandler struct {
 	hub         *ws.Hub
 	gameService *services.GameService
+	tokens      *services.ConnectTokenService
+	reconnects  *services.ReconnectTokens
+	upgrader    websocket.Upgrader
+	cfg         config.WebSocketConfig
 }
 
 // NewWebSocketHandler creates a new WebSocketHandler.
-func NewWebSocketHandler(hub *ws.Hub, gameService *services.GameService) *WebSocketHandler {
+func NewWebSocketHandler(hub *ws.Hub, gameService *services.GameService, cfg config.WebSocketConfig) *WebSocketHandler {
 	return &WebSocketHandler{
 		hub:         hub,
 		gameService: gameService,
+		upgrader:    newUpgrader(cfg),
+		cfg:         cfg,
+	}
+}
+
+// NewWebSocketHandlerWithTokens creates a new WebSocketHandler that also
+// accepts single-use connect tokens in place of a device ID.
+func NewWebSocketHandlerWithTokens(hub *ws.Hub, gameService *services.GameService, tokens *services.ConnectTokenService, cfg config.WebSocketConfig) *WebSocketHandler {
+	h := NewWebSocketHandler(hub, gameService, cfg)
+	h.tokens = tokens
+	return h
+}
+
+// SetReconnectTokens requires players reconnecting to a game to present the
+// reconnection token they received when they first joined.
+func (h *WebSocketHandler) SetReconnectTokens(tokens *services.ReconnectTokens) {
+	h.reconnects = tokens
+}
+
+// IssueConnectToken issues a short-lived, single-use token that authorizes
+// one WebSocket connection to the game.
+func (h *WebSocketHandler) IssueConnectToken(w http.ResponseWriter, r *http.Request) {
+	deviceID := r.Header.Get("X-Device-ID")
+	if deviceID == "" {
+		respondError(w, http.StatusUnauthorized, "missing_device_id", "Device ID is required")
+		return
+	}
+
+	gameID := chi.URLParam(r, "gameId")
+	if gameID == "" {
+		respondError(w, http.StatusBadRequest, "missing_game_id", "Game ID is required")
+		return
+	}
+
+	if h.tokens == nil {
+		respondError(w, http.StatusNotFound, "connect_tokens_disabled", "Connect tokens are not enabled")
+		return
+	}
+
+	token, err := h.tokens.Issue(r.Context(), gameID, deviceID)
+	if err != nil {
+		if errors.Is(err, services.ErrGameNotFound) {
+			respondError(w, http.StatusNotFound, "game_not_found", "Game not found")
+			return
+		}
+		if errors.Is(err, services.ErrPlayerNotInGame) {
+			respondError(w, http.StatusForbidden, "not_in_game", "You are not a player in this game")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "token_failed", "Failed to issue connect token")
+		return
 	}
+
+	respondJSON(w, http.StatusCreated, map[string]interface{}{
+		"token":      token.Token,
+		"expires_at": models.FormatTimestamp(token.ExpiresAt),
+	})
 }
 
 // HandleConnection handles WebSocket connection upgrades.
@@ -86,7 +157,44 @@ func (h *WebSocketHandler) HandleConnection(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	role := ws.ClientRolePlayer
+	switch r.URL.Query().Get("role") {
+	case "", string(ws.ClientRolePlayer):
+	case string(ws.ClientRoleSpectator):
+		role = ws.ClientRoleSpectator
+	default:
+		http.Error(w, "Role must be player or spectator", http.StatusBadRequest)
+		return
+	}
+
 	deviceID := r.Header.Get("X-Device-ID")
+
+	// A connect token identifies the device and is consumed on upgrade
+	var token string
+	if h.tokens != nil {
+		token = r.URL.Query().Get("token")
+	}
+	if token != "" {
+		tokenDeviceID, err := h.tokens.Validate(r.Context(), token, gameID)
+		if err != nil {
+			if errors.Is(err, services.ErrConnectTokenExpired) {
+				http.Error(w, "Connect token has expired", http.StatusUnauthorized)
+				return
+			}
+			if errors.Is(err, services.ErrConnectTokenUsed) {
+				http.Error(w, "Connect token has already been used", http.StatusUnauthorized)
+				return
+			}
+			http.Error(w, "Invalid connect token", http.StatusUnauthorized)
+			return
+		}
+		if deviceID != "" && deviceID != tokenDeviceID {
+			http.Error(w, "Connect token was issued to another device", http.StatusUnauthorized)
+			return
+		}
+		deviceID = tokenDeviceID
+	}
+
 	if deviceID == "" {
 		// Also check query parameter for WebSocket connections
 		deviceID = r.URL.Query().Get("device_id")
@@ -103,21 +211,84 @@ func (h *WebSocketHandler) HandleConnection(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// Verify player is part of the game
-	if game.RedPlayerID != deviceID && game.BlackPlayerID != deviceID {
+	// Players must be part of the game; anyone may watch a public game
+	isParticipant := game.RedPlayerID == deviceID || game.BlackPlayerID == deviceID
+	if role == ws.ClientRolePlayer && !isParticipant {
 		http.Error(w, "You are not a participant in this game", http.StatusForbidden)
 		return
 	}
+	if role == ws.ClientRoleSpectator && !isParticipant && !game.IsPublic {
+		http.Error(w, "This game is not open to spectators", http.StatusForbidden)
+		return
+	}
+
+	// A player already seated must prove they are the same player, so a
+	// client cannot take over a seat by claiming another device ID. Both
+	// players have been seated in any game with moves, whichever instance
+	// seated them, but only a shared secret lets them prove it anywhere but
+	// the instance that issued their token.
+	if role == ws.ClientRolePlayer && h.reconnects != nil &&
+		((h.reconnects.Shared() && game.TotalMoves > 0) || h.hub.IsReconnection(r.Context(), gameID, deviceID)) {
+		color := models.PlayerColorRed
+		if game.BlackPlayerID == deviceID {
+			color = models.PlayerColorBlack
+		}
+		reconnectToken := r.URL.Query().Get("reconnect_token")
+		if err := h.reconnects.Verify(reconnectToken, gameID, deviceID, color); err != nil {
+			log.Warn().
+				Str("game_id", gameID).
+				Str("device_id", deviceID).
+				Str("remote_addr", r.RemoteAddr).
+				Msg("WebSocket reconnection rejected: bad reconnection token")
+			if errors.Is(err, services.ErrReconnectTokenMissing) {
+				http.Error(w, "Reconnection token is required", http.StatusUnauthorized)
+				return
+			}
+			http.Error(w, "Invalid reconnection token", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	// Players take priority over spectators for connection slots
+	if !h.hub.AcquireConnection(role == ws.ClientRolePlayer) {
+		log.Warn().
+			Str("game_id", gameID).
+			Int("connections", h.hub.ConnectionCount()).
+			Msg("WebSocket connection rejected: server at capacity")
+		http.Error(w, "Server is at connection capacity", http.StatusServiceUnavailable)
+		return
+	}
 
 	// Upgrade connection to WebSocket
-	conn, err := upgrader.Upgrade(w, r, nil)
+	conn, err := h.upgrader.Upgrade(w, r, nil)
 	if err != nil {
+		h.hub.ReleaseConnection()
 		log.Error().Err(err).Msg("Failed to upgrade WebSocket connection")
 		return
 	}
 
+	// Consume the token only once the upgrade has succeeded; a concurrent
+	// connection that validated the same token loses here.
+	if token != "" {
+		if err := h.tokens.Consume(r.Context(), token); err != nil {
+			h.hub.ReleaseConnection()
+			conn.WriteMessage(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "connect token already used"))
+			conn.Close()
+			log.Warn().Err(err).Str("game_id", gameID).Msg("WebSocket connection rejected: connect token reused")
+			return
+		}
+	}
+
+	if h.cfg.EnableCompression {
+		if err := conn.SetCompressionLevel(h.cfg.CompressionLevel); err != nil {
+			log.Warn().Err(err).Int("level", h.cfg.CompressionLevel).Msg("Invalid WebSocket compression level")
+		}
+	}
+
 	// Create client and register with hub
-	client := ws.NewClient(h.hub, conn, gameID, deviceID)
+	client := ws.NewClientWithRole(h.hub, conn, gameID, deviceID, role)
+	client.CompressionThreshold = h.cfg.CompressionThreshold
 	h.hub.Register(client)
 
 	// Start client read/write goroutines
@@ -127,5 +298,6 @@ func (h *WebSocketHandler) HandleConnection(w http.ResponseWriter, r *http.Reque
 	log.Info().
 		Str("game_id", gameID).
 		Str("device_id", deviceID).
+		Str("role", string(role)).
 		Msg("WebSocket connection established")
 }
@@ -0,0 +1,524 @@
+// Package handlers provides unit tests for the WebSocket handler.
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/config"
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+	"github.com/xiangqi/chinese-chess-backend/internal/repository"
+	"github.com/xiangqi/chinese-chess-backend/internal/services"
+	ws "github.com/xiangqi/chinese-chess-backend/internal/websocket"
+)
+
+// dialWithCompression upgrades a connection using an upgrader built from cfg,
+// with a client that offers permessage-deflate, and returns the handshake response.
+func dialWithCompression(t *testing.T, cfg config.WebSocketConfig) *http.Response {
+	t.Helper()
+
+	upgrader := newUpgrader(cfg)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conn.Close()
+	}))
+	defer server.Close()
+
+	dialer := websocket.Dialer{EnableCompression: true}
+	conn, resp, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	conn.Close()
+	return resp
+}
+
+func TestUpgrader_AdvertisesCompressionWhenEnabled(t *testing.T) {
+	resp := dialWithCompression(t, config.WebSocketConfig{EnableCompression: true, CompressionLevel: 1})
+
+	extensions := resp.Header.Get("Sec-WebSocket-Extensions")
+	if !strings.Contains(extensions, "permessage-deflate") {
+		t.Errorf("Expected permessage-deflate to be negotiated, got %q", extensions)
+	}
+}
+
+func TestUpgrader_NoCompressionWhenDisabled(t *testing.T) {
+	resp := dialWithCompression(t, config.WebSocketConfig{EnableCompression: false})
+
+	if extensions := resp.Header.Get("Sec-WebSocket-Extensions"); extensions != "" {
+		t.Errorf("Expected no extensions to be negotiated, got %q", extensions)
+	}
+}
+
+// newTestWebSocketGameService creates a game service with an active game
+// "game-1" between "red-player" and "black-player".
+func newTestWebSocketGameService() *services.GameService {
+	gameRepo := newMockGameRepo()
+	gameRepo.Create(context.Background(), &models.Game{
+		ID:            "game-1",
+		RedPlayerID:   "red-player",
+		BlackPlayerID: "black-player",
+		Status:        models.GameStatusActive,
+	})
+	return services.NewGameService(gameRepo, &mockMoveRepo{}, newMockUserRepo())
+}
+
+// newTestWebSocketServer starts a server for WebSocket connections to game
+// "game-1" between "red-player" and "black-player", with the given cap.
+func newTestWebSocketServer(t *testing.T, maxConnections int) (*httptest.Server, *ws.Hub) {
+	t.Helper()
+
+	gameService := newTestWebSocketGameService()
+
+	hub := ws.NewHub(gameService, config.RulesConfig{})
+	hub.SetMaxConnections(maxConnections)
+	go hub.Run()
+
+	handler := NewWebSocketHandler(hub, gameService, config.WebSocketConfig{})
+	r := chi.NewRouter()
+	r.Get("/ws/games/{gameId}", handler.HandleConnection)
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+
+	return server, hub
+}
+
+// mockKeyValueStore is an in-memory KeyValueStore whose keys never expire.
+type mockKeyValueStore struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func newMockKeyValueStore() *mockKeyValueStore {
+	return &mockKeyValueStore{values: make(map[string]string)}
+}
+
+func (m *mockKeyValueStore) Get(ctx context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value, ok := m.values[key]
+	if !ok {
+		return "", repository.ErrKeyNotFound
+	}
+	return value, nil
+}
+
+func (m *mockKeyValueStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = value
+	return nil
+}
+
+func (m *mockKeyValueStore) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.values[key]; ok {
+		return false, nil
+	}
+	m.values[key] = value
+	return true, nil
+}
+
+func (m *mockKeyValueStore) Del(ctx context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range keys {
+		delete(m.values, key)
+	}
+	return nil
+}
+
+// mockHashStore is an in-memory HashStore whose hashes never expire.
+type mockHashStore struct {
+	mu     sync.Mutex
+	hashes map[string]map[string]string
+}
+
+func newMockHashStore() *mockHashStore {
+	return &mockHashStore{hashes: make(map[string]map[string]string)}
+}
+
+func (m *mockHashStore) SetField(ctx context.Context, key, field, value string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.hashes[key] == nil {
+		m.hashes[key] = make(map[string]string)
+	}
+	m.hashes[key][field] = value
+	return nil
+}
+
+func (m *mockHashStore) Fields(ctx context.Context, key string) (map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	fields := make(map[string]string, len(m.hashes[key]))
+	for field, value := range m.hashes[key] {
+		fields[field] = value
+	}
+	return fields, nil
+}
+
+func (m *mockHashStore) DeleteField(ctx context.Context, key, field string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.hashes[key], field)
+	return nil
+}
+
+// newTestTokenServer starts a server for game-1 that issues connect tokens
+// and accepts them on WebSocket upgrade.
+func newTestTokenServer(t *testing.T) (*httptest.Server, *ws.Hub) {
+	t.Helper()
+
+	gameService := newTestWebSocketGameService()
+	tokens := services.NewConnectTokenService(newMockKeyValueStore(), gameService, 0)
+
+	hub := ws.NewHub(gameService, config.RulesConfig{})
+	go hub.Run()
+	t.Cleanup(hub.Shutdown)
+
+	handler := NewWebSocketHandlerWithTokens(hub, gameService, tokens, config.WebSocketConfig{})
+	r := chi.NewRouter()
+	r.Post("/games/{gameId}/connect-token", handler.IssueConnectToken)
+	r.Get("/ws/games/{gameId}", handler.HandleConnection)
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+
+	return server, hub
+}
+
+// issueConnectToken requests a connect token for game-1 as the given device.
+func issueConnectToken(t *testing.T, server *httptest.Server, deviceID string) string {
+	t.Helper()
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/games/game-1/connect-token", nil)
+	req.Header.Set("X-Device-ID", deviceID)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Token request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Token     string `json:"token"`
+		ExpiresAt string `json:"expires_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode token response: %v", err)
+	}
+	if body.Token == "" || body.ExpiresAt == "" {
+		t.Fatalf("Expected token and expiry, got %+v", body)
+	}
+	return body.Token
+}
+
+// dialWithToken opens a WebSocket connection to game-1 using a connect token.
+func dialWithToken(server *httptest.Server, token string) (*websocket.Conn, *http.Response, error) {
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/games/game-1?token=" + token
+	return websocket.DefaultDialer.Dial(url, nil)
+}
+
+// dialGame opens a WebSocket connection to game-1 as the given device.
+func dialGame(server *httptest.Server, deviceID string) (*websocket.Conn, *http.Response, error) {
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/games/game-1?device_id=" + deviceID
+	return websocket.DefaultDialer.Dial(url, nil)
+}
+
+// waitForConnections waits until the hub reports the expected connection count.
+func waitForConnections(t *testing.T, hub *ws.Hub, want int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for hub.ConnectionCount() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d connections, have %d", want, hub.ConnectionCount())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWebSocketHandler_RejectsConnectionsBeyondCap(t *testing.T) {
+	server, hub := newTestWebSocketServer(t, 1)
+
+	red, _, err := dialGame(server, "red-player")
+	if err != nil {
+		t.Fatalf("First connection should be accepted: %v", err)
+	}
+
+	_, resp, err := dialGame(server, "black-player")
+	if err == nil {
+		t.Fatal("Connection beyond the cap should be rejected")
+	}
+	if resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status 503, got %v", resp)
+	}
+	if hub.ConnectionCount() != 1 {
+		t.Errorf("Rejected connection should not be counted, have %d", hub.ConnectionCount())
+	}
+
+	// Closing a connection frees its slot
+	red.Close()
+	waitForConnections(t, hub, 0)
+
+	black, _, err := dialGame(server, "black-player")
+	if err != nil {
+		t.Fatalf("Connection should be accepted once a slot is free: %v", err)
+	}
+	black.Close()
+	waitForConnections(t, hub, 0)
+	hub.Shutdown()
+}
+
+func TestWebSocketHandler_ConnectTokenIsSingleUse(t *testing.T) {
+	server, hub := newTestTokenServer(t)
+	token := issueConnectToken(t, server, "red-player")
+
+	conn, _, err := dialWithToken(server, token)
+	if err != nil {
+		t.Fatalf("Connection with a fresh token should be accepted: %v", err)
+	}
+	defer conn.Close()
+	waitForConnections(t, hub, 1)
+
+	_, resp, err := dialWithToken(server, token)
+	if err == nil {
+		t.Fatal("Reusing a connect token should be rejected")
+	}
+	if resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected status 401, got %v", resp)
+	}
+}
+
+func TestWebSocketHandler_RejectsUnknownConnectToken(t *testing.T) {
+	server, _ := newTestTokenServer(t)
+
+	_, resp, err := dialWithToken(server, "not-a-token")
+	if err == nil {
+		t.Fatal("Unknown connect token should be rejected")
+	}
+	if resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected status 401, got %v", resp)
+	}
+}
+
+func TestWebSocketHandler_IssueConnectToken_RequiresPlayer(t *testing.T) {
+	server, _ := newTestTokenServer(t)
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/games/game-1/connect-token", nil)
+	req.Header.Set("X-Device-ID", "stranger")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Token request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", resp.StatusCode)
+	}
+}
+
+// dialGameAs opens a WebSocket connection to game-1 as the given device and role.
+func dialGameAs(server *httptest.Server, deviceID, role string) (*websocket.Conn, *http.Response, error) {
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/games/game-1?device_id=" + deviceID + "&role=" + role
+	return websocket.DefaultDialer.Dial(url, nil)
+}
+
+func TestWebSocketHandler_SpectatorRole(t *testing.T) {
+	server, hub := newTestWebSocketServer(t, 0)
+	defer hub.Shutdown()
+
+	_, resp, err := dialGameAs(server, "red-player", "referee")
+	if err == nil || resp == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected status 400 for an unknown role, got %v", resp)
+	}
+
+	// game-1 is private, so only its players may watch it
+	_, resp, err = dialGameAs(server, "stranger", "spectator")
+	if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("Expected status 403 for a stranger watching a private game, got %v", resp)
+	}
+
+	conn, _, err := dialGameAs(server, "red-player", "spectator")
+	if err != nil {
+		t.Fatalf("A player should be able to watch their own game: %v", err)
+	}
+	waitForConnections(t, hub, 1)
+	conn.Close()
+	waitForConnections(t, hub, 0)
+}
+
+// newTestReconnectServer starts a server for game-1 that requires
+// reconnection tokens, signed with the returned signer.
+func newTestReconnectServer(t *testing.T) (*httptest.Server, *ws.Hub, *services.ReconnectTokens) {
+	t.Helper()
+
+	tokens, err := services.NewReconnectTokens("test-secret")
+	if err != nil {
+		t.Fatalf("Failed to create reconnect tokens: %v", err)
+	}
+
+	server, hub := startReconnectServer(t, newTestWebSocketGameService(), tokens)
+	return server, hub, tokens
+}
+
+// startReconnectServer starts a server for the given game service that
+// requires reconnection tokens signed by tokens.
+func startReconnectServer(t *testing.T, gameService *services.GameService, tokens *services.ReconnectTokens) (*httptest.Server, *ws.Hub) {
+	t.Helper()
+
+	hub := ws.NewHub(gameService, config.RulesConfig{})
+	hub.SetReconnectTokens(tokens)
+	go hub.Run()
+	t.Cleanup(hub.Shutdown)
+
+	handler := NewWebSocketHandler(hub, gameService, config.WebSocketConfig{})
+	handler.SetReconnectTokens(tokens)
+	r := chi.NewRouter()
+	r.Get("/ws/games/{gameId}", handler.HandleConnection)
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+
+	return server, hub
+}
+
+// joinGame sends a join message and returns the reconnection token from the
+// joined response.
+func joinGame(t *testing.T, conn *websocket.Conn) string {
+	t.Helper()
+	if err := conn.WriteJSON(map[string]interface{}{"type": "join", "payload": map[string]interface{}{}}); err != nil {
+		t.Fatalf("Failed to send join: %v", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	for {
+		var msg struct {
+			Type    string                 `json:"type"`
+			Payload map[string]interface{} `json:"payload"`
+		}
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("Expected a joined message: %v", err)
+		}
+		if msg.Type == "joined" {
+			token, _ := msg.Payload["reconnect_token"].(string)
+			return token
+		}
+	}
+}
+
+// dialReconnect opens a player connection to game-1 with a reconnection token.
+func dialReconnect(server *httptest.Server, deviceID, token string) (*websocket.Conn, *http.Response, error) {
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/games/game-1?device_id=" + deviceID + "&reconnect_token=" + token
+	return websocket.DefaultDialer.Dial(url, nil)
+}
+
+func TestWebSocketHandler_ReconnectWithToken(t *testing.T) {
+	server, hub, tokens := newTestReconnectServer(t)
+
+	conn, _, err := dialGame(server, "red-player")
+	if err != nil {
+		t.Fatalf("The first connection should not need a token: %v", err)
+	}
+	token := joinGame(t, conn)
+	if token != tokens.Issue("game-1", "red-player", models.PlayerColorRed) {
+		t.Fatalf("Expected the joined message to carry red's token, got %q", token)
+	}
+	conn.Close()
+	waitForConnections(t, hub, 0)
+
+	conn, _, err = dialReconnect(server, "red-player", token)
+	if err != nil {
+		t.Fatalf("Expected the reconnection to succeed: %v", err)
+	}
+	defer conn.Close()
+	waitForConnections(t, hub, 1)
+}
+
+func TestWebSocketHandler_ReconnectRejectsBadTokens(t *testing.T) {
+	server, hub, tokens := newTestReconnectServer(t)
+
+	conn, _, err := dialGame(server, "red-player")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	joinGame(t, conn)
+	conn.Close()
+	waitForConnections(t, hub, 0)
+
+	// A client claiming red's device ID without red's token is turned away
+	badTokens := map[string]string{
+		"missing":      "",
+		"forged":       "deadbeef",
+		"not hex":      "not-a-token",
+		"other player": tokens.Issue("game-1", "black-player", models.PlayerColorBlack),
+		"other game":   tokens.Issue("game-2", "red-player", models.PlayerColorRed),
+		"other color":  tokens.Issue("game-1", "red-player", models.PlayerColorBlack),
+	}
+	for name, token := range badTokens {
+		_, resp, err := dialReconnect(server, "red-player", token)
+		if err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("%s: expected status 401, got %v", name, resp)
+		}
+	}
+
+	// Black has not joined yet, so their first connection needs no token
+	conn, _, err = dialGame(server, "black-player")
+	if err != nil {
+		t.Fatalf("Expected black's first connection to succeed: %v", err)
+	}
+	conn.Close()
+}
+
+func TestWebSocketHandler_ReconnectRequiresTokenForSeatOnOtherInstance(t *testing.T) {
+	server, _, tokens := newTestReconnectServer(t)
+
+	// Another instance seated red, so this instance's room has never seen them
+	tokens.SetSeatStore(newMockKeyValueStore())
+	tokens.MarkSeated(context.Background(), "game-1", "red-player")
+
+	_, resp, err := dialGame(server, "red-player")
+	if err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected status 401 without a token, got %v", resp)
+	}
+
+	conn, _, err := dialReconnect(server, "red-player", tokens.Issue("game-1", "red-player", models.PlayerColorRed))
+	if err != nil {
+		t.Fatalf("Expected the reconnection to succeed: %v", err)
+	}
+	conn.Close()
+}
+
+func TestWebSocketHandler_ReconnectAfterRestartWithoutSharedSecret(t *testing.T) {
+	tokens, err := services.NewReconnectTokens("")
+	if err != nil {
+		t.Fatalf("Failed to create reconnect tokens: %v", err)
+	}
+	gameService := newTestWebSocketGameService()
+	game, _ := gameService.GetGame(context.Background(), "game-1")
+	game.TotalMoves = 2
+
+	// A freshly started instance with its own random secret could never
+	// verify a token issued before, so the player is let back in
+	server, hub := startReconnectServer(t, gameService, tokens)
+	conn, _, err := dialGame(server, "red-player")
+	if err != nil {
+		t.Fatalf("Expected the player to rejoin without a verifiable token: %v", err)
+	}
+	defer conn.Close()
+	waitForConnections(t, hub, 1)
+}
@@ -2,8 +2,11 @@
 package middleware
 
 import (
+	"context"
+	"net"
 	"net/http"
 	"regexp"
+	"strconv"
 	"sync"
 	"time"
 
@@ -50,36 +53,47 @@ func DeviceAuth(next http.Handler) http.Handler {
 	})
 }
 
+// limiter decides whether another request for a key is within its allowance.
+type limiter interface {
+	allow(ctx context.Context, key string) bool
+}
+
 // rateLimitEntry tracks request counts for rate limiting.
 type rateLimitEntry struct {
 	count     int
 	resetTime time.Time
 }
 
-// rateLimiter stores rate limit data per device.
+// rateLimiter stores rate limit data per device in process memory, so each
+// server instance keeps its own counts.
 type rateLimiter struct {
 	mu      sync.Mutex
 	entries map[string]*rateLimitEntry
 	limit   int
 	window  time.Duration
+
+	// done is closed when the cleanup goroutine exits
+	done chan struct{}
 }
 
-// newRateLimiter creates a new rate limiter.
-func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+// newRateLimiter creates a new rate limiter whose cleanup goroutine runs
+// until ctx is cancelled.
+func newRateLimiter(ctx context.Context, limit int, window time.Duration) *rateLimiter {
 	rl := &rateLimiter{
 		entries: make(map[string]*rateLimitEntry),
 		limit:   limit,
 		window:  window,
+		done:    make(chan struct{}),
 	}
 
 	// Start cleanup goroutine
-	go rl.cleanup()
+	go rl.cleanup(ctx)
 
 	return rl
 }
 
 // allow checks if a request should be allowed.
-func (rl *rateLimiter) allow(deviceID string) bool {
+func (rl *rateLimiter) allow(_ context.Context, deviceID string) bool {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
@@ -103,12 +117,20 @@ func (rl *rateLimiter) allow(deviceID string) bool {
 	return true
 }
 
-// cleanup removes expired entries periodically.
-func (rl *rateLimiter) cleanup() {
+// cleanup removes expired entries periodically until ctx is cancelled.
+func (rl *rateLimiter) cleanup(ctx context.Context) {
+	defer close(rl.done)
+
 	ticker := time.NewTicker(rl.window)
 	defer ticker.Stop()
 
-	for range ticker.C {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
 		rl.mu.Lock()
 		now := time.Now()
 		for deviceID, entry := range rl.entries {
@@ -125,10 +147,21 @@ var globalRateLimiter *rateLimiter
 
 // RateLimiter middleware limits requests per device.
 func RateLimiter(requestsPerMinute int) func(http.Handler) http.Handler {
+	return RateLimiterWithContext(context.Background(), requestsPerMinute)
+}
+
+// RateLimiterWithContext is RateLimiter with a context that stops the
+// limiter's background cleanup when cancelled, typically on server shutdown.
+func RateLimiterWithContext(ctx context.Context, requestsPerMinute int) func(http.Handler) http.Handler {
 	if globalRateLimiter == nil {
-		globalRateLimiter = newRateLimiter(requestsPerMinute, time.Minute)
+		globalRateLimiter = newRateLimiter(ctx, requestsPerMinute, time.Minute)
 	}
 
+	return deviceRateLimiter(globalRateLimiter)
+}
+
+// deviceRateLimiter rejects requests once a device exceeds l's allowance.
+func deviceRateLimiter(l limiter) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			deviceID := r.Header.Get("X-Device-ID")
@@ -137,7 +170,7 @@ func RateLimiter(requestsPerMinute int) func(http.Handler) http.Handler {
 				deviceID = r.RemoteAddr
 			}
 
-			if !globalRateLimiter.allow(deviceID) {
+			if !l.allow(r.Context(), deviceID) {
 				w.Header().Set("Content-Type", "application/json")
 				w.Header().Set("Retry-After", "60")
 				w.WriteHeader(http.StatusTooManyRequests)
@@ -149,3 +182,50 @@ func RateLimiter(requestsPerMinute int) func(http.Handler) http.Handler {
 		})
 	}
 }
+
+// RegistrationRateLimiter limits registrations per client IP address.
+// Registration is not covered by device authentication and a new client can
+// send any device ID, so the limit is keyed on the IP resolved by the
+// RealIP middleware instead.
+func RegistrationRateLimiter(limit int, window time.Duration) func(http.Handler) http.Handler {
+	return RegistrationRateLimiterWithContext(context.Background(), limit, window)
+}
+
+// RegistrationRateLimiterWithContext is RegistrationRateLimiter with a
+// context that stops the limiter's background cleanup when cancelled.
+func RegistrationRateLimiterWithContext(ctx context.Context, limit int, window time.Duration) func(http.Handler) http.Handler {
+	return ipRegistrationLimiter(newRateLimiter(ctx, limit, window), window)
+}
+
+// ipRegistrationLimiter rejects registrations once a client IP exceeds l's
+// allowance for the window.
+func ipRegistrationLimiter(l limiter, window time.Duration) func(http.Handler) http.Handler {
+	retryAfter := strconv.Itoa(int(window.Seconds()))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := clientIP(r)
+
+			if !l.allow(r.Context(), ip) {
+				log.Warn().Str("ip", ip).Msg("Registration rate limit exceeded")
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Retry-After", retryAfter)
+				w.WriteHeader(http.StatusTooManyRequests)
+				w.Write([]byte(`{"error":{"code":"registration_rate_limited","message":"Too many registrations from this address. Please try again later."}}`))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientIP returns the client IP address of a request without the port.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		// RealIP stores a bare address without a port
+		return r.RemoteAddr
+	}
+	return host
+}
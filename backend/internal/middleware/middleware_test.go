@@ -0,0 +1,172 @@
+// Package middleware provides unit tests for HTTP middleware.
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+func TestRegistrationRateLimiter(t *testing.T) {
+	handler := chimiddleware.RealIP(RegistrationRateLimiter(5, time.Hour)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		}),
+	))
+
+	register := func(ip string, deviceID string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/users/register", nil)
+		req.RemoteAddr = "10.0.0.1:12345"
+		req.Header.Set("X-Forwarded-For", ip)
+		req.Header.Set("X-Device-ID", deviceID)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// A fresh device ID per request must not bypass the limit
+	deviceIDs := []string{"device-1", "device-2", "device-3", "device-4", "device-5"}
+	for _, deviceID := range deviceIDs {
+		if code := register("203.0.113.7", deviceID); code != http.StatusCreated {
+			t.Fatalf("Expected registration to succeed, got %d", code)
+		}
+	}
+
+	if code := register("203.0.113.7", "device-6"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected %d after exceeding the IP limit, got %d", http.StatusTooManyRequests, code)
+	}
+
+	if code := register("198.51.100.2", "device-7"); code != http.StatusCreated {
+		t.Errorf("Expected registration from another IP to succeed, got %d", code)
+	}
+}
+
+func TestRegistrationRateLimiter_RemoteAddrPort(t *testing.T) {
+	handler := RegistrationRateLimiter(1, time.Hour)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		}),
+	)
+
+	// Different source ports from the same host share the limit
+	for i, addr := range []string{"192.0.2.1:1000", "192.0.2.1:2000"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/users/register", nil)
+		req.RemoteAddr = addr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		want := http.StatusCreated
+		if i > 0 {
+			want = http.StatusTooManyRequests
+		}
+		if rec.Code != want {
+			t.Errorf("Request from %s: expected %d, got %d", addr, want, rec.Code)
+		}
+	}
+}
+
+func TestRateLimiter_CleanupStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	limiter := newRateLimiter(ctx, 1, time.Hour)
+
+	select {
+	case <-limiter.done:
+		t.Fatal("Cleanup should keep running until the context is cancelled")
+	default:
+	}
+
+	cancel()
+
+	select {
+	case <-limiter.done:
+	case <-time.After(time.Second):
+		t.Fatal("Cleanup did not stop after the context was cancelled")
+	}
+}
+
+// mockCounter is an in-memory Counter standing in for Redis.
+type mockCounter struct {
+	mu     sync.Mutex
+	counts map[string]int64
+	err    error
+}
+
+func newMockCounter() *mockCounter {
+	return &mockCounter{counts: make(map[string]int64)}
+}
+
+func (m *mockCounter) IncrWithExpiry(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return 0, m.err
+	}
+	m.counts[key]++
+	return m.counts[key], nil
+}
+
+func TestRedisRateLimiter_SharesCountsAcrossInstances(t *testing.T) {
+	counter := newMockCounter()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	// Two server instances backed by the same store
+	instances := []http.Handler{
+		RedisRateLimiter(counter, 3)(ok),
+		RedisRateLimiter(counter, 3)(ok),
+	}
+
+	for i := 0; i < 5; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/games/history", nil)
+		req.Header.Set("X-Device-ID", "device-1")
+		rec := httptest.NewRecorder()
+
+		instances[i%2].ServeHTTP(rec, req)
+
+		want := http.StatusOK
+		if i >= 3 {
+			want = http.StatusTooManyRequests
+		}
+		if rec.Code != want {
+			t.Errorf("Request %d: expected %d, got %d", i+1, want, rec.Code)
+		}
+	}
+}
+
+func TestRedisRateLimiter_NewWindowResetsCount(t *testing.T) {
+	counter := newMockCounter()
+	rl := newRedisRateLimiter(counter, "test", 1, time.Minute)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	rl.now = func() time.Time { return now }
+
+	if !rl.allow(context.Background(), "device-1") {
+		t.Fatal("First request should be allowed")
+	}
+	if rl.allow(context.Background(), "device-1") {
+		t.Fatal("Second request in the window should be rejected")
+	}
+
+	now = now.Add(time.Minute)
+	if !rl.allow(context.Background(), "device-1") {
+		t.Error("First request in the next window should be allowed")
+	}
+}
+
+func TestRedisRateLimiter_AllowsWhenCounterFails(t *testing.T) {
+	counter := newMockCounter()
+	counter.err = errors.New("connection refused")
+	rl := newRedisRateLimiter(counter, "test", 1, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		if !rl.allow(context.Background(), "device-1") {
+			t.Fatalf("Request %d should be allowed while the counter is down", i+1)
+		}
+	}
+}
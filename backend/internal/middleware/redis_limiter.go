@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Counter increments shared counters, typically backed by Redis, so that
+// rate limits hold across every server instance.
+type Counter interface {
+	// IncrWithExpiry increments key, sets it to expire after ttl and
+	// returns the new count.
+	IncrWithExpiry(ctx context.Context, key string, ttl time.Duration) (int64, error)
+}
+
+// redisRateLimiter counts requests in fixed windows stored in a Counter.
+// Each window gets its own key, so a key left without an expiry can only
+// ever affect the window it belongs to.
+type redisRateLimiter struct {
+	counter Counter
+	prefix  string
+	limit   int
+	window  time.Duration
+	now     func() time.Time
+}
+
+// newRedisRateLimiter creates a limiter sharing counts through counter
+// under keys starting with prefix.
+func newRedisRateLimiter(counter Counter, prefix string, limit int, window time.Duration) *redisRateLimiter {
+	return &redisRateLimiter{
+		counter: counter,
+		prefix:  prefix,
+		limit:   limit,
+		window:  window,
+		now:     time.Now,
+	}
+}
+
+// allow checks if a request should be allowed. If the counter cannot be
+// reached the request is let through, since refusing all traffic during a
+// Redis outage would be worse than briefly not limiting it.
+func (rl *redisRateLimiter) allow(ctx context.Context, key string) bool {
+	bucket := rl.now().UnixNano() / int64(rl.window)
+	windowKey := fmt.Sprintf("%s:%s:%d", rl.prefix, key, bucket)
+
+	count, err := rl.counter.IncrWithExpiry(ctx, windowKey, rl.window)
+	if err != nil {
+		log.Warn().Err(err).Str("key", key).Msg("Rate limit counter unavailable, allowing request")
+		return true
+	}
+
+	return count <= int64(rl.limit)
+}
+
+// RedisRateLimiter is RateLimiter with counts shared through counter, for
+// deployments running more than one server instance.
+func RedisRateLimiter(counter Counter, requestsPerMinute int) func(http.Handler) http.Handler {
+	return deviceRateLimiter(newRedisRateLimiter(counter, "ratelimit:requests", requestsPerMinute, time.Minute))
+}
+
+// RedisRegistrationRateLimiter is RegistrationRateLimiter with counts shared
+// through counter.
+func RedisRegistrationRateLimiter(counter Counter, limit int, window time.Duration) func(http.Handler) http.Handler {
+	return ipRegistrationLimiter(newRedisRateLimiter(counter, "ratelimit:registrations", limit, window), window)
+}
@@ -13,17 +13,72 @@ type User struct {
 	Wins        int       `json:"wins" db:"wins"`                 // Games won
 	Losses      int       `json:"losses" db:"losses"`             // Games lost
 	Draws       int       `json:"draws" db:"draws"`               // Games drawn
+	AutoRematch bool      `json:"auto_rematch" db:"auto_rematch"` // Accept rematch requests without an offer
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`     // When user was created
 	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`     // When user was last updated
+
+	// ResignSuggestions opts the player in to resign_suggestion messages
+	// when their position has been hopeless for several moves.
+	ResignSuggestions bool `json:"resign_suggestions" db:"resign_suggestions"`
+
+	// DisplayNameChangedAt is when the display name was last changed, or nil
+	// if it has not changed since registration.
+	DisplayNameChangedAt *time.Time `json:"display_name_changed_at,omitempty" db:"display_name_changed_at"`
+
+	// WinBreakdown splits Wins by how each game was won.
+	WinBreakdown WinBreakdown `json:"win_breakdown"`
+
+	// Rating is the player's ELO rating from ranked games, and
+	// RatingDeviation how uncertain it still is.
+	Rating          int `json:"rating" db:"rating"`
+	RatingDeviation int `json:"rating_deviation" db:"rating_deviation"`
+}
+
+const (
+	// DefaultRating is the rating new players start with.
+	DefaultRating = 1200
+	// DefaultRatingDeviation is the rating deviation of a new player.
+	DefaultRatingDeviation = 350
+)
+
+// WinBreakdown counts a player's wins by result type, so that rankings can
+// weigh e.g. abandonment wins differently from checkmates.
+type WinBreakdown struct {
+	Checkmate   int `json:"checkmate" db:"wins_by_checkmate"`
+	Stalemate   int `json:"stalemate" db:"wins_by_stalemate"`
+	Timeout     int `json:"timeout" db:"wins_by_timeout"`
+	Resignation int `json:"resignation" db:"wins_by_resignation"`
+	Abandonment int `json:"abandonment" db:"wins_by_abandonment"`
+}
+
+// Add counts one win of the given result type. Result types that cannot
+// decide a game, such as draws, are ignored.
+func (b *WinBreakdown) Add(resultType ResultType) {
+	switch resultType {
+	case ResultTypeCheckmate:
+		b.Checkmate++
+	case ResultTypeStalemate:
+		b.Stalemate++
+	case ResultTypeTimeout:
+		b.Timeout++
+	case ResultTypeResignation:
+		b.Resignation++
+	case ResultTypeAbandonment:
+		b.Abandonment++
+	}
 }
 
 // UserStats returns the user's gameplay statistics.
 type UserStats struct {
-	TotalGames    int     `json:"total_games"`
-	Wins          int     `json:"wins"`
-	Losses        int     `json:"losses"`
-	Draws         int     `json:"draws"`
-	WinPercentage float64 `json:"win_percentage"`
+	TotalGames    int          `json:"total_games"`
+	Wins          int          `json:"wins"`
+	Losses        int          `json:"losses"`
+	Draws         int          `json:"draws"`
+	WinPercentage float64      `json:"win_percentage"`
+	WinBreakdown  WinBreakdown `json:"win_breakdown"`
+
+	Rating          int `json:"rating"`
+	RatingDeviation int `json:"rating_deviation"`
 }
 
 // Stats returns the user's stats.
@@ -38,6 +93,10 @@ func (u *User) Stats() UserStats {
 		Losses:        u.Losses,
 		Draws:         u.Draws,
 		WinPercentage: winPct,
+		WinBreakdown:  u.WinBreakdown,
+
+		Rating:          u.Rating,
+		RatingDeviation: u.RatingDeviation,
 	}
 }
 
@@ -60,22 +119,54 @@ const (
 	ResultTypeAbandonment ResultType = "abandonment"
 	ResultTypeDraw        ResultType = "draw"
 	ResultTypeStalemate   ResultType = "stalemate"
+	// ResultTypePerpetualCheck is a loss for the side that checked
+	// perpetually, under Asian rules.
+	ResultTypePerpetualCheck ResultType = "perpetual_check"
 )
 
-// Game represents a game record.
+// TimeControl is how a game's clocks run. TurnTimeoutSeconds is the time
+// for each turn under per-move control; under Fischer and Bronstein control
+// BaseTimeSeconds is each side's time for the whole game.
+type TimeControl string
+
+const (
+	// TimeControlPerMove gives each turn the full turn timeout.
+	TimeControlPerMove TimeControl = "per_move"
+	// TimeControlFischer adds IncrementSeconds to the mover's clock after
+	// each move.
+	TimeControlFischer TimeControl = "fischer"
+	// TimeControlBronstein waits IncrementSeconds at the start of each turn
+	// before the mover's clock starts running.
+	TimeControlBronstein TimeControl = "bronstein"
+)
+
+// Game represents a game record. RedTimeRemaining and BlackTimeRemaining
+// are the clocks saved after the last move, so a recreated room carries on
+// from them; they are nil until the first move.
 type Game struct {
-	ID                     string      `json:"id" db:"id"`
-	RedPlayerID            string      `json:"red_player_id" db:"red_player_id"`
-	BlackPlayerID          string      `json:"black_player_id" db:"black_player_id"`
-	Status                 GameStatus  `json:"status" db:"status"`
-	WinnerID               *string     `json:"winner_id,omitempty" db:"winner_id"`
-	ResultType             *ResultType `json:"result_type,omitempty" db:"result_type"`
-	TurnTimeoutSeconds     int         `json:"turn_timeout_seconds" db:"turn_timeout_seconds"`
-	RedRollbacksRemaining  int         `json:"red_rollbacks_remaining" db:"red_rollbacks_remaining"`
-	BlackRollbacksRemaining int        `json:"black_rollbacks_remaining" db:"black_rollbacks_remaining"`
-	TotalMoves             int         `json:"total_moves" db:"total_moves"`
-	CreatedAt              time.Time   `json:"created_at" db:"created_at"`
-	CompletedAt            *time.Time  `json:"completed_at,omitempty" db:"completed_at"`
+	ID                      string       `json:"id" db:"id"`
+	RedPlayerID             string       `json:"red_player_id" db:"red_player_id"`
+	BlackPlayerID           string       `json:"black_player_id" db:"black_player_id"`
+	Status                  GameStatus   `json:"status" db:"status"`
+	WinnerID                *string      `json:"winner_id,omitempty" db:"winner_id"`
+	WinnerColor             *PlayerColor `json:"winner_color,omitempty" db:"winner_color"`
+	ResultType              *ResultType  `json:"result_type,omitempty" db:"result_type"`
+	TurnTimeoutSeconds      int          `json:"turn_timeout_seconds" db:"turn_timeout_seconds"`
+	RedRollbacksRemaining   int          `json:"red_rollbacks_remaining" db:"red_rollbacks_remaining"`
+	BlackRollbacksRemaining int          `json:"black_rollbacks_remaining" db:"black_rollbacks_remaining"`
+	TotalMoves              int          `json:"total_moves" db:"total_moves"`
+	IsPublic                bool         `json:"is_public" db:"is_public"`
+	Ranked                  bool         `json:"ranked" db:"ranked"`
+	AssistEnabled           bool         `json:"assist_enabled" db:"assist_enabled"`
+	BotDifficulty           int          `json:"bot_difficulty,omitempty" db:"bot_difficulty"`
+	TimeControl             TimeControl  `json:"time_control" db:"time_control"`
+	IncrementSeconds        int          `json:"increment_seconds,omitempty" db:"increment_seconds"`
+	BaseTimeSeconds         int          `json:"base_time_seconds,omitempty" db:"base_time_seconds"`
+	RedTimeRemaining        *int         `json:"red_time_remaining,omitempty" db:"red_time_remaining"`
+	BlackTimeRemaining      *int         `json:"black_time_remaining,omitempty" db:"black_time_remaining"`
+	FinalFEN                *string      `json:"final_fen,omitempty" db:"final_fen"`
+	CreatedAt               time.Time    `json:"created_at" db:"created_at"`
+	CompletedAt             *time.Time   `json:"completed_at,omitempty" db:"completed_at"`
 }
 
 // PlayerColor represents the color/side of a player.
@@ -86,6 +177,14 @@ const (
 	PlayerColorBlack PlayerColor = "black"
 )
 
+// Opposite returns the opposite color.
+func (c PlayerColor) Opposite() PlayerColor {
+	if c == PlayerColorRed {
+		return PlayerColorBlack
+	}
+	return PlayerColorRed
+}
+
 // PieceType represents the type of a chess piece.
 type PieceType string
 
@@ -113,6 +212,17 @@ type Move struct {
 	Timestamp     time.Time  `json:"timestamp" db:"timestamp"`
 }
 
+// MovePayload represents a move as sent by a client, either over the
+// WebSocket connection or as part of an imported move list. Seq is the
+// client's sequence number for the move, used to acknowledge it and to
+// ignore retransmissions.
+type MovePayload struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	PieceType string `json:"piece_type"`
+	Seq       int64  `json:"seq,omitempty"`
+}
+
 // RollbackStatus represents the status of a rollback request.
 type RollbackStatus string
 
@@ -156,10 +266,34 @@ type GameState struct {
 	CapturedByBlack []Piece       `json:"captured_by_black"`
 }
 
+// MatchPreference trades matchmaking speed against opponent rating proximity.
+type MatchPreference string
+
+const (
+	// MatchPreferenceBalanced widens the accepted rating band at the normal pace.
+	MatchPreferenceBalanced MatchPreference = ""
+	// MatchPreferenceFast accepts any opponent to get a game quickly.
+	MatchPreferenceFast MatchPreference = "fast"
+	// MatchPreferenceClose widens the rating band slowly, waiting longer
+	// for an opponent of similar strength.
+	MatchPreferenceClose MatchPreference = "close"
+)
+
 // MatchmakingEntry represents a player in the matchmaking queue.
 type MatchmakingEntry struct {
-	DeviceID    string    `json:"device_id"`
-	DisplayName string    `json:"display_name"`
-	TurnTimeout int       `json:"turn_timeout"`
-	JoinedAt    time.Time `json:"joined_at"`
+	DeviceID    string          `json:"device_id"`
+	DisplayName string          `json:"display_name"`
+	TurnTimeout int             `json:"turn_timeout"`
+	TimeControl TimeControl     `json:"time_control,omitempty"`
+	Increment   int             `json:"increment,omitempty"`
+	BaseTime    int             `json:"base_time,omitempty"`
+	Public      bool            `json:"public"`
+	Ranked      bool            `json:"ranked"`
+	Assist      bool            `json:"assist"`
+	Rating      int             `json:"rating"`
+	Preference  MatchPreference `json:"preference,omitempty"`
+	// PreferredColor is the side the player asked to play, or empty for
+	// either
+	PreferredColor PlayerColor `json:"preferred_color,omitempty"`
+	JoinedAt       time.Time   `json:"joined_at"`
 }
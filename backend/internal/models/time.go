@@ -0,0 +1,15 @@
+// Package models contains the domain models for the Chinese Chess application.
+package models
+
+import (
+	"time"
+)
+
+// TimestampFormat is the layout of all timestamps sent to clients.
+const TimestampFormat = time.RFC3339
+
+// FormatTimestamp formats a time as an RFC 3339 timestamp in UTC,
+// e.g. "2024-01-15T10:30:00Z".
+func FormatTimestamp(t time.Time) string {
+	return t.UTC().Format(TimestampFormat)
+}
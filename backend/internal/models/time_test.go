@@ -0,0 +1,33 @@
+// Package models provides unit tests for timestamp formatting.
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatTimestamp_UTC(t *testing.T) {
+	ts := time.Date(2024, time.January, 15, 10, 30, 0, 0, time.UTC)
+
+	if got := FormatTimestamp(ts); got != "2024-01-15T10:30:00Z" {
+		t.Errorf("Expected '2024-01-15T10:30:00Z', got %q", got)
+	}
+}
+
+func TestFormatTimestamp_ConvertsToUTC(t *testing.T) {
+	shanghai := time.FixedZone("CST", 8*60*60)
+	ts := time.Date(2024, time.January, 15, 18, 30, 0, 0, shanghai)
+
+	got := FormatTimestamp(ts)
+	if got != "2024-01-15T10:30:00Z" {
+		t.Errorf("Expected non-UTC time converted to '2024-01-15T10:30:00Z', got %q", got)
+	}
+
+	parsed, err := time.Parse(time.RFC3339, got)
+	if err != nil {
+		t.Fatalf("Output should parse as RFC 3339: %v", err)
+	}
+	if !parsed.Equal(ts) {
+		t.Errorf("Expected parsed time to equal %v, got %v", ts, parsed)
+	}
+}
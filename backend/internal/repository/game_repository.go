@@ -18,27 +18,60 @@ var ErrGameNotFound = errors.New("game not found")
 // GameRepository handles game database operations.
 type GameRepository struct {
 	db *PostgresDB
+	// reads runs the history queries, on the read replica when one is set
+	reads querier
 }
 
 // NewGameRepository creates a new GameRepository.
 func NewGameRepository(db *PostgresDB) *GameRepository {
-	return &GameRepository{db: db}
+	return &GameRepository{db: db, reads: db.ReadPool()}
 }
 
 // Create creates a new game.
 func (r *GameRepository) Create(ctx context.Context, game *models.Game) error {
+	return insertGame(ctx, r.db.Pool(), game)
+}
+
+// CreateWithMoves creates a new game together with its moves in a single
+// transaction, so a failure part way through stores nothing.
+func (r *GameRepository) CreateWithMoves(ctx context.Context, game *models.Game, moves []*models.Move) error {
+	tx, err := r.db.Pool().Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := insertGame(ctx, tx, game); err != nil {
+		return err
+	}
+	for _, move := range moves {
+		if err := insertMove(ctx, tx, move); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit game: %w", err)
+	}
+	return nil
+}
+
+// insertGame inserts a game row using the pool or a transaction.
+func insertGame(ctx context.Context, db querier, game *models.Game) error {
 	query := `
 		INSERT INTO games (
 			id, red_player_id, black_player_id, status, winner_id, result_type,
 			turn_timeout_seconds, red_rollbacks_remaining, black_rollbacks_remaining,
-			total_moves, created_at, completed_at
+			total_moves, is_public, ranked, assist_enabled, bot_difficulty,
+			time_control, increment_seconds, base_time_seconds, final_fen, winner_color,
+			created_at, completed_at
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
 	`
 
 	game.CreatedAt = time.Now()
 
-	_, err := r.db.Pool().Exec(ctx, query,
+	_, err := db.Exec(ctx, query,
 		game.ID,
 		game.RedPlayerID,
 		game.BlackPlayerID,
@@ -49,6 +82,15 @@ func (r *GameRepository) Create(ctx context.Context, game *models.Game) error {
 		game.RedRollbacksRemaining,
 		game.BlackRollbacksRemaining,
 		game.TotalMoves,
+		game.IsPublic,
+		game.Ranked,
+		game.AssistEnabled,
+		game.BotDifficulty,
+		game.TimeControl,
+		game.IncrementSeconds,
+		game.BaseTimeSeconds,
+		game.FinalFEN,
+		game.WinnerColor,
 		game.CreatedAt,
 		game.CompletedAt,
 	)
@@ -65,7 +107,9 @@ func (r *GameRepository) GetByID(ctx context.Context, id string) (*models.Game,
 	query := `
 		SELECT id, red_player_id, black_player_id, status, winner_id, result_type,
 			   turn_timeout_seconds, red_rollbacks_remaining, black_rollbacks_remaining,
-			   total_moves, created_at, completed_at
+			   total_moves, is_public, ranked, assist_enabled, bot_difficulty,
+			   time_control, increment_seconds, base_time_seconds, red_time_remaining,
+			   black_time_remaining, final_fen, winner_color, created_at, completed_at
 		FROM games
 		WHERE id = $1
 	`
@@ -82,6 +126,17 @@ func (r *GameRepository) GetByID(ctx context.Context, id string) (*models.Game,
 		&game.RedRollbacksRemaining,
 		&game.BlackRollbacksRemaining,
 		&game.TotalMoves,
+		&game.IsPublic,
+		&game.Ranked,
+		&game.AssistEnabled,
+		&game.BotDifficulty,
+		&game.TimeControl,
+		&game.IncrementSeconds,
+		&game.BaseTimeSeconds,
+		&game.RedTimeRemaining,
+		&game.BlackTimeRemaining,
+		&game.FinalFEN,
+		&game.WinnerColor,
 		&game.CreatedAt,
 		&game.CompletedAt,
 	)
@@ -102,7 +157,8 @@ func (r *GameRepository) Update(ctx context.Context, game *models.Game) error {
 		UPDATE games
 		SET status = $2, winner_id = $3, result_type = $4,
 			red_rollbacks_remaining = $5, black_rollbacks_remaining = $6,
-			total_moves = $7, completed_at = $8
+			total_moves = $7, completed_at = $8, final_fen = $9,
+			red_time_remaining = $10, black_time_remaining = $11
 		WHERE id = $1
 	`
 
@@ -115,6 +171,9 @@ func (r *GameRepository) Update(ctx context.Context, game *models.Game) error {
 		game.BlackRollbacksRemaining,
 		game.TotalMoves,
 		game.CompletedAt,
+		game.FinalFEN,
+		game.RedTimeRemaining,
+		game.BlackTimeRemaining,
 	)
 
 	if err != nil {
@@ -133,7 +192,9 @@ func (r *GameRepository) GetHistoryByPlayer(ctx context.Context, playerID string
 	query := `
 		SELECT id, red_player_id, black_player_id, status, winner_id, result_type,
 			   turn_timeout_seconds, red_rollbacks_remaining, black_rollbacks_remaining,
-			   total_moves, created_at, completed_at
+			   total_moves, is_public, ranked, assist_enabled, bot_difficulty,
+			   time_control, increment_seconds, base_time_seconds, red_time_remaining,
+			   black_time_remaining, final_fen, winner_color, created_at, completed_at
 		FROM games
 		WHERE (red_player_id = $1 OR black_player_id = $1)
 		  AND status = 'completed'
@@ -141,7 +202,7 @@ func (r *GameRepository) GetHistoryByPlayer(ctx context.Context, playerID string
 		LIMIT $2 OFFSET $3
 	`
 
-	rows, err := r.db.Pool().Query(ctx, query, playerID, limit, offset)
+	rows, err := r.reads.Query(ctx, query, playerID, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get game history: %w", err)
 	}
@@ -161,6 +222,17 @@ func (r *GameRepository) GetHistoryByPlayer(ctx context.Context, playerID string
 			&game.RedRollbacksRemaining,
 			&game.BlackRollbacksRemaining,
 			&game.TotalMoves,
+			&game.IsPublic,
+			&game.Ranked,
+			&game.AssistEnabled,
+			&game.BotDifficulty,
+			&game.TimeControl,
+			&game.IncrementSeconds,
+			&game.BaseTimeSeconds,
+			&game.RedTimeRemaining,
+			&game.BlackTimeRemaining,
+			&game.FinalFEN,
+			&game.WinnerColor,
 			&game.CreatedAt,
 			&game.CompletedAt,
 		)
@@ -187,7 +259,7 @@ func (r *GameRepository) CountByPlayer(ctx context.Context, playerID string) (in
 	`
 
 	var count int
-	err := r.db.Pool().QueryRow(ctx, query, playerID).Scan(&count)
+	err := r.reads.QueryRow(ctx, query, playerID).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count games: %w", err)
 	}
@@ -200,7 +272,9 @@ func (r *GameRepository) GetActiveByPlayer(ctx context.Context, playerID string)
 	query := `
 		SELECT id, red_player_id, black_player_id, status, winner_id, result_type,
 			   turn_timeout_seconds, red_rollbacks_remaining, black_rollbacks_remaining,
-			   total_moves, created_at, completed_at
+			   total_moves, is_public, ranked, assist_enabled, bot_difficulty,
+			   time_control, increment_seconds, base_time_seconds, red_time_remaining,
+			   black_time_remaining, final_fen, winner_color, created_at, completed_at
 		FROM games
 		WHERE (red_player_id = $1 OR black_player_id = $1)
 		  AND status = 'active'
@@ -227,6 +301,17 @@ func (r *GameRepository) GetActiveByPlayer(ctx context.Context, playerID string)
 			&game.RedRollbacksRemaining,
 			&game.BlackRollbacksRemaining,
 			&game.TotalMoves,
+			&game.IsPublic,
+			&game.Ranked,
+			&game.AssistEnabled,
+			&game.BotDifficulty,
+			&game.TimeControl,
+			&game.IncrementSeconds,
+			&game.BaseTimeSeconds,
+			&game.RedTimeRemaining,
+			&game.BlackTimeRemaining,
+			&game.FinalFEN,
+			&game.WinnerColor,
 			&game.CreatedAt,
 			&game.CompletedAt,
 		)
@@ -238,3 +323,112 @@ func (r *GameRepository) GetActiveByPlayer(ctx context.Context, playerID string)
 
 	return games, nil
 }
+
+// GetLivePublic retrieves active public games with pagination, newest first.
+func (r *GameRepository) GetLivePublic(ctx context.Context, limit, offset int) ([]*models.Game, error) {
+	query := `
+		SELECT id, red_player_id, black_player_id, status, winner_id, result_type,
+			   turn_timeout_seconds, red_rollbacks_remaining, black_rollbacks_remaining,
+			   total_moves, is_public, ranked, assist_enabled, bot_difficulty,
+			   time_control, increment_seconds, base_time_seconds, red_time_remaining,
+			   black_time_remaining, final_fen, winner_color, created_at, completed_at
+		FROM games
+		WHERE status = 'active' AND is_public
+		ORDER BY created_at DESC
+		LIMIT $1 OFFSET $2
+	`
+
+	rows, err := r.db.Pool().Query(ctx, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get live games: %w", err)
+	}
+	defer rows.Close()
+
+	var games []*models.Game
+	for rows.Next() {
+		var game models.Game
+		err := rows.Scan(
+			&game.ID,
+			&game.RedPlayerID,
+			&game.BlackPlayerID,
+			&game.Status,
+			&game.WinnerID,
+			&game.ResultType,
+			&game.TurnTimeoutSeconds,
+			&game.RedRollbacksRemaining,
+			&game.BlackRollbacksRemaining,
+			&game.TotalMoves,
+			&game.IsPublic,
+			&game.Ranked,
+			&game.AssistEnabled,
+			&game.BotDifficulty,
+			&game.TimeControl,
+			&game.IncrementSeconds,
+			&game.BaseTimeSeconds,
+			&game.RedTimeRemaining,
+			&game.BlackTimeRemaining,
+			&game.FinalFEN,
+			&game.WinnerColor,
+			&game.CreatedAt,
+			&game.CompletedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan game: %w", err)
+		}
+		games = append(games, &game)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating game rows: %w", err)
+	}
+
+	return games, nil
+}
+
+// CountLivePublic returns the number of active public games.
+func (r *GameRepository) CountLivePublic(ctx context.Context) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM games
+		WHERE status = 'active' AND is_public
+	`
+
+	var count int
+	if err := r.db.Pool().QueryRow(ctx, query).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count live games: %w", err)
+	}
+
+	return count, nil
+}
+
+// CountCreatedSince returns the number of games created at or after since.
+func (r *GameRepository) CountCreatedSince(ctx context.Context, since time.Time) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM games
+		WHERE created_at >= $1
+	`
+
+	var count int
+	if err := r.db.Pool().QueryRow(ctx, query, since).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count games: %w", err)
+	}
+
+	return count, nil
+}
+
+// CountByStatus returns the number of games with the given status.
+func (r *GameRepository) CountByStatus(ctx context.Context, status models.GameStatus) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM games
+		WHERE status = $1
+	`
+
+	var count int
+	if err := r.db.Pool().QueryRow(ctx, query, status).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count games: %w", err)
+	}
+
+	return count, nil
+}
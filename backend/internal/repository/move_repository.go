@@ -20,6 +20,11 @@ func NewMoveRepository(db *PostgresDB) *MoveRepository {
 
 // Create creates a new move record.
 func (r *MoveRepository) Create(ctx context.Context, move *models.Move) error {
+	return insertMove(ctx, r.db.Pool(), move)
+}
+
+// insertMove inserts a move row using the pool or a transaction.
+func insertMove(ctx context.Context, db querier, move *models.Move) error {
 	query := `
 		INSERT INTO moves (
 			game_id, move_number, player_id, from_position, to_position,
@@ -29,7 +34,7 @@ func (r *MoveRepository) Create(ctx context.Context, move *models.Move) error {
 		RETURNING id
 	`
 
-	err := r.db.Pool().QueryRow(ctx, query,
+	err := db.QueryRow(ctx, query,
 		move.GameID,
 		move.MoveNumber,
 		move.PlayerID,
@@ -5,23 +5,58 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/xiangqi/chinese-chess-backend/internal/config"
 )
 
-// PostgresDB wraps a PostgreSQL connection pool.
+// PostgresDB wraps a PostgreSQL connection pool and an optional read-replica
+// pool for read-heavy queries.
 type PostgresDB struct {
-	pool *pgxpool.Pool
+	pool     *pgxpool.Pool
+	readPool *pgxpool.Pool
 }
 
-// NewPostgresDB creates a new PostgreSQL database connection.
+// querier is the part of a pool or transaction that queries use, so inserts
+// can run on their own or inside a transaction and reads can be routed to
+// either pool.
+type querier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// NewPostgresDB creates a new PostgreSQL database connection. If a read
+// replica is configured, a second pool is opened to it.
 func NewPostgresDB(cfg config.DatabaseConfig) (*PostgresDB, error) {
 	connString := fmt.Sprintf(
 		"postgres://%s:%s@%s:%d/%s?sslmode=%s&pool_max_conns=%d",
 		cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.DBName, cfg.SSLMode, cfg.MaxOpenConns,
 	)
 
+	pool, err := newPool(connString, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	db := &PostgresDB{pool: pool}
+
+	if cfg.ReadReplicaURL != "" {
+		readPool, err := newPool(cfg.ReadReplicaURL, cfg)
+		if err != nil {
+			pool.Close()
+			return nil, fmt.Errorf("read replica: %w", err)
+		}
+		db.readPool = readPool
+	}
+
+	return db, nil
+}
+
+// newPool opens a connection pool sized from cfg and checks it can connect.
+func newPool(connString string, cfg config.DatabaseConfig) (*pgxpool.Pool, error) {
 	poolConfig, err := pgxpool.ParseConfig(connString)
 	if err != nil {
 		return nil, fmt.Errorf("unable to parse connection string: %w", err)
@@ -37,10 +72,11 @@ func NewPostgresDB(cfg config.DatabaseConfig) (*PostgresDB, error) {
 
 	// Test connection
 	if err := pool.Ping(context.Background()); err != nil {
+		pool.Close()
 		return nil, fmt.Errorf("unable to ping database: %w", err)
 	}
 
-	return &PostgresDB{pool: pool}, nil
+	return pool, nil
 }
 
 // Pool returns the underlying connection pool.
@@ -48,7 +84,19 @@ func (db *PostgresDB) Pool() *pgxpool.Pool {
 	return db.pool
 }
 
-// Close closes the database connection pool.
+// ReadPool returns the read-replica pool for read-only queries that can
+// tolerate replication lag, or the primary pool if no replica is configured.
+func (db *PostgresDB) ReadPool() *pgxpool.Pool {
+	if db.readPool != nil {
+		return db.readPool
+	}
+	return db.pool
+}
+
+// Close closes the database connection pools.
 func (db *PostgresDB) Close() {
+	if db.readPool != nil {
+		db.readPool.Close()
+	}
 	db.pool.Close()
 }
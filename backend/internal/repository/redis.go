@@ -3,7 +3,10 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 
@@ -40,3 +43,145 @@ func (r *RedisClient) Client() *redis.Client {
 func (r *RedisClient) Close() error {
 	return r.client.Close()
 }
+
+// ErrKeyNotFound is returned when a key does not exist in Redis.
+var ErrKeyNotFound = errors.New("key not found")
+
+// Get returns the string value stored at key.
+func (r *RedisClient) Get(ctx context.Context, key string) (string, error) {
+	value, err := r.client.Get(ctx, key).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return "", ErrKeyNotFound
+		}
+		return "", fmt.Errorf("failed to get key: %w", err)
+	}
+	return value, nil
+}
+
+// Set stores value at key with the given TTL.
+func (r *RedisClient) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	if err := r.client.Set(ctx, key, value, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to set key: %w", err)
+	}
+	return nil
+}
+
+// SetNX stores value at key only if the key does not already exist.
+// It reports whether the value was stored.
+func (r *RedisClient) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	ok, err := r.client.SetNX(ctx, key, value, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to set key: %w", err)
+	}
+	return ok, nil
+}
+
+// IncrWithExpiry increments the counter at key and sets it to expire after
+// ttl, returning the new count.
+func (r *RedisClient) IncrWithExpiry(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	var incr *redis.IntCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, key)
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to increment key: %w", err)
+	}
+	return incr.Val(), nil
+}
+
+// Del removes the given keys.
+func (r *RedisClient) Del(ctx context.Context, keys ...string) error {
+	if err := r.client.Del(ctx, keys...).Err(); err != nil {
+		return fmt.Errorf("failed to delete keys: %w", err)
+	}
+	return nil
+}
+
+// AddMember adds member to the sorted set at key with the given score, or
+// updates its score if it is already there.
+func (r *RedisClient) AddMember(ctx context.Context, key, member string, score float64) error {
+	if err := r.client.ZAdd(ctx, key, redis.Z{Score: score, Member: member}).Err(); err != nil {
+		return fmt.Errorf("failed to add member: %w", err)
+	}
+	return nil
+}
+
+// MembersWithScoreAtMost returns the members of the sorted set at key whose
+// score is at most max, lowest score first.
+func (r *RedisClient) MembersWithScoreAtMost(ctx context.Context, key string, max float64) ([]string, error) {
+	members, err := r.client.ZRangeByScore(ctx, key, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatFloat(max, 'f', -1, 64),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to range sorted set: %w", err)
+	}
+	return members, nil
+}
+
+// Rank returns the zero-based rank of member in the sorted set at key, lowest
+// score first, or ErrKeyNotFound if it is not a member.
+func (r *RedisClient) Rank(ctx context.Context, key, member string) (int, error) {
+	rank, err := r.client.ZRank(ctx, key, member).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, ErrKeyNotFound
+		}
+		return 0, fmt.Errorf("failed to rank member: %w", err)
+	}
+	return int(rank), nil
+}
+
+// Size returns the number of members of the sorted set at key.
+func (r *RedisClient) Size(ctx context.Context, key string) (int, error) {
+	size, err := r.client.ZCard(ctx, key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count sorted set: %w", err)
+	}
+	return int(size), nil
+}
+
+// RemoveMember removes member from the sorted set at key. It reports whether
+// the member was present.
+func (r *RedisClient) RemoveMember(ctx context.Context, key, member string) (bool, error) {
+	removed, err := r.client.ZRem(ctx, key, member).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to remove member: %w", err)
+	}
+	return removed > 0, nil
+}
+
+// SetField sets field of the hash at key to value and makes the hash expire
+// after ttl, in one transaction.
+func (r *RedisClient) SetField(ctx context.Context, key, field, value string, ttl time.Duration) error {
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, field, value)
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set field: %w", err)
+	}
+	return nil
+}
+
+// Fields returns all fields of the hash at key, or an empty map if there is
+// no such hash.
+func (r *RedisClient) Fields(ctx context.Context, key string) (map[string]string, error) {
+	fields, err := r.client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fields: %w", err)
+	}
+	return fields, nil
+}
+
+// DeleteField removes field from the hash at key.
+func (r *RedisClient) DeleteField(ctx context.Context, key, field string) error {
+	if err := r.client.HDel(ctx, key, field).Err(); err != nil {
+		return fmt.Errorf("failed to delete field: %w", err)
+	}
+	return nil
+}
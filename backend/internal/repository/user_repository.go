@@ -28,8 +28,9 @@ func NewUserRepository(db *PostgresDB) *UserRepository {
 // Create creates a new user.
 func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 	query := `
-		INSERT INTO users (id, display_name, total_games, wins, losses, draws, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO users (id, display_name, total_games, wins, losses, draws, auto_rematch, created_at, updated_at, display_name_changed_at,
+			rating, rating_deviation, resign_suggestions)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 	`
 
 	now := time.Now()
@@ -43,8 +44,13 @@ func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 		user.Wins,
 		user.Losses,
 		user.Draws,
+		user.AutoRematch,
 		user.CreatedAt,
 		user.UpdatedAt,
+		user.DisplayNameChangedAt,
+		user.Rating,
+		user.RatingDeviation,
+		user.ResignSuggestions,
 	)
 
 	if err != nil {
@@ -57,7 +63,9 @@ func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 // GetByID retrieves a user by their device ID.
 func (r *UserRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
 	query := `
-		SELECT id, display_name, total_games, wins, losses, draws, created_at, updated_at
+		SELECT id, display_name, total_games, wins, losses, draws, auto_rematch, created_at, updated_at, display_name_changed_at,
+			   wins_by_checkmate, wins_by_stalemate, wins_by_timeout, wins_by_resignation, wins_by_abandonment,
+			   rating, rating_deviation, resign_suggestions
 		FROM users
 		WHERE id = $1
 	`
@@ -70,8 +78,18 @@ func (r *UserRepository) GetByID(ctx context.Context, id string) (*models.User,
 		&user.Wins,
 		&user.Losses,
 		&user.Draws,
+		&user.AutoRematch,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.DisplayNameChangedAt,
+		&user.WinBreakdown.Checkmate,
+		&user.WinBreakdown.Stalemate,
+		&user.WinBreakdown.Timeout,
+		&user.WinBreakdown.Resignation,
+		&user.WinBreakdown.Abandonment,
+		&user.Rating,
+		&user.RatingDeviation,
+		&user.ResignSuggestions,
 	)
 
 	if err != nil {
@@ -88,7 +106,8 @@ func (r *UserRepository) GetByID(ctx context.Context, id string) (*models.User,
 func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
 	query := `
 		UPDATE users
-		SET display_name = $2, updated_at = $3
+		SET display_name = $2, auto_rematch = $3, updated_at = $4, display_name_changed_at = $5,
+			resign_suggestions = $6
 		WHERE id = $1
 	`
 
@@ -97,7 +116,10 @@ func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
 	result, err := r.db.Pool().Exec(ctx, query,
 		user.ID,
 		user.DisplayName,
+		user.AutoRematch,
 		user.UpdatedAt,
+		user.DisplayNameChangedAt,
+		user.ResignSuggestions,
 	)
 
 	if err != nil {
@@ -115,7 +137,10 @@ func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
 func (r *UserRepository) UpdateStats(ctx context.Context, id string, stats models.UserStats) error {
 	query := `
 		UPDATE users
-		SET total_games = $2, wins = $3, losses = $4, draws = $5, updated_at = $6
+		SET total_games = $2, wins = $3, losses = $4, draws = $5, updated_at = $6,
+			wins_by_checkmate = $7, wins_by_stalemate = $8, wins_by_timeout = $9,
+			wins_by_resignation = $10, wins_by_abandonment = $11,
+			rating = $12, rating_deviation = $13
 		WHERE id = $1
 	`
 
@@ -126,6 +151,13 @@ func (r *UserRepository) UpdateStats(ctx context.Context, id string, stats model
 		stats.Losses,
 		stats.Draws,
 		time.Now(),
+		stats.WinBreakdown.Checkmate,
+		stats.WinBreakdown.Stalemate,
+		stats.WinBreakdown.Timeout,
+		stats.WinBreakdown.Resignation,
+		stats.WinBreakdown.Abandonment,
+		stats.Rating,
+		stats.RatingDeviation,
 	)
 
 	if err != nil {
@@ -151,3 +183,15 @@ func (r *UserRepository) Exists(ctx context.Context, id string) (bool, error) {
 
 	return exists, nil
 }
+
+// Count returns the number of registered users.
+func (r *UserRepository) Count(ctx context.Context) (int, error) {
+	query := `SELECT COUNT(*) FROM users`
+
+	var count int
+	if err := r.db.Pool().QueryRow(ctx, query).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,71 @@
+// Package services contains business logic for the application.
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	// DefaultAnalysisMaxDepth is used when no maximum search depth is configured.
+	DefaultAnalysisMaxDepth = 5
+	// DefaultAnalysisTimeout is used when no analysis timeout is configured.
+	DefaultAnalysisTimeout = 2 * time.Second
+)
+
+// AnalysisLimits caps the work done by a single analysis or AI request so
+// that a malicious request cannot tie up a worker. Searches must check the
+// context returned by WithTimeout periodically and stop once it is done.
+type AnalysisLimits struct {
+	MaxDepth int
+	Timeout  time.Duration
+}
+
+// NewAnalysisLimits creates analysis limits. Non-positive values fall back
+// to DefaultAnalysisMaxDepth and DefaultAnalysisTimeout.
+func NewAnalysisLimits(maxDepth int, timeout time.Duration) AnalysisLimits {
+	if maxDepth <= 0 {
+		maxDepth = DefaultAnalysisMaxDepth
+	}
+	if timeout <= 0 {
+		timeout = DefaultAnalysisTimeout
+	}
+	return AnalysisLimits{MaxDepth: maxDepth, Timeout: timeout}
+}
+
+// CheckDepth returns ErrDepthExceeded if the requested search depth is above
+// the maximum, and ErrInvalidDepth if it is not positive.
+func (l AnalysisLimits) CheckDepth(depth int) error {
+	if depth <= 0 {
+		return ErrInvalidDepth
+	}
+	if depth > l.MaxDepth {
+		return ErrDepthExceeded
+	}
+	return nil
+}
+
+// WithTimeout returns a context that is cancelled once the request's compute
+// budget is spent.
+func (l AnalysisLimits) WithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, l.Timeout)
+}
+
+// CheckContext is called periodically by searches. It returns
+// ErrAnalysisTimeout once the compute budget is spent, or the context's
+// error if it was cancelled for another reason.
+func CheckContext(ctx context.Context) error {
+	err := ctx.Err()
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrAnalysisTimeout
+	}
+	return err
+}
+
+// Analysis errors
+var (
+	ErrInvalidDepth    = errors.New("search depth must be positive")
+	ErrDepthExceeded   = errors.New("search depth exceeds the maximum")
+	ErrAnalysisTimeout = errors.New("analysis timed out")
+)
@@ -0,0 +1,73 @@
+// Package services provides unit tests for analysis limits.
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAnalysisLimits_CheckDepth(t *testing.T) {
+	limits := NewAnalysisLimits(5, time.Second)
+
+	tests := []struct {
+		depth int
+		want  error
+	}{
+		{1, nil},
+		{5, nil},
+		{6, ErrDepthExceeded},
+		{15, ErrDepthExceeded},
+		{0, ErrInvalidDepth},
+		{-1, ErrInvalidDepth},
+	}
+
+	for _, tt := range tests {
+		if got := limits.CheckDepth(tt.depth); got != tt.want {
+			t.Errorf("CheckDepth(%d) = %v, want %v", tt.depth, got, tt.want)
+		}
+	}
+}
+
+func TestAnalysisLimits_Defaults(t *testing.T) {
+	limits := NewAnalysisLimits(0, 0)
+
+	if limits.MaxDepth != DefaultAnalysisMaxDepth || limits.Timeout != DefaultAnalysisTimeout {
+		t.Errorf("Expected default limits, got %+v", limits)
+	}
+}
+
+func TestAnalysisLimits_Timeout(t *testing.T) {
+	limits := NewAnalysisLimits(5, 10*time.Millisecond)
+	ctx, cancel := limits.WithTimeout(context.Background())
+	defer cancel()
+
+	if err := CheckContext(ctx); err != nil {
+		t.Fatalf("Expected no error within the budget, got %v", err)
+	}
+
+	// A search polling the context stops once the budget is spent
+	deadline := time.After(time.Second)
+	for {
+		if err := CheckContext(ctx); err != nil {
+			if err != ErrAnalysisTimeout {
+				t.Fatalf("Expected ErrAnalysisTimeout, got %v", err)
+			}
+			return
+		}
+		select {
+		case <-deadline:
+			t.Fatal("Search was not stopped by the analysis timeout")
+		default:
+		}
+	}
+}
+
+func TestCheckContext_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := CheckContext(ctx); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
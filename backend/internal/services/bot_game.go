@@ -0,0 +1,104 @@
+// Package services contains business logic for the application.
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/game"
+	"github.com/xiangqi/chinese-chess-backend/internal/game/ai"
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+	"github.com/xiangqi/chinese-chess-backend/internal/repository"
+)
+
+var (
+	// ErrNotBotGame is returned when asking for a computer move in a game
+	// between two people.
+	ErrNotBotGame = errors.New("game has no computer opponent")
+	// ErrNotBotTurn is returned when asking for a computer move while it is
+	// not the computer's turn, or the game is over.
+	ErrNotBotTurn = errors.New("not the computer's turn")
+)
+
+// botTurnTimeout is the turn timeout of games against the computer, in
+// seconds.
+const botTurnTimeout = 300
+
+// BotPlayerID returns the user ID of the computer opponent at a difficulty.
+func BotPlayerID(difficulty int) string {
+	return fmt.Sprintf("bot-%d", difficulty)
+}
+
+// CreateBotGame creates a casual game in which the player plays red against
+// the computer at the given difficulty, from ai.MinDifficulty to
+// ai.MaxDifficulty. The computer plays black as a user of its own, created
+// on first use, and the game records its difficulty.
+func (s *GameService) CreateBotGame(ctx context.Context, playerID string, difficulty int) (*models.Game, error) {
+	if _, err := ai.DepthForDifficulty(difficulty); err != nil {
+		return nil, err
+	}
+
+	botID := BotPlayerID(difficulty)
+	if err := s.ensureBotUser(ctx, botID, difficulty); err != nil {
+		return nil, err
+	}
+
+	return s.CreateGame(ctx, playerID, botID, GameSettings{
+		TurnTimeout:   botTurnTimeout,
+		Assist:        true,
+		BotDifficulty: difficulty,
+	})
+}
+
+// ensureBotUser creates the computer opponent's user if it does not exist.
+func (s *GameService) ensureBotUser(ctx context.Context, botID string, difficulty int) error {
+	_, err := s.userRepo.GetByID(ctx, botID)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, repository.ErrUserNotFound) {
+		return fmt.Errorf("failed to get bot user: %w", err)
+	}
+
+	bot := &models.User{
+		ID:          botID,
+		DisplayName: fmt.Sprintf("Computer (Level %d)", difficulty),
+	}
+	if err := s.userRepo.Create(ctx, bot); err != nil {
+		return fmt.Errorf("failed to create bot user: %w", err)
+	}
+	return nil
+}
+
+// BotMove returns the computer's move in a bot game where it is black's
+// turn. The search runs on the search pool, if there is one.
+func (s *GameService) BotMove(ctx context.Context, gameID string) (game.Move, error) {
+	g, err := s.GetGame(ctx, gameID)
+	if err != nil {
+		return game.Move{}, err
+	}
+	if g.BotDifficulty == 0 {
+		return game.Move{}, ErrNotBotGame
+	}
+	depth, err := ai.DepthForDifficulty(g.BotDifficulty)
+	if err != nil {
+		return game.Move{}, err
+	}
+
+	engine, err := s.LoadEngine(ctx, gameID)
+	if err != nil {
+		return game.Move{}, err
+	}
+	if engine.IsGameOver() || engine.GetCurrentTurn() != models.PlayerColorBlack {
+		return game.Move{}, ErrNotBotTurn
+	}
+
+	var move game.Move
+	err = s.runSearch(ctx, func(ctx context.Context) error {
+		result, err := ai.NewEngine().Search(ctx, engine.GetBoard().Copy(), models.PlayerColorBlack, depth)
+		move = result.Move
+		return err
+	})
+	return move, err
+}
@@ -0,0 +1,102 @@
+// Package services provides unit tests for games against the computer.
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/game"
+	"github.com/xiangqi/chinese-chess-backend/internal/game/ai"
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
+
+func TestGameService_CreateBotGame(t *testing.T) {
+	service, userRepo := newTestGameServiceWithPlayers()
+	ctx := context.Background()
+
+	g, err := service.CreateBotGame(ctx, "red-player", 2)
+	if err != nil {
+		t.Fatalf("CreateBotGame failed: %v", err)
+	}
+	if g.RedPlayerID != "red-player" || g.BlackPlayerID != BotPlayerID(2) {
+		t.Errorf("Expected red-player against %s, got %s against %s", BotPlayerID(2), g.RedPlayerID, g.BlackPlayerID)
+	}
+	if g.BotDifficulty != 2 || g.Ranked {
+		t.Errorf("Expected a casual game against difficulty 2, got difficulty %d, ranked %v", g.BotDifficulty, g.Ranked)
+	}
+	if _, ok := userRepo.users[BotPlayerID(2)]; !ok {
+		t.Error("Expected the bot user to be created")
+	}
+
+	// The bot user is reused by later games
+	if _, err := service.CreateBotGame(ctx, "black-player", 2); err != nil {
+		t.Fatalf("Second CreateBotGame failed: %v", err)
+	}
+}
+
+func TestGameService_CreateBotGame_RejectsInvalidDifficulty(t *testing.T) {
+	service, _ := newTestGameServiceWithPlayers()
+
+	for _, difficulty := range []int{ai.MinDifficulty - 1, ai.MaxDifficulty + 1} {
+		if _, err := service.CreateBotGame(context.Background(), "red-player", difficulty); !errors.Is(err, ai.ErrInvalidDifficulty) {
+			t.Errorf("Difficulty %d: expected ErrInvalidDifficulty, got %v", difficulty, err)
+		}
+	}
+	if games := service.gameRepo.(*mockGameRepository).games; len(games) != 0 {
+		t.Errorf("Expected no game to be created, got %d", len(games))
+	}
+}
+
+func TestGameService_BotMove(t *testing.T) {
+	service, _ := newTestGameServiceWithPlayers()
+	ctx := context.Background()
+
+	g, err := service.CreateBotGame(ctx, "red-player", 1)
+	if err != nil {
+		t.Fatalf("CreateBotGame failed: %v", err)
+	}
+	if _, err := service.BotMove(ctx, g.ID); !errors.Is(err, ErrNotBotTurn) {
+		t.Fatalf("Expected ErrNotBotTurn while red is to move, got %v", err)
+	}
+
+	if err := service.RecordMove(ctx, &models.Move{
+		GameID:       g.ID,
+		MoveNumber:   1,
+		PlayerID:     "red-player",
+		FromPosition: "h2",
+		ToPosition:   "e2",
+	}); err != nil {
+		t.Fatalf("RecordMove failed: %v", err)
+	}
+
+	move, err := service.BotMove(ctx, g.ID)
+	if err != nil {
+		t.Fatalf("BotMove failed: %v", err)
+	}
+	engine, err := service.LoadEngine(ctx, g.ID)
+	if err != nil {
+		t.Fatalf("LoadEngine failed: %v", err)
+	}
+	result := engine.ValidateAndMakeMove(game.MoveRequest{
+		PlayerID: g.BlackPlayerID,
+		From:     move.From.Notation(),
+		To:       move.To.Notation(),
+	})
+	if !result.Success {
+		t.Errorf("Expected a legal move, got %s-%s: %s", move.From.Notation(), move.To.Notation(), result.ErrorMessage)
+	}
+}
+
+func TestGameService_BotMove_RequiresBotGame(t *testing.T) {
+	service, _ := newTestGameServiceWithPlayers()
+	ctx := context.Background()
+
+	g, err := service.CreateGame(ctx, "red-player", "black-player", GameSettings{TurnTimeout: 60})
+	if err != nil {
+		t.Fatalf("CreateGame failed: %v", err)
+	}
+	if _, err := service.BotMove(ctx, g.ID); !errors.Is(err, ErrNotBotGame) {
+		t.Errorf("Expected ErrNotBotGame, got %v", err)
+	}
+}
@@ -0,0 +1,233 @@
+// Package services contains business logic for the application.
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+	"github.com/xiangqi/chinese-chess-backend/internal/repository"
+)
+
+const (
+	challengeKey         = "challenge:"
+	challengeIncomingKey = "challenge:incoming:"
+	challengeAcceptedKey = "challenge:accepted:"
+	// DefaultChallengeTTL is used when no challenge TTL is configured.
+	DefaultChallengeTTL = 5 * time.Minute
+)
+
+// ChallengeService handles direct challenges between players. Pending
+// challenges are stored in Redis in a hash per challenged player, keyed by
+// challenger, so any server instance can list and accept them and a new
+// challenge replaces the challenger's previous one atomically.
+type ChallengeService struct {
+	store       KeyValueStore
+	incoming    HashStore
+	gameService *GameService
+	userService *UserService
+	ttl         time.Duration
+	now         func() time.Time
+}
+
+// NewChallengeService creates a new ChallengeService. A non-positive ttl
+// falls back to DefaultChallengeTTL.
+func NewChallengeService(store KeyValueStore, incoming HashStore, gameService *GameService, userService *UserService, ttl time.Duration) *ChallengeService {
+	if ttl <= 0 {
+		ttl = DefaultChallengeTTL
+	}
+	return &ChallengeService{
+		store:       store,
+		incoming:    incoming,
+		gameService: gameService,
+		userService: userService,
+		ttl:         ttl,
+		now:         time.Now,
+	}
+}
+
+// Challenge is an invitation from one player to another to start a game.
+type Challenge struct {
+	ID             string    `json:"id"`
+	ChallengerID   string    `json:"challenger_id"`
+	ChallengerName string    `json:"challenger_name"`
+	OpponentID     string    `json:"opponent_id"`
+	TurnTimeout    int       `json:"turn_timeout"`
+	CreatedAt      time.Time `json:"created_at"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+// expired reports whether the challenge can no longer be accepted.
+func (c *Challenge) expired(now time.Time) bool {
+	return !now.Before(c.ExpiresAt)
+}
+
+// Create challenges a registered player to a game. A new challenge to the
+// same opponent replaces the challenger's previous one.
+func (s *ChallengeService) Create(ctx context.Context, challengerID, opponentID string, turnTimeout int) (*Challenge, error) {
+	if challengerID == opponentID {
+		return nil, ErrSelfChallenge
+	}
+
+	if _, err := s.userService.GetByID(ctx, opponentID); err != nil {
+		return nil, err
+	}
+	challenger, err := s.userService.GetByID(ctx, challengerID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	challenge := &Challenge{
+		ID:             uuid.New().String(),
+		ChallengerID:   challengerID,
+		ChallengerName: challenger.DisplayName,
+		OpponentID:     opponentID,
+		TurnTimeout:    turnTimeout,
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(s.ttl),
+	}
+
+	data, err := json.Marshal(challenge)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode challenge: %w", err)
+	}
+	if err := s.store.Set(ctx, challengeKey+challenge.ID, string(data), s.ttl); err != nil {
+		return nil, fmt.Errorf("failed to store challenge: %w", err)
+	}
+	// The challenger's previous challenge is replaced in the same write;
+	// its key expires on its own and can no longer be accepted
+	if err := s.incoming.SetField(ctx, challengeIncomingKey+opponentID, challengerID, string(data), s.ttl); err != nil {
+		return nil, fmt.Errorf("failed to store challenge: %w", err)
+	}
+
+	return challenge, nil
+}
+
+// ListIncoming returns the pending challenges to a player, oldest first.
+func (s *ChallengeService) ListIncoming(ctx context.Context, deviceID string) ([]*Challenge, error) {
+	incoming, err := s.loadIncoming(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	pending := make([]*Challenge, 0, len(incoming))
+	for _, challenge := range incoming {
+		if !challenge.expired(now) {
+			pending = append(pending, challenge)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].CreatedAt.Before(pending[j].CreatedAt)
+	})
+	return pending, nil
+}
+
+// Accept accepts a challenge to the given player and creates the game, with
+// colors assigned at random. Challenges are casual, so they never affect
+// stats or ratings.
+func (s *ChallengeService) Accept(ctx context.Context, challengeID, deviceID string) (*models.Game, error) {
+	data, err := s.store.Get(ctx, challengeKey+challengeID)
+	if errors.Is(err, repository.ErrKeyNotFound) {
+		return nil, ErrChallengeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get challenge: %w", err)
+	}
+
+	var challenge Challenge
+	if err := json.Unmarshal([]byte(data), &challenge); err != nil {
+		return nil, ErrChallengeNotFound
+	}
+	if challenge.OpponentID != deviceID {
+		return nil, ErrNotChallenged
+	}
+	// A challenge replaced by a newer one from the same challenger is gone
+	incoming, err := s.loadIncoming(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if current, ok := incoming[challenge.ChallengerID]; !ok || current.ID != challenge.ID {
+		return nil, ErrChallengeNotFound
+	}
+	// Redis expires the key as well; the stored expiry guards against keys
+	// that outlive their TTL.
+	if challenge.expired(s.now()) {
+		s.remove(ctx, &challenge)
+		return nil, ErrChallengeExpired
+	}
+
+	// Only the first acceptance creates a game
+	claimed, err := s.store.SetNX(ctx, challengeAcceptedKey+challengeID, deviceID, s.ttl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to accept challenge: %w", err)
+	}
+	if !claimed {
+		return nil, ErrChallengeNotFound
+	}
+
+	redPlayerID, blackPlayerID := challenge.ChallengerID, challenge.OpponentID
+	if rand.Intn(2) == 0 {
+		redPlayerID, blackPlayerID = blackPlayerID, redPlayerID
+	}
+	game, err := s.gameService.CreateGame(ctx, redPlayerID, blackPlayerID, GameSettings{
+		TurnTimeout: challenge.TurnTimeout,
+		Assist:      true,
+	})
+	if err != nil {
+		_ = s.store.Del(ctx, challengeAcceptedKey+challengeID)
+		return nil, err
+	}
+
+	s.remove(ctx, &challenge)
+	return game, nil
+}
+
+// remove deletes a challenge and drops it from its opponent's incoming
+// challenges, unless the challenger has replaced it since.
+func (s *ChallengeService) remove(ctx context.Context, challenge *Challenge) {
+	_ = s.store.Del(ctx, challengeKey+challenge.ID)
+
+	incoming, err := s.loadIncoming(ctx, challenge.OpponentID)
+	if err != nil {
+		return
+	}
+	if current, ok := incoming[challenge.ChallengerID]; ok && current.ID == challenge.ID {
+		_ = s.incoming.DeleteField(ctx, challengeIncomingKey+challenge.OpponentID, challenge.ChallengerID)
+	}
+}
+
+// loadIncoming returns a player's incoming challenges by challenger,
+// including expired ones.
+func (s *ChallengeService) loadIncoming(ctx context.Context, deviceID string) (map[string]*Challenge, error) {
+	fields, err := s.incoming.Fields(ctx, challengeIncomingKey+deviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get challenges: %w", err)
+	}
+
+	incoming := make(map[string]*Challenge, len(fields))
+	for challengerID, data := range fields {
+		var challenge Challenge
+		if err := json.Unmarshal([]byte(data), &challenge); err != nil {
+			return nil, fmt.Errorf("failed to decode challenge: %w", err)
+		}
+		incoming[challengerID] = &challenge
+	}
+	return incoming, nil
+}
+
+// Challenge errors
+var (
+	ErrSelfChallenge     = errors.New("cannot challenge yourself")
+	ErrChallengeNotFound = errors.New("challenge not found")
+	ErrChallengeExpired  = errors.New("challenge has expired")
+	ErrNotChallenged     = errors.New("challenge was sent to another player")
+)
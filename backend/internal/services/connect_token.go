@@ -0,0 +1,148 @@
+// Package services contains business logic for the application.
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/repository"
+)
+
+const (
+	connectTokenKey     = "ws:token:"
+	connectTokenUsedKey = "ws:token:used:"
+	connectTokenBytes   = 32
+	// DefaultConnectTokenTTL is used when no TTL is configured.
+	DefaultConnectTokenTTL = 30 * time.Second
+)
+
+// ConnectTokenService issues short-lived, single-use tokens that authorize a
+// WebSocket connection to a game. Tokens are stored in Redis so that any
+// server instance can validate them, and are consumed on first use to prevent
+// replay.
+type ConnectTokenService struct {
+	store       KeyValueStore
+	gameService *GameService
+	ttl         time.Duration
+	now         func() time.Time
+}
+
+// NewConnectTokenService creates a new ConnectTokenService. A non-positive ttl
+// falls back to DefaultConnectTokenTTL.
+func NewConnectTokenService(store KeyValueStore, gameService *GameService, ttl time.Duration) *ConnectTokenService {
+	if ttl <= 0 {
+		ttl = DefaultConnectTokenTTL
+	}
+	return &ConnectTokenService{
+		store:       store,
+		gameService: gameService,
+		ttl:         ttl,
+		now:         time.Now,
+	}
+}
+
+// ConnectToken is an issued connect token.
+type ConnectToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// connectTokenClaims is the stored payload of a connect token.
+type connectTokenClaims struct {
+	GameID    string `json:"game_id"`
+	DeviceID  string `json:"device_id"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// Issue creates a connect token for a player of the given game.
+func (s *ConnectTokenService) Issue(ctx context.Context, gameID, deviceID string) (*ConnectToken, error) {
+	game, err := s.gameService.GetGame(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+	if game.RedPlayerID != deviceID && game.BlackPlayerID != deviceID {
+		return nil, ErrPlayerNotInGame
+	}
+
+	raw := make([]byte, connectTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("failed to generate connect token: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+
+	expiresAt := s.now().Add(s.ttl)
+	data, err := json.Marshal(connectTokenClaims{
+		GameID:    gameID,
+		DeviceID:  deviceID,
+		ExpiresAt: expiresAt.UnixMilli(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode connect token: %w", err)
+	}
+
+	if err := s.store.Set(ctx, connectTokenKey+token, string(data), s.ttl); err != nil {
+		return nil, fmt.Errorf("failed to store connect token: %w", err)
+	}
+
+	return &ConnectToken{Token: token, ExpiresAt: expiresAt}, nil
+}
+
+// Validate checks that a token is valid for the given game and returns the
+// device ID it was issued to. It does not consume the token.
+func (s *ConnectTokenService) Validate(ctx context.Context, token, gameID string) (string, error) {
+	data, err := s.store.Get(ctx, connectTokenKey+token)
+	if errors.Is(err, repository.ErrKeyNotFound) {
+		return "", ErrConnectTokenInvalid
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get connect token: %w", err)
+	}
+
+	var claims connectTokenClaims
+	if err := json.Unmarshal([]byte(data), &claims); err != nil {
+		return "", ErrConnectTokenInvalid
+	}
+	if claims.GameID != gameID {
+		return "", ErrConnectTokenInvalid
+	}
+	// Redis expires the key as well; the stored expiry guards against clock
+	// skew and keys that outlive their TTL.
+	if !s.now().Before(time.UnixMilli(claims.ExpiresAt)) {
+		return "", ErrConnectTokenExpired
+	}
+
+	_, err = s.store.Get(ctx, connectTokenUsedKey+token)
+	if err == nil {
+		return "", ErrConnectTokenUsed
+	}
+	if !errors.Is(err, repository.ErrKeyNotFound) {
+		return "", fmt.Errorf("failed to get connect token state: %w", err)
+	}
+
+	return claims.DeviceID, nil
+}
+
+// Consume marks a token as used. Only the first call succeeds; later calls
+// return ErrConnectTokenUsed.
+func (s *ConnectTokenService) Consume(ctx context.Context, token string) error {
+	stored, err := s.store.SetNX(ctx, connectTokenUsedKey+token, "1", s.ttl)
+	if err != nil {
+		return fmt.Errorf("failed to consume connect token: %w", err)
+	}
+	if !stored {
+		return ErrConnectTokenUsed
+	}
+	return nil
+}
+
+// Connect token errors
+var (
+	ErrConnectTokenInvalid = errors.New("connect token is invalid")
+	ErrConnectTokenExpired = errors.New("connect token has expired")
+	ErrConnectTokenUsed    = errors.New("connect token has already been used")
+)
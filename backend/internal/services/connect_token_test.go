@@ -0,0 +1,114 @@
+// Package services provides unit tests for the connect token service.
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
+
+// newTestConnectTokenService creates a token service for an active game
+// "game-1" between "red-player" and "black-player".
+func newTestConnectTokenService(t *testing.T) (*ConnectTokenService, *mockKeyValueStore) {
+	t.Helper()
+
+	gameRepo := newMockGameRepository()
+	gameRepo.Create(context.Background(), &models.Game{
+		ID:            "game-1",
+		RedPlayerID:   "red-player",
+		BlackPlayerID: "black-player",
+		Status:        models.GameStatusActive,
+	})
+	gameService := NewGameService(gameRepo, newMockMoveRepository(), newMockUserRepository())
+
+	store := newMockKeyValueStore()
+	return NewConnectTokenService(store, gameService, 0), store
+}
+
+func TestConnectTokenService_IssueAndValidate(t *testing.T) {
+	service, store := newTestConnectTokenService(t)
+	ctx := context.Background()
+
+	token, err := service.Issue(ctx, "game-1", "red-player")
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	if token.Token == "" {
+		t.Fatal("Expected a non-empty token")
+	}
+	if ttl := store.ttls[connectTokenKey+token.Token]; ttl != DefaultConnectTokenTTL {
+		t.Errorf("Expected token TTL %v, got %v", DefaultConnectTokenTTL, ttl)
+	}
+
+	deviceID, err := service.Validate(ctx, token.Token, "game-1")
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if deviceID != "red-player" {
+		t.Errorf("Expected device red-player, got %s", deviceID)
+	}
+
+	if _, err := service.Validate(ctx, token.Token, "game-2"); err != ErrConnectTokenInvalid {
+		t.Errorf("Expected ErrConnectTokenInvalid for another game, got %v", err)
+	}
+	if _, err := service.Validate(ctx, "unknown", "game-1"); err != ErrConnectTokenInvalid {
+		t.Errorf("Expected ErrConnectTokenInvalid for unknown token, got %v", err)
+	}
+}
+
+func TestConnectTokenService_IssueRequiresPlayer(t *testing.T) {
+	service, _ := newTestConnectTokenService(t)
+	ctx := context.Background()
+
+	if _, err := service.Issue(ctx, "game-1", "stranger"); err != ErrPlayerNotInGame {
+		t.Errorf("Expected ErrPlayerNotInGame, got %v", err)
+	}
+	if _, err := service.Issue(ctx, "missing", "red-player"); err != ErrGameNotFound {
+		t.Errorf("Expected ErrGameNotFound, got %v", err)
+	}
+}
+
+func TestConnectTokenService_Expiry(t *testing.T) {
+	service, _ := newTestConnectTokenService(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	service.now = func() time.Time { return now }
+
+	token, err := service.Issue(ctx, "game-1", "red-player")
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+
+	now = now.Add(DefaultConnectTokenTTL - time.Second)
+	if _, err := service.Validate(ctx, token.Token, "game-1"); err != nil {
+		t.Errorf("Token should still be valid before expiry, got %v", err)
+	}
+
+	now = now.Add(time.Second)
+	if _, err := service.Validate(ctx, token.Token, "game-1"); err != ErrConnectTokenExpired {
+		t.Errorf("Expected ErrConnectTokenExpired, got %v", err)
+	}
+}
+
+func TestConnectTokenService_SingleUse(t *testing.T) {
+	service, _ := newTestConnectTokenService(t)
+	ctx := context.Background()
+
+	token, err := service.Issue(ctx, "game-1", "black-player")
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+
+	if err := service.Consume(ctx, token.Token); err != nil {
+		t.Fatalf("First Consume failed: %v", err)
+	}
+	if err := service.Consume(ctx, token.Token); err != ErrConnectTokenUsed {
+		t.Errorf("Expected ErrConnectTokenUsed on second Consume, got %v", err)
+	}
+	if _, err := service.Validate(ctx, token.Token, "game-1"); err != ErrConnectTokenUsed {
+		t.Errorf("Expected ErrConnectTokenUsed after consumption, got %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+// Package services contains business logic for the application.
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/game"
+	"github.com/xiangqi/chinese-chess-backend/internal/game/record"
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
+
+// ImportError reports the first illegal move of an imported move list.
+type ImportError struct {
+	// Index is the zero-based position of the move in the imported list.
+	Index  int
+	Reason string
+}
+
+func (e *ImportError) Error() string {
+	return fmt.Sprintf("illegal move at index %d: %s", e.Index, e.Reason)
+}
+
+// Unwrap lets callers match import failures with errors.Is(err, ErrInvalidMove).
+func (e *ImportError) Unwrap() error {
+	return ErrInvalidMove
+}
+
+// ImportGame validates a move sequence from the initial position through a
+// fresh engine and stores it as a completed, private game for study. The
+// owner plays both sides, so the winner is kept as a color. The import is
+// rejected at the first illegal move with an *ImportError carrying its
+// index; nothing is stored in that case.
+//
+// A final position the engine recognizes as finished decides the result,
+// and a claimed result that disagrees with it is rejected with
+// ErrImportResultMismatch. Otherwise a decisive result is recorded as a
+// resignation and a draw as agreed.
+func (s *GameService) ImportGame(ctx context.Context, ownerID string, moves []models.MovePayload, result record.Result) (*models.Game, error) {
+	if len(moves) == 0 {
+		return nil, ErrEmptyImport
+	}
+
+	gameID := uuid.New().String()
+	engine := game.NewGameEngine(gameID, ownerID, ownerID)
+
+	for i, move := range moves {
+		from, err := game.NormalizePosition(move.From)
+		if err != nil {
+			return nil, &ImportError{Index: i, Reason: "invalid from position: " + err.Error()}
+		}
+		to, err := game.NormalizePosition(move.To)
+		if err != nil {
+			return nil, &ImportError{Index: i, Reason: "invalid to position: " + err.Error()}
+		}
+
+		result := engine.ValidateAndMakeMove(game.MoveRequest{
+			PlayerID: ownerID,
+			From:     from,
+			To:       to,
+		})
+		if !result.Success {
+			return nil, &ImportError{Index: i, Reason: result.ErrorMessage}
+		}
+	}
+
+	history := engine.GetMoveHistory()
+	now := time.Now()
+	finalFEN := engine.GetFEN()
+	imported := &models.Game{
+		ID:            gameID,
+		RedPlayerID:   ownerID,
+		BlackPlayerID: ownerID,
+		Status:        models.GameStatusCompleted,
+		TotalMoves:    len(history),
+		IsPublic:      false,
+		TimeControl:   models.TimeControlPerMove,
+		FinalFEN:      &finalFEN,
+		CompletedAt:   &now,
+	}
+	if err := setImportResult(imported, engine, result); err != nil {
+		return nil, err
+	}
+
+	stored := make([]*models.Move, 0, len(history))
+	for _, record := range history {
+		stored = append(stored, &models.Move{
+			GameID:        gameID,
+			MoveNumber:    record.MoveNumber,
+			PlayerID:      ownerID,
+			FromPosition:  record.From.Notation(),
+			ToPosition:    record.To.Notation(),
+			PieceType:     record.PieceType,
+			CapturedPiece: record.CapturedPiece,
+			IsCheck:       record.IsCheck,
+			Timestamp:     now,
+		})
+	}
+
+	if err := s.gameRepo.CreateWithMoves(ctx, imported, stored); err != nil {
+		return nil, fmt.Errorf("failed to store imported game: %w", err)
+	}
+
+	return imported, nil
+}
+
+// setImportResult records how an imported game ended, checking the claimed
+// result against the final position.
+func setImportResult(imported *models.Game, engine *game.GameEngine, claimed record.Result) error {
+	var resultType models.ResultType
+	decided := true
+	winner := engine.GetCurrentTurn().Opposite()
+	if engine.IsCheckmate() {
+		resultType = models.ResultTypeCheckmate
+	} else if engine.IsStalemate() {
+		resultType = models.ResultTypeStalemate
+	} else if offender, ok := engine.DetectPerpetualCheck(); ok {
+		resultType = models.ResultTypePerpetualCheck
+		winner = offender.Opposite()
+	} else if engine.IsDraw() {
+		resultType = models.ResultTypeDraw
+		winner = ""
+	} else {
+		decided = false
+	}
+
+	if decided {
+		want := record.ResultDraw
+		if winner == models.PlayerColorRed {
+			want = record.ResultRedWins
+		} else if winner == models.PlayerColorBlack {
+			want = record.ResultBlackWins
+		}
+		if claimed != record.ResultUnknown && claimed != want {
+			return ErrImportResultMismatch
+		}
+	} else {
+		switch claimed {
+		case record.ResultUnknown:
+			return nil
+		case record.ResultDraw:
+			resultType = models.ResultTypeDraw
+			winner = ""
+		default:
+			resultType = models.ResultTypeResignation
+			winner, _ = claimed.Winner()
+		}
+	}
+
+	imported.ResultType = &resultType
+	if winner != "" {
+		imported.WinnerColor = &winner
+	}
+	return nil
+}
+
+// Import errors
+var (
+	ErrEmptyImport          = errors.New("imported game has no moves")
+	ErrImportResultMismatch = errors.New("claimed result does not match the final position")
+)
@@ -0,0 +1,181 @@
+// Package services provides unit tests for game import.
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/game/record"
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
+
+func TestGameService_ImportGame_ValidGame(t *testing.T) {
+	gameRepo := newMockGameRepository()
+	moveRepo := newMockMoveRepository()
+	gameRepo.moveRepo = moveRepo
+	service := NewGameService(gameRepo, moveRepo, newMockUserRepository())
+	ctx := context.Background()
+
+	moves := []models.MovePayload{
+		{From: "h2", To: "e2"}, // Central cannon
+		{From: "h9", To: "g7"},
+		{From: "H0", To: "G2"}, // Positions are normalized
+		{From: "i9", To: "h9"},
+	}
+
+	imported, err := service.ImportGame(ctx, "owner", moves, record.ResultUnknown)
+	if err != nil {
+		t.Fatalf("ImportGame failed: %v", err)
+	}
+
+	if imported.Status != models.GameStatusCompleted {
+		t.Errorf("Expected imported game to be completed, got %s", imported.Status)
+	}
+	if imported.IsPublic {
+		t.Error("Imported game should not be public")
+	}
+	if imported.RedPlayerID != "owner" || imported.BlackPlayerID != "owner" {
+		t.Errorf("Expected owner to play both sides, got red=%s black=%s", imported.RedPlayerID, imported.BlackPlayerID)
+	}
+	if imported.TotalMoves != len(moves) {
+		t.Errorf("Expected %d total moves, got %d", len(moves), imported.TotalMoves)
+	}
+
+	stored, err := moveRepo.GetByGameID(ctx, imported.ID)
+	if err != nil {
+		t.Fatalf("GetByGameID failed: %v", err)
+	}
+	if len(stored) != len(moves) {
+		t.Fatalf("Expected %d stored moves, got %d", len(moves), len(stored))
+	}
+	if stored[2].FromPosition != "h0" || stored[2].ToPosition != "g2" || stored[2].PieceType != models.PieceTypeHorse {
+		t.Errorf("Unexpected third move: %+v", stored[2])
+	}
+
+	// The stored game replays cleanly
+	engine, err := service.LoadEngine(ctx, imported.ID)
+	if err != nil {
+		t.Fatalf("LoadEngine failed: %v", err)
+	}
+	if len(engine.GetMoveHistory()) != len(moves) {
+		t.Errorf("Expected %d replayed moves, got %d", len(moves), len(engine.GetMoveHistory()))
+	}
+}
+
+func TestGameService_ImportGame_RejectsIllegalMove(t *testing.T) {
+	gameRepo := newMockGameRepository()
+	moveRepo := newMockMoveRepository()
+	service := NewGameService(gameRepo, moveRepo, newMockUserRepository())
+
+	moves := []models.MovePayload{
+		{From: "h2", To: "e2"},
+		{From: "h9", To: "g7"},
+		{From: "a0", To: "a5"}, // Chariot cannot jump its own soldier
+		{From: "i9", To: "h9"},
+	}
+
+	_, err := service.ImportGame(context.Background(), "owner", moves, record.ResultUnknown)
+	if !errors.Is(err, ErrInvalidMove) {
+		t.Fatalf("Expected ErrInvalidMove, got %v", err)
+	}
+
+	var importErr *ImportError
+	if !errors.As(err, &importErr) {
+		t.Fatalf("Expected *ImportError, got %T", err)
+	}
+	if importErr.Index != 2 {
+		t.Errorf("Expected illegal move at index 2, got %d", importErr.Index)
+	}
+
+	if len(gameRepo.games) != 0 || len(moveRepo.moves) != 0 {
+		t.Error("Nothing should be stored for a rejected import")
+	}
+}
+
+func TestGameService_ImportGame_StoreFailure(t *testing.T) {
+	gameRepo := newMockGameRepository()
+	moveRepo := newMockMoveRepository()
+	gameRepo.moveRepo = moveRepo
+	gameRepo.createErr = errors.New("connection lost")
+	service := NewGameService(gameRepo, moveRepo, newMockUserRepository())
+
+	if _, err := service.ImportGame(context.Background(), "owner", quickMateMoves, record.ResultUnknown); err == nil {
+		t.Fatal("Expected the store failure to be returned")
+	}
+	if len(gameRepo.games) != 0 || len(moveRepo.moves) != 0 {
+		t.Error("Nothing should be stored when the game cannot be created")
+	}
+}
+
+func TestGameService_ImportGame_RejectsEmptyMoveList(t *testing.T) {
+	service := NewGameService(newMockGameRepository(), newMockMoveRepository(), newMockUserRepository())
+
+	if _, err := service.ImportGame(context.Background(), "owner", nil, record.ResultUnknown); err != ErrEmptyImport {
+		t.Errorf("Expected ErrEmptyImport, got %v", err)
+	}
+}
+
+// quickMateMoves end with red's cannon mating on c9.
+var quickMateMoves = []models.MovePayload{
+	{From: "b2", To: "b4"},
+	{From: "a6", To: "a5"},
+	{From: "b4", To: "c4"},
+	{From: "f9", To: "e8"},
+	{From: "c4", To: "c9"},
+}
+
+func TestGameService_ImportGame_Results(t *testing.T) {
+	tests := []struct {
+		name           string
+		moves          []models.MovePayload
+		claimed        record.Result
+		wantResultType *models.ResultType
+		wantWinner     models.PlayerColor
+	}{
+		{"unknown", quickMateMoves[:2], record.ResultUnknown, nil, ""},
+		{"claimed win", quickMateMoves[:2], record.ResultBlackWins, resultTypePtr(models.ResultTypeResignation), models.PlayerColorBlack},
+		{"claimed draw", quickMateMoves[:2], record.ResultDraw, resultTypePtr(models.ResultTypeDraw), ""},
+		{"checkmate", quickMateMoves, record.ResultUnknown, resultTypePtr(models.ResultTypeCheckmate), models.PlayerColorRed},
+		{"checkmate claimed", quickMateMoves, record.ResultRedWins, resultTypePtr(models.ResultTypeCheckmate), models.PlayerColorRed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewGameService(newMockGameRepository(), newMockMoveRepository(), newMockUserRepository())
+
+			imported, err := service.ImportGame(context.Background(), "owner", tt.moves, tt.claimed)
+			if err != nil {
+				t.Fatalf("ImportGame failed: %v", err)
+			}
+			if (imported.ResultType == nil) != (tt.wantResultType == nil) ||
+				(tt.wantResultType != nil && *imported.ResultType != *tt.wantResultType) {
+				t.Errorf("Expected result type %v, got %v", tt.wantResultType, imported.ResultType)
+			}
+			var winner models.PlayerColor
+			if imported.WinnerColor != nil {
+				winner = *imported.WinnerColor
+			}
+			if winner != tt.wantWinner {
+				t.Errorf("Expected winner %q, got %q", tt.wantWinner, winner)
+			}
+		})
+	}
+}
+
+func TestGameService_ImportGame_RejectsContradictedResult(t *testing.T) {
+	gameRepo := newMockGameRepository()
+	service := NewGameService(gameRepo, newMockMoveRepository(), newMockUserRepository())
+
+	_, err := service.ImportGame(context.Background(), "owner", quickMateMoves, record.ResultBlackWins)
+	if !errors.Is(err, ErrImportResultMismatch) {
+		t.Fatalf("Expected ErrImportResultMismatch, got %v", err)
+	}
+	if len(gameRepo.games) != 0 {
+		t.Error("Nothing should be stored for a rejected import")
+	}
+}
+
+func resultTypePtr(resultType models.ResultType) *models.ResultType {
+	return &resultType
+}
@@ -0,0 +1,101 @@
+// Package services contains business logic for the application.
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/game"
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
+
+// MaxPositionsPerRequest caps how many positions GetPositions returns at
+// once, so long games are fetched a page at a time.
+const MaxPositionsPerRequest = 200
+
+// BoardPosition is a game's position after a ply, with the move that led to
+// it. The initial position has no move.
+type BoardPosition struct {
+	FEN  string
+	Move *models.Move
+}
+
+// GetPositions replays a game once from the initial position and returns
+// up to limit positions starting at ply start. Ply 0 is the initial
+// position, so a game with n moves has n+1 positions; that total is
+// returned for paging.
+func (s *GameService) GetPositions(ctx context.Context, gameID string, start, limit int) ([]BoardPosition, int, error) {
+	g, err := s.GetGame(ctx, gameID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	moves, err := s.GetMoves(ctx, gameID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total := len(moves) + 1
+	if limit < 1 || limit > MaxPositionsPerRequest {
+		limit = MaxPositionsPerRequest
+	}
+	if start < 0 || start >= total {
+		return []BoardPosition{}, total, nil
+	}
+	end := min(start+limit, total)
+
+	engine := game.NewGameEngine(g.ID, g.RedPlayerID, g.BlackPlayerID)
+	positions := make([]BoardPosition, 0, end-start)
+	if start == 0 {
+		positions = append(positions, BoardPosition{FEN: engine.GetFEN()})
+	}
+
+	// Stop replaying once the last requested position is reached
+	for ply, move := range moves[:end-1] {
+		result := engine.ValidateAndMakeMove(game.MoveRequest{
+			PlayerID: move.PlayerID,
+			From:     move.FromPosition,
+			To:       move.ToPosition,
+		})
+		if !result.Success {
+			return nil, 0, fmt.Errorf("failed to replay move %d: %s", move.MoveNumber, result.ErrorMessage)
+		}
+		if ply+1 >= start {
+			positions = append(positions, BoardPosition{FEN: engine.GetFEN(), Move: move})
+		}
+	}
+
+	return positions, total, nil
+}
+
+// PositionEvaluation is the material standing of a game's current position.
+type PositionEvaluation struct {
+	MoveNumber int    `json:"move_number"`
+	FEN        string `json:"fen"`
+	// MaterialBalance is red's material minus black's, in tenths of a
+	// soldier.
+	MaterialBalance int `json:"material_balance"`
+	// Captured counts the pieces each side has captured, by type.
+	Captured map[models.PlayerColor]map[models.PieceType]int `json:"captured"`
+}
+
+// EvaluatePosition reconstructs a game's current position from its stored
+// moves and returns its material balance and the pieces each side has
+// captured.
+func (s *GameService) EvaluatePosition(ctx context.Context, gameID string) (*PositionEvaluation, error) {
+	engine, err := s.LoadEngine(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	board := engine.GetBoard()
+	return &PositionEvaluation{
+		MoveNumber:      len(engine.GetMoveHistory()),
+		FEN:             engine.GetFEN(),
+		MaterialBalance: board.MaterialBalance(),
+		Captured: map[models.PlayerColor]map[models.PieceType]int{
+			models.PlayerColorRed:   board.CapturedPieces(models.PlayerColorBlack),
+			models.PlayerColorBlack: board.CapturedPieces(models.PlayerColorRed),
+		},
+	}, nil
+}
@@ -8,42 +8,143 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
 
+	"github.com/xiangqi/chinese-chess-backend/internal/game"
 	"github.com/xiangqi/chinese-chess-backend/internal/models"
 	"github.com/xiangqi/chinese-chess-backend/internal/repository"
 )
 
 // GameService handles game business logic.
 type GameService struct {
-	gameRepo *repository.GameRepository
-	moveRepo *repository.MoveRepository
-	userRepo *repository.UserRepository
+	gameRepo GameRepository
+	moveRepo MoveRepository
+	userRepo UserRepository
+
+	// snapshots stores periodic board snapshots used to speed up LoadEngine.
+	// Snapshots are disabled when nil.
+	snapshots        KeyValueStore
+	snapshotInterval int
+
+	// resultHook is notified in the background whenever a game ends.
+	resultHook GameResultHook
+
+	// searchPool runs engine searches. Searches run on the caller's
+	// goroutine when nil.
+	searchPool *SearchPool
+
+	// ratingBounds keeps ratings updated by ranked games within a floor
+	// and a ceiling.
+	ratingBounds RatingBounds
 }
 
 // NewGameService creates a new GameService.
 func NewGameService(
-	gameRepo *repository.GameRepository,
-	moveRepo *repository.MoveRepository,
-	userRepo *repository.UserRepository,
+	gameRepo GameRepository,
+	moveRepo MoveRepository,
+	userRepo UserRepository,
 ) *GameService {
 	return &GameService{
-		gameRepo: gameRepo,
-		moveRepo: moveRepo,
-		userRepo: userRepo,
+		gameRepo:   gameRepo,
+		moveRepo:   moveRepo,
+		userRepo:   userRepo,
+		resultHook: noopResultHook{},
+
+		ratingBounds: NewRatingBounds(0, 0),
 	}
 }
 
-// CreateGame creates a new game between two players.
-func (s *GameService) CreateGame(ctx context.Context, redPlayerID, blackPlayerID string, turnTimeout int) (*models.Game, error) {
+// SetRatingBounds sets the floor and ceiling ratings are kept within.
+func (s *GameService) SetRatingBounds(bounds RatingBounds) {
+	s.ratingBounds = bounds
+}
+
+// SetResultHook sets the hook notified of completed games. A nil hook
+// disables notifications.
+func (s *GameService) SetResultHook(hook GameResultHook) {
+	if hook == nil {
+		hook = noopResultHook{}
+	}
+	s.resultHook = hook
+}
+
+// SetSearchPool sets the pool that engine searches run on. A nil pool runs
+// searches on the calling goroutine.
+func (s *GameService) SetSearchPool(pool *SearchPool) {
+	s.searchPool = pool
+}
+
+// runSearch runs an engine search on the search pool, if there is one.
+func (s *GameService) runSearch(ctx context.Context, search func(ctx context.Context) error) error {
+	if s.searchPool == nil {
+		return search(ctx)
+	}
+	return s.searchPool.Run(ctx, search)
+}
+
+// NewGameServiceWithSnapshots creates a new GameService that saves a snapshot
+// of each game every snapshotInterval moves, as well as after rollbacks and
+// when the game ends.
+func NewGameServiceWithSnapshots(
+	gameRepo GameRepository,
+	moveRepo MoveRepository,
+	userRepo UserRepository,
+	snapshots KeyValueStore,
+	snapshotInterval int,
+) *GameService {
+	service := NewGameService(gameRepo, moveRepo, userRepo)
+	service.snapshots = snapshots
+	service.snapshotInterval = snapshotInterval
+	return service
+}
+
+// GameSettings holds the settings a game is created with.
+type GameSettings struct {
+	// TurnTimeout is the time allowed per turn, in seconds.
+	TurnTimeout int
+	// TimeControl is how the clocks run. Empty means per-move.
+	TimeControl models.TimeControl
+	// Increment is the Fischer increment or Bronstein delay, in seconds.
+	Increment int
+	// BaseTime is each side's time for a Fischer or Bronstein game, in
+	// seconds. Per-move games leave it zero.
+	BaseTime int
+	// IsPublic lists the game for spectating while in progress.
+	IsPublic bool
+	// Ranked games count towards player stats and ratings; casual games are
+	// only recorded in history.
+	Ranked bool
+	// Assist lets the server send legal move hints, such as forced moves.
+	// Competitive games may turn it off.
+	Assist bool
+	// BotDifficulty is the difficulty of the computer opponent playing
+	// black, or zero when both players are human.
+	BotDifficulty int
+}
+
+// CreateGame creates a new game between two players with the given settings.
+func (s *GameService) CreateGame(ctx context.Context, redPlayerID, blackPlayerID string, settings GameSettings) (*models.Game, error) {
+	timeControl := settings.TimeControl
+	if timeControl == "" {
+		timeControl = models.TimeControlPerMove
+	}
+
 	game := &models.Game{
 		ID:                      uuid.New().String(),
 		RedPlayerID:             redPlayerID,
 		BlackPlayerID:           blackPlayerID,
 		Status:                  models.GameStatusActive,
-		TurnTimeoutSeconds:      turnTimeout,
+		TurnTimeoutSeconds:      settings.TurnTimeout,
 		RedRollbacksRemaining:   3,
 		BlackRollbacksRemaining: 3,
 		TotalMoves:              0,
+		IsPublic:                settings.IsPublic,
+		Ranked:                  settings.Ranked,
+		AssistEnabled:           settings.Assist,
+		BotDifficulty:           settings.BotDifficulty,
+		TimeControl:             timeControl,
+		IncrementSeconds:        settings.Increment,
+		BaseTimeSeconds:         settings.BaseTime,
 	}
 
 	if err := s.gameRepo.Create(ctx, game); err != nil {
@@ -65,6 +166,13 @@ func (s *GameService) GetGame(ctx context.Context, gameID string) (*models.Game,
 	return game, nil
 }
 
+// WantsResignSuggestions reports whether a player has opted in to resign
+// suggestions. A player who cannot be loaded is treated as not opted in.
+func (s *GameService) WantsResignSuggestions(ctx context.Context, playerID string) bool {
+	user, err := s.userRepo.GetByID(ctx, playerID)
+	return err == nil && user.ResignSuggestions
+}
+
 // GetHistory retrieves a player's game history.
 func (s *GameService) GetHistory(ctx context.Context, playerID string, page, pageSize int) ([]*models.Game, int, error) {
 	offset := (page - 1) * pageSize
@@ -82,6 +190,52 @@ func (s *GameService) GetHistory(ctx context.Context, playerID string, page, pag
 	return games, total, nil
 }
 
+// LiveGame is an in-progress public game with its players' display names.
+type LiveGame struct {
+	Game            *models.Game
+	RedPlayerName   string
+	BlackPlayerName string
+}
+
+// GetLivePublicGames retrieves active public games available for spectating.
+func (s *GameService) GetLivePublicGames(ctx context.Context, page, pageSize int) ([]*LiveGame, int, error) {
+	offset := (page - 1) * pageSize
+
+	games, err := s.gameRepo.GetLivePublic(ctx, pageSize, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get live games: %w", err)
+	}
+
+	total, err := s.gameRepo.CountLivePublic(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count live games: %w", err)
+	}
+
+	names := make(map[string]string)
+	displayName := func(playerID string) string {
+		if name, ok := names[playerID]; ok {
+			return name
+		}
+		name := ""
+		if user, err := s.userRepo.GetByID(ctx, playerID); err == nil {
+			name = user.DisplayName
+		}
+		names[playerID] = name
+		return name
+	}
+
+	liveGames := make([]*LiveGame, len(games))
+	for i, game := range games {
+		liveGames[i] = &LiveGame{
+			Game:            game,
+			RedPlayerName:   displayName(game.RedPlayerID),
+			BlackPlayerName: displayName(game.BlackPlayerID),
+		}
+	}
+
+	return liveGames, total, nil
+}
+
 // GetMoves retrieves all moves for a game.
 func (s *GameService) GetMoves(ctx context.Context, gameID string) ([]*models.Move, error) {
 	moves, err := s.moveRepo.GetByGameID(ctx, gameID)
@@ -91,6 +245,52 @@ func (s *GameService) GetMoves(ctx context.Context, gameID string) ([]*models.Mo
 	return moves, nil
 }
 
+// replayOptions turn off the rules that end a game on their own while stored
+// moves are replayed. The moves were accepted when they were played, and
+// the rules in force may have changed since.
+var replayOptions = []game.EngineOption{
+	game.WithPerpetualCheckRule(false),
+	game.WithNoCaptureDrawPlies(0),
+}
+
+// LoadEngine rebuilds a game engine for a game from its latest snapshot, if
+// any, and replays the stored moves made after it. The options configure
+// the engine for the game's rules from the last stored move on.
+func (s *GameService) LoadEngine(ctx context.Context, gameID string, opts ...game.EngineOption) (*game.GameEngine, error) {
+	g, err := s.GetGame(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	moves, err := s.GetMoves(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	var engine *game.GameEngine
+	// A missing or unreadable snapshot only costs a full replay
+	if snapshot, err := s.loadSnapshot(ctx, gameID); err == nil && snapshot != nil {
+		engine = engineFromSnapshot(g, snapshot, moves, replayOptions...)
+	}
+	if engine == nil {
+		engine = game.NewGameEngine(g.ID, g.RedPlayerID, g.BlackPlayerID, replayOptions...)
+	}
+
+	for _, move := range moves[len(engine.GetMoveHistory()):] {
+		result := engine.ValidateAndMakeMove(game.MoveRequest{
+			PlayerID: move.PlayerID,
+			From:     move.FromPosition,
+			To:       move.ToPosition,
+		})
+		if !result.Success {
+			return nil, fmt.Errorf("failed to replay move %d: %s", move.MoveNumber, result.ErrorMessage)
+		}
+	}
+
+	engine.SetOptions(opts...)
+	return engine, nil
+}
+
 // RecordMove records a move in a game.
 func (s *GameService) RecordMove(ctx context.Context, move *models.Move) error {
 	move.Timestamp = time.Now()
@@ -110,6 +310,30 @@ func (s *GameService) RecordMove(ctx context.Context, move *models.Move) error {
 		return fmt.Errorf("failed to update game: %w", err)
 	}
 
+	if s.snapshots != nil && s.snapshotInterval > 0 && move.MoveNumber%s.snapshotInterval == 0 {
+		// Snapshots only speed up recovery; the move itself is already stored
+		_ = s.flushSnapshot(ctx, move.GameID)
+	}
+
+	return nil
+}
+
+// SaveClocks stores both players' remaining time after a move, for rooms
+// recreated after a restart or on another instance.
+func (s *GameService) SaveClocks(ctx context.Context, gameID string, redTime, blackTime int) error {
+	game, err := s.gameRepo.GetByID(ctx, gameID)
+	if err != nil {
+		if errors.Is(err, repository.ErrGameNotFound) {
+			return ErrGameNotFound
+		}
+		return fmt.Errorf("failed to get game: %w", err)
+	}
+
+	game.RedTimeRemaining = &redTime
+	game.BlackTimeRemaining = &blackTime
+	if err := s.gameRepo.Update(ctx, game); err != nil {
+		return fmt.Errorf("failed to save clocks: %w", err)
+	}
 	return nil
 }
 
@@ -126,30 +350,77 @@ func (s *GameService) EndGame(ctx context.Context, gameID string, winnerID *stri
 	game.ResultType = &resultType
 	game.CompletedAt = &now
 
+	// Keep the final position so history and sharing don't need a replay
+	if engine, err := s.LoadEngine(ctx, gameID); err == nil {
+		fen := engine.GetFEN()
+		game.FinalFEN = &fen
+	}
+
 	if err := s.gameRepo.Update(ctx, game); err != nil {
 		return fmt.Errorf("failed to update game: %w", err)
 	}
 
-	// Update player stats
-	var redResult, blackResult GameResult
-	if winnerID == nil {
-		redResult = GameResultDraw
-		blackResult = GameResultDraw
-	} else if *winnerID == game.RedPlayerID {
-		redResult = GameResultWin
-		blackResult = GameResultLoss
-	} else {
-		redResult = GameResultLoss
-		blackResult = GameResultWin
+	// Update player stats; casual games only appear in history
+	if game.Ranked {
+		var redResult, blackResult GameResult
+		if winnerID == nil {
+			redResult = GameResultDraw
+			blackResult = GameResultDraw
+		} else if *winnerID == game.RedPlayerID {
+			redResult = GameResultWin
+			blackResult = GameResultLoss
+		} else {
+			redResult = GameResultLoss
+			blackResult = GameResultWin
+		}
+
+		userService := NewUserService(s.userRepo)
+		userService.SetRatingBounds(s.ratingBounds)
+		_ = userService.UpdateStatsWithResultType(ctx, game.RedPlayerID, redResult, resultType)
+		_ = userService.UpdateStatsWithResultType(ctx, game.BlackPlayerID, blackResult, resultType)
+
+		_ = userService.ApplyGameRatings(ctx, game.RedPlayerID, game.BlackPlayerID, redResult)
 	}
 
-	userService := NewUserService(s.userRepo)
-	_ = userService.UpdateStats(ctx, game.RedPlayerID, redResult)
-	_ = userService.UpdateStats(ctx, game.BlackPlayerID, blackResult)
+	if s.snapshots != nil {
+		_ = s.flushSnapshot(ctx, gameID)
+	}
+
+	// Notify without blocking; the game is already complete either way
+	summary := newGameSummary(game)
+	hook := s.resultHook
+	go func() {
+		if err := hook.GameEnded(context.Background(), summary); err != nil {
+			log.Warn().Err(err).Str("game_id", summary.GameID).Msg("Game result hook failed")
+		}
+	}()
 
 	return nil
 }
 
+// AbandonGame marks an active game that never got under way as abandoned.
+// Nobody wins and player stats are left alone. A game that is no longer
+// active, or already has moves, is left as it is.
+func (s *GameService) AbandonGame(ctx context.Context, gameID string) error {
+	game, err := s.gameRepo.GetByID(ctx, gameID)
+	if err != nil {
+		return fmt.Errorf("failed to get game: %w", err)
+	}
+	if game.Status != models.GameStatusActive || game.TotalMoves > 0 {
+		return nil
+	}
+
+	now := time.Now()
+	resultType := models.ResultTypeAbandonment
+	game.Status = models.GameStatusAbandoned
+	game.ResultType = &resultType
+	game.CompletedAt = &now
+	if err := s.gameRepo.Update(ctx, game); err != nil {
+		return fmt.Errorf("failed to update game: %w", err)
+	}
+	return nil
+}
+
 // UseRollback decrements a player's rollback count.
 func (s *GameService) UseRollback(ctx context.Context, gameID, playerID string) error {
 	game, err := s.gameRepo.GetByID(ctx, gameID)
@@ -196,6 +467,13 @@ func (s *GameService) RevertToMove(ctx context.Context, gameID string, moveNumbe
 		return fmt.Errorf("failed to update game: %w", err)
 	}
 
+	if s.snapshots != nil {
+		// Replace any snapshot taken after the reverted move
+		if err := s.flushSnapshot(ctx, gameID); err != nil {
+			_ = s.snapshots.Del(ctx, snapshotKey+gameID)
+		}
+	}
+
 	return nil
 }
 
@@ -0,0 +1,226 @@
+// Package services provides unit tests for the game service.
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
+
+// newTestGameServiceWithPlayers creates a game service with users
+// "red-player" and "black-player".
+func newTestGameServiceWithPlayers() (*GameService, *mockUserRepository) {
+	ctx := context.Background()
+	userRepo := newMockUserRepository()
+	userRepo.Create(ctx, &models.User{ID: "red-player", DisplayName: "RedPlayer"})
+	userRepo.Create(ctx, &models.User{ID: "black-player", DisplayName: "BlackPlayer"})
+
+	return NewGameService(newMockGameRepository(), newMockMoveRepository(), userRepo), userRepo
+}
+
+func TestGameService_CreateGame_TimeControl(t *testing.T) {
+	service, _ := newTestGameServiceWithPlayers()
+	ctx := context.Background()
+
+	game, err := service.CreateGame(ctx, "red-player", "black-player", GameSettings{TurnTimeout: 60})
+	if err != nil {
+		t.Fatalf("CreateGame failed: %v", err)
+	}
+	if game.TimeControl != models.TimeControlPerMove {
+		t.Errorf("Expected per-move time control by default, got %q", game.TimeControl)
+	}
+
+	game, err = service.CreateGame(ctx, "red-player", "black-player", GameSettings{
+		TurnTimeout: 600,
+		TimeControl: models.TimeControlFischer,
+		Increment:   5,
+		BaseTime:    900,
+	})
+	if err != nil {
+		t.Fatalf("CreateGame failed: %v", err)
+	}
+	if game.TimeControl != models.TimeControlFischer || game.IncrementSeconds != 5 || game.BaseTimeSeconds != 900 {
+		t.Errorf("Expected Fischer with 900s and a 5s increment, got %s/%d/%d",
+			game.TimeControl, game.BaseTimeSeconds, game.IncrementSeconds)
+	}
+}
+
+func TestGameService_EndGame_RankedUpdatesStats(t *testing.T) {
+	service, userRepo := newTestGameServiceWithPlayers()
+	ctx := context.Background()
+
+	game, err := service.CreateGame(ctx, "red-player", "black-player", GameSettings{TurnTimeout: 60, Ranked: true})
+	if err != nil {
+		t.Fatalf("CreateGame failed: %v", err)
+	}
+
+	winner := "red-player"
+	if err := service.EndGame(ctx, game.ID, &winner, models.ResultTypeCheckmate); err != nil {
+		t.Fatalf("EndGame failed: %v", err)
+	}
+
+	if red := userRepo.users["red-player"]; red.Wins != 1 || red.TotalGames != 1 {
+		t.Errorf("Expected red to have 1 win in 1 game, got %d in %d", red.Wins, red.TotalGames)
+	}
+	if black := userRepo.users["black-player"]; black.Losses != 1 || black.TotalGames != 1 {
+		t.Errorf("Expected black to have 1 loss in 1 game, got %d in %d", black.Losses, black.TotalGames)
+	}
+}
+
+func TestGameService_EndGame_RankedTransfersRating(t *testing.T) {
+	service, userRepo := newTestGameServiceWithPlayers()
+	ctx := context.Background()
+	userRepo.users["red-player"].Rating = 1400
+	userRepo.users["black-player"].Rating = 1200
+
+	game, err := service.CreateGame(ctx, "red-player", "black-player", GameSettings{TurnTimeout: 60, Ranked: true})
+	if err != nil {
+		t.Fatalf("CreateGame failed: %v", err)
+	}
+
+	// The underdog wins, so the transfer is larger than between equals
+	winner := "black-player"
+	if err := service.EndGame(ctx, game.ID, &winner, models.ResultTypeCheckmate); err != nil {
+		t.Fatalf("EndGame failed: %v", err)
+	}
+
+	red, black := userRepo.users["red-player"], userRepo.users["black-player"]
+	gain := black.Rating - 1200
+	if gain != RatingChange(1200, 1400, GameResultWin) || gain <= RatingKFactor/2 {
+		t.Errorf("Expected black to gain %d, got %+d", RatingChange(1200, 1400, GameResultWin), gain)
+	}
+	if loss := 1400 - red.Rating; loss != gain {
+		t.Errorf("Expected red to lose the %d points black gained, lost %d", gain, loss)
+	}
+
+	// Stats are still counted alongside the rating change
+	if red.Losses != 1 || black.Wins != 1 {
+		t.Errorf("Expected the game in both players' stats, got red %+v, black %+v", red.Stats(), black.Stats())
+	}
+	if black.RatingDeviation >= models.DefaultRatingDeviation {
+		t.Errorf("Expected a ranked game to narrow the rating deviation, got %d", black.RatingDeviation)
+	}
+}
+
+func TestGameService_EndGame_RankedDrawBetweenEqualsKeepsRatings(t *testing.T) {
+	service, userRepo := newTestGameServiceWithPlayers()
+	ctx := context.Background()
+
+	game, err := service.CreateGame(ctx, "red-player", "black-player", GameSettings{TurnTimeout: 60, Ranked: true})
+	if err != nil {
+		t.Fatalf("CreateGame failed: %v", err)
+	}
+	if err := service.EndGame(ctx, game.ID, nil, models.ResultTypeDraw); err != nil {
+		t.Fatalf("EndGame failed: %v", err)
+	}
+
+	for _, id := range []string{"red-player", "black-player"} {
+		user := userRepo.users[id]
+		if user.Rating != models.DefaultRating || user.Draws != 1 {
+			t.Errorf("Expected %s to keep %d with 1 draw, got %d with %d", id, models.DefaultRating, user.Rating, user.Draws)
+		}
+	}
+}
+
+func TestGameService_EndGame_CasualSkipsStats(t *testing.T) {
+	service, userRepo := newTestGameServiceWithPlayers()
+	ctx := context.Background()
+
+	game, err := service.CreateGame(ctx, "red-player", "black-player", GameSettings{TurnTimeout: 60, Ranked: false})
+	if err != nil {
+		t.Fatalf("CreateGame failed: %v", err)
+	}
+	if game.Ranked {
+		t.Fatal("Expected a casual game")
+	}
+
+	winner := "black-player"
+	if err := service.EndGame(ctx, game.ID, &winner, models.ResultTypeResignation); err != nil {
+		t.Fatalf("EndGame failed: %v", err)
+	}
+
+	for _, id := range []string{"red-player", "black-player"} {
+		if user := userRepo.users[id]; user.TotalGames != 0 || user.Wins != 0 || user.Losses != 0 {
+			t.Errorf("Casual game should not change stats for %s, got %+v", id, user.Stats())
+		}
+	}
+
+	// The game is still recorded in both players' history
+	for _, id := range []string{"red-player", "black-player"} {
+		games, total, err := service.GetHistory(ctx, id, 1, 20)
+		if err != nil {
+			t.Fatalf("GetHistory failed: %v", err)
+		}
+		if total != 1 || len(games) != 1 || games[0].ID != game.ID {
+			t.Errorf("Expected casual game in history for %s, got %d games", id, total)
+		}
+	}
+}
+
+func TestGameService_EndGame_StoresFinalFEN(t *testing.T) {
+	service, _ := newTestGameServiceWithPlayers()
+	ctx := context.Background()
+
+	game, err := service.CreateGame(ctx, "red-player", "black-player", GameSettings{TurnTimeout: 60, Ranked: true})
+	if err != nil {
+		t.Fatalf("CreateGame failed: %v", err)
+	}
+	if err := service.RecordMove(ctx, &models.Move{
+		GameID:       game.ID,
+		MoveNumber:   1,
+		PlayerID:     "red-player",
+		FromPosition: "h2",
+		ToPosition:   "e2",
+		PieceType:    models.PieceTypeCannon,
+	}); err != nil {
+		t.Fatalf("RecordMove failed: %v", err)
+	}
+
+	winner := "red-player"
+	if err := service.EndGame(ctx, game.ID, &winner, models.ResultTypeResignation); err != nil {
+		t.Fatalf("EndGame failed: %v", err)
+	}
+
+	completed, err := service.GetGame(ctx, game.ID)
+	if err != nil {
+		t.Fatalf("GetGame failed: %v", err)
+	}
+	if completed.FinalFEN == nil || *completed.FinalFEN == "" {
+		t.Fatal("Expected completed game to carry a final FEN")
+	}
+	expected := "rnbakabnr/9/1c5c1/p1p1p1p1p/9/9/P1P1P1P1P/1C2C4/9/RNBAKABNR b"
+	if *completed.FinalFEN != expected {
+		t.Errorf("Expected final FEN %s, got %s", expected, *completed.FinalFEN)
+	}
+}
+
+func TestGameService_EndGame_CountsWinByResultType(t *testing.T) {
+	service, userRepo := newTestGameServiceWithPlayers()
+	ctx := context.Background()
+
+	for _, resultType := range []models.ResultType{models.ResultTypeAbandonment, models.ResultTypeCheckmate} {
+		game, err := service.CreateGame(ctx, "red-player", "black-player", GameSettings{TurnTimeout: 60, Ranked: true})
+		if err != nil {
+			t.Fatalf("CreateGame failed: %v", err)
+		}
+		winner := "red-player"
+		if err := service.EndGame(ctx, game.ID, &winner, resultType); err != nil {
+			t.Fatalf("EndGame failed: %v", err)
+		}
+	}
+
+	red := userRepo.users["red-player"]
+	expected := models.WinBreakdown{Checkmate: 1, Abandonment: 1}
+	if red.WinBreakdown != expected {
+		t.Errorf("Expected win breakdown %+v, got %+v", expected, red.WinBreakdown)
+	}
+	if red.Wins != 2 {
+		t.Errorf("Expected 2 wins in total, got %d", red.Wins)
+	}
+
+	// Losses are not broken down
+	if black := userRepo.users["black-player"]; black.WinBreakdown != (models.WinBreakdown{}) {
+		t.Errorf("Expected empty win breakdown for the loser, got %+v", black.WinBreakdown)
+	}
+}
@@ -0,0 +1,89 @@
+// Package services contains business logic for the application.
+package services
+
+import (
+	"sort"
+	"time"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
+
+const (
+	// initialRatingSpread is the rating difference accepted right after
+	// joining the queue.
+	initialRatingSpread = 100
+	// ratingSpreadStep is how much the accepted difference grows per
+	// widening interval.
+	ratingSpreadStep = 50
+	// balancedWidenInterval is the widening interval for the default
+	// preference.
+	balancedWidenInterval = 15 * time.Second
+	// closeWidenInterval is the slower widening interval for players who
+	// prefer a close match.
+	closeWidenInterval = 60 * time.Second
+)
+
+// ratingSpread returns the largest rating difference a queued player accepts
+// at the given time. It returns -1 when any opponent is acceptable.
+func ratingSpread(entry *models.MatchmakingEntry, now time.Time) int {
+	interval := balancedWidenInterval
+	switch entry.Preference {
+	case models.MatchPreferenceFast:
+		return -1
+	case models.MatchPreferenceClose:
+		interval = closeWidenInterval
+	}
+
+	waited := now.Sub(entry.JoinedAt)
+	if waited < 0 {
+		waited = 0
+	}
+	return initialRatingSpread + ratingSpreadStep*int(waited/interval)
+}
+
+// withinRatingBand reports whether two queued players are close enough in
+// rating for both of them to accept the match at the given time.
+func withinRatingBand(a, b *models.MatchmakingEntry, now time.Time) bool {
+	diff := ratingDistance(a, b)
+	for _, entry := range []*models.MatchmakingEntry{a, b} {
+		if spread := ratingSpread(entry, now); spread >= 0 && diff > spread {
+			return false
+		}
+	}
+	return true
+}
+
+// rankOpponents returns the queued players the entry can be matched with at
+// the given time, closest in rating first. Equally close players keep their
+// queue order, so the longest waiting is preferred.
+func rankOpponents(entry *models.MatchmakingEntry, queued []*models.MatchmakingEntry, now time.Time) []*models.MatchmakingEntry {
+	eligible := make([]*models.MatchmakingEntry, 0, len(queued))
+	for _, opponent := range queued {
+		if opponent.DeviceID != entry.DeviceID && canMatch(entry, opponent, now) {
+			eligible = append(eligible, opponent)
+		}
+	}
+
+	sort.SliceStable(eligible, func(i, j int) bool {
+		return ratingDistance(entry, eligible[i]) < ratingDistance(entry, eligible[j])
+	})
+	return eligible
+}
+
+// ratingDistance returns the rating difference between two queued players.
+func ratingDistance(a, b *models.MatchmakingEntry) int {
+	diff := a.Rating - b.Rating
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff
+}
+
+// ValidMatchPreference reports whether p is a known matchmaking preference.
+func ValidMatchPreference(p models.MatchPreference) bool {
+	switch p {
+	case models.MatchPreferenceBalanced, models.MatchPreferenceFast, models.MatchPreferenceClose:
+		return true
+	}
+	return false
+}
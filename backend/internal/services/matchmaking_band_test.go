@@ -0,0 +1,127 @@
+// Package services provides unit tests for the matchmaking rating band.
+package services
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
+
+func TestWithinRatingBand_FastMatchesDistantOpponentImmediately(t *testing.T) {
+	now := time.Now()
+	waiting := &models.MatchmakingEntry{Rating: 1800, Preference: models.MatchPreferenceFast, JoinedAt: now.Add(-time.Second)}
+	joining := &models.MatchmakingEntry{Rating: 1200, Preference: models.MatchPreferenceFast, JoinedAt: now}
+
+	if !withinRatingBand(joining, waiting, now) {
+		t.Error("Expected fast players to match regardless of rating")
+	}
+}
+
+func TestWithinRatingBand_CloseWaitsForDistantOpponent(t *testing.T) {
+	now := time.Now()
+	waiting := &models.MatchmakingEntry{Rating: 1500, Preference: models.MatchPreferenceClose, JoinedAt: now.Add(-30 * time.Second)}
+	joining := &models.MatchmakingEntry{Rating: 1200, Preference: models.MatchPreferenceFast, JoinedAt: now}
+
+	if withinRatingBand(joining, waiting, now) {
+		t.Error("Expected close player to keep waiting for a nearer rating")
+	}
+
+	// After several widening intervals the band covers the difference
+	later := now.Add(4 * closeWidenInterval)
+	if !withinRatingBand(joining, waiting, later) {
+		t.Error("Expected close player's band to eventually cover 300 points")
+	}
+}
+
+func TestRatingSpread_WideningPace(t *testing.T) {
+	joined := time.Now()
+
+	tests := []struct {
+		name       string
+		preference models.MatchPreference
+		waited     time.Duration
+		want       int
+	}{
+		{"balanced on join", models.MatchPreferenceBalanced, 0, initialRatingSpread},
+		{"balanced after 30s", models.MatchPreferenceBalanced, 30 * time.Second, initialRatingSpread + 2*ratingSpreadStep},
+		{"close after 30s", models.MatchPreferenceClose, 30 * time.Second, initialRatingSpread},
+		{"close after 2m", models.MatchPreferenceClose, 2 * time.Minute, initialRatingSpread + 2*ratingSpreadStep},
+		{"fast is unlimited", models.MatchPreferenceFast, 0, -1},
+	}
+
+	for _, tt := range tests {
+		entry := &models.MatchmakingEntry{Preference: tt.preference, JoinedAt: joined}
+		if got := ratingSpread(entry, joined.Add(tt.waited)); got != tt.want {
+			t.Errorf("%s: expected spread %d, got %d", tt.name, tt.want, got)
+		}
+	}
+}
+
+// queuedAt returns balanced ranked entries for the given ratings, each
+// joining a second after the previous one, starting at joined.
+func queuedAt(joined time.Time, ratings ...int) []*models.MatchmakingEntry {
+	entries := make([]*models.MatchmakingEntry, len(ratings))
+	for i, rating := range ratings {
+		entries[i] = &models.MatchmakingEntry{
+			DeviceID:    fmt.Sprintf("player-%d", i+1),
+			TurnTimeout: 300,
+			Ranked:      true,
+			Rating:      rating,
+			JoinedAt:    joined.Add(time.Duration(i) * time.Second),
+		}
+	}
+	return entries
+}
+
+func TestRankOpponents_ClosestEligibleFirst(t *testing.T) {
+	now := time.Now()
+	queued := queuedAt(now.Add(-5*time.Second), 1000, 1290, 1150, 1230, 1170)
+	joining := &models.MatchmakingEntry{DeviceID: "joining", TurnTimeout: 300, Ranked: true, Rating: 1200, JoinedAt: now}
+
+	ranked := rankOpponents(joining, queued, now)
+
+	// 1000 is outside the ±100 band; the rest are ordered by distance, and
+	// the two players 30 points away keep their queue order
+	want := []string{"player-4", "player-5", "player-3", "player-2"}
+	got := make([]string, len(ranked))
+	for i, entry := range ranked {
+		got[i] = entry.DeviceID
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected opponents %v, got %v", want, got)
+	}
+}
+
+func TestRankOpponents_NoneInRangeKeepsWaiting(t *testing.T) {
+	now := time.Now()
+	queued := queuedAt(now, 1500, 900)
+	joining := &models.MatchmakingEntry{DeviceID: "joining", TurnTimeout: 300, Ranked: true, Rating: 1200, JoinedAt: now}
+
+	if ranked := rankOpponents(joining, queued, now); len(ranked) != 0 {
+		t.Errorf("Expected no opponent within range, got %d", len(ranked))
+	}
+}
+
+func TestRankOpponents_WindowExpandsOverTime(t *testing.T) {
+	joined := time.Now()
+	queued := queuedAt(joined, 1400)
+	waiting := &models.MatchmakingEntry{DeviceID: "waiting", TurnTimeout: 300, Ranked: true, Rating: 1200, JoinedAt: joined}
+
+	// 200 points needs two widening steps of both players' bands
+	for _, tt := range []struct {
+		waited time.Duration
+		match  bool
+	}{
+		{0, false},
+		{balancedWidenInterval, false},
+		{2 * balancedWidenInterval, true},
+	} {
+		ranked := rankOpponents(waiting, queued, joined.Add(tt.waited))
+		if matched := len(ranked) == 1; matched != tt.match {
+			t.Errorf("After %v: expected match %v, got %v", tt.waited, tt.match, matched)
+		}
+	}
+}
@@ -6,10 +6,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"math/rand"
 	"time"
 
-	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
 
 	"github.com/xiangqi/chinese-chess-backend/internal/models"
 	"github.com/xiangqi/chinese-chess-backend/internal/repository"
@@ -22,18 +23,207 @@ const (
 	matchmakingTTL        = 5 * time.Minute
 )
 
+// matchmakingUnclaimedKey is a sorted set of the matched games nobody has
+// opened yet, scored by the end of their claim window.
+const matchmakingUnclaimedKey = "matchmaking:unclaimed"
+
+// DefaultClaimWindow is how long a match result is kept for players who
+// have not opened the game yet, when no claim window is configured.
+const DefaultClaimWindow = 60 * time.Second
+
+// DefaultMaxWait is how long a player may wait in the queue before being
+// removed, when no max wait is configured.
+const DefaultMaxWait = 120 * time.Second
+
+// queueReapInterval is how often Run scans the queue for stale entries,
+// retries matches and abandons unclaimed games.
+const queueReapInterval = 10 * time.Second
+
+// MaxIncrementSeconds is the largest Fischer increment or Bronstein delay a
+// player may queue with.
+const MaxIncrementSeconds = 60
+
+// MinBaseTimeSeconds and MaxBaseTimeSeconds bound each side's time for a
+// Fischer or Bronstein game.
+const (
+	MinBaseTimeSeconds = 60
+	MaxBaseTimeSeconds = 3600
+)
+
 // MatchmakingService handles matchmaking logic.
 type MatchmakingService struct {
-	redis       *repository.RedisClient
+	queue       SortedSetStore
+	entries     KeyValueStore
+	results     KeyValueStore
 	gameService *GameService
+	claimWindow time.Duration
+	maxWait     time.Duration
+	now         func() time.Time
 }
 
 // NewMatchmakingService creates a new MatchmakingService.
 func NewMatchmakingService(redis *repository.RedisClient, gameService *GameService) *MatchmakingService {
+	return NewMatchmakingServiceWithClaimWindow(redis, gameService, DefaultClaimWindow)
+}
+
+// NewMatchmakingServiceWithClaimWindow creates a new MatchmakingService that
+// keeps match results for claimWindow after matching, so a player whose app
+// closed before opening the game can still find it by polling the status.
+// A non-positive claimWindow falls back to DefaultClaimWindow.
+func NewMatchmakingServiceWithClaimWindow(redis *repository.RedisClient, gameService *GameService, claimWindow time.Duration) *MatchmakingService {
+	if claimWindow <= 0 {
+		claimWindow = DefaultClaimWindow
+	}
 	return &MatchmakingService{
-		redis:       redis,
+		queue:       redis,
+		entries:     redis,
+		results:     redis,
 		gameService: gameService,
+		claimWindow: claimWindow,
+		maxWait:     DefaultMaxWait,
+		now:         time.Now,
+	}
+}
+
+// SetMaxWait sets how long a player may wait in the queue before Run removes
+// them. A non-positive maxWait falls back to DefaultMaxWait.
+func (s *MatchmakingService) SetMaxWait(maxWait time.Duration) {
+	if maxWait <= 0 {
+		maxWait = DefaultMaxWait
 	}
+	s.maxWait = maxWait
+}
+
+// Run removes players who have waited longer than the max wait from the
+// queue until ctx is cancelled, so an entry left behind by a crashed client
+// is not matched against. It also retries matches for the players still
+// waiting, whose rating bands widen as they wait, and abandons matched games
+// that nobody opened within the claim window.
+func (s *MatchmakingService) Run(ctx context.Context) {
+	ticker := time.NewTicker(queueReapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := s.reapStale(ctx); err != nil && ctx.Err() == nil {
+			log.Warn().Err(err).Msg("Failed to remove stale matchmaking entries")
+		}
+		if _, err := s.matchWaiting(ctx); err != nil && ctx.Err() == nil {
+			log.Warn().Err(err).Msg("Failed to match waiting players")
+		}
+		if _, err := s.abandonUnclaimed(ctx); err != nil && ctx.Err() == nil {
+			log.Warn().Err(err).Msg("Failed to abandon unclaimed games")
+		}
+	}
+}
+
+// MarkOpened records that a player opened a matched game, so it is not
+// abandoned when its claim window ends.
+func (s *MatchmakingService) MarkOpened(ctx context.Context, gameID string) error {
+	if _, err := s.queue.RemoveMember(ctx, matchmakingUnclaimedKey, gameID); err != nil {
+		return fmt.Errorf("failed to mark game opened: %w", err)
+	}
+	return nil
+}
+
+// abandonUnclaimed abandons the matched games whose claim window ended
+// before either player opened them. It returns their IDs.
+func (s *MatchmakingService) abandonUnclaimed(ctx context.Context) ([]string, error) {
+	expired, err := s.queue.MembersWithScoreAtMost(ctx, matchmakingUnclaimedKey, float64(s.now().UnixNano()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan unclaimed games: %w", err)
+	}
+
+	abandoned := make([]string, 0, len(expired))
+	for _, gameID := range expired {
+		// Another instance, or a player opening the game, got there first
+		ok, err := s.queue.RemoveMember(ctx, matchmakingUnclaimedKey, gameID)
+		if err != nil {
+			return abandoned, fmt.Errorf("failed to remove unclaimed game: %w", err)
+		}
+		if !ok {
+			continue
+		}
+
+		if err := s.gameService.AbandonGame(ctx, gameID); err != nil {
+			return abandoned, err
+		}
+		abandoned = append(abandoned, gameID)
+	}
+	return abandoned, nil
+}
+
+// matchWaiting retries matches between the players in the queue, oldest
+// first, with the rating bands they accept by now. It returns how many
+// matches were made.
+func (s *MatchmakingService) matchWaiting(ctx context.Context) (int, error) {
+	queued, err := s.queuedEntries(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	now := s.now()
+	matched := make(map[string]bool)
+	made := 0
+	for i, entry := range queued {
+		if matched[entry.DeviceID] {
+			continue
+		}
+
+		// Earlier players already tried this one
+		waiting := make([]*models.MatchmakingEntry, 0, len(queued)-i-1)
+		for _, opponent := range queued[i+1:] {
+			if !matched[opponent.DeviceID] {
+				waiting = append(waiting, opponent)
+			}
+		}
+
+		for _, opponent := range rankOpponents(entry, waiting, now) {
+			if _, err := s.createMatch(ctx, entry, opponent); err != nil {
+				continue
+			}
+			matched[entry.DeviceID] = true
+			matched[opponent.DeviceID] = true
+			made++
+			break
+		}
+	}
+	return made, nil
+}
+
+// reapStale removes players who joined the queue more than the max wait ago
+// and leaves them a timeout result to find when polling the status. It
+// returns the removed device IDs.
+func (s *MatchmakingService) reapStale(ctx context.Context) ([]string, error) {
+	// Queue scores are join times, so stale players score at most the cutoff
+	cutoff := s.now().Add(-s.maxWait)
+	stale, err := s.queue.MembersWithScoreAtMost(ctx, matchmakingQueueKey, float64(cutoff.UnixNano()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan queue: %w", err)
+	}
+
+	removed := make([]string, 0, len(stale))
+	for _, deviceID := range stale {
+		// A player matched or leaving meanwhile is already gone and keeps
+		// their own result
+		ok, err := s.queue.RemoveMember(ctx, matchmakingQueueKey, deviceID)
+		if err != nil {
+			return removed, fmt.Errorf("failed to remove from queue: %w", err)
+		}
+		if !ok {
+			continue
+		}
+
+		s.entries.Del(ctx, matchmakingPlayerKey+deviceID)
+		s.storeMatchResult(ctx, deviceID, &QueueStatus{Status: StatusTimeout})
+		removed = append(removed, deviceID)
+	}
+	return removed, nil
 }
 
 // JoinQueue adds a player to the matchmaking queue.
@@ -44,7 +234,13 @@ func (s *MatchmakingService) JoinQueue(ctx context.Context, entry *models.Matchm
 		return nil, ErrAlreadyInQueue
 	}
 
-	entry.JoinedAt = time.Now()
+	// A timeout from an earlier wait no longer applies
+	if result, ok := s.getMatchResult(ctx, entry.DeviceID); ok && result.Status == StatusTimeout {
+		s.results.Del(ctx, matchmakingResultKey+entry.DeviceID)
+	}
+
+	entry.JoinedAt = s.now()
+	entry.Rating = s.playerRating(ctx, entry.DeviceID)
 
 	// Store player entry
 	entryJSON, err := json.Marshal(entry)
@@ -54,15 +250,12 @@ func (s *MatchmakingService) JoinQueue(ctx context.Context, entry *models.Matchm
 
 	// Add to sorted set (score is timestamp for FIFO ordering)
 	score := float64(entry.JoinedAt.UnixNano())
-	if err := s.redis.Client().ZAdd(ctx, matchmakingQueueKey, redis.Z{
-		Score:  score,
-		Member: entry.DeviceID,
-	}).Err(); err != nil {
+	if err := s.queue.AddMember(ctx, matchmakingQueueKey, entry.DeviceID, score); err != nil {
 		return nil, fmt.Errorf("failed to add to queue: %w", err)
 	}
 
 	// Store entry details
-	if err := s.redis.Client().Set(ctx, matchmakingPlayerKey+entry.DeviceID, entryJSON, matchmakingTTL).Err(); err != nil {
+	if err := s.entries.Set(ctx, matchmakingPlayerKey+entry.DeviceID, string(entryJSON), matchmakingTTL); err != nil {
 		return nil, fmt.Errorf("failed to store entry: %w", err)
 	}
 
@@ -84,12 +277,12 @@ func (s *MatchmakingService) JoinQueue(ctx context.Context, entry *models.Matchm
 // LeaveQueue removes a player from the matchmaking queue.
 func (s *MatchmakingService) LeaveQueue(ctx context.Context, deviceID string) error {
 	// Remove from sorted set
-	if err := s.redis.Client().ZRem(ctx, matchmakingQueueKey, deviceID).Err(); err != nil {
+	if _, err := s.queue.RemoveMember(ctx, matchmakingQueueKey, deviceID); err != nil {
 		return fmt.Errorf("failed to remove from queue: %w", err)
 	}
 
 	// Remove entry details
-	if err := s.redis.Client().Del(ctx, matchmakingPlayerKey+deviceID).Err(); err != nil {
+	if err := s.entries.Del(ctx, matchmakingPlayerKey+deviceID); err != nil {
 		return fmt.Errorf("failed to remove entry: %w", err)
 	}
 
@@ -98,13 +291,15 @@ func (s *MatchmakingService) LeaveQueue(ctx context.Context, deviceID string) er
 
 // GetStatus returns the current queue status for a player.
 func (s *MatchmakingService) GetStatus(ctx context.Context, deviceID string) (*QueueStatus, error) {
-	// Check if there's a match result
-	resultJSON, err := s.redis.Client().Get(ctx, matchmakingResultKey+deviceID).Bytes()
-	if err == nil {
-		var result QueueStatus
-		if err := json.Unmarshal(resultJSON, &result); err == nil {
-			return &result, nil
+	// Check if there's a match result still within its claim window
+	if result, ok := s.getMatchResult(ctx, deviceID); ok && result.ClaimExpiresAt != nil {
+		remaining := result.ClaimExpiresAt.Sub(s.now())
+		if remaining > 0 {
+			result.ClaimSecondsRemaining = int((remaining + time.Second - 1) / time.Second)
+			return result, nil
 		}
+		s.results.Del(ctx, matchmakingResultKey+deviceID)
+		return &QueueStatus{Status: StatusIdle}, nil
 	}
 
 	// Check if player is in queue
@@ -122,74 +317,152 @@ func (s *MatchmakingService) GetStatus(ctx context.Context, deviceID string) (*Q
 
 // GetPlayerEntry retrieves a player's matchmaking entry.
 func (s *MatchmakingService) GetPlayerEntry(ctx context.Context, deviceID string) (*models.MatchmakingEntry, error) {
-	entryJSON, err := s.redis.Client().Get(ctx, matchmakingPlayerKey+deviceID).Bytes()
+	entryJSON, err := s.entries.Get(ctx, matchmakingPlayerKey+deviceID)
 	if err != nil {
-		if errors.Is(err, redis.Nil) {
+		if errors.Is(err, repository.ErrKeyNotFound) {
 			return nil, ErrNotInQueue
 		}
 		return nil, fmt.Errorf("failed to get entry: %w", err)
 	}
 
 	var entry models.MatchmakingEntry
-	if err := json.Unmarshal(entryJSON, &entry); err != nil {
+	if err := json.Unmarshal([]byte(entryJSON), &entry); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal entry: %w", err)
 	}
 
 	return &entry, nil
 }
 
-// tryMatch attempts to find a match for the given player.
-func (s *MatchmakingService) tryMatch(ctx context.Context, entry *models.MatchmakingEntry) (*QueueStatus, error) {
-	// Get all players in queue (excluding current player)
-	members, err := s.redis.Client().ZRange(ctx, matchmakingQueueKey, 0, -1).Result()
+// playerRating returns the player's current rating, or the default rating
+// for players without a profile.
+func (s *MatchmakingService) playerRating(ctx context.Context, deviceID string) int {
+	user, err := s.gameService.userRepo.GetByID(ctx, deviceID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get queue: %w", err)
+		return models.DefaultRating
 	}
+	return currentRating(user)
+}
 
-	for _, memberID := range members {
-		if memberID == entry.DeviceID {
-			continue
-		}
+// tryMatch attempts to find a match for the given player. The closest rated
+// opponent whose rating band, and the player's own, covers the difference is
+// chosen; when there is none the player keeps waiting.
+func (s *MatchmakingService) tryMatch(ctx context.Context, entry *models.MatchmakingEntry) (*QueueStatus, error) {
+	queued, err := s.queuedEntries(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-		opponent, err := s.GetPlayerEntry(ctx, memberID)
+	for _, opponent := range rankOpponents(entry, queued, s.now()) {
+		game, err := s.createMatch(ctx, entry, opponent)
 		if err != nil {
 			continue
 		}
 
-		// Simple matching: just pair any two players
-		// In production, you might match by timeout preference, skill level, etc.
-		game, err := s.createMatch(ctx, entry, opponent)
+		return game, nil
+	}
+
+	return nil, ErrNoMatchFound
+}
+
+// queuedEntries returns the entries of the players in the queue, oldest
+// first.
+func (s *MatchmakingService) queuedEntries(ctx context.Context) ([]*models.MatchmakingEntry, error) {
+	members, err := s.queue.MembersWithScoreAtMost(ctx, matchmakingQueueKey, math.Inf(1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get queue: %w", err)
+	}
+
+	queued := make([]*models.MatchmakingEntry, 0, len(members))
+	for _, memberID := range members {
+		entry, err := s.GetPlayerEntry(ctx, memberID)
 		if err != nil {
 			continue
 		}
+		queued = append(queued, entry)
+	}
+	return queued, nil
+}
 
-		return game, nil
+// canMatch reports whether two queued players may be paired at the given
+// time.
+func canMatch(a, b *models.MatchmakingEntry, now time.Time) bool {
+	// Only pair players who queued with the same timeout preset and time
+	// control, so neither is surprised by a faster clock than they asked for
+	if !sameTimeControl(a, b) {
+		return false
 	}
 
-	return nil, ErrNoMatchFound
+	// Ranked and casual players are matched separately
+	if a.Ranked != b.Ranked {
+		return false
+	}
+
+	// Both players must accept the rating difference given how long they
+	// have waited and their speed preference
+	return withinRatingBand(a, b, now)
+}
+
+// ValidTimeControl reports whether a queued time control, base time and
+// increment can be played. Per-move games take neither a base time nor an
+// increment; an empty time control is per-move.
+func ValidTimeControl(mode models.TimeControl, baseTime, increment int) bool {
+	switch mode {
+	case "", models.TimeControlPerMove:
+		return baseTime == 0 && increment == 0
+	case models.TimeControlFischer, models.TimeControlBronstein:
+		return baseTime >= MinBaseTimeSeconds && baseTime <= MaxBaseTimeSeconds &&
+			increment >= 0 && increment <= MaxIncrementSeconds
+	}
+	return false
+}
+
+// sameTimeControl reports whether two queued players asked for the same
+// clock. An empty time control is per-move.
+func sameTimeControl(a, b *models.MatchmakingEntry) bool {
+	modeA, modeB := a.TimeControl, b.TimeControl
+	if modeA == "" {
+		modeA = models.TimeControlPerMove
+	}
+	if modeB == "" {
+		modeB = models.TimeControlPerMove
+	}
+	return a.TurnTimeout == b.TurnTimeout && modeA == modeB &&
+		a.BaseTime == b.BaseTime && a.Increment == b.Increment
 }
 
 // createMatch creates a game between two matched players.
 func (s *MatchmakingService) createMatch(ctx context.Context, player1, player2 *models.MatchmakingEntry) (*QueueStatus, error) {
-	// Randomly assign colors
-	var redPlayer, blackPlayer *models.MatchmakingEntry
-	if rand.Intn(2) == 0 {
-		redPlayer = player1
-		blackPlayer = player2
-	} else {
-		redPlayer = player2
-		blackPlayer = player1
+	// Players from different timeout pools must never share a game; the
+	// game takes its time control from player1 below
+	if !sameTimeControl(player1, player2) {
+		return nil, ErrTimeoutMismatch
+	}
+
+	// Join and the Run loop both match players, so each player is taken
+	// off the queue before a game is created for them
+	if err := s.claimPlayers(ctx, player1, player2); err != nil {
+		return nil, err
 	}
 
-	// Use the shorter timeout preference
-	timeout := player1.TurnTimeout
-	if player2.TurnTimeout < timeout && player2.TurnTimeout > 0 {
-		timeout = player2.TurnTimeout
+	redPlayer, blackPlayer := assignColors(player1, player2)
+
+	// Both players queued with the same preset and ranked setting; the game
+	// is only listed publicly, or given move assistance, if both players
+	// allow it
+	settings := GameSettings{
+		TurnTimeout: player1.TurnTimeout,
+		TimeControl: player1.TimeControl,
+		Increment:   player1.Increment,
+		BaseTime:    player1.BaseTime,
+		IsPublic:    player1.Public && player2.Public,
+		Ranked:      player1.Ranked,
+		Assist:      player1.Assist && player2.Assist,
 	}
 
 	// Create game
-	game, err := s.gameService.CreateGame(ctx, redPlayer.DeviceID, blackPlayer.DeviceID, timeout)
+	game, err := s.gameService.CreateGame(ctx, redPlayer.DeviceID, blackPlayer.DeviceID, settings)
 	if err != nil {
+		s.requeue(ctx, player1, player2)
 		return nil, fmt.Errorf("failed to create game: %w", err)
 	}
 
@@ -222,20 +495,107 @@ func (s *MatchmakingService) createMatch(ctx context.Context, player1, player2 *
 	}
 
 	// Store results
-	result1JSON, _ := json.Marshal(result1)
-	result2JSON, _ := json.Marshal(result2)
-	s.redis.Client().Set(ctx, matchmakingResultKey+player1.DeviceID, result1JSON, matchmakingTTL)
-	s.redis.Client().Set(ctx, matchmakingResultKey+player2.DeviceID, result2JSON, matchmakingTTL)
+	s.storeMatchResult(ctx, player1.DeviceID, result1)
+	s.storeMatchResult(ctx, player2.DeviceID, result2)
+
+	// The game is abandoned if nobody opens it before the results expire
+	if err := s.queue.AddMember(ctx, matchmakingUnclaimedKey, game.ID, float64(result1.ClaimExpiresAt.UnixNano())); err != nil {
+		log.Warn().Err(err).Str("game_id", game.ID).Msg("Failed to track unclaimed game")
+	}
 
 	return result1, nil
 }
 
+// claimPlayers takes the players off the queue. If one of them is already
+// gone, e.g. matched meanwhile, the players taken so far are put back and
+// ErrNoMatchFound is returned.
+func (s *MatchmakingService) claimPlayers(ctx context.Context, players ...*models.MatchmakingEntry) error {
+	for i, player := range players {
+		ok, err := s.queue.RemoveMember(ctx, matchmakingQueueKey, player.DeviceID)
+		if err == nil && ok {
+			continue
+		}
+		s.requeue(ctx, players[:i]...)
+		if err != nil {
+			return fmt.Errorf("failed to claim player: %w", err)
+		}
+		return ErrNoMatchFound
+	}
+	return nil
+}
+
+// requeue puts claimed players back in the queue in their original places.
+func (s *MatchmakingService) requeue(ctx context.Context, players ...*models.MatchmakingEntry) {
+	for _, player := range players {
+		if err := s.queue.AddMember(ctx, matchmakingQueueKey, player.DeviceID, float64(player.JoinedAt.UnixNano())); err != nil {
+			log.Warn().Err(err).Str("device_id", player.DeviceID).Msg("Failed to put player back in the matchmaking queue")
+		}
+	}
+}
+
+// assignColors picks the red and black players of a match. A color
+// preference is honored unless both players asked for the same color, in
+// which case, as when neither has a preference, colors are assigned at
+// random.
+func assignColors(player1, player2 *models.MatchmakingEntry) (red, black *models.MatchmakingEntry) {
+	pref1, pref2 := player1.PreferredColor, player2.PreferredColor
+	switch {
+	case pref1 == pref2:
+	case pref1 == models.PlayerColorRed, pref2 == models.PlayerColorBlack:
+		return player1, player2
+	default:
+		return player2, player1
+	}
+
+	if rand.Intn(2) == 0 {
+		return player1, player2
+	}
+	return player2, player1
+}
+
+// storeMatchResult keeps a player's match or timeout result for the claim
+// window. The game does not start until both players connect, so it stays
+// pending while the result can still be claimed.
+func (s *MatchmakingService) storeMatchResult(ctx context.Context, deviceID string, result *QueueStatus) {
+	expiresAt := s.now().Add(s.claimWindow)
+	result.ClaimExpiresAt = &expiresAt
+	result.ClaimSecondsRemaining = int(s.claimWindow / time.Second)
+
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+	s.results.Set(ctx, matchmakingResultKey+deviceID, string(resultJSON), s.claimWindow)
+}
+
+// getMatchResult returns a player's stored match result, if any.
+func (s *MatchmakingService) getMatchResult(ctx context.Context, deviceID string) (*QueueStatus, bool) {
+	resultJSON, err := s.results.Get(ctx, matchmakingResultKey+deviceID)
+	if err != nil {
+		return nil, false
+	}
+	var result QueueStatus
+	if err := json.Unmarshal([]byte(resultJSON), &result); err != nil {
+		return nil, false
+	}
+	return &result, true
+}
+
+// QueueSize returns the number of players waiting in the matchmaking queue.
+func (s *MatchmakingService) QueueSize(ctx context.Context) (int, error) {
+	size, err := s.queue.Size(ctx, matchmakingQueueKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get queue size: %w", err)
+	}
+	return size, nil
+}
+
 func (s *MatchmakingService) getQueuePosition(ctx context.Context, deviceID string) (int, error) {
-	rank, err := s.redis.Client().ZRank(ctx, matchmakingQueueKey, deviceID).Result()
+	rank, err := s.queue.Rank(ctx, matchmakingQueueKey, deviceID)
 	if err != nil {
 		return 0, err
 	}
-	return int(rank) + 1, nil
+	return rank + 1, nil
 }
 
 func estimateWaitTime(position int) int {
@@ -252,6 +612,10 @@ type QueueStatus struct {
 	OpponentID          string            `json:"opponent_id,omitempty"`
 	OpponentName        string            `json:"opponent_name,omitempty"`
 	YourColor           models.PlayerColor `json:"your_color,omitempty"`
+	// ClaimExpiresAt is when a match result stops being returned to a player
+	// who has not opened the game.
+	ClaimExpiresAt        *time.Time `json:"claim_expires_at,omitempty"`
+	ClaimSecondsRemaining int        `json:"claim_seconds_remaining,omitempty"`
 }
 
 // MatchStatus represents the status of matchmaking.
@@ -262,11 +626,15 @@ const (
 	StatusWaiting MatchStatus = "waiting"
 	StatusMatched MatchStatus = "matched"
 	StatusLeft    MatchStatus = "left"
+	// StatusTimeout means the player waited longer than the max wait and
+	// was removed from the queue.
+	StatusTimeout MatchStatus = "timeout"
 )
 
 // Matchmaking errors
 var (
-	ErrAlreadyInQueue = errors.New("player is already in queue")
-	ErrNotInQueue     = errors.New("player is not in queue")
-	ErrNoMatchFound   = errors.New("no match found")
+	ErrAlreadyInQueue  = errors.New("player is already in queue")
+	ErrNotInQueue      = errors.New("player is not in queue")
+	ErrNoMatchFound    = errors.New("no match found")
+	ErrTimeoutMismatch = errors.New("players queued with different time controls")
 )
@@ -0,0 +1,485 @@
+// Package services provides unit tests for the matchmaking service.
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
+
+// newTestMatchmakingService creates a matchmaking service that keeps match
+// results in memory, with a clock the test can move.
+func newTestMatchmakingService(claimWindow time.Duration) (*MatchmakingService, *mockKeyValueStore, *time.Time) {
+	store := newMockKeyValueStore()
+	now := time.Now()
+	service := &MatchmakingService{
+		results:     store,
+		claimWindow: claimWindow,
+		now:         func() time.Time { return now },
+	}
+	return service, store, &now
+}
+
+func TestMatchmakingService_MatchResultPersistsWithinClaimWindow(t *testing.T) {
+	service, store, now := newTestMatchmakingService(time.Minute)
+	ctx := context.Background()
+
+	service.storeMatchResult(ctx, "player-1", &QueueStatus{
+		Status:    StatusMatched,
+		GameID:    "game-1",
+		YourColor: models.PlayerColorRed,
+	})
+	if ttl := store.ttls[matchmakingResultKey+"player-1"]; ttl != time.Minute {
+		t.Errorf("Expected result TTL to match the claim window, got %v", ttl)
+	}
+
+	// The app was closed; the player polls again 40 seconds later
+	*now = now.Add(40 * time.Second)
+	status, err := service.GetStatus(ctx, "player-1")
+	if err != nil {
+		t.Fatalf("GetStatus failed: %v", err)
+	}
+	if status.Status != StatusMatched || status.GameID != "game-1" {
+		t.Fatalf("Expected the match to persist, got %+v", status)
+	}
+	if status.ClaimSecondsRemaining != 20 {
+		t.Errorf("Expected 20 seconds left to claim, got %d", status.ClaimSecondsRemaining)
+	}
+}
+
+func TestMatchmakingService_MatchResultExpiresAfterClaimWindow(t *testing.T) {
+	service, store, now := newTestMatchmakingService(time.Minute)
+	ctx := context.Background()
+
+	service.storeMatchResult(ctx, "player-1", &QueueStatus{Status: StatusMatched, GameID: "game-1"})
+
+	*now = now.Add(time.Minute + time.Second)
+	status, err := service.GetStatus(ctx, "player-1")
+	if err != nil {
+		t.Fatalf("GetStatus failed: %v", err)
+	}
+	if status.Status != StatusIdle {
+		t.Errorf("Expected idle after the claim window, got %s", status.Status)
+	}
+	if _, ok := store.values[matchmakingResultKey+"player-1"]; ok {
+		t.Error("Expected the expired result to be removed")
+	}
+}
+
+// queueTestPlayer adds a player who joined at the given time to the queue.
+func queueTestPlayer(queue *mockSortedSetStore, entries *mockKeyValueStore, deviceID string, joinedAt time.Time) {
+	queue.add(matchmakingQueueKey, deviceID, float64(joinedAt.UnixNano()))
+	entries.Set(context.Background(), matchmakingPlayerKey+deviceID, `{"device_id":"`+deviceID+`"}`, matchmakingTTL)
+}
+
+func TestMatchmakingService_ReapStaleRemovesExpiredEntries(t *testing.T) {
+	service, store, now := newTestMatchmakingService(time.Minute)
+	queue := newMockSortedSetStore()
+	service.queue = queue
+	service.entries = store
+	service.SetMaxWait(2 * time.Minute)
+	ctx := context.Background()
+
+	queueTestPlayer(queue, store, "crashed", now.Add(-3*time.Minute))
+	queueTestPlayer(queue, store, "fresh", now.Add(-30*time.Second))
+
+	removed, err := service.reapStale(ctx)
+	if err != nil {
+		t.Fatalf("reapStale failed: %v", err)
+	}
+	if len(removed) != 1 || removed[0] != "crashed" {
+		t.Fatalf("Expected only the stale player to be removed, got %v", removed)
+	}
+	if queue.has(matchmakingQueueKey, "crashed") {
+		t.Error("Expected the stale player to leave the queue")
+	}
+	if _, ok := store.values[matchmakingPlayerKey+"crashed"]; ok {
+		t.Error("Expected the stale entry details to be removed")
+	}
+	if !queue.has(matchmakingQueueKey, "fresh") {
+		t.Error("Expected the fresh player to stay in the queue")
+	}
+	if _, ok := store.values[matchmakingPlayerKey+"fresh"]; !ok {
+		t.Error("Expected the fresh entry details to be kept")
+	}
+
+	status, err := service.GetStatus(ctx, "crashed")
+	if err != nil {
+		t.Fatalf("GetStatus failed: %v", err)
+	}
+	if status.Status != StatusTimeout {
+		t.Errorf("Expected a timeout status, got %s", status.Status)
+	}
+
+	// Once the fresh player has waited too long they are removed as well
+	*now = now.Add(2 * time.Minute)
+	removed, err = service.reapStale(ctx)
+	if err != nil {
+		t.Fatalf("reapStale failed: %v", err)
+	}
+	if len(removed) != 1 || removed[0] != "fresh" {
+		t.Errorf("Expected the fresh player to time out, got %v", removed)
+	}
+}
+
+func TestMatchmakingService_ReapStaleSkipsPlayersAlreadyGone(t *testing.T) {
+	service, store, now := newTestMatchmakingService(time.Minute)
+	queue := &racingSortedSetStore{mockSortedSetStore: newMockSortedSetStore()}
+	service.queue = queue
+	service.entries = store
+	ctx := context.Background()
+
+	queueTestPlayer(queue.mockSortedSetStore, store, "matched", now.Add(-time.Hour))
+	service.storeMatchResult(ctx, "matched", &QueueStatus{Status: StatusMatched, GameID: "game-1"})
+
+	removed, err := service.reapStale(ctx)
+	if err != nil {
+		t.Fatalf("reapStale failed: %v", err)
+	}
+	if len(removed) != 0 {
+		t.Errorf("Expected no removals, got %v", removed)
+	}
+	status, err := service.GetStatus(ctx, "matched")
+	if err != nil {
+		t.Fatalf("GetStatus failed: %v", err)
+	}
+	if status.Status != StatusMatched {
+		t.Errorf("Expected the match result to be kept, got %s", status.Status)
+	}
+}
+
+// racingSortedSetStore drops each member between the scan and its removal,
+// as if the player was matched in the meantime.
+type racingSortedSetStore struct {
+	*mockSortedSetStore
+}
+
+func (r *racingSortedSetStore) MembersWithScoreAtMost(ctx context.Context, key string, max float64) ([]string, error) {
+	members, err := r.mockSortedSetStore.MembersWithScoreAtMost(ctx, key, max)
+	for _, member := range members {
+		r.mockSortedSetStore.RemoveMember(ctx, key, member)
+	}
+	return members, err
+}
+
+func TestMatchmakingService_RunStopsOnCancel(t *testing.T) {
+	service, store, _ := newTestMatchmakingService(time.Minute)
+	service.queue = newMockSortedSetStore()
+	service.entries = store
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		service.Run(ctx)
+		close(done)
+	}()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected Run to return after the context is cancelled")
+	}
+}
+
+func TestMatchmakingService_MatchWaiting_AppliesWidenedBand(t *testing.T) {
+	gameService, _ := newTestGameServiceWithPlayers()
+	service, store, now := newTestMatchmakingService(time.Minute)
+	queue := newMockSortedSetStore()
+	service.queue = queue
+	service.entries = store
+	service.gameService = gameService
+	ctx := context.Background()
+
+	// 150 points apart: outside the initial band, inside it 15 seconds later
+	for _, entry := range []*models.MatchmakingEntry{
+		{DeviceID: "red-player", Rating: 1200, TurnTimeout: 60, JoinedAt: *now},
+		{DeviceID: "black-player", Rating: 1350, TurnTimeout: 60, JoinedAt: now.Add(time.Second)},
+	} {
+		data, _ := json.Marshal(entry)
+		queue.add(matchmakingQueueKey, entry.DeviceID, float64(entry.JoinedAt.UnixNano()))
+		store.Set(ctx, matchmakingPlayerKey+entry.DeviceID, string(data), matchmakingTTL)
+	}
+
+	if made, err := service.matchWaiting(ctx); err != nil || made != 0 {
+		t.Fatalf("Expected no match right after joining, got %d (%v)", made, err)
+	}
+
+	*now = now.Add(16 * time.Second)
+	if made, err := service.matchWaiting(ctx); err != nil || made != 1 {
+		t.Fatalf("Expected a match once the bands widened, got %d (%v)", made, err)
+	}
+	if queue.has(matchmakingQueueKey, "red-player") || queue.has(matchmakingQueueKey, "black-player") {
+		t.Error("Expected both players to leave the queue")
+	}
+	status, err := service.GetStatus(ctx, "black-player")
+	if err != nil {
+		t.Fatalf("GetStatus failed: %v", err)
+	}
+	if status.Status != StatusMatched || status.OpponentID != "red-player" {
+		t.Errorf("Expected black-player to be matched with red-player, got %+v", status)
+	}
+}
+
+func TestMatchmakingService_CreateMatch_SkipsClaimedPlayer(t *testing.T) {
+	gameService, _ := newTestGameServiceWithPlayers()
+	service, _, now := newTestMatchmakingService(time.Minute)
+	queue := newMockSortedSetStore()
+	service.queue = queue
+	service.gameService = gameService
+
+	// black-player was matched elsewhere between the scan and the claim
+	player1 := &models.MatchmakingEntry{DeviceID: "red-player", TurnTimeout: 60, JoinedAt: *now}
+	player2 := &models.MatchmakingEntry{DeviceID: "black-player", TurnTimeout: 60}
+	queue.add(matchmakingQueueKey, player1.DeviceID, float64(now.UnixNano()))
+
+	if _, err := service.createMatch(context.Background(), player1, player2); !errors.Is(err, ErrNoMatchFound) {
+		t.Fatalf("Expected ErrNoMatchFound, got %v", err)
+	}
+	if games := gameService.gameRepo.(*mockGameRepository).games; len(games) != 0 {
+		t.Errorf("Expected no game to be created, got %d", len(games))
+	}
+	if !queue.has(matchmakingQueueKey, "red-player") {
+		t.Error("Expected red-player to be put back in the queue")
+	}
+}
+
+func TestCanMatch_RequiresSameTimeoutPreset(t *testing.T) {
+	now := time.Now()
+	waiting := &models.MatchmakingEntry{DeviceID: "red-player", Rating: 1200, TurnTimeout: 60, JoinedAt: now.Add(-time.Second)}
+	joining := &models.MatchmakingEntry{DeviceID: "black-player", Rating: 1200, TurnTimeout: 300, JoinedAt: now}
+
+	if canMatch(joining, waiting, now) {
+		t.Error("Players with different timeout presets should not be matched")
+	}
+
+	joining.TurnTimeout = 60
+	if !canMatch(joining, waiting, now) {
+		t.Error("Players with the same preset and rating should be matched")
+	}
+
+	joining.Ranked = true
+	if canMatch(joining, waiting, now) {
+		t.Error("Ranked and casual players should not be matched")
+	}
+}
+
+func TestCanMatch_RequiresSameTimeControl(t *testing.T) {
+	now := time.Now()
+	waiting := &models.MatchmakingEntry{DeviceID: "red-player", Rating: 1200, TurnTimeout: 600,
+		TimeControl: models.TimeControlFischer, BaseTime: 600, Increment: 5, JoinedAt: now.Add(-time.Second)}
+	joining := &models.MatchmakingEntry{DeviceID: "black-player", Rating: 1200, TurnTimeout: 600, JoinedAt: now}
+
+	if canMatch(joining, waiting, now) {
+		t.Error("Per-move and Fischer players should not be matched")
+	}
+
+	joining.TimeControl = models.TimeControlFischer
+	joining.BaseTime = 600
+	joining.Increment = 10
+	if canMatch(joining, waiting, now) {
+		t.Error("Players with different increments should not be matched")
+	}
+
+	joining.BaseTime = 300
+	joining.Increment = 5
+	if canMatch(joining, waiting, now) {
+		t.Error("Players with different base times should not be matched")
+	}
+
+	joining.BaseTime = 600
+	if !canMatch(joining, waiting, now) {
+		t.Error("Players with the same time control should be matched")
+	}
+}
+
+func TestMatchmakingService_CreateMatch_RejectsTimeoutMismatch(t *testing.T) {
+	gameService, _ := newTestGameServiceWithPlayers()
+	service := &MatchmakingService{gameService: gameService}
+
+	player1 := &models.MatchmakingEntry{DeviceID: "red-player", TurnTimeout: 60}
+	player2 := &models.MatchmakingEntry{DeviceID: "black-player", TurnTimeout: 300}
+
+	if _, err := service.createMatch(context.Background(), player1, player2); !errors.Is(err, ErrTimeoutMismatch) {
+		t.Fatalf("Expected ErrTimeoutMismatch, got %v", err)
+	}
+	if games := gameService.gameRepo.(*mockGameRepository).games; len(games) != 0 {
+		t.Errorf("Expected no game to be created, got %d", len(games))
+	}
+}
+
+func TestAssignColors_OneSidedPreference(t *testing.T) {
+	for _, preferred := range []models.PlayerColor{models.PlayerColorRed, models.PlayerColorBlack} {
+		for i := 0; i < 20; i++ {
+			picky := &models.MatchmakingEntry{DeviceID: "picky", PreferredColor: preferred}
+			easygoing := &models.MatchmakingEntry{DeviceID: "easygoing"}
+
+			// Whichever player joined first, the preference is honored
+			for _, pair := range [][2]*models.MatchmakingEntry{{picky, easygoing}, {easygoing, picky}} {
+				red, black := assignColors(pair[0], pair[1])
+				got := models.PlayerColorBlack
+				if red == picky {
+					got = models.PlayerColorRed
+				}
+				if got != preferred || (red != easygoing && black != easygoing) {
+					t.Fatalf("Expected picky to play %s, got red %s and black %s", preferred, red.DeviceID, black.DeviceID)
+				}
+			}
+		}
+	}
+}
+
+func TestAssignColors_CompatiblePreferences(t *testing.T) {
+	wantsRed := &models.MatchmakingEntry{DeviceID: "wants-red", PreferredColor: models.PlayerColorRed}
+	wantsBlack := &models.MatchmakingEntry{DeviceID: "wants-black", PreferredColor: models.PlayerColorBlack}
+
+	for i := 0; i < 20; i++ {
+		if red, black := assignColors(wantsRed, wantsBlack); red != wantsRed || black != wantsBlack {
+			t.Fatalf("Expected both preferences to be satisfied, got red %s and black %s", red.DeviceID, black.DeviceID)
+		}
+		if red, black := assignColors(wantsBlack, wantsRed); red != wantsRed || black != wantsBlack {
+			t.Fatalf("Expected both preferences to be satisfied, got red %s and black %s", red.DeviceID, black.DeviceID)
+		}
+	}
+}
+
+func TestAssignColors_ConflictingPreferencesAreRandom(t *testing.T) {
+	player1 := &models.MatchmakingEntry{DeviceID: "player-1", PreferredColor: models.PlayerColorRed}
+	player2 := &models.MatchmakingEntry{DeviceID: "player-2", PreferredColor: models.PlayerColorRed}
+
+	redCounts := map[string]int{}
+	for i := 0; i < 200; i++ {
+		red, black := assignColors(player1, player2)
+		if red == black {
+			t.Fatalf("Expected two different players, got %s twice", red.DeviceID)
+		}
+		redCounts[red.DeviceID]++
+	}
+	if redCounts["player-1"] == 0 || redCounts["player-2"] == 0 {
+		t.Errorf("Expected either player to get red, got %v", redCounts)
+	}
+}
+
+func TestMatchmakingService_PlayerRating(t *testing.T) {
+	gameService, userRepo := newTestGameServiceWithPlayers()
+	userRepo.users["red-player"].Rating = 1480
+	service := &MatchmakingService{gameService: gameService, now: time.Now}
+	ctx := context.Background()
+
+	if rating := service.playerRating(ctx, "red-player"); rating != 1480 {
+		t.Errorf("Expected the stored rating 1480, got %d", rating)
+	}
+	// Players stored before ratings existed, and unknown players, queue at the default
+	if rating := service.playerRating(ctx, "black-player"); rating != models.DefaultRating {
+		t.Errorf("Expected the default rating for an unrated player, got %d", rating)
+	}
+	if rating := service.playerRating(ctx, "stranger"); rating != models.DefaultRating {
+		t.Errorf("Expected the default rating for an unknown player, got %d", rating)
+	}
+}
+
+func TestMatchmakingService_JoinQueue_UsesProfileRatings(t *testing.T) {
+	gameService, userRepo := newTestGameServiceWithPlayers()
+	userRepo.users["red-player"].Rating = 1200
+	userRepo.users["black-player"].Rating = 1600
+	service, store, now := newTestMatchmakingService(time.Minute)
+	service.queue = newMockSortedSetStore()
+	service.entries = store
+	service.gameService = gameService
+	ctx := context.Background()
+
+	if _, err := service.JoinQueue(ctx, &models.MatchmakingEntry{DeviceID: "red-player", TurnTimeout: 60}); err != nil {
+		t.Fatalf("JoinQueue failed: %v", err)
+	}
+	*now = now.Add(time.Second)
+	// A rating sent by the client is replaced by the stored one
+	status, err := service.JoinQueue(ctx, &models.MatchmakingEntry{DeviceID: "black-player", TurnTimeout: 60, Rating: 1200})
+	if err != nil {
+		t.Fatalf("JoinQueue failed: %v", err)
+	}
+	if status.Status != StatusWaiting || status.Position != 2 {
+		t.Fatalf("Expected players 400 points apart to keep waiting, got %+v", status)
+	}
+
+	entry, err := service.GetPlayerEntry(ctx, "black-player")
+	if err != nil {
+		t.Fatalf("GetPlayerEntry failed: %v", err)
+	}
+	if entry.Rating != 1600 {
+		t.Errorf("Expected the queued entry to carry the stored rating 1600, got %d", entry.Rating)
+	}
+}
+
+// matchTestPlayers matches red-player and black-player through createMatch
+// and returns the game created for them.
+func matchTestPlayers(t *testing.T, service *MatchmakingService, now time.Time) *models.Game {
+	t.Helper()
+	queue := service.queue.(*mockSortedSetStore)
+	player1 := &models.MatchmakingEntry{DeviceID: "red-player", TurnTimeout: 60, JoinedAt: now}
+	player2 := &models.MatchmakingEntry{DeviceID: "black-player", TurnTimeout: 60, JoinedAt: now}
+	queue.add(matchmakingQueueKey, player1.DeviceID, float64(now.UnixNano()))
+	queue.add(matchmakingQueueKey, player2.DeviceID, float64(now.UnixNano()))
+
+	result, err := service.createMatch(context.Background(), player1, player2)
+	if err != nil {
+		t.Fatalf("createMatch failed: %v", err)
+	}
+	game, err := service.gameService.GetGame(context.Background(), result.GameID)
+	if err != nil {
+		t.Fatalf("GetGame failed: %v", err)
+	}
+	return game
+}
+
+func TestMatchmakingService_AbandonUnclaimed_AbandonsUnopenedGame(t *testing.T) {
+	gameService, _ := newTestGameServiceWithPlayers()
+	service, store, now := newTestMatchmakingService(time.Minute)
+	service.queue = newMockSortedSetStore()
+	service.entries = store
+	service.gameService = gameService
+	ctx := context.Background()
+
+	game := matchTestPlayers(t, service, *now)
+
+	if abandoned, err := service.abandonUnclaimed(ctx); err != nil || len(abandoned) != 0 {
+		t.Fatalf("Expected nothing abandoned within the claim window, got %v (%v)", abandoned, err)
+	}
+
+	*now = now.Add(time.Minute + time.Second)
+	abandoned, err := service.abandonUnclaimed(ctx)
+	if err != nil {
+		t.Fatalf("abandonUnclaimed failed: %v", err)
+	}
+	if len(abandoned) != 1 || abandoned[0] != game.ID {
+		t.Fatalf("Expected the unopened game to be abandoned, got %v", abandoned)
+	}
+	if game.Status != models.GameStatusAbandoned || game.WinnerID != nil {
+		t.Errorf("Expected an abandoned game without a winner, got %s", game.Status)
+	}
+}
+
+func TestMatchmakingService_AbandonUnclaimed_KeepsOpenedGame(t *testing.T) {
+	gameService, _ := newTestGameServiceWithPlayers()
+	service, store, now := newTestMatchmakingService(time.Minute)
+	service.queue = newMockSortedSetStore()
+	service.entries = store
+	service.gameService = gameService
+	ctx := context.Background()
+
+	game := matchTestPlayers(t, service, *now)
+	if err := service.MarkOpened(ctx, game.ID); err != nil {
+		t.Fatalf("MarkOpened failed: %v", err)
+	}
+
+	*now = now.Add(time.Minute + time.Second)
+	if abandoned, err := service.abandonUnclaimed(ctx); err != nil || len(abandoned) != 0 {
+		t.Fatalf("Expected the opened game to be kept, got %v (%v)", abandoned, err)
+	}
+	if game.Status != models.GameStatusActive {
+		t.Errorf("Expected the opened game to stay active, got %s", game.Status)
+	}
+}
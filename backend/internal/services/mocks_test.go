@@ -0,0 +1,340 @@
+// Package services provides shared test doubles for the service tests.
+package services
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+	"github.com/xiangqi/chinese-chess-backend/internal/repository"
+)
+
+// mockGameRepository is a mock implementation of the game repository for testing.
+type mockGameRepository struct {
+	mu        sync.Mutex
+	games     map[string]*models.Game
+	createErr error
+	// moveRepo receives the moves stored by CreateWithMoves, if set.
+	moveRepo *mockMoveRepository
+}
+
+func newMockGameRepository() *mockGameRepository {
+	return &mockGameRepository{
+		games: make(map[string]*models.Game),
+	}
+}
+
+func (m *mockGameRepository) Create(ctx context.Context, game *models.Game) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.createErr != nil {
+		return m.createErr
+	}
+	game.CreatedAt = time.Now()
+	m.games[game.ID] = game
+	return nil
+}
+
+func (m *mockGameRepository) CreateWithMoves(ctx context.Context, game *models.Game, moves []*models.Move) error {
+	if err := m.Create(ctx, game); err != nil {
+		return err
+	}
+	if m.moveRepo != nil {
+		for _, move := range moves {
+			m.moveRepo.Create(ctx, move)
+		}
+	}
+	return nil
+}
+
+func (m *mockGameRepository) GetByID(ctx context.Context, id string) (*models.Game, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	game, ok := m.games[id]
+	if !ok {
+		return nil, repository.ErrGameNotFound
+	}
+	return game, nil
+}
+
+func (m *mockGameRepository) Update(ctx context.Context, game *models.Game) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.games[game.ID]; !ok {
+		return repository.ErrGameNotFound
+	}
+	m.games[game.ID] = game
+	return nil
+}
+
+func (m *mockGameRepository) GetHistoryByPlayer(ctx context.Context, playerID string, limit, offset int) ([]*models.Game, error) {
+	var games []*models.Game
+	for _, game := range m.byPlayer(playerID) {
+		if game.Status != models.GameStatusActive {
+			games = append(games, game)
+		}
+	}
+	if offset >= len(games) {
+		return []*models.Game{}, nil
+	}
+	games = games[offset:]
+	if len(games) > limit {
+		games = games[:limit]
+	}
+	return games, nil
+}
+
+func (m *mockGameRepository) CountByPlayer(ctx context.Context, playerID string) (int, error) {
+	count := 0
+	for _, game := range m.byPlayer(playerID) {
+		if game.Status != models.GameStatusActive {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *mockGameRepository) GetActiveByPlayer(ctx context.Context, playerID string) ([]*models.Game, error) {
+	var games []*models.Game
+	for _, game := range m.byPlayer(playerID) {
+		if game.Status == models.GameStatusActive {
+			games = append(games, game)
+		}
+	}
+	return games, nil
+}
+
+func (m *mockGameRepository) GetLivePublic(ctx context.Context, limit, offset int) ([]*models.Game, error) {
+	games := m.livePublic()
+	if offset >= len(games) {
+		return []*models.Game{}, nil
+	}
+	games = games[offset:]
+	if len(games) > limit {
+		games = games[:limit]
+	}
+	return games, nil
+}
+
+func (m *mockGameRepository) CountLivePublic(ctx context.Context) (int, error) {
+	return len(m.livePublic()), nil
+}
+
+// livePublic returns active public games, newest first.
+func (m *mockGameRepository) livePublic() []*models.Game {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var games []*models.Game
+	for _, game := range m.games {
+		if game.Status == models.GameStatusActive && game.IsPublic {
+			games = append(games, game)
+		}
+	}
+	sort.Slice(games, func(i, j int) bool {
+		return games[i].CreatedAt.After(games[j].CreatedAt)
+	})
+	return games
+}
+
+// byPlayer returns the player's games, newest first.
+func (m *mockGameRepository) byPlayer(playerID string) []*models.Game {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var games []*models.Game
+	for _, game := range m.games {
+		if game.RedPlayerID == playerID || game.BlackPlayerID == playerID {
+			games = append(games, game)
+		}
+	}
+	sort.Slice(games, func(i, j int) bool {
+		return games[i].CreatedAt.After(games[j].CreatedAt)
+	})
+	return games
+}
+
+// mockMoveRepository is a mock implementation of the move repository for testing.
+type mockMoveRepository struct {
+	mu    sync.Mutex
+	moves map[string][]*models.Move
+}
+
+func newMockMoveRepository() *mockMoveRepository {
+	return &mockMoveRepository{
+		moves: make(map[string][]*models.Move),
+	}
+}
+
+func (m *mockMoveRepository) Create(ctx context.Context, move *models.Move) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.moves[move.GameID] = append(m.moves[move.GameID], move)
+	return nil
+}
+
+func (m *mockMoveRepository) GetByGameID(ctx context.Context, gameID string) ([]*models.Move, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	moves := make([]*models.Move, len(m.moves[gameID]))
+	copy(moves, m.moves[gameID])
+	return moves, nil
+}
+
+func (m *mockMoveRepository) DeleteAfterMoveNumber(ctx context.Context, gameID string, moveNumber int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var kept []*models.Move
+	for _, move := range m.moves[gameID] {
+		if move.MoveNumber <= moveNumber {
+			kept = append(kept, move)
+		}
+	}
+	m.moves[gameID] = kept
+	return nil
+}
+
+// mockKeyValueStore is an in-memory implementation of KeyValueStore for testing.
+// TTLs are recorded but never expire.
+type mockKeyValueStore struct {
+	mu     sync.Mutex
+	values map[string]string
+	ttls   map[string]time.Duration
+	writes map[string]int
+}
+
+func newMockKeyValueStore() *mockKeyValueStore {
+	return &mockKeyValueStore{
+		values: make(map[string]string),
+		ttls:   make(map[string]time.Duration),
+		writes: make(map[string]int),
+	}
+}
+
+// writeCount returns how many times a key has been written.
+func (m *mockKeyValueStore) writeCount(key string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.writes[key]
+}
+
+func (m *mockKeyValueStore) Get(ctx context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value, ok := m.values[key]
+	if !ok {
+		return "", repository.ErrKeyNotFound
+	}
+	return value, nil
+}
+
+func (m *mockKeyValueStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = value
+	m.ttls[key] = ttl
+	m.writes[key]++
+	return nil
+}
+
+func (m *mockKeyValueStore) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.values[key]; ok {
+		return false, nil
+	}
+	m.values[key] = value
+	m.ttls[key] = ttl
+	m.writes[key]++
+	return true, nil
+}
+
+func (m *mockKeyValueStore) Del(ctx context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range keys {
+		delete(m.values, key)
+		delete(m.ttls, key)
+	}
+	return nil
+}
+
+// mockSortedSetStore is an in-memory sorted set store for testing.
+type mockSortedSetStore struct {
+	mu   sync.Mutex
+	sets map[string]map[string]float64
+}
+
+func newMockSortedSetStore() *mockSortedSetStore {
+	return &mockSortedSetStore{sets: make(map[string]map[string]float64)}
+}
+
+// add stores member in the sorted set at key with the given score.
+func (m *mockSortedSetStore) add(key, member string, score float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.sets[key] == nil {
+		m.sets[key] = make(map[string]float64)
+	}
+	m.sets[key][member] = score
+}
+
+// has reports whether member is in the sorted set at key.
+func (m *mockSortedSetStore) has(key, member string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.sets[key][member]
+	return ok
+}
+
+func (m *mockSortedSetStore) AddMember(ctx context.Context, key, member string, score float64) error {
+	m.add(key, member, score)
+	return nil
+}
+
+func (m *mockSortedSetStore) Rank(ctx context.Context, key, member string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	score, ok := m.sets[key][member]
+	if !ok {
+		return 0, repository.ErrKeyNotFound
+	}
+	rank := 0
+	for _, other := range m.sets[key] {
+		if other < score {
+			rank++
+		}
+	}
+	return rank, nil
+}
+
+func (m *mockSortedSetStore) Size(ctx context.Context, key string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.sets[key]), nil
+}
+
+func (m *mockSortedSetStore) MembersWithScoreAtMost(ctx context.Context, key string, max float64) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var members []string
+	for member, score := range m.sets[key] {
+		if score <= max {
+			members = append(members, member)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return m.sets[key][members[i]] < m.sets[key][members[j]]
+	})
+	return members, nil
+}
+
+func (m *mockSortedSetStore) RemoveMember(ctx context.Context, key, member string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.sets[key][member]; !ok {
+		return false, nil
+	}
+	delete(m.sets[key], member)
+	return true, nil
+}
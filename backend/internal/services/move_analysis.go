@@ -0,0 +1,159 @@
+// Package services contains business logic for the application.
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/game"
+	"github.com/xiangqi/chinese-chess-backend/internal/game/ai"
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
+
+// AnalyzedMove is a move with the engine's score for it, from the point of
+// view of the player who made it.
+type AnalyzedMove struct {
+	From      string           `json:"from"`
+	To        string           `json:"to"`
+	PieceType models.PieceType `json:"piece_type"`
+	Score     int              `json:"score"`
+}
+
+// MoveAnalysis compares a played move with the engine's best move in the
+// same position.
+type MoveAnalysis struct {
+	MoveNumber int `json:"move_number"`
+	// FEN is the position before the move was played
+	FEN    string       `json:"fen"`
+	Played AnalyzedMove `json:"played"`
+	Best   AnalyzedMove `json:"best"`
+	// Depth is the deepest search that finished within the time limit
+	Depth int `json:"depth"`
+}
+
+// AnalyzeMove replays a finished game up to a move and searches the
+// position before it, comparing the move that was played with the engine's
+// best. Only the game's players may analyze it. The search runs on the
+// search pool, deepening one ply at a time up to depth, and reports the last
+// depth that finished before the timeout. A depth of 0 searches to
+// limits.MaxDepth, and a deeper depth is refused with ErrDepthExceeded.
+func (s *GameService) AnalyzeMove(ctx context.Context, gameID, playerID string, moveNumber, depth int, limits AnalysisLimits) (*MoveAnalysis, error) {
+	if depth != 0 {
+		if err := limits.CheckDepth(depth); err != nil {
+			return nil, err
+		}
+		limits.MaxDepth = depth
+	}
+
+	g, err := s.GetGame(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+	if g.Status == models.GameStatusActive {
+		return nil, ErrGameNotFinished
+	}
+	if playerID != g.RedPlayerID && playerID != g.BlackPlayerID {
+		return nil, ErrPlayerNotInGame
+	}
+
+	moves, err := s.GetMoves(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+	if moveNumber < 1 || moveNumber > len(moves) {
+		return nil, ErrMoveNotFound
+	}
+
+	engine := game.NewGameEngine(g.ID, g.RedPlayerID, g.BlackPlayerID)
+	for _, move := range moves[:moveNumber-1] {
+		result := engine.ValidateAndMakeMove(game.MoveRequest{
+			PlayerID: move.PlayerID,
+			From:     move.FromPosition,
+			To:       move.ToPosition,
+		})
+		if !result.Success {
+			return nil, fmt.Errorf("failed to replay move %d: %s", move.MoveNumber, result.ErrorMessage)
+		}
+	}
+
+	stored := moves[moveNumber-1]
+	played, err := playedMove(stored)
+	if err != nil {
+		return nil, err
+	}
+
+	analysis := &MoveAnalysis{
+		MoveNumber: moveNumber,
+		FEN:        engine.GetFEN(),
+		Played: AnalyzedMove{
+			From:      stored.FromPosition,
+			To:        stored.ToPosition,
+			PieceType: stored.PieceType,
+		},
+	}
+
+	err = s.runSearch(ctx, func(ctx context.Context) error {
+		return deepenAnalysis(ctx, engine.GetBoard(), engine.GetCurrentTurn(), played, limits, analysis)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return analysis, nil
+}
+
+// deepenAnalysis searches the position one ply deeper at a time until
+// limits.MaxDepth or the timeout, filling in the scores and best move of the
+// deepest search that finished.
+func deepenAnalysis(ctx context.Context, board *game.Board, color models.PlayerColor, played game.Move, limits AnalysisLimits, analysis *MoveAnalysis) error {
+	searchCtx, cancel := limits.WithTimeout(ctx)
+	defer cancel()
+
+	searcher := ai.NewEngine()
+	for depth := 1; depth <= limits.MaxDepth; depth++ {
+		best, err := searcher.Search(searchCtx, board, color, depth)
+		if err != nil {
+			if errors.Is(err, ai.ErrNoLegalMoves) {
+				return fmt.Errorf("no legal moves before move %d", analysis.MoveNumber)
+			}
+			break
+		}
+		playedScore, err := searcher.EvaluateMove(searchCtx, board, color, played, depth)
+		if err != nil {
+			break
+		}
+
+		analysis.Depth = depth
+		analysis.Played.Score = playedScore
+		analysis.Best = AnalyzedMove{
+			From:      best.Move.From.Notation(),
+			To:        best.Move.To.Notation(),
+			PieceType: best.Move.PieceType,
+			Score:     best.Score,
+		}
+	}
+
+	if analysis.Depth == 0 {
+		if err := CheckContext(searchCtx); err != nil {
+			return err
+		}
+		return ErrAnalysisTimeout
+	}
+	return nil
+}
+
+// playedMove converts a stored move into a move the engine can score.
+func playedMove(move *models.Move) (game.Move, error) {
+	from, err := game.ParsePosition(move.FromPosition)
+	if err != nil {
+		return game.Move{}, fmt.Errorf("invalid stored move %d: %w", move.MoveNumber, err)
+	}
+	to, err := game.ParsePosition(move.ToPosition)
+	if err != nil {
+		return game.Move{}, fmt.Errorf("invalid stored move %d: %w", move.MoveNumber, err)
+	}
+	return game.Move{From: from, To: to, PieceType: move.PieceType}, nil
+}
+
+// ErrMoveNotFound is returned when a game has no move with the requested number.
+var ErrMoveNotFound = errors.New("move not found")
@@ -0,0 +1,118 @@
+// Package services contains business logic for the application.
+package services
+
+import (
+	"math"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
+
+// RatingKFactor is the maximum rating change from a single game.
+const RatingKFactor = 32
+
+const (
+	// DefaultRatingFloor is used when no rating floor is configured.
+	DefaultRatingFloor = 100
+	// DefaultRatingCeiling is used when no rating ceiling is configured.
+	DefaultRatingCeiling = 3000
+)
+
+// RatingBounds keeps ratings within a floor and a ceiling so that a long
+// losing or winning streak cannot push them to extremes.
+type RatingBounds struct {
+	Floor   int
+	Ceiling int
+}
+
+// NewRatingBounds creates rating bounds. Non-positive values fall back to
+// DefaultRatingFloor and DefaultRatingCeiling, and a ceiling that is not
+// above the floor falls back to both defaults.
+func NewRatingBounds(floor, ceiling int) RatingBounds {
+	if floor <= 0 {
+		floor = DefaultRatingFloor
+	}
+	if ceiling <= 0 {
+		ceiling = DefaultRatingCeiling
+	}
+	if ceiling <= floor {
+		floor, ceiling = DefaultRatingFloor, DefaultRatingCeiling
+	}
+	return RatingBounds{Floor: floor, Ceiling: ceiling}
+}
+
+// Clamp returns the rating limited to the bounds.
+func (b RatingBounds) Clamp(rating int) int {
+	return min(max(rating, b.Floor), b.Ceiling)
+}
+
+// ExpectedScore returns a player's expected score (0 to 1) against an
+// opponent under the ELO model.
+func ExpectedScore(rating, opponentRating int) float64 {
+	return 1 / (1 + math.Pow(10, float64(opponentRating-rating)/400))
+}
+
+// RatingChange returns the number of rating points a player gains (negative
+// for a loss of points) from a game against an opponent. A draw counts as
+// half a win, so a draw between equal ratings changes nothing while a draw
+// moves unequal ratings slightly towards each other. The opponent's change
+// is always the exact negative, so no points are created or destroyed.
+func RatingChange(rating, opponentRating int, result GameResult) int {
+	var score float64
+	switch result {
+	case GameResultWin:
+		score = 1
+	case GameResultDraw:
+		score = 0.5
+	}
+
+	return int(math.Round(RatingKFactor * (score - ExpectedScore(rating, opponentRating))))
+}
+
+// UpdateRatings returns both players' new ratings after a game, given the
+// result for the first player. The change is computed once and applied to
+// both sides with opposite signs before clamping, so the bounds can only
+// create points at the floor or destroy them at the ceiling, never shift
+// them between players. Clamping is logged, since it breaks the zero-sum
+// balance.
+func UpdateRatings(rating, opponentRating int, result GameResult, bounds RatingBounds) (newRating, newOpponentRating int) {
+	change := RatingChange(rating, opponentRating, result)
+
+	newRating = bounds.Clamp(rating + change)
+	newOpponentRating = bounds.Clamp(opponentRating - change)
+
+	if newRating != rating+change || newOpponentRating != opponentRating-change {
+		log.Info().
+			Int("rating", rating).
+			Int("opponent_rating", opponentRating).
+			Int("change", change).
+			Int("points_created", newRating+newOpponentRating-rating-opponentRating).
+			Msg("Rating clamped to bounds")
+	}
+
+	return newRating, newOpponentRating
+}
+
+const (
+	// MinRatingDeviation is the narrowest a player's rating deviation gets.
+	MinRatingDeviation = 50
+	// ratingDeviationStep is how much each ranked game narrows the rating
+	// deviation.
+	ratingDeviationStep = 15
+)
+
+// narrowRatingDeviation returns the rating deviation after one more ranked
+// game.
+func narrowRatingDeviation(deviation int) int {
+	return max(deviation-ratingDeviationStep, MinRatingDeviation)
+}
+
+// currentRating returns the user's rating, treating users stored before
+// ratings existed as new players.
+func currentRating(user *models.User) int {
+	if user.Rating == 0 {
+		return models.DefaultRating
+	}
+	return user.Rating
+}
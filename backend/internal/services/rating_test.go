@@ -0,0 +1,124 @@
+// Package services provides unit tests for ELO rating calculations.
+package services
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRatingChange_DrawBetweenEqualRatings(t *testing.T) {
+	for _, rating := range []int{800, 1200, 2000} {
+		if change := RatingChange(rating, rating, GameResultDraw); change != 0 {
+			t.Errorf("Draw at %d vs %d should not change rating, got %+d", rating, rating, change)
+		}
+	}
+}
+
+func TestRatingChange_DrawBetweenUnequalRatings(t *testing.T) {
+	higher := RatingChange(1400, 1200, GameResultDraw)
+	lower := RatingChange(1200, 1400, GameResultDraw)
+
+	if higher >= 0 {
+		t.Errorf("Higher-rated player should lose points on a draw, got %+d", higher)
+	}
+	if lower <= 0 {
+		t.Errorf("Lower-rated player should gain points on a draw, got %+d", lower)
+	}
+	if higher+lower != 0 {
+		t.Errorf("Draw should not create or destroy points, got %+d and %+d", higher, lower)
+	}
+	// A 200 point gap expects the favourite to score ~0.76, so a draw costs ~8
+	if higher != -8 {
+		t.Errorf("Expected the favourite to lose 8 points, got %+d", higher)
+	}
+
+	// The nudge is smaller than a decisive result
+	if win := RatingChange(1200, 1400, GameResultWin); lower >= win {
+		t.Errorf("Draw gain %+d should be smaller than win gain %+d", lower, win)
+	}
+}
+
+func TestRatingChange_WinAndLoss(t *testing.T) {
+	if change := RatingChange(1200, 1200, GameResultWin); change != RatingKFactor/2 {
+		t.Errorf("Win between equal ratings should gain %d, got %+d", RatingKFactor/2, change)
+	}
+	if change := RatingChange(1200, 1200, GameResultLoss); change != -RatingKFactor/2 {
+		t.Errorf("Loss between equal ratings should lose %d, got %+d", RatingKFactor/2, change)
+	}
+
+	// Beating a much stronger opponent is worth more than beating an equal one
+	if upset := RatingChange(1200, 1800, GameResultWin); upset <= RatingKFactor/2 || upset > RatingKFactor {
+		t.Errorf("Upset win should gain between %d and %d, got %+d", RatingKFactor/2, RatingKFactor, upset)
+	}
+}
+
+func TestExpectedScore(t *testing.T) {
+	if score := ExpectedScore(1500, 1500); score != 0.5 {
+		t.Errorf("Expected 0.5 for equal ratings, got %f", score)
+	}
+	if sum := ExpectedScore(1600, 1300) + ExpectedScore(1300, 1600); sum < 0.9999 || sum > 1.0001 {
+		t.Errorf("Expected scores should sum to 1, got %f", sum)
+	}
+	// A 400 point gap gives the favourite ten to one odds
+	if score := ExpectedScore(1600, 1200); math.Abs(score-10.0/11) > 1e-9 {
+		t.Errorf("Expected 10/11 for a 400 point favourite, got %f", score)
+	}
+}
+
+func TestUpdateRatings_FloorHoldsOnLoss(t *testing.T) {
+	bounds := NewRatingBounds(100, 3000)
+
+	rating, opponent := UpdateRatings(100, 1200, GameResultLoss, bounds)
+	if rating != 100 {
+		t.Errorf("Player at the floor should stay at 100 after a loss, got %d", rating)
+	}
+	// The winner still gains what the loss would have cost
+	if want := 1200 - RatingChange(100, 1200, GameResultLoss); opponent != want {
+		t.Errorf("Expected the winner to reach %d, got %d", want, opponent)
+	}
+
+	// Just above the floor, the loss is cut short at the floor
+	if rating, _ := UpdateRatings(105, 105, GameResultLoss, bounds); rating != 100 {
+		t.Errorf("Expected the loss to stop at the floor, got %d", rating)
+	}
+}
+
+func TestUpdateRatings_CeilingHoldsOnWin(t *testing.T) {
+	bounds := NewRatingBounds(100, 3000)
+
+	rating, opponent := UpdateRatings(2995, 2995, GameResultWin, bounds)
+	if rating != 3000 {
+		t.Errorf("Expected the win to stop at the ceiling, got %d", rating)
+	}
+	if opponent != 2995-RatingKFactor/2 {
+		t.Errorf("Expected the loser to drop by %d, got %d", RatingKFactor/2, opponent)
+	}
+}
+
+func TestUpdateRatings_ZeroSumWithinBounds(t *testing.T) {
+	bounds := NewRatingBounds(100, 3000)
+
+	for _, result := range []GameResult{GameResultWin, GameResultDraw, GameResultLoss} {
+		rating, opponent := UpdateRatings(1400, 1200, result, bounds)
+		if rating+opponent != 2600 {
+			t.Errorf("%s: ratings within bounds should keep their sum, got %d + %d", result, rating, opponent)
+		}
+	}
+}
+
+func TestNewRatingBounds_Defaults(t *testing.T) {
+	tests := []struct {
+		floor, ceiling int
+		want           RatingBounds
+	}{
+		{0, 0, RatingBounds{DefaultRatingFloor, DefaultRatingCeiling}},
+		{200, 2500, RatingBounds{200, 2500}},
+		{3000, 100, RatingBounds{DefaultRatingFloor, DefaultRatingCeiling}},
+	}
+
+	for _, tt := range tests {
+		if got := NewRatingBounds(tt.floor, tt.ceiling); got != tt.want {
+			t.Errorf("NewRatingBounds(%d, %d) = %+v, want %+v", tt.floor, tt.ceiling, got, tt.want)
+		}
+	}
+}
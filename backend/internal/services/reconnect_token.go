@@ -0,0 +1,125 @@
+// Package services contains business logic for the application.
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+	"github.com/xiangqi/chinese-chess-backend/internal/repository"
+)
+
+const (
+	// reconnectSecretBytes is the size of the secret generated when none is
+	// configured.
+	reconnectSecretBytes = 32
+
+	reconnectSeatKey = "reconnect:seated:"
+	// reconnectSeatTTL is how long a player is remembered as seated in a
+	// game, which outlasts any game.
+	reconnectSeatTTL = 24 * time.Hour
+)
+
+// ReconnectTokens signs the tokens a player presents to take their seat
+// again after a dropped connection. A token is an HMAC of the game, device
+// and color, so it cannot be forged for another player without the server
+// secret, and any server instance sharing the secret can verify it.
+type ReconnectTokens struct {
+	secret []byte
+	// shared is set when the secret was configured rather than generated.
+	shared bool
+
+	// seats remembers which players have been seated, so any instance,
+	// including one started after the seat was taken, asks for a token.
+	// Only the local room knows when nil.
+	seats KeyValueStore
+}
+
+// SetSeatStore sets the store that remembers which players have been
+// seated in a game.
+func (t *ReconnectTokens) SetSeatStore(store KeyValueStore) {
+	t.seats = store
+}
+
+// MarkSeated records that a player has been seated in a game, so later
+// connections for them must present their token.
+func (t *ReconnectTokens) MarkSeated(ctx context.Context, gameID, deviceID string) {
+	if t.seats == nil {
+		return
+	}
+	if err := t.seats.Set(ctx, reconnectSeatKey+gameID+":"+deviceID, "1", reconnectSeatTTL); err != nil {
+		log.Warn().Err(err).Str("game_id", gameID).Str("device_id", deviceID).Msg("Failed to record seated player")
+	}
+}
+
+// WasSeated reports whether a player has been recorded as seated in a game.
+// A store error counts as seated, so a token is asked for rather than
+// skipped.
+func (t *ReconnectTokens) WasSeated(ctx context.Context, gameID, deviceID string) bool {
+	if t.seats == nil {
+		return false
+	}
+	_, err := t.seats.Get(ctx, reconnectSeatKey+gameID+":"+deviceID)
+	return !errors.Is(err, repository.ErrKeyNotFound)
+}
+
+// NewReconnectTokens creates a ReconnectTokens keyed by secret. An empty
+// secret is replaced by a random one, so tokens only verify on the server
+// that issued them and until it restarts.
+func NewReconnectTokens(secret string) (*ReconnectTokens, error) {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, reconnectSecretBytes)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+	return &ReconnectTokens{secret: key, shared: len(secret) > 0}, nil
+}
+
+// Shared reports whether the secret was configured rather than generated,
+// so tokens verify on every instance sharing it and across restarts.
+func (t *ReconnectTokens) Shared() bool {
+	return t.shared
+}
+
+// Issue returns the reconnection token of a player seated in a game.
+func (t *ReconnectTokens) Issue(gameID, deviceID string, color models.PlayerColor) string {
+	mac := hmac.New(sha256.New, t.secret)
+	// Separators keep distinct fields from running into each other
+	mac.Write([]byte(gameID))
+	mac.Write([]byte{0})
+	mac.Write([]byte(deviceID))
+	mac.Write([]byte{0})
+	mac.Write([]byte(color))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a reconnection token against the player it claims to be for.
+func (t *ReconnectTokens) Verify(token, gameID, deviceID string, color models.PlayerColor) error {
+	if token == "" {
+		return ErrReconnectTokenMissing
+	}
+	got, err := hex.DecodeString(token)
+	if err != nil {
+		return ErrReconnectTokenInvalid
+	}
+	want, _ := hex.DecodeString(t.Issue(gameID, deviceID, color))
+	if !hmac.Equal(got, want) {
+		return ErrReconnectTokenInvalid
+	}
+	return nil
+}
+
+// Reconnect token errors
+var (
+	ErrReconnectTokenMissing = errors.New("reconnection token is required")
+	ErrReconnectTokenInvalid = errors.New("invalid reconnection token")
+)
@@ -0,0 +1,94 @@
+// Package services provides unit tests for reconnection tokens.
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
+
+func newTestReconnectTokens(t *testing.T, secret string) *ReconnectTokens {
+	t.Helper()
+	tokens, err := NewReconnectTokens(secret)
+	if err != nil {
+		t.Fatalf("NewReconnectTokens failed: %v", err)
+	}
+	return tokens
+}
+
+func TestReconnectTokens_IssueAndVerify(t *testing.T) {
+	tokens := newTestReconnectTokens(t, "secret")
+
+	token := tokens.Issue("game-1", "red-player", models.PlayerColorRed)
+	if token == "" {
+		t.Fatal("Expected a token")
+	}
+	if again := tokens.Issue("game-1", "red-player", models.PlayerColorRed); again != token {
+		t.Errorf("Expected the same player to get the same token, got %q and %q", token, again)
+	}
+	if err := tokens.Verify(token, "game-1", "red-player", models.PlayerColorRed); err != nil {
+		t.Errorf("Expected the token to verify, got %v", err)
+	}
+
+	// Instances sharing the secret accept each other's tokens
+	other := newTestReconnectTokens(t, "secret")
+	if err := other.Verify(token, "game-1", "red-player", models.PlayerColorRed); err != nil {
+		t.Errorf("Expected another instance to verify the token, got %v", err)
+	}
+}
+
+func TestReconnectTokens_RejectsMismatchedTokens(t *testing.T) {
+	tokens := newTestReconnectTokens(t, "secret")
+	token := tokens.Issue("game-1", "red-player", models.PlayerColorRed)
+
+	tests := []struct {
+		name     string
+		token    string
+		gameID   string
+		deviceID string
+		color    models.PlayerColor
+		want     error
+	}{
+		{"missing", "", "game-1", "red-player", models.PlayerColorRed, ErrReconnectTokenMissing},
+		{"forged", "deadbeef", "game-1", "red-player", models.PlayerColorRed, ErrReconnectTokenInvalid},
+		{"not hex", "zz", "game-1", "red-player", models.PlayerColorRed, ErrReconnectTokenInvalid},
+		{"other game", token, "game-2", "red-player", models.PlayerColorRed, ErrReconnectTokenInvalid},
+		{"other device", token, "game-1", "black-player", models.PlayerColorRed, ErrReconnectTokenInvalid},
+		{"other color", token, "game-1", "red-player", models.PlayerColorBlack, ErrReconnectTokenInvalid},
+		{"other secret", newTestReconnectTokens(t, "other").Issue("game-1", "red-player", models.PlayerColorRed),
+			"game-1", "red-player", models.PlayerColorRed, ErrReconnectTokenInvalid},
+	}
+
+	for _, tt := range tests {
+		if err := tokens.Verify(tt.token, tt.gameID, tt.deviceID, tt.color); !errors.Is(err, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestReconnectTokens_FieldsDoNotRunTogether(t *testing.T) {
+	tokens := newTestReconnectTokens(t, "secret")
+
+	a := tokens.Issue("game-1", "red-player", models.PlayerColorRed)
+	b := tokens.Issue("game-1red", "-player", models.PlayerColorRed)
+	if a == b {
+		t.Error("Expected tokens for different fields to differ")
+	}
+}
+
+func TestReconnectTokens_EmptySecretIsRandom(t *testing.T) {
+	first := newTestReconnectTokens(t, "")
+	second := newTestReconnectTokens(t, "")
+
+	token := first.Issue("game-1", "red-player", models.PlayerColorRed)
+	if err := second.Verify(token, "game-1", "red-player", models.PlayerColorRed); !errors.Is(err, ErrReconnectTokenInvalid) {
+		t.Errorf("Expected generated secrets to differ, got %v", err)
+	}
+	if first.Shared() {
+		t.Error("Expected a generated secret not to be shared")
+	}
+	if !newTestReconnectTokens(t, "configured").Shared() {
+		t.Error("Expected a configured secret to be shared")
+	}
+}
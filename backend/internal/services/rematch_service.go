@@ -0,0 +1,172 @@
+// Package services contains business logic for the application.
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+	"github.com/xiangqi/chinese-chess-backend/internal/repository"
+)
+
+const (
+	rematchOfferKey  = "rematch:offer:"
+	rematchResultKey = "rematch:result:"
+	rematchClaimKey  = "rematch:claim:"
+	rematchOfferTTL  = 2 * time.Minute
+	rematchResultTTL = 10 * time.Minute
+)
+
+// RematchService handles rematch requests between the players of a finished game.
+type RematchService struct {
+	store       KeyValueStore
+	gameService *GameService
+	userService *UserService
+}
+
+// NewRematchService creates a new RematchService.
+func NewRematchService(store KeyValueStore, gameService *GameService, userService *UserService) *RematchService {
+	return &RematchService{
+		store:       store,
+		gameService: gameService,
+		userService: userService,
+	}
+}
+
+// RequestRematch asks for a rematch of a finished game with the same settings.
+// The new game is created immediately when the opponent has auto-rematch enabled
+// or has already requested a rematch themselves; otherwise the request is left
+// pending. Repeating the request is safe and returns the new game once created.
+func (s *RematchService) RequestRematch(ctx context.Context, gameID, playerID string) (*RematchStatus, error) {
+	game, err := s.gameService.GetGame(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	var opponentID string
+	switch playerID {
+	case game.RedPlayerID:
+		opponentID = game.BlackPlayerID
+	case game.BlackPlayerID:
+		opponentID = game.RedPlayerID
+	default:
+		return nil, ErrPlayerNotInGame
+	}
+
+	if game.Status == models.GameStatusActive {
+		return nil, ErrGameNotFinished
+	}
+
+	// A rematch may already have been created by the opponent accepting.
+	newGameID, err := s.store.Get(ctx, rematchResultKey+gameID)
+	if err == nil {
+		return rematchCreated(game, playerID, newGameID), nil
+	}
+	if !errors.Is(err, repository.ErrKeyNotFound) {
+		return nil, fmt.Errorf("failed to get rematch result: %w", err)
+	}
+
+	opponent, err := s.userService.GetByID(ctx, opponentID)
+	if err != nil && !errors.Is(err, ErrUserNotFound) {
+		return nil, fmt.Errorf("failed to get opponent: %w", err)
+	}
+	if opponent != nil && opponent.AutoRematch {
+		return s.createRematch(ctx, game, playerID)
+	}
+
+	stored, err := s.store.SetNX(ctx, rematchOfferKey+gameID, playerID, rematchOfferTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store rematch offer: %w", err)
+	}
+	if stored {
+		return &RematchStatus{Status: RematchStatusPending}, nil
+	}
+
+	// An offer already exists; if it came from the opponent, this request accepts it.
+	offeredBy, err := s.store.Get(ctx, rematchOfferKey+gameID)
+	if err != nil && !errors.Is(err, repository.ErrKeyNotFound) {
+		return nil, fmt.Errorf("failed to get rematch offer: %w", err)
+	}
+	if offeredBy == opponentID {
+		return s.createRematch(ctx, game, playerID)
+	}
+
+	return &RematchStatus{Status: RematchStatusPending}, nil
+}
+
+// createRematch creates the new game with colors swapped and the same settings.
+// Both players may accept at once, so only the request that claims the
+// rematch creates the game; the other sees it once it is stored.
+func (s *RematchService) createRematch(ctx context.Context, game *models.Game, playerID string) (*RematchStatus, error) {
+	claimed, err := s.store.SetNX(ctx, rematchClaimKey+game.ID, playerID, rematchResultTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim rematch: %w", err)
+	}
+	if !claimed {
+		newGameID, err := s.store.Get(ctx, rematchResultKey+game.ID)
+		if err == nil {
+			return rematchCreated(game, playerID, newGameID), nil
+		}
+		if !errors.Is(err, repository.ErrKeyNotFound) {
+			return nil, fmt.Errorf("failed to get rematch result: %w", err)
+		}
+		return &RematchStatus{Status: RematchStatusPending}, nil
+	}
+
+	newGame, err := s.gameService.CreateGame(ctx, game.BlackPlayerID, game.RedPlayerID, GameSettings{
+		TurnTimeout: game.TurnTimeoutSeconds,
+		TimeControl: game.TimeControl,
+		Increment:   game.IncrementSeconds,
+		BaseTime:    game.BaseTimeSeconds,
+		IsPublic:    game.IsPublic,
+		Ranked:      game.Ranked,
+		Assist:      game.AssistEnabled,
+	})
+	if err != nil {
+		_ = s.store.Del(ctx, rematchClaimKey+game.ID)
+		return nil, err
+	}
+
+	if err := s.store.Set(ctx, rematchResultKey+game.ID, newGame.ID, rematchResultTTL); err != nil {
+		return nil, fmt.Errorf("failed to store rematch result: %w", err)
+	}
+	_ = s.store.Del(ctx, rematchOfferKey+game.ID)
+
+	return rematchCreated(game, playerID, newGame.ID), nil
+}
+
+// rematchCreated builds the status for a created rematch. Colors are swapped
+// relative to the original game.
+func rematchCreated(game *models.Game, playerID, newGameID string) *RematchStatus {
+	color := models.PlayerColorRed
+	if playerID == game.RedPlayerID {
+		color = models.PlayerColorBlack
+	}
+	return &RematchStatus{
+		Status:    RematchStatusCreated,
+		GameID:    newGameID,
+		YourColor: color,
+	}
+}
+
+// RematchStatus represents the outcome of a rematch request.
+type RematchStatus struct {
+	Status    RematchState       `json:"status"`
+	GameID    string             `json:"game_id,omitempty"`
+	YourColor models.PlayerColor `json:"your_color,omitempty"`
+}
+
+// RematchState represents the state of a rematch request.
+type RematchState string
+
+const (
+	RematchStatusPending RematchState = "pending"
+	RematchStatusCreated RematchState = "created"
+)
+
+// Rematch errors
+var (
+	ErrGameNotFinished = errors.New("game is not finished")
+)
@@ -0,0 +1,187 @@
+// Package services provides unit tests for the rematch service.
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
+
+// newTestRematchService creates a rematch service backed by mocks, with a
+// completed game "game-1" between "red-player" and "black-player".
+func newTestRematchService(t *testing.T, opponentAutoRematch bool) (*RematchService, *mockGameRepository) {
+	t.Helper()
+	ctx := context.Background()
+
+	userRepo := newMockUserRepository()
+	userRepo.Create(ctx, &models.User{ID: "red-player", DisplayName: "RedPlayer"})
+	userRepo.Create(ctx, &models.User{ID: "black-player", DisplayName: "BlackPlayer", AutoRematch: opponentAutoRematch})
+
+	gameRepo := newMockGameRepository()
+	gameRepo.Create(ctx, &models.Game{
+		ID:                 "game-1",
+		RedPlayerID:        "red-player",
+		BlackPlayerID:      "black-player",
+		Status:             models.GameStatusCompleted,
+		TurnTimeoutSeconds: 120,
+		Ranked:             true,
+		AssistEnabled:      true,
+	})
+
+	gameService := NewGameService(gameRepo, newMockMoveRepository(), userRepo)
+	service := NewRematchService(newMockKeyValueStore(), gameService, NewUserService(userRepo))
+	return service, gameRepo
+}
+
+func TestRematchService_AutoAccept(t *testing.T) {
+	service, gameRepo := newTestRematchService(t, true)
+	ctx := context.Background()
+
+	status, err := service.RequestRematch(ctx, "game-1", "red-player")
+	if err != nil {
+		t.Fatalf("RequestRematch failed: %v", err)
+	}
+
+	if status.Status != RematchStatusCreated {
+		t.Fatalf("Expected status %s, got %s", RematchStatusCreated, status.Status)
+	}
+	if status.GameID == "" || status.GameID == "game-1" {
+		t.Fatalf("Expected a new game ID, got %q", status.GameID)
+	}
+	if status.YourColor != models.PlayerColorBlack {
+		t.Errorf("Expected colors to swap to black, got %s", status.YourColor)
+	}
+
+	newGame, err := gameRepo.GetByID(ctx, status.GameID)
+	if err != nil {
+		t.Fatalf("New game was not created: %v", err)
+	}
+	if newGame.RedPlayerID != "black-player" || newGame.BlackPlayerID != "red-player" {
+		t.Errorf("Expected swapped colors, got red=%s black=%s", newGame.RedPlayerID, newGame.BlackPlayerID)
+	}
+	if newGame.TurnTimeoutSeconds != 120 {
+		t.Errorf("Expected turn timeout 120, got %d", newGame.TurnTimeoutSeconds)
+	}
+	if !newGame.Ranked {
+		t.Error("Expected the rematch to stay ranked")
+	}
+	if !newGame.AssistEnabled {
+		t.Error("Expected the rematch to keep move assistance")
+	}
+	if newGame.Status != models.GameStatusActive {
+		t.Errorf("Expected new game to be active, got %s", newGame.Status)
+	}
+
+	// The opponent requesting afterwards gets the same game
+	opponentStatus, err := service.RequestRematch(ctx, "game-1", "black-player")
+	if err != nil {
+		t.Fatalf("RequestRematch failed: %v", err)
+	}
+	if opponentStatus.GameID != status.GameID {
+		t.Errorf("Expected opponent to get game %s, got %s", status.GameID, opponentStatus.GameID)
+	}
+	if opponentStatus.YourColor != models.PlayerColorRed {
+		t.Errorf("Expected opponent color red, got %s", opponentStatus.YourColor)
+	}
+}
+
+func TestRematchService_Pending(t *testing.T) {
+	service, gameRepo := newTestRematchService(t, false)
+	ctx := context.Background()
+
+	status, err := service.RequestRematch(ctx, "game-1", "red-player")
+	if err != nil {
+		t.Fatalf("RequestRematch failed: %v", err)
+	}
+
+	if status.Status != RematchStatusPending {
+		t.Fatalf("Expected status %s, got %s", RematchStatusPending, status.Status)
+	}
+	if status.GameID != "" {
+		t.Errorf("Pending rematch should not have a game ID, got %q", status.GameID)
+	}
+	if len(gameRepo.games) != 1 {
+		t.Errorf("No game should be created while pending, have %d games", len(gameRepo.games))
+	}
+
+	// Repeating the request stays pending
+	status, err = service.RequestRematch(ctx, "game-1", "red-player")
+	if err != nil {
+		t.Fatalf("RequestRematch failed: %v", err)
+	}
+	if status.Status != RematchStatusPending {
+		t.Errorf("Expected repeated request to stay pending, got %s", status.Status)
+	}
+}
+
+func TestRematchService_PendingAcceptedByOpponent(t *testing.T) {
+	service, _ := newTestRematchService(t, false)
+	ctx := context.Background()
+
+	if _, err := service.RequestRematch(ctx, "game-1", "red-player"); err != nil {
+		t.Fatalf("RequestRematch failed: %v", err)
+	}
+
+	status, err := service.RequestRematch(ctx, "game-1", "black-player")
+	if err != nil {
+		t.Fatalf("RequestRematch failed: %v", err)
+	}
+	if status.Status != RematchStatusCreated {
+		t.Fatalf("Expected opponent's request to create the rematch, got %s", status.Status)
+	}
+
+	// The original requester now sees the created game
+	requesterStatus, err := service.RequestRematch(ctx, "game-1", "red-player")
+	if err != nil {
+		t.Fatalf("RequestRematch failed: %v", err)
+	}
+	if requesterStatus.Status != RematchStatusCreated || requesterStatus.GameID != status.GameID {
+		t.Errorf("Expected requester to see game %s, got %+v", status.GameID, requesterStatus)
+	}
+}
+
+func TestRematchService_CreateRematch_CreatesOneGame(t *testing.T) {
+	service, gameRepo := newTestRematchService(t, true)
+	ctx := context.Background()
+	game, err := gameRepo.GetByID(ctx, "game-1")
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+
+	// Both players accepting at once must not create two games
+	var wg sync.WaitGroup
+	for _, playerID := range []string{"red-player", "black-player"} {
+		wg.Add(1)
+		go func(playerID string) {
+			defer wg.Done()
+			if _, err := service.createRematch(ctx, game, playerID); err != nil {
+				t.Errorf("createRematch failed: %v", err)
+			}
+		}(playerID)
+	}
+	wg.Wait()
+
+	if len(gameRepo.games) != 2 {
+		t.Errorf("Expected one rematch game, have %d games", len(gameRepo.games))
+	}
+}
+
+func TestRematchService_Errors(t *testing.T) {
+	service, gameRepo := newTestRematchService(t, true)
+	ctx := context.Background()
+
+	if _, err := service.RequestRematch(ctx, "missing", "red-player"); err != ErrGameNotFound {
+		t.Errorf("Expected ErrGameNotFound, got %v", err)
+	}
+
+	if _, err := service.RequestRematch(ctx, "game-1", "stranger"); err != ErrPlayerNotInGame {
+		t.Errorf("Expected ErrPlayerNotInGame, got %v", err)
+	}
+
+	gameRepo.games["game-1"].Status = models.GameStatusActive
+	if _, err := service.RequestRematch(ctx, "game-1", "red-player"); err != ErrGameNotFinished {
+		t.Errorf("Expected ErrGameNotFinished, got %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+// Package services contains business logic for the application.
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
+
+// GameRepository defines the game persistence operations used by the services.
+type GameRepository interface {
+	Create(ctx context.Context, game *models.Game) error
+	CreateWithMoves(ctx context.Context, game *models.Game, moves []*models.Move) error
+	GetByID(ctx context.Context, id string) (*models.Game, error)
+	Update(ctx context.Context, game *models.Game) error
+	GetHistoryByPlayer(ctx context.Context, playerID string, limit, offset int) ([]*models.Game, error)
+	CountByPlayer(ctx context.Context, playerID string) (int, error)
+	GetActiveByPlayer(ctx context.Context, playerID string) ([]*models.Game, error)
+	GetLivePublic(ctx context.Context, limit, offset int) ([]*models.Game, error)
+	CountLivePublic(ctx context.Context) (int, error)
+}
+
+// MoveRepository defines the move persistence operations used by the services.
+type MoveRepository interface {
+	Create(ctx context.Context, move *models.Move) error
+	GetByGameID(ctx context.Context, gameID string) ([]*models.Move, error)
+	DeleteAfterMoveNumber(ctx context.Context, gameID string, moveNumber int) error
+}
+
+// UserRepository defines the user persistence operations used by the services.
+type UserRepository interface {
+	Create(ctx context.Context, user *models.User) error
+	GetByID(ctx context.Context, id string) (*models.User, error)
+	Update(ctx context.Context, user *models.User) error
+	UpdateStats(ctx context.Context, id string, stats models.UserStats) error
+}
+
+// GameCounter defines the aggregate game counts used for server stats.
+type GameCounter interface {
+	CountCreatedSince(ctx context.Context, since time.Time) (int, error)
+	CountByStatus(ctx context.Context, status models.GameStatus) (int, error)
+}
+
+// UserCounter defines the aggregate user counts used for server stats.
+type UserCounter interface {
+	Count(ctx context.Context) (int, error)
+}
+
+// KeyValueStore defines the short-lived key/value operations used by the services.
+// It is implemented by repository.RedisClient; Get returns repository.ErrKeyNotFound
+// when the key does not exist.
+type KeyValueStore interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+	Del(ctx context.Context, keys ...string) error
+}
+
+// SortedSetStore defines the sorted set operations used by the matchmaking
+// queue. It is implemented by repository.RedisClient.
+type SortedSetStore interface {
+	AddMember(ctx context.Context, key, member string, score float64) error
+	MembersWithScoreAtMost(ctx context.Context, key string, max float64) ([]string, error)
+	RemoveMember(ctx context.Context, key, member string) (bool, error)
+	Rank(ctx context.Context, key, member string) (int, error)
+	Size(ctx context.Context, key string) (int, error)
+}
+
+// HashStore defines the hash operations used for pending challenges. It is
+// implemented by repository.RedisClient.
+type HashStore interface {
+	// SetField sets a field of the hash at key and makes the whole hash
+	// expire after ttl.
+	SetField(ctx context.Context, key, field, value string, ttl time.Duration) error
+	Fields(ctx context.Context, key string) (map[string]string, error)
+	DeleteField(ctx context.Context, key, field string) error
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
+
+// GameSummary describes a completed game for external systems. CompletedAt
+// is formatted like every timestamp sent to clients.
+type GameSummary struct {
+	GameID        string            `json:"game_id"`
+	RedPlayerID   string            `json:"red_player_id"`
+	BlackPlayerID string            `json:"black_player_id"`
+	WinnerID      *string           `json:"winner_id"`
+	ResultType    models.ResultType `json:"result_type"`
+	Ranked        bool              `json:"ranked"`
+	TotalMoves    int               `json:"total_moves"`
+	FinalFEN      string            `json:"final_fen,omitempty"`
+	CompletedAt   string            `json:"completed_at"`
+}
+
+// newGameSummary builds the summary of a completed game.
+func newGameSummary(game *models.Game) GameSummary {
+	summary := GameSummary{
+		GameID:        game.ID,
+		RedPlayerID:   game.RedPlayerID,
+		BlackPlayerID: game.BlackPlayerID,
+		WinnerID:      game.WinnerID,
+		Ranked:        game.Ranked,
+		TotalMoves:    game.TotalMoves,
+	}
+	if game.ResultType != nil {
+		summary.ResultType = *game.ResultType
+	}
+	if game.FinalFEN != nil {
+		summary.FinalFEN = *game.FinalFEN
+	}
+	if game.CompletedAt != nil {
+		summary.CompletedAt = models.FormatTimestamp(*game.CompletedAt)
+	}
+	return summary
+}
+
+// GameResultHook is notified of every completed game, for example to feed
+// analytics or a notification service.
+type GameResultHook interface {
+	GameEnded(ctx context.Context, summary GameSummary) error
+}
+
+// noopResultHook is the default hook, which does nothing.
+type noopResultHook struct{}
+
+func (noopResultHook) GameEnded(ctx context.Context, summary GameSummary) error {
+	return nil
+}
+
+// WebhookResultHook posts each game summary as JSON to a URL.
+type WebhookResultHook struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookResultHook creates a hook posting to url, giving up on each
+// request after timeout.
+func NewWebhookResultHook(url string, timeout time.Duration) *WebhookResultHook {
+	return &WebhookResultHook{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// GameEnded posts the summary to the webhook URL.
+func (h *WebhookResultHook) GameEnded(ctx context.Context, summary GameSummary) error {
+	body, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("failed to encode game summary: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post game result: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+// Package services provides unit tests for game result hooks.
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
+
+// stubResultHook records the summaries it is notified of.
+type stubResultHook struct {
+	summaries chan GameSummary
+}
+
+func (h *stubResultHook) GameEnded(ctx context.Context, summary GameSummary) error {
+	h.summaries <- summary
+	return nil
+}
+
+func TestGameService_EndGame_NotifiesResultHook(t *testing.T) {
+	service, _ := newTestGameServiceWithPlayers()
+	hook := &stubResultHook{summaries: make(chan GameSummary, 1)}
+	service.SetResultHook(hook)
+	ctx := context.Background()
+
+	game, err := service.CreateGame(ctx, "red-player", "black-player", GameSettings{TurnTimeout: 60, Ranked: true})
+	if err != nil {
+		t.Fatalf("CreateGame failed: %v", err)
+	}
+
+	winner := "black-player"
+	if err := service.EndGame(ctx, game.ID, &winner, models.ResultTypeResignation); err != nil {
+		t.Fatalf("EndGame failed: %v", err)
+	}
+
+	select {
+	case summary := <-hook.summaries:
+		if summary.GameID != game.ID {
+			t.Errorf("Expected game %s, got %s", game.ID, summary.GameID)
+		}
+		if summary.WinnerID == nil || *summary.WinnerID != winner {
+			t.Errorf("Expected winner %s, got %v", winner, summary.WinnerID)
+		}
+		if summary.ResultType != models.ResultTypeResignation {
+			t.Errorf("Expected resignation, got %s", summary.ResultType)
+		}
+		if !summary.Ranked {
+			t.Error("Expected a ranked game summary")
+		}
+		if _, err := time.Parse(models.TimestampFormat, summary.CompletedAt); err != nil {
+			t.Errorf("Expected completed_at in the API timestamp format, got %q", summary.CompletedAt)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Result hook was not notified")
+	}
+}
+
+func TestWebhookResultHook_PostsSummary(t *testing.T) {
+	received := make(chan GameSummary, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("Expected POST, got %s", r.Method)
+		}
+		var summary GameSummary
+		if err := json.NewDecoder(r.Body).Decode(&summary); err != nil {
+			t.Errorf("Failed to decode summary: %v", err)
+		}
+		received <- summary
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	hook := NewWebhookResultHook(server.URL, time.Second)
+	if err := hook.GameEnded(context.Background(), GameSummary{GameID: "game-1", ResultType: models.ResultTypeCheckmate}); err != nil {
+		t.Fatalf("GameEnded failed: %v", err)
+	}
+
+	summary := <-received
+	if summary.GameID != "game-1" || summary.ResultType != models.ResultTypeCheckmate {
+		t.Errorf("Unexpected summary posted: %+v", summary)
+	}
+}
+
+func TestWebhookResultHook_ReportsErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	hook := NewWebhookResultHook(server.URL, time.Second)
+	if err := hook.GameEnded(context.Background(), GameSummary{GameID: "game-1"}); err == nil {
+		t.Error("Expected an error for a failed webhook response")
+	}
+}
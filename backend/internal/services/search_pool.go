@@ -0,0 +1,84 @@
+// Package services contains business logic for the application.
+package services
+
+import (
+	"context"
+	"errors"
+	"runtime"
+)
+
+// DefaultSearchQueueSize is used when no search queue size is configured.
+const DefaultSearchQueueSize = 16
+
+// ErrSearchBusy is returned when the search queue is full.
+var ErrSearchBusy = errors.New("search pool is busy")
+
+// searchTask is a search waiting for a worker.
+type searchTask struct {
+	ctx    context.Context
+	run    func(ctx context.Context) error
+	result chan error
+}
+
+// SearchPool runs CPU-bound engine searches on a fixed number of worker
+// goroutines, so analysis and AI requests cannot starve the rest of the
+// server. Searches beyond the workers wait in a bounded queue; once that is
+// full, new searches are rejected with ErrSearchBusy.
+type SearchPool struct {
+	tasks chan searchTask
+}
+
+// NewSearchPool creates a search pool and starts its workers. Non-positive
+// values fall back to one worker per CPU and DefaultSearchQueueSize.
+func NewSearchPool(workers, queueSize int) *SearchPool {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if queueSize <= 0 {
+		queueSize = DefaultSearchQueueSize
+	}
+
+	p := &SearchPool{tasks: make(chan searchTask, queueSize)}
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// Run queues a search and waits for it to finish, returning its error. It
+// returns ErrSearchBusy without running the search if the queue is full,
+// and the context's error if ctx is done first. A search whose caller gave
+// up before it reached a worker is skipped.
+func (p *SearchPool) Run(ctx context.Context, search func(ctx context.Context) error) error {
+	task := searchTask{ctx: ctx, run: search, result: make(chan error, 1)}
+
+	select {
+	case p.tasks <- task:
+	default:
+		return ErrSearchBusy
+	}
+
+	select {
+	case err := <-task.result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops the workers once the queued searches have run. Run must not
+// be called after Close.
+func (p *SearchPool) Close() {
+	close(p.tasks)
+}
+
+// work runs queued searches until the pool is closed.
+func (p *SearchPool) work() {
+	for task := range p.tasks {
+		if err := task.ctx.Err(); err != nil {
+			task.result <- err
+			continue
+		}
+		task.result <- task.run(task.ctx)
+	}
+}
@@ -0,0 +1,112 @@
+// Package services provides unit tests for the search pool.
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSearchPool_RejectsWorkBeyondCapacity(t *testing.T) {
+	pool := NewSearchPool(1, 1)
+	defer pool.Close()
+	ctx := context.Background()
+
+	// Occupy the only worker
+	started := make(chan struct{})
+	release := make(chan struct{})
+	running := make(chan error, 1)
+	go func() {
+		running <- pool.Run(ctx, func(context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	// Fill the queue
+	queued := make(chan error, 1)
+	go func() {
+		queued <- pool.Run(ctx, func(context.Context) error { return nil })
+	}()
+	deadline := time.Now().Add(time.Second)
+	for len(pool.tasks) < 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("Search was never queued")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	ran := false
+	err := pool.Run(ctx, func(context.Context) error {
+		ran = true
+		return nil
+	})
+	if !errors.Is(err, ErrSearchBusy) {
+		t.Errorf("Expected ErrSearchBusy with the queue full, got %v", err)
+	}
+	if ran {
+		t.Error("Rejected search should not run")
+	}
+
+	close(release)
+	for _, result := range []chan error{running, queued} {
+		select {
+		case err := <-result:
+			if err != nil {
+				t.Errorf("Accepted search failed: %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Accepted search did not finish")
+		}
+	}
+}
+
+func TestSearchPool_ReturnsSearchError(t *testing.T) {
+	pool := NewSearchPool(2, 4)
+	defer pool.Close()
+
+	want := errors.New("search failed")
+	if err := pool.Run(context.Background(), func(context.Context) error { return want }); !errors.Is(err, want) {
+		t.Errorf("Expected the search's error, got %v", err)
+	}
+}
+
+func TestSearchPool_SkipsAbandonedSearch(t *testing.T) {
+	pool := NewSearchPool(1, 2)
+	defer pool.Close()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go pool.Run(context.Background(), func(context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+
+	// The caller gives up while its search is still queued
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	ran := make(chan struct{}, 1)
+	err := pool.Run(ctx, func(context.Context) error {
+		ran <- struct{}{}
+		return nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the context's error, got %v", err)
+	}
+
+	close(release)
+	// A follow-up search runs after the abandoned one is skipped
+	if err := pool.Run(context.Background(), func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("Follow-up search failed: %v", err)
+	}
+	select {
+	case <-ran:
+		t.Error("Abandoned search should not run")
+	default:
+	}
+}
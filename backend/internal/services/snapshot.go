@@ -0,0 +1,122 @@
+// Package services contains business logic for the application.
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/game"
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+	"github.com/xiangqi/chinese-chess-backend/internal/repository"
+)
+
+const (
+	snapshotKey = "game:snapshot:"
+	snapshotTTL = 24 * time.Hour
+)
+
+// gameSnapshot is the persisted board position of a game after a given move.
+// Recovery starts from the snapshot and replays only the moves after it.
+type gameSnapshot struct {
+	MoveNumber  int                 `json:"move_number"`
+	CurrentTurn models.PlayerColor  `json:"current_turn"`
+	Board       [][]game.PieceState `json:"board"`
+	// PliesSinceCapture is the engine's no-capture counter, which cannot be
+	// recovered from the board
+	PliesSinceCapture int `json:"plies_since_capture"`
+	// PositionCounts are the engine's repetition counts by position key
+	PositionCounts map[uint64]int `json:"position_counts,omitempty"`
+}
+
+// flushSnapshot saves a snapshot of the game's current position.
+func (s *GameService) flushSnapshot(ctx context.Context, gameID string) error {
+	engine, err := s.LoadEngine(ctx, gameID)
+	if err != nil {
+		return err
+	}
+
+	state := engine.GetGameState()
+	data, err := json.Marshal(gameSnapshot{
+		MoveNumber:        state.MoveCount,
+		CurrentTurn:       engine.GetCurrentTurn(),
+		Board:             state.Board,
+		PliesSinceCapture: engine.MovesSinceCapture(),
+		PositionCounts:    engine.PositionCounts(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	if err := s.snapshots.Set(ctx, snapshotKey+gameID, string(data), snapshotTTL); err != nil {
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	return nil
+}
+
+// loadSnapshot returns the latest snapshot of a game, or nil if there is none.
+func (s *GameService) loadSnapshot(ctx context.Context, gameID string) (*gameSnapshot, error) {
+	if s.snapshots == nil {
+		return nil, nil
+	}
+
+	data, err := s.snapshots.Get(ctx, snapshotKey+gameID)
+	if errors.Is(err, repository.ErrKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get snapshot: %w", err)
+	}
+
+	var snapshot gameSnapshot
+	if err := json.Unmarshal([]byte(data), &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// engineFromSnapshot rebuilds an engine from a snapshot and the moves made up
+// to it. It returns nil if the snapshot does not match the stored moves.
+func engineFromSnapshot(g *models.Game, snapshot *gameSnapshot, moves []*models.Move, opts ...game.EngineOption) *game.GameEngine {
+	if snapshot.MoveNumber <= 0 || snapshot.MoveNumber > len(moves) ||
+		moves[snapshot.MoveNumber-1].MoveNumber != snapshot.MoveNumber {
+		return nil
+	}
+
+	board, err := game.BoardFromState(snapshot.Board)
+	if err != nil {
+		return nil
+	}
+
+	history := make([]game.MoveRecord, 0, snapshot.MoveNumber)
+	for _, move := range moves[:snapshot.MoveNumber] {
+		from, err := game.ParsePosition(move.FromPosition)
+		if err != nil {
+			return nil
+		}
+		to, err := game.ParsePosition(move.ToPosition)
+		if err != nil {
+			return nil
+		}
+		history = append(history, game.MoveRecord{
+			MoveNumber:    move.MoveNumber,
+			From:          from,
+			To:            to,
+			PieceType:     move.PieceType,
+			CapturedPiece: move.CapturedPiece,
+			IsCheck:       move.IsCheck,
+			Timestamp:     move.Timestamp,
+			PlayerID:      move.PlayerID,
+		})
+	}
+
+	engine, err := game.NewGameEngineFromState(g.ID, g.RedPlayerID, g.BlackPlayerID, board, snapshot.CurrentTurn, history, opts...)
+	if err != nil {
+		return nil
+	}
+	engine.SetMovesSinceCapture(snapshot.PliesSinceCapture)
+	engine.SetPositionCounts(snapshot.PositionCounts)
+	return engine
+}
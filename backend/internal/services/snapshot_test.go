@@ -0,0 +1,253 @@
+// Package services provides unit tests for game snapshots.
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
+
+// snapshotTestMoves is an opening sequence used to drive snapshot tests.
+var snapshotTestMoves = [][2]string{
+	{"b0", "c2"}, {"b9", "c7"},
+	{"h0", "g2"}, {"h9", "g7"},
+	{"a0", "b0"}, {"a9", "b9"},
+	{"i0", "h0"}, {"i9", "h9"},
+}
+
+// newTestSnapshotService creates a game service with snapshots every interval
+// moves and an active game "game-1".
+func newTestSnapshotService(t *testing.T, interval int) (*GameService, *mockKeyValueStore, *mockMoveRepository) {
+	t.Helper()
+
+	userRepo := newMockUserRepository()
+	gameRepo := newMockGameRepository()
+	gameRepo.Create(context.Background(), &models.Game{
+		ID:            "game-1",
+		RedPlayerID:   "red-player",
+		BlackPlayerID: "black-player",
+		Status:        models.GameStatusActive,
+	})
+
+	store := newMockKeyValueStore()
+	moveRepo := newMockMoveRepository()
+	service := NewGameServiceWithSnapshots(gameRepo, moveRepo, userRepo, store, interval)
+	return service, store, moveRepo
+}
+
+// recordMoves records moves from snapshotTestMoves up to and including move
+// number upTo, starting after the moves already recorded.
+func recordMoves(t *testing.T, service *GameService, upTo int) {
+	t.Helper()
+	ctx := context.Background()
+
+	recorded, err := service.GetMoves(ctx, "game-1")
+	if err != nil {
+		t.Fatalf("GetMoves failed: %v", err)
+	}
+
+	for i := len(recorded); i < upTo; i++ {
+		playerID := "red-player"
+		if i%2 == 1 {
+			playerID = "black-player"
+		}
+		err := service.RecordMove(ctx, &models.Move{
+			GameID:       "game-1",
+			MoveNumber:   i + 1,
+			PlayerID:     playerID,
+			FromPosition: snapshotTestMoves[i][0],
+			ToPosition:   snapshotTestMoves[i][1],
+		})
+		if err != nil {
+			t.Fatalf("RecordMove %d failed: %v", i+1, err)
+		}
+	}
+}
+
+func TestGameService_SnapshotCadence(t *testing.T) {
+	service, store, _ := newTestSnapshotService(t, 5)
+	key := snapshotKey + "game-1"
+
+	recordMoves(t, service, 4)
+	if got := store.writeCount(key); got != 0 {
+		t.Fatalf("Expected no snapshot before 5 moves, got %d writes", got)
+	}
+
+	recordMoves(t, service, 5)
+	if got := store.writeCount(key); got != 1 {
+		t.Fatalf("Expected exactly one snapshot after 5 moves, got %d writes", got)
+	}
+
+	recordMoves(t, service, 8)
+	if got := store.writeCount(key); got != 1 {
+		t.Errorf("Expected no further snapshot before move 10, got %d writes", got)
+	}
+
+	snapshot, err := service.loadSnapshot(context.Background(), "game-1")
+	if err != nil || snapshot == nil {
+		t.Fatalf("Expected a stored snapshot, got %v (err %v)", snapshot, err)
+	}
+	if snapshot.MoveNumber != 5 {
+		t.Errorf("Expected snapshot at move 5, got %d", snapshot.MoveNumber)
+	}
+	if snapshot.CurrentTurn != models.PlayerColorBlack {
+		t.Errorf("Expected black to move in snapshot, got %s", snapshot.CurrentTurn)
+	}
+}
+
+func TestGameService_SnapshotDisabled(t *testing.T) {
+	service, store, _ := newTestSnapshotService(t, 0)
+
+	recordMoves(t, service, 8)
+	if got := store.writeCount(snapshotKey + "game-1"); got != 0 {
+		t.Errorf("Expected no snapshots with interval 0, got %d writes", got)
+	}
+}
+
+func TestGameService_LoadEngineFromSnapshot(t *testing.T) {
+	service, _, moveRepo := newTestSnapshotService(t, 5)
+	recordMoves(t, service, 7)
+
+	full := NewGameService(service.gameRepo, moveRepo, service.userRepo)
+	expected, err := full.LoadEngine(context.Background(), "game-1")
+	if err != nil {
+		t.Fatalf("Full replay failed: %v", err)
+	}
+
+	// Corrupt a move covered by the snapshot; only the tail should be replayed
+	moveRepo.moves["game-1"][0].ToPosition = "b5"
+
+	engine, err := service.LoadEngine(context.Background(), "game-1")
+	if err != nil {
+		t.Fatalf("LoadEngine failed: %v", err)
+	}
+
+	if engine.GetBoard().String() != expected.GetBoard().String() {
+		t.Errorf("Board mismatch:\nexpected:\n%s\ngot:\n%s", expected.GetBoard(), engine.GetBoard())
+	}
+	if engine.GetCurrentTurn() != expected.GetCurrentTurn() {
+		t.Errorf("Expected turn %s, got %s", expected.GetCurrentTurn(), engine.GetCurrentTurn())
+	}
+	if len(engine.GetMoveHistory()) != 7 {
+		t.Errorf("Expected 7 moves in history, got %d", len(engine.GetMoveHistory()))
+	}
+}
+
+func TestGameService_SnapshotOnRollbackAndGameEnd(t *testing.T) {
+	service, store, _ := newTestSnapshotService(t, 5)
+	ctx := context.Background()
+	key := snapshotKey + "game-1"
+
+	recordMoves(t, service, 6)
+	if err := service.RevertToMove(ctx, "game-1", 4); err != nil {
+		t.Fatalf("RevertToMove failed: %v", err)
+	}
+	if got := store.writeCount(key); got != 2 {
+		t.Fatalf("Expected a snapshot after rollback, got %d writes", got)
+	}
+	if snapshot, _ := service.loadSnapshot(ctx, "game-1"); snapshot == nil || snapshot.MoveNumber != 4 {
+		t.Fatalf("Expected snapshot at move 4 after rollback, got %+v", snapshot)
+	}
+
+	engine, err := service.LoadEngine(ctx, "game-1")
+	if err != nil {
+		t.Fatalf("LoadEngine after rollback failed: %v", err)
+	}
+	if len(engine.GetMoveHistory()) != 4 {
+		t.Errorf("Expected 4 moves after rollback, got %d", len(engine.GetMoveHistory()))
+	}
+
+	if err := service.EndGame(ctx, "game-1", nil, models.ResultTypeDraw); err != nil {
+		t.Fatalf("EndGame failed: %v", err)
+	}
+	if got := store.writeCount(key); got != 3 {
+		t.Errorf("Expected a snapshot at game end, got %d writes", got)
+	}
+}
+
+func TestGameService_SnapshotKeepsNoCaptureCounter(t *testing.T) {
+	service, _, moveRepo := newTestSnapshotService(t, 5)
+	ctx := context.Background()
+
+	// Two captures, then quiet moves; the snapshot at move 5 is three plies
+	// after the last capture
+	moves := [][2]string{
+		{"h2", "h9"}, {"i9", "h9"},
+		{"b0", "c2"}, {"b9", "c7"},
+		{"a0", "a1"}, {"a9", "a8"},
+	}
+	for i, move := range moves {
+		playerID := "red-player"
+		if i%2 == 1 {
+			playerID = "black-player"
+		}
+		err := service.RecordMove(ctx, &models.Move{
+			GameID:       "game-1",
+			MoveNumber:   i + 1,
+			PlayerID:     playerID,
+			FromPosition: move[0],
+			ToPosition:   move[1],
+		})
+		if err != nil {
+			t.Fatalf("RecordMove %d failed: %v", i+1, err)
+		}
+	}
+
+	snapshot, err := service.loadSnapshot(ctx, "game-1")
+	if err != nil || snapshot == nil {
+		t.Fatalf("Expected a stored snapshot, got %v (err %v)", snapshot, err)
+	}
+	if snapshot.PliesSinceCapture != 3 {
+		t.Errorf("Expected 3 plies since capture in snapshot, got %d", snapshot.PliesSinceCapture)
+	}
+
+	// Corrupt the captures covered by the snapshot; the counter must come
+	// from the snapshot rather than a replay
+	moveRepo.moves["game-1"][0].ToPosition = "h5"
+	moveRepo.moves["game-1"][1].ToPosition = "i8"
+
+	engine, err := service.LoadEngine(ctx, "game-1")
+	if err != nil {
+		t.Fatalf("LoadEngine failed: %v", err)
+	}
+	if got := engine.MovesSinceCapture(); got != 4 {
+		t.Errorf("Expected 4 plies since capture after restoring, got %d", got)
+	}
+}
+
+func TestGameService_SnapshotKeepsRepetitionCounts(t *testing.T) {
+	service, _, moveRepo := newTestSnapshotService(t, 5)
+	ctx := context.Background()
+
+	// The starting position recurs after moves 4 and 8, either side of the
+	// snapshot at move 5
+	shuffle := [][2]string{{"b0", "c2"}, {"b9", "c7"}, {"c2", "b0"}, {"c7", "b9"}}
+	for i := 0; i < 8; i++ {
+		playerID := "red-player"
+		if i%2 == 1 {
+			playerID = "black-player"
+		}
+		err := service.RecordMove(ctx, &models.Move{
+			GameID:       "game-1",
+			MoveNumber:   i + 1,
+			PlayerID:     playerID,
+			FromPosition: shuffle[i%4][0],
+			ToPosition:   shuffle[i%4][1],
+		})
+		if err != nil {
+			t.Fatalf("RecordMove %d failed: %v", i+1, err)
+		}
+	}
+
+	// Corrupt a move covered by the snapshot so it cannot be replayed
+	moveRepo.moves["game-1"][0].ToPosition = "b5"
+
+	engine, err := service.LoadEngine(ctx, "game-1")
+	if err != nil {
+		t.Fatalf("LoadEngine failed: %v", err)
+	}
+	if got := engine.RepetitionCount(); got != 3 {
+		t.Errorf("Expected the starting position 3 times after restoring, got %d", got)
+	}
+}
@@ -0,0 +1,100 @@
+// Package services contains business logic for the application.
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
+
+// DefaultStatsCacheTTL is how long server stats are served from cache.
+const DefaultStatsCacheTTL = 30 * time.Second
+
+// QueueSizer reports the number of players waiting for a match. It is
+// implemented by MatchmakingService.
+type QueueSizer interface {
+	QueueSize(ctx context.Context) (int, error)
+}
+
+// ServerStats holds aggregate numbers for the public server stats widget.
+type ServerStats struct {
+	TotalUsers  int `json:"total_users"`
+	GamesToday  int `json:"games_today"`
+	ActiveGames int `json:"active_games"`
+	QueueSize   int `json:"queue_size"`
+}
+
+// StatsService computes aggregate server stats and caches them briefly so
+// that a popular widget does not hit the database on every request.
+type StatsService struct {
+	games    GameCounter
+	users    UserCounter
+	queue    QueueSizer
+	cacheTTL time.Duration
+	now      func() time.Time
+
+	mu       sync.Mutex
+	cached   *ServerStats
+	cachedAt time.Time
+}
+
+// NewStatsService creates a new StatsService caching results for
+// DefaultStatsCacheTTL.
+func NewStatsService(games GameCounter, users UserCounter, queue QueueSizer) *StatsService {
+	return &StatsService{
+		games:    games,
+		users:    users,
+		queue:    queue,
+		cacheTTL: DefaultStatsCacheTTL,
+		now:      time.Now,
+	}
+}
+
+// GetServerStats returns the current server stats, served from cache when
+// they were computed less than the cache TTL ago. Games played today counts
+// games created since midnight UTC.
+func (s *StatsService) GetServerStats(ctx context.Context) (*ServerStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if s.cached != nil && now.Sub(s.cachedAt) < s.cacheTTL {
+		stats := *s.cached
+		return &stats, nil
+	}
+
+	totalUsers, err := s.users.Count(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	startOfDay := now.UTC().Truncate(24 * time.Hour)
+	gamesToday, err := s.games.CountCreatedSince(ctx, startOfDay)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count games today: %w", err)
+	}
+
+	activeGames, err := s.games.CountByStatus(ctx, models.GameStatusActive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count active games: %w", err)
+	}
+
+	queueSize, err := s.queue.QueueSize(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get queue size: %w", err)
+	}
+
+	s.cached = &ServerStats{
+		TotalUsers:  totalUsers,
+		GamesToday:  gamesToday,
+		ActiveGames: activeGames,
+		QueueSize:   queueSize,
+	}
+	s.cachedAt = now
+
+	stats := *s.cached
+	return &stats, nil
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"github.com/xiangqi/chinese-chess-backend/internal/models"
@@ -15,12 +16,35 @@ import (
 
 // UserService handles user business logic.
 type UserService struct {
-	userRepo *repository.UserRepository
+	userRepo UserRepository
+
+	// renameInterval is the minimum time between display name changes.
+	// Changes are not limited when zero.
+	renameInterval time.Duration
+
+	// ratingBounds keeps ratings between a floor and a ceiling.
+	ratingBounds RatingBounds
 }
 
 // NewUserService creates a new UserService.
-func NewUserService(userRepo *repository.UserRepository) *UserService {
-	return &UserService{userRepo: userRepo}
+func NewUserService(userRepo UserRepository) *UserService {
+	return &UserService{
+		userRepo:     userRepo,
+		ratingBounds: NewRatingBounds(0, 0),
+	}
+}
+
+// SetRatingBounds sets the floor and ceiling ratings are kept within.
+func (s *UserService) SetRatingBounds(bounds RatingBounds) {
+	s.ratingBounds = bounds
+}
+
+// NewUserServiceWithRenameInterval creates a new UserService that allows a
+// user to change their display name at most once per renameInterval.
+func NewUserServiceWithRenameInterval(userRepo UserRepository, renameInterval time.Duration) *UserService {
+	service := NewUserService(userRepo)
+	service.renameInterval = renameInterval
+	return service
 }
 
 // Register creates a new user or returns existing user.
@@ -48,6 +72,9 @@ func (s *UserService) Register(ctx context.Context, deviceID, displayName string
 		Wins:        0,
 		Losses:      0,
 		Draws:       0,
+
+		Rating:          models.DefaultRating,
+		RatingDeviation: models.DefaultRatingDeviation,
 	}
 
 	if err := s.userRepo.Create(ctx, user); err != nil {
@@ -85,8 +112,57 @@ func (s *UserService) UpdateDisplayName(ctx context.Context, deviceID, displayNa
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
+	// Keeping the current name is not a rename
+	if displayName == user.DisplayName {
+		return user, nil
+	}
+
+	now := time.Now()
+	if s.renameInterval > 0 && user.DisplayNameChangedAt != nil &&
+		now.Before(user.DisplayNameChangedAt.Add(s.renameInterval)) {
+		return nil, ErrRenameTooSoon
+	}
+
 	// Update display name
 	user.DisplayName = displayName
+	user.DisplayNameChangedAt = &now
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	return user, nil
+}
+
+// SetAutoRematch updates whether a user automatically accepts rematch requests.
+func (s *UserService) SetAutoRematch(ctx context.Context, deviceID string, enabled bool) (*models.User, error) {
+	user, err := s.userRepo.GetByID(ctx, deviceID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	user.AutoRematch = enabled
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	return user, nil
+}
+
+// SetResignSuggestions updates whether a user is sent resign suggestions
+// in hopeless positions.
+func (s *UserService) SetResignSuggestions(ctx context.Context, deviceID string, enabled bool) (*models.User, error) {
+	user, err := s.userRepo.GetByID(ctx, deviceID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	user.ResignSuggestions = enabled
 	if err := s.userRepo.Update(ctx, user); err != nil {
 		return nil, fmt.Errorf("failed to update user: %w", err)
 	}
@@ -96,6 +172,12 @@ func (s *UserService) UpdateDisplayName(ctx context.Context, deviceID, displayNa
 
 // UpdateStats updates a user's game statistics.
 func (s *UserService) UpdateStats(ctx context.Context, deviceID string, result GameResult) error {
+	return s.UpdateStatsWithResultType(ctx, deviceID, result, "")
+}
+
+// UpdateStatsWithResultType updates a user's game statistics and, for a win,
+// counts it in the win breakdown under the way the game ended.
+func (s *UserService) UpdateStatsWithResultType(ctx context.Context, deviceID string, result GameResult, resultType models.ResultType) error {
 	user, err := s.userRepo.GetByID(ctx, deviceID)
 	if err != nil {
 		return fmt.Errorf("failed to get user: %w", err)
@@ -105,6 +187,7 @@ func (s *UserService) UpdateStats(ctx context.Context, deviceID string, result G
 	switch result {
 	case GameResultWin:
 		user.Wins++
+		user.WinBreakdown.Add(resultType)
 	case GameResultLoss:
 		user.Losses++
 	case GameResultDraw:
@@ -114,6 +197,35 @@ func (s *UserService) UpdateStats(ctx context.Context, deviceID string, result G
 	return s.userRepo.UpdateStats(ctx, deviceID, user.Stats())
 }
 
+// ApplyGameRatings updates both players' ratings after a ranked game, given
+// the result for the first player, with UpdateRatings. Both changes are based
+// on the ratings from before the game. Each game also narrows both players'
+// rating deviations.
+func (s *UserService) ApplyGameRatings(ctx context.Context, playerID, opponentID string, result GameResult) error {
+	user, err := s.userRepo.GetByID(ctx, playerID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	opponent, err := s.userRepo.GetByID(ctx, opponentID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	user.Rating, opponent.Rating = UpdateRatings(currentRating(user), currentRating(opponent), result, s.ratingBounds)
+	for _, u := range []*models.User{user, opponent} {
+		deviation := u.RatingDeviation
+		if deviation == 0 {
+			deviation = models.DefaultRatingDeviation
+		}
+		u.RatingDeviation = narrowRatingDeviation(deviation)
+	}
+
+	if err := s.userRepo.UpdateStats(ctx, playerID, user.Stats()); err != nil {
+		return err
+	}
+	return s.userRepo.UpdateStats(ctx, opponentID, opponent.Stats())
+}
+
 // ValidateDisplayName validates a display name.
 func (s *UserService) ValidateDisplayName(name string) error {
 	// Length check (3-20 characters)
@@ -159,4 +271,5 @@ var (
 	ErrDisplayNameTooLong      = errors.New("display name must be at most 20 characters")
 	ErrDisplayNameInvalidChars = errors.New("display name can only contain letters, numbers, underscores, and hyphens")
 	ErrDisplayNameReserved     = errors.New("display name contains a reserved word")
+	ErrRenameTooSoon           = errors.New("display name was changed too recently")
 )
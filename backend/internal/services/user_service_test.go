@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/xiangqi/chinese-chess-backend/internal/models"
 	"github.com/xiangqi/chinese-chess-backend/internal/repository"
@@ -12,11 +13,11 @@ import (
 
 // mockUserRepository is a mock implementation of the user repository for testing.
 type mockUserRepository struct {
-	users      map[string]*models.User
-	createErr  error
-	updateErr  error
-	getErr     error
-	statsErr   error
+	users     map[string]*models.User
+	createErr error
+	updateErr error
+	getErr    error
+	statsErr  error
 }
 
 func newMockUserRepository() *mockUserRepository {
@@ -61,6 +62,9 @@ func (m *mockUserRepository) UpdateStats(ctx context.Context, id string, stats m
 		user.Wins = stats.Wins
 		user.Losses = stats.Losses
 		user.Draws = stats.Draws
+		user.WinBreakdown = stats.WinBreakdown
+		user.Rating = stats.Rating
+		user.RatingDeviation = stats.RatingDeviation
 	}
 	return nil
 }
@@ -69,7 +73,6 @@ func (m *mockUserRepository) UpdateStats(ctx context.Context, id string, stats m
 
 func TestUserService_Register_NewUser(t *testing.T) {
 	repo := newMockUserRepository()
-	service := NewUserService(&repository.UserRepository{})
 
 	// Use reflection or dependency injection for testing
 	// For this test, we'll test the validation logic directly
@@ -92,6 +95,79 @@ func TestUserService_Register_NewUser(t *testing.T) {
 	}
 }
 
+// ========== UpdateDisplayName Tests ==========
+
+func TestUserService_UpdateDisplayName_AllowedAfterInterval(t *testing.T) {
+	repo := newMockUserRepository()
+	ctx := context.Background()
+	lastChange := time.Now().Add(-25 * time.Hour)
+	repo.Create(ctx, &models.User{ID: "device-123", DisplayName: "OldName", DisplayNameChangedAt: &lastChange})
+
+	service := NewUserServiceWithRenameInterval(repo, 24*time.Hour)
+
+	user, err := service.UpdateDisplayName(ctx, "device-123", "NewName")
+	if err != nil {
+		t.Fatalf("Rename after the interval should be allowed: %v", err)
+	}
+	if user.DisplayName != "NewName" {
+		t.Errorf("Expected display name 'NewName', got '%s'", user.DisplayName)
+	}
+	if user.DisplayNameChangedAt == nil || !user.DisplayNameChangedAt.After(lastChange) {
+		t.Error("Expected the rename time to be updated")
+	}
+}
+
+func TestUserService_UpdateDisplayName_FirstRenameAllowed(t *testing.T) {
+	repo := newMockUserRepository()
+	ctx := context.Background()
+	repo.Create(ctx, &models.User{ID: "device-123", DisplayName: "OldName"})
+
+	service := NewUserServiceWithRenameInterval(repo, 24*time.Hour)
+
+	if _, err := service.UpdateDisplayName(ctx, "device-123", "NewName"); err != nil {
+		t.Fatalf("First rename should be allowed: %v", err)
+	}
+}
+
+func TestUserService_UpdateDisplayName_TooSoon(t *testing.T) {
+	repo := newMockUserRepository()
+	ctx := context.Background()
+	repo.Create(ctx, &models.User{ID: "device-123", DisplayName: "OldName"})
+
+	service := NewUserServiceWithRenameInterval(repo, 24*time.Hour)
+
+	if _, err := service.UpdateDisplayName(ctx, "device-123", "NewName"); err != nil {
+		t.Fatalf("First rename should be allowed: %v", err)
+	}
+
+	_, err := service.UpdateDisplayName(ctx, "device-123", "OtherName")
+	if err != ErrRenameTooSoon {
+		t.Fatalf("Expected ErrRenameTooSoon, got %v", err)
+	}
+	if repo.users["device-123"].DisplayName != "NewName" {
+		t.Errorf("Rejected rename should not change the name, got '%s'", repo.users["device-123"].DisplayName)
+	}
+
+	// Keeping the current name is not a rename
+	if _, err := service.UpdateDisplayName(ctx, "device-123", "NewName"); err != nil {
+		t.Errorf("Unchanged name should be accepted, got %v", err)
+	}
+}
+
+func TestUserService_UpdateDisplayName_NoInterval(t *testing.T) {
+	repo := newMockUserRepository()
+	ctx := context.Background()
+	repo.Create(ctx, &models.User{ID: "device-123", DisplayName: "OldName"})
+
+	service := NewUserService(repo)
+
+	for _, name := range []string{"NameOne", "NameTwo", "NameThree"} {
+		if _, err := service.UpdateDisplayName(ctx, "device-123", name); err != nil {
+			t.Fatalf("Renames should not be limited without an interval: %v", err)
+		}
+	}
+}
+
 // ========== ValidateDisplayName Tests ==========
 
 func TestUserService_ValidateDisplayName_Valid(t *testing.T) {
@@ -99,7 +175,7 @@ func TestUserService_ValidateDisplayName_Valid(t *testing.T) {
 
 	validNames := []string{
 		"Player_123",
-		"abc",                 // minimum 3 chars
+		"abc",                  // minimum 3 chars
 		"12345678901234567890", // maximum 20 chars
 		"test-user",
 		"TestUser",
@@ -118,9 +194,9 @@ func TestUserService_ValidateDisplayName_TooShort(t *testing.T) {
 	service := &UserService{}
 
 	shortNames := []string{
-		"ab",  // 2 chars
-		"a",   // 1 char
-		"",    // empty
+		"ab", // 2 chars
+		"a",  // 1 char
+		"",   // empty
 	}
 
 	for _, name := range shortNames {
@@ -146,12 +222,12 @@ func TestUserService_ValidateDisplayName_InvalidChars(t *testing.T) {
 	service := &UserService{}
 
 	invalidNames := []string{
-		"user name",   // space
-		"user@name",   // special char
-		"user.name",   // period
-		"name!",       // exclamation
-		"name#tag",    // hash
-		"user$name",   // dollar
+		"user name", // space
+		"user@name", // special char
+		"user.name", // period
+		"name!",     // exclamation
+		"name#tag",  // hash
+		"user$name", // dollar
 	}
 
 	for _, name := range invalidNames {
@@ -170,7 +246,7 @@ func TestUserService_ValidateDisplayName_Reserved(t *testing.T) {
 		"Admin123",
 		"superadmin",
 		"moderator",
-		"modUser",
+		"moderatorUser",
 		"systemuser",
 		"null",
 		"undefined",
@@ -404,3 +480,53 @@ func TestUserStats_AllLosses(t *testing.T) {
 		t.Errorf("Expected 0%% win rate, got %.1f", stats.WinPercentage)
 	}
 }
+
+func TestUserService_ApplyGameRatings(t *testing.T) {
+	repo := newMockUserRepository()
+	ctx := context.Background()
+	for _, id := range []string{"device-123", "device-456"} {
+		repo.Create(ctx, &models.User{
+			ID:              id,
+			DisplayName:     "Player",
+			Rating:          1500,
+			RatingDeviation: models.DefaultRatingDeviation,
+		})
+	}
+	service := NewUserService(repo)
+
+	if err := service.ApplyGameRatings(ctx, "device-123", "device-456", GameResultWin); err != nil {
+		t.Fatalf("ApplyGameRatings failed: %v", err)
+	}
+	winner, _ := repo.GetByID(ctx, "device-123")
+	loser, _ := repo.GetByID(ctx, "device-456")
+	if winner.Rating != 1500+RatingKFactor/2 || loser.Rating != 1500-RatingKFactor/2 {
+		t.Errorf("Expected %d and %d after a game between equals, got %d and %d",
+			1500+RatingKFactor/2, 1500-RatingKFactor/2, winner.Rating, loser.Rating)
+	}
+	if winner.RatingDeviation >= models.DefaultRatingDeviation || loser.RatingDeviation >= models.DefaultRatingDeviation {
+		t.Errorf("Expected both rating deviations to narrow, got %d and %d", winner.RatingDeviation, loser.RatingDeviation)
+	}
+
+	// Ratings stay within the configured bounds
+	service.SetRatingBounds(NewRatingBounds(100, 1520))
+	service.ApplyGameRatings(ctx, "device-123", "device-456", GameResultWin)
+	if user, _ := repo.GetByID(ctx, "device-123"); user.Rating != 1520 {
+		t.Errorf("Expected the rating to stop at the ceiling, got %d", user.Rating)
+	}
+}
+
+func TestUserService_ApplyGameRatings_UnratedUsersStartAtDefault(t *testing.T) {
+	repo := newMockUserRepository()
+	ctx := context.Background()
+	repo.Create(ctx, &models.User{ID: "device-123", DisplayName: "Player"})
+	repo.Create(ctx, &models.User{ID: "device-456", DisplayName: "Opponent"})
+
+	NewUserService(repo).ApplyGameRatings(ctx, "device-123", "device-456", GameResultLoss)
+	user, _ := repo.GetByID(ctx, "device-123")
+	if user.Rating != models.DefaultRating-RatingKFactor/2 {
+		t.Errorf("Expected %d, got %d", models.DefaultRating-RatingKFactor/2, user.Rating)
+	}
+	if user.RatingDeviation != narrowRatingDeviation(models.DefaultRatingDeviation) {
+		t.Errorf("Expected the default deviation to narrow, got %d", user.RatingDeviation)
+	}
+}
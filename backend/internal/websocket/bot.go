@@ -0,0 +1,78 @@
+// Package websocket handles WebSocket connections for real-time gameplay.
+package websocket
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
+
+const (
+	// botMoveTimeout bounds the computer's search for a move.
+	botMoveTimeout = 30 * time.Second
+
+	// botSendBuffer is how many messages the room can send the computer's
+	// seat between two of its moves.
+	botSendBuffer = 16
+)
+
+// newBotClient returns the seat of the computer opponent in a bot game.
+// It has no connection: the room plays its moves and discards what it is
+// sent.
+func newBotClient(room *GameRoom) *Client {
+	return &Client{
+		Hub:      room.Hub,
+		Send:     make(chan []byte, botSendBuffer),
+		GameID:   room.GameID,
+		DeviceID: room.Game.BlackPlayerID,
+		Role:     ClientRolePlayer,
+	}
+}
+
+// discardBotMessages empties the computer's send buffer.
+func discardBotMessages(bot *Client) {
+	for {
+		select {
+		case <-bot.Send:
+		default:
+			return
+		}
+	}
+}
+
+// scheduleBotMove searches for the computer's move in the background when
+// it is black's turn in a bot game, and plays it unless the game has moved
+// on in the meantime. Callers must hold r.mu.
+func (r *GameRoom) scheduleBotMove() {
+	if r.bot == nil || r.botSearching || !r.started || r.IsGameOver || r.CurrentTurn != models.PlayerColorBlack {
+		return
+	}
+	r.botSearching = true
+	moveCount := r.MoveCount
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), botMoveTimeout)
+		defer cancel()
+
+		move, err := r.GameService.BotMove(ctx, r.GameID)
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.botSearching = false
+
+		if err != nil {
+			log.Error().Err(err).Str("game_id", r.GameID).Msg("Failed to find computer move")
+			return
+		}
+
+		// A rollback or the end of the game makes the search stale
+		if r.IsGameOver || r.MoveCount != moveCount {
+			return
+		}
+		r.makeMove(r.bot, move.From.Notation(), move.To.Notation(), "", 0)
+		discardBotMessages(r.bot)
+	}()
+}
@@ -3,26 +3,46 @@ package websocket
 
 import (
 	"encoding/json"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/rs/zerolog/log"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/game"
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
 )
 
 const (
 	// Time allowed to write a message to the peer.
 	writeWait = 10 * time.Second
 
-	// Time allowed to read the next pong message from the peer.
+	// Time allowed to read the next pong message from a player.
 	pongWait = 60 * time.Second
 
-	// Send pings to peer with this period. Must be less than pongWait.
+	// Send pings to players with this period. Must be less than pongWait.
 	pingPeriod = (pongWait * 9) / 10
 
+	// Time allowed to read the next pong message from a spectator. A dropped
+	// spectator does not affect the game, so liveness checks are relaxed to
+	// cut ping traffic for large audiences.
+	spectatorPongWait = 3 * time.Minute
+
+	// Send pings to spectators with this period. Must be less than spectatorPongWait.
+	spectatorPingPeriod = (spectatorPongWait * 9) / 10
+
 	// Maximum message size allowed from peer.
 	maxMessageSize = 512
 )
 
+// ClientRole is the role a client connects to a game with.
+type ClientRole string
+
+const (
+	ClientRolePlayer    ClientRole = "player"
+	ClientRoleSpectator ClientRole = "spectator"
+)
+
 // Client represents a WebSocket client connection.
 type Client struct {
 	Hub      *Hub
@@ -30,17 +50,73 @@ type Client struct {
 	Send     chan []byte
 	GameID   string
 	DeviceID string
+	Role     ClientRole
+
+	// CompressionThreshold is the minimum batch size in bytes that is sent
+	// compressed when permessage-deflate was negotiated. Smaller messages
+	// are sent uncompressed since deflate gains little on them.
+	CompressionThreshold int
+
+	// Heartbeat timing, chosen by role
+	pongWait   time.Duration
+	pingPeriod time.Duration
+
+	// limiter caps incoming messages per second; created on first use
+	limiter *messageLimiter
+
+	// leaving is set by a message handler to disconnect the client once the
+	// current message is handled. Only touched by the ReadPump goroutine.
+	leaving bool
+
+	// lastMove is the latest sequenced move applied for this client, so
+	// retransmissions are acknowledged instead of played again. Guarded by
+	// the game room's lock.
+	lastMove moveSeq
+
+	// latency is the rolling average of the round trips of the client's
+	// heartbeat pings. Guarded by the game room's lock.
+	latency latencyAverage
+
+	// watching is set once the room accepts the client as a spectator. The
+	// hub only fans room broadcasts out to spectators who are watching, so
+	// the spectator cap cannot be sidestepped by never joining.
+	watching atomic.Bool
+
+	// pingSentAt is when the heartbeat ping awaiting its pong was sent, in
+	// Unix nanoseconds, or zero. WritePump sets it and ReadPump clears it.
+	pingSentAt atomic.Int64
 }
 
-// NewClient creates a new client.
+// moveSeq pairs a client's move sequence number with the move number it
+// produced.
+type moveSeq struct {
+	seq        int64
+	moveNumber int
+}
+
+// NewClient creates a new player client.
 func NewClient(hub *Hub, conn *websocket.Conn, gameID, deviceID string) *Client {
-	return &Client{
-		Hub:      hub,
-		Conn:     conn,
-		Send:     make(chan []byte, 256),
-		GameID:   gameID,
-		DeviceID: deviceID,
+	return NewClientWithRole(hub, conn, gameID, deviceID, ClientRolePlayer)
+}
+
+// NewClientWithRole creates a new client with the heartbeat timing for its
+// role. Players get tight liveness detection; spectators a relaxed schedule.
+func NewClientWithRole(hub *Hub, conn *websocket.Conn, gameID, deviceID string, role ClientRole) *Client {
+	client := &Client{
+		Hub:        hub,
+		Conn:       conn,
+		Send:       make(chan []byte, 256),
+		GameID:     gameID,
+		DeviceID:   deviceID,
+		Role:       role,
+		pongWait:   pongWait,
+		pingPeriod: pingPeriod,
 	}
+	if role == ClientRoleSpectator {
+		client.pongWait = spectatorPongWait
+		client.pingPeriod = spectatorPingPeriod
+	}
+	return client
 }
 
 // ReadPump pumps messages from the WebSocket connection to the hub.
@@ -51,9 +127,10 @@ func (c *Client) ReadPump() {
 	}()
 
 	c.Conn.SetReadLimit(maxMessageSize)
-	c.Conn.SetReadDeadline(time.Now().Add(pongWait))
-	c.Conn.SetPongHandler(func(string) error {
-		c.Conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.Conn.SetReadDeadline(time.Now().Add(c.pongWait))
+	c.Conn.SetPongHandler(func(data string) error {
+		c.Conn.SetReadDeadline(time.Now().Add(c.pongWait))
+		c.handlePong(data, time.Now())
 		return nil
 	})
 
@@ -68,12 +145,17 @@ func (c *Client) ReadPump() {
 
 		// Handle incoming message
 		c.handleMessage(message)
+
+		// Stopping unregisters the client from the hub
+		if c.leaving {
+			break
+		}
 	}
 }
 
 // WritePump pumps messages from the hub to the WebSocket connection.
 func (c *Client) WritePump() {
-	ticker := time.NewTicker(pingPeriod)
+	ticker := time.NewTicker(c.pingPeriod)
 	defer func() {
 		ticker.Stop()
 		c.Conn.Close()
@@ -89,17 +171,28 @@ func (c *Client) WritePump() {
 				return
 			}
 
+			// Add queued messages to the current websocket message
+			batch := [][]byte{message}
+			size := len(message)
+			n := len(c.Send)
+			for i := 0; i < n; i++ {
+				queued := <-c.Send
+				batch = append(batch, queued)
+				size += len(queued) + 1
+			}
+
+			// Only has an effect when compression was negotiated
+			c.Conn.EnableWriteCompression(size >= c.CompressionThreshold)
+
 			w, err := c.Conn.NextWriter(websocket.TextMessage)
 			if err != nil {
 				return
 			}
-			w.Write(message)
-
-			// Add queued messages to the current websocket message
-			n := len(c.Send)
-			for i := 0; i < n; i++ {
-				w.Write([]byte{'\n'})
-				w.Write(<-c.Send)
+			for i, m := range batch {
+				if i > 0 {
+					w.Write([]byte{'\n'})
+				}
+				w.Write(m)
 			}
 
 			if err := w.Close(); err != nil {
@@ -108,7 +201,7 @@ func (c *Client) WritePump() {
 
 		case <-ticker.C:
 			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+			if err := c.Conn.WriteMessage(websocket.PingMessage, c.pingData(time.Now())); err != nil {
 				return
 			}
 		}
@@ -117,6 +210,10 @@ func (c *Client) WritePump() {
 
 // handleMessage processes an incoming message from the client.
 func (c *Client) handleMessage(data []byte) {
+	if !c.allowMessage() {
+		return
+	}
+
 	var msg IncomingMessage
 	if err := json.Unmarshal(data, &msg); err != nil {
 		log.Error().Err(err).Str("data", string(data)).Msg("Failed to parse message")
@@ -130,11 +227,18 @@ func (c *Client) handleMessage(data []byte) {
 		Str("device_id", c.DeviceID).
 		Msg("Received message")
 
+	if c.Role == ClientRoleSpectator && playerOnlyMessages[msg.Type] {
+		c.sendError("spectator", "Spectators cannot send "+msg.Type+" messages")
+		return
+	}
+
 	switch msg.Type {
 	case "join":
 		c.handleJoin(msg.Payload)
 	case "move":
-		c.handleMove(msg.Payload)
+		c.handleMove(msg.Payload, msg.Seq)
+	case "premove":
+		c.handlePremove(msg.Payload)
 	case "rollback_request":
 		c.handleRollbackRequest(msg.Payload)
 	case "rollback_response":
@@ -145,26 +249,98 @@ func (c *Client) handleMessage(data []byte) {
 		c.handleDrawResponse(msg.Payload)
 	case "resign":
 		c.handleResign(msg.Payload)
+	case "resync":
+		c.handleResync(msg.Payload)
+	case "get_valid_moves":
+		c.handleGetValidMoves(msg.Payload)
+	case "get_forced_move":
+		c.handleGetForcedMove()
+	case "chat":
+		c.handleChat(msg.Payload)
 	case "ping":
-		c.handlePing()
+		c.handlePing(msg.Payload)
 	default:
 		c.sendError("unknown_type", "Unknown message type: "+msg.Type)
 	}
 }
 
+// playerOnlyMessages are the message types that act on the game and are
+// rejected from spectators.
+var playerOnlyMessages = map[string]bool{
+	"move":              true,
+	"premove":           true,
+	"resign":            true,
+	"rollback_request":  true,
+	"rollback_response": true,
+	"draw_offer":        true,
+	"draw_response":     true,
+	"get_valid_moves":   true,
+	"get_forced_move":   true,
+}
+
+// allowMessage applies the hub's per-client message rate limit. Excess
+// messages are dropped with a single error per second; a client that keeps
+// flooding is disconnected.
+func (c *Client) allowMessage() bool {
+	if c.Hub == nil || c.Hub.messageRateLimit <= 0 {
+		return true
+	}
+	if c.limiter == nil {
+		c.limiter = newMessageLimiter(c.Hub.messageRateLimit)
+	}
+
+	allowed, first := c.limiter.allow()
+	if allowed {
+		return true
+	}
+
+	if c.limiter.abusive() {
+		log.Warn().
+			Str("game_id", c.GameID).
+			Str("device_id", c.DeviceID).
+			Msg("Disconnecting client for sustained message flooding")
+		if c.Conn != nil {
+			c.Conn.Close()
+		}
+		return false
+	}
+
+	if first {
+		c.sendError("rate_limited", "Too many messages, please slow down")
+	}
+	return false
+}
+
 // Message handlers
 
 func (c *Client) handleJoin(payload json.RawMessage) {
 	// Get or create game room
+	// A client that cannot be seated is disconnected rather than left
+	// registered in the hub, where it would still receive the game's updates
 	room, err := c.Hub.GetOrCreateRoom(c.GameID)
 	if err != nil {
 		c.sendError("game_not_found", "Game not found")
+		c.leaving = true
+		return
+	}
+
+	if c.Role == ClientRoleSpectator {
+		if err := room.AddSpectator(c); err != nil {
+			c.sendError("join_failed", err.Error())
+			c.leaving = true
+			return
+		}
+		log.Info().
+			Str("game_id", c.GameID).
+			Str("device_id", c.DeviceID).
+			Msg("Spectator joined game")
 		return
 	}
 
 	// Join the room
 	if err := room.JoinPlayer(c); err != nil {
 		c.sendError("join_failed", err.Error())
+		c.leaving = true
 		return
 	}
 
@@ -175,12 +351,28 @@ func (c *Client) handleJoin(payload json.RawMessage) {
 		Msg("Player joined game")
 }
 
-func (c *Client) handleMove(payload json.RawMessage) {
-	var move MovePayload
+func (c *Client) handleMove(payload json.RawMessage, seq int64) {
+	var move models.MovePayload
 	if err := json.Unmarshal(payload, &move); err != nil {
 		c.sendError("invalid_move", "Invalid move format")
 		return
 	}
+	// The sequence number may be sent in the payload or on the message
+	if move.Seq == 0 {
+		move.Seq = seq
+	}
+
+	// Normalize positions so client quirks like "E4" are stored canonically
+	from, err := game.NormalizePosition(move.From)
+	if err != nil {
+		c.sendError("invalid_position", "Invalid from position: "+err.Error())
+		return
+	}
+	to, err := game.NormalizePosition(move.To)
+	if err != nil {
+		c.sendError("invalid_position", "Invalid to position: "+err.Error())
+		return
+	}
 
 	// Get the game room
 	room := c.Hub.GetRoom(c.GameID)
@@ -190,7 +382,36 @@ func (c *Client) handleMove(payload json.RawMessage) {
 	}
 
 	// Delegate move handling to the room
-	room.HandleMove(c, move.From, move.To, move.PieceType)
+	room.HandleMove(c, from, to, move.PieceType, move.Seq)
+}
+
+func (c *Client) handlePremove(payload json.RawMessage) {
+	var move models.MovePayload
+	if err := json.Unmarshal(payload, &move); err != nil {
+		c.sendError("invalid_move", "Invalid move format")
+		return
+	}
+
+	from, err := game.NormalizePosition(move.From)
+	if err != nil {
+		c.sendError("invalid_position", "Invalid from position: "+err.Error())
+		return
+	}
+	to, err := game.NormalizePosition(move.To)
+	if err != nil {
+		c.sendError("invalid_position", "Invalid to position: "+err.Error())
+		return
+	}
+
+	// Get the game room
+	room := c.Hub.GetRoom(c.GameID)
+	if room == nil {
+		c.sendError("room_not_found", "Game room not found")
+		return
+	}
+
+	// Delegate to room
+	room.HandlePremove(c, from, to, move.PieceType)
 }
 
 func (c *Client) handleRollbackRequest(payload json.RawMessage) {
@@ -269,13 +490,91 @@ func (c *Client) handleResign(payload json.RawMessage) {
 	room.HandleResign(c)
 }
 
-func (c *Client) handlePing() {
+func (c *Client) handleResync(payload json.RawMessage) {
+	var request ResyncPayload
+	if err := json.Unmarshal(payload, &request); err != nil {
+		c.sendError("invalid_resync", "Invalid resync format")
+		return
+	}
+
+	// Get the game room
+	room := c.Hub.GetRoom(c.GameID)
+	if room == nil {
+		c.sendError("room_not_found", "Game room not found")
+		return
+	}
+
+	// Delegate to room
+	room.HandleResync(c, request.LastMoveNumber)
+}
+
+func (c *Client) handleGetValidMoves(payload json.RawMessage) {
+	var request GetValidMovesPayload
+	if err := json.Unmarshal(payload, &request); err != nil {
+		c.sendError("invalid_valid_moves", "Invalid valid moves request format")
+		return
+	}
+
+	// Get the game room
+	room := c.Hub.GetRoom(c.GameID)
+	if room == nil {
+		c.sendError("room_not_found", "Game room not found")
+		return
+	}
+
+	// Delegate to room
+	room.HandleGetValidMoves(c, request.From)
+}
+
+func (c *Client) handleGetForcedMove() {
+	// Get the game room
+	room := c.Hub.GetRoom(c.GameID)
+	if room == nil {
+		c.sendError("room_not_found", "Game room not found")
+		return
+	}
+
+	// Delegate to room
+	room.HandleGetForcedMove(c)
+}
+
+func (c *Client) handleChat(payload json.RawMessage) {
+	var chat ChatPayload
+	if err := json.Unmarshal(payload, &chat); err != nil {
+		c.sendError("invalid_chat", "Invalid chat format")
+		return
+	}
+
+	// Get the game room
+	room := c.Hub.GetRoom(c.GameID)
+	if room == nil {
+		c.sendError("room_not_found", "Game room not found")
+		return
+	}
+
+	// Delegate to room
+	room.HandleChat(c, chat.Text)
+}
+
+func (c *Client) handlePing(payload json.RawMessage) {
+	var ping PingPayload
+	if len(payload) > 0 && string(payload) != "null" {
+		if err := json.Unmarshal(payload, &ping); err != nil {
+			c.sendError("invalid_ping", "Invalid ping format")
+			return
+		}
+	}
+
+	pong := map[string]interface{}{
+		"server_time": models.FormatTimestamp(time.Now()),
+	}
+	if ping.ClientTime != 0 {
+		pong["client_time"] = ping.ClientTime
+	}
 	c.send(OutgoingMessage{
-		Type: "pong",
-		Payload: map[string]interface{}{
-			"server_time": time.Now().Format(time.RFC3339),
-		},
-		Timestamp: time.Now(),
+		Type:      "pong",
+		Payload:   pong,
+		Timestamp: models.FormatTimestamp(time.Now()),
 		MessageID: generateMessageID(),
 	})
 }
@@ -298,7 +597,7 @@ func (c *Client) sendError(code, message string) {
 			"code":    code,
 			"message": message,
 		},
-		Timestamp: time.Now(),
+		Timestamp: models.FormatTimestamp(time.Now()),
 		MessageID: generateMessageID(),
 	})
 }
@@ -306,26 +605,45 @@ func (c *Client) sendError(code, message string) {
 // Message types
 
 // IncomingMessage represents a message from a client.
+// Seq is an optional client sequence number, currently used for moves.
 type IncomingMessage struct {
 	Type      string          `json:"type"`
 	Payload   json.RawMessage `json:"payload"`
 	Timestamp time.Time       `json:"timestamp"`
 	MessageID string          `json:"message_id"`
+	Seq       int64           `json:"seq,omitempty"`
 }
 
 // OutgoingMessage represents a message to a client.
 type OutgoingMessage struct {
 	Type      string                 `json:"type"`
 	Payload   map[string]interface{} `json:"payload"`
-	Timestamp time.Time              `json:"timestamp"`
+	Timestamp string                 `json:"timestamp"`
 	MessageID string                 `json:"message_id"`
 }
 
-// MovePayload represents a move message payload.
-type MovePayload struct {
-	From      string `json:"from"`
-	To        string `json:"to"`
-	PieceType string `json:"piece_type"`
+// ResyncPayload represents a resync request payload.
+// LastMoveNumber is the number of the last move the client has applied.
+type ResyncPayload struct {
+	LastMoveNumber int `json:"last_move_number"`
+}
+
+// GetValidMovesPayload represents a request for the legal destinations of
+// the piece on From.
+type GetValidMovesPayload struct {
+	From string `json:"from"`
+}
+
+// PingPayload represents a ping. ClientTime is the client's clock in
+// milliseconds, echoed back in the pong so the client can time the round
+// trip.
+type PingPayload struct {
+	ClientTime int64 `json:"client_time"`
+}
+
+// ChatPayload represents a chat message from a player.
+type ChatPayload struct {
+	Text string `json:"text"`
 }
 
 // generateMessageID generates a unique message ID.
@@ -0,0 +1,307 @@
+// Package websocket provides unit tests for WebSocket clients.
+package websocket
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/config"
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
+
+func TestClient_MessagesStayInUpgradedGame(t *testing.T) {
+	g := newTestGame(t, config.RulesConfig{})
+	room := g.room(t)
+
+	// The same players have a second game; the client is upgraded for it
+	otherGame := &models.Game{
+		ID:                 "game-2",
+		RedPlayerID:        g.game.RedPlayerID,
+		BlackPlayerID:      g.game.BlackPlayerID,
+		Status:             models.GameStatusActive,
+		TurnTimeoutSeconds: 300,
+	}
+	g.gameRepo.Create(context.Background(), otherGame)
+	t.Cleanup(func() { g.hub.RemoveRoom(otherGame.ID) })
+
+	client := newTestClient(g.hub, otherGame.ID, g.game.RedPlayerID)
+
+	// Payloads naming another game are ignored; routing uses the upgraded game
+	client.handleMessage([]byte(`{"type":"join","payload":{"game_id":"game-1"}}`))
+	client.handleMessage([]byte(`{"type":"move","payload":{"game_id":"game-1","from":"h2","to":"e2","piece_type":"cannon"}}`))
+
+	if room.RedPlayer != nil {
+		t.Error("Client should not be seated in a game it was not upgraded for")
+	}
+	if room.MoveCount != 0 {
+		t.Errorf("Game-1 room should have no moves, got %d", room.MoveCount)
+	}
+	if moves, _ := g.moveRepo.GetByGameID(context.Background(), g.game.ID); len(moves) != 0 {
+		t.Errorf("No moves should be stored for game-1, got %d", len(moves))
+	}
+
+	otherRoom := g.hub.GetRoom(otherGame.ID)
+	if otherRoom == nil || otherRoom.RedPlayer != client {
+		t.Fatal("Client should be seated in the game it was upgraded for")
+	}
+	if moves, _ := g.moveRepo.GetByGameID(context.Background(), otherGame.ID); len(moves) != 1 {
+		t.Errorf("Move should be stored for game-2, got %d moves", len(moves))
+	}
+}
+
+func TestClient_FailedJoinLeavesCleanly(t *testing.T) {
+	g := newTestGame(t, config.RulesConfig{})
+	room := g.room(t)
+	client := newTestClient(g.hub, g.game.ID, "stranger")
+	g.hub.registerClient(client)
+
+	client.handleMessage([]byte(`{"type":"join"}`))
+
+	msg := nextMessage(t, client)
+	if msg.Type != "error" || msg.Payload["code"] != "join_failed" {
+		t.Fatalf("Expected join_failed error, got %s %v", msg.Type, msg.Payload)
+	}
+	if !client.leaving {
+		t.Error("Client should be disconnected after a failed join")
+	}
+	if room.RedPlayer != nil || room.BlackPlayer != nil {
+		t.Error("Client should not be seated after a failed join")
+	}
+
+	// Moves that arrive before the connection closes are still rejected
+	client.handleMessage([]byte(`{"type":"move","payload":{"from":"h2","to":"e2","piece_type":"cannon"}}`))
+	msg = nextMessage(t, client)
+	if msg.Type != "error" || msg.Payload["code"] != "not_joined" {
+		t.Errorf("Expected not_joined error, got %s %v", msg.Type, msg.Payload)
+	}
+	if room.MoveCount != 0 {
+		t.Errorf("Expected no moves, got %d", room.MoveCount)
+	}
+
+	// ReadPump then unregisters the client, leaving the room untouched
+	g.hub.unregisterClient(client)
+	if clients := g.hub.GetClientsInGame(g.game.ID); len(clients) != 0 {
+		t.Errorf("Expected no clients registered, got %d", len(clients))
+	}
+	if room.DisconnectedPlayer != "" {
+		t.Errorf("Failed join should not be treated as a disconnection, got %q", room.DisconnectedPlayer)
+	}
+}
+
+func TestClient_JoinUnknownGameLeaves(t *testing.T) {
+	g := newTestGame(t, config.RulesConfig{})
+	client := newTestClient(g.hub, "missing-game", g.game.RedPlayerID)
+
+	client.handleMessage([]byte(`{"type":"join"}`))
+
+	msg := nextMessage(t, client)
+	if msg.Type != "error" || msg.Payload["code"] != "game_not_found" {
+		t.Fatalf("Expected game_not_found error, got %s %v", msg.Type, msg.Payload)
+	}
+	if !client.leaving {
+		t.Error("Client should be disconnected after joining an unknown game")
+	}
+}
+
+func TestClient_HeartbeatTimingByRole(t *testing.T) {
+	player := NewClient(nil, nil, "game-1", "red-player")
+	if player.Role != ClientRolePlayer {
+		t.Errorf("Expected NewClient to create a player, got %s", player.Role)
+	}
+	if player.pongWait != pongWait || player.pingPeriod != pingPeriod {
+		t.Errorf("Expected player timing %v/%v, got %v/%v", pongWait, pingPeriod, player.pongWait, player.pingPeriod)
+	}
+
+	spectator := NewClientWithRole(nil, nil, "game-1", "viewer", ClientRoleSpectator)
+	if spectator.pongWait != spectatorPongWait || spectator.pingPeriod != spectatorPingPeriod {
+		t.Errorf("Expected spectator timing %v/%v, got %v/%v",
+			spectatorPongWait, spectatorPingPeriod, spectator.pongWait, spectator.pingPeriod)
+	}
+	if spectator.pongWait <= player.pongWait || spectator.pingPeriod <= player.pingPeriod {
+		t.Error("Spectators should use a more relaxed heartbeat than players")
+	}
+	if spectator.pingPeriod >= spectator.pongWait {
+		t.Error("Spectator ping period must be shorter than the pong wait")
+	}
+}
+
+func TestClient_MessageRateLimit(t *testing.T) {
+	g := newTestGame(t, config.RulesConfig{})
+	g.hub.SetMessageRateLimit(3)
+	client := newTestClient(g.hub, g.game.ID, g.game.RedPlayerID)
+
+	for i := 0; i < 3; i++ {
+		client.handleMessage([]byte(`{"type":"ping"}`))
+		if msg := nextMessage(t, client); msg.Type != "pong" {
+			t.Fatalf("Expected pong within the limit, got %s", msg.Type)
+		}
+	}
+
+	// The fourth message in the same second is dropped with an error, and
+	// further excess is dropped silently
+	client.handleMessage([]byte(`{"type":"ping"}`))
+	msg := nextMessage(t, client)
+	if msg.Type != "error" || msg.Payload["code"] != "rate_limited" {
+		t.Errorf("Expected rate_limited error, got %s %v", msg.Type, msg.Payload)
+	}
+	client.handleMessage([]byte(`{"type":"ping"}`))
+	expectNoMessage(t, client)
+}
+
+func TestMessageLimiter_SustainedAbuse(t *testing.T) {
+	limiter := newMessageLimiter(2)
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	for window := 0; window < maxRateStrikes; window++ {
+		if limiter.abusive() {
+			t.Fatalf("Client flagged after only %d windows", window)
+		}
+		for i := 0; i < 5; i++ {
+			limiter.allow()
+		}
+		now = now.Add(time.Second)
+	}
+	if !limiter.abusive() {
+		t.Error("Expected sustained flooding to be flagged")
+	}
+
+	// A quiet window clears the strikes
+	now = now.Add(3 * time.Second)
+	if allowed, _ := limiter.allow(); !allowed || limiter.abusive() {
+		t.Error("Expected the limiter to recover after the client slowed down")
+	}
+}
+
+// newTestSpectator registers a joined spectator client for the game and
+// discards the game state it is sent on joining.
+func newTestSpectator(t *testing.T, g *testGame, deviceID string) *Client {
+	t.Helper()
+	spectator := newTestClient(g.hub, g.game.ID, deviceID)
+	spectator.Role = ClientRoleSpectator
+	g.hub.registerClient(spectator)
+
+	spectator.handleMessage([]byte(`{"type":"join"}`))
+	if msg := nextMessage(t, spectator); msg.Type != "game_state" {
+		t.Fatalf("Expected game_state on joining, got %s %v", msg.Type, msg.Payload)
+	}
+	return spectator
+}
+
+// deliverBroadcasts hands the hub's pending broadcasts to the room's
+// clients, as the hub's run loop would.
+func deliverBroadcasts(hub *Hub) {
+	for {
+		select {
+		case broadcast := <-hub.broadcast:
+			hub.broadcastToRoom(broadcast)
+		default:
+			return
+		}
+	}
+}
+
+func TestClient_SpectatorCannotPlay(t *testing.T) {
+	g := newTestGame(t, config.RulesConfig{})
+	room := g.room(t)
+	red := newTestClient(g.hub, g.game.ID, g.game.RedPlayerID)
+	black := newTestClient(g.hub, g.game.ID, g.game.BlackPlayerID)
+	room.RedPlayer, room.BlackPlayer = red, black
+
+	// Watching from the red player's device still gives no seat
+	spectator := newTestSpectator(t, g, g.game.RedPlayerID)
+	if room.RedPlayer != red {
+		t.Fatal("Spectator should not replace the seated red player")
+	}
+
+	for _, message := range []string{
+		`{"type":"move","payload":{"from":"h2","to":"e2","piece_type":"cannon"}}`,
+		`{"type":"resign"}`,
+		`{"type":"draw_offer"}`,
+		`{"type":"rollback_request"}`,
+	} {
+		spectator.handleMessage([]byte(message))
+		msg := nextMessage(t, spectator)
+		if msg.Type != "error" || msg.Payload["code"] != "spectator" {
+			t.Errorf("Expected spectator error for %s, got %s %v", message, msg.Type, msg.Payload)
+		}
+	}
+
+	if room.MoveCount != 0 || room.IsGameOver {
+		t.Errorf("Spectator messages should not change the game, got %d moves, over=%v", room.MoveCount, room.IsGameOver)
+	}
+	if moves, _ := g.moveRepo.GetByGameID(context.Background(), g.game.ID); len(moves) != 0 {
+		t.Errorf("No moves should be stored, got %d", len(moves))
+	}
+	if counts := countBroadcasts(g.hub); counts["draw_offered"] != 0 || counts["rollback_requested"] != 0 {
+		t.Errorf("Spectator requests should not reach the players, got %v", counts)
+	}
+}
+
+func TestClient_SpectatorReceivesBroadcasts(t *testing.T) {
+	g := newTestGame(t, config.RulesConfig{})
+	room := g.room(t)
+	red := newTestClient(g.hub, g.game.ID, g.game.RedPlayerID)
+	black := newTestClient(g.hub, g.game.ID, g.game.BlackPlayerID)
+	g.hub.registerClient(red)
+	g.hub.registerClient(black)
+	room.RedPlayer, room.BlackPlayer = red, black
+	drainMessageTypes(t, red)
+	drainMessageTypes(t, black)
+
+	spectator := newTestSpectator(t, g, "viewer")
+	if room.SpectatorCount() != 1 {
+		t.Fatalf("Expected 1 spectator, got %d", room.SpectatorCount())
+	}
+	// Spectators arriving are not announced to the players
+	expectNoMessage(t, red)
+	if opponent := g.hub.GetOpponent(g.game.ID, g.game.RedPlayerID); opponent != black {
+		t.Error("A spectator should never be reported as a player's opponent")
+	}
+
+	red.handleMessage([]byte(`{"type":"move","payload":{"from":"h2","to":"e2","piece_type":"cannon"}}`))
+	deliverBroadcasts(g.hub)
+
+	msg := nextMessage(t, spectator)
+	if msg.Type != "opponent_move" || msg.Payload["from"] != "h2" || msg.Payload["to"] != "e2" {
+		t.Errorf("Expected the move to reach the spectator, got %s %v", msg.Type, msg.Payload)
+	}
+
+	// Leaving frees the spectator's place without affecting the game
+	g.hub.unregisterClient(spectator)
+	if room.SpectatorCount() != 0 {
+		t.Errorf("Expected no spectators after leaving, got %d", room.SpectatorCount())
+	}
+	if room.DisconnectedPlayer != "" {
+		t.Errorf("A spectator leaving should not count as a disconnection, got %q", room.DisconnectedPlayer)
+	}
+}
+
+func TestClient_SpectatorGetsNoBroadcastsUntilAccepted(t *testing.T) {
+	g := newTestGame(t, config.RulesConfig{})
+	g.hub.SetMaxSpectators(1)
+	room := g.room(t)
+	red := newTestClient(g.hub, g.game.ID, g.game.RedPlayerID)
+	black := newTestClient(g.hub, g.game.ID, g.game.BlackPlayerID)
+	room.RedPlayer, room.BlackPlayer = red, black
+
+	// One spectator never joins; another is turned away by the cap
+	lurker := newTestClient(g.hub, g.game.ID, "lurker")
+	lurker.Role = ClientRoleSpectator
+	g.hub.registerClient(lurker)
+	newTestSpectator(t, g, "viewer")
+	late := newTestClient(g.hub, g.game.ID, "late")
+	late.Role = ClientRoleSpectator
+	g.hub.registerClient(late)
+	late.handleMessage([]byte(`{"type":"join"}`))
+	if msg := nextMessage(t, late); msg.Type != "error" {
+		t.Fatalf("Expected the spectator over the cap to be refused, got %s %v", msg.Type, msg.Payload)
+	}
+
+	red.handleMessage([]byte(`{"type":"move","payload":{"from":"h2","to":"e2","piece_type":"cannon"}}`))
+	deliverBroadcasts(g.hub)
+
+	expectNoMessage(t, lurker)
+	expectNoMessage(t, late)
+}
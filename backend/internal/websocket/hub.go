@@ -4,9 +4,12 @@ package websocket
 import (
 	"context"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/rs/zerolog/log"
 
+	"github.com/xiangqi/chinese-chess-backend/internal/config"
 	"github.com/xiangqi/chinese-chess-backend/internal/models"
 	"github.com/xiangqi/chinese-chess-backend/internal/services"
 )
@@ -31,6 +34,33 @@ type Hub struct {
 	// Room manager for game rooms with timers and state
 	roomManager *RoomManager
 
+	// Optional game rules applied to every room
+	rules config.RulesConfig
+
+	// Number of accepted connections that have not been unregistered yet
+	connections atomic.Int64
+
+	// Maximum number of concurrent connections; 0 means unlimited
+	maxConnections int64
+
+	// Messages each client may send per second; 0 means unlimited
+	messageRateLimit int
+
+	// How often a waiting player is told the grace left for a disconnected
+	// opponent; 0 disables the countdown
+	disconnectCountdown time.Duration
+
+	// Maximum number of spectators per game; 0 means unlimited
+	maxSpectators int
+
+	// Optional signer of the tokens players reconnect with; nil disables
+	// reconnection tokens
+	reconnectTokens *services.ReconnectTokens
+
+	// Optional matchmaking service told when a player opens a matched
+	// game, so it is not abandoned as unclaimed
+	matchmaking *services.MatchmakingService
+
 	// Mutex for thread-safe operations
 	mu sync.RWMutex
 
@@ -46,7 +76,7 @@ type BroadcastMessage struct {
 }
 
 // NewHub creates a new Hub.
-func NewHub(gameService *services.GameService) *Hub {
+func NewHub(gameService *services.GameService, rules config.RulesConfig) *Hub {
 	return &Hub{
 		rooms:       make(map[string]map[*Client]bool),
 		broadcast:   make(chan *BroadcastMessage, 256),
@@ -54,10 +84,107 @@ func NewHub(gameService *services.GameService) *Hub {
 		unregister:  make(chan *Client),
 		gameService: gameService,
 		roomManager: NewRoomManager(),
+		rules:       rules,
 		shutdown:    make(chan struct{}),
 	}
 }
 
+// playerReservePercent is the share of the connection cap kept free for
+// players, so spectators are turned away first when the server is busy.
+const playerReservePercent = 10
+
+// SetMaxConnections sets the maximum number of concurrent connections.
+// Zero disables the limit.
+func (h *Hub) SetMaxConnections(max int) {
+	h.maxConnections = int64(max)
+}
+
+// SetMessageRateLimit sets how many messages each client may send per
+// second. Zero disables the limit.
+func (h *Hub) SetMessageRateLimit(perSecond int) {
+	h.messageRateLimit = perSecond
+}
+
+// SetDisconnectCountdownInterval sets how often rooms created afterwards
+// tell a waiting player the grace remaining for a disconnected opponent.
+// Zero disables the countdown.
+func (h *Hub) SetDisconnectCountdownInterval(interval time.Duration) {
+	h.disconnectCountdown = interval
+}
+
+// SetMaxSpectators sets how many spectators rooms created afterwards admit.
+// Zero disables the limit.
+func (h *Hub) SetMaxSpectators(max int) {
+	h.maxSpectators = max
+}
+
+// SetReconnectTokens sets the signer of the reconnection tokens sent to
+// players when they join a game.
+func (h *Hub) SetReconnectTokens(tokens *services.ReconnectTokens) {
+	h.reconnectTokens = tokens
+}
+
+// SetMatchmakingService sets the matchmaking service told when a player
+// opens a matched game.
+func (h *Hub) SetMatchmakingService(matchmaking *services.MatchmakingService) {
+	h.matchmaking = matchmaking
+}
+
+// markOpened tells the matchmaking service that a player opened the game.
+func (h *Hub) markOpened(gameID string) {
+	if h == nil || h.matchmaking == nil {
+		return
+	}
+	if err := h.matchmaking.MarkOpened(context.Background(), gameID); err != nil {
+		log.Warn().Err(err).Str("game_id", gameID).Msg("Failed to mark game opened")
+	}
+}
+
+// IsReconnection reports whether a player has already been seated in the
+// game, in this hub's room or as recorded by the reconnection tokens, so a
+// new connection for them is a reconnection.
+func (h *Hub) IsReconnection(ctx context.Context, gameID, deviceID string) bool {
+	if room := h.roomManager.GetRoom(gameID); room != nil && room.HasJoined(deviceID) {
+		return true
+	}
+	return h.reconnectTokens != nil && h.reconnectTokens.WasSeated(ctx, gameID, deviceID)
+}
+
+// AcquireConnection reserves a connection slot, reporting false when the
+// server is at capacity. Spectators are limited to the part of the cap not
+// reserved for players. Each acquired slot is released when the client is
+// unregistered, or with ReleaseConnection if the client is never registered.
+func (h *Hub) AcquireConnection(isPlayer bool) bool {
+	limit := h.maxConnections
+	if limit <= 0 {
+		h.connections.Add(1)
+		return true
+	}
+	if !isPlayer {
+		limit -= limit * playerReservePercent / 100
+	}
+
+	for {
+		current := h.connections.Load()
+		if current >= limit {
+			return false
+		}
+		if h.connections.CompareAndSwap(current, current+1) {
+			return true
+		}
+	}
+}
+
+// ReleaseConnection frees a connection slot acquired with AcquireConnection.
+func (h *Hub) ReleaseConnection() {
+	h.connections.Add(-1)
+}
+
+// ConnectionCount returns the number of active connections.
+func (h *Hub) ConnectionCount() int {
+	return int(h.connections.Load())
+}
+
 // GetRoomManager returns the room manager.
 func (h *Hub) GetRoomManager() *RoomManager {
 	return h.roomManager
@@ -144,9 +271,10 @@ func (h *Hub) Register(client *Client) {
 	h.register <- client
 }
 
-// Unregister removes a client from the hub.
+// Unregister removes a client from the hub and frees its connection slot.
 func (h *Hub) Unregister(client *Client) {
 	h.unregister <- client
+	h.ReleaseConnection()
 }
 
 // Broadcast sends a message to all clients in a game room.
@@ -191,7 +319,7 @@ func (h *Hub) GetOpponent(gameID string, deviceID string) *Client {
 	}
 
 	for client := range room {
-		if client.DeviceID != deviceID {
+		if client.DeviceID != deviceID && client.Role != ClientRoleSpectator {
 			return client
 		}
 	}
@@ -234,7 +362,11 @@ func (h *Hub) unregisterClient(client *Client) {
 
 			// Notify the game room for disconnection handling
 			if gameRoom := h.roomManager.GetRoom(client.GameID); gameRoom != nil {
-				gameRoom.LeavePlayer(client)
+				if client.Role == ClientRoleSpectator {
+					gameRoom.RemoveSpectator(client)
+				} else {
+					gameRoom.LeavePlayer(client)
+				}
 			}
 
 			// Notify other players in the room
@@ -263,6 +395,10 @@ func (h *Hub) broadcastToRoom(message *BroadcastMessage) {
 		if message.Sender != nil && client == message.Sender {
 			continue
 		}
+		// Spectators only receive the game once the room accepts them
+		if client.Role == ClientRoleSpectator && !client.watching.Load() {
+			continue
+		}
 
 		select {
 		case client.Send <- message.Message:
@@ -274,10 +410,11 @@ func (h *Hub) broadcastToRoom(message *BroadcastMessage) {
 	}
 }
 
-// notifyRoomOfConnection notifies other players when someone connects/disconnects.
+// notifyRoomOfConnection notifies other players when a player connects or
+// disconnects. Spectators coming and going are not announced.
 func (h *Hub) notifyRoomOfConnection(client *Client, connected bool) {
 	room := h.rooms[client.GameID]
-	if room == nil {
+	if room == nil || client.Role == ClientRoleSpectator {
 		return
 	}
 
@@ -291,7 +428,7 @@ func (h *Hub) notifyRoomOfConnection(client *Client, connected bool) {
 	message := []byte(`{"type":"connection_status","payload":{"` + messageType + `":true}}`)
 
 	for other := range room {
-		if other != client {
+		if other != client && other.Role != ClientRoleSpectator {
 			select {
 			case other.Send <- message:
 			default:
@@ -0,0 +1,51 @@
+// Package websocket provides unit tests for the hub.
+package websocket
+
+import (
+	"testing"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/config"
+)
+
+func TestHub_AcquireConnection_PlayersTakePriority(t *testing.T) {
+	hub := newTestGame(t, config.RulesConfig{}).hub
+	hub.SetMaxConnections(10)
+
+	// Spectators may fill the cap up to the player reserve
+	for i := 0; i < 9; i++ {
+		if !hub.AcquireConnection(false) {
+			t.Fatalf("Spectator connection %d should be accepted", i+1)
+		}
+	}
+	if hub.AcquireConnection(false) {
+		t.Error("Spectators should not use the slots reserved for players")
+	}
+
+	if !hub.AcquireConnection(true) {
+		t.Error("Player should get a reserved slot")
+	}
+	if hub.AcquireConnection(true) {
+		t.Error("Players should be rejected once the cap is reached")
+	}
+
+	hub.ReleaseConnection()
+	if !hub.AcquireConnection(true) {
+		t.Error("Released slot should be available again")
+	}
+	if hub.ConnectionCount() != 10 {
+		t.Errorf("Expected 10 connections, got %d", hub.ConnectionCount())
+	}
+}
+
+func TestHub_AcquireConnection_Unlimited(t *testing.T) {
+	hub := newTestGame(t, config.RulesConfig{}).hub
+
+	for i := 0; i < 100; i++ {
+		if !hub.AcquireConnection(false) {
+			t.Fatal("Connections should not be limited without a cap")
+		}
+	}
+	if hub.ConnectionCount() != 100 {
+		t.Errorf("Expected 100 connections, got %d", hub.ConnectionCount())
+	}
+}
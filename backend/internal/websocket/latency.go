@@ -0,0 +1,72 @@
+// Package websocket handles WebSocket connections for real-time gameplay.
+package websocket
+
+import (
+	"strconv"
+	"time"
+)
+
+const (
+	// latencySampleCount is how many recent round trips a client's latency
+	// is averaged over.
+	latencySampleCount = 5
+
+	// maxLatencySample is the longest round trip counted; longer ones are
+	// treated as this long.
+	maxLatencySample = 10 * time.Second
+
+	// maxLatencyGrace caps the free time a player gets each turn for the
+	// delay of their moves reaching the server.
+	maxLatencyGrace = 2 * time.Second
+)
+
+// latencyAverage is the rolling average of a client's most recent round
+// trips. It is guarded by the game room's lock.
+type latencyAverage struct {
+	samples [latencySampleCount]time.Duration
+	count   int // samples recorded, up to latencySampleCount
+	next    int // index the next sample is written to
+}
+
+// add records a round trip and returns the new average.
+func (l *latencyAverage) add(rtt time.Duration) time.Duration {
+	l.samples[l.next] = min(rtt, maxLatencySample)
+	l.next = (l.next + 1) % latencySampleCount
+	if l.count < latencySampleCount {
+		l.count++
+	}
+	return l.average()
+}
+
+// average returns the average round trip, or zero if none was recorded.
+func (l *latencyAverage) average() time.Duration {
+	if l.count == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, sample := range l.samples[:l.count] {
+		total += sample
+	}
+	return total / time.Duration(l.count)
+}
+
+// pingData stamps a heartbeat ping with the time it is sent. The client's
+// pong echoes it back, so the round trip is timed on the server's clock.
+func (c *Client) pingData(now time.Time) []byte {
+	sent := now.UnixNano()
+	c.pingSentAt.Store(sent)
+	return []byte(strconv.FormatInt(sent, 10))
+}
+
+// handlePong records the round trip of the latest heartbeat ping. A pong
+// that does not echo it, or echoes it a second time, is ignored, so a
+// client cannot pass off an older ping's send time as its round trip.
+func (c *Client) handlePong(data string, now time.Time) {
+	sent := c.pingSentAt.Load()
+	if sent == 0 || data != strconv.FormatInt(sent, 10) || !c.pingSentAt.CompareAndSwap(sent, 0) {
+		return
+	}
+	if room := c.Hub.GetRoom(c.GameID); room != nil {
+		room.RecordLatency(c, now.Sub(time.Unix(0, sent)))
+	}
+}
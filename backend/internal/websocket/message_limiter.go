@@ -0,0 +1,58 @@
+// Package websocket handles WebSocket connections for real-time gameplay.
+package websocket
+
+import "time"
+
+// maxRateStrikes is how many consecutive one-second windows a client may
+// exceed its message rate in before it is disconnected.
+const maxRateStrikes = 5
+
+// messageLimiter limits how many messages a client may send per second. It
+// is only used from the client's read loop, so it needs no locking.
+type messageLimiter struct {
+	limit       int
+	windowStart time.Time
+	count       int
+	exceeded    bool // the limit was exceeded in the current window
+	strikes     int  // consecutive windows in which the limit was exceeded
+	now         func() time.Time
+}
+
+// newMessageLimiter creates a limiter allowing limit messages per second.
+func newMessageLimiter(limit int) *messageLimiter {
+	return &messageLimiter{limit: limit, now: time.Now}
+}
+
+// allow records a message and reports whether it is within the limit. first
+// is true for the first message over the limit in a window, so the client
+// is warned once rather than for every dropped message.
+func (l *messageLimiter) allow() (allowed, first bool) {
+	now := l.now()
+	if elapsed := now.Sub(l.windowStart); elapsed >= time.Second {
+		// Strikes only accumulate over back-to-back windows
+		if !l.exceeded || elapsed >= 2*time.Second {
+			l.strikes = 0
+		}
+		l.windowStart = now
+		l.count = 0
+		l.exceeded = false
+	}
+
+	l.count++
+	if l.count <= l.limit {
+		return true, false
+	}
+
+	if !l.exceeded {
+		l.exceeded = true
+		l.strikes++
+		return false, true
+	}
+	return false, false
+}
+
+// abusive reports whether the client has exceeded its rate for long enough
+// that it should be disconnected.
+func (l *messageLimiter) abusive() bool {
+	return l.strikes >= maxRateStrikes
+}
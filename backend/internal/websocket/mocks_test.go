@@ -0,0 +1,275 @@
+// Package websocket provides shared test doubles for the WebSocket tests.
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/config"
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+	"github.com/xiangqi/chinese-chess-backend/internal/repository"
+	"github.com/xiangqi/chinese-chess-backend/internal/services"
+)
+
+// mockGameRepository is an in-memory game repository for testing.
+type mockGameRepository struct {
+	mu    sync.Mutex
+	games map[string]*models.Game
+}
+
+func newMockGameRepository() *mockGameRepository {
+	return &mockGameRepository{games: make(map[string]*models.Game)}
+}
+
+func (m *mockGameRepository) Create(ctx context.Context, game *models.Game) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.games[game.ID] = game
+	return nil
+}
+
+func (m *mockGameRepository) CreateWithMoves(ctx context.Context, game *models.Game, moves []*models.Move) error {
+	return m.Create(ctx, game)
+}
+
+func (m *mockGameRepository) GetByID(ctx context.Context, id string) (*models.Game, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	game, ok := m.games[id]
+	if !ok {
+		return nil, repository.ErrGameNotFound
+	}
+	copied := *game
+	return &copied, nil
+}
+
+func (m *mockGameRepository) Update(ctx context.Context, game *models.Game) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	copied := *game
+	m.games[game.ID] = &copied
+	return nil
+}
+
+func (m *mockGameRepository) GetHistoryByPlayer(ctx context.Context, playerID string, limit, offset int) ([]*models.Game, error) {
+	return nil, nil
+}
+
+func (m *mockGameRepository) CountByPlayer(ctx context.Context, playerID string) (int, error) {
+	return 0, nil
+}
+
+func (m *mockGameRepository) GetActiveByPlayer(ctx context.Context, playerID string) ([]*models.Game, error) {
+	return nil, nil
+}
+
+func (m *mockGameRepository) GetLivePublic(ctx context.Context, limit, offset int) ([]*models.Game, error) {
+	return nil, nil
+}
+
+func (m *mockGameRepository) CountLivePublic(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+// mockMoveRepository is an in-memory move repository for testing.
+type mockMoveRepository struct {
+	mu    sync.Mutex
+	moves map[string][]*models.Move
+}
+
+func newMockMoveRepository() *mockMoveRepository {
+	return &mockMoveRepository{moves: make(map[string][]*models.Move)}
+}
+
+func (m *mockMoveRepository) Create(ctx context.Context, move *models.Move) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.moves[move.GameID] = append(m.moves[move.GameID], move)
+	return nil
+}
+
+func (m *mockMoveRepository) GetByGameID(ctx context.Context, gameID string) ([]*models.Move, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	moves := make([]*models.Move, len(m.moves[gameID]))
+	copy(moves, m.moves[gameID])
+	return moves, nil
+}
+
+func (m *mockMoveRepository) DeleteAfterMoveNumber(ctx context.Context, gameID string, moveNumber int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var kept []*models.Move
+	for _, move := range m.moves[gameID] {
+		if move.MoveNumber <= moveNumber {
+			kept = append(kept, move)
+		}
+	}
+	m.moves[gameID] = kept
+	return nil
+}
+
+// mockUserRepository is an in-memory user repository for testing.
+type mockUserRepository struct {
+	mu    sync.Mutex
+	users map[string]*models.User
+}
+
+func newMockUserRepository() *mockUserRepository {
+	return &mockUserRepository{users: make(map[string]*models.User)}
+}
+
+func (m *mockUserRepository) Create(ctx context.Context, user *models.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.users[user.ID] = user
+	return nil
+}
+
+func (m *mockUserRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, ok := m.users[id]
+	if !ok {
+		return nil, repository.ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (m *mockUserRepository) Update(ctx context.Context, user *models.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.users[user.ID] = user
+	return nil
+}
+
+func (m *mockUserRepository) UpdateStats(ctx context.Context, id string, stats models.UserStats) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if user, ok := m.users[id]; ok {
+		user.TotalGames = stats.TotalGames
+		user.Wins = stats.Wins
+		user.Losses = stats.Losses
+		user.Draws = stats.Draws
+		user.WinBreakdown = stats.WinBreakdown
+		user.Rating = stats.Rating
+		user.RatingDeviation = stats.RatingDeviation
+	}
+	return nil
+}
+
+// testGame bundles a hub, an active game and its repositories for room tests.
+type testGame struct {
+	hub      *Hub
+	gameRepo *mockGameRepository
+	moveRepo *mockMoveRepository
+	userRepo *mockUserRepository
+	game     *models.Game
+}
+
+// newTestGame creates a hub backed by mock repositories with an active game
+// "game-1" between "red-player" and "black-player".
+func newTestGame(t *testing.T, rules config.RulesConfig) *testGame {
+	t.Helper()
+
+	gameRepo := newMockGameRepository()
+	moveRepo := newMockMoveRepository()
+	userRepo := newMockUserRepository()
+	ctx := context.Background()
+	userRepo.Create(ctx, &models.User{ID: "red-player", DisplayName: "RedPlayer"})
+	userRepo.Create(ctx, &models.User{ID: "black-player", DisplayName: "BlackPlayer"})
+
+	game := &models.Game{
+		ID:                      "game-1",
+		RedPlayerID:             "red-player",
+		BlackPlayerID:           "black-player",
+		Status:                  models.GameStatusActive,
+		TurnTimeoutSeconds:      300,
+		RedRollbacksRemaining:   3,
+		BlackRollbacksRemaining: 3,
+		AssistEnabled:           true,
+	}
+	gameRepo.Create(ctx, game)
+
+	gameService := services.NewGameService(gameRepo, moveRepo, userRepo)
+	hub := NewHub(gameService, rules)
+
+	return &testGame{
+		hub:      hub,
+		gameRepo: gameRepo,
+		moveRepo: moveRepo,
+		userRepo: userRepo,
+		game:     game,
+	}
+}
+
+// room returns the game room, creating it on first use, and registers
+// cleanup of its timers with the test.
+func (g *testGame) room(t *testing.T) *GameRoom {
+	t.Helper()
+	room, err := g.hub.GetOrCreateRoom(g.game.ID)
+	if err != nil {
+		t.Fatalf("Failed to create room: %v", err)
+	}
+	t.Cleanup(func() { g.hub.RemoveRoom(g.game.ID) })
+	return room
+}
+
+// playMoves stores moves directly in the move repository as if they had been
+// played, alternating red and black starting with red.
+func (g *testGame) playMoves(moves ...[2]string) {
+	for i, m := range moves {
+		playerID := g.game.RedPlayerID
+		if i%2 == 1 {
+			playerID = g.game.BlackPlayerID
+		}
+		g.moveRepo.Create(context.Background(), &models.Move{
+			GameID:       g.game.ID,
+			MoveNumber:   i + 1,
+			PlayerID:     playerID,
+			FromPosition: m[0],
+			ToPosition:   m[1],
+		})
+	}
+}
+
+// newTestClient creates a client without a connection whose outgoing
+// messages can be read from its Send channel.
+func newTestClient(hub *Hub, gameID, deviceID string) *Client {
+	return &Client{
+		Hub:      hub,
+		Send:     make(chan []byte, 256),
+		GameID:   gameID,
+		DeviceID: deviceID,
+	}
+}
+
+// nextMessage returns the next message sent to the client, failing the test
+// if none arrives in time.
+func nextMessage(t *testing.T, client *Client) OutgoingMessage {
+	t.Helper()
+	select {
+	case data := <-client.Send:
+		var msg OutgoingMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatalf("Failed to unmarshal message: %v", err)
+		}
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for message")
+	}
+	return OutgoingMessage{}
+}
+
+// expectNoMessage fails the test if the client has a pending message.
+func expectNoMessage(t *testing.T, client *Client) {
+	t.Helper()
+	select {
+	case data := <-client.Send:
+		t.Fatalf("Expected no message, got %s", data)
+	default:
+	}
+}
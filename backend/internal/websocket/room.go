@@ -4,11 +4,18 @@ package websocket
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"math"
+	"strings"
 	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/rs/zerolog/log"
 
+	"github.com/xiangqi/chinese-chess-backend/internal/config"
+	"github.com/xiangqi/chinese-chess-backend/internal/game"
 	"github.com/xiangqi/chinese-chess-backend/internal/models"
 	"github.com/xiangqi/chinese-chess-backend/internal/services"
 )
@@ -21,26 +28,69 @@ type GameRoom struct {
 	GameService  *services.GameService
 	Timer        *GameTimer
 	TimerManager *TimerManager
+	Rules        config.RulesConfig
 
 	// Connected players
 	RedPlayer   *Client
 	BlackPlayer *Client
 
+	// bot is the computer's seat in a bot game, always seated as black;
+	// botSearching is set while it looks for its next move
+	bot          *Client
+	botSearching bool
+
+	// Connected spectators, capped at MaxSpectators unless it is 0
+	spectators    map[*Client]bool
+	MaxSpectators int
+
+	// engine holds the board the room validates moves against. It is
+	// rebuilt from the stored moves when the room is created and after a
+	// rollback.
+	engine *game.GameEngine
+
 	// Game state
-	CurrentTurn     models.PlayerColor
-	MoveCount       int
-	GameState       *models.GameState
-	IsGameOver      bool
+	CurrentTurn models.PlayerColor
+	MoveCount   int
+	GameState   *models.GameState
+	IsGameOver  bool
+	started     bool
+
+	// hopelessMoves counts each player's consecutive hopeless positions
+	// for resign suggestions
+	hopelessMoves map[string]int
+
+	// chatLimiters caps each connected player's chat messages per second
+	chatLimiters map[*Client]*messageLimiter
+
+	// premoves holds the move each connected player queued during their
+	// opponent's turn, played as soon as the turn passes to them
+	premoves map[*Client]premove
+
+	// departedMoves keeps the last sequenced move of players who left, so
+	// their next connection still recognizes retransmitted moves
+	departedMoves map[string]moveSeq
 
 	// Rollback state
-	PendingRollback    *RollbackRequest
-	RollbackTimeout    *time.Timer
+	PendingRollback *RollbackRequest
+	RollbackTimeout *time.Timer
+
+	// Draw offer state; an unanswered offer is declined after
+	// DrawOfferWindow
+	PendingDrawOffer *DrawOffer
+	DrawOfferTimeout *time.Timer
+	DrawOfferWindow  time.Duration
 
 	// Disconnection handling
 	DisconnectedPlayer string
 	DisconnectTimer    *time.Timer
 	GracePeriod        time.Duration
 
+	// CountdownInterval is how often the waiting player is told the grace
+	// remaining; 0 disables the countdown
+	CountdownInterval time.Duration
+	disconnectedAt    time.Time
+	stopCountdown     chan struct{}
+
 	mu sync.RWMutex
 }
 
@@ -52,6 +102,17 @@ type RollbackRequest struct {
 	TimeoutSeconds     int
 }
 
+// DrawOffer represents a pending draw offer.
+type DrawOffer struct {
+	OfferingPlayerID string
+	OfferedAt        time.Time
+	TimeoutSeconds   int
+}
+
+// ErrSpectatorsFull is returned when a game already has as many spectators
+// as its room allows.
+var ErrSpectatorsFull = errors.New("game has reached its spectator limit")
+
 // RoomManager manages all active game rooms.
 type RoomManager struct {
 	rooms        map[string]*GameRoom
@@ -72,8 +133,19 @@ func (m *RoomManager) CreateRoom(gameID string, game *models.Game, hub *Hub, gam
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// Create timer for this game
-	timer := m.timerManager.CreateTimer(gameID, hub, game.TurnTimeoutSeconds)
+	// Create timer for this game. Running-total games created before the
+	// base time was stored played with the turn timeout instead.
+	control := TimeControl{
+		Mode:      game.TimeControl,
+		BaseTime:  game.TurnTimeoutSeconds,
+		Increment: game.IncrementSeconds,
+	}
+	if runningClocks(game) && game.BaseTimeSeconds > 0 {
+		control.BaseTime = game.BaseTimeSeconds
+	}
+	timer := m.timerManager.CreateTimerWithFirstMoveGrace(gameID, hub, control, hub.rules.FirstMoveGraceSeconds)
+	timer.RedPlayerID = game.RedPlayerID
+	timer.BlackPlayerID = game.BlackPlayerID
 
 	room := &GameRoom{
 		GameID:       gameID,
@@ -82,10 +154,44 @@ func (m *RoomManager) CreateRoom(gameID string, game *models.Game, hub *Hub, gam
 		GameService:  gameService,
 		Timer:        timer,
 		TimerManager: m.timerManager,
+		Rules:        hub.rules,
 		CurrentTurn:  models.PlayerColorRed,
 		MoveCount:    0,
 		IsGameOver:   false,
 		GracePeriod:  60 * time.Second,
+
+		DrawOfferWindow: 30 * time.Second,
+
+		CountdownInterval: hub.disconnectCountdown,
+
+		spectators:    make(map[*Client]bool),
+		MaxSpectators: hub.maxSpectators,
+
+		hopelessMoves: make(map[string]int),
+		chatLimiters:  make(map[*Client]*messageLimiter),
+		premoves:      make(map[*Client]premove),
+		departedMoves: make(map[string]moveSeq),
+	}
+
+	// The computer never connects, so it is seated from the start
+	if game.BotDifficulty > 0 {
+		room.bot = newBotClient(room)
+		room.BlackPlayer = room.bot
+	}
+
+	// A game may already have moves, e.g. after a server restart
+	if engine, err := room.loadEngine(); err != nil {
+		log.Warn().Err(err).Str("game_id", gameID).Msg("Failed to load engine for room")
+	} else {
+		room.MoveCount = len(engine.GetMoveHistory())
+		room.CurrentTurn = engine.GetCurrentTurn()
+		timer.SetMoveCount(room.MoveCount)
+		redTime, blackTime, _, _ := timer.GetState()
+		// Running totals carry on from the clocks saved after the last move
+		if runningClocks(game) && game.RedTimeRemaining != nil && game.BlackTimeRemaining != nil {
+			redTime, blackTime = *game.RedTimeRemaining, *game.BlackTimeRemaining
+		}
+		timer.UpdateFromServer(redTime, blackTime, string(room.CurrentTurn))
 	}
 
 	m.rooms[gameID] = room
@@ -99,6 +205,13 @@ func (m *RoomManager) CreateRoom(gameID string, game *models.Game, hub *Hub, gam
 	return room
 }
 
+// runningClocks reports whether a game's clocks are running totals for the
+// whole game, as under Fischer and Bronstein control, rather than reset
+// each turn.
+func runningClocks(game *models.Game) bool {
+	return game.TimeControl == models.TimeControlFischer || game.TimeControl == models.TimeControlBronstein
+}
+
 // GetRoom retrieves a game room by ID.
 func (m *RoomManager) GetRoom(gameID string) *GameRoom {
 	m.mu.RLock()
@@ -134,9 +247,15 @@ func (r *GameRoom) Cleanup() {
 		r.RollbackTimeout.Stop()
 	}
 
+	if r.DrawOfferTimeout != nil {
+		r.DrawOfferTimeout.Stop()
+	}
+
 	if r.DisconnectTimer != nil {
 		r.DisconnectTimer.Stop()
 	}
+
+	r.stopDisconnectCountdown()
 }
 
 // JoinPlayer adds a player to the room.
@@ -144,11 +263,41 @@ func (r *GameRoom) JoinPlayer(client *Client) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	// A client may only ever act in the game it was upgraded for
+	if client.GameID != r.GameID {
+		log.Warn().
+			Str("game_id", r.GameID).
+			Str("client_game_id", client.GameID).
+			Str("device_id", client.DeviceID).
+			Msg("Client tried to join another game's room")
+		return services.ErrPlayerNotInGame
+	}
+
+	// Spectators are never seated, even when watching from a player's device
+	if client.Role == ClientRoleSpectator {
+		return services.ErrPlayerNotInGame
+	}
+
+	// Repeated joins on the same connection are no-ops; a new connection for
+	// the same device is handled as a reconnection below
+	if client == r.RedPlayer || client == r.BlackPlayer {
+		log.Debug().
+			Str("game_id", r.GameID).
+			Str("device_id", client.DeviceID).
+			Msg("Ignoring duplicate join")
+		return nil
+	}
+
+	var color models.PlayerColor
 	if client.DeviceID == r.Game.RedPlayerID {
+		r.inheritMoveSeq(client, r.RedPlayer)
 		r.RedPlayer = client
+		color = models.PlayerColorRed
 		log.Info().Str("game_id", r.GameID).Str("player", "red").Msg("Red player joined")
 	} else if client.DeviceID == r.Game.BlackPlayerID {
+		r.inheritMoveSeq(client, r.BlackPlayer)
 		r.BlackPlayer = client
+		color = models.PlayerColorBlack
 		log.Info().Str("game_id", r.GameID).Str("player", "black").Msg("Black player joined")
 	} else {
 		log.Warn().
@@ -157,21 +306,66 @@ func (r *GameRoom) JoinPlayer(client *Client) error {
 			Msg("Unknown player tried to join")
 		return services.ErrPlayerNotInGame
 	}
+	r.sendJoined(client, color)
+
+	// A matched game is claimed once either player opens it
+	if !r.started {
+		r.Hub.markOpened(r.GameID)
+	}
 
 	// Check if player was disconnected
 	if r.DisconnectedPlayer == client.DeviceID {
 		r.handleReconnection(client)
 	}
 
-	// Start timer if both players are connected
-	if r.RedPlayer != nil && r.BlackPlayer != nil && !r.Timer.IsRunning {
+	// Start the game once, when both players are first connected
+	if r.RedPlayer != nil && r.BlackPlayer != nil && !r.started && !r.IsGameOver {
+		r.started = true
 		r.Timer.Start()
 		r.sendGameState()
 	}
 
+	// A room recreated on the computer's turn resumes its search
+	r.scheduleBotMove()
+
 	return nil
 }
 
+// HasJoined reports whether a player has been seated in the room, whether
+// or not they are still connected.
+func (r *GameRoom) HasJoined(deviceID string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if (r.RedPlayer != nil && r.RedPlayer.DeviceID == deviceID) ||
+		(r.BlackPlayer != nil && r.BlackPlayer.DeviceID == deviceID) {
+		return true
+	}
+	_, departed := r.departedMoves[deviceID]
+	return departed
+}
+
+// sendJoined confirms a player's seat. With reconnection tokens enabled it
+// carries the token the player must present to reconnect, and the seat is
+// recorded so every instance asks for the token from then on.
+func (r *GameRoom) sendJoined(client *Client, color models.PlayerColor) {
+	if r.Hub == nil || r.Hub.reconnectTokens == nil {
+		return
+	}
+	r.Hub.reconnectTokens.MarkSeated(context.Background(), r.GameID, client.DeviceID)
+
+	sendToClient(client, OutgoingMessage{
+		Type: "joined",
+		Payload: map[string]interface{}{
+			"game_id":         r.GameID,
+			"color":           color,
+			"reconnect_token": r.Hub.reconnectTokens.Issue(r.GameID, client.DeviceID, color),
+		},
+		Timestamp: models.FormatTimestamp(time.Now()),
+		MessageID: generateMessageID(),
+	})
+}
+
 // LeavePlayer removes a player from the room.
 func (r *GameRoom) LeavePlayer(client *Client) {
 	r.mu.Lock()
@@ -181,19 +375,82 @@ func (r *GameRoom) LeavePlayer(client *Client) {
 
 	if r.RedPlayer == client {
 		r.RedPlayer = nil
-		r.DisconnectedPlayer = client.DeviceID
 		leavingPlayerColor = "red"
 	} else if r.BlackPlayer == client {
 		r.BlackPlayer = nil
-		r.DisconnectedPlayer = client.DeviceID
 		leavingPlayerColor = "black"
 	}
-
+	delete(r.chatLimiters, client)
+	delete(r.premoves, client)
 	if leavingPlayerColor != "" {
+		r.departedMoves[client.DeviceID] = client.lastMove
+	}
+
+	// The game stays pending until both players have connected, so leaving
+	// before then cannot forfeit it by abandonment
+	if leavingPlayerColor != "" && r.started {
+		r.DisconnectedPlayer = client.DeviceID
 		r.handleDisconnection(client.DeviceID, leavingPlayerColor)
 	}
 }
 
+// inheritMoveSeq carries the last sequenced move over to a player's new
+// connection, from the connection it replaces or from when they left.
+func (r *GameRoom) inheritMoveSeq(client, previous *Client) {
+	if previous != nil {
+		client.lastMove = previous.lastMove
+	} else if last, ok := r.departedMoves[client.DeviceID]; ok {
+		client.lastMove = last
+	}
+	delete(r.departedMoves, client.DeviceID)
+}
+
+// AddSpectator lets a client watch the game. From then on the spectator
+// receives the room's broadcasts through the hub, and is sent the full game state right
+// away, so joining mid-game needs no separate resync.
+func (r *GameRoom) AddSpectator(client *Client) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if client.GameID != r.GameID {
+		return services.ErrPlayerNotInGame
+	}
+
+	if !r.spectators[client] {
+		if r.MaxSpectators > 0 && len(r.spectators) >= r.MaxSpectators {
+			return ErrSpectatorsFull
+		}
+		r.spectators[client] = true
+		client.watching.Store(true)
+
+		log.Info().
+			Str("game_id", r.GameID).
+			Str("device_id", client.DeviceID).
+			Int("spectators", len(r.spectators)).
+			Msg("Spectator joined")
+	}
+
+	r.sendFullState(client)
+	return nil
+}
+
+// RemoveSpectator stops a client from watching the game.
+func (r *GameRoom) RemoveSpectator(client *Client) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.spectators, client)
+	client.watching.Store(false)
+}
+
+// SpectatorCount returns the number of spectators watching the game.
+func (r *GameRoom) SpectatorCount() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.spectators)
+}
+
 // handleDisconnection handles a player disconnection.
 func (r *GameRoom) handleDisconnection(deviceID string, color string) {
 	log.Info().
@@ -208,9 +465,62 @@ func (r *GameRoom) handleDisconnection(deviceID string, color string) {
 	r.broadcastConnectionStatus("opponent_disconnected", deviceID)
 
 	// Start grace period timer
+	r.disconnectedAt = time.Now()
 	r.DisconnectTimer = time.AfterFunc(r.GracePeriod, func() {
 		r.handleAbandonmentTimeout(deviceID)
 	})
+	r.startDisconnectCountdown(deviceID)
+}
+
+// startDisconnectCountdown periodically tells the waiting player how long
+// the disconnected player has left to return, counting down from the start
+// of the DisconnectTimer.
+func (r *GameRoom) startDisconnectCountdown(deviceID string) {
+	r.stopDisconnectCountdown()
+	if r.CountdownInterval <= 0 {
+		return
+	}
+
+	stop := make(chan struct{})
+	r.stopCountdown = stop
+	deadline := r.disconnectedAt.Add(r.GracePeriod)
+
+	go func() {
+		ticker := time.NewTicker(r.CountdownInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+
+			r.mu.Lock()
+			// Stopped while waiting for the lock
+			if r.stopCountdown != stop || r.IsGameOver {
+				r.mu.Unlock()
+				return
+			}
+			remaining := time.Until(deadline)
+			if remaining > 0 {
+				r.broadcastDisconnectCountdown(deviceID, remaining)
+			}
+			r.mu.Unlock()
+
+			if remaining <= 0 {
+				return
+			}
+		}
+	}()
+}
+
+// stopDisconnectCountdown stops the countdown, if one is running.
+func (r *GameRoom) stopDisconnectCountdown() {
+	if r.stopCountdown != nil {
+		close(r.stopCountdown)
+		r.stopCountdown = nil
+	}
 }
 
 // handleReconnection handles a player reconnecting.
@@ -225,12 +535,16 @@ func (r *GameRoom) handleReconnection(client *Client) {
 		r.DisconnectTimer.Stop()
 		r.DisconnectTimer = nil
 	}
+	r.stopDisconnectCountdown()
 
 	r.DisconnectedPlayer = ""
 
 	// Resume the timer
 	r.Timer.Resume()
 
+	// Catch the returning player up before telling the opponent
+	r.sendFullState(client)
+
 	// Notify the other player
 	r.broadcastConnectionStatus("opponent_reconnected", client.DeviceID)
 }
@@ -264,22 +578,46 @@ func (r *GameRoom) handleAbandonmentTimeout(disconnectedPlayerID string) {
 	r.endGame(winnerID, winnerColor, models.ResultTypeAbandonment)
 }
 
-// HandleMove processes a move from a player.
-func (r *GameRoom) HandleMove(client *Client, from, to string, pieceType string) {
+// HandleMove processes a move from a player. A positive seq is acknowledged
+// once the move is applied; a seq the client has already used is treated as
+// a retransmission and only acknowledged again.
+func (r *GameRoom) HandleMove(client *Client, from, to string, pieceType string, seq int64) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	r.makeMove(client, from, to, pieceType, seq)
+}
+
+// makeMove validates and plays a move for a client. Callers must hold the
+// room's write lock.
+func (r *GameRoom) makeMove(client *Client, from, to string, pieceType string, seq int64) {
+	if seq > 0 && seq <= client.lastMove.seq {
+		log.Debug().
+			Str("game_id", r.GameID).
+			Str("device_id", client.DeviceID).
+			Int64("seq", seq).
+			Msg("Ignoring retransmitted move")
+		r.sendMoveAck(client, seq, true)
+		return
+	}
+
 	if r.IsGameOver {
 		sendErrorToClient(client, "game_ended", "Game has already ended")
 		return
 	}
 
-	// Validate it's the player's turn
+	// Only a seated player can move; a client that has not joined yet, or
+	// was replaced by a newer connection, gets a clear error instead of
+	// failing turn validation
 	var playerColor string
-	if client.DeviceID == r.Game.RedPlayerID {
+	switch client {
+	case r.RedPlayer:
 		playerColor = "red"
-	} else {
+	case r.BlackPlayer:
 		playerColor = "black"
+	default:
+		sendErrorToClient(client, "not_joined", "Join the game before making moves")
+		return
 	}
 
 	if string(r.CurrentTurn) != playerColor {
@@ -287,19 +625,53 @@ func (r *GameRoom) HandleMove(client *Client, from, to string, pieceType string)
 		return
 	}
 
-	// Record the move in the database
+	engine, err := r.loadEngine()
+	if err != nil {
+		log.Error().Err(err).Str("game_id", r.GameID).Msg("Failed to load engine for move")
+		sendErrorToClient(client, "move_failed", "Failed to load game state")
+		return
+	}
+
+	// A client naming a different piece than the board holds is out of sync
+	if pieceType != "" {
+		if pos, err := game.ParsePosition(from); err == nil {
+			if piece := engine.GetBoard().At(pos); piece != nil && string(piece.Type) != pieceType {
+				message := "piece type does not match the board"
+				r.sendMoveResult(client, false, nil, "", &message, nil)
+				return
+			}
+		}
+	}
+
+	// Only legal moves are stored
+	result := engine.ValidateAndMakeMove(game.MoveRequest{
+		PlayerID: client.DeviceID,
+		From:     from,
+		To:       to,
+	})
+	if !result.Success {
+		r.sendMoveResult(client, false, nil, "", &result.ErrorMessage, nil)
+		return
+	}
+
 	move := &models.Move{
 		GameID:       r.GameID,
 		MoveNumber:   r.MoveCount + 1,
 		PlayerID:     client.DeviceID,
 		FromPosition: from,
 		ToPosition:   to,
-		PieceType:    models.PieceType(pieceType),
+		PieceType:    result.Move.PieceType,
 		Timestamp:    time.Now(),
 	}
+	event := annotateMove(engine, move)
 
+	// Record the move in the database
 	if err := r.GameService.RecordMove(context.Background(), move); err != nil {
 		log.Error().Err(err).Msg("Failed to record move")
+		// Keep the board in step with the stored moves
+		if err := engine.UndoLastMove(); err != nil {
+			r.engine = nil
+		}
 		sendErrorToClient(client, "move_failed", "Failed to record move")
 		return
 	}
@@ -315,12 +687,469 @@ func (r *GameRoom) HandleMove(client *Client, from, to string, pieceType string)
 
 	// Switch timer
 	r.Timer.SwitchTurn()
+	if runningClocks(r.Game) {
+		redTime, blackTime, _, _ := r.Timer.GetState()
+		if err := r.GameService.SaveClocks(context.Background(), r.GameID, redTime, blackTime); err != nil {
+			log.Warn().Err(err).Str("game_id", r.GameID).Msg("Failed to save clocks")
+		}
+	}
 
 	// Send confirmation to the player who moved
-	r.sendMoveResult(client, true, move, nil)
+	r.sendMoveResult(client, true, move, event, nil, engine)
+	if seq > 0 {
+		client.lastMove = moveSeq{seq: seq, moveNumber: move.MoveNumber}
+		r.sendMoveAck(client, seq, false)
+	}
 
 	// Broadcast to opponent
-	r.broadcastOpponentMove(client, move)
+	r.broadcastOpponentMove(client, move, event, engine)
+
+	r.checkResignSuggestion(engine)
+
+	switch {
+	case result.IsCheckmate:
+		r.endGame(*result.WinnerID, playerColor, models.ResultTypeCheckmate)
+		return
+	case result.IsStalemate:
+		// A stalemated player loses, as they cannot move
+		r.endGame(*result.WinnerID, playerColor, models.ResultTypeStalemate)
+		return
+	}
+
+	if result.IsDraw {
+		log.Info().Str("game_id", r.GameID).Msg("Game drawn by the no-capture rule")
+		r.endGame("", "", models.ResultTypeDraw)
+		return
+	}
+
+	// The engine only decides perpetual checks when the feature is enabled
+	if result.IsPerpetualCheck {
+		winner := *engine.GetWinner()
+		log.Info().Str("game_id", r.GameID).Str("offender", string(winner.Opposite())).Msg("Game lost by perpetual check")
+		r.endGame(*result.WinnerID, string(winner), models.ResultTypePerpetualCheck)
+		return
+	}
+
+	if r.Rules.Enabled(config.FeatureRepetition) &&
+		engine.RepetitionCount() >= game.RepetitionDrawCount {
+		log.Info().Str("game_id", r.GameID).Msg("Game drawn by threefold repetition")
+		r.endGame("", "", models.ResultTypeDraw)
+		return
+	}
+
+	// The opponent may have queued their reply already
+	r.playPremove(move)
+	r.scheduleBotMove()
+}
+
+// premove is a move queued during the opponent's turn.
+type premove struct {
+	from      string
+	to        string
+	pieceType string
+}
+
+// HandlePremove queues a move to play as soon as it is the client's turn,
+// replacing any move queued before. On the client's own turn it is played
+// right away.
+func (r *GameRoom) HandlePremove(client *Client, from, to string, pieceType string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.IsGameOver {
+		sendErrorToClient(client, "game_ended", "Game has already ended")
+		return
+	}
+
+	var playerColor models.PlayerColor
+	switch client {
+	case r.RedPlayer:
+		playerColor = models.PlayerColorRed
+	case r.BlackPlayer:
+		playerColor = models.PlayerColorBlack
+	default:
+		sendErrorToClient(client, "not_joined", "Join the game before making moves")
+		return
+	}
+
+	// The opponent's move may have arrived while the premove was sent
+	if r.CurrentTurn == playerColor {
+		r.makeMove(client, from, to, pieceType, 0)
+		return
+	}
+
+	r.premoves[client] = premove{from: from, to: to, pieceType: pieceType}
+	sendToClient(client, OutgoingMessage{
+		Type: "premove_set",
+		Payload: map[string]interface{}{
+			"from": from,
+			"to":   to,
+		},
+		Timestamp: models.FormatTimestamp(time.Now()),
+		MessageID: generateMessageID(),
+	})
+}
+
+// playPremove plays the premove of the player whose turn it now is, if they
+// queued one, after the opponent's move. A premove touching a square that
+// move changed, or no longer legal, is cancelled instead. Callers must hold
+// the room's write lock.
+func (r *GameRoom) playPremove(opponentMove *models.Move) {
+	client := r.RedPlayer
+	if r.CurrentTurn == models.PlayerColorBlack {
+		client = r.BlackPlayer
+	}
+	if client == nil {
+		return
+	}
+	pm, ok := r.premoves[client]
+	if !ok {
+		return
+	}
+	delete(r.premoves, client)
+
+	// The player planned around a position that no longer holds
+	changed := map[string]bool{opponentMove.FromPosition: true, opponentMove.ToPosition: true}
+	if changed[pm.from] || changed[pm.to] {
+		r.sendPremoveCancelled(client, pm, "squares_changed")
+		return
+	}
+
+	if !r.premoveIsLegal(pm) {
+		r.sendPremoveCancelled(client, pm, "illegal")
+		return
+	}
+
+	r.makeMove(client, pm.from, pm.to, pm.pieceType, 0)
+}
+
+// premoveIsLegal reports whether a premove can be played by the side to
+// move in the current position.
+func (r *GameRoom) premoveIsLegal(pm premove) bool {
+	engine, err := r.loadEngine()
+	if err != nil {
+		log.Error().Err(err).Str("game_id", r.GameID).Msg("Failed to load engine for premove")
+		return false
+	}
+
+	position, err := game.ParsePosition(pm.from)
+	if err != nil {
+		return false
+	}
+	piece := engine.GetBoard().At(position)
+	if piece == nil || piece.Color != r.CurrentTurn {
+		return false
+	}
+	if pm.pieceType != "" && string(piece.Type) != pm.pieceType {
+		return false
+	}
+
+	moves, err := engine.GetValidMoveDetails(pm.from)
+	if err != nil {
+		return false
+	}
+	for _, move := range moves {
+		if move.To == pm.to {
+			return true
+		}
+	}
+	return false
+}
+
+// cancelPremoves drops every queued premove, telling each player why.
+func (r *GameRoom) cancelPremoves(reason string) {
+	for client, pm := range r.premoves {
+		r.sendPremoveCancelled(client, pm, reason)
+		delete(r.premoves, client)
+	}
+}
+
+func (r *GameRoom) sendPremoveCancelled(client *Client, pm premove, reason string) {
+	sendToClient(client, OutgoingMessage{
+		Type: "premove_cancelled",
+		Payload: map[string]interface{}{
+			"from":   pm.from,
+			"to":     pm.to,
+			"reason": reason,
+		},
+		Timestamp: models.FormatTimestamp(time.Now()),
+		MessageID: generateMessageID(),
+	})
+}
+
+// engineOptions returns the engine options for the room's rules. Every rule
+// is set, since stored moves are replayed with them off.
+func (r *GameRoom) engineOptions() []game.EngineOption {
+	return []game.EngineOption{
+		game.WithPerpetualCheckRule(r.Rules.Enabled(config.FeaturePerpetualCheck)),
+		game.WithNoCaptureDrawPlies(r.Rules.NoCaptureDrawPlies),
+	}
+}
+
+// loadEngine returns the room's engine, rebuilding it from the stored moves
+// if the room has none. Callers must hold the room's write lock.
+func (r *GameRoom) loadEngine() (*game.GameEngine, error) {
+	if r.engine != nil {
+		return r.engine, nil
+	}
+	engine, err := r.GameService.LoadEngine(context.Background(), r.GameID, r.engineOptions()...)
+	if err != nil {
+		return nil, err
+	}
+	r.engine = engine
+	return engine, nil
+}
+
+// Move events tell clients which effect to play for a move. A capture that
+// also gives check is reported as a check with is_capture set.
+const (
+	moveEventMove      = "move"
+	moveEventCapture   = "capture"
+	moveEventCheck     = "check"
+	moveEventCheckmate = "checkmate"
+)
+
+// annotateMove fills in the capture and check details of the engine's latest
+// move on the recorded move and returns the move's event.
+func annotateMove(engine *game.GameEngine, move *models.Move) string {
+	history := engine.GetMoveHistory()
+	if len(history) == 0 {
+		return moveEventMove
+	}
+	last := history[len(history)-1]
+	move.CapturedPiece = last.CapturedPiece
+	move.IsCheck = last.IsCheck
+
+	switch {
+	case engine.IsCheckmate():
+		return moveEventCheckmate
+	case last.IsCheck:
+		return moveEventCheck
+	case last.CapturedPiece != nil:
+		return moveEventCapture
+	}
+	return moveEventMove
+}
+
+// checkResignSuggestion evaluates the position for the player to move and
+// sends them a resign_suggestion once it has been hopeless for the
+// configured number of consecutive moves, if they have opted in. It never
+// resigns on their behalf.
+func (r *GameRoom) checkResignSuggestion(engine *game.GameEngine) {
+	color := engine.GetCurrentTurn()
+	playerID := r.Game.RedPlayerID
+	player := r.RedPlayer
+	if color == models.PlayerColorBlack {
+		playerID = r.Game.BlackPlayerID
+		player = r.BlackPlayer
+	}
+
+	evaluation := game.Evaluate(engine.GetBoard(), color)
+	if evaluation > r.Rules.ResignSuggestionThreshold {
+		r.hopelessMoves[playerID] = 0
+		return
+	}
+
+	r.hopelessMoves[playerID]++
+	// Suggest once per hopeless streak
+	if r.hopelessMoves[playerID] != r.Rules.ResignSuggestionMoves || player == nil {
+		return
+	}
+	if !r.GameService.WantsResignSuggestions(context.Background(), playerID) {
+		return
+	}
+
+	message := OutgoingMessage{
+		Type: "resign_suggestion",
+		Payload: map[string]interface{}{
+			"evaluation": evaluation,
+		},
+		Timestamp: models.FormatTimestamp(time.Now()),
+		MessageID: generateMessageID(),
+	}
+	data, _ := json.Marshal(message)
+	player.Send <- data
+}
+
+// maxResyncDelta is the largest number of missed moves sent as a delta on
+// resync. Clients further behind receive the full game state instead.
+const maxResyncDelta = 20
+
+// HandleResync brings a client up to date after it missed messages.
+// When the client's last known move is recent, only the moves made since
+// then are sent; otherwise the full game state is sent.
+func (r *GameRoom) HandleResync(client *Client, lastMoveNumber int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	engine, err := r.loadEngine()
+	if err != nil {
+		log.Error().Err(err).Str("game_id", r.GameID).Msg("Failed to load game for resync")
+		sendErrorToClient(client, "resync_failed", "Failed to load game state")
+		return
+	}
+
+	history := engine.GetMoveHistory()
+	redTime, blackTime, _, _ := r.Timer.GetState()
+
+	payload := map[string]interface{}{
+		"current_turn": string(engine.GetCurrentTurn()),
+		"move_count":   len(history),
+		"is_check":     engine.IsCheck(),
+		"red_time":     redTime,
+		"black_time":   blackTime,
+	}
+
+	missed := len(history) - lastMoveNumber
+	if lastMoveNumber >= 0 && missed >= 0 && missed <= maxResyncDelta {
+		moves := make([]map[string]interface{}, 0, missed)
+		for _, move := range history[lastMoveNumber:] {
+			moves = append(moves, resyncMove(move))
+		}
+		payload["mode"] = "delta"
+		payload["from_move_number"] = lastMoveNumber
+		payload["moves"] = moves
+	} else {
+		payload["mode"] = "full"
+		payload["state"] = r.clientGameState(engine)
+	}
+
+	message := OutgoingMessage{
+		Type:      "resync",
+		Payload:   payload,
+		Timestamp: models.FormatTimestamp(time.Now()),
+		MessageID: generateMessageID(),
+	}
+
+	data, _ := json.Marshal(message)
+	client.Send <- data
+}
+
+// HandleGetValidMoves replies with the legal destinations of the client's
+// piece on from. Moves that would leave the player's own general in check
+// are left out. Asking out of turn is allowed but gets an empty list, so
+// hints cannot be used to plan ahead on the opponent's time.
+func (r *GameRoom) HandleGetValidMoves(client *Client, from string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var playerColor models.PlayerColor
+	switch client {
+	case r.RedPlayer:
+		playerColor = models.PlayerColorRed
+	case r.BlackPlayer:
+		playerColor = models.PlayerColorBlack
+	default:
+		sendErrorToClient(client, "not_joined", "Join the game before asking for moves")
+		return
+	}
+
+	if !r.Game.AssistEnabled {
+		sendErrorToClient(client, "assist_disabled", "Move hints are disabled for this game")
+		return
+	}
+
+	engine, err := r.loadEngine()
+	if err != nil {
+		log.Error().Err(err).Str("game_id", r.GameID).Msg("Failed to load game for valid moves")
+		sendErrorToClient(client, "valid_moves_failed", "Failed to load game state")
+		return
+	}
+
+	position, err := game.ParsePosition(from)
+	if err != nil {
+		sendErrorToClient(client, "invalid_position", "Invalid square: "+from)
+		return
+	}
+	piece := engine.GetBoard().At(position)
+	if piece == nil {
+		sendErrorToClient(client, "no_piece", "No piece at "+from)
+		return
+	}
+	if piece.Color != playerColor {
+		sendErrorToClient(client, "not_your_piece", "That piece belongs to your opponent")
+		return
+	}
+
+	moves := []string{}
+	if !r.IsGameOver && engine.GetCurrentTurn() == playerColor {
+		if moves, err = engine.GetValidMoves(from); err != nil {
+			sendErrorToClient(client, "valid_moves_failed", err.Error())
+			return
+		}
+	}
+
+	message := OutgoingMessage{
+		Type: "valid_moves",
+		Payload: map[string]interface{}{
+			"from":  position.Notation(),
+			"moves": moves,
+		},
+		Timestamp: models.FormatTimestamp(time.Now()),
+		MessageID: generateMessageID(),
+	}
+
+	data, _ := json.Marshal(message)
+	client.Send <- data
+}
+
+// HandleGetForcedMove replies with the only legal move of the side to move,
+// or no move when there is a choice. Games played without assistance
+// refuse with an assist_disabled error.
+func (r *GameRoom) HandleGetForcedMove(client *Client) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if client != r.RedPlayer && client != r.BlackPlayer {
+		sendErrorToClient(client, "not_joined", "Join the game before asking for hints")
+		return
+	}
+	if !r.Game.AssistEnabled {
+		sendErrorToClient(client, "assist_disabled", "Move hints are disabled for this game")
+		return
+	}
+
+	engine, err := r.loadEngine()
+	if err != nil {
+		log.Error().Err(err).Str("game_id", r.GameID).Msg("Failed to load game for forced move")
+		sendErrorToClient(client, "forced_move_failed", "Failed to load game state")
+		return
+	}
+
+	sendToClient(client, OutgoingMessage{
+		Type: "forced_move",
+		Payload: map[string]interface{}{
+			"forced_move": engine.GetGameState().ForcedMove,
+		},
+		Timestamp: models.FormatTimestamp(time.Now()),
+		MessageID: generateMessageID(),
+	})
+}
+
+// clientGameState returns the engine's game state as sent to clients. The
+// forced move hint is left out of games played without assistance.
+func (r *GameRoom) clientGameState(engine *game.GameEngine) *game.GameState {
+	state := engine.GetGameState()
+	if !r.Game.AssistEnabled {
+		state.ForcedMove = nil
+	}
+	return state
+}
+
+// resyncMove converts an engine move record into a resync payload entry.
+func resyncMove(move game.MoveRecord) map[string]interface{} {
+	entry := map[string]interface{}{
+		"move_number": move.MoveNumber,
+		"player_id":   move.PlayerID,
+		"from":        move.From.Notation(),
+		"to":          move.To.Notation(),
+		"piece_type":  string(move.PieceType),
+		"is_check":    move.IsCheck,
+	}
+	if move.CapturedPiece != nil {
+		entry["captured"] = string(*move.CapturedPiece)
+	}
+	return entry
 }
 
 // HandleRollbackRequest processes a rollback request.
@@ -339,6 +1168,25 @@ func (r *GameRoom) HandleRollbackRequest(client *Client) {
 		return
 	}
 
+	// A rollback undoes the opponent's latest move, so it may only be
+	// requested by the player whose turn it is
+	playerColor := models.PlayerColorRed
+	opponentID := r.Game.BlackPlayerID
+	if client.DeviceID == r.Game.BlackPlayerID {
+		playerColor = models.PlayerColorBlack
+		opponentID = r.Game.RedPlayerID
+	}
+
+	if r.CurrentTurn != playerColor {
+		sendErrorToClient(client, "not_your_turn", "A rollback can only be requested on your turn")
+		return
+	}
+
+	if r.MoveCount == 0 || !r.lastMoveIsBy(opponentID) {
+		sendErrorToClient(client, "invalid_rollback", "The last move was not made by your opponent")
+		return
+	}
+
 	// Check if player has rollbacks remaining
 	var rollbacksRemaining int
 	if client.DeviceID == r.Game.RedPlayerID {
@@ -347,11 +1195,16 @@ func (r *GameRoom) HandleRollbackRequest(client *Client) {
 		rollbacksRemaining = r.Game.BlackRollbacksRemaining
 	}
 
-	if rollbacksRemaining <= 0 {
+	if rollbacksRemaining <= 0 && !r.unlimitedRollbacks() {
 		sendErrorToClient(client, "no_rollbacks", "No rollbacks remaining")
 		return
 	}
 
+	if r.Rules.NoRollbackAfterCheck && r.wasPutInCheck(client.DeviceID) {
+		sendErrorToClient(client, "rollback_after_check", "Rollback is not allowed after being put in check")
+		return
+	}
+
 	// Create pending rollback
 	r.PendingRollback = &RollbackRequest{
 		RequestingPlayerID: client.DeviceID,
@@ -375,15 +1228,76 @@ func (r *GameRoom) HandleRollbackRequest(client *Client) {
 		Msg("Rollback requested")
 }
 
+// unlimitedRollbacks reports whether rollbacks in this game ignore the
+// remaining count, which the rules allow for casual games.
+func (r *GameRoom) unlimitedRollbacks() bool {
+	return r.Rules.CasualUnlimitedRollbacks && !r.Game.Ranked
+}
+
+// lastMoveIsBy reports whether the latest move, which a rollback would
+// revert, was made by the given player. Callers must hold the room's write
+// lock.
+func (r *GameRoom) lastMoveIsBy(playerID string) bool {
+	engine, err := r.loadEngine()
+	if err != nil {
+		log.Warn().Err(err).Str("game_id", r.GameID).Msg("Failed to load engine for rollback check")
+		return false
+	}
+
+	last, ok := engine.LastMove()
+	return ok && last.MoveNumber == r.MoveCount && last.PlayerID == playerID
+}
+
+// wasPutInCheck reports whether the opponent's latest move put the player in
+// check. Rollbacks are only requested on the player's own turn, so this is
+// the check the player is currently facing. Callers must hold the room's
+// write lock.
+func (r *GameRoom) wasPutInCheck(playerID string) bool {
+	engine, err := r.loadEngine()
+	if err != nil {
+		log.Warn().Err(err).Str("game_id", r.GameID).Msg("Failed to load engine for rollback check")
+		return false
+	}
+
+	opponentID := r.Game.RedPlayerID
+	if playerID == r.Game.RedPlayerID {
+		opponentID = r.Game.BlackPlayerID
+	}
+
+	lastMove, ok := engine.LastMoveBy(opponentID)
+	return ok && lastMove.IsCheck
+}
+
 // HandleRollbackResponse processes a response to a rollback request.
 func (r *GameRoom) HandleRollbackResponse(client *Client, accept bool) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	// The game may have ended, e.g. by checkmate, between the request and
+	// this response. Reverting a finished game would leave it inconsistent,
+	// so a rollback still pending is dropped and both players are told why.
+	// Ending the game normally cancels it already, in which case only the
+	// late responder hears about it.
+	if r.IsGameOver {
+		if r.PendingRollback == nil {
+			sendErrorToClient(client, "game_ended", "Game has already ended")
+			return
+		}
+		requestingPlayerID := r.PendingRollback.RequestingPlayerID
+		r.clearPendingRollback()
+		r.broadcastRollbackCancelled(requestingPlayerID, "game_ended")
+		return
+	}
+
 	if r.PendingRollback == nil {
 		sendErrorToClient(client, "no_request", "No pending rollback request")
 		return
 	}
+	// Only the opponent can answer a request
+	if client.DeviceID == r.PendingRollback.RequestingPlayerID {
+		sendErrorToClient(client, "own_rollback_request", "Cannot answer your own rollback request")
+		return
+	}
 
 	// Cancel timeout timer
 	if r.RollbackTimeout != nil {
@@ -396,16 +1310,19 @@ func (r *GameRoom) HandleRollbackResponse(client *Client, accept bool) {
 	r.PendingRollback = nil
 
 	if accept {
-		// Decrement rollback count for the requesting player
-		if err := r.GameService.UseRollback(context.Background(), r.GameID, requestingPlayerID); err != nil {
-			log.Error().Err(err).Msg("Failed to decrement rollback count")
-		}
-
-		// Update local game state
-		if requestingPlayerID == r.Game.RedPlayerID {
-			r.Game.RedRollbacksRemaining--
-		} else {
-			r.Game.BlackRollbacksRemaining--
+		// Decrement rollback count for the requesting player, unless
+		// rollbacks are free in this game
+		if !r.unlimitedRollbacks() {
+			if err := r.GameService.UseRollback(context.Background(), r.GameID, requestingPlayerID); err != nil {
+				log.Error().Err(err).Msg("Failed to decrement rollback count")
+			}
+
+			// Update local game state
+			if requestingPlayerID == r.Game.RedPlayerID {
+				r.Game.RedRollbacksRemaining--
+			} else {
+				r.Game.BlackRollbacksRemaining--
+			}
 		}
 
 		// Revert game state
@@ -414,6 +1331,10 @@ func (r *GameRoom) HandleRollbackResponse(client *Client, accept bool) {
 		}
 
 		r.MoveCount = moveNumber - 1
+		r.engine = nil
+
+		// Premoves were planned for the position being undone
+		r.cancelPremoves("rollback")
 
 		// Switch turn back
 		if r.CurrentTurn == models.PlayerColorRed {
@@ -461,6 +1382,109 @@ func (r *GameRoom) handleRollbackTimeout() {
 	r.broadcastRollbackResult(false, 0)
 }
 
+// clearPendingRollback drops any pending rollback request and stops its
+// timeout.
+func (r *GameRoom) clearPendingRollback() {
+	if r.RollbackTimeout != nil {
+		r.RollbackTimeout.Stop()
+		r.RollbackTimeout = nil
+	}
+	r.PendingRollback = nil
+}
+
+// RecordLatency records a round trip the server timed to a client. A seated
+// player's average latency is reported in the game state, and half of it,
+// the delay of their moves reaching the server, is added to their free time
+// each turn.
+func (r *GameRoom) RecordLatency(client *Client, rtt time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	average := client.latency.add(rtt)
+	switch client {
+	case r.RedPlayer:
+		r.Timer.SetLatencyGrace("red", average/2)
+	case r.BlackPlayer:
+		r.Timer.SetLatencyGrace("black", average/2)
+	}
+}
+
+// playerLatency returns a seated player's average round trip in
+// milliseconds, or zero if the seat is empty or nothing was measured.
+// Callers must hold r.mu.
+func playerLatency(player *Client) int64 {
+	if player == nil {
+		return 0
+	}
+	return player.latency.average().Milliseconds()
+}
+
+const (
+	// maxChatLength is the longest chat message accepted, in runes.
+	maxChatLength = 200
+
+	// chatMessagesPerSecond is how many chat messages each player may send
+	// per second.
+	chatMessagesPerSecond = 3
+)
+
+// HandleChat broadcasts a chat message from a seated player to the room.
+// Control characters are stripped, and messages that are empty or too long
+// afterwards are rejected.
+func (r *GameRoom) HandleChat(client *Client, text string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var playerColor string
+	switch client {
+	case r.RedPlayer:
+		playerColor = "red"
+	case r.BlackPlayer:
+		playerColor = "black"
+	default:
+		sendErrorToClient(client, "not_participant", "Only players in this game can chat")
+		return
+	}
+
+	limiter := r.chatLimiters[client]
+	if limiter == nil {
+		limiter = newMessageLimiter(chatMessagesPerSecond)
+		r.chatLimiters[client] = limiter
+	}
+	if allowed, _ := limiter.allow(); !allowed {
+		sendErrorToClient(client, "chat_rate_limited", "Too many chat messages, please slow down")
+		return
+	}
+
+	text = strings.TrimSpace(strings.Map(func(c rune) rune {
+		if unicode.IsControl(c) {
+			return -1
+		}
+		return c
+	}, text))
+	if text == "" {
+		sendErrorToClient(client, "chat_empty", "Chat message is empty")
+		return
+	}
+	if utf8.RuneCountInString(text) > maxChatLength {
+		sendErrorToClient(client, "chat_too_long", "Chat message is too long")
+		return
+	}
+
+	now := time.Now()
+	r.broadcast(OutgoingMessage{
+		Type: "chat",
+		Payload: map[string]interface{}{
+			"color":     playerColor,
+			"player_id": client.DeviceID,
+			"text":      text,
+			"sent_at":   models.FormatTimestamp(now),
+		},
+		Timestamp: models.FormatTimestamp(now),
+		MessageID: generateMessageID(),
+	})
+}
+
 // HandleResign processes a resignation.
 func (r *GameRoom) HandleResign(client *Client) {
 	r.mu.Lock()
@@ -491,14 +1515,31 @@ func (r *GameRoom) HandleDrawOffer(client *Client) {
 		return
 	}
 
+	if r.PendingDrawOffer != nil {
+		sendErrorToClient(client, "draw_offer_pending", "A draw offer is already pending")
+		return
+	}
+
+	offer := &DrawOffer{
+		OfferingPlayerID: client.DeviceID,
+		OfferedAt:        time.Now(),
+		TimeoutSeconds:   int(r.DrawOfferWindow / time.Second),
+	}
+	r.PendingDrawOffer = offer
+
+	// Decline the offer if it is not answered in time
+	r.DrawOfferTimeout = time.AfterFunc(r.DrawOfferWindow, func() {
+		r.handleDrawOfferTimeout(offer)
+	})
+
 	// Broadcast draw offer to opponent
 	message := OutgoingMessage{
 		Type: "draw_offered",
 		Payload: map[string]interface{}{
 			"offerer":         client.DeviceID,
-			"timeout_seconds": 30,
+			"timeout_seconds": offer.TimeoutSeconds,
 		},
-		Timestamp: time.Now(),
+		Timestamp: models.FormatTimestamp(time.Now()),
 		MessageID: generateMessageID(),
 	}
 
@@ -514,6 +1555,17 @@ func (r *GameRoom) HandleDrawResponse(client *Client, accept bool) {
 		return
 	}
 
+	if r.PendingDrawOffer == nil {
+		sendErrorToClient(client, "no_draw_offer", "No pending draw offer")
+		return
+	}
+	// Only the opponent can answer an offer
+	if client.DeviceID == r.PendingDrawOffer.OfferingPlayerID {
+		sendErrorToClient(client, "own_draw_offer", "Cannot answer your own draw offer")
+		return
+	}
+	r.clearPendingDrawOffer()
+
 	if accept {
 		r.endGame("", "", models.ResultTypeDraw)
 	} else {
@@ -522,20 +1574,71 @@ func (r *GameRoom) HandleDrawResponse(client *Client, accept bool) {
 			Type: "draw_declined",
 			Payload: map[string]interface{}{
 				"declined_by": client.DeviceID,
+				"reason":      "declined",
 			},
-			Timestamp: time.Now(),
+			Timestamp: models.FormatTimestamp(time.Now()),
 			MessageID: generateMessageID(),
 		}
 		r.broadcast(message)
 	}
 }
 
+// handleDrawOfferTimeout declines a draw offer nobody answered. An offer
+// answered in the meantime, or replaced by a later one, is left alone.
+func (r *GameRoom) handleDrawOfferTimeout(offer *DrawOffer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.PendingDrawOffer != offer {
+		return
+	}
+
+	log.Info().
+		Str("game_id", r.GameID).
+		Str("offerer", offer.OfferingPlayerID).
+		Msg("Draw offer timed out")
+
+	r.PendingDrawOffer = nil
+	r.DrawOfferTimeout = nil
+
+	r.broadcast(OutgoingMessage{
+		Type: "draw_declined",
+		Payload: map[string]interface{}{
+			"offerer": offer.OfferingPlayerID,
+			"reason":  "timeout",
+		},
+		Timestamp: models.FormatTimestamp(time.Now()),
+		MessageID: generateMessageID(),
+	})
+}
+
+// clearPendingDrawOffer drops any pending draw offer and stops its timeout.
+func (r *GameRoom) clearPendingDrawOffer() {
+	if r.DrawOfferTimeout != nil {
+		r.DrawOfferTimeout.Stop()
+		r.DrawOfferTimeout = nil
+	}
+	r.PendingDrawOffer = nil
+}
+
 // endGame ends the game with the specified result.
 func (r *GameRoom) endGame(winnerID, winnerColor string, resultType models.ResultType) {
 	r.IsGameOver = true
 
 	// Stop the timer
 	r.Timer.Stop()
+	r.stopDisconnectCountdown()
+
+	// A pending rollback can no longer be accepted
+	if r.PendingRollback != nil {
+		requestingPlayerID := r.PendingRollback.RequestingPlayerID
+		r.clearPendingRollback()
+		r.broadcastRollbackCancelled(requestingPlayerID, "game_ended")
+	}
+
+	// Nor can a pending draw offer
+	r.clearPendingDrawOffer()
+	r.cancelPremoves("game_ended")
 
 	// Update game in database
 	var winnerIDPtr *string
@@ -555,7 +1658,7 @@ func (r *GameRoom) endGame(winnerID, winnerColor string, resultType models.Resul
 			"winner_id":    winnerID,
 			"winner_color": winnerColor,
 		},
-		Timestamp: time.Now(),
+		Timestamp: models.FormatTimestamp(time.Now()),
 		MessageID: generateMessageID(),
 	}
 
@@ -599,34 +1702,107 @@ func (r *GameRoom) broadcastConnectionStatus(status string, playerID string) {
 			"status":    status,
 			"player_id": playerID,
 		},
-		Timestamp: time.Now(),
+		Timestamp: models.FormatTimestamp(time.Now()),
 		MessageID: generateMessageID(),
 	}
 	r.broadcast(message)
 }
 
-func (r *GameRoom) sendGameState() {
-	redTime, blackTime, currentTurn, _ := r.Timer.GetState()
-
+// broadcastDisconnectCountdown tells the waiting player how many seconds
+// the disconnected player has left before forfeiting by abandonment.
+func (r *GameRoom) broadcastDisconnectCountdown(playerID string, remaining time.Duration) {
 	message := OutgoingMessage{
-		Type: "game_state",
+		Type: "opponent_disconnect_countdown",
 		Payload: map[string]interface{}{
-			"game_id":          r.GameID,
-			"current_turn":     currentTurn,
-			"move_count":       r.MoveCount,
-			"red_time":         redTime,
-			"black_time":       blackTime,
-			"red_rollbacks":    r.Game.RedRollbacksRemaining,
-			"black_rollbacks":  r.Game.BlackRollbacksRemaining,
-			"is_check":         false, // TODO: Get from game state
+			"player_id":         playerID,
+			"remaining_seconds": int(math.Ceil(remaining.Seconds())),
 		},
-		Timestamp: time.Now(),
+		Timestamp: models.FormatTimestamp(time.Now()),
 		MessageID: generateMessageID(),
 	}
 	r.broadcast(message)
 }
 
-func (r *GameRoom) sendMoveResult(client *Client, success bool, move *models.Move, error *string) {
+func (r *GameRoom) sendGameState() {
+	r.broadcast(r.gameStateMessage(false))
+}
+
+// sendFullState sends one client the game as it stands: the board, move
+// count, side to move, both clocks and the rollbacks left. Clients joining
+// mid-game need it, as they have not seen the moves so far.
+func (r *GameRoom) sendFullState(client *Client) {
+	sendToClient(client, r.gameStateMessage(true))
+}
+
+// gameStateMessage builds a game_state message. A full message also carries
+// the board, for clients that have not followed the game from the start.
+func (r *GameRoom) gameStateMessage(full bool) OutgoingMessage {
+	redTime, blackTime, currentTurn, _ := r.Timer.GetState()
+
+	payload := map[string]interface{}{
+		"game_id":         r.GameID,
+		"current_turn":    currentTurn,
+		"move_count":      r.MoveCount,
+		"red_time":        redTime,
+		"black_time":      blackTime,
+		"red_rollbacks":   r.Game.RedRollbacksRemaining,
+		"black_rollbacks": r.Game.BlackRollbacksRemaining,
+		"is_check":        false,
+		"is_checkmate":    false,
+		"is_stalemate":    false,
+	}
+
+	engine, err := r.loadEngine()
+	if err != nil {
+		log.Warn().Err(err).Str("game_id", r.GameID).Msg("Failed to load engine for game state")
+	} else {
+		payload["is_check"] = engine.IsCheck()
+		payload["is_checkmate"] = engine.IsCheckmate()
+		payload["is_stalemate"] = engine.IsStalemate()
+		if full {
+			payload["state"] = r.clientGameState(engine)
+		}
+
+		// Include the last move so clients can highlight it right away.
+		// The initial position has none.
+		if r.MoveCount > 0 {
+			if state := engine.GetGameState(); state.LastMove != nil {
+				payload["last_move"] = state.LastMove
+			}
+		}
+	}
+	r.addMoveHints(payload, engine)
+
+	return OutgoingMessage{
+		Type:      "game_state",
+		Payload:   payload,
+		Timestamp: models.FormatTimestamp(time.Now()),
+		MessageID: generateMessageID(),
+	}
+}
+
+// addMoveHints adds both players' latencies to a payload and, in assisted
+// games, the hints for the side to move: the forced move when it has a
+// single legal move and the pieces under threat. The initial position has
+// no hints, and neither does a nil engine. Callers must hold r.mu.
+func (r *GameRoom) addMoveHints(payload map[string]interface{}, engine *game.GameEngine) {
+	payload["red_latency"] = playerLatency(r.RedPlayer)
+	payload["black_latency"] = playerLatency(r.BlackPlayer)
+
+	if engine == nil || r.MoveCount == 0 || !r.Game.AssistEnabled {
+		return
+	}
+	if state := engine.GetGameState(); state.ForcedMove != nil {
+		payload["forced_move"] = state.ForcedMove
+	}
+	if r.Rules.ThreatHints {
+		payload["threatened_pieces"] = engine.GetThreatenedPieces()
+	}
+}
+
+// sendMoveResult answers a move. A successful move carries the hints for
+// the position it left, as players get no game_state after each move.
+func (r *GameRoom) sendMoveResult(client *Client, success bool, move *models.Move, event string, error *string, engine *game.GameEngine) {
 	payload := map[string]interface{}{
 		"success": success,
 	}
@@ -638,7 +1814,10 @@ func (r *GameRoom) sendMoveResult(client *Client, success bool, move *models.Mov
 			"piece_type":  string(move.PieceType),
 			"move_number": move.MoveNumber,
 			"is_check":    move.IsCheck,
+			"is_capture":  move.CapturedPiece != nil,
+			"event":       event,
 		}
+		r.addMoveHints(payload, engine)
 	}
 
 	if error != nil {
@@ -648,7 +1827,7 @@ func (r *GameRoom) sendMoveResult(client *Client, success bool, move *models.Mov
 	message := OutgoingMessage{
 		Type:      "move_result",
 		Payload:   payload,
-		Timestamp: time.Now(),
+		Timestamp: models.FormatTimestamp(time.Now()),
 		MessageID: generateMessageID(),
 	}
 
@@ -656,17 +1835,42 @@ func (r *GameRoom) sendMoveResult(client *Client, success bool, move *models.Mov
 	client.Send <- data
 }
 
-func (r *GameRoom) broadcastOpponentMove(sender *Client, move *models.Move) {
+// sendMoveAck acknowledges a sequenced move. The move number is only known
+// for the client's latest move, so older retransmissions are acknowledged
+// without one.
+func (r *GameRoom) sendMoveAck(client *Client, seq int64, duplicate bool) {
+	payload := map[string]interface{}{
+		"seq":       seq,
+		"duplicate": duplicate,
+	}
+	if seq == client.lastMove.seq {
+		payload["move_number"] = client.lastMove.moveNumber
+	}
+
+	sendToClient(client, OutgoingMessage{
+		Type:      "ack",
+		Payload:   payload,
+		Timestamp: models.FormatTimestamp(time.Now()),
+		MessageID: generateMessageID(),
+	})
+}
+
+func (r *GameRoom) broadcastOpponentMove(sender *Client, move *models.Move, event string, engine *game.GameEngine) {
+	payload := map[string]interface{}{
+		"from":        move.FromPosition,
+		"to":          move.ToPosition,
+		"piece_type":  string(move.PieceType),
+		"move_number": move.MoveNumber,
+		"is_check":    move.IsCheck,
+		"is_capture":  move.CapturedPiece != nil,
+		"event":       event,
+	}
+	r.addMoveHints(payload, engine)
+
 	message := OutgoingMessage{
-		Type: "opponent_move",
-		Payload: map[string]interface{}{
-			"from":        move.FromPosition,
-			"to":          move.ToPosition,
-			"piece_type":  string(move.PieceType),
-			"move_number": move.MoveNumber,
-			"is_check":    move.IsCheck,
-		},
-		Timestamp: time.Now(),
+		Type:      "opponent_move",
+		Payload:   payload,
+		Timestamp: models.FormatTimestamp(time.Now()),
 		MessageID: generateMessageID(),
 	}
 	r.broadcastExcept(sender, message)
@@ -680,7 +1884,7 @@ func (r *GameRoom) broadcastRollbackRequest(requester *Client) {
 			"move_to_revert":  r.MoveCount,
 			"timeout_seconds": 30,
 		},
-		Timestamp: time.Now(),
+		Timestamp: models.FormatTimestamp(time.Now()),
 		MessageID: generateMessageID(),
 	}
 	r.broadcastExcept(requester, message)
@@ -693,12 +1897,43 @@ func (r *GameRoom) broadcastRollbackResult(accepted bool, rollbacksRemaining int
 			"accepted":            accepted,
 			"rollbacks_remaining": rollbacksRemaining,
 		},
-		Timestamp: time.Now(),
+		Timestamp: models.FormatTimestamp(time.Now()),
 		MessageID: generateMessageID(),
 	}
 	r.broadcast(message)
 }
 
+// broadcastRollbackCancelled tells both players that a rollback requested by
+// requestingPlayerID was dropped without being applied.
+func (r *GameRoom) broadcastRollbackCancelled(requestingPlayerID, reason string) {
+	rollbacksRemaining := r.Game.BlackRollbacksRemaining
+	if requestingPlayerID == r.Game.RedPlayerID {
+		rollbacksRemaining = r.Game.RedRollbacksRemaining
+	}
+
+	message := OutgoingMessage{
+		Type: "rollback_result",
+		Payload: map[string]interface{}{
+			"accepted":            false,
+			"rollbacks_remaining": rollbacksRemaining,
+			"reason":              reason,
+		},
+		Timestamp: models.FormatTimestamp(time.Now()),
+		MessageID: generateMessageID(),
+	}
+	r.broadcast(message)
+}
+
+// sendToClient sends a message to a single client.
+func sendToClient(client *Client, msg OutgoingMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to marshal message")
+		return
+	}
+	client.Send <- data
+}
+
 func sendErrorToClient(client *Client, code, message string) {
 	msg := OutgoingMessage{
 		Type: "error",
@@ -706,7 +1941,7 @@ func sendErrorToClient(client *Client, code, message string) {
 			"code":    code,
 			"message": message,
 		},
-		Timestamp: time.Now(),
+		Timestamp: models.FormatTimestamp(time.Now()),
 		MessageID: generateMessageID(),
 	}
 	data, _ := json.Marshal(msg)
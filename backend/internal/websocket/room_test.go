@@ -0,0 +1,1880 @@
+// Package websocket provides unit tests for game rooms.
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/config"
+	"github.com/xiangqi/chinese-chess-backend/internal/game"
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
+
+// checkOnRedMoves ends with the black chariot capturing on d0, giving check
+// to the red general on e0.
+var checkOnRedMoves = [][2]string{
+	{"a3", "a4"}, {"a9", "a8"},
+	{"i3", "i4"}, {"a8", "d8"},
+	{"c3", "c4"}, {"d8", "d0"},
+}
+
+// setupRollbackRoom plays the given moves and returns a room whose local
+// state matches them, plus clients for both players.
+func setupRollbackRoom(t *testing.T, rules config.RulesConfig, moves [][2]string) (*GameRoom, *Client, *Client) {
+	t.Helper()
+	g := newTestGame(t, rules)
+	g.playMoves(moves...)
+
+	room := g.room(t)
+	room.MoveCount = len(moves)
+	if len(moves)%2 == 1 {
+		room.CurrentTurn = "black"
+	}
+
+	red := newTestClient(g.hub, g.game.ID, g.game.RedPlayerID)
+	black := newTestClient(g.hub, g.game.ID, g.game.BlackPlayerID)
+	return room, red, black
+}
+
+func TestRoom_JoinPlayer_RejectsClientForAnotherGame(t *testing.T) {
+	g := newTestGame(t, config.RulesConfig{})
+	room := g.room(t)
+
+	client := newTestClient(g.hub, "game-2", g.game.RedPlayerID)
+	if err := room.JoinPlayer(client); err == nil {
+		t.Fatal("Expected join from a client upgraded for another game to fail")
+	}
+	if room.RedPlayer != nil {
+		t.Error("Client should not be seated after a rejected join")
+	}
+}
+
+func TestRoomManager_CreateRoom_ResumesTimerOnSideToMove(t *testing.T) {
+	g := newTestGame(t, config.RulesConfig{})
+	g.playMoves(checkOnRedMoves[:3]...)
+
+	room := g.room(t)
+	if room.CurrentTurn != models.PlayerColorBlack {
+		t.Fatalf("Expected black to move after 3 moves, got %s", room.CurrentTurn)
+	}
+	if _, _, turn, _ := room.Timer.GetState(); turn != "black" {
+		t.Errorf("Expected the timer to run on black's clock, got %s", turn)
+	}
+}
+
+func TestRoomManager_CreateRoom_RestoresRunningClocks(t *testing.T) {
+	g := newTestGame(t, config.RulesConfig{})
+	g.game.TimeControl = models.TimeControlFischer
+	g.game.BaseTimeSeconds = 600
+	g.game.IncrementSeconds = 5
+	redTime, blackTime := 500, 450
+	g.game.RedTimeRemaining, g.game.BlackTimeRemaining = &redTime, &blackTime
+	g.playMoves(checkOnRedMoves[:3]...)
+
+	room := g.room(t)
+	if red, black, _, _ := room.Timer.GetState(); red != 500 || black != 450 {
+		t.Errorf("Expected the saved clocks 500/450, got %d/%d", red, black)
+	}
+}
+
+func TestRoom_HandleMove_SavesRunningClocks(t *testing.T) {
+	g := newTestGame(t, config.RulesConfig{})
+	g.game.TimeControl = models.TimeControlFischer
+	g.game.BaseTimeSeconds = 600
+	room := g.room(t)
+	if red, _, _, _ := room.Timer.GetState(); red != 600 {
+		t.Fatalf("Expected the base time on red's clock, got %d", red)
+	}
+	red := newTestClient(g.hub, g.game.ID, g.game.RedPlayerID)
+	black := newTestClient(g.hub, g.game.ID, g.game.BlackPlayerID)
+	room.RedPlayer, room.BlackPlayer = red, black
+
+	room.HandleMove(red, "h2", "e2", "cannon", 0)
+
+	saved, _ := g.gameRepo.GetByID(context.Background(), g.game.ID)
+	if saved.RedTimeRemaining == nil || saved.BlackTimeRemaining == nil {
+		t.Fatal("Expected the clocks to be saved after the move")
+	}
+	if *saved.BlackTimeRemaining != 600 {
+		t.Errorf("Expected black's untouched clock to be saved, got %d", *saved.BlackTimeRemaining)
+	}
+}
+
+// countBroadcasts drains the hub's pending broadcasts and counts messages
+// of each type. The hub is not running in room tests, so broadcasts queue up.
+func countBroadcasts(hub *Hub) map[string]int {
+	counts := make(map[string]int)
+	for {
+		select {
+		case broadcast := <-hub.broadcast:
+			var msg OutgoingMessage
+			if err := json.Unmarshal(broadcast.Message, &msg); err == nil {
+				counts[msg.Type]++
+			}
+		default:
+			return counts
+		}
+	}
+}
+
+func TestRoom_JoinPlayer_DuplicateJoinStartsOnce(t *testing.T) {
+	g := newTestGame(t, config.RulesConfig{})
+	room := g.room(t)
+
+	red := newTestClient(g.hub, g.game.ID, g.game.RedPlayerID)
+	black := newTestClient(g.hub, g.game.ID, g.game.BlackPlayerID)
+
+	for _, client := range []*Client{red, black, black, red} {
+		if err := room.JoinPlayer(client); err != nil {
+			t.Fatalf("JoinPlayer failed: %v", err)
+		}
+	}
+
+	if n := countBroadcasts(g.hub)["game_state"]; n != 1 {
+		t.Errorf("Expected one game start, got %d", n)
+	}
+	if !room.Timer.IsRunning {
+		t.Error("Timer should be running")
+	}
+}
+
+func TestRoom_JoinPlayer_NoRestartAfterGameOver(t *testing.T) {
+	g := newTestGame(t, config.RulesConfig{})
+	room := g.room(t)
+
+	red := newTestClient(g.hub, g.game.ID, g.game.RedPlayerID)
+	black := newTestClient(g.hub, g.game.ID, g.game.BlackPlayerID)
+	room.JoinPlayer(red)
+	room.JoinPlayer(black)
+
+	room.mu.Lock()
+	room.IsGameOver = true
+	room.Timer.Stop()
+	room.mu.Unlock()
+	countBroadcasts(g.hub)
+
+	// A fresh connection for red after the game ended must not restart it
+	room.JoinPlayer(newTestClient(g.hub, g.game.ID, g.game.RedPlayerID))
+
+	if room.Timer.IsRunning {
+		t.Error("Timer should not restart after the game ended")
+	}
+	if n := countBroadcasts(g.hub)["game_state"]; n != 0 {
+		t.Errorf("Expected no further game start, got %d", n)
+	}
+}
+
+func TestRoom_LeavePlayer_BeforeStartDoesNotForfeit(t *testing.T) {
+	g := newTestGame(t, config.RulesConfig{})
+	room := g.room(t)
+	red := newTestClient(g.hub, g.game.ID, g.game.RedPlayerID)
+
+	room.JoinPlayer(red)
+	room.LeavePlayer(red)
+
+	if room.DisconnectedPlayer != "" || room.DisconnectTimer != nil {
+		t.Error("Leaving a pending game should not start the abandonment countdown")
+	}
+	if counts := countBroadcasts(g.hub); counts["opponent_disconnected"] != 0 {
+		t.Errorf("Expected no disconnect broadcast, got %d", counts["opponent_disconnected"])
+	}
+
+	// The player can still claim the game and start it with the opponent
+	black := newTestClient(g.hub, g.game.ID, g.game.BlackPlayerID)
+	room.JoinPlayer(red)
+	room.JoinPlayer(black)
+	if !room.started {
+		t.Error("Expected the game to start once both players connect")
+	}
+}
+
+func TestRoom_JoinPlayer_ReconnectionStillHandled(t *testing.T) {
+	g := newTestGame(t, config.RulesConfig{})
+	room := g.room(t)
+
+	red := newTestClient(g.hub, g.game.ID, g.game.RedPlayerID)
+	black := newTestClient(g.hub, g.game.ID, g.game.BlackPlayerID)
+	room.JoinPlayer(red)
+	room.JoinPlayer(black)
+
+	room.LeavePlayer(black)
+	countBroadcasts(g.hub)
+
+	reconnected := newTestClient(g.hub, g.game.ID, g.game.BlackPlayerID)
+	if err := room.JoinPlayer(reconnected); err != nil {
+		t.Fatalf("JoinPlayer failed: %v", err)
+	}
+
+	if room.BlackPlayer != reconnected {
+		t.Error("Reconnected client should be seated")
+	}
+	if room.DisconnectedPlayer != "" {
+		t.Errorf("Expected no disconnected player, got %s", room.DisconnectedPlayer)
+	}
+	counts := countBroadcasts(g.hub)
+	if counts["connection_status"] != 1 {
+		t.Errorf("Expected the reconnection to be announced, got %v", counts)
+	}
+	if counts["game_state"] != 0 {
+		t.Errorf("Reconnection should not start the game again, got %d game starts", counts["game_state"])
+	}
+}
+
+func TestRoom_Reconnect_SendsFullStateToReconnectingPlayer(t *testing.T) {
+	g := newTestGame(t, config.RulesConfig{})
+	g.playMoves([2]string{"h2", "e2"}, [2]string{"h9", "g7"})
+	room := g.room(t)
+	defer room.Cleanup()
+
+	red := newTestClient(g.hub, g.game.ID, g.game.RedPlayerID)
+	black := newTestClient(g.hub, g.game.ID, g.game.BlackPlayerID)
+	room.JoinPlayer(red)
+	room.JoinPlayer(black)
+
+	room.LeavePlayer(black)
+	countBroadcasts(g.hub)
+	drainMessageTypes(t, red)
+
+	// Distinct clock values show the current clocks are reported
+	room.Timer.mu.Lock()
+	room.Timer.RedTimeRemaining = 250
+	room.Timer.BlackTimeRemaining = 180
+	room.Timer.mu.Unlock()
+
+	reconnected := newTestClient(g.hub, g.game.ID, g.game.BlackPlayerID)
+	if err := room.JoinPlayer(reconnected); err != nil {
+		t.Fatalf("JoinPlayer failed: %v", err)
+	}
+
+	msg := nextMessage(t, reconnected)
+	if msg.Type != "game_state" {
+		t.Fatalf("Expected a game_state message, got %s", msg.Type)
+	}
+	payload := msg.Payload
+	if payload["move_count"] != float64(2) {
+		t.Errorf("Expected 2 moves, got %v", payload["move_count"])
+	}
+	if payload["current_turn"] != "red" {
+		t.Errorf("Expected red to move, got %v", payload["current_turn"])
+	}
+	if payload["red_time"] != float64(250) || payload["black_time"] != float64(180) {
+		t.Errorf("Expected clocks 250/180, got %v/%v", payload["red_time"], payload["black_time"])
+	}
+	if payload["red_rollbacks"] != float64(3) || payload["black_rollbacks"] != float64(3) {
+		t.Errorf("Expected 3 rollbacks each, got %v/%v", payload["red_rollbacks"], payload["black_rollbacks"])
+	}
+	state, ok := payload["state"].(map[string]interface{})
+	if !ok || state["board"] == nil {
+		t.Fatalf("Expected the board in the full state, got %v", payload["state"])
+	}
+
+	// Only the returning player is sent the state
+	if types := drainMessageTypes(t, red); types["game_state"] != 0 {
+		t.Errorf("Expected red not to be sent the state, got %v", types)
+	}
+	if counts := countBroadcasts(g.hub); counts["game_state"] != 0 {
+		t.Errorf("Expected the state not to be broadcast, got %v", counts)
+	}
+}
+
+// awaitBroadcast waits up to timeout for a broadcast of the given type,
+// skipping any others.
+func awaitBroadcast(t *testing.T, hub *Hub, msgType string, timeout time.Duration) OutgoingMessage {
+	t.Helper()
+	deadline := time.After(timeout)
+	for {
+		select {
+		case broadcast := <-hub.broadcast:
+			var msg OutgoingMessage
+			if err := json.Unmarshal(broadcast.Message, &msg); err != nil {
+				t.Fatalf("Failed to unmarshal broadcast: %v", err)
+			}
+			if msg.Type == msgType {
+				return msg
+			}
+		case <-deadline:
+			t.Fatalf("No %s broadcast within %s", msgType, timeout)
+			return OutgoingMessage{}
+		}
+	}
+}
+
+func TestRoom_Disconnect_BroadcastsCountdown(t *testing.T) {
+	g := newTestGame(t, config.RulesConfig{})
+	room := g.room(t)
+	room.GracePeriod = 10 * time.Second
+	room.CountdownInterval = 10 * time.Millisecond
+	defer room.Cleanup()
+
+	red := newTestClient(g.hub, g.game.ID, g.game.RedPlayerID)
+	black := newTestClient(g.hub, g.game.ID, g.game.BlackPlayerID)
+	room.JoinPlayer(red)
+	room.JoinPlayer(black)
+
+	room.LeavePlayer(black)
+
+	for i := 0; i < 2; i++ {
+		msg := awaitBroadcast(t, g.hub, "opponent_disconnect_countdown", time.Second)
+		if msg.Payload["player_id"] != g.game.BlackPlayerID {
+			t.Errorf("Expected countdown for %s, got %v", g.game.BlackPlayerID, msg.Payload["player_id"])
+		}
+		remaining, _ := msg.Payload["remaining_seconds"].(float64)
+		if remaining <= 0 || remaining > 10 {
+			t.Errorf("Expected remaining grace within the 10s period, got %v", msg.Payload["remaining_seconds"])
+		}
+	}
+}
+
+func TestRoom_Reconnect_StopsCountdown(t *testing.T) {
+	g := newTestGame(t, config.RulesConfig{})
+	room := g.room(t)
+	room.GracePeriod = 10 * time.Second
+	room.CountdownInterval = 10 * time.Millisecond
+	defer room.Cleanup()
+
+	red := newTestClient(g.hub, g.game.ID, g.game.RedPlayerID)
+	black := newTestClient(g.hub, g.game.ID, g.game.BlackPlayerID)
+	room.JoinPlayer(red)
+	room.JoinPlayer(black)
+
+	room.LeavePlayer(black)
+	awaitBroadcast(t, g.hub, "opponent_disconnect_countdown", time.Second)
+
+	room.JoinPlayer(newTestClient(g.hub, g.game.ID, g.game.BlackPlayerID))
+	countBroadcasts(g.hub)
+
+	time.Sleep(50 * time.Millisecond)
+	if counts := countBroadcasts(g.hub); counts["opponent_disconnect_countdown"] != 0 {
+		t.Errorf("Expected no countdown after reconnecting, got %d", counts["opponent_disconnect_countdown"])
+	}
+}
+
+func TestRoom_TimerUpdate_NamesActivePlayer(t *testing.T) {
+	room, _, _ := setupRollbackRoom(t, config.RulesConfig{}, nil)
+
+	room.Timer.tick()
+	msg := nextBroadcast(t, room.Hub, "timer")
+	payload := msg.Payload
+	if payload["current_turn"] != "red" || payload["active_player_id"] != room.Game.RedPlayerID {
+		t.Errorf("Expected red's device ID on red's turn, got %v", payload)
+	}
+
+	room.Timer.SwitchTurn()
+	room.Timer.tick()
+	msg = nextBroadcast(t, room.Hub, "timer")
+	payload = msg.Payload
+	if payload["current_turn"] != "black" || payload["active_player_id"] != room.Game.BlackPlayerID {
+		t.Errorf("Expected black's device ID on black's turn, got %v", payload)
+	}
+}
+
+func TestRoom_RollbackRequest_BlockedWhileInCheck(t *testing.T) {
+	room, red, _ := setupRollbackRoom(t, config.RulesConfig{NoRollbackAfterCheck: true}, checkOnRedMoves)
+
+	room.HandleRollbackRequest(red)
+
+	msg := nextMessage(t, red)
+	if msg.Type != "error" || msg.Payload["code"] != "rollback_after_check" {
+		t.Errorf("Expected rollback_after_check error, got %s %v", msg.Type, msg.Payload)
+	}
+	if room.PendingRollback != nil {
+		t.Error("Rollback should not be pending")
+	}
+	if room.engine == nil {
+		t.Error("Expected the check to be read from the room's cached engine")
+	}
+}
+
+func TestRoom_RollbackRequest_OutOfTurnAfterEscapingCheck(t *testing.T) {
+	moves := append(append([][2]string{}, checkOnRedMoves...), [2]string{"e0", "d0"})
+	room, red, _ := setupRollbackRoom(t, config.RulesConfig{NoRollbackAfterCheck: true}, moves)
+
+	room.HandleRollbackRequest(red)
+
+	// Red has just moved, so the turn check rejects the request first
+	msg := nextMessage(t, red)
+	if msg.Type != "error" || msg.Payload["code"] != "not_your_turn" {
+		t.Errorf("Expected not_your_turn error, got %s %v", msg.Type, msg.Payload)
+	}
+	if room.PendingRollback != nil {
+		t.Error("Rollback should not be pending")
+	}
+}
+
+func TestRoom_RollbackRequest_AllowedOnceCheckIsOver(t *testing.T) {
+	moves := append(append([][2]string{}, checkOnRedMoves...), [2]string{"e0", "d0"}, [2]string{"i9", "i8"})
+	room, red, _ := setupRollbackRoom(t, config.RulesConfig{NoRollbackAfterCheck: true}, moves)
+
+	room.HandleRollbackRequest(red)
+
+	expectNoMessage(t, red)
+	if room.PendingRollback == nil || room.PendingRollback.RequestingPlayerID != "red-player" {
+		t.Error("Rollback should be pending for red")
+	}
+}
+
+func TestRoom_RollbackRequest_AllowedForCheckingPlayer(t *testing.T) {
+	// Black gave check and asks to undo red's escape on their own turn
+	moves := append(append([][2]string{}, checkOnRedMoves...), [2]string{"e0", "d0"})
+	room, _, black := setupRollbackRoom(t, config.RulesConfig{NoRollbackAfterCheck: true}, moves)
+
+	room.HandleRollbackRequest(black)
+
+	expectNoMessage(t, black)
+	if room.PendingRollback == nil {
+		t.Error("The player giving check should still be able to request a rollback")
+	}
+}
+
+func TestRoom_RollbackRequest_AllowedInCheckWhenRuleDisabled(t *testing.T) {
+	room, red, _ := setupRollbackRoom(t, config.RulesConfig{}, checkOnRedMoves)
+
+	room.HandleRollbackRequest(red)
+
+	expectNoMessage(t, red)
+	if room.PendingRollback == nil {
+		t.Error("Rollback should be pending when the rule is disabled")
+	}
+}
+
+func TestRoom_RollbackRequest_RejectedOffTurn(t *testing.T) {
+	// Black just moved, so it is red's turn
+	room, _, black := setupRollbackRoom(t, config.RulesConfig{}, checkOnRedMoves)
+
+	room.HandleRollbackRequest(black)
+
+	msg := nextMessage(t, black)
+	if msg.Type != "error" || msg.Payload["code"] != "not_your_turn" {
+		t.Errorf("Expected not_your_turn error, got %s %v", msg.Type, msg.Payload)
+	}
+	if room.PendingRollback != nil {
+		t.Error("Rollback should not be pending")
+	}
+}
+
+func TestRoom_RollbackRequest_AllowedOnTurn(t *testing.T) {
+	room, _, black := setupRollbackRoom(t, config.RulesConfig{}, [][2]string{{"h2", "e2"}})
+
+	room.HandleRollbackRequest(black)
+
+	expectNoMessage(t, black)
+	if room.PendingRollback == nil || room.PendingRollback.MoveNumberToRevert != 1 {
+		t.Errorf("Expected a pending rollback of move 1, got %+v", room.PendingRollback)
+	}
+	if room.engine == nil {
+		t.Error("Expected the last move to be read from the room's cached engine")
+	}
+}
+
+func TestRoom_RollbackAcceptedAfterMate(t *testing.T) {
+	room, red, black := setupRollbackRoom(t, config.RulesConfig{}, [][2]string{{"h2", "e2"}})
+	rollbacks := room.Game.BlackRollbacksRemaining
+
+	room.HandleRollbackRequest(black)
+	if room.PendingRollback == nil {
+		t.Fatal("Expected a pending rollback")
+	}
+	countBroadcasts(room.Hub)
+
+	// The game ends before red answers the request
+	room.mu.Lock()
+	room.endGame(room.Game.RedPlayerID, "red", models.ResultTypeCheckmate)
+	room.mu.Unlock()
+
+	if room.PendingRollback != nil || room.RollbackTimeout != nil {
+		t.Error("Ending the game should cancel the pending rollback and its timer")
+	}
+	msg := nextBroadcast(t, room.Hub, "rollback_result")
+	if msg.Payload["accepted"] != false || msg.Payload["reason"] != "game_ended" {
+		t.Errorf("Expected the rollback to be cancelled, got %v", msg.Payload)
+	}
+	countBroadcasts(room.Hub)
+
+	// The late acceptance is a no-op answered only to red
+	room.HandleRollbackResponse(red, true)
+
+	msg = nextMessage(t, red)
+	if msg.Type != "error" || msg.Payload["code"] != "game_ended" {
+		t.Errorf("Expected game_ended error, got %s %v", msg.Type, msg.Payload)
+	}
+	if room.MoveCount != 1 {
+		t.Errorf("Expected the finished game to keep its moves, got move count %d", room.MoveCount)
+	}
+	if room.Game.BlackRollbacksRemaining != rollbacks {
+		t.Errorf("Expected black's rollbacks to be kept, got %d", room.Game.BlackRollbacksRemaining)
+	}
+	if counts := countBroadcasts(room.Hub); counts["rollback_result"] != 0 {
+		t.Errorf("Expected no second cancellation, got %d", counts["rollback_result"])
+	}
+}
+
+func TestRoom_RollbackResponse_CancelsPendingAfterGameOver(t *testing.T) {
+	room, red, black := setupRollbackRoom(t, config.RulesConfig{}, [][2]string{{"h2", "e2"}})
+
+	room.HandleRollbackRequest(black)
+	countBroadcasts(room.Hub)
+	room.IsGameOver = true
+
+	room.HandleRollbackResponse(red, true)
+
+	if room.PendingRollback != nil {
+		t.Error("Expected the pending rollback to be cleared")
+	}
+	msg := nextBroadcast(t, room.Hub, "rollback_result")
+	if msg.Payload["accepted"] != false || msg.Payload["reason"] != "game_ended" {
+		t.Errorf("Expected a cancelled rollback result, got %v", msg.Payload)
+	}
+	if msg.Payload["rollbacks_remaining"] != float64(room.Game.BlackRollbacksRemaining) {
+		t.Errorf("Expected black's remaining rollbacks, got %v", msg.Payload["rollbacks_remaining"])
+	}
+}
+
+func TestRoom_Rollback_CasualUnlimited(t *testing.T) {
+	room, red, black := setupRollbackRoom(t, config.RulesConfig{CasualUnlimitedRollbacks: true}, [][2]string{{"h2", "e2"}})
+	room.Game.Ranked = false
+	room.Game.BlackRollbacksRemaining = 0
+
+	room.HandleRollbackRequest(black)
+
+	expectNoMessage(t, black)
+	if room.PendingRollback == nil {
+		t.Fatal("Expected a casual rollback to be allowed with no rollbacks left")
+	}
+
+	// The opponent must still accept it
+	room.HandleRollbackResponse(red, true)
+
+	if room.MoveCount != 0 {
+		t.Errorf("Expected the move to be reverted, got move count %d", room.MoveCount)
+	}
+	if room.Game.BlackRollbacksRemaining != 0 {
+		t.Errorf("Expected the rollback count to be left alone, got %d", room.Game.BlackRollbacksRemaining)
+	}
+}
+
+func TestRoom_RollbackResponse_RequesterCannotAccept(t *testing.T) {
+	room, _, black := setupRollbackRoom(t, config.RulesConfig{CasualUnlimitedRollbacks: true}, [][2]string{{"h2", "e2"}})
+	room.Game.Ranked = false
+	rollbacks := room.Game.BlackRollbacksRemaining
+
+	room.HandleRollbackRequest(black)
+	room.HandleRollbackResponse(black, true)
+
+	msg := nextMessage(t, black)
+	if msg.Type != "error" || msg.Payload["code"] != "own_rollback_request" {
+		t.Errorf("Expected own_rollback_request error, got %s %v", msg.Type, msg.Payload)
+	}
+	if room.MoveCount != 1 {
+		t.Errorf("Expected the move to stay, got move count %d", room.MoveCount)
+	}
+	if room.Game.BlackRollbacksRemaining != rollbacks {
+		t.Errorf("Expected black's rollbacks to be unchanged, got %d", room.Game.BlackRollbacksRemaining)
+	}
+	if room.PendingRollback == nil {
+		t.Error("Expected the request to stay pending for the opponent")
+	}
+}
+
+func TestRoom_Rollback_RankedStillLimited(t *testing.T) {
+	room, _, black := setupRollbackRoom(t, config.RulesConfig{CasualUnlimitedRollbacks: true}, [][2]string{{"h2", "e2"}})
+	room.Game.Ranked = true
+	room.Game.BlackRollbacksRemaining = 0
+
+	room.HandleRollbackRequest(black)
+
+	msg := nextMessage(t, black)
+	if msg.Type != "error" || msg.Payload["code"] != "no_rollbacks" {
+		t.Errorf("Expected no_rollbacks error in a ranked game, got %s %v", msg.Type, msg.Payload)
+	}
+}
+
+func TestRoom_RollbackRequest_RejectedWithoutMoves(t *testing.T) {
+	room, red, _ := setupRollbackRoom(t, config.RulesConfig{}, nil)
+
+	room.HandleRollbackRequest(red)
+
+	msg := nextMessage(t, red)
+	if msg.Type != "error" || msg.Payload["code"] != "invalid_rollback" {
+		t.Errorf("Expected invalid_rollback error, got %s %v", msg.Type, msg.Payload)
+	}
+}
+
+func TestRoom_RollbackRequest_RejectedWhenLastMoveIsOwn(t *testing.T) {
+	// Local state claims it is black's turn but the stored last move is black's
+	room, _, black := setupRollbackRoom(t, config.RulesConfig{}, [][2]string{{"h2", "e2"}, {"h9", "g7"}})
+	room.CurrentTurn = "black"
+
+	room.HandleRollbackRequest(black)
+
+	msg := nextMessage(t, black)
+	if msg.Type != "error" || msg.Payload["code"] != "invalid_rollback" {
+		t.Errorf("Expected invalid_rollback error, got %s %v", msg.Type, msg.Payload)
+	}
+	if room.PendingRollback != nil {
+		t.Error("Rollback should not be pending")
+	}
+}
+
+// ========== Resync Tests ==========
+
+func TestRoom_Resync_SmallDelta(t *testing.T) {
+	g := newTestGame(t, config.RulesConfig{})
+	g.playMoves(checkOnRedMoves...)
+	room := g.room(t)
+	red := newTestClient(g.hub, g.game.ID, g.game.RedPlayerID)
+
+	room.HandleResync(red, 4)
+
+	msg := nextMessage(t, red)
+	if msg.Type != "resync" || msg.Payload["mode"] != "delta" {
+		t.Fatalf("Expected delta resync, got %s %v", msg.Type, msg.Payload["mode"])
+	}
+	moves, ok := msg.Payload["moves"].([]interface{})
+	if !ok || len(moves) != 2 {
+		t.Fatalf("Expected 2 missed moves, got %v", msg.Payload["moves"])
+	}
+	first := moves[0].(map[string]interface{})
+	if first["move_number"] != float64(5) || first["from"] != "c3" || first["to"] != "c4" {
+		t.Errorf("Unexpected first move: %v", first)
+	}
+	last := moves[1].(map[string]interface{})
+	if last["captured"] != "advisor" || last["is_check"] != true {
+		t.Errorf("Expected last move to capture an advisor with check, got %v", last)
+	}
+	if msg.Payload["move_count"] != float64(6) || msg.Payload["current_turn"] != "red" {
+		t.Errorf("Unexpected position summary: %v", msg.Payload)
+	}
+	if _, hasState := msg.Payload["state"]; hasState {
+		t.Error("Delta resync should not include the full state")
+	}
+}
+
+func TestRoom_Resync_UsesCachedEngine(t *testing.T) {
+	g := newTestGame(t, config.RulesConfig{})
+	g.playMoves(checkOnRedMoves...)
+	room := g.room(t)
+	red := newTestClient(g.hub, g.game.ID, g.game.RedPlayerID)
+
+	room.HandleResync(red, 4)
+	nextMessage(t, red)
+	engine := room.engine
+	if engine == nil {
+		t.Fatal("Expected the engine to be cached after a resync")
+	}
+
+	room.HandleResync(red, 6)
+	nextMessage(t, red)
+	if room.engine != engine {
+		t.Error("Expected later resyncs to reuse the cached engine")
+	}
+}
+
+func TestRoom_Resync_UpToDate(t *testing.T) {
+	g := newTestGame(t, config.RulesConfig{})
+	g.playMoves(checkOnRedMoves...)
+	room := g.room(t)
+	red := newTestClient(g.hub, g.game.ID, g.game.RedPlayerID)
+
+	room.HandleResync(red, 6)
+
+	msg := nextMessage(t, red)
+	if msg.Payload["mode"] != "delta" {
+		t.Fatalf("Expected delta resync, got %v", msg.Payload["mode"])
+	}
+	if moves := msg.Payload["moves"].([]interface{}); len(moves) != 0 {
+		t.Errorf("Expected no missed moves, got %d", len(moves))
+	}
+}
+
+func TestRoom_Resync_FullStateFallback(t *testing.T) {
+	// Shuffle chariots back and forth to build a long history
+	var moves [][2]string
+	for len(moves) <= maxResyncDelta+2 {
+		moves = append(moves, [2]string{"a0", "a1"}, [2]string{"a9", "a8"}, [2]string{"a1", "a0"}, [2]string{"a8", "a9"})
+	}
+
+	testCases := []struct {
+		name           string
+		lastMoveNumber int
+	}{
+		{"too far behind", 0},
+		{"unknown future move", len(moves) + 5},
+		{"negative move number", -1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := newTestGame(t, config.RulesConfig{})
+			g.playMoves(moves...)
+			room := g.room(t)
+			red := newTestClient(g.hub, g.game.ID, g.game.RedPlayerID)
+
+			room.HandleResync(red, tc.lastMoveNumber)
+
+			msg := nextMessage(t, red)
+			if msg.Type != "resync" || msg.Payload["mode"] != "full" {
+				t.Fatalf("Expected full resync, got %s %v", msg.Type, msg.Payload["mode"])
+			}
+			state, ok := msg.Payload["state"].(map[string]interface{})
+			if !ok {
+				t.Fatal("Full resync should include the game state")
+			}
+			if state["move_count"] != float64(len(moves)) {
+				t.Errorf("Expected move_count %d, got %v", len(moves), state["move_count"])
+			}
+			if _, hasMoves := msg.Payload["moves"]; hasMoves {
+				t.Error("Full resync should not include a move delta")
+			}
+		})
+	}
+}
+
+// ========== Valid Moves Tests ==========
+
+// validMoves asks for the piece on from and returns the destinations sent back.
+func validMoves(t *testing.T, room *GameRoom, client *Client, from string) []string {
+	t.Helper()
+	room.HandleGetValidMoves(client, from)
+
+	msg := nextMessage(t, client)
+	if msg.Type != "valid_moves" {
+		t.Fatalf("Expected valid_moves, got %s %v", msg.Type, msg.Payload)
+	}
+	if msg.Payload["from"] != from {
+		t.Errorf("Expected from %s, got %v", from, msg.Payload["from"])
+	}
+	raw, ok := msg.Payload["moves"].([]interface{})
+	if !ok {
+		t.Fatalf("Expected a move list, got %v", msg.Payload["moves"])
+	}
+	moves := make([]string, len(raw))
+	for i, move := range raw {
+		moves[i] = move.(string)
+	}
+	sort.Strings(moves)
+	return moves
+}
+
+func TestRoom_GetValidMoves_OwnPieceOnTurn(t *testing.T) {
+	room, red, black := setupRollbackRoom(t, config.RulesConfig{}, nil)
+	room.RedPlayer, room.BlackPlayer = red, black
+
+	if moves := validMoves(t, room, red, "b0"); !reflect.DeepEqual(moves, []string{"a2", "c2"}) {
+		t.Errorf("Expected horse moves [a2 c2], got %v", moves)
+	}
+}
+
+func TestRoom_GetValidMoves_UsesCachedEngine(t *testing.T) {
+	room, red, black := setupRollbackRoom(t, config.RulesConfig{}, nil)
+	room.RedPlayer, room.BlackPlayer = red, black
+
+	validMoves(t, room, red, "b0")
+	engine := room.engine
+	if engine == nil {
+		t.Fatal("Expected the engine to be cached after asking for moves")
+	}
+
+	validMoves(t, room, red, "h0")
+	if room.engine != engine {
+		t.Error("Expected later requests to reuse the cached engine")
+	}
+}
+
+func TestRoom_GetValidMoves_FiltersSelfCheck(t *testing.T) {
+	room, red, black := setupRollbackRoom(t, config.RulesConfig{}, checkOnRedMoves)
+	room.RedPlayer, room.BlackPlayer = red, black
+
+	// The horse cannot answer the chariot's check
+	if moves := validMoves(t, room, red, "h0"); len(moves) != 0 {
+		t.Errorf("Expected no horse moves while in check, got %v", moves)
+	}
+	if moves := validMoves(t, room, red, "e0"); !reflect.DeepEqual(moves, []string{"d0", "e1"}) {
+		t.Errorf("Expected the general to capture or step away, got %v", moves)
+	}
+}
+
+func TestRoom_GetValidMoves_OutOfTurnIsEmpty(t *testing.T) {
+	room, red, black := setupRollbackRoom(t, config.RulesConfig{}, nil)
+	room.RedPlayer, room.BlackPlayer = red, black
+
+	if moves := validMoves(t, room, black, "b9"); len(moves) != 0 {
+		t.Errorf("Expected no moves out of turn, got %v", moves)
+	}
+}
+
+func TestRoom_GetValidMoves_Rejected(t *testing.T) {
+	testCases := []struct {
+		name     string
+		from     string
+		noAssist bool
+		code     string
+	}{
+		{"empty square", "e4", false, "no_piece"},
+		{"opponent piece", "b9", false, "not_your_piece"},
+		{"invalid square", "z9", false, "invalid_position"},
+		{"assist disabled", "b0", true, "assist_disabled"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			room, red, black := setupRollbackRoom(t, config.RulesConfig{}, nil)
+			room.RedPlayer, room.BlackPlayer = red, black
+			room.Game.AssistEnabled = !tc.noAssist
+
+			room.HandleGetValidMoves(red, tc.from)
+
+			msg := nextMessage(t, red)
+			if msg.Type != "error" || msg.Payload["code"] != tc.code {
+				t.Errorf("Expected %s error, got %s %v", tc.code, msg.Type, msg.Payload)
+			}
+		})
+	}
+}
+
+func TestRoom_GetValidMoves_RequiresSeat(t *testing.T) {
+	room, _, _ := setupRollbackRoom(t, config.RulesConfig{}, nil)
+	stranger := newTestClient(room.Hub, room.GameID, "stranger")
+
+	room.HandleGetValidMoves(stranger, "b0")
+
+	msg := nextMessage(t, stranger)
+	if msg.Type != "error" || msg.Payload["code"] != "not_joined" {
+		t.Errorf("Expected not_joined error, got %s %v", msg.Type, msg.Payload)
+	}
+}
+
+// nextBroadcast returns the next pending hub broadcast of the given type.
+func nextBroadcast(t *testing.T, hub *Hub, msgType string) OutgoingMessage {
+	t.Helper()
+	for {
+		select {
+		case broadcast := <-hub.broadcast:
+			var msg OutgoingMessage
+			if err := json.Unmarshal(broadcast.Message, &msg); err != nil {
+				t.Fatalf("Failed to unmarshal broadcast: %v", err)
+			}
+			if msg.Type == msgType {
+				return msg
+			}
+		default:
+			t.Fatalf("No %s broadcast pending", msgType)
+			return OutgoingMessage{}
+		}
+	}
+}
+
+func TestRoom_HandleMove_CapturingCheckEvent(t *testing.T) {
+	room, red, black := setupRollbackRoom(t, config.RulesConfig{}, checkOnRedMoves[:5])
+	room.RedPlayer, room.BlackPlayer = red, black
+	countBroadcasts(room.Hub)
+
+	// The black chariot takes the d0 advisor with check
+	room.HandleMove(black, "d8", "d0", "chariot", 0)
+
+	result := nextMessage(t, black)
+	if result.Type != "move_result" {
+		t.Fatalf("Expected move_result, got %s", result.Type)
+	}
+	move, ok := result.Payload["move"].(map[string]interface{})
+	if !ok {
+		t.Fatal("move_result should include the move")
+	}
+	if move["event"] != "check" || move["is_capture"] != true || move["is_check"] != true {
+		t.Errorf("Expected a capturing check, got event=%v is_capture=%v is_check=%v",
+			move["event"], move["is_capture"], move["is_check"])
+	}
+
+	opponent := nextBroadcast(t, room.Hub, "opponent_move")
+	if opponent.Payload["event"] != "check" || opponent.Payload["is_capture"] != true {
+		t.Errorf("Expected opponent_move to report a capturing check, got event=%v is_capture=%v",
+			opponent.Payload["event"], opponent.Payload["is_capture"])
+	}
+}
+
+func TestRoom_HandleMove_RejectsIllegalMove(t *testing.T) {
+	testCases := []struct {
+		name      string
+		from, to  string
+		pieceType string
+	}{
+		{"diagonal chariot move", "a0", "b1", "chariot"},
+		{"wrong piece type", "a0", "a1", "horse"},
+		{"empty square", "e4", "e5", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			room, red, black := setupRollbackRoom(t, config.RulesConfig{}, nil)
+			room.RedPlayer, room.BlackPlayer = red, black
+
+			room.HandleMove(red, tc.from, tc.to, tc.pieceType, 0)
+
+			result := nextMessage(t, red)
+			if result.Type != "move_result" || result.Payload["success"] != false {
+				t.Fatalf("Expected a failed move_result, got %s %v", result.Type, result.Payload)
+			}
+			if result.Payload["error"] == nil {
+				t.Error("A rejected move should say why")
+			}
+			if countBroadcasts(room.Hub)["opponent_move"] != 0 {
+				t.Error("A rejected move should not reach the opponent")
+			}
+
+			moves, err := room.GameService.GetMoves(context.Background(), room.GameID)
+			if err != nil {
+				t.Fatalf("GetMoves failed: %v", err)
+			}
+			if len(moves) != 0 || room.MoveCount != 0 || room.CurrentTurn != models.PlayerColorRed {
+				t.Errorf("Rejected move should not be stored, got %d moves, count %d, turn %s",
+					len(moves), room.MoveCount, room.CurrentTurn)
+			}
+
+			// The board is unchanged, so red can still play
+			room.HandleMove(red, "a0", "a1", "chariot", 0)
+			if result := nextMessage(t, red); result.Payload["success"] != true {
+				t.Errorf("Expected a legal move to succeed after the rejection, got %v", result.Payload)
+			}
+		})
+	}
+}
+
+func TestRoom_HandleMove_CheckmateEndsGame(t *testing.T) {
+	room, red, black := setupRollbackRoom(t, config.RulesConfig{}, nil)
+	room.RedPlayer, room.BlackPlayer = red, black
+
+	// The cannon takes the c9 elephant and checks along the back rank over
+	// the d9 advisor, while the advisor moved to e8 blocks the escape
+	playRoomMoves(room, red, black,
+		[2]string{"b2", "b4"}, [2]string{"a6", "a5"},
+		[2]string{"b4", "c4"}, [2]string{"f9", "e8"},
+		[2]string{"c4", "c9"})
+
+	if !room.IsGameOver {
+		t.Fatal("Expected checkmate to end the game")
+	}
+	msg := nextBroadcast(t, room.Hub, "game_end")
+	if msg.Payload["result_type"] != string(models.ResultTypeCheckmate) ||
+		msg.Payload["winner_id"] != room.Game.RedPlayerID || msg.Payload["winner_color"] != "red" {
+		t.Errorf("Expected red to win by checkmate, got %v", msg.Payload)
+	}
+}
+
+func TestRoom_HandleMove_RejectsUnseatedClient(t *testing.T) {
+	g := newTestGame(t, config.RulesConfig{})
+	room := g.room(t)
+	red := newTestClient(g.hub, g.game.ID, g.game.RedPlayerID)
+	black := newTestClient(g.hub, g.game.ID, g.game.BlackPlayerID)
+	room.BlackPlayer = black
+
+	// Red's device sends a move before joining; it is red's turn, so only
+	// the seat check can reject it
+	room.HandleMove(red, "h2", "e2", "cannon", 0)
+
+	msg := nextMessage(t, red)
+	if msg.Type != "error" || msg.Payload["code"] != "not_joined" {
+		t.Errorf("Expected not_joined error, got %s %v", msg.Type, msg.Payload)
+	}
+	if room.MoveCount != 0 {
+		t.Errorf("Move from an unseated client should not be recorded, got %d moves", room.MoveCount)
+	}
+
+	// Another connection for a seated player is not the seated client either
+	room.HandleMove(newTestClient(g.hub, g.game.ID, g.game.BlackPlayerID), "h7", "e7", "cannon", 0)
+	if room.MoveCount != 0 {
+		t.Errorf("Move from a second black connection should not be recorded, got %d moves", room.MoveCount)
+	}
+}
+
+func TestRoom_HandleMove_QuietAndCaptureEvents(t *testing.T) {
+	g := newTestGame(t, config.RulesConfig{})
+	room := g.room(t)
+	red := newTestClient(g.hub, g.game.ID, g.game.RedPlayerID)
+	black := newTestClient(g.hub, g.game.ID, g.game.BlackPlayerID)
+	room.RedPlayer, room.BlackPlayer = red, black
+
+	testCases := []struct {
+		client    *Client
+		from, to  string
+		event     string
+		isCapture bool
+	}{
+		{red, "h2", "e2", "move", false},
+		{black, "b7", "b0", "capture", true}, // Cannon takes the b0 horse over the b2 cannon
+	}
+
+	for _, tc := range testCases {
+		room.HandleMove(tc.client, tc.from, tc.to, "cannon", 0)
+		move, _ := nextMessage(t, tc.client).Payload["move"].(map[string]interface{})
+		if move["event"] != tc.event || move["is_capture"] != tc.isCapture {
+			t.Errorf("%s-%s: expected event=%s is_capture=%v, got event=%v is_capture=%v",
+				tc.from, tc.to, tc.event, tc.isCapture, move["event"], move["is_capture"])
+		}
+	}
+}
+
+// resignSuggestionRules suggests resigning after two consecutive positions
+// at least a horse down.
+var resignSuggestionRules = config.RulesConfig{
+	ResignSuggestionThreshold: -40,
+	ResignSuggestionMoves:     2,
+}
+
+// repetitionMoves shuffles both left horses out and back twice, repeating
+// the starting position for the third time on the last move.
+var repetitionMoves = [][2]string{
+	{"b0", "c2"}, {"b9", "c7"}, {"c2", "b0"}, {"c7", "b9"},
+	{"b0", "c2"}, {"b9", "c7"}, {"c2", "b0"}, {"c7", "b9"},
+}
+
+func TestRoom_HandleMove_RepetitionDraw(t *testing.T) {
+	rules := config.RulesConfig{Features: []string{string(config.FeatureRepetition)}}
+	room, red, black := setupRollbackRoom(t, rules, nil)
+	room.RedPlayer, room.BlackPlayer = red, black
+
+	playRoomMoves(room, red, black, repetitionMoves[:7]...)
+	if room.IsGameOver {
+		t.Fatal("Game should not end before the third repetition")
+	}
+
+	// Black makes the eighth move
+	playRoomMoves(room, black, red, repetitionMoves[7:]...)
+	if !room.IsGameOver {
+		t.Fatal("Expected the game to end on threefold repetition")
+	}
+	msg := nextBroadcast(t, room.Hub, "game_end")
+	if msg.Payload["result_type"] != string(models.ResultTypeDraw) {
+		t.Errorf("Expected a draw, got %v", msg.Payload["result_type"])
+	}
+}
+
+func TestRoom_HandleMove_RepetitionIgnoredWithoutFeature(t *testing.T) {
+	room, red, black := setupRollbackRoom(t, config.RulesConfig{}, nil)
+	room.RedPlayer, room.BlackPlayer = red, black
+
+	playRoomMoves(room, red, black, repetitionMoves...)
+	if room.IsGameOver {
+		t.Error("Repetition should not end the game unless the feature is enabled")
+	}
+}
+
+func TestRoom_HandleMove_NoCaptureDraw(t *testing.T) {
+	rules := config.RulesConfig{NoCaptureDrawPlies: game.DefaultNoCaptureDrawPlies}
+	room, red, black := setupRollbackRoom(t, rules, nil)
+	room.RedPlayer, room.BlackPlayer = red, black
+
+	// Shuffle the horses up to the last ply before the limit
+	for ply := 4; ply < game.DefaultNoCaptureDrawPlies; ply += 4 {
+		playRoomMoves(room, red, black, repetitionMoves[:4]...)
+	}
+	playRoomMoves(room, red, black, repetitionMoves[:3]...)
+	if room.IsGameOver {
+		t.Fatalf("Game should not end before %d plies", game.DefaultNoCaptureDrawPlies)
+	}
+
+	playRoomMoves(room, black, red, repetitionMoves[3:4]...)
+	if !room.IsGameOver {
+		t.Fatal("Expected the game to be drawn by the no-capture rule")
+	}
+	msg := nextBroadcast(t, room.Hub, "game_end")
+	if msg.Payload["result_type"] != string(models.ResultTypeDraw) {
+		t.Errorf("Expected a draw, got %v", msg.Payload["result_type"])
+	}
+}
+
+// perpetualCheckMoves bring red's chariot up the b-file and then check
+// black's general between e8 and e7 from b8 and b7, reaching the checking
+// position on b8 for the third time on the last move.
+var perpetualCheckMoves = [][2]string{
+	{"b2", "d2"}, {"e9", "e8"}, {"a0", "a1"}, {"i9", "i8"},
+	{"a1", "b1"}, {"i8", "i9"}, {"b1", "b7"}, {"i9", "i8"},
+	{"b7", "b8"}, {"e8", "e7"}, {"b8", "b7"}, {"e7", "e8"},
+	{"b7", "b8"}, {"e8", "e7"}, {"b8", "b7"}, {"e7", "e8"},
+	{"b7", "b8"},
+}
+
+func TestRoom_HandleMove_PerpetualCheckLoses(t *testing.T) {
+	rules := config.RulesConfig{Features: []string{string(config.FeaturePerpetualCheck)}}
+	room, red, black := setupRollbackRoom(t, rules, nil)
+	room.RedPlayer, room.BlackPlayer = red, black
+
+	playRoomMoves(room, red, black, perpetualCheckMoves[:16]...)
+	if room.IsGameOver {
+		t.Fatal("Game should not end before the third checking repetition")
+	}
+
+	playRoomMoves(room, red, black, perpetualCheckMoves[16:]...)
+	if !room.IsGameOver {
+		t.Fatal("Expected the game to end on perpetual check")
+	}
+	msg := nextBroadcast(t, room.Hub, "game_end")
+	if msg.Payload["result_type"] != string(models.ResultTypePerpetualCheck) {
+		t.Errorf("Expected a perpetual check result, got %v", msg.Payload["result_type"])
+	}
+	if msg.Payload["winner_id"] != room.Game.BlackPlayerID {
+		t.Errorf("Expected black to win against the checking side, got %v", msg.Payload["winner_id"])
+	}
+}
+
+func TestRoom_HandleMove_PerpetualCheckIgnoredWithoutFeature(t *testing.T) {
+	room, red, black := setupRollbackRoom(t, config.RulesConfig{}, nil)
+	room.RedPlayer, room.BlackPlayer = red, black
+
+	playRoomMoves(room, red, black, perpetualCheckMoves...)
+	if room.IsGameOver {
+		t.Error("Perpetual check should not end the game unless the feature is enabled")
+	}
+
+	// Stored games replay past the perpetual check, even with the rule on
+	room.HandleMove(black, "e8", "e7", "", 0)
+	if room.MoveCount != len(perpetualCheckMoves)+1 {
+		t.Fatalf("Expected the game to go on, got %d moves", room.MoveCount)
+	}
+	for _, opts := range [][]game.EngineOption{nil, {game.WithPerpetualCheckRule(true)}} {
+		if _, err := room.GameService.LoadEngine(context.Background(), room.GameID, opts...); err != nil {
+			t.Errorf("Expected the game to replay, got %v", err)
+		}
+	}
+}
+
+// playRoomMoves plays moves through the room, alternating between the two
+// clients starting with the first.
+func playRoomMoves(room *GameRoom, red, black *Client, moves ...[2]string) {
+	for i, m := range moves {
+		client := red
+		if i%2 == 1 {
+			client = black
+		}
+		room.HandleMove(client, m[0], m[1], "", 0)
+	}
+}
+
+// drainMessageTypes returns the types of all messages pending for the client.
+func drainMessageTypes(t *testing.T, client *Client) map[string]int {
+	t.Helper()
+	types := make(map[string]int)
+	for {
+		select {
+		case data := <-client.Send:
+			var msg OutgoingMessage
+			if err := json.Unmarshal(data, &msg); err != nil {
+				t.Fatalf("Failed to unmarshal message: %v", err)
+			}
+			types[msg.Type]++
+		default:
+			return types
+		}
+	}
+}
+
+// optInResignSuggestions turns on resign suggestions for the given players.
+func (g *testGame) optInResignSuggestions(playerIDs ...string) {
+	for _, playerID := range playerIDs {
+		g.userRepo.users[playerID].ResignSuggestions = true
+	}
+}
+
+func TestRoom_ResignSuggestion_SentInLostPosition(t *testing.T) {
+	g := newTestGame(t, resignSuggestionRules)
+	g.optInResignSuggestions(g.game.RedPlayerID, g.game.BlackPlayerID)
+	room := g.room(t)
+	red := newTestClient(g.hub, g.game.ID, g.game.RedPlayerID)
+	black := newTestClient(g.hub, g.game.ID, g.game.BlackPlayerID)
+	room.RedPlayer, room.BlackPlayer = red, black
+
+	// Red's cannon takes the h9 horse over the black cannon and black never
+	// recaptures
+	playRoomMoves(room, red, black, [2]string{"h2", "h9"}, [2]string{"a9", "a8"}, [2]string{"a3", "a4"})
+
+	if got := drainMessageTypes(t, black)["resign_suggestion"]; got != 1 {
+		t.Errorf("Expected one resign_suggestion for black, got %d", got)
+	}
+	if got := drainMessageTypes(t, red)["resign_suggestion"]; got != 0 {
+		t.Errorf("Expected no resign_suggestion for red, got %d", got)
+	}
+	if room.IsGameOver {
+		t.Error("A resign suggestion must never end the game")
+	}
+}
+
+func TestRoom_ResignSuggestion_NotSentInBalancedPosition(t *testing.T) {
+	g := newTestGame(t, resignSuggestionRules)
+	g.optInResignSuggestions(g.game.RedPlayerID, g.game.BlackPlayerID)
+	room := g.room(t)
+	red := newTestClient(g.hub, g.game.ID, g.game.RedPlayerID)
+	black := newTestClient(g.hub, g.game.ID, g.game.BlackPlayerID)
+	room.RedPlayer, room.BlackPlayer = red, black
+
+	playRoomMoves(room, red, black,
+		[2]string{"h2", "e2"}, [2]string{"h9", "g7"},
+		[2]string{"h0", "g2"}, [2]string{"i9", "h9"},
+		[2]string{"i0", "h0"}, [2]string{"b9", "c7"},
+	)
+
+	for _, client := range []*Client{red, black} {
+		if got := drainMessageTypes(t, client)["resign_suggestion"]; got != 0 {
+			t.Errorf("Expected no resign_suggestion for %s, got %d", client.DeviceID, got)
+		}
+	}
+}
+
+func TestRoom_ResignSuggestion_NotSentWithoutOptIn(t *testing.T) {
+	g := newTestGame(t, resignSuggestionRules)
+	g.optInResignSuggestions(g.game.RedPlayerID)
+	room := g.room(t)
+	red := newTestClient(g.hub, g.game.ID, g.game.RedPlayerID)
+	black := newTestClient(g.hub, g.game.ID, g.game.BlackPlayerID)
+	room.RedPlayer, room.BlackPlayer = red, black
+
+	playRoomMoves(room, red, black, [2]string{"h2", "h9"}, [2]string{"a9", "a8"}, [2]string{"a3", "a4"})
+
+	if got := drainMessageTypes(t, black)["resign_suggestion"]; got != 0 {
+		t.Errorf("Expected no resign_suggestion for black without opting in, got %d", got)
+	}
+}
+
+func TestRoom_GameState_ReportsCheck(t *testing.T) {
+	room, red, black := setupRollbackRoom(t, config.RulesConfig{}, nil)
+	room.RedPlayer, room.BlackPlayer = red, black
+
+	playRoomMoves(room, red, black, checkOnRedMoves[:5]...)
+	countBroadcasts(room.Hub)
+
+	room.HandleMove(black, "d8", "d0", "chariot", 0)
+	if msg := nextBroadcast(t, room.Hub, "opponent_move"); msg.Payload["is_check"] != true {
+		t.Errorf("Expected opponent_move to carry is_check, got %v", msg.Payload["is_check"])
+	}
+
+	room.mu.Lock()
+	room.sendGameState()
+	room.mu.Unlock()
+
+	msg := nextBroadcast(t, room.Hub, "game_state")
+	if msg.Payload["is_check"] != true {
+		t.Errorf("Expected is_check after the chariot's check, got %v", msg.Payload["is_check"])
+	}
+	if msg.Payload["is_checkmate"] != false || msg.Payload["is_stalemate"] != false {
+		t.Errorf("Red can still answer the check, got is_checkmate=%v is_stalemate=%v",
+			msg.Payload["is_checkmate"], msg.Payload["is_stalemate"])
+	}
+}
+
+func TestRoom_GameState_AssistToggle(t *testing.T) {
+	for _, assist := range []bool{true, false} {
+		room, _, _ := setupRollbackRoom(t, config.RulesConfig{ThreatHints: true}, checkOnRedMoves)
+		room.Game.AssistEnabled = assist
+
+		room.mu.Lock()
+		room.sendGameState()
+		room.mu.Unlock()
+
+		msg := nextBroadcast(t, room.Hub, "game_state")
+		if _, ok := msg.Payload["threatened_pieces"]; ok != assist {
+			t.Errorf("Assist %v: expected threatened_pieces present = %v, got %v", assist, assist, ok)
+		}
+		if !assist {
+			if _, ok := msg.Payload["forced_move"]; ok {
+				t.Error("Forced move hint should be left out without assistance")
+			}
+		}
+		if _, ok := msg.Payload["last_move"]; !ok {
+			t.Errorf("Assist %v: last move should always be sent", assist)
+		}
+	}
+}
+
+// setupForcedMoveRoom returns a room whose black general on e9, hemmed in
+// by the red chariot on a8 and the facing red general on d0, has one legal
+// move: e9 to f9.
+func setupForcedMoveRoom(t *testing.T, assist bool) (*GameRoom, *Client) {
+	t.Helper()
+	room, red, black := setupRollbackRoom(t, config.RulesConfig{}, nil)
+	room.RedPlayer, room.BlackPlayer = red, black
+	room.Game.AssistEnabled = assist
+
+	board, err := game.ParseFEN("4k4/R8/9/9/9/9/9/9/9/3K5")
+	if err != nil {
+		t.Fatalf("ParseFEN failed: %v", err)
+	}
+	engine, err := game.NewGameEngineFromState(room.GameID, room.Game.RedPlayerID, room.Game.BlackPlayerID, board, models.PlayerColorBlack, nil)
+	if err != nil {
+		t.Fatalf("NewGameEngineFromState failed: %v", err)
+	}
+	room.engine = engine
+	room.CurrentTurn = models.PlayerColorBlack
+	return room, black
+}
+
+func TestRoom_FullState_ForcedMoveFollowsAssist(t *testing.T) {
+	for _, assist := range []bool{true, false} {
+		room, black := setupForcedMoveRoom(t, assist)
+
+		room.mu.Lock()
+		room.sendFullState(black)
+		room.mu.Unlock()
+
+		msg := nextMessage(t, black)
+		state, _ := msg.Payload["state"].(map[string]interface{})
+		if _, ok := state["forced_move"]; ok != assist {
+			t.Errorf("Assist %v: expected the forced move in the full state = %v, got %v", assist, assist, state["forced_move"])
+		}
+	}
+}
+
+func TestRoom_GetForcedMove(t *testing.T) {
+	room, black := setupForcedMoveRoom(t, true)
+
+	black.handleMessage([]byte(`{"type":"get_forced_move"}`))
+	msg := nextMessage(t, black)
+	forced, _ := msg.Payload["forced_move"].(map[string]interface{})
+	if msg.Type != "forced_move" || forced["from"] != "e9" || forced["to"] != "f9" {
+		t.Errorf("Expected the forced move e9-f9, got %s %v", msg.Type, msg.Payload)
+	}
+
+	room.Game.AssistEnabled = false
+	black.handleMessage([]byte(`{"type":"get_forced_move"}`))
+	if msg := nextMessage(t, black); msg.Type != "error" || msg.Payload["code"] != "assist_disabled" {
+		t.Errorf("Expected assist_disabled without assistance, got %s %v", msg.Type, msg.Payload)
+	}
+}
+
+func TestRoom_HandleMove_CarriesHints(t *testing.T) {
+	for _, assist := range []bool{true, false} {
+		room, red, black := setupRollbackRoom(t, config.RulesConfig{ThreatHints: true}, checkOnRedMoves[:5])
+		room.RedPlayer, room.BlackPlayer = red, black
+		room.Game.AssistEnabled = assist
+		countBroadcasts(room.Hub)
+
+		room.HandleMove(black, "d8", "d0", "chariot", 0)
+
+		result := nextMessage(t, black)
+		opponent := nextBroadcast(t, room.Hub, "opponent_move")
+		for _, msg := range []OutgoingMessage{result, opponent} {
+			if _, ok := msg.Payload["threatened_pieces"]; ok != assist {
+				t.Errorf("Assist %v: expected threatened_pieces in %s = %v, got %v", assist, msg.Type, assist, ok)
+			}
+			if _, ok := msg.Payload["red_latency"]; !ok {
+				t.Errorf("Assist %v: %s should carry the latencies", assist, msg.Type)
+			}
+		}
+	}
+}
+
+func TestRoom_JoinPlayer_RejectsSpectator(t *testing.T) {
+	g := newTestGame(t, config.RulesConfig{})
+	room := g.room(t)
+
+	client := newTestClient(g.hub, g.game.ID, g.game.RedPlayerID)
+	client.Role = ClientRoleSpectator
+	if err := room.JoinPlayer(client); err == nil {
+		t.Fatal("Expected a spectator to be refused a seat")
+	}
+	if room.RedPlayer != nil {
+		t.Error("Spectator should not be seated as red")
+	}
+}
+
+func TestRoom_AddSpectator_SendsFullStateMidGame(t *testing.T) {
+	g := newTestGame(t, config.RulesConfig{})
+	g.playMoves([2]string{"h2", "e2"}, [2]string{"h9", "g7"})
+	room := g.room(t)
+
+	spectator := newTestClient(g.hub, g.game.ID, "viewer")
+	spectator.Role = ClientRoleSpectator
+	if err := room.AddSpectator(spectator); err != nil {
+		t.Fatalf("AddSpectator failed: %v", err)
+	}
+
+	msg := nextMessage(t, spectator)
+	if msg.Type != "game_state" {
+		t.Fatalf("Expected game_state, got %s", msg.Type)
+	}
+	if msg.Payload["move_count"] != float64(2) || msg.Payload["current_turn"] != "red" {
+		t.Errorf("Expected red to move after 2 moves, got %v", msg.Payload)
+	}
+	state, ok := msg.Payload["state"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected the full state, got %v", msg.Payload)
+	}
+	if board, ok := state["board"].([]interface{}); !ok || len(board) == 0 {
+		t.Errorf("Expected the board in the full state, got %v", state["board"])
+	}
+	// Only the spectator is sent the state
+	if counts := countBroadcasts(g.hub); counts["game_state"] != 0 {
+		t.Errorf("Spectator joining should not broadcast game_state, got %v", counts)
+	}
+}
+
+func TestRoom_AddSpectator_EnforcesCap(t *testing.T) {
+	g := newTestGame(t, config.RulesConfig{})
+	g.hub.SetMaxSpectators(2)
+	room := g.room(t)
+
+	for i, deviceID := range []string{"viewer-1", "viewer-2", "viewer-3"} {
+		spectator := newTestClient(g.hub, g.game.ID, deviceID)
+		spectator.Role = ClientRoleSpectator
+		err := room.AddSpectator(spectator)
+		if i < 2 && err != nil {
+			t.Fatalf("Spectator %d should be admitted: %v", i+1, err)
+		}
+		if i == 2 && err != ErrSpectatorsFull {
+			t.Fatalf("Expected ErrSpectatorsFull beyond the cap, got %v", err)
+		}
+	}
+	if room.SpectatorCount() != 2 {
+		t.Errorf("Expected 2 spectators, got %d", room.SpectatorCount())
+	}
+}
+
+func TestRoom_Chat_BroadcastToOpponent(t *testing.T) {
+	g := newTestGame(t, config.RulesConfig{})
+	room := g.room(t)
+	red := newTestClient(g.hub, g.game.ID, g.game.RedPlayerID)
+	black := newTestClient(g.hub, g.game.ID, g.game.BlackPlayerID)
+	g.hub.registerClient(red)
+	g.hub.registerClient(black)
+	room.RedPlayer, room.BlackPlayer = red, black
+	drainMessageTypes(t, black)
+
+	room.HandleChat(red, "  good\x07 game\n ")
+	deliverBroadcasts(g.hub)
+
+	msg := nextMessage(t, black)
+	if msg.Type != "chat" {
+		t.Fatalf("Expected chat, got %s %v", msg.Type, msg.Payload)
+	}
+	if msg.Payload["text"] != "good game" {
+		t.Errorf("Expected control characters stripped, got %q", msg.Payload["text"])
+	}
+	if msg.Payload["color"] != "red" {
+		t.Errorf("Expected chat tagged red, got %v", msg.Payload["color"])
+	}
+	if msg.Payload["sent_at"] == nil || msg.Payload["sent_at"] == "" {
+		t.Error("Expected a server timestamp on the chat message")
+	}
+}
+
+func TestRoom_Chat_Rejected(t *testing.T) {
+	room, red, black := setupRollbackRoom(t, config.RulesConfig{}, nil)
+	room.RedPlayer, room.BlackPlayer = red, black
+
+	tests := []struct {
+		name   string
+		client *Client
+		text   string
+		code   string
+	}{
+		{"too long", red, strings.Repeat("车", maxChatLength+1), "chat_too_long"},
+		{"only control characters", red, "\x00\x1b", "chat_empty"},
+		{"not seated", newTestClient(room.Hub, room.GameID, "stranger"), "hi", "not_participant"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			room.HandleChat(tt.client, tt.text)
+			msg := nextMessage(t, tt.client)
+			if msg.Type != "error" || msg.Payload["code"] != tt.code {
+				t.Errorf("Expected %s error, got %s %v", tt.code, msg.Type, msg.Payload)
+			}
+		})
+	}
+
+	// A message of exactly the maximum length is accepted
+	room.HandleChat(black, strings.Repeat("车", maxChatLength))
+	if counts := countBroadcasts(room.Hub); counts["chat"] != 1 {
+		t.Errorf("Expected only the valid chat to be broadcast, got %v", counts)
+	}
+}
+
+func TestRoom_Chat_RateLimited(t *testing.T) {
+	room, red, black := setupRollbackRoom(t, config.RulesConfig{}, nil)
+	room.RedPlayer, room.BlackPlayer = red, black
+
+	for i := 0; i < chatMessagesPerSecond+1; i++ {
+		room.HandleChat(red, "hi")
+	}
+
+	msg := nextMessage(t, red)
+	if msg.Type != "error" || msg.Payload["code"] != "chat_rate_limited" {
+		t.Errorf("Expected chat_rate_limited error, got %s %v", msg.Type, msg.Payload)
+	}
+	if counts := countBroadcasts(room.Hub); counts["chat"] != chatMessagesPerSecond {
+		t.Errorf("Expected %d chats broadcast, got %v", chatMessagesPerSecond, counts)
+	}
+}
+
+// nextAck skips the client's messages up to its next ack and returns it.
+func nextAck(t *testing.T, client *Client) OutgoingMessage {
+	t.Helper()
+	for {
+		msg := nextMessage(t, client)
+		if msg.Type == "ack" {
+			return msg
+		}
+	}
+}
+
+func TestRoom_HandleMove_DuplicateSeqAppliedOnce(t *testing.T) {
+	g := newTestGame(t, config.RulesConfig{})
+	room := g.room(t)
+	red := newTestClient(g.hub, g.game.ID, g.game.RedPlayerID)
+	black := newTestClient(g.hub, g.game.ID, g.game.BlackPlayerID)
+	room.RedPlayer, room.BlackPlayer = red, black
+
+	move := []byte(`{"type":"move","seq":7,"payload":{"from":"h2","to":"e2","piece_type":"cannon"}}`)
+	red.handleMessage(move)
+	ack := nextAck(t, red)
+	if ack.Payload["seq"] != float64(7) || ack.Payload["move_number"] != float64(1) || ack.Payload["duplicate"] != false {
+		t.Errorf("Expected ack for seq 7 as move 1, got %v", ack.Payload)
+	}
+
+	red.handleMessage(move)
+	ack = nextAck(t, red)
+	if ack.Payload["seq"] != float64(7) || ack.Payload["move_number"] != float64(1) || ack.Payload["duplicate"] != true {
+		t.Errorf("Expected duplicate ack for seq 7 as move 1, got %v", ack.Payload)
+	}
+	expectNoMessage(t, red)
+
+	if moves, _ := g.moveRepo.GetByGameID(context.Background(), g.game.ID); len(moves) != 1 {
+		t.Errorf("Expected the move to be recorded once, got %d moves", len(moves))
+	}
+	if room.MoveCount != 1 || room.CurrentTurn != models.PlayerColorBlack {
+		t.Errorf("Expected black to move after 1 move, got %d moves, %s to move", room.MoveCount, room.CurrentTurn)
+	}
+}
+
+func TestRoom_HandleMove_DuplicateSeqAfterReconnect(t *testing.T) {
+	g := newTestGame(t, config.RulesConfig{})
+	room := g.room(t)
+	red := newTestClient(g.hub, g.game.ID, g.game.RedPlayerID)
+	black := newTestClient(g.hub, g.game.ID, g.game.BlackPlayerID)
+	room.RedPlayer, room.BlackPlayer = red, black
+
+	room.HandleMove(red, "h2", "e2", "cannon", 3)
+	room.LeavePlayer(red)
+
+	// The retransmission arrives on the new connection
+	reconnected := newTestClient(g.hub, g.game.ID, g.game.RedPlayerID)
+	if err := room.JoinPlayer(reconnected); err != nil {
+		t.Fatalf("Rejoin failed: %v", err)
+	}
+	room.HandleMove(reconnected, "h2", "e2", "cannon", 3)
+
+	ack := nextAck(t, reconnected)
+	if ack.Payload["duplicate"] != true || ack.Payload["move_number"] != float64(1) {
+		t.Errorf("Expected duplicate ack for move 1, got %v", ack.Payload)
+	}
+	if moves, _ := g.moveRepo.GetByGameID(context.Background(), g.game.ID); len(moves) != 1 {
+		t.Errorf("Expected the move to be recorded once, got %d moves", len(moves))
+	}
+}
+
+func TestRoom_DrawOffer_TimesOut(t *testing.T) {
+	room, red, _ := setupRollbackRoom(t, config.RulesConfig{}, nil)
+	room.DrawOfferWindow = 20 * time.Millisecond
+	countBroadcasts(room.Hub)
+
+	room.HandleDrawOffer(red)
+	if msg := nextBroadcast(t, room.Hub, "draw_offered"); msg.Payload["offerer"] != room.Game.RedPlayerID {
+		t.Errorf("Expected red's offer, got %v", msg.Payload)
+	}
+
+	msg := awaitBroadcast(t, room.Hub, "draw_declined", time.Second)
+	if msg.Payload["reason"] != "timeout" || msg.Payload["offerer"] != room.Game.RedPlayerID {
+		t.Errorf("Expected red's offer to time out, got %v", msg.Payload)
+	}
+
+	room.mu.Lock()
+	pending, timer := room.PendingDrawOffer, room.DrawOfferTimeout
+	room.mu.Unlock()
+	if pending != nil || timer != nil {
+		t.Error("Expected the timed out offer to be cleared")
+	}
+
+	// A new offer can be made once the old one has expired
+	room.HandleDrawOffer(red)
+	if counts := countBroadcasts(room.Hub); counts["draw_offered"] != 1 {
+		t.Errorf("Expected a new offer after the timeout, got %v", counts)
+	}
+}
+
+func TestRoom_DrawOffer_RejectsSecondOffer(t *testing.T) {
+	room, red, black := setupRollbackRoom(t, config.RulesConfig{}, nil)
+	countBroadcasts(room.Hub)
+	drainMessageTypes(t, red)
+	drainMessageTypes(t, black)
+
+	room.HandleDrawOffer(red)
+	room.HandleDrawOffer(black)
+	room.HandleDrawOffer(red)
+
+	for _, client := range []*Client{black, red} {
+		msg := nextMessage(t, client)
+		if msg.Type != "error" || msg.Payload["code"] != "draw_offer_pending" {
+			t.Errorf("Expected draw_offer_pending for %s, got %s %v", client.DeviceID, msg.Type, msg.Payload)
+		}
+	}
+	if counts := countBroadcasts(room.Hub); counts["draw_offered"] != 1 {
+		t.Errorf("Expected a single draw offer, got %v", counts)
+	}
+	if room.PendingDrawOffer == nil || room.PendingDrawOffer.OfferingPlayerID != room.Game.RedPlayerID {
+		t.Errorf("Expected red's offer to stay pending, got %+v", room.PendingDrawOffer)
+	}
+}
+
+func TestRoom_DrawResponse_CancelsTimeout(t *testing.T) {
+	room, red, black := setupRollbackRoom(t, config.RulesConfig{}, nil)
+	room.DrawOfferWindow = 20 * time.Millisecond
+	countBroadcasts(room.Hub)
+
+	room.HandleDrawOffer(red)
+	room.HandleDrawResponse(black, false)
+
+	msg := nextBroadcast(t, room.Hub, "draw_declined")
+	if msg.Payload["reason"] != "declined" || msg.Payload["declined_by"] != room.Game.BlackPlayerID {
+		t.Errorf("Expected black to decline, got %v", msg.Payload)
+	}
+	if room.PendingDrawOffer != nil || room.DrawOfferTimeout != nil {
+		t.Error("Expected the answered offer to be cleared")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if counts := countBroadcasts(room.Hub); counts["draw_declined"] != 0 {
+		t.Errorf("Expected no timeout after the offer was answered, got %v", counts)
+	}
+}
+
+func TestRoom_DrawResponse_RequiresPendingOffer(t *testing.T) {
+	room, _, black := setupRollbackRoom(t, config.RulesConfig{}, nil)
+	drainMessageTypes(t, black)
+
+	room.HandleDrawResponse(black, true)
+
+	if msg := nextMessage(t, black); msg.Type != "error" || msg.Payload["code"] != "no_draw_offer" {
+		t.Errorf("Expected no_draw_offer, got %s %v", msg.Type, msg.Payload)
+	}
+	if room.IsGameOver {
+		t.Error("Expected the game to continue without an offer")
+	}
+}
+
+func TestRoom_DrawResponse_RejectsOfferingPlayer(t *testing.T) {
+	room, red, _ := setupRollbackRoom(t, config.RulesConfig{}, nil)
+	drainMessageTypes(t, red)
+
+	room.HandleDrawOffer(red)
+	room.HandleDrawResponse(red, true)
+
+	if msg := nextMessage(t, red); msg.Type != "error" || msg.Payload["code"] != "own_draw_offer" {
+		t.Errorf("Expected own_draw_offer, got %s %v", msg.Type, msg.Payload)
+	}
+	if room.IsGameOver {
+		t.Error("Expected the game to continue when the offering player answers")
+	}
+	if room.PendingDrawOffer == nil {
+		t.Error("Expected the offer to stay pending for the opponent")
+	}
+}
+
+// setupPremoveRoom returns a started room after the given moves, with both
+// players seated and their pending messages drained.
+func setupPremoveRoom(t *testing.T, moves [][2]string) (*GameRoom, *Client, *Client) {
+	t.Helper()
+	room, red, black := setupRollbackRoom(t, config.RulesConfig{}, moves)
+	room.JoinPlayer(red)
+	room.JoinPlayer(black)
+	t.Cleanup(room.Cleanup)
+	countBroadcasts(room.Hub)
+	drainMessageTypes(t, red)
+	drainMessageTypes(t, black)
+	return room, red, black
+}
+
+func TestRoom_Premove_PlayedWhenTurnSwitches(t *testing.T) {
+	room, red, black := setupPremoveRoom(t, nil)
+
+	room.HandlePremove(black, "a9", "a8", "chariot")
+	if msg := nextMessage(t, black); msg.Type != "premove_set" {
+		t.Fatalf("Expected premove_set, got %s %v", msg.Type, msg.Payload)
+	}
+	if room.MoveCount != 0 {
+		t.Fatalf("Expected the premove to wait for black's turn, got %d moves", room.MoveCount)
+	}
+
+	room.HandleMove(red, "h2", "e2", "", 0)
+
+	if room.MoveCount != 2 || room.CurrentTurn != models.PlayerColorRed {
+		t.Fatalf("Expected black's premove to be played, got %d moves with %s to move", room.MoveCount, room.CurrentTurn)
+	}
+	msg := nextMessage(t, black)
+	move, _ := msg.Payload["move"].(map[string]interface{})
+	if msg.Type != "move_result" || msg.Payload["success"] != true || move["from"] != "a9" || move["to"] != "a8" {
+		t.Errorf("Expected black's premove result, got %s %v", msg.Type, msg.Payload)
+	}
+	if counts := countBroadcasts(room.Hub); counts["opponent_move"] != 2 {
+		t.Errorf("Expected both moves to be broadcast, got %v", counts)
+	}
+	if _, ok := room.premoves[black]; ok {
+		t.Error("Expected the played premove to be cleared")
+	}
+}
+
+func TestRoom_Premove_ReplacesEarlierPremove(t *testing.T) {
+	room, red, black := setupPremoveRoom(t, nil)
+
+	room.HandlePremove(black, "a9", "a8", "")
+	room.HandlePremove(black, "a9", "a7", "")
+	drainMessageTypes(t, black)
+
+	room.HandleMove(red, "h2", "e2", "", 0)
+
+	msg := nextMessage(t, black)
+	move, _ := msg.Payload["move"].(map[string]interface{})
+	if msg.Type != "move_result" || move["to"] != "a7" {
+		t.Errorf("Expected the latest premove to be played, got %s %v", msg.Type, msg.Payload)
+	}
+}
+
+func TestRoom_Premove_CancelledWhenIllegal(t *testing.T) {
+	// Black's next move checks red along the back rank
+	room, red, black := setupPremoveRoom(t, checkOnRedMoves[:5])
+
+	room.HandlePremove(red, "i4", "i5", "")
+	drainMessageTypes(t, red)
+
+	room.HandleMove(black, "d8", "d0", "", 0)
+
+	msg := nextMessage(t, red)
+	if msg.Type != "premove_cancelled" || msg.Payload["reason"] != "illegal" {
+		t.Fatalf("Expected the premove to be cancelled as illegal, got %s %v", msg.Type, msg.Payload)
+	}
+	if room.MoveCount != 6 || room.CurrentTurn != models.PlayerColorRed {
+		t.Errorf("Expected red still to move after the cancelled premove, got %d moves with %s to move", room.MoveCount, room.CurrentTurn)
+	}
+	expectNoMessage(t, red)
+}
+
+func TestRoom_Premove_CancelledWhenSquaresChange(t *testing.T) {
+	room, red, black := setupPremoveRoom(t, nil)
+
+	// Red's cannon captures the horse black meant to move
+	room.HandlePremove(black, "h9", "g7", "")
+	drainMessageTypes(t, black)
+
+	room.HandleMove(red, "h2", "h9", "", 0)
+
+	msg := nextMessage(t, black)
+	if msg.Type != "premove_cancelled" || msg.Payload["reason"] != "squares_changed" {
+		t.Fatalf("Expected the premove to be cancelled, got %s %v", msg.Type, msg.Payload)
+	}
+	if room.MoveCount != 1 || room.CurrentTurn != models.PlayerColorBlack {
+		t.Errorf("Expected black still to move, got %d moves with %s to move", room.MoveCount, room.CurrentTurn)
+	}
+}
+
+func TestRoom_Ping_ReportsAverageLatency(t *testing.T) {
+	g := newTestGame(t, config.RulesConfig{})
+	room := g.room(t)
+	red := newTestClient(g.hub, g.game.ID, g.game.RedPlayerID)
+	black := newTestClient(g.hub, g.game.ID, g.game.BlackPlayerID)
+	room.JoinPlayer(red)
+	room.JoinPlayer(black)
+	drainMessageTypes(t, red)
+
+	// The first sample falls out of the rolling window
+	sent := time.Now()
+	for _, rtt := range []time.Duration{900, 100, 200, 300, 400, 500} {
+		data := red.pingData(sent)
+		red.handlePong(string(data), sent.Add(rtt*time.Millisecond))
+	}
+
+	room.sendFullState(red)
+	state := nextMessage(t, red)
+	if state.Type != "game_state" {
+		t.Fatalf("Expected game_state, got %s", state.Type)
+	}
+	if state.Payload["red_latency"] != float64(300) {
+		t.Errorf("Expected red's average latency of 300ms, got %v", state.Payload["red_latency"])
+	}
+	if state.Payload["black_latency"] != float64(0) {
+		t.Errorf("Expected no latency measured for black, got %v", state.Payload["black_latency"])
+	}
+
+	room.Timer.mu.RLock()
+	grace := room.Timer.redLatencyGrace
+	room.Timer.mu.RUnlock()
+	if grace != 150*time.Millisecond {
+		t.Errorf("Expected red to get half the round trip as grace, got %v", grace)
+	}
+}
+
+func TestRoom_Pong_IgnoresUnexpectedPongs(t *testing.T) {
+	g := newTestGame(t, config.RulesConfig{})
+	room := g.room(t)
+	red := newTestClient(g.hub, g.game.ID, g.game.RedPlayerID)
+	room.JoinPlayer(red)
+
+	sent := time.Now()
+	data := string(red.pingData(sent))
+	// A pong for an older ping, and a repeated pong, are not round trips
+	red.handlePong(strconv.FormatInt(sent.Add(-time.Minute).UnixNano(), 10), sent.Add(time.Second))
+	red.handlePong(data, sent.Add(100*time.Millisecond))
+	red.handlePong(data, sent.Add(5*time.Second))
+
+	room.mu.RLock()
+	defer room.mu.RUnlock()
+	if latency := playerLatency(red); latency != 100 {
+		t.Errorf("Expected only the matching pong to count, got %dms", latency)
+	}
+}
+
+func TestClient_Ping_EchoesClientTime(t *testing.T) {
+	g := newTestGame(t, config.RulesConfig{})
+	g.room(t)
+	red := newTestClient(g.hub, g.game.ID, g.game.RedPlayerID)
+
+	red.handleMessage([]byte(`{"type":"ping","payload":{"client_time":1700000000000}}`))
+	msg := nextMessage(t, red)
+	if msg.Type != "pong" || msg.Payload["client_time"] != float64(1700000000000) {
+		t.Fatalf("Expected a pong echoing the client time, got %s %v", msg.Type, msg.Payload)
+	}
+}
+
+func TestRoom_BotGame_ComputerRepliesAsBlack(t *testing.T) {
+	g := newTestGame(t, config.RulesConfig{})
+	g.game.BlackPlayerID = "bot-1"
+	g.game.BotDifficulty = 1
+	room := g.room(t)
+
+	red := newTestClient(g.hub, g.game.ID, g.game.RedPlayerID)
+	if err := room.JoinPlayer(red); err != nil {
+		t.Fatalf("JoinPlayer failed: %v", err)
+	}
+	room.HandleMove(red, "h2", "e2", "cannon", 0)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		room.mu.RLock()
+		moveCount, turn := room.MoveCount, room.CurrentTurn
+		room.mu.RUnlock()
+		if moveCount == 2 {
+			if turn != models.PlayerColorRed {
+				t.Errorf("Expected red to move after the computer's reply, got %s", turn)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Computer did not reply, move count %d", moveCount)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if moves, _ := g.moveRepo.GetByGameID(context.Background(), g.game.ID); len(moves) != 2 || moves[1].PlayerID != "bot-1" {
+		t.Errorf("Expected the computer's move to be stored, got %d moves", len(moves))
+	}
+}
@@ -3,10 +3,13 @@ package websocket
 
 import (
 	"encoding/json"
+	"runtime/debug"
 	"sync"
 	"time"
 
 	"github.com/rs/zerolog/log"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
 )
 
 // GameTimer manages the turn timer for a specific game.
@@ -16,9 +19,34 @@ type GameTimer struct {
 	RedTimeRemaining int
 	BlackTimeRemaining int
 	CurrentTurn      string // "red" or "black"
-	TurnTimeout      int    // timeout in seconds per turn
+	TurnTimeout      int    // timeout in seconds per turn, or per side for the game
 	IsPaused         bool   // paused during disconnection
 	IsRunning        bool
+	FirstMoveGrace   int // extra seconds for each side's first move
+	MoveCount        int // moves made in the game
+
+	// TimeControl is how the clocks run, and Increment the Fischer
+	// increment or Bronstein delay in seconds
+	TimeControl models.TimeControl
+	Increment   int
+
+	// Device IDs of the players, so timer updates can name whose turn it is
+	RedPlayerID   string
+	BlackPlayerID string
+
+	// Extra free time each turn for the delay of a player's moves reaching
+	// the server, set from their measured latency
+	redLatencyGrace   time.Duration
+	blackLatencyGrace time.Duration
+
+	// Move-time accounting. The mover's clock and the time at the start of
+	// their turn let SwitchTurn deduct the exact thinking time, so clocks do
+	// not drift when ticks are missed. Paused time is not counted.
+	turnStartedAt time.Time
+	turnStartTime int
+	pausedAt      time.Time
+	pausedFor     time.Duration
+	now           func() time.Time
 
 	mu       sync.RWMutex
 	ticker   *time.Ticker
@@ -39,8 +67,25 @@ func NewTimerManager() *TimerManager {
 	}
 }
 
+// TimeControl configures a game's clocks.
+type TimeControl struct {
+	// Mode is how the clocks run. Empty means per-move.
+	Mode models.TimeControl
+	// BaseTime is the time for each turn under per-move control, and each
+	// side's time for the whole game otherwise, in seconds.
+	BaseTime int
+	// Increment is the Fischer increment or Bronstein delay, in seconds.
+	Increment int
+}
+
 // CreateTimer creates a new timer for a game.
-func (m *TimerManager) CreateTimer(gameID string, hub *Hub, turnTimeout int) *GameTimer {
+func (m *TimerManager) CreateTimer(gameID string, hub *Hub, control TimeControl) *GameTimer {
+	return m.CreateTimerWithFirstMoveGrace(gameID, hub, control, 0)
+}
+
+// CreateTimerWithFirstMoveGrace creates a new timer for a game that gives
+// each side firstMoveGrace extra seconds for their opening move.
+func (m *TimerManager) CreateTimerWithFirstMoveGrace(gameID string, hub *Hub, control TimeControl, firstMoveGrace int) *GameTimer {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -49,18 +94,33 @@ func (m *TimerManager) CreateTimer(gameID string, hub *Hub, turnTimeout int) *Ga
 		existing.Stop()
 	}
 
+	if control.Mode == "" {
+		control.Mode = models.TimeControlPerMove
+	}
+
 	timer := &GameTimer{
 		GameID:             gameID,
 		Hub:                hub,
-		RedTimeRemaining:   turnTimeout,
-		BlackTimeRemaining: turnTimeout,
+		RedTimeRemaining:   control.BaseTime,
+		BlackTimeRemaining: control.BaseTime,
 		CurrentTurn:        "red", // Red always starts
-		TurnTimeout:        turnTimeout,
+		TurnTimeout:        control.BaseTime,
 		IsPaused:           false,
 		IsRunning:          false,
+		FirstMoveGrace:     firstMoveGrace,
+		TimeControl:        control.Mode,
+		Increment:          control.Increment,
+		now:                time.Now,
 		stopChan:           make(chan struct{}),
 		done:               make(chan struct{}),
 	}
+	// A per-move clock holds the grace; otherwise it is free thinking time
+	if control.Mode == models.TimeControlPerMove {
+		timer.RedTimeRemaining += firstMoveGrace
+		timer.BlackTimeRemaining += firstMoveGrace
+	}
+	timer.RedTimeRemaining = timer.clampClock("red", timer.RedTimeRemaining)
+	timer.BlackTimeRemaining = timer.clampClock("black", timer.BlackTimeRemaining)
 
 	m.timers[gameID] = timer
 	return timer
@@ -92,6 +152,7 @@ func (t *GameTimer) Start() {
 		return
 	}
 	t.IsRunning = true
+	t.beginTurn()
 	t.ticker = time.NewTicker(1 * time.Second)
 	t.stopChan = make(chan struct{})
 	t.done = make(chan struct{})
@@ -135,6 +196,9 @@ func (t *GameTimer) Stop() {
 func (t *GameTimer) Pause() {
 	t.mu.Lock()
 	defer t.mu.Unlock()
+	if !t.IsPaused {
+		t.pausedAt = t.now()
+	}
 	t.IsPaused = true
 	log.Info().Str("game_id", t.GameID).Msg("Timer paused")
 }
@@ -143,21 +207,49 @@ func (t *GameTimer) Pause() {
 func (t *GameTimer) Resume() {
 	t.mu.Lock()
 	defer t.mu.Unlock()
+	if t.IsPaused && !t.pausedAt.IsZero() {
+		t.pausedFor += t.now().Sub(t.pausedAt)
+		t.pausedAt = time.Time{}
+	}
 	t.IsPaused = false
 	log.Info().Str("game_id", t.GameID).Msg("Timer resumed")
 }
 
-// SwitchTurn switches the active turn and resets the current player's time.
+// SwitchTurn deducts the mover's thinking time from their clock, then
+// switches the active turn. Under per-move time control the next player's
+// time is reset; under Fischer the mover is credited the increment.
 func (t *GameTimer) SwitchTurn() {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
+	if elapsed, ok := t.thinkingTime(); ok {
+		charged := max(elapsed-t.freeTime(), 0)
+		remaining := t.clampClock(t.CurrentTurn, t.turnStartTime-int(charged/time.Second))
+		// A flagged player is not saved by the increment
+		if t.TimeControl == models.TimeControlFischer && remaining > 0 {
+			remaining += t.Increment
+		}
+		if t.CurrentTurn == "red" {
+			t.RedTimeRemaining = remaining
+		} else {
+			t.BlackTimeRemaining = remaining
+		}
+	}
+
+	t.MoveCount++
 	if t.CurrentTurn == "red" {
 		t.CurrentTurn = "black"
-		t.BlackTimeRemaining = t.TurnTimeout
+		if t.TimeControl == models.TimeControlPerMove {
+			t.BlackTimeRemaining = t.clampClock("black", t.turnBudget())
+		}
 	} else {
 		t.CurrentTurn = "red"
-		t.RedTimeRemaining = t.TurnTimeout
+		if t.TimeControl == models.TimeControlPerMove {
+			t.RedTimeRemaining = t.clampClock("red", t.turnBudget())
+		}
+	}
+	if !t.turnStartedAt.IsZero() {
+		t.beginTurn()
 	}
 
 	log.Debug().
@@ -166,20 +258,129 @@ func (t *GameTimer) SwitchTurn() {
 		Msg("Turn switched")
 }
 
+// beginTurn records the start of the current player's turn for move-time
+// accounting. Callers must hold t.mu.
+func (t *GameTimer) beginTurn() {
+	now := t.now()
+	t.turnStartedAt = now
+	t.turnStartTime = t.RedTimeRemaining
+	if t.CurrentTurn == "black" {
+		t.turnStartTime = t.BlackTimeRemaining
+	}
+	t.pausedFor = 0
+	t.pausedAt = time.Time{}
+	if t.IsPaused {
+		t.pausedAt = now
+	}
+}
+
+// thinkingTime returns how long the current player has been thinking this
+// turn, excluding paused time. It returns false if the timer has not
+// started. Callers must hold t.mu.
+func (t *GameTimer) thinkingTime() (time.Duration, bool) {
+	if t.turnStartedAt.IsZero() {
+		return 0, false
+	}
+	now := t.now()
+	elapsed := now.Sub(t.turnStartedAt) - t.pausedFor
+	if t.IsPaused && !t.pausedAt.IsZero() {
+		elapsed -= now.Sub(t.pausedAt)
+	}
+	return max(elapsed, 0), true
+}
+
+// freeTime returns how long the current player may think this turn before
+// their clock runs: their latency grace, plus the Bronstein delay and the
+// first move grace on each side's first move when the clocks run for the
+// whole game. Callers must hold t.mu.
+func (t *GameTimer) freeTime() time.Duration {
+	latency := t.redLatencyGrace
+	if t.CurrentTurn == "black" {
+		latency = t.blackLatencyGrace
+	}
+	if t.TimeControl == models.TimeControlPerMove {
+		return latency
+	}
+	free := 0
+	if t.TimeControl == models.TimeControlBronstein {
+		free = t.Increment
+	}
+	if t.MoveCount < 2 {
+		free += t.FirstMoveGrace
+	}
+	return time.Duration(free)*time.Second + latency
+}
+
+// SetLatencyGrace sets the extra free time a player gets each turn to make
+// up for the delay of their moves reaching the server. It is capped at
+// maxLatencyGrace so a slow connection cannot stall the clock.
+func (t *GameTimer) SetLatencyGrace(color string, grace time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	grace = min(max(grace, 0), maxLatencyGrace)
+	if color == "red" {
+		t.redLatencyGrace = grace
+	} else {
+		t.blackLatencyGrace = grace
+	}
+}
+
+// turnBudget returns the time for a per-move turn starting now. Each side's first
+// move (the first two moves of the game) gets the first move grace on top.
+// Callers must hold t.mu.
+func (t *GameTimer) turnBudget() int {
+	if t.MoveCount < 2 {
+		return t.TurnTimeout + t.FirstMoveGrace
+	}
+	return t.TurnTimeout
+}
+
+// SetMoveCount sets how many moves a game already in progress has had, so
+// the first move grace is only given on each side's first move of the game.
+// Under per-move control the clocks are reset to the turn budget.
+func (t *GameTimer) SetMoveCount(moves int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.MoveCount = moves
+	if t.TimeControl == models.TimeControlPerMove {
+		t.RedTimeRemaining = t.clampClock("red", t.turnBudget())
+		t.BlackTimeRemaining = t.clampClock("black", t.turnBudget())
+	}
+}
+
 // UpdateFromServer updates the timer with server-authoritative values.
+// Negative times are clamped to zero.
 func (t *GameTimer) UpdateFromServer(redTime, blackTime int, currentTurn string) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	t.RedTimeRemaining = redTime
-	t.BlackTimeRemaining = blackTime
+	t.RedTimeRemaining = t.clampClock("red", redTime)
+	t.BlackTimeRemaining = t.clampClock("black", blackTime)
 	t.CurrentTurn = currentTurn
+	if !t.turnStartedAt.IsZero() {
+		t.beginTurn()
+	}
 }
 
-// GetState returns the current timer state.
+// GetState returns the current timer state. Remaining times are never
+// negative.
 func (t *GameTimer) GetState() (redTime, blackTime int, currentTurn string, isPaused bool) {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
-	return t.RedTimeRemaining, t.BlackTimeRemaining, t.CurrentTurn, t.IsPaused
+	return max(t.RedTimeRemaining, 0), max(t.BlackTimeRemaining, 0), t.CurrentTurn, t.IsPaused
+}
+
+// clampClock returns seconds clamped at zero, logging negative values as an
+// anomaly since no valid clock update produces them.
+func (t *GameTimer) clampClock(color string, seconds int) int {
+	if seconds >= 0 {
+		return seconds
+	}
+	log.Warn().
+		Str("game_id", t.GameID).
+		Str("color", color).
+		Int("seconds", seconds).
+		Msg("Negative clock value clamped to zero")
+	return 0
 }
 
 // run is the main timer loop.
@@ -192,51 +393,91 @@ func (t *GameTimer) run() {
 			return
 
 		case <-t.ticker.C:
-			t.tick()
+			if !t.safeTick() {
+				t.haltAfterPanic()
+				return
+			}
 		}
 	}
 }
 
+// safeTick runs tick, recovering from a panic in it or in the broadcast and
+// timeout handlers it calls, so one game's timer cannot crash the process.
+// It reports whether the tick completed.
+func (t *GameTimer) safeTick() (ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error().
+				Str("game_id", t.GameID).
+				Interface("panic", r).
+				Bytes("stack", debug.Stack()).
+				Msg("Timer tick panicked, stopping timer")
+			ok = false
+		}
+	}()
+
+	t.tick()
+	return true
+}
+
+// haltAfterPanic marks the timer stopped after its loop gave up, so that it
+// reports its real state and can be started again.
+func (t *GameTimer) haltAfterPanic() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.IsRunning = false
+	if t.ticker != nil {
+		t.ticker.Stop()
+	}
+}
+
 // tick decrements the current player's time by one second.
 func (t *GameTimer) tick() {
+	redTime, blackTime, currentTurn, loserColor, ok := t.countDown()
+	if !ok {
+		return
+	}
+
+	// Broadcast timer update to clients every second
+	t.broadcastTimerUpdate(redTime, blackTime, currentTurn)
+
+	// Handle timeout
+	if loserColor != "" {
+		t.handleTimeout(loserColor)
+	}
+}
+
+// countDown charges the current player a second, unless the timer is
+// paused or they are within their free time. It returns the clocks and the
+// color of a player who ran out of time, and false if the timer is paused.
+func (t *GameTimer) countDown() (redTime, blackTime int, currentTurn, loserColor string, ok bool) {
 	t.mu.Lock()
+	defer t.mu.Unlock()
 
 	if t.IsPaused {
-		t.mu.Unlock()
-		return
+		return 0, 0, "", "", false
 	}
 
-	var timeoutOccurred bool
-	var loserColor string
-
-	if t.CurrentTurn == "red" {
+	elapsed, started := t.thinkingTime()
+	switch {
+	case started && elapsed < t.freeTime():
+		// The clock stands still during a Bronstein delay or first move grace
+	case t.CurrentTurn == "red":
 		t.RedTimeRemaining--
 		if t.RedTimeRemaining <= 0 {
 			t.RedTimeRemaining = 0
-			timeoutOccurred = true
 			loserColor = "red"
 		}
-	} else {
+	default:
 		t.BlackTimeRemaining--
 		if t.BlackTimeRemaining <= 0 {
 			t.BlackTimeRemaining = 0
-			timeoutOccurred = true
 			loserColor = "black"
 		}
 	}
 
-	redTime := t.RedTimeRemaining
-	blackTime := t.BlackTimeRemaining
-	currentTurn := t.CurrentTurn
-	t.mu.Unlock()
-
-	// Broadcast timer update to clients every second
-	t.broadcastTimerUpdate(redTime, blackTime, currentTurn)
-
-	// Handle timeout
-	if timeoutOccurred {
-		t.handleTimeout(loserColor)
-	}
+	return t.RedTimeRemaining, t.BlackTimeRemaining, t.CurrentTurn, loserColor, true
 }
 
 // broadcastTimerUpdate sends timer state to all clients in the game.
@@ -247,8 +488,11 @@ func (t *GameTimer) broadcastTimerUpdate(redTime, blackTime int, currentTurn str
 			"red_time":     redTime,
 			"black_time":   blackTime,
 			"current_turn": currentTurn,
+			// Lets each client tell whether it is to move without
+			// comparing colors
+			"active_player_id": t.activePlayerID(currentTurn),
 		},
-		Timestamp: time.Now(),
+		Timestamp: models.FormatTimestamp(time.Now()),
 		MessageID: generateMessageID(),
 	}
 
@@ -261,6 +505,14 @@ func (t *GameTimer) broadcastTimerUpdate(redTime, blackTime int, currentTurn str
 	t.Hub.BroadcastToGame(t.GameID, data)
 }
 
+// activePlayerID returns the device ID of the player whose turn it is.
+func (t *GameTimer) activePlayerID(currentTurn string) string {
+	if currentTurn == "red" {
+		return t.RedPlayerID
+	}
+	return t.BlackPlayerID
+}
+
 // handleTimeout handles a timeout event (player loses).
 func (t *GameTimer) handleTimeout(loserColor string) {
 	log.Info().
@@ -284,7 +536,7 @@ func (t *GameTimer) handleTimeout(loserColor string) {
 			"winner_color":  winnerColor,
 			"timeout_color": loserColor,
 		},
-		Timestamp: time.Now(),
+		Timestamp: models.FormatTimestamp(time.Now()),
 		MessageID: generateMessageID(),
 	}
 
@@ -0,0 +1,257 @@
+// Package websocket provides unit tests for game timers.
+package websocket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/xiangqi/chinese-chess-backend/internal/models"
+)
+
+func TestGameTimer_FirstMoveGrace(t *testing.T) {
+	manager := NewTimerManager()
+	timer := manager.CreateTimerWithFirstMoveGrace("game-1", nil, TimeControl{BaseTime: 60}, 30)
+
+	redTime, blackTime, _, _ := timer.GetState()
+	if redTime != 90 {
+		t.Errorf("Expected red's first turn to have 90s, got %d", redTime)
+	}
+
+	// Red moves; black's first turn also gets the grace
+	timer.SwitchTurn()
+	_, blackTime, _, _ = timer.GetState()
+	if blackTime != 90 {
+		t.Errorf("Expected black's first turn to have 90s, got %d", blackTime)
+	}
+
+	// Later turns return to the normal budget
+	timer.SwitchTurn()
+	redTime, _, _, _ = timer.GetState()
+	if redTime != 60 {
+		t.Errorf("Expected red's second turn to have 60s, got %d", redTime)
+	}
+	timer.SwitchTurn()
+	_, blackTime, _, _ = timer.GetState()
+	if blackTime != 60 {
+		t.Errorf("Expected black's second turn to have 60s, got %d", blackTime)
+	}
+}
+
+func TestGameTimer_SetMoveCount_NoFirstMoveGraceAfterOpening(t *testing.T) {
+	timer := NewTimerManager().CreateTimerWithFirstMoveGrace("game-1", nil, TimeControl{BaseTime: 60}, 30)
+	timer.SetMoveCount(4)
+
+	redTime, blackTime, _, _ := timer.GetState()
+	if redTime != 60 || blackTime != 60 {
+		t.Errorf("Expected no grace in a game past its first moves, got red=%d black=%d", redTime, blackTime)
+	}
+}
+
+func TestGameTimer_SetMoveCount_KeepsGraceForBlacksFirstMove(t *testing.T) {
+	timer := NewTimerManager().CreateTimerWithFirstMoveGrace("game-1", nil, TimeControl{BaseTime: 60}, 30)
+	timer.SetMoveCount(1)
+
+	if _, blackTime, _, _ := timer.GetState(); blackTime != 90 {
+		t.Errorf("Expected black's first turn to keep the grace, got %d", blackTime)
+	}
+}
+
+func TestGameTimer_NoFirstMoveGraceByDefault(t *testing.T) {
+	timer := NewTimerManager().CreateTimer("game-1", nil, TimeControl{BaseTime: 60})
+
+	redTime, _, _, _ := timer.GetState()
+	if redTime != 60 {
+		t.Errorf("Expected 60s without a grace, got %d", redTime)
+	}
+	timer.SwitchTurn()
+	if _, blackTime, _, _ := timer.GetState(); blackTime != 60 {
+		t.Errorf("Expected 60s without a grace, got %d", blackTime)
+	}
+}
+
+func TestGameTimer_UpdateFromServer_ClampsNegativeTimes(t *testing.T) {
+	timer := NewTimerManager().CreateTimer("game-1", nil, TimeControl{BaseTime: 60})
+
+	timer.UpdateFromServer(-5, -120, "black")
+
+	redTime, blackTime, currentTurn, _ := timer.GetState()
+	if redTime != 0 || blackTime != 0 {
+		t.Errorf("Expected negative times to be clamped to 0, got red=%d black=%d", redTime, blackTime)
+	}
+	if currentTurn != "black" {
+		t.Errorf("Expected current turn black, got %s", currentTurn)
+	}
+
+	// Valid values are applied unchanged
+	timer.UpdateFromServer(42, 17, "red")
+	if redTime, blackTime, _, _ = timer.GetState(); redTime != 42 || blackTime != 17 {
+		t.Errorf("Expected red=42 black=17, got red=%d black=%d", redTime, blackTime)
+	}
+}
+
+func TestGameTimer_GetState_NeverNegative(t *testing.T) {
+	timer := NewTimerManager().CreateTimer("game-1", nil, TimeControl{BaseTime: 60})
+	timer.RedTimeRemaining = -3
+
+	if redTime, _, _, _ := timer.GetState(); redTime != 0 {
+		t.Errorf("Expected GetState to report 0, got %d", redTime)
+	}
+}
+
+// newClockedTimer returns a started timer whose clock the test controls.
+func newClockedTimer(t *testing.T, control TimeControl) (*GameTimer, *time.Time) {
+	timer := NewTimerManager().CreateTimer("game-1", nil, control)
+	now := time.Now()
+	timer.now = func() time.Time { return now }
+	timer.Start()
+	t.Cleanup(timer.Stop)
+	return timer, &now
+}
+
+func TestGameTimer_SwitchTurn_DeductsThinkingTime(t *testing.T) {
+	timer, now := newClockedTimer(t, TimeControl{BaseTime: 60})
+
+	// Red deliberates for five seconds, without any ticks arriving
+	*now = now.Add(5*time.Second + 200*time.Millisecond)
+	timer.SwitchTurn()
+
+	redTime, blackTime, currentTurn, _ := timer.GetState()
+	if redTime != 55 {
+		t.Errorf("Expected 5s deducted from red's clock, got %d remaining", redTime)
+	}
+	if blackTime != 60 || currentTurn != "black" {
+		t.Errorf("Expected black to start a full 60s turn, got %d (%s)", blackTime, currentTurn)
+	}
+}
+
+func TestGameTimer_SwitchTurn_ExcludesPausedTime(t *testing.T) {
+	timer, now := newClockedTimer(t, TimeControl{BaseTime: 60})
+
+	*now = now.Add(3 * time.Second)
+	timer.Pause()
+	*now = now.Add(30 * time.Second)
+	timer.Resume()
+	*now = now.Add(2 * time.Second)
+	timer.SwitchTurn()
+
+	if redTime, _, _, _ := timer.GetState(); redTime != 55 {
+		t.Errorf("Expected only 5s of thinking deducted, got %d remaining", redTime)
+	}
+}
+
+func TestGameTimer_LatencyGraceIsCapped(t *testing.T) {
+	timer, now := newClockedTimer(t, TimeControl{BaseTime: 60})
+	timer.SetLatencyGrace("red", 5*time.Second)
+	timer.SetLatencyGrace("black", 500*time.Millisecond)
+
+	// Red's grace is capped at two seconds, so three of five are charged
+	*now = now.Add(5*time.Second + 200*time.Millisecond)
+	timer.SwitchTurn()
+	if redTime, _, _, _ := timer.GetState(); redTime != 57 {
+		t.Errorf("Expected 3s deducted from red's clock, got %d remaining", redTime)
+	}
+
+	*now = now.Add(1500 * time.Millisecond)
+	timer.SwitchTurn()
+	if _, blackTime, _, _ := timer.GetState(); blackTime != 59 {
+		t.Errorf("Expected 1s deducted from black's clock, got %d remaining", blackTime)
+	}
+}
+
+func TestGameTimer_Fischer_AccruesIncrement(t *testing.T) {
+	timer, now := newClockedTimer(t, TimeControl{Mode: models.TimeControlFischer, BaseTime: 60, Increment: 5})
+
+	moves := []struct {
+		thinking   time.Duration
+		red, black int
+	}{
+		{2 * time.Second, 63, 60},  // Red: 60 - 2 + 5
+		{10 * time.Second, 63, 55}, // Black: 60 - 10 + 5
+		{time.Second, 67, 55},      // Red keeps what it saved
+		{0, 67, 60},                // A premove still earns the increment
+		{20 * time.Second, 52, 60},
+	}
+	for i, move := range moves {
+		*now = now.Add(move.thinking)
+		timer.SwitchTurn()
+
+		redTime, blackTime, _, _ := timer.GetState()
+		if redTime != move.red || blackTime != move.black {
+			t.Errorf("After move %d: expected red=%d black=%d, got red=%d black=%d",
+				i+1, move.red, move.black, redTime, blackTime)
+		}
+	}
+}
+
+func TestGameTimer_Fischer_NoIncrementAfterFlag(t *testing.T) {
+	timer, now := newClockedTimer(t, TimeControl{Mode: models.TimeControlFischer, BaseTime: 10, Increment: 5})
+
+	*now = now.Add(12 * time.Second)
+	timer.SwitchTurn()
+
+	if redTime, _, _, _ := timer.GetState(); redTime != 0 {
+		t.Errorf("Expected red's spent clock to stay at 0, got %d", redTime)
+	}
+}
+
+func TestGameTimer_Bronstein_DelaysClock(t *testing.T) {
+	timer, now := newClockedTimer(t, TimeControl{Mode: models.TimeControlBronstein, BaseTime: 60, Increment: 5})
+
+	// Within the delay nothing is charged, and nothing is gained either
+	*now = now.Add(3 * time.Second)
+	timer.SwitchTurn()
+	// Beyond it only the excess is charged
+	*now = now.Add(8 * time.Second)
+	timer.SwitchTurn()
+
+	redTime, blackTime, _, _ := timer.GetState()
+	if redTime != 60 || blackTime != 57 {
+		t.Errorf("Expected red=60 black=57, got red=%d black=%d", redTime, blackTime)
+	}
+}
+
+func TestGameTimer_Fischer_FirstMoveGraceIsFree(t *testing.T) {
+	timer := NewTimerManager().CreateTimerWithFirstMoveGrace("game-1", nil,
+		TimeControl{Mode: models.TimeControlFischer, BaseTime: 60, Increment: 5}, 30)
+	now := time.Now()
+	timer.now = func() time.Time { return now }
+	timer.Start()
+	t.Cleanup(timer.Stop)
+
+	if redTime, _, _, _ := timer.GetState(); redTime != 60 {
+		t.Fatalf("Expected the grace to stay off the clock, got %d", redTime)
+	}
+
+	// Red's first 30 seconds are free; later moves are charged in full
+	now = now.Add(40 * time.Second)
+	timer.SwitchTurn()
+	now = now.Add(time.Second)
+	timer.SwitchTurn()
+	now = now.Add(40 * time.Second)
+	timer.SwitchTurn()
+
+	if redTime, _, _, _ := timer.GetState(); redTime != 20 {
+		t.Errorf("Expected red=20 (60 - 10 + 5 - 40 + 5), got %d", redTime)
+	}
+}
+
+func TestGameTimer_RecoversFromTickPanic(t *testing.T) {
+	// Without a hub, broadcasting the timer update panics
+	timer := NewTimerManager().CreateTimer("game-1", nil, TimeControl{BaseTime: 60})
+	timer.IsRunning = true
+	timer.ticker = time.NewTicker(time.Millisecond)
+
+	go timer.run()
+
+	select {
+	case <-timer.done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the timer loop to exit after the panic")
+	}
+
+	timer.mu.RLock()
+	defer timer.mu.RUnlock()
+	if timer.IsRunning {
+		t.Error("Expected the timer to be marked stopped after the panic")
+	}
+}